@@ -259,6 +259,19 @@ type InternalMeta struct {
 	Disabled bool   `bson:"disabled" json:"disabled"`
 	Path     string `bson:"path" json:"path"`
 	Method   string `bson:"method" json:"method"`
+	// Name makes the endpoint addressable from looping as `tyk://<api>/@<name>`.
+	Name string `bson:"name" json:"name,omitempty"`
+	// Parameters is the contract a named looping call must satisfy. Path
+	// parameters such as `{id}` are filled from the parameter of the same name.
+	Parameters []InternalParameter `bson:"parameters" json:"parameters,omitempty"`
+}
+
+// InternalParameter describes a parameter accepted by a named internal endpoint.
+type InternalParameter struct {
+	Name     string `bson:"name" json:"name"`
+	Required bool   `bson:"required" json:"required"`
+	// Pattern is an optional regular expression the value has to match.
+	Pattern string `bson:"pattern" json:"pattern,omitempty"`
 }
 
 type RequestSizeMeta struct {
@@ -296,6 +309,9 @@ type RoutingTriggerOptions struct {
 	SessionMetaMatches    map[string]StringRegexMap `bson:"session_meta_matches" json:"session_meta_matches"`
 	RequestContextMatches map[string]StringRegexMap `bson:"request_context_matches" json:"request_context_matches"`
 	PayloadMatches        StringRegexMap            `bson:"payload_matches" json:"payload_matches"`
+	// ClaimMatches match the claims of the JWT of the request, set in the request context when
+	// context variables are enabled.
+	ClaimMatches map[string]StringRegexMap `bson:"claim_matches,omitempty" json:"claim_matches,omitempty"`
 }
 
 // NewRoutingTriggerOptions allocates the maps inside RoutingTriggerOptions.
@@ -307,16 +323,22 @@ func NewRoutingTriggerOptions() RoutingTriggerOptions {
 		SessionMetaMatches:    make(map[string]StringRegexMap),
 		RequestContextMatches: make(map[string]StringRegexMap),
 		PayloadMatches:        StringRegexMap{},
+		ClaimMatches:          make(map[string]StringRegexMap),
 	}
 }
 
 type RoutingTrigger struct {
+	// Name identifies the trigger, the groups it captures are also set in the request context
+	// under its name, as `trigger-<name>-...`.
+	Name      string                `bson:"name,omitempty" json:"name,omitempty"`
 	On        RoutingTriggerOnType  `bson:"on" json:"on"`
 	Options   RoutingTriggerOptions `bson:"options" json:"options"`
 	RewriteTo string                `bson:"rewrite_to" json:"rewrite_to"`
 }
 
 type URLRewriteMeta struct {
+	// Name identifies the rule, in the results of the URL rewrite test endpoint.
+	Name         string           `bson:"name,omitempty" json:"name,omitempty"`
 	Disabled     bool             `bson:"disabled" json:"disabled"`
 	Path         string           `bson:"path" json:"path"`
 	Method       string           `bson:"method" json:"method"`
@@ -587,6 +609,31 @@ type CacheOptions struct {
 	EnableUpstreamCacheControl bool     `bson:"enable_upstream_cache_control" json:"enable_upstream_cache_control"`
 	CacheControlTTLHeader      string   `bson:"cache_control_ttl_header" json:"cache_control_ttl_header"`
 	CacheByHeaders             []string `bson:"cache_by_headers" json:"cache_by_headers"`
+	// WarmUp prefetches endpoints into the cache at interval, so they are always served from it.
+	WarmUp CacheWarmUp `bson:"warm_up" json:"warm_up"`
+}
+
+// CacheWarmUp configures prefetching cacheable endpoints through the API at interval. Only the
+// endpoints of keyless APIs are warmed up, their responses being shared by all the clients.
+type CacheWarmUp struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Interval in seconds between two warm-ups of the endpoints. Defaults to the cache timeout.
+	Interval int64 `bson:"interval" json:"interval"`
+	// RateLimit is the maximum number of warm-up requests per second sent to the upstream.
+	// Defaults to 1 request per second.
+	RateLimit float64 `bson:"rate_limit" json:"rate_limit"`
+	// Endpoints are the endpoints warmed up. Only safe methods are warmed up.
+	Endpoints []CacheWarmUpEndpoint `bson:"endpoints" json:"endpoints"`
+}
+
+// CacheWarmUpEndpoint is an endpoint warmed up in the cache.
+type CacheWarmUpEndpoint struct {
+	// Method of the requests, GET by default.
+	Method string `bson:"method" json:"method"`
+	// Path of the endpoint, with its query string, without the listen path.
+	Path string `bson:"path" json:"path"`
+	// Headers of the requests, including the headers the cache is keyed by.
+	Headers map[string]string `bson:"headers" json:"headers"`
 }
 
 type ResponseProcessor struct {
@@ -714,6 +761,7 @@ type APIDefinition struct {
 	JWTExpiresAtValidationSkew           uint64                 `bson:"jwt_expires_at_validation_skew" json:"jwt_expires_at_validation_skew"`
 	JWTNotBeforeValidationSkew           uint64                 `bson:"jwt_not_before_validation_skew" json:"jwt_not_before_validation_skew"`
 	JWTSkipKid                           bool                   `bson:"jwt_skip_kid" json:"jwt_skip_kid"`
+	JWTDecryptionKeys                    []string               `bson:"jwt_decryption_keys" json:"jwt_decryption_keys"`
 	Scopes                               Scopes                 `bson:"scopes" json:"scopes,omitempty"`
 	IDPClientIDMappingDisabled           bool                   `bson:"idp_client_id_mapping_disabled" json:"idp_client_id_mapping_disabled"`
 	JWTScopeToPolicyMapping              map[string]string      `bson:"jwt_scope_to_policy_mapping" json:"jwt_scope_to_policy_mapping"` // Deprecated: use Scopes.JWT.ScopeToPolicy or Scopes.OIDC.ScopeToPolicy
@@ -776,6 +824,768 @@ type APIDefinition struct {
 
 	// UpstreamAuth stores information about authenticating against upstream.
 	UpstreamAuth UpstreamAuth `bson:"upstream_auth" json:"upstream_auth"`
+
+	// InternalToken stores information about the JWT minted by the Gateway for the upstream.
+	InternalToken InternalToken `bson:"internal_token" json:"internal_token"`
+
+	// AllowedMethods restricts the HTTP methods accepted by the API.
+	AllowedMethods AllowedMethods `bson:"allowed_methods" json:"allowed_methods"`
+
+	// SlowRequests configures the detection of slow requests.
+	SlowRequests SlowRequests `bson:"slow_requests" json:"slow_requests"`
+
+	// FaultInjection configures faults injected into requests for chaos testing.
+	FaultInjection FaultInjection `bson:"fault_injection" json:"fault_injection"`
+
+	// WSSecurity configures the validation of WS-Security headers of SOAP requests.
+	WSSecurity WSSecurity `bson:"ws_security" json:"ws_security"`
+
+	// DPoP configures the validation of DPoP proofs for sender-constrained access tokens.
+	DPoP DPoP `bson:"dpop" json:"dpop"`
+	// FAPI enforces the Financial-grade API security profile.
+	FAPI FAPI `bson:"fapi" json:"fapi"`
+	// UpstreamRouting selects the upstream targets of requests from their headers, JWT claims or session metadata.
+	UpstreamRouting UpstreamRouting `bson:"upstream_routing" json:"upstream_routing"`
+	// TenantIsolation resolves the upstream host of requests from the organisation and session of the requester.
+	TenantIsolation TenantIsolation `bson:"tenant_isolation" json:"tenant_isolation"`
+	// ResourceIndicator enforces access tokens and sessions are issued for the API as a resource.
+	ResourceIndicator ResourceIndicator `bson:"resource_indicator" json:"resource_indicator"`
+	// BruteForceProtection temporarily bans clients which repeatedly fail to authenticate.
+	BruteForceProtection BruteForceProtection `bson:"brute_force_protection" json:"brute_force_protection"`
+	// Tokenization replaces sensitive JSON fields of request bodies with tokens.
+	Tokenization Tokenization `bson:"tokenization" json:"tokenization"`
+	// ResponseFieldFilters removes fields of JSON response bodies while streaming them to the client.
+	ResponseFieldFilters ResponseFieldFilters `bson:"response_field_filters" json:"response_field_filters"`
+	// ProtocolPassthrough configures the proxying of trailers and informational responses.
+	ProtocolPassthrough ProtocolPassthrough `bson:"protocol_passthrough" json:"protocol_passthrough"`
+	// HeaderPolicy configures the validation and normalization of request headers.
+	HeaderPolicy HeaderPolicy `bson:"header_policy" json:"header_policy"`
+	// Ownership is the team owning the API and how to reach it. It's added to the events
+	// and analytics of the API.
+	Ownership APIOwnership `bson:"ownership" json:"ownership"`
+	// Metering declares the cost units of requests, accumulated per key and exported as
+	// metering events for usage-based billing.
+	Metering Metering `bson:"metering" json:"metering"`
+	// Domains are the custom domains the HTTP API is served on in addition to `domain`, when
+	// custom domains are enabled.
+	Domains []APIDomain `bson:"domains" json:"domains"`
+	// RequestPolicies are expressions over the request, the session and the JWT claims, evaluated
+	// in order after authentication to allow, deny or transform requests without plugins. The
+	// expressions are gval expressions, which differ from CEL, see RequestPolicy.
+	RequestPolicies []RequestPolicy `bson:"request_policies" json:"request_policies"`
+	// ExternalAuthorization delegates the authorization of requests to an external service
+	// compatible with the Envoy `ext_authz` services, like OPA.
+	ExternalAuthorization ExternalAuthorization `bson:"external_authorization" json:"external_authorization"`
+	// ConsumerScopedMiddleware are the names of the middleware of the API which only run for the
+	// keys enabling them with their middleware flags, e.g. `ResponseTransformMiddleware`, to roll
+	// out middleware to some consumers first. Request middleware running before authentication
+	// has no key to check and never runs when consumer scoped.
+	ConsumerScopedMiddleware []string `bson:"consumer_scoped_middleware" json:"consumer_scoped_middleware"`
+	// OAuthRefreshTokens configures the reuse detection of the refresh tokens of the OAuth server.
+	OAuthRefreshTokens OAuthRefreshTokens `bson:"oauth_refresh_tokens" json:"oauth_refresh_tokens"`
+	// InboundDedup configures the deduplication of the requests received by the API.
+	InboundDedup InboundDedup `bson:"inbound_dedup" json:"inbound_dedup"`
+	// ResponseStatusMapping maps the upstream response statuses to different statuses for the clients.
+	ResponseStatusMapping ResponseStatusMapping `bson:"response_status_mapping" json:"response_status_mapping"`
+	// UpstreamErrorBudget switches the API into a degraded mode while its upstream fails to connect.
+	UpstreamErrorBudget UpstreamErrorBudget `bson:"upstream_error_budget" json:"upstream_error_budget"`
+	// RateLimiter selects the rate limiting algorithm of the API by name, overriding the `rate_limiter` of the Gateway.
+	RateLimiter string `bson:"rate_limiter" json:"rate_limiter"`
+	// RateLimitHeaders adds the RateLimit headers of the draft IETF RateLimit header fields to the responses.
+	RateLimitHeaders RateLimitHeaders `bson:"rate_limit_headers" json:"rate_limit_headers"`
+	// QuotaThresholds fires the QuotaThresholdReached event when the quota usage of a key reaches a threshold.
+	QuotaThresholds QuotaThresholds `bson:"quota_thresholds" json:"quota_thresholds"`
+	// Experiments buckets the consumers of the API into the variants of A/B experiments.
+	Experiments Experiments `bson:"experiments" json:"experiments"`
+	// DebugHeaders adds cache, rate limiter, upstream and timing diagnostics headers to the responses of allow-listed clients.
+	DebugHeaders DebugHeaders `bson:"debug_headers" json:"debug_headers"`
+}
+
+// DebugHeaders configures the diagnostics headers added to the responses of allow-listed
+// clients, for debugging in the field without access to the Gateway logs:
+// X-Tyk-Cache-Status, X-Tyk-RateLimit-Bucket, X-Tyk-Upstream-Target and
+// X-Tyk-Middleware-Timing. Other clients never get the headers.
+type DebugHeaders struct {
+	// Enabled enables the debug headers.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// AllowedIPs are the IPs or CIDRs of the client connections getting the headers. The
+	// X-Real-IP and X-Forwarded-For headers are ignored, they are set by the clients.
+	AllowedIPs []string `bson:"allowed_ips" json:"allowed_ips"`
+	// AllowedKeys are the keys getting the headers, by key ID or key hash.
+	AllowedKeys []string `bson:"allowed_keys" json:"allowed_keys"`
+}
+
+// QuotaThresholds configures the QuotaThresholdReached events of the API, warning the consumers
+// of the API before their key runs out of quota.
+type QuotaThresholds struct {
+	// Enabled enables the quota threshold events.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Thresholds are the percentages of the quota of the key, the event firing once per quota
+	// period when the usage of the key reaches each of them, e.g. [80, 100].
+	Thresholds []float64 `bson:"thresholds" json:"thresholds"`
+}
+
+// RateLimitHeaders configures the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// response headers, reporting the current quota of the key to the clients, or the window of its
+// rate limit when the request is rate limited or the quota is unlimited.
+type RateLimitHeaders struct {
+	// Enabled adds the RateLimit headers to the responses of the requests with a key.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Policy adds the RateLimit-Policy header, with the quota and the rate limit of the key and their windows.
+	Policy bool `bson:"policy" json:"policy"`
+}
+
+// UpstreamErrorBudget holds the budget of the upstream connect and TLS failures of an API. Past
+// the budget the API is degraded: the requests aren't proxied to the upstream, and are served
+// from the stale cached responses or with the fallback response instead, until the upstream
+// responds to a recovery probe again.
+type UpstreamErrorBudget struct {
+	// Enabled enables the upstream error budget.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Window is the number of seconds the failures are counted over. Defaults to 60.
+	Window int `bson:"window" json:"window"`
+	// MinRequests is the number of requests in the window from which the budget applies. Defaults to 10.
+	MinRequests int `bson:"min_requests" json:"min_requests"`
+	// FailureRatio is the ratio of the requests in the window which failed to connect from which
+	// the API is degraded, between 0 and 1. Defaults to 0.5.
+	FailureRatio float64 `bson:"failure_ratio" json:"failure_ratio"`
+	// ProbeInterval is the number of seconds between the recovery probes of a degraded API, HEAD
+	// requests sent to the upstream through the transport of the API. Defaults to 10.
+	ProbeInterval int `bson:"probe_interval" json:"probe_interval"`
+	// ServeStale serves the expired cached responses of the API while it is degraded. The cached
+	// responses are kept for StaleTTL seconds after they expire.
+	ServeStale bool `bson:"serve_stale" json:"serve_stale"`
+	// StaleTTL is the number of seconds the cached responses are kept after they expire, to be
+	// served while the API is degraded. Defaults to 86400.
+	StaleTTL int64 `bson:"stale_ttl" json:"stale_ttl"`
+	// FallbackCode is the status of the response of the requests to a degraded API which aren't
+	// served from the cache. Defaults to 503.
+	FallbackCode int `bson:"fallback_code" json:"fallback_code"`
+	// FallbackBody is the body of the fallback response. The Gateway error template is used when empty.
+	FallbackBody string `bson:"fallback_body" json:"fallback_body"`
+	// FallbackHeaders are the headers of the fallback response.
+	FallbackHeaders map[string]string `bson:"fallback_headers" json:"fallback_headers"`
+}
+
+// ResponseStatusMapping holds the rules mapping upstream response statuses to the statuses sent to
+// the clients, so the clients see consistent statuses across heterogeneous upstreams.
+type ResponseStatusMapping struct {
+	// Enabled enables response status mapping.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Rules are the status mapping rules. The first rule matching the response applies.
+	Rules []ResponseStatusRule `bson:"rules" json:"rules"`
+}
+
+// ResponseStatusRule maps the responses of an endpoint matching the upstream statuses and body
+// conditions to a status, e.g. an upstream 500 with `{"code": "NOT_FOUND"}` to a 404.
+type ResponseStatusRule struct {
+	// Path is the endpoint path, matched the same way as the extended paths.
+	// An empty path matches every request.
+	Path string `bson:"path" json:"path"`
+	// Method is the HTTP method the rule applies to. An empty method matches every method.
+	Method string `bson:"method" json:"method"`
+	// UpstreamStatuses are the upstream statuses the rule applies to. Empty matches every status.
+	UpstreamStatuses []int `bson:"upstream_statuses" json:"upstream_statuses"`
+	// BodyField is the dot separated path of a field of JSON response bodies, e.g. `error.code`,
+	// which must equal BodyValue for the rule to apply.
+	BodyField string `bson:"body_field" json:"body_field"`
+	BodyValue string `bson:"body_value" json:"body_value"`
+	// BodyPattern is a regular expression the response body must match for the rule to apply.
+	BodyPattern string `bson:"body_pattern" json:"body_pattern"`
+	// Status is the status sent to the clients.
+	Status int `bson:"status" json:"status"`
+}
+
+// InboundDedup configures the deduplication of the requests received by an API, for the webhook
+// providers delivering at least once. A request is a duplicate when its method, path, query,
+// consumer, body and hashed headers were already received within the TTL, and the first request
+// was handled successfully. The unset fields fall back to the `inbound_dedup` settings of the Gateway.
+type InboundDedup struct {
+	// Enabled deduplicates the requests of the API.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Disabled opts the API out of the deduplication enabled for all APIs in the Gateway settings.
+	Disabled bool `bson:"disabled" json:"disabled"`
+	// Headers are the request headers hashed with the method, path, query, consumer and body.
+	Headers []string `bson:"headers" json:"headers"`
+	// TTL is the number of seconds a request is remembered for.
+	TTL int64 `bson:"ttl" json:"ttl"`
+	// DuplicateStatusCode is the status code of the responses to the duplicates.
+	DuplicateStatusCode int `bson:"duplicate_status_code" json:"duplicate_status_code"`
+}
+
+// OAuthRefreshTokens configures the refresh tokens issued by the OAuth server of an API. The
+// refresh tokens are rotated: a refresh issues a new refresh token and revokes the presented one.
+type OAuthRefreshTokens struct {
+	// ReuseDetection revokes the tokens of a token family, the tokens issued for a grant and its
+	// refreshes, when a rotated refresh token is presented again, as it was likely leaked. The
+	// refresh tokens without a family, e.g. issued before the reuse detection was enabled, are
+	// rejected as reused.
+	ReuseDetection bool `bson:"reuse_detection" json:"reuse_detection"`
+	// ReuseInterval is the number of seconds a rotated refresh token can be presented again without
+	// revoking its family, for the clients retrying a refresh whose response was lost.
+	ReuseInterval int64 `bson:"reuse_interval" json:"reuse_interval"`
+}
+
+const (
+	// ExternalAuthorizationHTTP calls the HTTP service with the method, path and headers of the request.
+	ExternalAuthorizationHTTP = "http"
+	// ExternalAuthorizationGRPC calls the `envoy.service.auth.v3.Authorization/Check` method of the gRPC service.
+	ExternalAuthorizationGRPC = "grpc"
+)
+
+// ExternalAuthorization configures the external authorization service of an API, which is
+// called after authentication.
+//
+// HTTP services allow requests responding with 200 and deny them with any other status, returned
+// to the client with the headers and body of the response. gRPC services allow requests with an OK
+// status and can set and remove headers of the upstream request.
+type ExternalAuthorization struct {
+	// Enabled enables the external authorization of requests.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Protocol is `http` or `grpc`, `http` by default.
+	Protocol string `bson:"protocol" json:"protocol"`
+	// URL is the base URL of an HTTP service, the path of the request is appended to it, or the
+	// `host:port` address of a gRPC service.
+	URL string `bson:"url" json:"url"`
+	// TLS connects to the gRPC service with TLS.
+	TLS bool `bson:"tls" json:"tls"`
+	// Timeout of the calls to the service, in milliseconds. Defaults to 500.
+	Timeout int64 `bson:"timeout" json:"timeout"`
+	// ForwardHeaders are the request headers sent to the service, all of them when empty.
+	ForwardHeaders []string `bson:"forward_headers" json:"forward_headers"`
+	// MaxBodySize is the number of bytes of the request body sent to the service, the body isn't
+	// sent when 0.
+	MaxBodySize int64 `bson:"max_body_size" json:"max_body_size"`
+	// AllowBinary sends the body to gRPC services as bytes, in the `raw_body` field of the check
+	// requests. Bodies which aren't valid UTF-8 are always sent as bytes.
+	AllowBinary bool `bson:"allow_binary" json:"allow_binary"`
+	// AllowedUpstreamHeaders are the headers of the responses of HTTP services allowing requests
+	// which are added to the upstream request.
+	AllowedUpstreamHeaders []string `bson:"allowed_upstream_headers" json:"allowed_upstream_headers"`
+	// FailOpen allows the requests when the service can't be reached or times out, they are
+	// denied with 403 otherwise.
+	FailOpen bool `bson:"fail_open" json:"fail_open"`
+	// CacheTTL is the number of seconds the decisions are cached for, by method, path, query,
+	// forwarded headers and body. Decisions aren't cached when 0.
+	CacheTTL int64 `bson:"cache_ttl" json:"cache_ttl"`
+}
+
+const (
+	// RequestPolicyAllow skips the remaining policies of the API.
+	RequestPolicyAllow = "allow"
+	// RequestPolicyDeny rejects the request.
+	RequestPolicyDeny = "deny"
+	// RequestPolicyTransform sets or deletes request headers and carries on with the remaining policies.
+	RequestPolicyTransform = "transform"
+)
+
+// RequestPolicy is a condition on requests and the action taken for the requests it matches.
+//
+// Conditions are boolean expressions, like `request.header['x-region'] == session.meta.region`,
+// over the variables:
+//   - `request`: `method`, `path`, `host`, `remote_addr`, `header` and `query`, the header names are lower case,
+//   - `session`: `meta`, `alias`, `org_id` and `tags`, empty for keyless APIs,
+//   - `claims`: the claims of the JWT, when context variables are enabled.
+//
+// The functions `size`, `lower`, `upper`, `startsWith`, `endsWith`, `contains` and `matches` are
+// available.
+//
+// The expressions are evaluated with gval, not CEL. Their syntax is close to CEL for simple
+// conditions, but CEL expressions don't always behave the same:
+//   - missing header, query, meta or claim values are `nil` instead of errors, `nil` is false in conditions,
+//   - comparisons convert their operands, `'1' == 1` is true, and all numbers are floats,
+//   - `in` only looks up lists, `'a' in request.header` is an error,
+//   - the CEL macros, like `has` and `exists`, and the CEL types and conversions aren't supported.
+type RequestPolicy struct {
+	// Name identifies the policy in logs.
+	Name string `bson:"name" json:"name"`
+	// When is the condition of the policy.
+	When string `bson:"when" json:"when"`
+	// Action is `allow`, `deny` or `transform`.
+	Action string `bson:"action" json:"action"`
+	// Code is the status code requests are denied with, 403 by default.
+	Code int `bson:"code" json:"code,omitempty"`
+	// Message is the error message requests are denied with.
+	Message string `bson:"message" json:"message,omitempty"`
+	// SetHeaders are the request headers set by transforms, the values are expressions.
+	SetHeaders map[string]string `bson:"set_headers" json:"set_headers,omitempty"`
+	// DeleteHeaders are the request headers deleted by transforms.
+	DeleteHeaders []string `bson:"delete_headers" json:"delete_headers,omitempty"`
+}
+
+// APIDomain is a custom domain of an API.
+type APIDomain struct {
+	// Host is the hostname. A `*` label matches a single label, `*.api.example.com` matches
+	// `eu.api.example.com` but not `a.eu.api.example.com`, and a `**` label matches one or more
+	// labels, `**.api.example.com` matches both.
+	Host string `bson:"host" json:"host"`
+	// Certificates are the IDs of the certificates served for the domain, which are preferred
+	// to the certificates of the API for its hostnames.
+	Certificates []string `bson:"certificates" json:"certificates"`
+}
+
+// Metering holds the cost units of the requests to an API. The units of a request are the
+// sum of the static units, the units per kilobyte of response body and the units read
+// from a field of the JSON response body.
+type Metering struct {
+	// Enabled enables metering.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Units are the cost units of the requests which match no endpoint.
+	Units float64 `bson:"units" json:"units"`
+	// Endpoints are the cost units of endpoints. The first endpoint matching the request applies.
+	Endpoints []MeteredEndpoint `bson:"endpoints" json:"endpoints"`
+}
+
+// MeteredEndpoint holds the cost units of the requests to an endpoint.
+type MeteredEndpoint struct {
+	// Path is the endpoint path, matched the same way as the extended paths.
+	// An empty path matches every request.
+	Path string `bson:"path" json:"path"`
+	// Method is the HTTP method of the endpoint. An empty method matches every method.
+	Method string `bson:"method" json:"method"`
+	// Units are the static cost units of a request.
+	Units float64 `bson:"units" json:"units"`
+	// UnitsPerKB are the cost units of every kilobyte of response body.
+	UnitsPerKB float64 `bson:"units_per_kb" json:"units_per_kb"`
+	// UnitsField is the dot separated path of a number in JSON response bodies which is
+	// added to the cost units, e.g. `usage.tokens`.
+	UnitsField string `bson:"units_field" json:"units_field"`
+}
+
+// APIOwnership describes who owns an API and how to contact them, so that alerts about
+// the API reach the right team.
+type APIOwnership struct {
+	// Owner is the person or service accountable for the API.
+	Owner string `bson:"owner" json:"owner"`
+	// Team is the team maintaining the API.
+	Team string `bson:"team" json:"team"`
+	// Contacts are the channels the team is notified on.
+	Contacts []APIContact `bson:"contacts" json:"contacts"`
+}
+
+// APIContactType is the kind of channel of an API contact.
+type APIContactType string
+
+const (
+	// APIContactEmail is an email address.
+	APIContactEmail APIContactType = "email"
+	// APIContactWebhook is an URL receiving the webhooks of events.
+	APIContactWebhook APIContactType = "webhook"
+	// APIContactSlack is the URL of a Slack incoming webhook, posting to a channel.
+	APIContactSlack APIContactType = "slack"
+)
+
+// APIContact is a channel on which the owners of an API can be reached.
+type APIContact struct {
+	// Type is the kind of channel.
+	Type APIContactType `bson:"type" json:"type"`
+	// Address is the email address or the URL of the channel.
+	Address string `bson:"address" json:"address"`
+}
+
+// Empty returns true if no owner, team or contact is set.
+func (o APIOwnership) Empty() bool {
+	return o.Owner == "" && o.Team == "" && len(o.Contacts) == 0
+}
+
+// WebhookURLs returns the URLs of the webhook and Slack contacts, to which events are routed.
+func (o APIOwnership) WebhookURLs() []string {
+	var urls []string
+	for _, contact := range o.Contacts {
+		if contact.Type == APIContactWebhook || contact.Type == APIContactSlack {
+			urls = append(urls, contact.Address)
+		}
+	}
+
+	return urls
+}
+
+// HeaderPolicy configures how request headers are validated and normalized before they
+// are processed, so that the gateway and the upstream read the same headers.
+type HeaderPolicy struct {
+	// Enabled enables the header policy.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// CanonicalizeKeys folds the header keys which aren't in canonical form, like the ones
+	// preserved with ignore_canonical_mime_header_key, into their canonical form.
+	CanonicalizeKeys bool `bson:"canonicalize_keys" json:"canonicalize_keys"`
+	// Duplicates is the handling of headers sent more than once. An empty policy leaves them.
+	Duplicates HeaderDuplicatesPolicy `bson:"duplicates" json:"duplicates"`
+	// MaxCount is the maximum number of request headers, counting every value.
+	MaxCount int `bson:"max_count" json:"max_count"`
+	// MaxSize is the maximum size of a request header, name and value, in bytes.
+	MaxSize int `bson:"max_size" json:"max_size"`
+	// RejectUnderscores rejects the requests with header names containing underscores,
+	// which some servers treat as dashes.
+	RejectUnderscores bool `bson:"reject_underscores" json:"reject_underscores"`
+}
+
+// HeaderDuplicatesPolicy is the handling of request headers sent more than once.
+type HeaderDuplicatesPolicy string
+
+const (
+	// HeaderDuplicatesReject rejects the requests with duplicate headers.
+	HeaderDuplicatesReject HeaderDuplicatesPolicy = "reject"
+	// HeaderDuplicatesMerge merges the values of duplicate headers into one, separated by commas.
+	HeaderDuplicatesMerge HeaderDuplicatesPolicy = "merge"
+	// HeaderDuplicatesFirstWins keeps the first value of duplicate headers.
+	HeaderDuplicatesFirstWins HeaderDuplicatesPolicy = "first_wins"
+)
+
+// ProtocolPassthrough configures the proxying of the HTTP messages sent around requests
+// and responses, which gRPC and browsers rely on.
+type ProtocolPassthrough struct {
+	// DisableTrailers stops the proxying of request and response trailers, and of the
+	// `TE: trailers` request header.
+	DisableTrailers bool `bson:"disable_trailers" json:"disable_trailers"`
+	// InterimResponses forwards the 1xx informational responses of the upstream,
+	// such as 100 Continue and 103 Early Hints, to the client.
+	InterimResponses bool `bson:"interim_responses" json:"interim_responses"`
+}
+
+// ResponseFieldFilters holds the field filters of JSON responses. Responses are filtered
+// while they are streamed, so large responses are never loaded in memory.
+type ResponseFieldFilters struct {
+	// Enabled enables response field filtering.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// MaxBufferedNodeSize is the size limit, in bytes, of the object keys buffered to match
+	// paths. Responses with larger keys are cut. Defaults to 65536.
+	MaxBufferedNodeSize int `bson:"max_buffered_node_size" json:"max_buffered_node_size"`
+	// Filters are the filters of the endpoints. The first filter matching the request applies.
+	// The gzip and deflate responses are filtered; the responses in other content encodings are
+	// replaced by a 502 error.
+	Filters []ResponseFieldFilter `bson:"filters" json:"filters"`
+}
+
+// ResponseFieldFilter filters the JSON responses of an endpoint. Paths are dot separated,
+// with `[]` for every item of an array, e.g. `orders.[].customer.email`.
+type ResponseFieldFilter struct {
+	// Path is the endpoint path, matched the same way as the extended paths.
+	// An empty path matches every request.
+	Path string `bson:"path" json:"path"`
+	// Method is the HTTP method the filter applies to. An empty method matches every method.
+	Method string `bson:"method" json:"method"`
+	// Include lists the only fields kept in responses. All fields are kept when empty.
+	Include []string `bson:"include" json:"include"`
+	// Exclude lists the fields removed from responses.
+	Exclude []string `bson:"exclude" json:"exclude"`
+}
+
+// TokenizationMethod is the way a field is tokenized.
+type TokenizationMethod string
+
+const (
+	// TokenizationMethodFPE encrypts the field with format-preserving encryption (FF1).
+	TokenizationMethodFPE TokenizationMethod = "fpe"
+	// TokenizationMethodVault exchanges the field for a token with a tokenization vault.
+	TokenizationMethodVault TokenizationMethod = "vault"
+)
+
+// TokenizationAlphabet is the set of characters format-preserving encryption operates on.
+// Other characters, like separators, are kept as they are.
+type TokenizationAlphabet string
+
+const (
+	// TokenizationAlphabetDigits encrypts the digits of a field, the default.
+	TokenizationAlphabetDigits TokenizationAlphabet = "digits"
+	// TokenizationAlphabetAlphanumeric encrypts the digits and ASCII letters of a field.
+	TokenizationAlphabetAlphanumeric TokenizationAlphabet = "alphanumeric"
+)
+
+// Tokenization holds the fields of JSON request bodies which are tokenized before the body
+// reaches the upstream and analytics, and optionally detokenized in responses.
+type Tokenization struct {
+	// Enabled enables tokenization.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Key is the hex encoded AES key of format-preserving encryption, of 16, 24 or 32 bytes.
+	// It can reference a secret, e.g. `secrets://tokenization-key`.
+	Key string `bson:"key" json:"key"`
+	// Vault is the tokenization vault fields using the vault method are exchanged with.
+	Vault TokenizationVault `bson:"vault" json:"vault"`
+	// Fields are the tokenized fields.
+	Fields []TokenizationField `bson:"fields" json:"fields"`
+}
+
+// TokenizationVault is a tokenization service. The Gateway posts `{"action": "tokenize", "values": [...]}`,
+// or the `detokenize` action, and expects `{"values": [...]}` in the same order.
+type TokenizationVault struct {
+	// URL is the endpoint of the vault.
+	URL string `bson:"url" json:"url"`
+	// Headers are added to vault requests, e.g. for authentication.
+	Headers map[string]string `bson:"headers" json:"headers"`
+	// Timeout of vault requests, in seconds. Defaults to 5.
+	Timeout int64 `bson:"timeout" json:"timeout"`
+}
+
+// TokenizationField is a tokenized field of JSON bodies.
+type TokenizationField struct {
+	// Path is the dot separated path of the field, with `[]` for every item of an array, e.g. `cards.[].pan`.
+	// Only string values are tokenized.
+	Path string `bson:"path" json:"path"`
+	// Method is the tokenization method, `fpe` or `vault`.
+	Method TokenizationMethod `bson:"method" json:"method"`
+	// Alphabet is the alphabet of format-preserving encryption, `digits` or `alphanumeric`.
+	Alphabet TokenizationAlphabet `bson:"alphabet" json:"alphabet"`
+	// DetokenizeResponse restores the original value of the field in response bodies.
+	DetokenizeResponse bool `bson:"detokenize_response" json:"detokenize_response"`
+}
+
+// BruteForceProtection holds the limits of authentication failures, counted per source IP
+// and per presented credential. When a client reaches MaxAttempts within Window, it is banned
+// for BanDuration, doubled on every further lockout up to MaxBanDuration.
+type BruteForceProtection struct {
+	// Enabled enables counting authentication failures and banning clients.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// MaxAttempts is the number of failures which triggers a lockout. Defaults to 5.
+	MaxAttempts int64 `bson:"max_attempts" json:"max_attempts"`
+	// Window is the period, in seconds, failures are counted over. Defaults to 60.
+	Window int64 `bson:"window" json:"window"`
+	// BanDuration is the duration, in seconds, of the first lockout. Defaults to 60.
+	BanDuration int64 `bson:"ban_duration" json:"ban_duration"`
+	// MaxBanDuration caps the duration, in seconds, of lockouts. Defaults to 3600.
+	// Lockouts are forgotten after twice this duration without a new one.
+	MaxBanDuration int64 `bson:"max_ban_duration" json:"max_ban_duration"`
+	// DisableIPTracking disables counting failures per source IP, for APIs behind a NAT
+	// shared by many clients.
+	DisableIPTracking bool `bson:"disable_ip_tracking" json:"disable_ip_tracking"`
+}
+
+// ResourceIndicator holds the resource indicator (RFC 8707) of an API. JWT and external
+// OAuth access tokens must include it in their `aud` claim, and sessions must hold the
+// required scopes in the access rights of the API.
+type ResourceIndicator struct {
+	// Enabled enables resource indicator enforcement.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Resource is the resource indicator of the API, e.g. `https://api.example.com/orders`.
+	Resource string `bson:"resource" json:"resource"`
+	// RequiredScopes are the scopes sessions must be granted for the API.
+	RequiredScopes []string `bson:"required_scopes" json:"required_scopes"`
+}
+
+// TenantIsolation holds the upstream URL template of APIs proxying to backends which shard
+// tenants by hostname. The template is resolved per request and the resulting host must
+// end with one of the allowed domain suffixes.
+type TenantIsolation struct {
+	// Enabled enables the upstream URL template.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// UpstreamTemplate is a Go template of the upstream URL, e.g.
+	// `http://{{.OrgID}}.internal.svc`. The variables are `OrgID`, `APIID`, `Alias` and
+	// `Meta`, the session metadata.
+	UpstreamTemplate string `bson:"upstream_template" json:"upstream_template"`
+	// AllowedHostSuffixes are the domain suffixes resolved upstream hosts must end with.
+	AllowedHostSuffixes []string `bson:"allowed_host_suffixes" json:"allowed_host_suffixes"`
+}
+
+// Upstream routing rule sources.
+const (
+	UpstreamRoutingSourceHeader = "header"
+	UpstreamRoutingSourceClaim  = "claim"
+	UpstreamRoutingSourceMeta   = "meta"
+	// UpstreamRoutingSourceIP matches the IP of the client connection against the CIDRs or IPs
+	// of the values. The X-Real-IP and X-Forwarded-For headers are ignored.
+	UpstreamRoutingSourceIP = "ip"
+	// UpstreamRoutingSourceListener matches the local address the request was received on
+	// against the CIDRs, IPs or `:port` values.
+	UpstreamRoutingSourceListener = "listener"
+)
+
+// UpstreamRouting holds the rules routing requests to dedicated upstream targets, e.g.
+// enterprise tenants to their own cluster. Rules are evaluated in order after
+// authentication, requests matching none go to the upstream of the API.
+type UpstreamRouting struct {
+	// Enabled enables upstream routing rules.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Rules are the routing rules, the first matching rule applies.
+	Rules []UpstreamRoutingRule `bson:"rules" json:"rules"`
+}
+
+// UpstreamRoutingRule routes requests with a matching header, JWT claim or session
+// metadata value, or from a matching client network or listener, to its targets.
+type UpstreamRoutingRule struct {
+	// Name identifies the rule, requests it routes are tagged `upstream-route-<name>` in analytics.
+	Name string `bson:"name" json:"name"`
+	// Source is where the value is read from: `header`, `claim`, `meta`, `ip` or `listener`.
+	// Claims are read from the context variables, which must be enabled.
+	Source string `bson:"source" json:"source"`
+	// Key is the name of the header, claim or metadata key. It's unused by the `ip` and
+	// `listener` sources.
+	Key string `bson:"key" json:"key"`
+	// Values are the values matching the rule. Any non empty value matches when empty. The
+	// values of the `ip` and `listener` sources are CIDRs or IPs, and ports like `:8443`
+	// for listeners, e.g. the internal networks served by a dedicated upstream.
+	Values []string `bson:"values" json:"values"`
+	// Targets are the upstream URLs of matching requests, load balanced when many.
+	Targets []string `bson:"targets" json:"targets"`
+}
+
+const (
+	// ExperimentBucketKey buckets the consumers by their key, the default.
+	ExperimentBucketKey = "key"
+	// ExperimentBucketClaim buckets the consumers by a JWT claim, read from the context variables.
+	ExperimentBucketClaim  = "claim"
+	ExperimentBucketHeader = "header"
+	ExperimentBucketMeta   = "meta"
+)
+
+// Experiments holds the A/B experiments of the API. Consumers are assigned a variant of each
+// experiment from the hash of their key or of a claim, header or metadata value, so that they
+// get the same variant on every request and Gateway. Requests without a value aren't assigned.
+type Experiments struct {
+	// Enabled enables the experiments.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Tests are the experiments. The first one assigning a variant with a target routes the request.
+	Tests []Experiment `bson:"tests" json:"tests"`
+}
+
+// Experiment assigns the consumers to its variants in proportion to their weights. Changing
+// the variants or their weights reassigns a share of the consumers.
+type Experiment struct {
+	// Name identifies the experiment, requests are tagged `experiment-<name>-<variant>` in analytics.
+	Name string `bson:"name" json:"name"`
+	// BucketBy is what consumers are bucketed by: `key`, `claim`, `header` or `meta`.
+	BucketBy string `bson:"bucket_by" json:"bucket_by"`
+	// Key is the name of the claim, header or metadata key. It's unused by the `key` bucketing.
+	Key string `bson:"key" json:"key"`
+	// Variants are the variants of the experiment.
+	Variants []ExperimentVariant `bson:"variants" json:"variants"`
+}
+
+// ExperimentVariant is a variant of an experiment, routed to its own upstream or with its own
+// request headers.
+type ExperimentVariant struct {
+	// Name identifies the variant.
+	Name string `bson:"name" json:"name"`
+	// Weight is the share of the consumers assigned the variant, relative to the other variants.
+	Weight int `bson:"weight" json:"weight"`
+	// Target is the upstream URL of the requests of the variant. The upstream of the API is
+	// used when empty.
+	Target string `bson:"target" json:"target"`
+	// Headers are added to the requests of the variant sent upstream.
+	Headers map[string]string `bson:"headers" json:"headers"`
+}
+
+// FAPI holds the configuration of the Financial-grade API (FAPI) security profile. When
+// enabled, access tokens must be sender constrained with mutual TLS or DPoP and signed
+// with PS256 or ES256, can't be sent in the query string, and API definitions which can't
+// meet those requirements fail validation.
+type FAPI struct {
+	// Enabled enforces the FAPI profile.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// RequiredHeaders are request headers clients must send, such as `x-fapi-auth-date`
+	// or `x-fapi-customer-ip-address`.
+	RequiredHeaders []string `bson:"required_headers" json:"required_headers"`
+}
+
+// DPoP holds the configuration of DPoP (RFC 9449) proof-of-possession validation. Access
+// tokens bound to a key with a `cnf.jkt` claim are only accepted with the DPoP scheme and
+// a proof signed with that key.
+type DPoP struct {
+	// Enabled enables DPoP proof validation for JWT and external OAuth access tokens.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Required rejects access tokens which are not bound to a key.
+	Required bool `bson:"required" json:"required"`
+	// RequireNonce requires proofs to carry a nonce issued by the gateway in the
+	// `DPoP-Nonce` response header.
+	RequireNonce bool `bson:"require_nonce" json:"require_nonce"`
+	// ProofLifetime is the time in seconds a proof is accepted for after being issued, and
+	// the lifetime of nonces. Defaults to 300.
+	ProofLifetime int64 `bson:"proof_lifetime" json:"proof_lifetime"`
+	// ThumbprintHeader is the request header carrying the thumbprint of the validated
+	// key to the upstream. Defaults to `X-Tyk-DPoP-Thumbprint`.
+	ThumbprintHeader string `bson:"thumbprint_header" json:"thumbprint_header"`
+}
+
+// WSSecurity holds the WS-Security validation configuration of SOAP APIs. Requests are
+// rejected unless their `wsse:Security` header holds the required tokens.
+type WSSecurity struct {
+	// Enabled enables WS-Security validation.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// RequireUsernameToken requires a `UsernameToken` matching one of the users. Both
+	// plain text and digest passwords are accepted, digest nonces can't be replayed.
+	RequireUsernameToken bool `bson:"require_username_token" json:"require_username_token"`
+	// Users holds the passwords of the accepted usernames.
+	Users map[string]string `bson:"users" json:"users"`
+	// RequireTimestamp requires a `Timestamp` which has not expired.
+	RequireTimestamp bool `bson:"require_timestamp" json:"require_timestamp"`
+	// ClockSkew is the tolerated difference in seconds between the clocks of the client
+	// and the gateway when checking timestamps. Defaults to 300.
+	ClockSkew int64 `bson:"clock_skew" json:"clock_skew"`
+	// RequireSignature requires an XML signature covering the SOAP body, made with the key
+	// of one of the signature certificates.
+	RequireSignature bool `bson:"require_signature" json:"require_signature"`
+	// SignatureCertificates are the IDs of the certificates signatures are verified with.
+	SignatureCertificates []string `bson:"signature_certificates" json:"signature_certificates"`
+}
+
+// FaultInjection holds the fault injection configuration of an API. It is meant for chaos
+// experiments in staging environments and can be toggled at runtime through the Gateway API.
+type FaultInjection struct {
+	// Enabled enables fault injection.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Faults is the list of faults to inject. The first fault matching the request applies.
+	Faults []Fault `bson:"faults" json:"faults"`
+}
+
+// Fault is a fault injected into a share of the requests to an endpoint.
+type Fault struct {
+	// Path is the endpoint path, matched the same way as the extended paths.
+	// An empty path matches every request.
+	Path string `bson:"path" json:"path"`
+	// Method is the HTTP method the fault applies to. An empty method matches every method.
+	Method string `bson:"method" json:"method"`
+	// Percentage is the share of matching requests the fault is injected into, from 0 to 100.
+	Percentage float64 `bson:"percentage" json:"percentage"`
+	// Delay is the latency in milliseconds added before the request is proxied.
+	Delay int64 `bson:"delay" json:"delay"`
+	// StatusCode, when set, answers the request with this status code instead of proxying it.
+	StatusCode int `bson:"status_code" json:"status_code"`
+	// ResetConnection closes the client connection without sending a response.
+	ResetConnection bool `bson:"reset_connection" json:"reset_connection"`
+}
+
+// SlowRequests holds the slow request detection configuration. Requests taking longer than
+// the threshold are tagged `slow-request` in analytics, logged with a timing breakdown and counted.
+type SlowRequests struct {
+	// Enabled enables slow request detection.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Threshold is the duration in milliseconds after which a request is considered slow.
+	Threshold int64 `bson:"threshold" json:"threshold"`
+}
+
+// AllowedMethods holds the HTTP method allow lists of an API. Requests using a method
+// which isn't allowed are rejected with `405 Method Not Allowed`. The TRACE, TRACK and
+// CONNECT methods are rejected unless an allow list explicitly contains them.
+type AllowedMethods struct {
+	// Methods is the list of methods accepted by the API. When empty, all methods are
+	// accepted except TRACE, TRACK and CONNECT.
+	Methods []string `bson:"methods" json:"methods"`
+	// Endpoints holds method allow lists for specific paths, taking precedence over Methods.
+	Endpoints []EndpointMethods `bson:"endpoints" json:"endpoints"`
+}
+
+// EndpointMethods is the list of methods accepted by an endpoint.
+type EndpointMethods struct {
+	// Path is the endpoint path, matched the same way as the extended paths.
+	Path string `bson:"path" json:"path"`
+	// Methods is the list of methods accepted by the endpoint.
+	Methods []string `bson:"methods" json:"methods"`
+}
+
+// InternalToken holds the configuration of the short-lived JWT the Gateway issues for the upstream
+// in exchange for the validated client credential, signed with the Gateway signing keys.
+type InternalToken struct {
+	// Enabled enables issuing internal tokens.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Header is the name of the header the token is sent in.
+	// Defaults to `Authorization`, in which case the `Bearer` scheme is used.
+	Header string `bson:"header" json:"header"`
+	// Issuer is the value of the `iss` claim.
+	Issuer string `bson:"issuer" json:"issuer"`
+	// Audience is the value of the `aud` claim.
+	Audience string `bson:"audience" json:"audience"`
+	// ExpiresIn is the lifetime of the token in seconds. Defaults to 60.
+	ExpiresIn int64 `bson:"expires_in" json:"expires_in"`
+	// MetadataClaims maps session metadata keys to the names of the claims they are added as.
+	MetadataClaims map[string]string `bson:"metadata_claims" json:"metadata_claims"`
 }
 
 // UpstreamAuth holds the configurations related to upstream API authentication.
@@ -972,10 +1782,36 @@ type ProxyConfig struct {
 		SSLMinVersion           uint16   `bson:"ssl_min_version" json:"ssl_min_version"`
 		SSLMaxVersion           uint16   `bson:"ssl_max_version" json:"ssl_max_version"`
 		SSLForceCommonNameCheck bool     `json:"ssl_force_common_name_check"`
-		ProxyURL                string   `bson:"proxy_url" json:"proxy_url"`
+		// SSLCACertificates is a list of certificate IDs used as the only trusted CAs for the upstream of this API.
+		SSLCACertificates []string `bson:"ssl_ca_certificates" json:"ssl_ca_certificates,omitempty"`
+		ProxyURL          string   `bson:"proxy_url" json:"proxy_url"`
+		// HTTP2 configures HTTP/2 to the upstream.
+		HTTP2 UpstreamHTTP2 `bson:"http2" json:"http2"`
 	} `bson:"transport" json:"transport"`
 }
 
+// UpstreamHTTP2 configures HTTP/2 to the upstream of an API, and the health checks of its
+// connections.
+type UpstreamHTTP2 struct {
+	// Enabled negotiates HTTP/2 with ALPN with TLS upstreams, as `proxy_enable_http2` does for every API.
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// PriorKnowledge speaks HTTP/2 without negotiation over cleartext (h2c) to http upstreams, as
+	// `h2c://` target URLs do.
+	PriorKnowledge bool `bson:"prior_knowledge" json:"prior_knowledge"`
+	// PingInterval is the duration, in seconds, a connection can be idle before a ping checks it's
+	// alive. Connections aren't checked when 0.
+	PingInterval float64 `bson:"ping_interval" json:"ping_interval"`
+	// PingTimeout is the duration, in seconds, after which connections not answering pings are
+	// closed. Defaults to 15 seconds.
+	PingTimeout float64 `bson:"ping_timeout" json:"ping_timeout"`
+	// MaxConcurrentStreams caps the number of concurrent requests to the upstream, further requests
+	// wait for a stream. Unlimited when 0.
+	MaxConcurrentStreams int `bson:"max_concurrent_streams" json:"max_concurrent_streams"`
+	// StrictMaxConcurrentStreams queues requests above the maximum concurrent streams advertised by
+	// the upstream instead of opening new connections.
+	StrictMaxConcurrentStreams bool `bson:"strict_max_concurrent_streams" json:"strict_max_concurrent_streams"`
+}
+
 type CORSConfig struct {
 	Enable             bool     `bson:"enable" json:"enable"`
 	AllowedOrigins     []string `bson:"allowed_origins" json:"allowed_origins"`
@@ -1635,6 +2471,9 @@ type WebHookHandlerConf struct {
 	HeaderList map[string]string `bson:"header_map" json:"header_map"`
 	// The cool-down for the event so it does not trigger again (in seconds).
 	EventTimeout int64 `bson:"event_timeout" json:"event_timeout"`
+	// RouteToOwner sends the webhook to the webhook and Slack contacts of the API owners,
+	// instead of the target path, when the API has any.
+	RouteToOwner bool `bson:"route_to_owner,omitempty" json:"route_to_owner,omitempty"`
 }
 
 // Scan scans WebHookHandlerConf from `any` in.
@@ -22,6 +22,8 @@ type HealthCheckResponse struct {
 	Output      string                     `json:"output,omitempty"`
 	Description string                     `json:"description,omitempty"`
 	Details     map[string]HealthCheckItem `json:"details,omitempty"`
+	// Fingerprint identifies the loaded APIs, policies and configuration of the Gateway.
+	Fingerprint string `json:"fingerprint,omitempty"`
 }
 
 type HealthCheckItem struct {
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+)
+
+// exampleResponse is a recorded or documented response of an endpoint, used as mock.
+type exampleResponse struct {
+	Code    int
+	Headers map[string]string
+	Body    string
+}
+
+type exampleEndpoint struct {
+	path    string
+	methods map[string]exampleResponse
+	// order keeps the methods in the order they were found, for a stable output.
+	order []string
+}
+
+// exampleEndpoints collects the endpoints of sources made of request/response examples,
+// such as Postman collections and HAR captures. The first example of a method wins.
+type exampleEndpoints struct {
+	endpoints []*exampleEndpoint
+	byPath    map[string]*exampleEndpoint
+}
+
+func (e *exampleEndpoints) add(path, method string, response exampleResponse) {
+	if e.byPath == nil {
+		e.byPath = make(map[string]*exampleEndpoint)
+	}
+
+	if path == "" {
+		path = "/"
+	}
+	method = strings.ToUpper(method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	endpoint, ok := e.byPath[path]
+	if !ok {
+		endpoint = &exampleEndpoint{path: path, methods: make(map[string]exampleResponse)}
+		e.byPath[path] = endpoint
+		e.endpoints = append(e.endpoints, endpoint)
+	}
+
+	if _, ok := endpoint.methods[method]; ok {
+		return
+	}
+
+	if response.Code == 0 {
+		response.Code = http.StatusOK
+	}
+
+	endpoint.methods[method] = response
+	endpoint.order = append(endpoint.order, method)
+}
+
+func (e *exampleEndpoints) toAPIVersion(name string, asMock bool) apidef.VersionInfo {
+	versionInfo := apidef.VersionInfo{
+		Name:             name,
+		UseExtendedPaths: true,
+	}
+
+	for _, endpoint := range e.endpoints {
+		whitelistMeta := apidef.EndPointMeta{
+			Path:          endpoint.path,
+			MethodActions: make(map[string]apidef.EndpointMethodMeta, len(endpoint.methods)),
+		}
+
+		for _, method := range endpoint.order {
+			response := endpoint.methods[method]
+
+			methodMeta := apidef.EndpointMethodMeta{
+				Action:  apidef.NoAction,
+				Code:    response.Code,
+				Headers: response.Headers,
+				Data:    response.Body,
+			}
+			if asMock {
+				methodMeta.Action = apidef.Reply
+			}
+			whitelistMeta.MethodActions[method] = methodMeta
+
+			versionInfo.ExtendedPaths.TrackEndpoints = append(versionInfo.ExtendedPaths.TrackEndpoints, apidef.TrackEndpointMeta{
+				Path:   endpoint.path,
+				Method: method,
+			})
+		}
+
+		versionInfo.ExtendedPaths.WhiteList = append(versionInfo.ExtendedPaths.WhiteList, whitelistMeta)
+	}
+
+	return versionInfo
+}
+
+// newDraftAPIDefinition creates the keyless API definition the example based importers
+// insert their version into.
+func newDraftAPIDefinition(name, orgID, upstreamURL string) apidef.APIDefinition {
+	ad := apidef.APIDefinition{
+		Name:             name,
+		Active:           true,
+		UseKeylessAccess: true,
+		APIID:            uuid.NewHex(),
+		OrgID:            orgID,
+	}
+	ad.VersionDefinition.Key = "version"
+	ad.VersionDefinition.Location = "header"
+	ad.VersionData.Versions = make(map[string]apidef.VersionInfo)
+	ad.Proxy.ListenPath = "/" + ad.APIID + "/"
+	ad.Proxy.StripListenPath = true
+	ad.Proxy.TargetURL = upstreamURL
+
+	return ad
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const HARSource APIImporterSource = "har"
+
+// harSkippedHeaders are response headers describing the captured transfer rather than
+// the response, which are not replayed by mocks.
+var harSkippedHeaders = map[string]bool{
+	"content-length":    true,
+	"content-encoding":  true,
+	"transfer-encoding": true,
+	"connection":        true,
+	"date":              true,
+	"set-cookie":        true,
+}
+
+type HARHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HAREntry struct {
+	Request struct {
+		Method  string      `json:"method"`
+		URL     string      `json:"url"`
+		Headers []HARHeader `json:"headers"`
+	} `json:"request"`
+	Response struct {
+		Status  int         `json:"status"`
+		Headers []HARHeader `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// HARCapture is an HTTP Archive (HAR 1.2), as exported by browsers and proxies.
+type HARCapture struct {
+	Log struct {
+		Version string `json:"version"`
+		Pages   []struct {
+			Title string `json:"title"`
+		} `json:"pages"`
+		Entries []HAREntry `json:"entries"`
+	} `json:"log"`
+}
+
+func (h *HARCapture) LoadFrom(r io.Reader) error {
+	return json.NewDecoder(r).Decode(&h)
+}
+
+func (h *HARCapture) ConvertIntoApiVersion(asMock bool) (apidef.VersionInfo, error) {
+	if len(h.Log.Entries) == 0 {
+		return apidef.VersionInfo{}, errors.New("There are no entries in this HAR capture, are you sure it is correctly formatted?")
+	}
+
+	endpoints := exampleEndpoints{}
+	for _, entry := range h.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			log.WithError(err).Warningf("Skipping HAR entry with invalid URL %q", entry.Request.URL)
+			continue
+		}
+
+		response := exampleResponse{
+			Code: entry.Response.Status,
+			Body: entry.Response.Content.Text,
+		}
+
+		if entry.Response.Content.Encoding == "base64" {
+			body, err := base64.StdEncoding.DecodeString(response.Body)
+			if err != nil {
+				log.WithError(err).Warningf("Couldn't decode the response body of %s %s", entry.Request.Method, u.Path)
+			} else {
+				response.Body = string(body)
+			}
+		}
+
+		for _, header := range entry.Response.Headers {
+			if harSkippedHeaders[strings.ToLower(header.Name)] || strings.HasPrefix(header.Name, ":") {
+				continue
+			}
+			if response.Headers == nil {
+				response.Headers = make(map[string]string)
+			}
+			response.Headers[header.Name] = header.Value
+		}
+
+		endpoints.add(u.Path, entry.Request.Method, response)
+	}
+
+	if len(endpoints.endpoints) == 0 {
+		return apidef.VersionInfo{}, errors.New("no valid request found in the HAR capture")
+	}
+
+	return endpoints.toAPIVersion("Default", asMock), nil
+}
+
+// upstream returns the origin of the first captured request.
+func (h *HARCapture) upstream() string {
+	for _, entry := range h.Log.Entries {
+		if u, err := url.Parse(entry.Request.URL); err == nil && u.Host != "" {
+			return u.Scheme + "://" + u.Host
+		}
+	}
+
+	return ""
+}
+
+func (h *HARCapture) InsertIntoAPIDefinitionAsVersion(version apidef.VersionInfo, def *apidef.APIDefinition, versionName string) error {
+	def.VersionData.NotVersioned = false
+	def.VersionData.Versions[versionName] = version
+	return nil
+}
+
+// ToAPIDefinition creates an API from the capture. Without an upstream URL, the origin
+// of the captured requests is used.
+func (h *HARCapture) ToAPIDefinition(orgID, upstreamURL string, asMock bool) (*apidef.APIDefinition, error) {
+	if upstreamURL == "" {
+		upstreamURL = h.upstream()
+	}
+
+	name := upstreamURL
+	if len(h.Log.Pages) > 0 && h.Log.Pages[0].Title != "" {
+		name = h.Log.Pages[0].Title
+	}
+
+	ad := newDraftAPIDefinition(name, orgID, upstreamURL)
+
+	versionData, err := h.ConvertIntoApiVersion(asMock)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.InsertIntoAPIDefinitionAsVersion(versionData, &ad, versionData.Name)
+	return &ad, err
+}
@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestToAPIDefinition_HAR(t *testing.T) {
+	imp, err := GetImporterForSource(HARSource)
+	require.NoError(t, err)
+
+	require.NoError(t, imp.LoadFrom(bytes.NewBufferString(harCaptureJSON)))
+
+	def, err := imp.ToAPIDefinition("testOrg", "", false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Pet store", def.Name)
+	assert.Equal(t, "https://api.example.com", def.Proxy.TargetURL)
+
+	v, ok := def.VersionData.Versions["Default"]
+	require.True(t, ok)
+
+	require.Len(t, v.ExtendedPaths.WhiteList, 2)
+	assert.Len(t, v.ExtendedPaths.TrackEndpoints, 2)
+
+	assert.Equal(t, "/pets", v.ExtendedPaths.WhiteList[0].Path)
+	assert.Equal(t, apidef.EndpointMethodMeta{
+		Action:  apidef.NoAction,
+		Code:    200,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Data:    `[{"id":1}]`,
+	}, v.ExtendedPaths.WhiteList[0].MethodActions["GET"])
+
+	assert.Equal(t, "/pets/1", v.ExtendedPaths.WhiteList[1].Path)
+	assert.Equal(t, "{\"id\":1}", v.ExtendedPaths.WhiteList[1].MethodActions["GET"].Data)
+
+	t.Run("upstream target is kept", func(t *testing.T) {
+		def, err := imp.ToAPIDefinition("testOrg", "http://test.com", true)
+		require.NoError(t, err)
+		assert.Equal(t, "http://test.com", def.Proxy.TargetURL)
+		assert.Equal(t, apidef.Reply, def.VersionData.Versions["Default"].ExtendedPaths.WhiteList[0].MethodActions["GET"].Action)
+	})
+}
+
+var harCaptureJSON = `{
+  "log": {
+    "version": "1.2",
+    "pages": [{"title": "Pet store"}],
+    "entries": [
+      {
+        "request": {"method": "GET", "url": "https://api.example.com/pets?limit=1", "headers": []},
+        "response": {
+          "status": 200,
+          "headers": [
+            {"name": "Content-Type", "value": "application/json"},
+            {"name": "Content-Length", "value": "10"},
+            {"name": "Date", "value": "Mon, 12 Oct 2026 10:00:00 GMT"}
+          ],
+          "content": {"mimeType": "application/json", "text": "[{\"id\":1}]"}
+        }
+      },
+      {
+        "request": {"method": "GET", "url": "https://api.example.com/pets?limit=2", "headers": []},
+        "response": {"status": 200, "headers": [], "content": {"text": "[]"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://api.example.com/pets/1", "headers": []},
+        "response": {
+          "status": 200,
+          "headers": [],
+          "content": {"mimeType": "application/json", "text": "eyJpZCI6MX0=", "encoding": "base64"}
+        }
+      }
+    ]
+  }
+}`
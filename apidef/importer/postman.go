@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+const PostmanSource APIImporterSource = "postman"
+
+// postmanVariable matches `{{variable}}` references in Postman URLs.
+var postmanVariable = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+
+type PostmanHeader struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// PostmanURL is the URL of a Postman request, which a collection can store either as a
+// string or as an object.
+type PostmanURL struct {
+	Raw  string   `json:"raw"`
+	Path []string `json:"path"`
+}
+
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+
+	var obj struct {
+		Raw  string          `json:"raw"`
+		Path json.RawMessage `json:"path"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	u.Raw = obj.Raw
+
+	if len(obj.Path) == 0 {
+		return nil
+	}
+
+	// path is either a list of segments or a single string
+	if err := json.Unmarshal(obj.Path, &u.Path); err != nil {
+		var path string
+		if err := json.Unmarshal(obj.Path, &path); err != nil {
+			return err
+		}
+		u.Path = []string{path}
+	}
+
+	return nil
+}
+
+type PostmanRequest struct {
+	Method string          `json:"method"`
+	URL    PostmanURL      `json:"url"`
+	Header []PostmanHeader `json:"header"`
+}
+
+type PostmanResponse struct {
+	Name   string          `json:"name"`
+	Code   int             `json:"code"`
+	Header []PostmanHeader `json:"header"`
+	Body   string          `json:"body"`
+}
+
+// PostmanItem is either a request with its saved example responses, or a folder of items.
+type PostmanItem struct {
+	Name     string            `json:"name"`
+	Item     []PostmanItem     `json:"item"`
+	Request  *PostmanRequest   `json:"request"`
+	Response []PostmanResponse `json:"response"`
+}
+
+// PostmanCollection is a Postman collection in the v2.0 or v2.1 format.
+type PostmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item []PostmanItem `json:"item"`
+}
+
+func (p *PostmanCollection) LoadFrom(r io.Reader) error {
+	return json.NewDecoder(r).Decode(&p)
+}
+
+func (p *PostmanCollection) ConvertIntoApiVersion(asMock bool) (apidef.VersionInfo, error) {
+	endpoints := exampleEndpoints{}
+	p.collectEndpoints(p.Item, &endpoints)
+
+	if len(endpoints.endpoints) == 0 {
+		return apidef.VersionInfo{}, errors.New("There are no requests defined in this collection, are you sure it is correctly formatted?")
+	}
+
+	return endpoints.toAPIVersion("Default", asMock), nil
+}
+
+func (p *PostmanCollection) collectEndpoints(items []PostmanItem, endpoints *exampleEndpoints) {
+	for _, item := range items {
+		if item.Request == nil {
+			p.collectEndpoints(item.Item, endpoints)
+			continue
+		}
+
+		// the first saved example is used as mock response
+		response := exampleResponse{}
+		if len(item.Response) > 0 {
+			example := item.Response[0]
+			response.Code = example.Code
+			response.Body = example.Body
+
+			for _, h := range example.Header {
+				if h.Disabled {
+					continue
+				}
+				if response.Headers == nil {
+					response.Headers = make(map[string]string)
+				}
+				response.Headers[h.Key] = h.Value
+			}
+		}
+
+		endpoints.add(postmanPath(item.Request.URL), item.Request.Method, response)
+	}
+}
+
+// postmanPath returns the path of a request URL, turning `:param` segments and
+// `{{variable}}` references into path parameters.
+func postmanPath(u PostmanURL) string {
+	segments := u.Path
+	if len(segments) == 0 {
+		raw := u.Raw
+		if i := strings.IndexAny(raw, "?#"); i >= 0 {
+			raw = raw[:i]
+		}
+
+		// a leading variable usually holds the base URL
+		if loc := postmanVariable.FindStringIndex(raw); loc != nil && loc[0] == 0 {
+			raw = raw[loc[1]:]
+		} else if parsed, err := url.Parse(raw); err == nil && parsed.Host != "" {
+			raw = parsed.Path
+		} else if i := strings.Index(raw, "/"); i > 0 && !strings.HasPrefix(raw, "/") {
+			raw = raw[i:]
+		}
+
+		segments = strings.Split(strings.Trim(raw, "/"), "/")
+	}
+
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, ":") {
+			segment = "{" + segment[1:] + "}"
+		}
+		parts = append(parts, postmanVariable.ReplaceAllString(segment, "{$1}"))
+	}
+
+	return "/" + strings.Join(parts, "/")
+}
+
+func (p *PostmanCollection) InsertIntoAPIDefinitionAsVersion(version apidef.VersionInfo, def *apidef.APIDefinition, versionName string) error {
+	def.VersionData.NotVersioned = false
+	def.VersionData.Versions[versionName] = version
+	return nil
+}
+
+func (p *PostmanCollection) ToAPIDefinition(orgID, upstreamURL string, asMock bool) (*apidef.APIDefinition, error) {
+	ad := newDraftAPIDefinition(p.Info.Name, orgID, upstreamURL)
+
+	versionData, err := p.ConvertIntoApiVersion(asMock)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.InsertIntoAPIDefinitionAsVersion(versionData, &ad, versionData.Name)
+	return &ad, err
+}
@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestToAPIDefinition_Postman(t *testing.T) {
+	imp, err := GetImporterForSource(PostmanSource)
+	require.NoError(t, err)
+
+	require.NoError(t, imp.LoadFrom(bytes.NewBufferString(postmanCollectionJSON)))
+
+	def, err := imp.ToAPIDefinition("testOrg", "http://test.com", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Pets", def.Name)
+	assert.False(t, def.VersionData.NotVersioned)
+
+	v, ok := def.VersionData.Versions["Default"]
+	require.True(t, ok)
+
+	require.Len(t, v.ExtendedPaths.WhiteList, 2)
+	assert.Len(t, v.ExtendedPaths.TrackEndpoints, 3)
+
+	pets := v.ExtendedPaths.WhiteList[0]
+	assert.Equal(t, "/pets", pets.Path)
+	assert.Equal(t, apidef.EndpointMethodMeta{
+		Action:  apidef.Reply,
+		Code:    200,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Data:    `[{"id": 1}]`,
+	}, pets.MethodActions["GET"])
+	assert.Equal(t, 201, pets.MethodActions["POST"].Code)
+
+	pet := v.ExtendedPaths.WhiteList[1]
+	assert.Equal(t, "/pets/{petId}", pet.Path)
+	assert.Equal(t, 200, pet.MethodActions["DELETE"].Code)
+
+	t.Run("no requests", func(t *testing.T) {
+		imp := &PostmanCollection{}
+		require.NoError(t, imp.LoadFrom(bytes.NewBufferString(`{"info": {"name": "Empty"}, "item": []}`)))
+
+		_, err := imp.ToAPIDefinition("testOrg", "http://test.com", true)
+		assert.Error(t, err)
+	})
+}
+
+func TestPostmanPath(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"{{baseUrl}}/pets/:id?limit=10":       "/pets/{id}",
+		"https://api.example.com/v1/pets":     "/v1/pets",
+		"api.example.com/pets/{{petId}}/tags": "/pets/{petId}/tags",
+		"/pets":                               "/pets",
+		"{{baseUrl}}":                         "/",
+	} {
+		assert.Equal(t, expected, postmanPath(PostmanURL{Raw: raw}), raw)
+	}
+
+	assert.Equal(t, "/users/{id}", postmanPath(PostmanURL{Raw: "ignored", Path: []string{"users", ":id"}}))
+}
+
+var postmanCollectionJSON = `{
+  "info": {
+    "name": "Pets",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "item": [
+    {
+      "name": "pets",
+      "item": [
+        {
+          "name": "List pets",
+          "request": {
+            "method": "GET",
+            "url": {"raw": "{{baseUrl}}/pets", "host": ["{{baseUrl}}"], "path": ["pets"]}
+          },
+          "response": [
+            {
+              "name": "OK",
+              "code": 200,
+              "header": [
+                {"key": "Content-Type", "value": "application/json"},
+                {"key": "X-Debug", "value": "1", "disabled": true}
+              ],
+              "body": "[{\"id\": 1}]"
+            }
+          ]
+        },
+        {
+          "name": "Create pet",
+          "request": {"method": "POST", "url": "{{baseUrl}}/pets"},
+          "response": [{"name": "Created", "code": 201, "body": "{\"id\": 2}"}]
+        }
+      ]
+    },
+    {
+      "name": "Delete pet",
+      "request": {
+        "method": "DELETE",
+        "url": {"raw": "{{baseUrl}}/pets/:petId", "path": ["pets", ":petId"]}
+      }
+    }
+  ]
+}`
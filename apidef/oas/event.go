@@ -100,6 +100,9 @@ type WebhookEvent struct {
 	BodyTemplate string `json:"bodyTemplate,omitempty" bson:"bodyTemplate,omitempty"`
 	// Headers are the list of request headers to be used.
 	Headers Headers `json:"headers,omitempty" bson:"headers,omitempty"`
+	// RouteToOwner sends the webhook to the webhook and Slack contacts of the API owners,
+	// instead of URL, when the API has any.
+	RouteToOwner bool `json:"routeToOwner,omitempty" bson:"routeToOwner,omitempty"`
 }
 
 // GetWebhookConf converts EventHandler.WebhookEvent apidef.WebHookHandlerConf.
@@ -113,6 +116,7 @@ func (e *EventHandler) GetWebhookConf() apidef.WebHookHandlerConf {
 		HeaderList:   e.Webhook.Headers.Map(),
 		EventTimeout: int64(e.Webhook.CoolDownPeriod.Seconds()),
 		TemplatePath: e.Webhook.BodyTemplate,
+		RouteToOwner: e.Webhook.RouteToOwner,
 	}
 }
 
@@ -149,6 +153,7 @@ func (e *EventHandlers) Fill(api apidef.APIDefinition) {
 						Headers:        NewHeaders(whConf.HeaderList),
 						BodyTemplate:   whConf.TemplatePath,
 						CoolDownPeriod: ReadableDuration(time.Duration(whConf.EventTimeout) * time.Second),
+						RouteToOwner:   whConf.RouteToOwner,
 					},
 				}
 
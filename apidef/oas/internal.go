@@ -7,16 +7,56 @@ import "github.com/TykTechnologies/tyk/apidef"
 type Internal struct {
 	// Enabled if set to true makes the endpoint available only for internal requests.
 	Enabled bool `bson:"enabled" json:"enabled"`
+
+	// Name makes the endpoint addressable from looping as `tyk://<api>/@<name>`.
+	//
+	// Tyk classic API definition: `version_data.versions...extended_paths.internal[*].name`.
+	Name string `bson:"name,omitempty" json:"name,omitempty"`
+
+	// Parameters is the contract a named looping call has to satisfy.
+	//
+	// Tyk classic API definition: `version_data.versions...extended_paths.internal[*].parameters`.
+	Parameters []InternalParameter `bson:"parameters,omitempty" json:"parameters,omitempty"`
+}
+
+// InternalParameter describes a parameter accepted by a named internal endpoint.
+type InternalParameter struct {
+	// Name is the parameter name. Path placeholders like `{id}` are filled from the parameter with the same name.
+	Name string `bson:"name" json:"name"`
+	// Required rejects looping calls which don't provide the parameter.
+	Required bool `bson:"required,omitempty" json:"required,omitempty"`
+	// Pattern is an optional regular expression the parameter value has to match.
+	Pattern string `bson:"pattern,omitempty" json:"pattern,omitempty"`
 }
 
 // Fill fills *Internal receiver with data from apidef.InternalMeta.
 func (i *Internal) Fill(meta apidef.InternalMeta) {
 	i.Enabled = !meta.Disabled
+	i.Name = meta.Name
+
+	i.Parameters = nil
+	for _, p := range meta.Parameters {
+		i.Parameters = append(i.Parameters, InternalParameter{
+			Name:     p.Name,
+			Required: p.Required,
+			Pattern:  p.Pattern,
+		})
+	}
 }
 
 // ExtractTo fills *apidef.InternalMeta from *Internal.
 func (i *Internal) ExtractTo(meta *apidef.InternalMeta) {
 	meta.Disabled = !i.Enabled
+	meta.Name = i.Name
+
+	meta.Parameters = nil
+	for _, p := range i.Parameters {
+		meta.Parameters = append(meta.Parameters, apidef.InternalParameter{
+			Name:     p.Name,
+			Required: p.Required,
+			Pattern:  p.Pattern,
+		})
+	}
 }
 
 func (s *OAS) fillInternal(metas []apidef.InternalMeta) {
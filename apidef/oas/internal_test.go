@@ -47,3 +47,29 @@ func TestInternal_ExtractTo(t *testing.T) {
 	disabled.ExtractTo(&got)
 	assert.Equal(t, wantDisabled, got)
 }
+
+func TestInternal_NamedEndpoint(t *testing.T) {
+	meta := apidef.InternalMeta{
+		Name: "user-lookup",
+		Parameters: []apidef.InternalParameter{
+			{Name: "id", Required: true, Pattern: "^[0-9]+$"},
+			{Name: "fields"},
+		},
+	}
+
+	var internal Internal
+	internal.Fill(meta)
+
+	assert.Equal(t, Internal{
+		Enabled: true,
+		Name:    "user-lookup",
+		Parameters: []InternalParameter{
+			{Name: "id", Required: true, Pattern: "^[0-9]+$"},
+			{Name: "fields"},
+		},
+	}, internal)
+
+	var got apidef.InternalMeta
+	internal.ExtractTo(&got)
+	assert.Equal(t, meta, got)
+}
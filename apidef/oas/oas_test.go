@@ -247,8 +247,21 @@ func TestOAS_ExtractTo_ResetAPIDefinition(t *testing.T) {
 		"APIDefinition.Proxy.Transport.SSLMinVersion",
 		"APIDefinition.Proxy.Transport.SSLMaxVersion",
 		"APIDefinition.Proxy.Transport.SSLForceCommonNameCheck",
+		"APIDefinition.Proxy.Transport.SSLCACertificates[0]",
 		"APIDefinition.Proxy.Transport.ProxyURL",
+		"APIDefinition.Proxy.Transport.HTTP2.Enabled",
+		"APIDefinition.Proxy.Transport.HTTP2.PriorKnowledge",
+		"APIDefinition.Proxy.Transport.HTTP2.PingInterval",
+		"APIDefinition.Proxy.Transport.HTTP2.PingTimeout",
+		"APIDefinition.Proxy.Transport.HTTP2.MaxConcurrentStreams",
+		"APIDefinition.Proxy.Transport.HTTP2.StrictMaxConcurrentStreams",
 		"APIDefinition.DisableQuota",
+		"APIDefinition.CacheOptions.WarmUp.Enabled",
+		"APIDefinition.CacheOptions.WarmUp.Interval",
+		"APIDefinition.CacheOptions.WarmUp.RateLimit",
+		"APIDefinition.CacheOptions.WarmUp.Endpoints[0].Method",
+		"APIDefinition.CacheOptions.WarmUp.Endpoints[0].Path",
+		"APIDefinition.CacheOptions.WarmUp.Endpoints[0].Headers[0]",
 		"APIDefinition.SessionLifetimeRespectsKeyExpiration",
 		"APIDefinition.SessionLifetime",
 		"APIDefinition.AuthProvider.Name",
@@ -312,6 +325,152 @@ func TestOAS_ExtractTo_ResetAPIDefinition(t *testing.T) {
 		"APIDefinition.AnalyticsPlugin.Enabled",
 		"APIDefinition.AnalyticsPlugin.PluginPath",
 		"APIDefinition.AnalyticsPlugin.FuncName",
+		"APIDefinition.InternalToken.Enabled",
+		"APIDefinition.InternalToken.Header",
+		"APIDefinition.InternalToken.Issuer",
+		"APIDefinition.InternalToken.Audience",
+		"APIDefinition.InternalToken.ExpiresIn",
+		"APIDefinition.InternalToken.MetadataClaims[0]",
+		"APIDefinition.AllowedMethods.Methods[0]",
+		"APIDefinition.AllowedMethods.Endpoints[0].Path",
+		"APIDefinition.AllowedMethods.Endpoints[0].Methods[0]",
+		"APIDefinition.SlowRequests.Enabled",
+		"APIDefinition.SlowRequests.Threshold",
+		"APIDefinition.FaultInjection.Enabled",
+		"APIDefinition.FaultInjection.Faults[0].Path",
+		"APIDefinition.FaultInjection.Faults[0].Method",
+		"APIDefinition.FaultInjection.Faults[0].Percentage",
+		"APIDefinition.FaultInjection.Faults[0].Delay",
+		"APIDefinition.FaultInjection.Faults[0].StatusCode",
+		"APIDefinition.FaultInjection.Faults[0].ResetConnection",
+		"APIDefinition.WSSecurity.Enabled",
+		"APIDefinition.WSSecurity.RequireUsernameToken",
+		"APIDefinition.WSSecurity.Users[0]",
+		"APIDefinition.WSSecurity.RequireTimestamp",
+		"APIDefinition.WSSecurity.ClockSkew",
+		"APIDefinition.WSSecurity.RequireSignature",
+		"APIDefinition.WSSecurity.SignatureCertificates[0]",
+		"APIDefinition.DPoP.Enabled",
+		"APIDefinition.DPoP.Required",
+		"APIDefinition.DPoP.RequireNonce",
+		"APIDefinition.DPoP.ProofLifetime",
+		"APIDefinition.DPoP.ThumbprintHeader",
+		"APIDefinition.FAPI.Enabled",
+		"APIDefinition.FAPI.RequiredHeaders[0]",
+		"APIDefinition.UpstreamRouting.Enabled",
+		"APIDefinition.UpstreamRouting.Rules[0].Name",
+		"APIDefinition.UpstreamRouting.Rules[0].Source",
+		"APIDefinition.UpstreamRouting.Rules[0].Key",
+		"APIDefinition.UpstreamRouting.Rules[0].Values[0]",
+		"APIDefinition.UpstreamRouting.Rules[0].Targets[0]",
+		"APIDefinition.TenantIsolation.Enabled",
+		"APIDefinition.TenantIsolation.UpstreamTemplate",
+		"APIDefinition.TenantIsolation.AllowedHostSuffixes[0]",
+		"APIDefinition.ResourceIndicator.Enabled",
+		"APIDefinition.ResourceIndicator.Resource",
+		"APIDefinition.ResourceIndicator.RequiredScopes[0]",
+		"APIDefinition.BruteForceProtection.Enabled",
+		"APIDefinition.BruteForceProtection.MaxAttempts",
+		"APIDefinition.BruteForceProtection.Window",
+		"APIDefinition.BruteForceProtection.BanDuration",
+		"APIDefinition.BruteForceProtection.MaxBanDuration",
+		"APIDefinition.BruteForceProtection.DisableIPTracking",
+		"APIDefinition.Tokenization.Enabled",
+		"APIDefinition.Tokenization.Key",
+		"APIDefinition.Tokenization.Vault.URL",
+		"APIDefinition.Tokenization.Vault.Headers[0]",
+		"APIDefinition.Tokenization.Vault.Timeout",
+		"APIDefinition.Tokenization.Fields[0].Path",
+		"APIDefinition.Tokenization.Fields[0].Method",
+		"APIDefinition.Tokenization.Fields[0].Alphabet",
+		"APIDefinition.Tokenization.Fields[0].DetokenizeResponse",
+		"APIDefinition.ResponseFieldFilters.Enabled",
+		"APIDefinition.ResponseFieldFilters.MaxBufferedNodeSize",
+		"APIDefinition.ResponseFieldFilters.Filters[0].Path",
+		"APIDefinition.ResponseFieldFilters.Filters[0].Method",
+		"APIDefinition.ResponseFieldFilters.Filters[0].Include[0]",
+		"APIDefinition.ResponseFieldFilters.Filters[0].Exclude[0]",
+		"APIDefinition.ProtocolPassthrough.DisableTrailers",
+		"APIDefinition.ProtocolPassthrough.InterimResponses",
+		"APIDefinition.HeaderPolicy.Enabled",
+		"APIDefinition.HeaderPolicy.CanonicalizeKeys",
+		"APIDefinition.HeaderPolicy.Duplicates",
+		"APIDefinition.HeaderPolicy.MaxCount",
+		"APIDefinition.HeaderPolicy.MaxSize",
+		"APIDefinition.HeaderPolicy.RejectUnderscores",
+		"APIDefinition.Ownership.Owner",
+		"APIDefinition.Ownership.Team",
+		"APIDefinition.Ownership.Contacts[0].Type",
+		"APIDefinition.Ownership.Contacts[0].Address",
+		"APIDefinition.Metering.Enabled",
+		"APIDefinition.Metering.Units",
+		"APIDefinition.Metering.Endpoints[0].Path",
+		"APIDefinition.Metering.Endpoints[0].Method",
+		"APIDefinition.Metering.Endpoints[0].Units",
+		"APIDefinition.Metering.Endpoints[0].UnitsPerKB",
+		"APIDefinition.Metering.Endpoints[0].UnitsField",
+		"APIDefinition.Domains[0].Host",
+		"APIDefinition.Domains[0].Certificates[0]",
+		"APIDefinition.RequestPolicies[0].Name",
+		"APIDefinition.RequestPolicies[0].When",
+		"APIDefinition.RequestPolicies[0].Action",
+		"APIDefinition.RequestPolicies[0].Code",
+		"APIDefinition.RequestPolicies[0].Message",
+		"APIDefinition.RequestPolicies[0].SetHeaders[0]",
+		"APIDefinition.RequestPolicies[0].DeleteHeaders[0]",
+		"APIDefinition.ExternalAuthorization.Enabled",
+		"APIDefinition.ExternalAuthorization.Protocol",
+		"APIDefinition.ExternalAuthorization.URL",
+		"APIDefinition.ExternalAuthorization.TLS",
+		"APIDefinition.ExternalAuthorization.Timeout",
+		"APIDefinition.ExternalAuthorization.ForwardHeaders[0]",
+		"APIDefinition.ExternalAuthorization.MaxBodySize",
+		"APIDefinition.ExternalAuthorization.AllowBinary",
+		"APIDefinition.ExternalAuthorization.AllowedUpstreamHeaders[0]",
+		"APIDefinition.ExternalAuthorization.FailOpen",
+		"APIDefinition.ExternalAuthorization.CacheTTL",
+		"APIDefinition.ConsumerScopedMiddleware[0]",
+		"APIDefinition.OAuthRefreshTokens.ReuseDetection",
+		"APIDefinition.OAuthRefreshTokens.ReuseInterval",
+		"APIDefinition.InboundDedup.Enabled",
+		"APIDefinition.InboundDedup.Disabled",
+		"APIDefinition.InboundDedup.Headers[0]",
+		"APIDefinition.InboundDedup.TTL",
+		"APIDefinition.InboundDedup.DuplicateStatusCode",
+		"APIDefinition.ResponseStatusMapping.Enabled",
+		"APIDefinition.ResponseStatusMapping.Rules[0].Path",
+		"APIDefinition.ResponseStatusMapping.Rules[0].Method",
+		"APIDefinition.ResponseStatusMapping.Rules[0].UpstreamStatuses[0]",
+		"APIDefinition.ResponseStatusMapping.Rules[0].BodyField",
+		"APIDefinition.ResponseStatusMapping.Rules[0].BodyValue",
+		"APIDefinition.ResponseStatusMapping.Rules[0].BodyPattern",
+		"APIDefinition.ResponseStatusMapping.Rules[0].Status",
+		"APIDefinition.UpstreamErrorBudget.Enabled",
+		"APIDefinition.UpstreamErrorBudget.Window",
+		"APIDefinition.UpstreamErrorBudget.MinRequests",
+		"APIDefinition.UpstreamErrorBudget.FailureRatio",
+		"APIDefinition.UpstreamErrorBudget.ProbeInterval",
+		"APIDefinition.UpstreamErrorBudget.ServeStale",
+		"APIDefinition.UpstreamErrorBudget.StaleTTL",
+		"APIDefinition.UpstreamErrorBudget.FallbackCode",
+		"APIDefinition.UpstreamErrorBudget.FallbackBody",
+		"APIDefinition.UpstreamErrorBudget.FallbackHeaders[0]",
+		"APIDefinition.RateLimiter",
+		"APIDefinition.RateLimitHeaders.Enabled",
+		"APIDefinition.RateLimitHeaders.Policy",
+		"APIDefinition.QuotaThresholds.Enabled",
+		"APIDefinition.QuotaThresholds.Thresholds[0]",
+		"APIDefinition.Experiments.Enabled",
+		"APIDefinition.Experiments.Tests[0].Name",
+		"APIDefinition.Experiments.Tests[0].BucketBy",
+		"APIDefinition.Experiments.Tests[0].Key",
+		"APIDefinition.Experiments.Tests[0].Variants[0].Name",
+		"APIDefinition.Experiments.Tests[0].Variants[0].Weight",
+		"APIDefinition.Experiments.Tests[0].Variants[0].Target",
+		"APIDefinition.Experiments.Tests[0].Variants[0].Headers[0]",
+		"APIDefinition.DebugHeaders.Enabled",
+		"APIDefinition.DebugHeaders.AllowedIPs[0]",
+		"APIDefinition.DebugHeaders.AllowedKeys[0]",
 	}
 
 	assert.Equal(t, expectedFields, noOASSupportFields)
@@ -0,0 +1,187 @@
+package oas
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+// PublicDocument returns a copy of the OAS document describing the API as the Gateway exposes
+// it, for documentation and client SDK generation. The copy is served at apiURL, documents the
+// authentication enforced by the Gateway, omits the internal endpoints, documents the response
+// headers added by the Gateway, omits the request headers injected by the Gateway, and has no
+// Tyk extension.
+func (s *OAS) PublicDocument(api apidef.APIDefinition, apiURL string) (*OAS, error) {
+	public, err := s.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	public.Servers = openapi3.Servers{{URL: apiURL}}
+	public.fillPublicSecurity(api)
+
+	var global *Global
+	if middleware := public.GetTykMiddleware(); middleware != nil {
+		global = middleware.Global
+	}
+	operations := public.getTykOperations()
+
+	for path, pathItem := range public.Paths {
+		for method, operation := range pathItem.Operations() {
+			tykOperation := operations[operation.OperationID]
+			if tykOperation != nil && tykOperation.Internal != nil && tykOperation.Internal.Enabled {
+				pathItem.SetOperation(method, nil)
+				continue
+			}
+
+			if global != nil {
+				documentTransformHeaders(operation, global.TransformRequestHeaders, global.TransformResponseHeaders)
+			}
+
+			if tykOperation != nil {
+				documentTransformHeaders(operation, tykOperation.TransformRequestHeaders, tykOperation.TransformResponseHeaders)
+			}
+		}
+
+		if len(pathItem.Operations()) == 0 {
+			delete(public.Paths, path)
+		}
+	}
+
+	public.RemoveTykExtension()
+
+	return public, nil
+}
+
+// fillPublicSecurity documents the authentication modes of api. The API keys, JWTs and basic
+// credentials the Gateway accepts are documented even when the security schemes they use are
+// only referenced by the Tyk extension. Keyless APIs document no security.
+func (s *OAS) fillPublicSecurity(api apidef.APIDefinition) {
+	if api.UseKeylessAccess {
+		s.Security = nil
+		if s.Components != nil {
+			s.Components.SecuritySchemes = nil
+		}
+
+		for _, pathItem := range s.Paths {
+			for _, operation := range pathItem.Operations() {
+				operation.Security = nil
+			}
+		}
+
+		return
+	}
+
+	if s.Components == nil {
+		s.Components = &openapi3.Components{}
+	}
+
+	if s.Components.SecuritySchemes == nil {
+		s.Components.SecuritySchemes = openapi3.SecuritySchemes{}
+	}
+
+	document := func(authType string, scheme func(apidef.AuthConfig) *openapi3.SecurityScheme) {
+		authConfig := api.AuthConfigs[authType]
+		name := authConfig.Name
+		if name == "" {
+			name = authType
+		}
+
+		if _, ok := s.Components.SecuritySchemes[name]; !ok {
+			s.Components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{Value: scheme(authConfig)}
+		}
+
+		s.appendSecurity(name)
+	}
+
+	if api.UseStandardAuth {
+		document(apidef.AuthTokenType, func(authConfig apidef.AuthConfig) *openapi3.SecurityScheme {
+			scheme := openapi3.NewSecurityScheme().WithType(typeAPIKey)
+			switch {
+			case !authConfig.DisableHeader || (!authConfig.UseParam && !authConfig.UseCookie):
+				name := authConfig.AuthHeaderName
+				if name == "" {
+					name = defaultAuthSourceName
+				}
+				return scheme.WithIn(header).WithName(name)
+			case authConfig.UseParam:
+				return scheme.WithIn(query).WithName(authConfig.ParamName)
+			default:
+				return scheme.WithIn(cookie).WithName(authConfig.CookieName)
+			}
+		})
+	}
+
+	if api.EnableJWT {
+		document(apidef.JWTType, func(apidef.AuthConfig) *openapi3.SecurityScheme {
+			return openapi3.NewJWTSecurityScheme()
+		})
+	}
+
+	if api.UseBasicAuth {
+		document(apidef.BasicType, func(apidef.AuthConfig) *openapi3.SecurityScheme {
+			return openapi3.NewSecurityScheme().WithType(typeHTTP).WithScheme(schemeBasic)
+		})
+	}
+
+	if len(s.Components.SecuritySchemes) == 0 {
+		s.Components.SecuritySchemes = nil
+	}
+}
+
+// documentTransformHeaders removes the header parameters which the Gateway sets on requests
+// from operation, and documents the headers the Gateway adds to responses.
+func documentTransformHeaders(operation *openapi3.Operation, request, response *TransformHeaders) {
+	if request != nil && request.Enabled && len(request.Add) > 0 {
+		parameters := operation.Parameters[:0]
+		for _, parameter := range operation.Parameters {
+			if parameter.Value != nil && parameter.Value.In == header && hasHeader(request.Add, parameter.Value.Name) {
+				continue
+			}
+			parameters = append(parameters, parameter)
+		}
+		operation.Parameters = parameters
+	}
+
+	if response == nil || !response.Enabled || len(response.Add) == 0 {
+		return
+	}
+
+	if operation.Responses == nil {
+		operation.Responses = openapi3.NewResponses()
+	}
+
+	for _, resp := range operation.Responses {
+		if resp.Value == nil {
+			continue
+		}
+
+		if resp.Value.Headers == nil {
+			resp.Value.Headers = openapi3.Headers{}
+		}
+
+		for _, h := range response.Add {
+			name := http.CanonicalHeaderKey(h.Name)
+			if _, ok := resp.Value.Headers[name]; ok {
+				continue
+			}
+
+			resp.Value.Headers[name] = &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+				Description: "Set by the Gateway.",
+				Schema:      openapi3.NewStringSchema().NewRef(),
+			}}}
+		}
+	}
+}
+
+func hasHeader(headers Headers, name string) bool {
+	for _, h := range headers {
+		if http.CanonicalHeaderKey(h.Name) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+
+	return false
+}
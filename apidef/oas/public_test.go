@@ -0,0 +1,89 @@
+package oas
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+func TestOAS_PublicDocument(t *testing.T) {
+	newOAS := func() *OAS {
+		s := &OAS{T: openapi3.T{
+			OpenAPI: "3.0.3",
+			Info:    &openapi3.Info{Title: "pets", Version: "1"},
+			Servers: openapi3.Servers{{URL: "https://upstream.internal"}},
+			Paths: openapi3.Paths{
+				"/pets": {
+					Get: &openapi3.Operation{
+						OperationID: "pets",
+						Parameters: openapi3.Parameters{
+							{Value: openapi3.NewHeaderParameter("X-Upstream-Secret")},
+							{Value: openapi3.NewQueryParameter("limit")},
+						},
+						Responses: openapi3.NewResponses(),
+					},
+				},
+				"/admin": {
+					Post: &openapi3.Operation{OperationID: "admin", Responses: openapi3.NewResponses()},
+				},
+			},
+		}}
+
+		s.SetTykExtension(&XTykAPIGateway{
+			Middleware: &Middleware{
+				Global: &Global{
+					TransformResponseHeaders: &TransformHeaders{Enabled: true, Add: Headers{{Name: "x-served-by", Value: "tyk"}}},
+				},
+				Operations: Operations{
+					"pets":  {TransformRequestHeaders: &TransformHeaders{Enabled: true, Add: Headers{{Name: "X-Upstream-Secret", Value: "s"}}}},
+					"admin": {Internal: &Internal{Enabled: true}},
+				},
+			},
+		})
+
+		return s
+	}
+
+	api := apidef.APIDefinition{
+		UseStandardAuth: true,
+		AuthConfigs:     map[string]apidef.AuthConfig{apidef.AuthTokenType: {AuthHeaderName: "X-Api-Key"}},
+	}
+
+	s := newOAS()
+	public, err := s.PublicDocument(api, "https://gateway.example.com/pets/")
+	require.NoError(t, err)
+
+	assert.Nil(t, public.GetTykExtension())
+	assert.Equal(t, openapi3.Servers{{URL: "https://gateway.example.com/pets/"}}, public.Servers)
+
+	assert.NotContains(t, public.Paths, "/admin")
+
+	get := public.Paths["/pets"].Get
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "limit", get.Parameters[0].Value.Name)
+	assert.Contains(t, get.Responses.Default().Value.Headers, "X-Served-By")
+
+	scheme := public.Components.SecuritySchemes[apidef.AuthTokenType].Value
+	assert.Equal(t, "apiKey", scheme.Type)
+	assert.Equal(t, "header", scheme.In)
+	assert.Equal(t, "X-Api-Key", scheme.Name)
+	assert.Contains(t, public.Security[0], apidef.AuthTokenType)
+
+	// the document is left untouched
+	assert.NotNil(t, s.GetTykExtension())
+	assert.Contains(t, s.Paths, "/admin")
+	assert.Len(t, s.Paths["/pets"].Get.Parameters, 2)
+
+	t.Run("keyless", func(t *testing.T) {
+		s := newOAS()
+		s.Security = openapi3.SecurityRequirements{{"token": {}}}
+
+		public, err := s.PublicDocument(apidef.APIDefinition{UseKeylessAccess: true}, "http://gateway/")
+		require.NoError(t, err)
+		assert.Empty(t, public.Security)
+	})
+}
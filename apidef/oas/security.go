@@ -113,6 +113,13 @@ type JWT struct {
 	// Tyk classic API definition: `jwt_signing_method`
 	SigningMethod string `bson:"signingMethod,omitempty" json:"signingMethod,omitempty"`
 
+	// DecryptionKeys contains the IDs of the certificates whose private keys decrypt
+	// encrypted JWTs (JWE). The decrypted payload must be a signed JWT, which is then
+	// validated as usual.
+	//
+	// Tyk classic API definition: `jwt_decryption_keys`
+	DecryptionKeys []string `bson:"decryptionKeys,omitempty" json:"decryptionKeys,omitempty"`
+
 	// IdentityBaseField specifies the claim name uniquely identifying the subject of the JWT.
 	// The identity fields that are checked in order are: `kid`, IdentityBaseField, `sub`.
 	//
@@ -199,6 +206,7 @@ func (s *OAS) fillJWT(api apidef.APIDefinition) {
 	jwt.AuthSources.Fill(ac)
 	jwt.Source = api.JWTSource
 	jwt.SigningMethod = api.JWTSigningMethod
+	jwt.DecryptionKeys = api.JWTDecryptionKeys
 	jwt.IdentityBaseField = api.JWTIdentityBaseField
 	jwt.SkipKid = api.JWTSkipKid
 	jwt.PolicyFieldName = api.JWTPolicyFieldName
@@ -234,6 +242,7 @@ func (s *OAS) extractJWTTo(api *apidef.APIDefinition, name string) {
 	jwt.AuthSources.ExtractTo(&ac)
 	api.JWTSource = jwt.Source
 	api.JWTSigningMethod = jwt.SigningMethod
+	api.JWTDecryptionKeys = jwt.DecryptionKeys
 	api.JWTIdentityBaseField = jwt.IdentityBaseField
 	api.JWTSkipKid = jwt.SkipKid
 	api.JWTPolicyFieldName = jwt.PolicyFieldName
@@ -809,6 +818,7 @@ func resetSecuritySchemes(api *apidef.APIDefinition) {
 	api.EnableJWT = false
 	api.JWTSource = ""
 	api.JWTSigningMethod = ""
+	api.JWTDecryptionKeys = nil
 	api.JWTIdentityBaseField = ""
 	api.JWTSkipKid = false
 	api.JWTPolicyFieldName = ""
@@ -0,0 +1,318 @@
+package oas
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ErrSecuritySchemesMismatch is returned when the security declared by an OAS document doesn't
+// match how Tyk protects the API.
+var ErrSecuritySchemesMismatch = errors.New("the OAS security doesn't match the Tyk authentication")
+
+// SecurityMismatch is a difference between the security declared by an OAS document and the
+// authentication configured in its x-tyk-api-gateway extension.
+type SecurityMismatch struct {
+	// Scheme is the name of the security scheme, empty when the mismatch isn't about one.
+	Scheme string `json:"scheme,omitempty"`
+	// Reason describes the mismatch.
+	Reason string `json:"reason"`
+	// Fixable is true when SyncSecuritySchemes can fix the mismatch.
+	Fixable bool `json:"fixable"`
+}
+
+// String returns the mismatch in a readable form.
+func (m SecurityMismatch) String() string {
+	if m.Scheme == "" {
+		return m.Reason
+	}
+
+	return fmt.Sprintf("security scheme %q: %s", m.Scheme, m.Reason)
+}
+
+// SecurityMismatchError returns an error listing the mismatches, or nil when there are none.
+func SecurityMismatchError(mismatches []SecurityMismatch) error {
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		reasons = append(reasons, mismatch.String())
+	}
+
+	return fmt.Errorf("%w: %s", ErrSecuritySchemesMismatch, strings.Join(reasons, "; "))
+}
+
+// ValidateSecuritySchemes compares the security schemes required by the OAS document with the
+// Tyk authentication, and returns the mismatches. Tyk enforces the enabled Tyk security schemes
+// required by the first security requirement of the document, and only them.
+func (s *OAS) ValidateSecuritySchemes() []SecurityMismatch {
+	return s.checkSecuritySchemes(false)
+}
+
+// SyncSecuritySchemes fixes the OAS security requirements to match the Tyk authentication, which
+// is taken as the source of truth, and returns the mismatches it couldn't fix.
+func (s *OAS) SyncSecuritySchemes() []SecurityMismatch {
+	s.checkSecuritySchemes(true)
+	return s.checkSecuritySchemes(false)
+}
+
+func (s *OAS) checkSecuritySchemes(fix bool) (mismatches []SecurityMismatch) {
+	if s.GetTykExtension() == nil {
+		return nil
+	}
+
+	report := func(scheme, reason string, fixable bool) {
+		mismatches = append(mismatches, SecurityMismatch{Scheme: scheme, Reason: reason, Fixable: fixable})
+	}
+
+	auth := s.getTykAuthentication()
+	if auth == nil || !auth.Enabled {
+		if s.declaresSecurity() {
+			report("", "the document requires security but the Tyk authentication is disabled", true)
+			if fix {
+				s.clearSecurity()
+			}
+		}
+
+		return mismatches
+	}
+
+	var required openapi3.SecurityRequirement
+	if len(s.Security) > 0 {
+		required = s.Security[0]
+	}
+
+	enforced := make(map[string]bool)
+	for _, name := range sortedSchemeNames(auth.SecuritySchemes) {
+		if !securitySchemeEnabled(auth.SecuritySchemes[name]) {
+			continue
+		}
+
+		nativeSS := s.nativeSecurityScheme(name)
+		if nativeSS == nil {
+			report(name, "the Tyk security scheme has no OAS security scheme", false)
+			continue
+		}
+
+		if reason := securitySchemeTypeMismatch(auth.SecuritySchemes[name], nativeSS); reason != "" {
+			report(name, reason, false)
+			continue
+		}
+
+		enforced[name] = true
+		if _, ok := required[name]; !ok {
+			report(name, "the Tyk security scheme isn't required by the document security", true)
+			if fix {
+				s.appendSecurity(name)
+			}
+		}
+	}
+
+	if len(s.Security) > 1 {
+		report("", "the alternative security requirements of the document aren't enforced by Tyk", true)
+		if fix {
+			s.Security = s.Security[:1]
+		}
+	}
+
+	for _, name := range sortedRequirementNames(required) {
+		if !enforced[name] {
+			report(name, "the document requires the security scheme but Tyk doesn't enforce it", true)
+			if fix {
+				delete(s.Security[0], name)
+			}
+		}
+	}
+
+	if fix && len(s.Security) == 1 && len(s.Security[0]) == 0 {
+		s.Security = nil
+	}
+
+	for _, path := range sortedPaths(s.Paths) {
+		operations := s.Paths[path].Operations()
+		for _, method := range sortedMethods(operations) {
+			operation := operations[method]
+			if operation.Security == nil {
+				continue
+			}
+
+			reason := fmt.Sprintf("operation %s %s overrides the document security, which Tyk doesn't enforce", method, path)
+			if len(*operation.Security) == 0 && len(enforced) > 0 {
+				reason = fmt.Sprintf("operation %s %s is declared public but Tyk requires authentication", method, path)
+			} else if operationSecurityMatches(*operation.Security, enforced) {
+				continue
+			}
+
+			report("", reason, true)
+			if fix {
+				operation.Security = nil
+			}
+		}
+	}
+
+	return mismatches
+}
+
+// declaresSecurity returns true when the document or any of its operations requires security.
+func (s *OAS) declaresSecurity() bool {
+	for _, requirement := range s.Security {
+		if len(requirement) > 0 {
+			return true
+		}
+	}
+
+	for _, pathItem := range s.Paths {
+		for _, operation := range pathItem.Operations() {
+			if operation.Security == nil {
+				continue
+			}
+
+			for _, requirement := range *operation.Security {
+				if len(requirement) > 0 {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// clearSecurity removes the security requirements of the document and of its operations.
+func (s *OAS) clearSecurity() {
+	s.Security = nil
+	for _, pathItem := range s.Paths {
+		for _, operation := range pathItem.Operations() {
+			operation.Security = nil
+		}
+	}
+}
+
+func (s *OAS) nativeSecurityScheme(name string) *openapi3.SecurityScheme {
+	if s.Components == nil {
+		return nil
+	}
+
+	ref := s.Components.SecuritySchemes[name]
+	if ref == nil {
+		return nil
+	}
+
+	return ref.Value
+}
+
+// securitySchemeEnabled returns whether a Tyk security scheme, typed or decoded to a map, is enabled.
+func securitySchemeEnabled(scheme interface{}) bool {
+	switch v := scheme.(type) {
+	case *Token:
+		return v.Enabled
+	case *JWT:
+		return v.Enabled
+	case *Basic:
+		return v.Enabled
+	case *OAuth:
+		return v.Enabled
+	case *ExternalOAuth:
+		return v.Enabled
+	case map[string]interface{}:
+		enabled, _ := v["enabled"].(bool)
+		return enabled
+	}
+
+	return false
+}
+
+// securitySchemeTypeMismatch returns why a Tyk security scheme can't enforce an OAS security
+// scheme, or an empty string when it can.
+func securitySchemeTypeMismatch(scheme interface{}, nativeSS *openapi3.SecurityScheme) string {
+	var want string
+	var matches bool
+
+	switch {
+	case nativeSS.Type == typeAPIKey:
+		want = "token"
+		_, matches = scheme.(*Token)
+	case nativeSS.Type == typeHTTP && nativeSS.Scheme == schemeBearer && nativeSS.BearerFormat == bearerFormatJWT:
+		want = "jwt"
+		_, matches = scheme.(*JWT)
+	case nativeSS.Type == typeHTTP && nativeSS.Scheme == schemeBasic:
+		want = "basic"
+		_, matches = scheme.(*Basic)
+	case nativeSS.Type == typeOAuth2:
+		want = "oauth"
+		switch scheme.(type) {
+		case *OAuth, *ExternalOAuth:
+			matches = true
+		}
+	default:
+		return fmt.Sprintf("the OAS security scheme type %q isn't supported by the Tyk authentication", nativeSS.Type)
+	}
+
+	// the schemes decoded from JSON are maps and take their type from the OAS security scheme
+	if _, ok := scheme.(map[string]interface{}); ok || matches {
+		return ""
+	}
+
+	return fmt.Sprintf("the OAS security scheme requires %s authentication but the Tyk security scheme configures %T", want, scheme)
+}
+
+// operationSecurityMatches returns true when the security of an operation requires exactly the
+// enforced security schemes.
+func operationSecurityMatches(security openapi3.SecurityRequirements, enforced map[string]bool) bool {
+	if len(security) != 1 || len(security[0]) != len(enforced) {
+		return false
+	}
+
+	for name := range security[0] {
+		if !enforced[name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortedSchemeNames(ss SecuritySchemes) []string {
+	names := make([]string, 0, len(ss))
+	for name := range ss {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func sortedRequirementNames(requirement openapi3.SecurityRequirement) []string {
+	names := make([]string, 0, len(requirement))
+	for name := range requirement {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func sortedPaths(paths openapi3.Paths) []string {
+	names := make([]string, 0, len(paths))
+	for path := range paths {
+		names = append(names, path)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func sortedMethods(operations map[string]*openapi3.Operation) []string {
+	methods := make([]string, 0, len(operations))
+	for method := range operations {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
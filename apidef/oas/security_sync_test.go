@@ -0,0 +1,96 @@
+package oas
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAS_SyncSecuritySchemes(t *testing.T) {
+	newOAS := func(auth *Authentication) *OAS {
+		s := &OAS{T: openapi3.T{
+			Components: &openapi3.Components{SecuritySchemes: openapi3.SecuritySchemes{
+				"token": {Value: &openapi3.SecurityScheme{Type: typeAPIKey, In: header, Name: "Authorization"}},
+				"basic": {Value: &openapi3.SecurityScheme{Type: typeHTTP, Scheme: schemeBasic}},
+				"oidc":  {Value: &openapi3.SecurityScheme{Type: "openIdConnect"}},
+			}},
+			Paths: openapi3.Paths{"/pets": {Get: &openapi3.Operation{}}},
+		}}
+		s.SetTykExtension(&XTykAPIGateway{Server: Server{Authentication: auth}})
+		return s
+	}
+
+	t.Run("in sync", func(t *testing.T) {
+		s := newOAS(&Authentication{Enabled: true, SecuritySchemes: SecuritySchemes{"token": &Token{Enabled: true}}})
+		s.Security = openapi3.SecurityRequirements{{"token": []string{}}}
+
+		assert.Empty(t, s.ValidateSecuritySchemes())
+	})
+
+	t.Run("decoded schemes", func(t *testing.T) {
+		s := newOAS(&Authentication{Enabled: true, SecuritySchemes: SecuritySchemes{"basic": map[string]interface{}{"enabled": true}}})
+		s.Security = openapi3.SecurityRequirements{{"basic": []string{}}}
+
+		assert.Empty(t, s.ValidateSecuritySchemes())
+	})
+
+	t.Run("authentication disabled", func(t *testing.T) {
+		s := newOAS(nil)
+		s.Security = openapi3.SecurityRequirements{{"token": []string{}}}
+		s.Paths["/pets"].Get.Security = &openapi3.SecurityRequirements{{"basic": []string{}}}
+
+		mismatches := s.ValidateSecuritySchemes()
+		assert.Len(t, mismatches, 1)
+		assert.True(t, mismatches[0].Fixable)
+
+		assert.Empty(t, s.SyncSecuritySchemes())
+		assert.Nil(t, s.Security)
+		assert.Nil(t, s.Paths["/pets"].Get.Security)
+	})
+
+	t.Run("requirements fixed from the Tyk schemes", func(t *testing.T) {
+		s := newOAS(&Authentication{Enabled: true, SecuritySchemes: SecuritySchemes{
+			"token": &Token{Enabled: true},
+			"basic": &Basic{Enabled: false},
+		}})
+		s.Security = openapi3.SecurityRequirements{{"basic": []string{}}, {"oidc": []string{}}}
+		s.Paths["/pets"].Get.Security = &openapi3.SecurityRequirements{}
+
+		mismatches := s.ValidateSecuritySchemes()
+		assert.Equal(t, []SecurityMismatch{
+			{Scheme: "token", Reason: "the Tyk security scheme isn't required by the document security", Fixable: true},
+			{Reason: "the alternative security requirements of the document aren't enforced by Tyk", Fixable: true},
+			{Scheme: "basic", Reason: "the document requires the security scheme but Tyk doesn't enforce it", Fixable: true},
+			{Reason: "operation GET /pets is declared public but Tyk requires authentication", Fixable: true},
+		}, mismatches)
+		assert.ErrorIs(t, SecurityMismatchError(mismatches), ErrSecuritySchemesMismatch)
+
+		assert.Empty(t, s.SyncSecuritySchemes())
+		assert.Equal(t, openapi3.SecurityRequirements{{"token": []string{}}}, s.Security)
+		assert.Nil(t, s.Paths["/pets"].Get.Security)
+	})
+
+	t.Run("unfixable", func(t *testing.T) {
+		s := newOAS(&Authentication{Enabled: true, SecuritySchemes: SecuritySchemes{
+			"basic":   &Token{Enabled: true},
+			"missing": &Token{Enabled: true},
+			"oidc":    &OAuth{Enabled: true},
+		}})
+		s.Security = openapi3.SecurityRequirements{{"basic": []string{}, "missing": []string{}, "oidc": []string{}}}
+
+		mismatches := s.SyncSecuritySchemes()
+		assert.Len(t, mismatches, 3)
+		for _, mismatch := range mismatches {
+			assert.False(t, mismatch.Fixable, mismatch.String())
+		}
+		assert.Empty(t, s.Security)
+	})
+
+	t.Run("no tyk extension", func(t *testing.T) {
+		s := &OAS{}
+		s.Security = openapi3.SecurityRequirements{{"token": []string{}}}
+
+		assert.Nil(t, s.ValidateSecuritySchemes())
+	})
+}
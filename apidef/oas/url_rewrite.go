@@ -10,11 +10,15 @@ import (
 // URLRewrite configures URL rewriting.
 // Tyk classic API definition: `version_data.versions[].extended_paths.url_rewrite`.
 type URLRewrite struct {
+	// Name identifies the rule, in the results of the URL rewrite test endpoint.
+	Name string `bson:"name,omitempty" json:"name,omitempty"`
+
 	// Enabled activates URL rewriting if set to true.
 	Enabled bool `bson:"enabled" json:"enabled"`
 
 	// Pattern is the regular expression against which the request URL is compared for the primary rewrite check.
 	// If this matches the defined pattern, the primary URL rewrite is triggered.
+	// Named capture groups, e.g. `(?P<id>[0-9]+)`, can be referenced in `rewriteTo` as `${id}`.
 	Pattern string `bson:"pattern,omitempty" json:"pattern,omitempty"`
 
 	// RewriteTo specifies the URL to which the request shall be rewritten if the primary URL rewrite is triggered.
@@ -36,6 +40,7 @@ type URLRewrite struct {
 // - `sessionMetadata`, match pattern against session metadata
 // - `requestBody`, match pattern against request body
 // - `requestContext`, match pattern against request context
+// - `jwtClaim`, match pattern against named claim of the JWT
 //
 // The default `url` is used as the input source.
 type URLRewriteInput string
@@ -54,6 +59,7 @@ const (
 	InputSessionMetadata URLRewriteInput = "sessionMetadata"
 	InputRequestBody     URLRewriteInput = "requestBody"
 	InputRequestContext  URLRewriteInput = "requestContext"
+	InputJWTClaim        URLRewriteInput = "jwtClaim"
 
 	ConditionAll URLRewriteCondition = "all"
 	ConditionAny URLRewriteCondition = "any"
@@ -74,11 +80,16 @@ var (
 		InputSessionMetadata,
 		InputRequestBody,
 		InputRequestContext,
+		InputJWTClaim,
 	}
 )
 
 // URLRewriteTrigger represents a set of matching rules for a rewrite.
 type URLRewriteTrigger struct {
+	// Name identifies the trigger, the groups it captures are also set in the request context
+	// under its name, as `trigger-<name>-...`.
+	Name string `bson:"name,omitempty" json:"name,omitempty"`
+
 	// Condition indicates the logical combination that will be applied to the rules for an advanced trigger.
 	Condition URLRewriteCondition `bson:"condition" json:"condition"`
 
@@ -117,6 +128,7 @@ type URLRewriteRule struct {
 
 // Fill fills *URLRewrite receiver from apidef.URLRewriteMeta.
 func (v *URLRewrite) Fill(meta apidef.URLRewriteMeta) {
+	v.Name = meta.Name
 	v.Enabled = !meta.Disabled
 	v.Pattern = meta.MatchPattern
 	v.RewriteTo = meta.RewriteTo
@@ -139,6 +151,7 @@ func (v *URLRewrite) fillTriggers(from []apidef.RoutingTrigger) []*URLRewriteTri
 		}
 
 		trigger := &URLRewriteTrigger{
+			Name:      t.Name,
 			Condition: URLRewriteCondition(t.On),
 			Rules:     rules,
 			RewriteTo: t.RewriteTo,
@@ -167,6 +180,7 @@ func (v *URLRewrite) fillRules(from apidef.RoutingTriggerOptions) []*URLRewriteR
 	v.appendRules(&result, from.PathPartMatches, InputPath)
 	v.appendRules(&result, from.SessionMetaMatches, InputSessionMetadata)
 	v.appendRules(&result, from.RequestContextMatches, InputRequestContext)
+	v.appendRules(&result, from.ClaimMatches, InputJWTClaim)
 
 	v.appendRules(&result, map[string]apidef.StringRegexMap{
 		"": from.PayloadMatches,
@@ -193,6 +207,7 @@ func (*URLRewrite) appendRules(rules *[]*URLRewriteRule, from map[string]apidef.
 
 // ExtractTo fills *apidef.URLRewriteMeta from *URLRewrite.
 func (v *URLRewrite) ExtractTo(dest *apidef.URLRewriteMeta) {
+	dest.Name = v.Name
 	dest.Disabled = !v.Enabled
 	dest.MatchPattern = v.Pattern
 	dest.RewriteTo = v.RewriteTo
@@ -206,6 +221,7 @@ func (v *URLRewrite) extractTriggers() []apidef.RoutingTrigger {
 	triggers := make([]apidef.RoutingTrigger, len(v.Triggers))
 	for i, trigger := range v.Triggers {
 		routingTrigger := apidef.RoutingTrigger{
+			Name:      trigger.Name,
 			On:        apidef.RoutingTriggerOnType(trigger.Condition),
 			RewriteTo: trigger.RewriteTo,
 			Options:   v.extractTriggerOptions(trigger.Rules),
@@ -237,6 +253,8 @@ func (*URLRewrite) extractTriggerOptions(rules []*URLRewriteRule) apidef.Routing
 			result.QueryValMatches[rule.Name] = item
 		case InputSessionMetadata:
 			result.SessionMetaMatches[rule.Name] = item
+		case InputJWTClaim:
+			result.ClaimMatches[rule.Name] = item
 		}
 	}
 
@@ -246,7 +264,7 @@ func (*URLRewrite) extractTriggerOptions(rules []*URLRewriteRule) apidef.Routing
 // Valid returns true if the type value matches valid values, false otherwise.
 func (i URLRewriteInput) Valid() bool {
 	switch i {
-	case InputQuery, InputPath, InputHeader, InputSessionMetadata, InputRequestBody, InputRequestContext:
+	case InputQuery, InputPath, InputHeader, InputSessionMetadata, InputRequestBody, InputRequestContext, InputJWTClaim:
 		return true
 	}
 	return false
@@ -75,6 +75,15 @@ const Schema = `{
     "custom_plugin_auth_enabled": {
       "type": "boolean"
     },
+    "jwt_decryption_keys": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "string"
+      }
+    },
     "jwt_skip_kid": {
       "type": "boolean"
     },
@@ -255,6 +264,312 @@ const Schema = `{
         "null"
       ]
     },
+    "rate_limiter": {
+      "type": "string",
+      "enum": [
+        "",
+        "drl",
+        "sentinel",
+        "sliding-log",
+        "fixed-window",
+        "sliding-window",
+        "leaky-bucket",
+        "token-bucket",
+        "gcra"
+      ]
+    },
+    "rate_limit_headers": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "policy": {
+          "type": "boolean"
+        }
+      }
+    },
+    "quota_thresholds": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "thresholds": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "number",
+            "minimum": 0,
+            "maximum": 100
+          }
+        }
+      }
+    },
+    "experiments": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "tests": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "name": {
+                "type": "string"
+              },
+              "bucket_by": {
+                "type": "string",
+                "enum": [
+                  "",
+                  "key",
+                  "claim",
+                  "header",
+                  "meta"
+                ]
+              },
+              "key": {
+                "type": "string"
+              },
+              "variants": {
+                "type": [
+                  "array",
+                  "null"
+                ],
+                "items": {
+                  "type": "object",
+                  "properties": {
+                    "name": {
+                      "type": "string"
+                    },
+                    "weight": {
+                      "type": "integer",
+                      "minimum": 0
+                    },
+                    "target": {
+                      "type": "string"
+                    },
+                    "headers": {
+                      "type": [
+                        "object",
+                        "null"
+                      ],
+                      "additionalProperties": {
+                        "type": "string"
+                      }
+                    }
+                  },
+                  "required": [
+                    "name"
+                  ]
+                }
+              }
+            },
+            "required": [
+              "name",
+              "variants"
+            ]
+          }
+        }
+      }
+    },
+    "debug_headers": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "allowed_ips": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        },
+        "allowed_keys": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "upstream_error_budget": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "window": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "min_requests": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "failure_ratio": {
+          "type": "number",
+          "minimum": 0,
+          "maximum": 1
+        },
+        "probe_interval": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "serve_stale": {
+          "type": "boolean"
+        },
+        "stale_ttl": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "fallback_code": {
+          "type": "integer",
+          "minimum": 0,
+          "maximum": 599
+        },
+        "fallback_body": {
+          "type": "string"
+        },
+        "fallback_headers": {
+          "type": [
+            "object",
+            "null"
+          ],
+          "additionalProperties": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "response_status_mapping": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "rules": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "path": {
+                "type": "string"
+              },
+              "method": {
+                "type": "string"
+              },
+              "upstream_statuses": {
+                "type": [
+                  "array",
+                  "null"
+                ],
+                "items": {
+                  "type": "integer"
+                }
+              },
+              "body_field": {
+                "type": "string"
+              },
+              "body_value": {
+                "type": "string"
+              },
+              "body_pattern": {
+                "type": "string"
+              },
+              "status": {
+                "type": "integer",
+                "minimum": 100,
+                "maximum": 599
+              }
+            },
+            "required": [
+              "status"
+            ]
+          }
+        }
+      }
+    },
+    "inbound_dedup": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "disabled": {
+          "type": "boolean"
+        },
+        "headers": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        },
+        "ttl": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "duplicate_status_code": {
+          "type": "integer",
+          "minimum": 0
+        }
+      }
+    },
+    "oauth_refresh_tokens": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "reuse_detection": {
+          "type": "boolean"
+        },
+        "reuse_interval": {
+          "type": "integer",
+          "minimum": 0
+        }
+      }
+    },
     "external_oauth": {
       "type": [
         "object",
@@ -507,6 +822,44 @@ const Schema = `{
             },
             "ssl_force_common_name_check": {
               "type": "boolean"
+            },
+            "ssl_ca_certificates": {
+              "type": [
+                "array",
+                "null"
+              ],
+              "items": {
+                "type": "string"
+              }
+            },
+            "http2": {
+              "type": [
+                "object",
+                "null"
+              ],
+              "properties": {
+                "enabled": {
+                  "type": "boolean"
+                },
+                "prior_knowledge": {
+                  "type": "boolean"
+                },
+                "ping_interval": {
+                  "type": "number",
+                  "minimum": 0
+                },
+                "ping_timeout": {
+                  "type": "number",
+                  "minimum": 0
+                },
+                "max_concurrent_streams": {
+                  "type": "integer",
+                  "minimum": 0
+                },
+                "strict_max_concurrent_streams": {
+                  "type": "boolean"
+                }
+              }
             }
           }
         }
@@ -992,6 +1345,753 @@ const Schema = `{
     "detailed_tracing": {
       "type": "boolean"
     },
+    "domains": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "object",
+        "properties": {
+          "host": {
+            "type": "string",
+            "pattern": "^[^\\s/]+$"
+          },
+          "certificates": {
+            "type": [
+              "array",
+              "null"
+            ],
+            "items": {
+              "type": "string"
+            }
+          }
+        },
+        "required": [
+          "host"
+        ]
+      }
+    },
+    "request_policies": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "when": {
+            "type": "string",
+            "minLength": 1
+          },
+          "action": {
+            "type": "string",
+            "enum": [
+              "allow",
+              "deny",
+              "transform"
+            ]
+          },
+          "code": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "message": {
+            "type": "string"
+          },
+          "set_headers": {
+            "type": [
+              "object",
+              "null"
+            ],
+            "additionalProperties": {
+              "type": "string"
+            }
+          },
+          "delete_headers": {
+            "type": [
+              "array",
+              "null"
+            ],
+            "items": {
+              "type": "string"
+            }
+          }
+        },
+        "required": [
+          "when",
+          "action"
+        ]
+      }
+    },
+    "external_authorization": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "protocol": {
+          "type": "string",
+          "enum": [
+            "",
+            "http",
+            "grpc"
+          ]
+        },
+        "url": {
+          "type": "string"
+        },
+        "tls": {
+          "type": "boolean"
+        },
+        "timeout": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "forward_headers": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        },
+        "max_body_size": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "allow_binary": {
+          "type": "boolean"
+        },
+        "allowed_upstream_headers": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        },
+        "fail_open": {
+          "type": "boolean"
+        },
+        "cache_ttl": {
+          "type": "integer",
+          "minimum": 0
+        }
+      }
+    },
+    "consumer_scoped_middleware": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "string"
+      }
+    },
+    "metering": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "units": {
+          "type": "number",
+          "minimum": 0
+        },
+        "endpoints": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "path": {
+                "type": "string"
+              },
+              "method": {
+                "type": "string"
+              },
+              "units": {
+                "type": "number",
+                "minimum": 0
+              },
+              "units_per_kb": {
+                "type": "number",
+                "minimum": 0
+              },
+              "units_field": {
+                "type": "string"
+              }
+            }
+          }
+        }
+      }
+    },
+    "ownership": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "owner": {
+          "type": "string"
+        },
+        "team": {
+          "type": "string"
+        },
+        "contacts": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "type": {
+                "type": "string",
+                "enum": [
+                  "email",
+                  "webhook",
+                  "slack"
+                ]
+              },
+              "address": {
+                "type": "string"
+              }
+            },
+            "required": [
+              "type",
+              "address"
+            ]
+          }
+        }
+      }
+    },
+    "header_policy": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "canonicalize_keys": {
+          "type": "boolean"
+        },
+        "duplicates": {
+          "type": "string",
+          "enum": [
+            "",
+            "reject",
+            "merge",
+            "first_wins"
+          ]
+        },
+        "max_count": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "max_size": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "reject_underscores": {
+          "type": "boolean"
+        }
+      }
+    },
+    "protocol_passthrough": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "disable_trailers": {
+          "type": "boolean"
+        },
+        "interim_responses": {
+          "type": "boolean"
+        }
+      }
+    },
+    "response_field_filters": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "max_buffered_node_size": {
+          "type": "integer"
+        },
+        "filters": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "path": {
+                "type": "string"
+              },
+              "method": {
+                "type": "string"
+              },
+              "include": {
+                "type": [
+                  "array",
+                  "null"
+                ],
+                "items": {
+                  "type": "string"
+                }
+              },
+              "exclude": {
+                "type": [
+                  "array",
+                  "null"
+                ],
+                "items": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "tokenization": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "key": {
+          "type": "string"
+        },
+        "vault": {
+          "type": [
+            "object",
+            "null"
+          ],
+          "properties": {
+            "url": {
+              "type": "string"
+            },
+            "headers": {
+              "type": [
+                "object",
+                "null"
+              ]
+            },
+            "timeout": {
+              "type": "integer"
+            }
+          }
+        },
+        "fields": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "path": {
+                "type": "string"
+              },
+              "method": {
+                "type": "string",
+                "enum": [
+                  "fpe",
+                  "vault"
+                ]
+              },
+              "alphabet": {
+                "type": "string",
+                "enum": [
+                  "",
+                  "digits",
+                  "alphanumeric"
+                ]
+              },
+              "detokenize_response": {
+                "type": "boolean"
+              }
+            }
+          }
+        }
+      }
+    },
+    "brute_force_protection": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "max_attempts": {
+          "type": "integer"
+        },
+        "window": {
+          "type": "integer"
+        },
+        "ban_duration": {
+          "type": "integer"
+        },
+        "max_ban_duration": {
+          "type": "integer"
+        },
+        "disable_ip_tracking": {
+          "type": "boolean"
+        }
+      }
+    },
+    "resource_indicator": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "resource": {
+          "type": "string"
+        },
+        "required_scopes": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "tenant_isolation": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "upstream_template": {
+          "type": "string"
+        },
+        "allowed_host_suffixes": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "upstream_routing": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "rules": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "name": {
+                "type": "string"
+              },
+              "source": {
+                "type": "string",
+                "enum": [
+                  "header",
+                  "claim",
+                  "meta",
+                  "ip",
+                  "listener"
+                ]
+              },
+              "key": {
+                "type": "string"
+              },
+              "values": {
+                "type": [
+                  "array",
+                  "null"
+                ],
+                "items": {
+                  "type": "string"
+                }
+              },
+              "targets": {
+                "type": [
+                  "array",
+                  "null"
+                ],
+                "items": {
+                  "type": "string"
+                }
+              }
+            },
+            "required": [
+              "source",
+              "key",
+              "targets"
+            ]
+          }
+        }
+      }
+    },
+    "fapi": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "required_headers": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "dpop": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "required": {
+          "type": "boolean"
+        },
+        "require_nonce": {
+          "type": "boolean"
+        },
+        "proof_lifetime": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "thumbprint_header": {
+          "type": "string"
+        }
+      }
+    },
+    "ws_security": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "require_username_token": {
+          "type": "boolean"
+        },
+        "users": {
+          "type": [
+            "object",
+            "null"
+          ],
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "require_timestamp": {
+          "type": "boolean"
+        },
+        "clock_skew": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "require_signature": {
+          "type": "boolean"
+        },
+        "signature_certificates": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "fault_injection": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "faults": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "path": {
+                "type": "string"
+              },
+              "method": {
+                "type": "string"
+              },
+              "percentage": {
+                "type": "number",
+                "minimum": 0,
+                "maximum": 100
+              },
+              "delay": {
+                "type": "integer",
+                "minimum": 0
+              },
+              "status_code": {
+                "type": "integer"
+              },
+              "reset_connection": {
+                "type": "boolean"
+              }
+            }
+          }
+        }
+      }
+    },
+    "slow_requests": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "threshold": {
+          "type": "integer",
+          "minimum": 0
+        }
+      }
+    },
+    "allowed_methods": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "methods": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "string"
+          }
+        },
+        "endpoints": {
+          "type": [
+            "array",
+            "null"
+          ],
+          "items": {
+            "type": "object",
+            "properties": {
+              "path": {
+                "type": "string"
+              },
+              "methods": {
+                "type": [
+                  "array",
+                  "null"
+                ],
+                "items": {
+                  "type": "string"
+                }
+              }
+            },
+            "required": [
+              "path"
+            ]
+          }
+        }
+      }
+    },
+    "internal_token": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "enabled": {
+          "type": "boolean"
+        },
+        "header": {
+          "type": "string"
+        },
+        "issuer": {
+          "type": "string"
+        },
+        "audience": {
+          "type": "string"
+        },
+        "expires_in": {
+          "type": "number"
+        },
+        "metadata_claims": {
+          "type": [
+            "object",
+            "null"
+          ]
+        }
+      }
+    },
     "upstream_auth": {
       "type": "object",
       "properties": {
@@ -6,6 +6,7 @@ import (
 	"net"
 	"sort"
 	"strings"
+	"text/template"
 )
 
 type ValidationResult struct {
@@ -58,6 +59,9 @@ var DefaultValidationRuleSet = ValidationRuleSet{
 	&RuleValidateIPList{},
 	&RuleValidateEnforceTimeout{},
 	&RuleUpstreamAuth{},
+	&RuleFAPI{},
+	&RuleTenantIsolation{},
+	&RuleTokenization{},
 }
 
 func Validate(definition *APIDefinition, ruleSet ValidationRuleSet) ValidationResult {
@@ -250,3 +254,147 @@ func (r *RuleUpstreamAuth) Validate(apiDef *APIDefinition, validationResult *Val
 		validationResult.AppendError(ErrInvalidUpstreamOAuthAuthorizationType)
 	}
 }
+
+var (
+	// ErrFAPIAuthRequired is the error to return when a FAPI API doesn't authenticate access tokens.
+	ErrFAPIAuthRequired = errors.New("FAPI profile requires JWT, OAuth or external OAuth authentication")
+	// ErrFAPISenderConstraint is the error to return when the access tokens of a FAPI API aren't sender constrained.
+	ErrFAPISenderConstraint = errors.New("FAPI profile requires mutual TLS or required DPoP proofs")
+	// ErrFAPISigningMethod is the error to return when a FAPI API accepts tokens signed with a symmetric key.
+	ErrFAPISigningMethod = errors.New("FAPI profile requires rsa or ecdsa signing method")
+	// ErrFAPIQueryStringToken is the error to return when a FAPI API accepts access tokens in the query string.
+	ErrFAPIQueryStringToken = errors.New("FAPI profile doesn't allow access tokens in the query string")
+)
+
+// RuleFAPI implements validations for the FAPI profile.
+type RuleFAPI struct{}
+
+// Validate validates the api definition can meet the requirements of the FAPI profile.
+func (r *RuleFAPI) Validate(apiDef *APIDefinition, validationResult *ValidationResult) {
+	if !apiDef.FAPI.Enabled {
+		return
+	}
+
+	fail := func(err error) {
+		validationResult.IsValid = false
+		validationResult.AppendError(err)
+	}
+
+	if apiDef.UseKeylessAccess || !(apiDef.EnableJWT || apiDef.UseOauth2 || apiDef.ExternalOAuth.Enabled) {
+		fail(ErrFAPIAuthRequired)
+		return
+	}
+
+	// DPoP proofs are validated for JWT access tokens only
+	dpop := apiDef.DPoP.Enabled && apiDef.DPoP.Required && (apiDef.EnableJWT || apiDef.ExternalOAuth.Enabled)
+	if !apiDef.UseMutualTLSAuth && !dpop {
+		fail(ErrFAPISenderConstraint)
+	}
+
+	var signingMethods []string
+	if apiDef.EnableJWT {
+		signingMethods = append(signingMethods, apiDef.JWTSigningMethod)
+	}
+	if apiDef.ExternalOAuth.Enabled {
+		for _, provider := range apiDef.ExternalOAuth.Providers {
+			if provider.JWT.Enabled {
+				signingMethods = append(signingMethods, provider.JWT.SigningMethod)
+			}
+		}
+	}
+	for _, signingMethod := range signingMethods {
+		if signingMethod != "rsa" && signingMethod != "ecdsa" {
+			fail(ErrFAPISigningMethod)
+			break
+		}
+	}
+
+	for name, authConfig := range apiDef.AuthConfigs {
+		if authConfig.UseParam && (shouldValidateAuthSource(name, apiDef) || name == ExternalOAuthType && apiDef.ExternalOAuth.Enabled) {
+			fail(ErrFAPIQueryStringToken)
+			break
+		}
+	}
+}
+
+var (
+	// ErrTenantIsolationNoAllowedSuffix is the error to return when tenant isolation doesn't allow any upstream domain.
+	ErrTenantIsolationNoAllowedSuffix = errors.New("tenant isolation requires allowed host suffixes")
+	// ErrTenantIsolationInvalidTemplate is the error to return when the upstream template of tenant isolation can't be parsed.
+	ErrTenantIsolationInvalidTemplate = errors.New("invalid tenant isolation upstream template")
+)
+
+// RuleTenantIsolation implements validations for tenant isolation configurations.
+type RuleTenantIsolation struct{}
+
+// Validate validates api definition tenant isolation configurations.
+func (r *RuleTenantIsolation) Validate(apiDef *APIDefinition, validationResult *ValidationResult) {
+	conf := apiDef.TenantIsolation
+	if !conf.Enabled {
+		return
+	}
+
+	if len(conf.AllowedHostSuffixes) == 0 {
+		validationResult.IsValid = false
+		validationResult.AppendError(ErrTenantIsolationNoAllowedSuffix)
+	}
+
+	if _, err := template.New("upstream").Parse(conf.UpstreamTemplate); err != nil || conf.UpstreamTemplate == "" {
+		validationResult.IsValid = false
+		validationResult.AppendError(ErrTenantIsolationInvalidTemplate)
+	}
+}
+
+var (
+	// ErrTokenizationNoPath is the error to return when a tokenized field has no path.
+	ErrTokenizationNoPath = errors.New("tokenized fields require a path")
+	// ErrTokenizationInvalidMethod is the error to return when a tokenized field uses an unknown method.
+	ErrTokenizationInvalidMethod = errors.New("invalid tokenization method")
+	// ErrTokenizationInvalidAlphabet is the error to return when a tokenized field uses an unknown alphabet.
+	ErrTokenizationInvalidAlphabet = errors.New("invalid tokenization alphabet")
+	// ErrTokenizationNoKey is the error to return when fields are encrypted without a key.
+	ErrTokenizationNoKey = errors.New("format-preserving tokenization requires a key")
+	// ErrTokenizationNoVault is the error to return when fields are exchanged without a vault URL.
+	ErrTokenizationNoVault = errors.New("vault tokenization requires a vault URL")
+)
+
+// RuleTokenization implements validations for tokenization configurations.
+type RuleTokenization struct{}
+
+// Validate validates api definition tokenization configurations.
+func (r *RuleTokenization) Validate(apiDef *APIDefinition, validationResult *ValidationResult) {
+	conf := apiDef.Tokenization
+	if !conf.Enabled {
+		return
+	}
+
+	fail := func(err error) {
+		validationResult.IsValid = false
+		validationResult.AppendError(err)
+	}
+
+	for _, field := range conf.Fields {
+		if field.Path == "" {
+			fail(ErrTokenizationNoPath)
+		}
+
+		switch field.Alphabet {
+		case "", TokenizationAlphabetDigits, TokenizationAlphabetAlphanumeric:
+		default:
+			fail(ErrTokenizationInvalidAlphabet)
+		}
+
+		switch field.Method {
+		case TokenizationMethodFPE:
+			if conf.Key == "" {
+				fail(ErrTokenizationNoKey)
+			}
+		case TokenizationMethodVault:
+			if conf.Vault.URL == "" {
+				fail(ErrTokenizationNoVault)
+			}
+		default:
+			fail(ErrTokenizationInvalidMethod)
+		}
+	}
+}
@@ -508,3 +508,224 @@ func TestRuleUpstreamAuth_Validate(t *testing.T) {
 		t.Run(tc.name, runValidationTest(apiDef, ruleSet, tc.result))
 	}
 }
+
+func TestRuleFAPI_Validate(t *testing.T) {
+	ruleSet := ValidationRuleSet{
+		&RuleFAPI{},
+	}
+
+	fapiJWT := func(modify func(apiDef *APIDefinition)) *APIDefinition {
+		apiDef := &APIDefinition{
+			EnableJWT:        true,
+			JWTSigningMethod: "ecdsa",
+			UseMutualTLSAuth: true,
+			FAPI:             FAPI{Enabled: true},
+		}
+		if modify != nil {
+			modify(apiDef)
+		}
+		return apiDef
+	}
+
+	testCases := []struct {
+		name   string
+		apiDef *APIDefinition
+		result ValidationResult
+	}{
+		{
+			name:   "not enabled",
+			apiDef: &APIDefinition{UseKeylessAccess: true},
+			result: ValidationResult{IsValid: true},
+		},
+		{
+			name:   "JWT with mutual TLS",
+			apiDef: fapiJWT(nil),
+			result: ValidationResult{IsValid: true},
+		},
+		{
+			name: "JWT with required DPoP",
+			apiDef: fapiJWT(func(apiDef *APIDefinition) {
+				apiDef.UseMutualTLSAuth = false
+				apiDef.DPoP = DPoP{Enabled: true, Required: true}
+			}),
+			result: ValidationResult{IsValid: true},
+		},
+		{
+			name: "keyless",
+			apiDef: fapiJWT(func(apiDef *APIDefinition) {
+				apiDef.UseKeylessAccess = true
+			}),
+			result: ValidationResult{IsValid: false, Errors: []error{ErrFAPIAuthRequired}},
+		},
+		{
+			name: "not sender constrained",
+			apiDef: fapiJWT(func(apiDef *APIDefinition) {
+				apiDef.UseMutualTLSAuth = false
+				apiDef.DPoP = DPoP{Enabled: true}
+			}),
+			result: ValidationResult{IsValid: false, Errors: []error{ErrFAPISenderConstraint}},
+		},
+		{
+			name: "DPoP with OAuth tokens",
+			apiDef: fapiJWT(func(apiDef *APIDefinition) {
+				apiDef.EnableJWT = false
+				apiDef.UseOauth2 = true
+				apiDef.UseMutualTLSAuth = false
+				apiDef.DPoP = DPoP{Enabled: true, Required: true}
+			}),
+			result: ValidationResult{IsValid: false, Errors: []error{ErrFAPISenderConstraint}},
+		},
+		{
+			name: "HMAC signing method",
+			apiDef: fapiJWT(func(apiDef *APIDefinition) {
+				apiDef.JWTSigningMethod = "hmac"
+			}),
+			result: ValidationResult{IsValid: false, Errors: []error{ErrFAPISigningMethod}},
+		},
+		{
+			name: "external OAuth HMAC signing method",
+			apiDef: fapiJWT(func(apiDef *APIDefinition) {
+				apiDef.EnableJWT = false
+				apiDef.ExternalOAuth = ExternalOAuth{
+					Enabled:   true,
+					Providers: []Provider{{JWT: JWTValidation{Enabled: true, SigningMethod: "hmac"}}},
+				}
+			}),
+			result: ValidationResult{IsValid: false, Errors: []error{ErrFAPISigningMethod}},
+		},
+		{
+			name: "query string token",
+			apiDef: fapiJWT(func(apiDef *APIDefinition) {
+				apiDef.AuthConfigs = map[string]AuthConfig{
+					"authToken": {UseParam: true},
+					"jwt":       {UseParam: true},
+				}
+			}),
+			result: ValidationResult{IsValid: false, Errors: []error{ErrFAPIQueryStringToken}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, runValidationTest(tc.apiDef, ruleSet, tc.result))
+	}
+}
+
+func TestRuleTenantIsolation_Validate(t *testing.T) {
+	ruleSet := ValidationRuleSet{
+		&RuleTenantIsolation{},
+	}
+
+	testCases := []struct {
+		name            string
+		tenantIsolation TenantIsolation
+		result          ValidationResult
+	}{
+		{
+			name:            "not enabled",
+			tenantIsolation: TenantIsolation{UpstreamTemplate: "{{"},
+			result:          ValidationResult{IsValid: true},
+		},
+		{
+			name: "valid",
+			tenantIsolation: TenantIsolation{
+				Enabled:             true,
+				UpstreamTemplate:    "http://{{.OrgID}}.internal.svc",
+				AllowedHostSuffixes: []string{"internal.svc"},
+			},
+			result: ValidationResult{IsValid: true},
+		},
+		{
+			name: "no allowed suffix",
+			tenantIsolation: TenantIsolation{
+				Enabled:          true,
+				UpstreamTemplate: "http://{{.OrgID}}.internal.svc",
+			},
+			result: ValidationResult{IsValid: false, Errors: []error{ErrTenantIsolationNoAllowedSuffix}},
+		},
+		{
+			name: "invalid template",
+			tenantIsolation: TenantIsolation{
+				Enabled:             true,
+				UpstreamTemplate:    "http://{{.OrgID}.internal.svc",
+				AllowedHostSuffixes: []string{"internal.svc"},
+			},
+			result: ValidationResult{IsValid: false, Errors: []error{ErrTenantIsolationInvalidTemplate}},
+		},
+	}
+
+	for _, tc := range testCases {
+		apiDef := &APIDefinition{
+			TenantIsolation: tc.tenantIsolation,
+		}
+
+		t.Run(tc.name, runValidationTest(apiDef, ruleSet, tc.result))
+	}
+}
+
+func TestRuleTokenization_Validate(t *testing.T) {
+	ruleSet := ValidationRuleSet{
+		&RuleTokenization{},
+	}
+
+	testCases := []struct {
+		name         string
+		tokenization Tokenization
+		result       ValidationResult
+	}{
+		{
+			name:         "not enabled",
+			tokenization: Tokenization{Fields: []TokenizationField{{Method: "unknown"}}},
+			result:       ValidationResult{IsValid: true},
+		},
+		{
+			name: "valid",
+			tokenization: Tokenization{
+				Enabled: true,
+				Key:     "secrets://tokenization-key",
+				Vault:   TokenizationVault{URL: "http://vault.internal"},
+				Fields: []TokenizationField{
+					{Path: "card.pan", Method: TokenizationMethodFPE},
+					{Path: "iban", Method: TokenizationMethodFPE, Alphabet: TokenizationAlphabetAlphanumeric},
+					{Path: "ssn", Method: TokenizationMethodVault},
+				},
+			},
+			result: ValidationResult{IsValid: true},
+		},
+		{
+			name: "no path",
+			tokenization: Tokenization{
+				Enabled: true,
+				Key:     "2B7E151628AED2A6ABF7158809CF4F3C",
+				Fields:  []TokenizationField{{Method: TokenizationMethodFPE}},
+			},
+			result: ValidationResult{IsValid: false, Errors: []error{ErrTokenizationNoPath}},
+		},
+		{
+			name: "invalid method and alphabet",
+			tokenization: Tokenization{
+				Enabled: true,
+				Fields:  []TokenizationField{{Path: "pan", Method: "hash", Alphabet: "hex"}},
+			},
+			result: ValidationResult{IsValid: false, Errors: []error{ErrTokenizationInvalidAlphabet, ErrTokenizationInvalidMethod}},
+		},
+		{
+			name: "missing key and vault",
+			tokenization: Tokenization{
+				Enabled: true,
+				Fields: []TokenizationField{
+					{Path: "pan", Method: TokenizationMethodFPE},
+					{Path: "ssn", Method: TokenizationMethodVault},
+				},
+			},
+			result: ValidationResult{IsValid: false, Errors: []error{ErrTokenizationNoKey, ErrTokenizationNoVault}},
+		},
+	}
+
+	for _, tc := range testCases {
+		apiDef := &APIDefinition{
+			Tokenization: tc.tokenization,
+		}
+
+		t.Run(tc.name, runValidationTest(apiDef, ruleSet, tc.result))
+	}
+}
@@ -10,6 +10,8 @@ import (
 	kingpin "github.com/alecthomas/kingpin/v2"
 
 	"github.com/TykTechnologies/tyk/cli/bundler"
+	"github.com/TykTechnologies/tyk/cli/control"
+	"github.com/TykTechnologies/tyk/cli/definitions"
 	"github.com/TykTechnologies/tyk/cli/importer"
 	"github.com/TykTechnologies/tyk/cli/linter"
 	"github.com/TykTechnologies/tyk/cli/plugin"
@@ -76,8 +78,9 @@ func Init(confPaths []string) {
 	startCmd.Default()
 
 	// Linter:
-	lintCmd := app.Command("lint", "Runs a linter on Tyk configuration file")
-	lintCmd.Action(func(c *kingpin.ParseContext) error {
+	lintCmd := app.Command("lint", "Runs a linter on Tyk configuration file or API definitions")
+	lintConfigCmd := lintCmd.Command("config", "Runs a linter on Tyk configuration file").Default()
+	lintConfigCmd.Action(func(c *kingpin.ParseContext) error {
 		confSchema, err := ioutil.ReadFile("cli/linter/schema.json")
 		if err != nil {
 			return err
@@ -99,6 +102,9 @@ func Init(confPaths []string) {
 		return nil
 	})
 
+	// Add API definition lint and convert commands:
+	definitions.AddTo(app)
+
 	// Add version command:
 	version.AddTo(app)
 
@@ -110,6 +116,9 @@ func Init(confPaths []string) {
 
 	// Add plugin commands:
 	plugin.AddTo(app)
+
+	// Add Control API commands:
+	control.AddTo(app)
 }
 
 // Parse parses the command-line arguments.
@@ -0,0 +1,446 @@
+package control
+
+//lint:file-ignore faillint This file should be ignored by faillint (fmt in use).
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+
+	"github.com/TykTechnologies/tyk/header"
+)
+
+const (
+	defaultURL          = "http://localhost:8080"
+	defaultProfilesFile = ".tyk/profiles.json"
+	requestTimeout      = 30 * time.Second
+)
+
+var (
+	ctl = &Control{out: os.Stdout, in: os.Stdin}
+
+	errNoSecret       = errors.New("no control API secret, set --secret or add a profile with tyk profile add")
+	errUnknownProfile = errors.New("unknown profile")
+)
+
+// Profile holds the credentials of the Control API of a Gateway.
+type Profile struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// Profiles are the stored profiles.
+type Profiles struct {
+	// Default is the name of the profile used without --profile.
+	Default  string             `json:"default"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// Control holds the flags of the commands managing a running Gateway through its Control API.
+type Control struct {
+	// the connection flags are shared by the commands
+	profilesFile string
+	profile      string
+	url          string
+	secret       string
+
+	out io.Writer
+	in  io.Reader
+
+	profileName    *string
+	profileURL     *string
+	profileSecret  *string
+	profileDefault *bool
+
+	keyFile  *string
+	keysJSON *bool
+
+	apiID     *string
+	apiFile   *string
+	apiOutput *string
+	oas       *bool
+	reload    *bool
+
+	group *bool
+}
+
+// AddTo adds the profile, keys, apis and reload commands.
+func AddTo(app *kingpin.Application) {
+	connFlags := func(cmd *kingpin.CmdClause) {
+		cmd.Flag("profiles", "Profiles file").Envar("TYK_PROFILES").PlaceHolder("FILE").StringVar(&ctl.profilesFile)
+		cmd.Flag("profile", "Name of the profile to use").Envar("TYK_PROFILE").StringVar(&ctl.profile)
+		cmd.Flag("url", "Base URL of the Control API, overrides the profile").Envar("TYK_GW_URL").PlaceHolder("URL").StringVar(&ctl.url)
+		cmd.Flag("secret", "Control API secret, overrides the profile").Envar("TYK_GW_SECRET").StringVar(&ctl.secret)
+	}
+
+	profileCmd := app.Command("profile", "Manage the Control API profiles")
+	connFlags(profileCmd)
+	addCmd := profileCmd.Command("add", "Add or replace a profile")
+	ctl.profileName = addCmd.Arg("name", "Name of the profile").Required().String()
+	ctl.profileURL = addCmd.Flag("gw-url", "Base URL of the Control API").Default(defaultURL).String()
+	ctl.profileSecret = addCmd.Flag("gw-secret", "Control API secret").Required().String()
+	ctl.profileDefault = addCmd.Flag("default", "Use the profile by default").Bool()
+	addCmd.Action(ctl.AddProfile)
+	profileCmd.Command("list", "List the profiles").Action(ctl.ListProfiles)
+
+	keysCmd := app.Command("keys", "Manage the keys of a running Gateway")
+	connFlags(keysCmd)
+	createCmd := keysCmd.Command("create", "Create a key from a session JSON file, or the standard input")
+	ctl.keyFile = createCmd.Flag("file", "Session file").Short('f').Default("-").String()
+	createCmd.Action(ctl.CreateKey)
+	listCmd := keysCmd.Command("list", "List the keys")
+	ctl.keysJSON = listCmd.Flag("json", "Output in JSON format").Bool()
+	listCmd.Action(ctl.ListKeys)
+
+	apisCmd := app.Command("apis", "Manage the APIs of a running Gateway")
+	connFlags(apisCmd)
+	ctl.oas = apisCmd.Flag("oas", "Use Tyk OAS API definitions").Bool()
+	exportCmd := apisCmd.Command("export", "Export an API definition, or all of them")
+	ctl.apiID = exportCmd.Arg("api-id", "ID of the API").String()
+	ctl.apiOutput = exportCmd.Flag("output", "Output file").Short('o').Default("-").String()
+	exportCmd.Action(ctl.ExportAPIs)
+	importCmd := apisCmd.Command("import", "Import API definitions, creating or updating them")
+	ctl.apiFile = importCmd.Arg("file", "API definition file, or a JSON array of definitions").Required().String()
+	ctl.reload = importCmd.Flag("reload", "Reload the Gateway once imported").Bool()
+	importCmd.Action(ctl.ImportAPIs)
+
+	reloadCmd := app.Command("reload", "Reload a running Gateway")
+	connFlags(reloadCmd)
+	ctl.group = reloadCmd.Flag("group", "Reload all the Gateways of the cluster").Bool()
+	reloadCmd.Action(ctl.Reload)
+}
+
+func (c *Control) profilesPath() string {
+	if c.profilesFile != "" {
+		return c.profilesFile
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaultProfilesFile
+	}
+	return filepath.Join(home, defaultProfilesFile)
+}
+
+func (c *Control) loadProfiles() (*Profiles, error) {
+	profiles := &Profiles{Profiles: map[string]Profile{}}
+
+	data, err := os.ReadFile(c.profilesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, profiles); err != nil {
+		return nil, fmt.Errorf("couldn't parse profiles file: %w", err)
+	}
+	if profiles.Profiles == nil {
+		profiles.Profiles = map[string]Profile{}
+	}
+
+	return profiles, nil
+}
+
+// AddProfile stores a profile, the profiles file is only readable by its owner.
+func (c *Control) AddProfile(_ *kingpin.ParseContext) error {
+	profiles, err := c.loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	profiles.Profiles[*c.profileName] = Profile{URL: strings.TrimSuffix(*c.profileURL, "/"), Secret: *c.profileSecret}
+	if *c.profileDefault || profiles.Default == "" {
+		profiles.Default = *c.profileName
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	path := c.profilesPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// ListProfiles prints the profiles, the default profile is starred.
+func (c *Control) ListProfiles(_ *kingpin.ParseContext) error {
+	profiles, err := c.loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(profiles.Profiles))
+	for name := range profiles.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		marker := " "
+		if name == profiles.Default {
+			marker = "*"
+		}
+		fmt.Fprintf(c.out, "%s %s\t%s\n", marker, name, profiles.Profiles[name].URL)
+	}
+
+	return nil
+}
+
+// connection returns the profile in use, with the URL and secret flags applied.
+func (c *Control) connection() (Profile, error) {
+	profiles, err := c.loadProfiles()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name := profiles.Default
+	if c.profile != "" {
+		name = c.profile
+	}
+
+	conn, ok := profiles.Profiles[name]
+	if !ok && c.profile != "" {
+		return Profile{}, fmt.Errorf("%w %q", errUnknownProfile, name)
+	}
+
+	if c.url != "" {
+		conn.URL = strings.TrimSuffix(c.url, "/")
+	}
+	if c.secret != "" {
+		conn.Secret = c.secret
+	}
+	if conn.URL == "" {
+		conn.URL = defaultURL
+	}
+	if conn.Secret == "" {
+		return Profile{}, errNoSecret
+	}
+
+	return conn, nil
+}
+
+// do sends a request to the Control API and returns the response body, errors are returned
+// with the message of the Control API.
+func (c *Control) do(method, path string, body []byte) ([]byte, int, error) {
+	conn, err := c.connection()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequest(method, conn.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set(header.XTykAuthorization, conn.Secret)
+	if body != nil {
+		req.Header.Set(header.ContentType, header.ApplicationJSON)
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return data, resp.StatusCode, fmt.Errorf("%s %s: %s (%d)", method, path, apiErr.Message, resp.StatusCode)
+		}
+		return data, resp.StatusCode, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	return data, resp.StatusCode, nil
+}
+
+func (c *Control) readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(c.in)
+	}
+	return os.ReadFile(path)
+}
+
+func (c *Control) writeJSON(path string, data []byte) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "    "); err != nil {
+		out.Reset()
+		out.Write(data)
+	}
+	out.WriteByte('\n')
+
+	if path == "" || path == "-" {
+		_, err := c.out.Write(out.Bytes())
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0o644)
+}
+
+// CreateKey creates a key and prints the response of the Gateway, with the key.
+func (c *Control) CreateKey(_ *kingpin.ParseContext) error {
+	session, err := c.readInput(*c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	data, _, err := c.do(http.MethodPost, "/tyk/keys", session)
+	if err != nil {
+		return err
+	}
+
+	return c.writeJSON("-", data)
+}
+
+// ListKeys prints the keys, one per line.
+func (c *Control) ListKeys(_ *kingpin.ParseContext) error {
+	data, _, err := c.do(http.MethodGet, "/tyk/keys", nil)
+	if err != nil {
+		return err
+	}
+
+	if *c.keysJSON {
+		return c.writeJSON("-", data)
+	}
+
+	var keys struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+
+	for _, key := range keys.Keys {
+		fmt.Fprintln(c.out, key)
+	}
+
+	return nil
+}
+
+func (c *Control) apisPath() string {
+	if *c.oas {
+		return "/tyk/apis/oas"
+	}
+	return "/tyk/apis"
+}
+
+// ExportAPIs writes an API definition, or all of them as a JSON array.
+func (c *Control) ExportAPIs(_ *kingpin.ParseContext) error {
+	path := c.apisPath()
+	if *c.apiID != "" {
+		path += "/" + url.PathEscape(*c.apiID)
+	}
+
+	data, _, err := c.do(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.writeJSON(*c.apiOutput, data)
+}
+
+// apiID returns the ID of a classic or Tyk OAS API definition.
+func apiID(def map[string]json.RawMessage) string {
+	var id string
+	if raw, ok := def["api_id"]; ok {
+		_ = json.Unmarshal(raw, &id)
+		return id
+	}
+
+	var ext struct {
+		Info struct {
+			ID string `json:"id"`
+		} `json:"info"`
+	}
+	if raw, ok := def["x-tyk-api-gateway"]; ok {
+		_ = json.Unmarshal(raw, &ext)
+	}
+	return ext.Info.ID
+}
+
+// ImportAPIs creates the API definitions of a file, or updates them when they exist.
+func (c *Control) ImportAPIs(ctx *kingpin.ParseContext) error {
+	data, err := c.readInput(*c.apiFile)
+	if err != nil {
+		return err
+	}
+
+	var defs []json.RawMessage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &defs); err != nil {
+			return err
+		}
+	} else {
+		defs = []json.RawMessage{data}
+	}
+
+	for _, def := range defs {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(def, &fields); err != nil {
+			return err
+		}
+
+		id := apiID(fields)
+		method, path := http.MethodPost, c.apisPath()
+		if id != "" {
+			if _, code, _ := c.do(http.MethodGet, path+"/"+url.PathEscape(id), nil); code == http.StatusOK {
+				method, path = http.MethodPut, path+"/"+url.PathEscape(id)
+			}
+		}
+
+		resp, _, err := c.do(method, path, def)
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Key    string `json:"key"`
+			Action string `json:"action"`
+		}
+		_ = json.Unmarshal(resp, &result)
+		fmt.Fprintf(c.out, "%s %s\n", result.Action, result.Key)
+	}
+
+	if *c.reload {
+		return c.Reload(ctx)
+	}
+
+	return nil
+}
+
+// Reload reloads the Gateway, or the Gateways of the cluster with --group.
+func (c *Control) Reload(_ *kingpin.ParseContext) error {
+	path := "/tyk/reload?block=true"
+	if c.group != nil && *c.group {
+		path = "/tyk/reload/group"
+	}
+
+	if _, _, err := c.do(http.MethodGet, path, nil); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.out, "reloaded")
+	return nil
+}
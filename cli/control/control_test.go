@@ -0,0 +1,140 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGateway is a Control API with an API and a key.
+type fakeGateway struct {
+	requests []string
+	apis     map[string]string
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Tyk-Authorization") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"status":"error","message":"Attempted administrative access with invalid or missing key!"}`))
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	g.requests = append(g.requests, r.Method+" "+r.URL.RequestURI())
+
+	switch {
+	case r.URL.Path == "/tyk/keys" && r.Method == http.MethodGet:
+		_, _ = w.Write([]byte(`{"keys":["key-1","key-2"]}`))
+	case r.URL.Path == "/tyk/keys" && r.Method == http.MethodPost:
+		_, _ = w.Write([]byte(`{"key":"new-key","status":"ok","action":"added"}`))
+	case strings.HasPrefix(r.URL.Path, "/tyk/apis/"):
+		id := strings.TrimPrefix(r.URL.Path, "/tyk/apis/")
+		def, ok := g.apis[id]
+		switch {
+		case r.Method == http.MethodGet && ok:
+			_, _ = w.Write([]byte(def))
+		case r.Method == http.MethodPut && ok:
+			g.apis[id] = string(body)
+			_, _ = w.Write([]byte(`{"key":"` + id + `","status":"ok","action":"modified"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":"error","message":"API not found"}`))
+		}
+	case r.URL.Path == "/tyk/apis" && r.Method == http.MethodPost:
+		_, _ = w.Write([]byte(`{"key":"created","status":"ok","action":"added"}`))
+	case r.URL.Path == "/tyk/reload":
+		_, _ = w.Write([]byte(`{"status":"ok","message":""}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func run(t *testing.T, in string, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	ctl = &Control{out: &out, in: strings.NewReader(in)}
+
+	app := kingpin.New("tyk", "")
+	AddTo(app)
+	_, err := app.Parse(args)
+
+	return out.String(), err
+}
+
+func TestControl(t *testing.T) {
+	gw := &fakeGateway{apis: map[string]string{"existing": `{"api_id":"existing","name":"Existing"}`}}
+	server := httptest.NewServer(gw)
+	defer server.Close()
+
+	profiles := filepath.Join(t.TempDir(), "profiles.json")
+
+	_, err := run(t, "", "keys", "list", "--profiles", profiles, "--url", server.URL)
+	assert.ErrorIs(t, err, errNoSecret)
+
+	_, err = run(t, "", "profile", "add", "local", "--profiles", profiles, "--gw-url", server.URL+"/", "--gw-secret", "secret")
+	require.NoError(t, err)
+
+	info, err := os.Stat(profiles)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	out, err := run(t, "", "profile", "list", "--profiles", profiles)
+	require.NoError(t, err)
+	assert.Equal(t, "* local\t"+server.URL+"\n", out)
+
+	t.Run("keys", func(t *testing.T) {
+		out, err := run(t, "", "keys", "list", "--profiles", profiles)
+		require.NoError(t, err)
+		assert.Equal(t, "key-1\nkey-2\n", out)
+
+		out, err = run(t, `{"rate": 10, "per": 1}`, "keys", "create", "--profiles", profiles)
+		require.NoError(t, err)
+		assert.Contains(t, out, `"key": "new-key"`)
+
+		_, err = run(t, "", "keys", "list", "--profiles", profiles, "--secret", "wrong")
+		assert.ErrorContains(t, err, "invalid or missing key")
+
+		_, err = run(t, "", "keys", "list", "--profiles", profiles, "--profile", "missing")
+		assert.ErrorIs(t, err, errUnknownProfile)
+	})
+
+	t.Run("apis", func(t *testing.T) {
+		out, err := run(t, "", "apis", "export", "existing", "--profiles", profiles)
+		require.NoError(t, err)
+
+		var def map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(out), &def))
+		assert.Equal(t, "Existing", def["name"])
+
+		file := filepath.Join(t.TempDir(), "apis.json")
+		require.NoError(t, os.WriteFile(file, []byte(`[{"api_id":"existing","name":"Updated"},{"api_id":"new","name":"New"}]`), 0o644))
+
+		gw.requests = nil
+		out, err = run(t, "", "apis", "import", file, "--reload", "--profiles", profiles)
+		require.NoError(t, err)
+		assert.Equal(t, "modified existing\nadded created\nreloaded\n", out)
+		assert.Equal(t, []string{
+			"GET /tyk/apis/existing", "PUT /tyk/apis/existing",
+			"GET /tyk/apis/new", "POST /tyk/apis",
+			"GET /tyk/reload?block=true",
+		}, gw.requests)
+		assert.Contains(t, gw.apis["existing"], "Updated")
+	})
+
+	t.Run("oas id", func(t *testing.T) {
+		assert.Equal(t, "oas-id", apiID(map[string]json.RawMessage{
+			"x-tyk-api-gateway": json.RawMessage(`{"info":{"id":"oas-id"}}`),
+		}))
+	})
+}
@@ -0,0 +1,445 @@
+package definitions
+
+//lint:file-ignore faillint This file should be ignored by faillint (fmt in use).
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/hashicorp/go-multierror"
+	schema "github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+
+	typeClassic = "classic"
+	typeOAS     = "oas"
+)
+
+var (
+	defs = &Definitions{out: os.Stdout}
+
+	errNoDefinitions   = errors.New("no API definitions found")
+	errInvalid         = errors.New("invalid API definitions found")
+	errConversion      = errors.New("API definitions failed to convert")
+	errNoTykExtension  = errors.New("the OAS document doesn't have the " + oas.ExtensionTykAPIGateway + " extension")
+	errUnknownDocument = errors.New("not an API definition")
+)
+
+// Definitions holds the flags of the commands working on API definition files, offline.
+type Definitions struct {
+	out io.Writer
+
+	lintPaths  *[]string
+	lintFormat *string
+
+	convertPaths  *[]string
+	convertTo     *string
+	convertOutput *string
+	convertFormat *string
+}
+
+// LintResult is the result of linting an API definition file.
+type LintResult struct {
+	Path   string   `json:"path"`
+	Type   string   `json:"type,omitempty"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ConvertResult is the result of converting an API definition file.
+type ConvertResult struct {
+	Path string `json:"path"`
+	Type string `json:"type,omitempty"`
+	// Outputs are the written files, an OAS API for each version of a versioned classic API.
+	Outputs []string `json:"outputs,omitempty"`
+	// Skipped is set for API definitions which already have the requested type.
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AddTo adds the `lint apis` and `convert` commands. The lint command must have been added.
+func AddTo(app *kingpin.Application) {
+	formatFlag := func(cmd *kingpin.CmdClause) *string {
+		return cmd.Flag("format", "output format, text or json").Default(formatText).Enum(formatText, formatJSON)
+	}
+
+	lintCmd := app.GetCommand("lint").Command("apis", "Lints classic and Tyk OAS API definition files, offline")
+	defs.lintPaths = lintCmd.Arg("paths", "API definition files or directories").Required().Strings()
+	defs.lintFormat = formatFlag(lintCmd)
+	lintCmd.Action(defs.Lint)
+
+	convertCmd := app.Command("convert", "Converts classic API definition files to Tyk OAS, or Tyk OAS to classic, offline")
+	defs.convertPaths = convertCmd.Arg("paths", "API definition files or directories").Required().Strings()
+	defs.convertTo = convertCmd.Flag("to", "the type to convert to, oas or classic").Required().Enum(typeOAS, typeClassic)
+	defs.convertOutput = convertCmd.Flag("output", "the directory the converted API definitions are written to").Short('o').Required().String()
+	defs.convertFormat = formatFlag(convertCmd)
+	convertCmd.Action(defs.Convert)
+}
+
+// Lint lints the API definitions, it fails when any of them is invalid.
+func (d *Definitions) Lint(_ *kingpin.ParseContext) error {
+	files, err := collectFiles(*d.lintPaths)
+	if err != nil {
+		return err
+	}
+
+	results := make([]LintResult, 0, len(files))
+	valid := true
+	for _, file := range files {
+		result := lintFile(file)
+		valid = valid && result.Valid
+		results = append(results, result)
+	}
+
+	if *d.lintFormat == formatJSON {
+		if err := writeJSON(d.out, results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			if result.Valid {
+				fmt.Fprintf(d.out, "%s: ok\n", result.Path)
+				continue
+			}
+			for _, msg := range result.Errors {
+				fmt.Fprintf(d.out, "%s: %s\n", result.Path, msg)
+			}
+		}
+	}
+
+	if !valid {
+		return errInvalid
+	}
+	return nil
+}
+
+// Convert converts the API definitions, it fails when any of them can't be converted.
+func (d *Definitions) Convert(_ *kingpin.ParseContext) error {
+	files, err := collectFiles(*d.convertPaths)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*d.convertOutput, 0o755); err != nil {
+		return err
+	}
+
+	results := make([]ConvertResult, 0, len(files))
+	converted := true
+	for _, file := range files {
+		result := convertFile(file, *d.convertTo, *d.convertOutput)
+		converted = converted && result.Error == ""
+		results = append(results, result)
+	}
+
+	if *d.convertFormat == formatJSON {
+		if err := writeJSON(d.out, results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			switch {
+			case result.Error != "":
+				fmt.Fprintf(d.out, "%s: %s\n", result.Path, result.Error)
+			case result.Skipped:
+				fmt.Fprintf(d.out, "%s: skipped, already %s\n", result.Path, result.Type)
+			default:
+				fmt.Fprintf(d.out, "%s: %s\n", result.Path, strings.Join(result.Outputs, ", "))
+			}
+		}
+	}
+
+	if !converted {
+		return errConversion
+	}
+	return nil
+}
+
+// collectFiles returns the JSON and YAML files of the paths, walking the directories.
+func collectFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var dirFiles []string
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && isDefinitionFile(file) {
+				dirFiles = append(dirFiles, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+
+	if len(files) == 0 {
+		return nil, errNoDefinitions
+	}
+	return files, nil
+}
+
+func isDefinitionFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// readDefinition reads an API definition file, as JSON, and returns its type.
+func readDefinition(path string) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, "", err
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, "", err
+	}
+
+	if _, ok := fields["openapi"]; ok {
+		return data, typeOAS, nil
+	}
+	if _, ok := fields["proxy"]; ok {
+		return data, typeClassic, nil
+	}
+	if _, ok := fields["api_id"]; ok {
+		return data, typeClassic, nil
+	}
+	return nil, "", errUnknownDocument
+}
+
+func lintFile(path string) LintResult {
+	result := LintResult{Path: path}
+
+	data, typ, err := readDefinition(path)
+	if err != nil {
+		result.Errors = []string{err.Error()}
+		return result
+	}
+	result.Type = typ
+
+	if typ == typeOAS {
+		result.Errors = lintOAS(data)
+	} else {
+		result.Errors = lintClassic(data)
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+func lintOAS(data []byte) []string {
+	var version struct {
+		OpenAPI string `json:"openapi"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return []string{err.Error()}
+	}
+
+	if err := oas.ValidateOASObject(data, version.OpenAPI); err != nil {
+		return errorStrings(err)
+	}
+
+	var doc oas.OAS
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []string{err.Error()}
+	}
+
+	if doc.GetTykExtension() == nil {
+		return []string{errNoTykExtension.Error()}
+	}
+
+	var api apidef.APIDefinition
+	doc.ExtractTo(&api)
+	return validateDefinition(&api)
+}
+
+func lintClassic(data []byte) []string {
+	result, err := schema.Validate(schema.NewStringLoader(apidef.Schema), schema.NewBytesLoader(data))
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var errs []string
+	for _, resultErr := range result.Errors() {
+		errs = append(errs, resultErr.String())
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	var api apidef.APIDefinition
+	if err := json.Unmarshal(data, &api); err != nil {
+		return []string{err.Error()}
+	}
+	return validateDefinition(&api)
+}
+
+// validateDefinition runs the validation rules the Gateway applies to the API definitions it's sent.
+func validateDefinition(api *apidef.APIDefinition) []string {
+	result := apidef.Validate(api, apidef.DefaultValidationRuleSet)
+	if !result.HasErrors() {
+		return nil
+	}
+	return result.ErrorStrings()
+}
+
+func errorStrings(err error) []string {
+	var merr *multierror.Error
+	if !errors.As(err, &merr) {
+		return []string{err.Error()}
+	}
+
+	errs := make([]string, 0, len(merr.Errors))
+	for _, e := range merr.Errors {
+		errs = append(errs, e.Error())
+	}
+	return errs
+}
+
+func convertFile(path, to, outputDir string) ConvertResult {
+	result := ConvertResult{Path: path}
+
+	data, typ, err := readDefinition(path)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Type = typ
+
+	if typ == to {
+		result.Skipped = true
+		return result
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var outputs map[string]interface{}
+	if to == typeOAS {
+		outputs, err = convertToOAS(data, name)
+	} else {
+		outputs, err = convertToClassic(data, name)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	for _, file := range sortedKeys(outputs) {
+		output := filepath.Join(outputDir, file)
+		if err := writeJSONFile(output, outputs[file]); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Outputs = append(result.Outputs, output)
+	}
+
+	return result
+}
+
+// convertToOAS converts a classic API definition, its versions become separate OAS APIs.
+func convertToOAS(data []byte, name string) (map[string]interface{}, error) {
+	var api apidef.APIDefinition
+	if err := json.Unmarshal(data, &api); err != nil {
+		return nil, err
+	}
+
+	base, versions, err := oas.MigrateAndFillOAS(&api)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]interface{}{name + ".json": base.OAS}
+	for _, version := range versions {
+		outputs[name+"-"+url.PathEscape(version.Classic.VersionName)+".json"] = version.OAS
+	}
+	return outputs, nil
+}
+
+func convertToClassic(data []byte, name string) (map[string]interface{}, error) {
+	var doc oas.OAS
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.GetTykExtension() == nil {
+		return nil, errNoTykExtension
+	}
+
+	var api apidef.APIDefinition
+	doc.ExtractTo(&api)
+	api.IsOAS = false
+
+	return map[string]interface{}{name + ".json": &api}, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(v)
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSON(f, v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,93 @@
+package definitions
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const classicAPI = `{
+	"name": "Classic",
+	"api_id": "classic",
+	"org_id": "default",
+	"use_keyless": true,
+	"active": true,
+	"proxy": {"listen_path": "/classic/", "target_url": "http://localhost:8080", "strip_listen_path": true},
+	"version_data": {"not_versioned": true, "versions": {"Default": {"name": "Default"}}}
+}`
+
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	defs = &Definitions{out: &out}
+
+	app := kingpin.New("tyk", "")
+	app.Command("lint", "")
+	AddTo(app)
+	_, err := app.Parse(args)
+
+	return out.String(), err
+}
+
+func TestLintAndConvert(t *testing.T) {
+	dir := t.TempDir()
+	apis := filepath.Join(dir, "apis")
+	require.NoError(t, os.MkdirAll(apis, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(apis, "classic.json"), []byte(classicAPI), 0o644))
+
+	out, err := run(t, "lint", "apis", apis)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(apis, "classic.json")+": ok\n", out)
+
+	oasDir := filepath.Join(dir, "oas")
+	out, err = run(t, "convert", apis, "--to", "oas", "--output", oasDir, "--format", "json")
+	require.NoError(t, err)
+
+	var converted []ConvertResult
+	require.NoError(t, json.Unmarshal([]byte(out), &converted))
+	require.Len(t, converted, 1)
+	assert.Equal(t, []string{filepath.Join(oasDir, "classic.json")}, converted[0].Outputs)
+
+	_, err = run(t, "lint", "apis", oasDir)
+	require.NoError(t, err)
+
+	out, err = run(t, "convert", oasDir, apis, "--to", "classic", "--output", filepath.Join(dir, "classic"))
+	require.NoError(t, err)
+	assert.Contains(t, out, filepath.Join(apis, "classic.json")+": skipped, already classic\n")
+
+	data, err := os.ReadFile(filepath.Join(dir, "classic", "classic.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"listen_path": "/classic/"`)
+	assert.NotContains(t, string(data), `"is_oas": true`)
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid")
+		require.NoError(t, os.MkdirAll(invalid, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(invalid, "classic.yaml"), []byte("api_id: yaml\nproxy:\n  listen_path: /yaml/\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(invalid, "oas.json"), []byte(`{"openapi":"3.0.3","info":{"title":"OAS","version":"1"},"paths":{}}`), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(invalid, "other.json"), []byte(`{}`), 0o644))
+
+		out, err := run(t, "lint", "apis", invalid, "--format", "json")
+		assert.ErrorIs(t, err, errInvalid)
+
+		var results []LintResult
+		require.NoError(t, json.Unmarshal([]byte(out), &results))
+		require.Len(t, results, 3)
+
+		assert.Equal(t, typeClassic, results[0].Type)
+		assert.Contains(t, results[0].Errors, "(root): name is required")
+		assert.Equal(t, typeOAS, results[1].Type)
+		assert.NotEmpty(t, results[1].Errors)
+		assert.Equal(t, []string{errUnknownDocument.Error()}, results[2].Errors)
+
+		_, err = run(t, "convert", filepath.Join(invalid, "oas.json"), "--to", "classic", "--output", filepath.Join(dir, "out"))
+		assert.ErrorIs(t, err, errConversion)
+	})
+}
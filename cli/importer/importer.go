@@ -19,7 +19,7 @@ import (
 
 const (
 	cmdName = "import"
-	cmdDesc = "Imports a BluePrint/Swagger/WSDL file"
+	cmdDesc = "Imports a BluePrint/Swagger/WSDL/Postman/HAR file"
 )
 
 var (
@@ -34,6 +34,8 @@ type Importer struct {
 	swaggerMode    *bool
 	bluePrintMode  *bool
 	wsdlMode       *bool
+	postmanMode    *bool
+	harMode        *bool
 	portNames      *string
 	createAPI      *bool
 	orgID          *string
@@ -50,6 +52,8 @@ func AddTo(app *kingpin.Application) {
 	imp.swaggerMode = cmd.Flag("swagger", "Use Swagger mode").Bool()
 	imp.bluePrintMode = cmd.Flag("blueprint", "Use BluePrint mode").Bool()
 	imp.wsdlMode = cmd.Flag("wsdl", "Use WSDL mode").Bool()
+	imp.postmanMode = cmd.Flag("postman", "Use Postman collection mode").Bool()
+	imp.harMode = cmd.Flag("har", "Use HAR capture mode, the upstream target defaults to the captured origin").Bool()
 	imp.portNames = cmd.Flag("port-names", "Specify port name of each service in the WSDL file. Input format is comma separated list of serviceName:portName").String()
 	imp.createAPI = cmd.Flag("create-api", "Creates a new API definition from the blueprint").Bool()
 	imp.orgID = cmd.Flag("org-id", "assign the API Definition to this org_id (required with create-api").String()
@@ -77,6 +81,16 @@ func (i *Importer) Import(_ *kingpin.ParseContext) (err error) {
 		if err != nil {
 			log.Fatal(err)
 		}
+	} else if *i.postmanMode {
+		err = i.handleExamplesMode(importer.PostmanSource)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else if *i.harMode {
+		err = i.handleExamplesMode(importer.HARSource)
+		if err != nil {
+			log.Fatal(err)
+		}
 	} else {
 		log.Fatal(errUnknownMode)
 	}
@@ -273,6 +287,54 @@ func (i *Importer) handleWSDLMode() error {
 	return nil
 }
 
+// handleExamplesMode imports sources made of request and response examples, such as
+// Postman collections and HAR captures.
+func (i *Importer) handleExamplesMode(source importer.APIImporterSource) error {
+	var def *apidef.APIDefinition
+
+	if *i.createAPI {
+		if *i.orgID == "" || (*i.upstreamTarget == "" && source != importer.HARSource) {
+			return fmt.Errorf("no upstream target or org ID defined, these are both required")
+		}
+	} else if err := i.validateInput(); err != nil {
+		return err
+	}
+
+	src, err := i.loadFile(source, *i.input)
+	if err != nil {
+		return fmt.Errorf("file load error: %w", err)
+	}
+
+	if *i.createAPI {
+		def, err = src.ToAPIDefinition(*i.orgID, *i.upstreamTarget, *i.asMock)
+		if err != nil {
+			return fmt.Errorf("failed to create API Definition from file: %w", err)
+		}
+	} else {
+		def, err = i.apiDefLoadFile(*i.forAPI)
+		if err != nil {
+			return fmt.Errorf("failed to load and decode file data for API Definition: %w", err)
+		}
+
+		versionData, err := src.ConvertIntoApiVersion(*i.asMock)
+		if err != nil {
+			return fmt.Errorf("conversion into API Def failed: %w", err)
+		}
+
+		if def.VersionData.Versions == nil {
+			def.VersionData.Versions = make(map[string]apidef.VersionInfo)
+		}
+
+		if err := src.InsertIntoAPIDefinitionAsVersion(versionData, def, *i.asVersion); err != nil {
+			return fmt.Errorf("insertion failed: %w", err)
+		}
+	}
+
+	i.printDef(def)
+
+	return nil
+}
+
 func (i *Importer) printDef(def *apidef.APIDefinition) {
 	asJSON, err := json.MarshalIndent(def, "", "    ")
 	if err != nil {
@@ -339,6 +401,25 @@ func (i *Importer) bluePrintLoadFile(path string) (*importer.BluePrintAST, error
 	return blueprint.(*importer.BluePrintAST), nil
 }
 
+func (i *Importer) loadFile(source importer.APIImporterSource, path string) (importer.APIImporter, error) {
+	src, err := importer.GetImporterForSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := src.LoadFrom(f); err != nil {
+		return nil, err
+	}
+
+	return src, nil
+}
+
 func (i *Importer) apiDefLoadFile(path string) (*apidef.APIDefinition, error) {
 	f, err := os.Open(path)
 	if err != nil {
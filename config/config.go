@@ -75,6 +75,47 @@ const (
 	DefaultOTelResourceName = "tyk-gateway"
 )
 
+// KeyFormat describes the format of generated API keys, e.g. `sk_live_...` keys which
+// secret scanners can detect. Formatted keys aren't encoded with their organisation and
+// hash algorithm.
+type KeyFormat struct {
+	// Prefix is prepended to generated keys.
+	Prefix string `json:"prefix"`
+	// Length is the number of random characters of keys. Defaults to 32.
+	Length int `json:"length"`
+	// Alphabet holds the characters keys are made of. Defaults to alphanumeric characters.
+	Alphabet string `json:"alphabet"`
+	// Checksum appends a 6 characters CRC32 checksum to keys, so scanners can tell keys
+	// from random strings.
+	Checksum bool `json:"checksum"`
+}
+
+// Enabled tells if keys are formatted.
+func (k KeyFormat) Enabled() bool {
+	return k.Prefix != "" || k.Length > 0 || k.Alphabet != "" || k.Checksum
+}
+
+// KeyFormatConfig configures the generation of API keys.
+type KeyFormatConfig struct {
+	KeyFormat
+
+	// OrgFormats overrides the key format for the organisations of the map keys.
+	OrgFormats map[string]KeyFormat `json:"org_formats"`
+
+	// Plugin configures a Go plugin generating keys instead of the gateway.
+	Plugin KeyGeneratorPlugin `json:"plugin"`
+}
+
+// KeyGeneratorPlugin configures a Go plugin function generating API keys. The function
+// must have the `func(orgID string) string` signature, keys are generated by the gateway
+// when it returns an empty key.
+type KeyGeneratorPlugin struct {
+	// Path is the path of the plugin shared object.
+	Path string `json:"path"`
+	// FuncName is the name of the function symbol.
+	FuncName string `json:"func_name"`
+}
+
 type PoliciesConfig struct {
 	// Set this value to `file` to look in the file system for a definition file. Set to `service` to use the Dashboard service.
 	PolicySource string `json:"policy_source"`
@@ -102,6 +143,15 @@ type PoliciesConfig struct {
 	PolicyPath string `json:"policy_path"`
 }
 
+// DefinitionHistoryConfig configures the revision history of API definitions and policies.
+type DefinitionHistoryConfig struct {
+	// Enabled enables recording a revision whenever an API definition or a policy is
+	// added, modified or deleted through the Gateway API.
+	Enabled bool `json:"enabled"`
+	// MaxRevisions is the number of revisions kept for every API definition and policy. Defaults to 10.
+	MaxRevisions int `json:"max_revisions"`
+}
+
 type DBAppConfOptionsConfig struct {
 	// Set the URL to your Dashboard instance (or a load balanced instance). The URL needs to be formatted as: `http://dashboard_host:port`
 	ConnectionString string `json:"connection_string"`
@@ -117,6 +167,19 @@ type DBAppConfOptionsConfig struct {
 	Tags []string `json:"tags"`
 }
 
+// StorageInstrumentationConfig configures the instrumentation of the commands sent to Redis, used
+// to find hot keys and slow Lua scripts.
+type StorageInstrumentationConfig struct {
+	// Enabled records per-command latency histograms, listed by the `/tyk/debug/storage` endpoint
+	// and sent to StatsD when it's enabled.
+	Enabled bool `json:"enabled"`
+	// SlowThreshold is the duration, in milliseconds, above which commands are logged as slow.
+	// Defaults to 50.
+	SlowThreshold float64 `json:"slow_threshold"`
+	// SlowLogSize is the number of the last slow commands kept for the debug endpoint. Defaults to 100.
+	SlowLogSize int `json:"slow_log_size"`
+}
+
 type StorageOptionsConf struct {
 	// This should be set to `redis` (lowercase)
 	Type string `json:"type"`
@@ -241,6 +304,18 @@ type AnalyticsConfigConfig struct {
 	// This is especially useful when `storage.enable_cluster` is set to `true` since it will distribute the analytic keys across all the cluster nodes.
 	EnableMultipleAnalyticsKeys bool `json:"enable_multiple_analytics_keys"`
 
+	// AnalyticsKeyShards is the number of analytics keys the records are divided in when
+	// `enable_multiple_analytics_keys` is enabled, the maximum when the number is auto-tuned. Default: 10.
+	AnalyticsKeyShards int `json:"analytics_key_shards"`
+
+	// AutoTuneAnalyticsKeyShards adjusts the number of analytics keys to the throughput of analytics records,
+	// up to `analytics_key_shards`. The number of keys in use is published in Redis, for purgers to discover it.
+	AutoTuneAnalyticsKeyShards bool `json:"auto_tune_analytics_key_shards"`
+
+	// AnalyticsKeyShardThroughput is the number of records per second written to each analytics key when
+	// auto-tuning the number of keys. Default: 1000.
+	AnalyticsKeyShardThroughput int `json:"analytics_key_shard_throughput"`
+
 	// You can set the interval length on how often the tyk Gateway will purge analytics data. This value is in seconds and defaults to 10 seconds.
 	PurgeInterval float32 `json:"purge_interval"`
 
@@ -248,6 +323,128 @@ type AnalyticsConfigConfig struct {
 
 	// Determines the serialization engine for analytics. Available options: msgpack, and protobuf. By default, msgpack.
 	SerializerType string `json:"serializer_type"`
+
+	// PurgeSerializerType determines the serialization of the records shipped by the analytics purger.
+	// Available options: json, and protobuf, which cuts the size of the payloads and requires the
+	// backend to support it. By default, json.
+	PurgeSerializerType string `json:"purge_serializer_type"`
+
+	// RPCAggregation configures shipping per-minute rollups of the analytics to MDCB, or to the purger backend.
+	RPCAggregation RPCAnalyticsAggregationConfig `json:"rpc_aggregation"`
+
+	// PurgeBatch caps the batches of records shipped by the analytics purger.
+	PurgeBatch AnalyticsPurgeBatchConfig `json:"purge_batch"`
+
+	// PurgeSpill buffers the analytics which fail to ship on disk, until the purger backend is
+	// reachable again.
+	PurgeSpill AnalyticsPurgeSpillConfig `json:"purge_spill"`
+
+	// PurgeConcurrency is the number of analytics keys the purger drains in parallel, for the
+	// gateways emitting many records. Default: 1, the keys are drained one after the other.
+	PurgeConcurrency int `json:"purge_concurrency"`
+
+	// PurgeStallThreshold is the number of seconds without a successful purge after which the
+	// analytics purger is reported failing by the health check. Default: 6 purge intervals, and
+	// at least 60 seconds.
+	PurgeStallThreshold int64 `json:"purge_stall_threshold"`
+
+	// PurgerBackend selects where the analytics records are shipped to. The records are shipped to
+	// MDCB when `type` is `rpc`, self-managed installations can ship them to Kafka or to an HTTP endpoint.
+	PurgerBackend AnalyticsPurgerBackendConfig `json:"purger_backend"`
+}
+
+// AnalyticsPurgeSpillConfig configures the disk spill of the analytics purger. The payloads which
+// fail to ship, because MDCB or the purger backend is down, are written to files of the spill
+// directory instead of being requeued in Redis, and shipped first once the backend is reachable.
+type AnalyticsPurgeSpillConfig struct {
+	// Path is the spill directory. The spill is disabled when empty.
+	Path string `json:"path"`
+	// MaxBytes caps the size of the spilled payloads, the oldest are dropped over the cap. Default: 512MB.
+	MaxBytes int64 `json:"max_bytes"`
+	// TTL is the number of seconds the spilled payloads are kept for. Default: 86400.
+	TTL int64 `json:"ttl"`
+}
+
+// AnalyticsPurgeBatchConfig configures the batches of records shipped by the analytics purger. The
+// records of the batches which fail to ship are pushed back to Redis, for the next purge to ship
+// them, until they expire after `storage_expiration_time`.
+type AnalyticsPurgeBatchConfig struct {
+	// MaxRecords is the maximum number of records of a batch. Default: 1000.
+	MaxRecords int `json:"max_records"`
+
+	// MaxBytes is the maximum size of a batch in bytes. Default: 4194304 (4MB).
+	MaxBytes int `json:"max_bytes"`
+
+	// DisableRequeue drops the records of the batches which fail to ship.
+	DisableRequeue bool `json:"disable_requeue"`
+}
+
+// AnalyticsPurgerBackendConfig configures the backend the analytics records are purged to. The records
+// and their rollups are shipped as JSON arrays, every `purge_interval`.
+type AnalyticsPurgerBackendConfig struct {
+	// Type is the backend: `rpc` for MDCB, the default when `analytics_config.type` is `rpc`, `kafka` or `http`.
+	Type string `json:"type"`
+
+	// Kafka configures the `kafka` backend.
+	Kafka AnalyticsKafkaBackendConfig `json:"kafka"`
+
+	// HTTP configures the `http` backend.
+	HTTP AnalyticsHTTPBackendConfig `json:"http"`
+}
+
+// AnalyticsKafkaBackendConfig configures the Kafka backend of the analytics purger. Every record is
+// produced as a message, keyed `PurgeAnalyticsData` for the records and `PurgeAnalyticsAggregates`
+// for their rollups.
+type AnalyticsKafkaBackendConfig struct {
+	// Brokers are the addresses of the Kafka brokers.
+	Brokers []string `json:"brokers"`
+
+	// Topic is the topic the records are produced to.
+	Topic string `json:"topic"`
+
+	// ClientID is the client ID of the Gateway. Default: sarama.
+	ClientID string `json:"client_id"`
+
+	// Timeout is the timeout in seconds of the connections and of the produce requests. Default: 30.
+	Timeout int `json:"timeout"`
+
+	// UseSSL connects to the brokers with TLS.
+	UseSSL bool `json:"use_ssl"`
+
+	// SSLInsecureSkipVerify skips the verification of the certificates of the brokers.
+	SSLInsecureSkipVerify bool `json:"ssl_insecure_skip_verify"`
+
+	// SASLUsername and SASLPassword authenticate the Gateway with SASL/PLAIN.
+	SASLUsername string `json:"sasl_username"`
+	SASLPassword string `json:"sasl_password"`
+}
+
+// AnalyticsHTTPBackendConfig configures the HTTP backend of the analytics purger. The records are
+// posted to the URL, with the `X-Tyk-Analytics-Function` header set to `PurgeAnalyticsData` for the
+// records and to `PurgeAnalyticsAggregates` for their rollups.
+type AnalyticsHTTPBackendConfig struct {
+	// URL is the endpoint the records are posted to.
+	URL string `json:"url"`
+
+	// Headers are added to the requests, e.g. to authenticate the Gateway.
+	Headers map[string]string `json:"headers"`
+
+	// Timeout is the timeout of the requests in seconds. Default: 30.
+	Timeout int `json:"timeout"`
+
+	// SSLInsecureSkipVerify skips the verification of the certificate of the endpoint.
+	SSLInsecureSkipVerify bool `json:"ssl_insecure_skip_verify"`
+}
+
+// RPCAnalyticsAggregationConfig configures the aggregation of the analytics records at the edge
+// before shipping them to MDCB. The records are rolled up per minute, API, key and response code,
+// reducing the bandwidth used by high-traffic edges by orders of magnitude.
+type RPCAnalyticsAggregationConfig struct {
+	// Enable shipping the rollups of the analytics records instead of the records.
+	Enabled bool `json:"enabled"`
+
+	// Ship the analytics records along with their rollups.
+	ShipRawRecords bool `json:"ship_raw_records"`
 }
 
 type HealthCheckConfig struct {
@@ -260,6 +457,82 @@ type HealthCheckConfig struct {
 	HealthCheckValueTimeout int64 `json:"health_check_value_timeouts"`
 }
 
+// DeveloperCatalogueConfig configures the catalogue of the OAS APIs loaded by the Gateway. The
+// catalogue lists the OAS documents of the APIs without their Tyk extension and security details,
+// with servers pointing to the Gateway.
+type DeveloperCatalogueConfig struct {
+	// Enable serving the catalogue.
+	Enabled bool `json:"enabled"`
+
+	// Path of the catalogue endpoint. Defaults to `/catalogue`.
+	Path string `json:"path"`
+
+	// Require an API key in the `Authorization` header. The catalogue then lists only the APIs the key has access to.
+	RequireKey bool `json:"require_key"`
+}
+
+// MeteringConfig configures the export of the cost units of the requests to metered APIs. Each
+// metered request is exported as a CloudEvent, as expected by metering systems like OpenMeter.
+type MeteringConfig struct {
+	// Enable pushing the metering events to the `tyk-metering-events` list of the analytics Redis.
+	EnableExport bool `json:"enable_export"`
+
+	// Source of the CloudEvents. Defaults to `tyk-gateway/` followed by the node ID.
+	Source string `json:"source"`
+
+	// Type of the CloudEvents. Defaults to `tyk.api.request`.
+	EventType string `json:"event_type"`
+
+	// MaxExportedEvents caps the length of the `tyk-metering-events` list, dropping the oldest events
+	// once the list is full. Defaults to 100000.
+	MaxExportedEvents int64 `json:"max_exported_events"`
+
+	// TotalsTTL is the time in seconds the accumulated units of a key are kept after its last metered
+	// request. Defaults to 30 days.
+	TotalsTTL int64 `json:"totals_ttl"`
+}
+
+// StartupDependenciesConfig configures the wait for the dependencies of the Gateway on startup.
+type StartupDependenciesConfig struct {
+	// Enabled waits for the dependencies before the Gateway loads the APIs and the policies.
+	Enabled bool `json:"enabled"`
+
+	// Order is the order the dependencies are waited for in, among `redis`, `rpc` and `certificates`.
+	// RPC is waited for in RPC mode only, and the certificate store when `http_server_options.ssl_certificates`
+	// is set. Default: redis, rpc, certificates.
+	Order []string `json:"order"`
+
+	// HealthOnly serves only the health check endpoint while waiting for the dependencies, reporting the
+	// Gateway as failing, instead of the Control API.
+	HealthOnly bool `json:"health_only"`
+
+	// Redis configures the wait for Redis.
+	Redis StartupDependencyConfig `json:"redis"`
+
+	// RPC configures the wait for the RPC connection to MDCB.
+	RPC StartupDependencyConfig `json:"rpc"`
+
+	// Certificates configures the wait for the server certificates in the certificate store.
+	Certificates StartupDependencyConfig `json:"certificates"`
+}
+
+// StartupDependencyConfig configures the wait for a startup dependency.
+type StartupDependencyConfig struct {
+	// Timeout is the number of seconds to wait for the dependency. Default: 60.
+	Timeout int `json:"timeout"`
+
+	// RetryInterval is the number of seconds between the first checks of the dependency. The interval
+	// doubles after each failed check, up to MaxRetryInterval. Default: 1.
+	RetryInterval int `json:"retry_interval"`
+
+	// MaxRetryInterval is the maximum number of seconds between the checks of the dependency. Default: 10.
+	MaxRetryInterval int `json:"max_retry_interval"`
+
+	// Optional lets the Gateway start, with a warning, when the dependency isn't ready in time.
+	// The Gateway exits when a dependency which isn't optional isn't ready in time.
+	Optional bool `json:"optional"`
+}
+
 type LivenessCheckConfig struct {
 	// Frequencies of performing interval healthchecks for Redis, Dashboard, and RPC layer.
 	// Expressed in Nanoseconds. For example: 1000000000 -> 1s.
@@ -267,6 +540,18 @@ type LivenessCheckConfig struct {
 	CheckDuration time.Duration `json:"check_duration"`
 }
 
+// RegexpSafeModeConfig configures the safe mode of the regular expressions of the API definitions:
+// paths, URL rewrite patterns and triggers.
+type RegexpSafeModeConfig struct {
+	// Enabled skips loading the API definitions with invalid regular expressions, nested unbounded
+	// repetitions like `(a+)+`, or repetitions and compiled programs above the limits.
+	Enabled bool `json:"enabled"`
+	// MaxRepeat is the maximum count of counted repetitions like `a{1,100}`. Defaults to 1000.
+	MaxRepeat int `json:"max_repeat"`
+	// MaxProgramSize is the maximum number of instructions a regular expression compiles to. Defaults to 10000.
+	MaxProgramSize int `json:"max_program_size"`
+}
+
 type DnsCacheConfig struct {
 	// Setting this value to `true` will enable caching of DNS queries responses used for API endpoint’s host names. By default caching is disabled.
 	Enabled bool `json:"enabled"`
@@ -287,6 +572,38 @@ type DnsCacheConfig struct {
 	MultipleIPsHandleStrategy IPsHandleStrategy `json:"multiple_ips_handle_strategy"`
 }
 
+// DNSResolverConfig configures the DNS resolver the Gateway resolves the upstream and service
+// discovery host names with, instead of the host resolver.
+type DNSResolverConfig struct {
+	// Set this to `true` to resolve the host names with the configured DNS servers.
+	Enabled bool `json:"enabled"`
+
+	// Servers are the DNS servers queried in turn, as `host:port`. The port defaults to 53, or 853 with the `tls` protocol.
+	// A query goes to the next server when a server doesn't answer within the timeout or fails to answer, e.g. with SERVFAIL.
+	// With the `https` protocol the servers are the URLs of the DNS over HTTPS endpoints, eg. `https://dns.example.com/dns-query`.
+	Servers []string `json:"servers"`
+
+	// Protocol is the protocol the DNS servers are queried with:
+	//
+	// * `udp` (default) and `tcp` for plain DNS,
+	// * `tls` for DNS over TLS,
+	// * `https` for DNS over HTTPS.
+	Protocol string `json:"protocol"`
+
+	// TLSServerName is the name the certificates of the DNS over TLS servers are verified against. Defaults to the host of the server.
+	TLSServerName string `json:"tls_server_name"`
+
+	// SearchDomains are appended to the host names which aren't fully qualified, as the `search` option of `resolv.conf`.
+	// The search domains of the host aren't used.
+	SearchDomains []string `json:"search_domains"`
+
+	// Ndots is the number of dots a host name has at least to be queried as is before the search domains are appended, as the `ndots` option of `resolv.conf`. Defaults to 1.
+	Ndots int `json:"ndots"`
+
+	// Timeout is the timeout of a DNS query in seconds. Defaults to 5.
+	Timeout int `json:"timeout"`
+}
+
 type MonitorConfig struct {
 	// Set this to `true` to have monitors enabled in your configuration for the node.
 	EnableTriggerMonitors bool               `json:"enable_trigger_monitors"`
@@ -370,6 +687,208 @@ type SlaveOptionsConfig struct {
 	SynchroniserEnabled bool `json:"synchroniser_enabled"`
 }
 
+// AirGappedConfig configures the air-gapped mode, where the gateway doesn't connect to MDCB.
+// The RPC analytics purger writes the analytics to files in the export path, and keys are
+// imported from files dropped in the import path, so that a transfer process can move them
+// in and out of the environment.
+type AirGappedConfig struct {
+	// Enable the air-gapped mode. The `slave_options` RPC connection is disabled.
+	Enabled bool `json:"enabled"`
+
+	// Directory the analytics are exported to when `analytics_config.type` is `rpc`, as JSON files
+	// holding the analytics records shipped to MDCB with the `PurgeAnalyticsData` RPC function, or their rollups.
+	ExportPath string `json:"export_path"`
+
+	// Directory the keys are imported from. Imported files are moved to its `imported` sub directory.
+	ImportPath string `json:"import_path"`
+
+	// Interval in seconds between two imports of keys. Defaults to 10 seconds.
+	ImportInterval int `json:"import_interval"`
+}
+
+// ConfigBackupsConfig configures the backups of the Gateway configuration.
+type ConfigBackupsConfig struct {
+	// Path of the directory the backups are written to. Defaults to `config_backups` in the working
+	// directory. The backups are named `tyk-config-backup-<time>.conf`, the other files of the
+	// directory are left alone.
+	Path string `json:"path"`
+
+	// MaxBackups is the number of backups kept, the oldest backups are pruned. Defaults to 10.
+	MaxBackups int `json:"max_backups"`
+}
+
+// RemoteConfigSignatureConfig configures the signature verification of the remote configurations.
+type RemoteConfigSignatureConfig struct {
+	// Secret is the shared secret of the HMAC-SHA256 signatures.
+	Secret string `json:"secret"`
+
+	// PublicKeyPath is the path to the public key of the RSA signatures. It takes precedence
+	// over Secret.
+	PublicKeyPath string `json:"public_key_path"`
+
+	// MaxAge is the number of seconds a signed payload is accepted for around its timestamp.
+	// Defaults to 300, five minutes. The payloads not newer than the last applied payload are
+	// rejected too, so that a signed payload can't be replayed.
+	MaxAge int64 `json:"max_age"`
+}
+
+// LoadDiagnosticsConfig configures the diagnostics bundles of API load failures.
+type LoadDiagnosticsConfig struct {
+	// Path of the directory the bundles are also written to, as JSON files. Bundles are only
+	// kept in memory when empty.
+	Path string `json:"path"`
+
+	// MaxBundles is the number of bundles kept, in memory and in the directory of the bundles,
+	// the oldest bundles are dropped. Defaults to 20.
+	MaxBundles int `json:"max_bundles"`
+}
+
+// TrashConfig configures the trash of the APIs and keys deleted with the Gateway API.
+type TrashConfig struct {
+	// Enabled keeps the deleted APIs and keys in the trash.
+	Enabled bool `json:"enabled"`
+
+	// RetentionPeriod is the number of seconds the deleted APIs and keys are kept in the trash
+	// before being purged. Defaults to 86400, a day.
+	RetentionPeriod int64 `json:"retention_period"`
+}
+
+// StorageGCConfig configures the report of the orphaned storage artifacts: the quota counters of
+// deleted keys and the OAuth clients of removed APIs.
+type StorageGCConfig struct {
+	// Enabled runs the report periodically. The report can still be run with the Gateway API.
+	Enabled bool `json:"enabled"`
+
+	// Interval in seconds between two reports. Defaults to 3600, an hour.
+	Interval int `json:"interval"`
+
+	// Cleanup deletes the orphaned quota counters found by the periodic reports. They're only
+	// reported otherwise, and the OAuth clients are always only reported.
+	Cleanup bool `json:"cleanup"`
+}
+
+// KeySpaceSnapshotsConfig configures the signed snapshots of the key space: the sessions of the
+// keys, referencing their policies, and their quota counters. The snapshots are restored by the
+// standby clusters for disaster recovery, without replicating Redis. Snapshots require
+// `hash_keys`, so that the keys themselves aren't exported.
+type KeySpaceSnapshotsConfig struct {
+	// Enabled exports a snapshot periodically. A single Gateway of the cluster exports it at a
+	// time. The snapshots can still be exported and restored with the Gateway API.
+	Enabled bool `json:"enabled"`
+
+	// Interval in seconds between two snapshots. Defaults to 3600, an hour.
+	Interval int `json:"interval"`
+
+	// Storage the snapshots are written to: `file`, the default, or `s3`.
+	Storage string `json:"storage"`
+
+	// Path of the directory the snapshots are written to with the `file` storage. Defaults to the
+	// working directory.
+	Path string `json:"path"`
+
+	// S3 configures the bucket the snapshots are written to with the `s3` storage.
+	S3 KeySpaceSnapshotsS3Config `json:"s3"`
+
+	// MaxSnapshots is the number of snapshots kept, the oldest snapshots are pruned. Defaults to 24.
+	MaxSnapshots int `json:"max_snapshots"`
+
+	// Secret is the shared secret of the HMAC-SHA256 signatures of the snapshots.
+	Secret string `json:"secret"`
+
+	// PrivateKeyPath is the path to the private key the snapshots are signed with, with RSA. It
+	// takes precedence over Secret.
+	PrivateKeyPath string `json:"private_key_path"`
+
+	// PublicKeyPath is the path to the public key the RSA signatures of the restored snapshots are
+	// verified with. It takes precedence over Secret.
+	PublicKeyPath string `json:"public_key_path"`
+}
+
+// KeySpaceSnapshotsS3Config configures the S3 bucket of the key space snapshots. The credentials
+// are loaded like the AWS SDK does, from the environment, the shared files or the instance role.
+type KeySpaceSnapshotsS3Config struct {
+	// Bucket the snapshots are written to.
+	Bucket string `json:"bucket"`
+
+	// Prefix of the object keys of the snapshots.
+	Prefix string `json:"prefix"`
+
+	// Region of the bucket.
+	Region string `json:"region"`
+
+	// Endpoint of an S3 compatible storage, replacing the AWS endpoint.
+	Endpoint string `json:"endpoint"`
+
+	// UsePathStyle addresses the bucket in the path of the URLs, for the S3 compatible storages.
+	UsePathStyle bool `json:"use_path_style"`
+}
+
+// InboundDedupConfig configures the deduplication of the requests received by the APIs, for
+// the webhook providers delivering at least once. The API definitions override the settings.
+type InboundDedupConfig struct {
+	// EnableAllAPIs deduplicates the requests of every API, unless disabled in the API definition.
+	EnableAllAPIs bool `json:"enable_all_apis"`
+	// Headers are the request headers hashed with the method, path, query, consumer and body, e.g. the delivery
+	// ID header of the provider.
+	Headers []string `json:"headers"`
+	// TTL is the number of seconds a request is remembered for, 300 by default.
+	TTL int64 `json:"ttl"`
+	// DuplicateStatusCode is the status code of the responses to the duplicates, 200 by default
+	// so the providers stop retrying.
+	DuplicateStatusCode int `json:"duplicate_status_code"`
+}
+
+// StorageBudgetsConfig configures the byte budgets of the analytics and cache keys, so that
+// they can't evict the sessions from a shared Redis. A budget is disabled when zero.
+//
+// The budgets are enforced by every Gateway on its own and are approximate: the memory used
+// by Redis isn't measured. Set them well below the memory of Redis, accounting for the number
+// of Gateways sharing it.
+type StorageBudgetsConfig struct {
+	// AnalyticsBytes is the budget of the analytics lists. Their size is estimated with their
+	// length, shared by the Gateways, times the average size of the records encoded by this
+	// Gateway. The records are dropped while the budget is exceeded, until the lists are purged.
+	AnalyticsBytes int64 `json:"analytics_bytes"`
+
+	// CacheBytes is the budget of the response cache entries stored by this Gateway, counted
+	// with the size of the cached responses. The entries stored by other Gateways aren't
+	// counted, so the cache of N Gateways can use up to N times the budget. The responses aren't
+	// cached while the budget is exceeded, until entries expire.
+	CacheBytes int64 `json:"cache_bytes"`
+
+	// AlertThreshold is the ratio of a budget from which a `StorageBudgetApproached` event is
+	// fired. Defaults to 0.8.
+	AlertThreshold float64 `json:"alert_threshold"`
+}
+
+// PolicyBundleConfig configures the policy bundle of the Gateway.
+//
+// A bundle is a JSON document with the `admin` policies, evaluated for the Control API
+// requests, and the `requests` policies, evaluated for the requests of all the APIs after
+// authentication. Policies have the format of the API request policies: bundles of OPA Rego
+// policies aren't supported.
+type PolicyBundleConfig struct {
+	// Enable the policy bundle. The requests of the APIs are rejected with HTTP 503 until a bundle
+	// is loaded, while the Control API requests are allowed unless `admin_fail_closed` is set.
+	Enabled bool `json:"enabled"`
+
+	// AdminFailClosed rejects the Control API requests with HTTP 503 until a bundle is loaded too.
+	AdminFailClosed bool `json:"admin_fail_closed"`
+
+	// Path of the bundle file.
+	Path string `json:"path"`
+
+	// URL the bundle is downloaded from, when the path isn't set.
+	URL string `json:"url"`
+
+	// Interval in seconds between two loads of the bundle. Defaults to 30 seconds.
+	PollInterval int `json:"poll_interval"`
+
+	// DecisionLogs logs the decisions of the policies, with the matching policy and the
+	// revision of the bundle.
+	DecisionLogs bool `json:"decision_logs"`
+}
+
 type LocalSessionCacheConf struct {
 	// By default sessions are set to cache. Set this to `true` to stop Tyk from caching keys locally on the node.
 	DisableCacheSessionState bool `json:"disable_cached_session_state"`
@@ -377,6 +896,31 @@ type LocalSessionCacheConf struct {
 	CachedSessionTimeout int `json:"cached_session_timeout"`
 	CacheSessionEviction int `json:"cached_session_eviction"`
 }
+
+// ExternalSessionLookupConfig configures the lookup of the keys not found in the key store
+// in an external identity store, for keys provisioned lazily by an external IAM.
+type ExternalSessionLookupConfig struct {
+	// Enable looking up the unknown keys in the external identity store.
+	Enabled bool `json:"enabled"`
+
+	// URL of the HTTP lookup endpoint. The gateway POSTs the key, the API ID and the organisation ID
+	// as JSON to it, and expects the session of the key in response with a `200` status code,
+	// or a `404` status code when the key is unknown.
+	URL string `json:"url"`
+
+	// Headers added to the lookup requests, e.g. to authenticate the gateway to the identity store.
+	Headers map[string]string `json:"headers"`
+
+	// Timeout of the lookup requests in seconds. Defaults to 5 seconds.
+	Timeout float64 `json:"timeout"`
+
+	// Time in seconds the sessions returned by the identity store are kept in the key store before
+	// being looked up again. Defaults to 3600 seconds.
+	CacheTTL int64 `json:"cache_ttl"`
+
+	// Time in seconds the keys unknown to the identity store aren't looked up again. Defaults to 60 seconds.
+	NegativeCacheTTL int64 `json:"negative_cache_ttl"`
+}
 type CertsData []CertData
 
 func (certs *CertsData) Decode(value string) error {
@@ -463,6 +1007,15 @@ type HttpServerOptionsConfig struct {
 	// SSL certificates used by your Gateway server. A list of certificate IDs or path to files.
 	SSLCertificates []string `json:"ssl_certificates"`
 
+	// CertificateReloadInterval is the number of seconds between the checks of the server certificate files and
+	// certificate store entries for changes. The changed certificates are swapped into the running listeners without
+	// a reload, firing a `ServerCertificatesChanged` event. The checks are disabled when 0.
+	//
+	// The files and entries are polled, so a change is served from the next check at the latest. A certificate which
+	// fails to load, e.g. a file caught half written, keeps the previously loaded certificate served until a later
+	// check loads it.
+	CertificateReloadInterval int `json:"certificate_reload_interval"`
+
 	// Start your Gateway HTTP server on specific server name
 	ServerName string `json:"server_name"`
 
@@ -504,6 +1057,56 @@ type HttpServerOptionsConfig struct {
 	// See more information about setting request size limits here:
 	// https://tyk.io/docs/basic-config-and-security/control-limit-traffic/request-size-limits/#maximum-request-sizes
 	MaxRequestBodySize int64 `json:"max_request_body_size"`
+
+	// StrictParsing rejects the requests framed ambiguously, which the upstreams could parse
+	// differently than Tyk Gateway: requests with both the `Content-Length` and `Transfer-Encoding`
+	// headers, several `Content-Length` or `Transfer-Encoding` headers, obsolete line folding,
+	// bare LF line endings and abnormal chunks. These requests are rejected with HTTP 400 and
+	// the connection is closed.
+	StrictParsing StrictParsingConfig `json:"strict_parsing"`
+
+	// PathNormalization normalizes the request paths before they're routed and matched against
+	// the access rules and endpoint configurations: duplicate slashes are collapsed, the `.` and
+	// `..` segments are resolved and the percent-encoded unreserved characters are decoded, so
+	// that `/api//admin`, `/api/./admin`, `/api/x/../admin` and `/api/%61dmin` are all `/api/admin`.
+	PathNormalization PathNormalizationConfig `json:"path_normalization"`
+}
+
+// PathNormalizationConfig configures the normalization of request paths.
+type PathNormalizationConfig struct {
+	// Enabled enables path normalization.
+	Enabled bool `json:"enabled"`
+	// Strict rejects the ambiguous paths with HTTP 400 instead of normalizing them: paths with
+	// encoded slashes or backslashes, backslashes, NUL bytes, or `..` segments above the root.
+	Strict bool `json:"strict"`
+}
+
+// StrictParsingConfig configures the strict parsing of HTTP/1.x requests.
+type StrictParsingConfig struct {
+	// Enabled enables strict parsing.
+	Enabled bool `json:"enabled"`
+	// Ports restricts strict parsing to the listeners of these ports. All the HTTP listeners,
+	// including the ones of APIs with a custom `listen_port`, are strict when empty.
+	Ports []int `json:"ports"`
+}
+
+// EnabledForPort tells whether strict parsing applies to the listener of a port.
+func (c StrictParsingConfig) EnabledForPort(port int) bool {
+	if !c.Enabled {
+		return false
+	}
+
+	if len(c.Ports) == 0 {
+		return true
+	}
+
+	for _, p := range c.Ports {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
 }
 
 type AuthOverrideConf struct {
@@ -585,6 +1188,51 @@ type SecurityConfig struct {
 	PinnedPublicKeys map[string]string `json:"pinned_public_keys"`
 
 	Certificates CertificatesConfig `json:"certificates"`
+
+	// SigningKeys configures the keys the Gateway uses to sign upstream requests and the tokens it issues.
+	SigningKeys SigningKeysConfig `json:"signing_keys"`
+
+	// SecretScanning configures the GitHub secret scanning partner endpoint, to which GitHub reports Tyk keys found in public repositories.
+	SecretScanning SecretScanningConfig `json:"secret_scanning"`
+}
+
+// SecretScanningConfig configures the verification of leaked keys reported by GitHub secret scanning.
+// The endpoint is served at `/secret-scanning/verify` on the control API when enabled.
+type SecretScanningConfig struct {
+	// Enable the secret scanning verification endpoint.
+	Enabled bool `json:"enabled"`
+
+	// URL of the public keys GitHub signs secret scanning reports with.
+	// Defaults to `https://api.github.com/meta/public_keys/secret_scanning`.
+	PublicKeysURL string `json:"public_keys_url"`
+
+	// Deactivate keys confirmed as leaked. A `KeyLeaked` event is fired for every leaked key either way.
+	SuspendLeakedKeys bool `json:"suspend_leaked_keys"`
+
+	// Overrides SuspendLeakedKeys per organisation, by org ID.
+	OrgSuspendLeakedKeys map[string]bool `json:"org_suspend_leaked_keys"`
+}
+
+// SigningKeysConfig holds the Gateway signing keys. Keys are identified by `kid`, which allows
+// rotating the active key while upstreams still verify signatures made with the previous one.
+type SigningKeysConfig struct {
+	// Enable publishing the public part of all signing keys as a JSON Web Key Set at `/.well-known/jwks.json`.
+	EnableJWKS bool `json:"enable_jwks"`
+
+	// The `kid` of the key used for signing. If not set, the first key in the list is used.
+	ActiveKeyID string `json:"active_key_id"`
+
+	// List of signing keys. Keep a retired key in the list until signatures made with it are no longer accepted.
+	Keys []SigningKey `json:"keys"`
+}
+
+// SigningKey maps a key ID to a certificate in the certificate store.
+type SigningKey struct {
+	// The key ID published as `kid`.
+	KeyID string `json:"kid"`
+
+	// ID of a certificate, with an RSA private key, stored in the Tyk certificate store.
+	CertificateID string `json:"certificate_id"`
 }
 
 type NewRelicConfig struct {
@@ -700,6 +1348,37 @@ type Config struct {
 	// Allow your Dashboard to remotely set Gateway configuration via the Nodes screen.
 	AllowRemoteConfig bool `bson:"allow_remote_config" json:"allow_remote_config"`
 
+	// RemoteConfigSignature configures the signature verification of the remote configurations.
+	// Unsigned configurations, or configurations with an invalid signature, are rejected when a
+	// secret or a public key is set.
+	RemoteConfigSignature RemoteConfigSignatureConfig `json:"remote_config_signature"`
+
+	// ConfigBackups configures the backups of the configuration written before the remote
+	// configurations are applied.
+	ConfigBackups ConfigBackupsConfig `json:"config_backups"`
+
+	// LoadDiagnostics configures the diagnostics bundles captured when APIs fail to load, with the
+	// definition, the configuration and the error, returned by the `/tyk/debug/load-failures` endpoint.
+	LoadDiagnostics LoadDiagnosticsConfig `json:"load_diagnostics"`
+
+	// Trash configures the soft deletion of the APIs and keys deleted with the Gateway API, which
+	// are kept in the trash to be restored within the retention period.
+	Trash TrashConfig `json:"trash"`
+
+	// StorageGC configures the report and the cleanup of the orphaned storage artifacts, which
+	// grow the storage unbounded on long-lived clusters.
+	StorageGC StorageGCConfig `json:"storage_gc"`
+
+	// KeySpaceSnapshots configures the scheduled export of signed snapshots of the key space, to
+	// restore the keys and their quotas on a cold standby cluster.
+	KeySpaceSnapshots KeySpaceSnapshotsConfig `json:"key_space_snapshots"`
+
+	// StorageBudgets configures the byte budgets of the analytics and response cache keys.
+	StorageBudgets StorageBudgetsConfig `json:"storage_budgets"`
+
+	// InboundDedup configures the deduplication of the requests received by the APIs.
+	InboundDedup InboundDedupConfig `json:"inbound_dedup"`
+
 	// Global Certificate configuration
 	Security SecurityConfig `json:"security"`
 
@@ -716,6 +1395,16 @@ type Config struct {
 	// The value defaults to 1, values lower than 1 are ignored.
 	ReloadInterval int64 `json:"reload_interval"`
 
+	// ChainCacheTTL keeps the compiled middleware chains of the API definitions unloaded by a reload
+	// for the given number of seconds, keyed by the checksum of the definitions. Definitions loaded back
+	// unchanged, e.g. after a partial sync, reuse their chains instead of being compiled again.
+	// The definitions replaced by a changed definition are unloaded right away. Disabled when 0.
+	ChainCacheTTL int64 `json:"chain_cache_ttl"`
+
+	// DefinitionHistory configures the revision history of the API definitions and policies
+	// changed through the Gateway API, exposed by the history and diff endpoints.
+	DefinitionHistory DefinitionHistoryConfig `json:"definition_history"`
+
 	// Enable Key hashing
 	HashKeys bool `json:"hash_keys"`
 
@@ -741,6 +1430,10 @@ type Config struct {
 	// Minimum API token length
 	MinTokenLength int `json:"min_token_length"`
 
+	// KeyFormat configures the format of generated API keys globally and per
+	// organisation, or a Go plugin generating them.
+	KeyFormat KeyFormatConfig `json:"key_format"`
+
 	// Path to error and webhook templates. Defaults to the current binary path.
 	TemplatePath string `json:"template_path"`
 
@@ -784,6 +1477,13 @@ type Config struct {
 	// These settings must be configured for every RPC slave/worker node.
 	SlaveOptions SlaveOptionsConfig `json:"slave_options"`
 
+	// AirGapped replaces the RPC connection to MDCB with file import and export jobs, for disconnected environments.
+	AirGapped AirGappedConfig `json:"air_gapped"`
+
+	// PolicyBundle gates the Control API operations and the requests of all the APIs with the
+	// policies of a bundle loaded from disk or over HTTP.
+	PolicyBundle PolicyBundleConfig `json:"policy_bundle"`
+
 	// If set to `true`, distributed rate limiter will be disabled for this node, and it will be excluded from any rate limit calculation.
 	//
 	// Note:
@@ -891,6 +1591,9 @@ type Config struct {
 	// Enables you to rename the /hello endpoint
 	HealthCheckEndpointName string `json:"health_check_endpoint_name"`
 
+	// DeveloperCatalogue configures the machine-readable catalogue of the loaded OAS APIs, served to API consumers.
+	DeveloperCatalogue DeveloperCatalogueConfig `json:"developer_catalogue"`
+
 	// Change the expiry time of a refresh token. By default 14 days (in seconds).
 	OauthRefreshExpire int64 `json:"oauth_refresh_token_expire"`
 
@@ -921,12 +1624,19 @@ type Config struct {
 	// This section defines options on what analytics data to store.
 	AnalyticsConfig AnalyticsConfigConfig `json:"analytics_config"`
 
+	// Metering configures the export of the cost units of metered APIs, for usage-based billing.
+	Metering MeteringConfig `json:"metering"`
+
 	// Enable separate analytics storage. Used together with `analytics_storage`.
 	EnableSeperateAnalyticsStore bool               `json:"enable_separate_analytics_store"`
 	AnalyticsStorage             StorageOptionsConf `json:"analytics_storage"`
 
 	LivenessCheck LivenessCheckConfig `json:"liveness_check"`
 
+	// StartupDependencies makes the Gateway wait for Redis, RPC and the certificate store on startup,
+	// before it loads the APIs and the policies.
+	StartupDependencies StartupDependenciesConfig `json:"startup_dependencies"`
+
 	// This section enables the global configuration of the expireable DNS records caching for your Gateway API endpoints.
 	// By design caching affects only http(s), ws(s) protocols APIs and doesn’t affect any plugin/middleware DNS queries.
 	//
@@ -939,6 +1649,19 @@ type Config struct {
 	// ```
 	DnsCache DnsCacheConfig `json:"dns_cache"`
 
+	// This section configures the DNS servers the Gateway resolves the host names of the upstreams and of the service discovery
+	// endpoints with, for split-DNS networks. The host resolver is used when disabled.
+	//
+	// ```
+	// "dns_resolver": {
+	//   "enabled": true,
+	//   "servers": ["10.0.0.2:53", "10.0.0.3:53"],
+	//   "protocol": "udp",
+	//   "search_domains": ["corp.example.com"]
+	// }
+	// ```
+	DNSResolver DNSResolverConfig `json:"dns_resolver"`
+
 	// If set to `true` this allows you to disable the regular expression cache. The default setting is `false`.
 	DisableRegexpCache bool `json:"disable_regexp_cache"`
 
@@ -946,10 +1669,18 @@ type Config struct {
 	// The default is 60 seconds. This must be a positive value. If you set to 0 this uses the default value.
 	RegexpCacheExpire int32 `json:"regexp_cache_expire"`
 
+	// RegexpSafeMode rejects the API definitions with regular expressions which could be expensive to
+	// match, see RegexpSafeModeConfig. The invalid regular expressions of the API definitions are logged
+	// per API whether it's enabled or not.
+	RegexpSafeMode RegexpSafeModeConfig `json:"regexp_safe_mode"`
+
 	// Tyk can cache some data locally, this can speed up lookup times on a single node and lower the number of connections and operations being done on Redis. It will however introduce a slight delay when updating or modifying keys as the cache must expire.
 	// This does not affect rate limiting.
 	LocalSessionCache LocalSessionCacheConf `json:"local_session_cache"`
 
+	// ExternalSessionLookup configures the lookup of the keys not found in the key store in an external identity store.
+	ExternalSessionLookup ExternalSessionLookupConfig `json:"external_session_lookup"`
+
 	// Enable to use a separate Redis for cache storage
 	EnableSeperateCacheStore bool               `json:"enable_separate_cache_store"`
 	CacheStorage             StorageOptionsConf `json:"cache_storage"`
@@ -1058,6 +1789,15 @@ type Config struct {
 	// StatsD prefix
 	StatsdPrefix string `json:"statsd_prefix"`
 
+	// EnableConnectionMetrics collects metrics on client and upstream connections: TLS handshake
+	// durations, TLS session resumptions, upstream connection reuse and new dials, and client
+	// connection churn. The metrics are listed by the `/tyk/debug/connections` endpoint, and sent
+	// to StatsD when it's enabled.
+	EnableConnectionMetrics bool `json:"enable_connection_metrics"`
+
+	// StorageInstrumentation records the latency of the commands sent to Redis and logs the slow ones.
+	StorageInstrumentation StorageInstrumentationConfig `json:"storage_instrumentation"`
+
 	// Event System
 	EventHandlers        apidef.EventHandlerMetaConfig         `json:"event_handlers"`
 	EventTriggers        map[apidef.TykEvent][]TykEventHandler `json:"event_trigers_defunct"`  // Deprecated: Config.GetEventTriggers instead.
@@ -1154,6 +1894,9 @@ type OASConfig struct {
 
 	// ValidateSchemaDefaults enables validation of values provided in `default` fields against the declared schemas in the OpenAPI Document. Defaults to false.
 	ValidateSchemaDefaults bool `json:"validate_schema_defaults"`
+
+	// ValidateSecuritySchemes rejects the OpenAPI Documents whose security schemes don't match their Tyk authentication configuration, instead of logging the mismatches. The mismatches can be fixed on creation and import with the `syncSecurity=true` query parameter. Defaults to false.
+	ValidateSecuritySchemes bool `json:"validate_security_schemes"`
 }
 
 type ResourceSyncConfig struct {
@@ -1275,6 +2018,8 @@ type EventMessage struct {
 	Type      apidef.TykEvent
 	Meta      interface{}
 	TimeStamp string
+	// Ownership is the ownership of the API the event was fired for, empty for system events.
+	Ownership apidef.APIOwnership
 }
 
 // TykEventHandler defines an event handler, e.g. LogMessageEventHandler will handle an event by logging it to stdout.
@@ -7,12 +7,32 @@ import (
 // RateLimit contains flags and configuration for controlling rate limiting behaviour.
 // It is embedded in the main config structure.
 type RateLimit struct {
+	// RateLimiter selects the rate limiting algorithm by name, instead of the flags enabling the rate limiters:
+	//
+	// - `drl`: the distributed rate limiter, falling back to the Redis Rate Limiter over `drl_threshold`,
+	// - `sentinel`: the Redis Sentinel Rate Limiter,
+	// - `sliding-log`: the Redis Rate Limiter,
+	// - `fixed-window` and `sliding-window`: counters of the requests over windows of the rate limit period. Unlike
+	//   `enable_sliding_window_rate_limiter`, which blocks the last request of the rate, `sliding-window` allows the rate,
+	// - `leaky-bucket`: queues the requests over the rate, delaying them instead of blocking them,
+	// - `token-bucket`: refills the rate of tokens every period,
+	// - `gcra`: the generic cell rate algorithm, a leaky bucket which blocks the requests over the rate, with a single timestamp per key.
+	//
+	// APIs can select another rate limiter with their `rate_limiter`. When unset, the flags select the rate limiter.
+	RateLimiter string `json:"rate_limiter"`
+
 	// EnableFixedWindow enables fixed window rate limiting.
 	EnableFixedWindowRateLimiter bool `json:"enable_fixed_window_rate_limiter"`
 
 	// Redis based rate limiter with sliding log. Provides 100% rate limiting accuracy, but require two additional Redis roundtrips for each request.
 	EnableRedisRollingLimiter bool `json:"enable_redis_rolling_limiter"`
 
+	// EnableAtomicRateLimiter checks the Redis rate limit and the quota of a request, and updates the
+	// session quota, in a single Lua script: one Redis round trip per request instead of several.
+	// The rate limit is only checked by the script with the Redis Rate Limiter, and smoothing isn't
+	// supported. The keys of the sliding logs share the Redis Cluster hash slot of the quota keys.
+	EnableAtomicRateLimiter bool `json:"enable_atomic_rate_limiter"`
+
 	// To enable, set to `true`. The sentinel-based rate limiter delivers a smoother performance curve as rate-limit calculations happen off-thread, but a stricter time-out based cool-down for clients. For example, when a throttling action is triggered, they are required to cool-down for the period of the rate limit.
 	// Disabling the sentinel based rate limiter will make rate-limit calculations happen on-thread and therefore offers a staggered cool-down and a smoother rate-limit experience for the client.
 	// For example, you can slow your connection throughput to regain entry into your rate limit. This is more of a “throttle” than a “block”.
@@ -29,6 +49,17 @@ type RateLimit struct {
 	// How frequently a distributed rate limiter synchronises information between the Gateway nodes. Default: 2 seconds.
 	DRLNotificationFrequency int `json:"drl_notification_frequency"`
 
+	// DRLServerTTL is the number of seconds after which a Gateway which stopped sending distributed rate limiter notifications
+	// is evicted, so that it stops counting in the share of the rate limits of the other Gateways, and the `DRLServerEvicted`
+	// event is fired. It should be a few times `drl_notification_frequency`. When unset, the distributed rate limiter forgets
+	// the Gateways after a few seconds without firing any event. It must be between 1 and 4 seconds, as the distributed rate
+	// limiter forgets the Gateways after 4 seconds anyway: the Gateway doesn't start with a longer TTL.
+	DRLServerTTL int `json:"drl_server_ttl"`
+
+	// DRLAdaptiveNotifications adapts the frequency of the distributed rate limiter notifications to the load of the Gateway,
+	// instead of notifying every `drl_notification_frequency` seconds.
+	DRLAdaptiveNotifications DRLAdaptiveNotificationsConfig `json:"drl_adaptive_notifications"`
+
 	// A distributed rate limiter is inaccurate on small rate limits, and it will fallback to a Redis or Sentinel rate limiter on an individual user basis, if its rate limiter lower then threshold.
 	// A Rate limiter threshold calculated using the following formula: `rate_threshold = drl_threshold * number_of_gateways`.
 	// So you have 2 Gateways, and your threshold is set to 5, if a user rate limit is larger than 10, it will use the distributed rate limiter algorithm.
@@ -39,8 +70,31 @@ type RateLimit struct {
 	DRLEnableSentinelRateLimiter bool `json:"drl_enable_sentinel_rate_limiter"`
 }
 
+// DRLAdaptiveNotificationsConfig configures the adaptive frequency of the distributed rate limiter notifications.
+// The Gateway notifies as often as the minimum frequency while its request rate changes rapidly, and backs off
+// up to the maximum frequency while its request rate is steady, reducing the Redis pub/sub traffic of large clusters.
+type DRLAdaptiveNotificationsConfig struct {
+	// Set this to `true` to adapt the frequency of the notifications to the load.
+	Enabled bool `json:"enabled"`
+
+	// MinFrequency is the shortest interval between the notifications in seconds. Default: 1 second.
+	MinFrequency int `json:"min_frequency"`
+
+	// MaxFrequency is the longest interval between the notifications in seconds. The Gateways forget the Gateways which don't
+	// notify for 4 seconds, so it is capped to 3 seconds, or to half of `drl_server_ttl` when it is shorter. Default: the cap.
+	MaxFrequency int `json:"max_frequency"`
+
+	// ChangeThreshold is the change of the request rate since the last notification, as a fraction of the rate, from which
+	// the Gateway notifies again at the minimum frequency. The rates under 10 requests per second count as 10. Default: 0.2.
+	ChangeThreshold float64 `json:"change_threshold"`
+}
+
 // String returns a readable setting for the rate limiter in effect.
 func (r *RateLimit) String() string {
+	if r.RateLimiter != "" {
+		return fmt.Sprintf("Rate limiter %q enabled", r.RateLimiter)
+	}
+
 	info := "using transactions"
 	if r.EnableNonTransactionalRateLimiter {
 		info = "using pipeline"
@@ -56,6 +110,10 @@ func (r *RateLimit) String() string {
 		info = info + ", with smoothing"
 	}
 
+	if r.EnableRedisRollingLimiter && r.EnableAtomicRateLimiter {
+		return "Atomic Redis Rate Limiter enabled (using a script)"
+	}
+
 	if r.EnableRedisRollingLimiter {
 		return fmt.Sprintf("Redis Rate Limiter enabled (%s)", info)
 	}
@@ -53,6 +53,30 @@ const (
 	// CacheOptions holds cache options required for cache writer middleware.
 	CacheOptions
 	OASDefinition
+
+	// LoopInternalEndpoint holds the name of the internal endpoint a looping request was addressed to.
+	LoopInternalEndpoint
+
+	// RequestTiming holds the timing breakdown of a request used for slow request detection.
+	RequestTiming
+
+	// UpstreamRoute holds the upstream target chosen by the upstream routing rules.
+	UpstreamRoute
+
+	// TraceSpans holds the span recorder of a request traced with the trace endpoint.
+	TraceSpans
+
+	// InboundDedupKey holds the deduplication key of a request, released when the upstream fails.
+	InboundDedupKey
+
+	// Experiments holds the variants of the A/B experiments a request was assigned.
+	Experiments
+
+	// DebugDiagnostics holds the cache, limiter, upstream and timing diagnostics of a request of an API with debug headers.
+	DebugDiagnostics
+
+	// CacheWarmUp marks the requests of the cache warm-up, refreshing the cached response.
+	CacheWarmUp
 )
 
 func ctxSetSession(r *http.Request, s *user.SessionState, scheduleUpdate bool, hashKey bool) {
@@ -25,6 +25,7 @@ type IDnsCacheManager interface {
 	InitDNSCaching(ttl, checkInterval time.Duration)
 	WrapDialer(dialer *net.Dialer) DialContextFunc
 	SetCacheStorage(cache IDnsCacheStorage)
+	SetResolver(resolver *Resolver)
 	CacheStorage() IDnsCacheStorage
 	IsCacheEnabled() bool
 	DisposeCache()
@@ -46,11 +47,12 @@ type DnsCacheManager struct {
 	cacheStorage IDnsCacheStorage
 	strategy     config.IPsHandleStrategy
 	rand         *rand.Rand
+	resolver     *Resolver
 }
 
 // NewDnsCacheManager returns new empty/non-initialized DnsCacheManager
 func NewDnsCacheManager(multipleIPsHandleStrategy config.IPsHandleStrategy) *DnsCacheManager {
-	manager := &DnsCacheManager{nil, multipleIPsHandleStrategy, nil}
+	manager := &DnsCacheManager{nil, multipleIPsHandleStrategy, nil, nil}
 	return manager
 }
 
@@ -58,6 +60,11 @@ func (m *DnsCacheManager) SetCacheStorage(cache IDnsCacheStorage) {
 	m.cacheStorage = cache
 }
 
+// SetResolver sets the resolver the host names are resolved with, the host resolver when nil.
+func (m *DnsCacheManager) SetResolver(resolver *Resolver) {
+	m.resolver = resolver
+}
+
 func (m *DnsCacheManager) CacheStorage() IDnsCacheStorage {
 	return m.cacheStorage
 }
@@ -86,7 +93,7 @@ func (m *DnsCacheManager) doCachedDial(d *net.Dialer, ctx context.Context, netwo
 	}
 
 	if !m.IsCacheEnabled() {
-		return safeDial(address, "")
+		return m.resolver.dial(d, ctx, network, address)
 	}
 
 	host, port, err := net.SplitHostPort(address)
@@ -151,6 +158,7 @@ func (m *DnsCacheManager) InitDNSCaching(ttl, checkInterval time.Duration) {
 	if !m.IsCacheEnabled() {
 		logger.Infof("Initializing dns cache with ttl=%s, duration=%s", ttl, checkInterval)
 		storage := NewDnsCacheStorage(ttl, checkInterval)
+		storage.resolver = m.resolver
 		m.SetCacheStorage(IDnsCacheStorage(storage))
 	}
 }
@@ -0,0 +1,297 @@
+package dnscache
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+const (
+	ResolverProtocolUDP   = "udp"
+	ResolverProtocolTCP   = "tcp"
+	ResolverProtocolTLS   = "tls"
+	ResolverProtocolHTTPS = "https"
+
+	defaultResolverTimeout = 5 * time.Second
+	defaultResolverNdots   = 1
+
+	dohContentType = "application/dns-message"
+)
+
+// Resolver resolves host names with the configured DNS servers instead of the host resolver,
+// applying its own search domains. A nil Resolver resolves with the host resolver.
+type Resolver struct {
+	// resolvers query the configured servers, one server each, in the order of the servers.
+	resolvers     []*net.Resolver
+	timeout       time.Duration
+	searchDomains []string
+	ndots         int
+}
+
+// NewResolver returns the resolver querying the DNS servers of the configuration.
+func NewResolver(conf config.DNSResolverConfig) (*Resolver, error) {
+	if len(conf.Servers) == 0 {
+		return nil, errors.New("the DNS resolver requires at least one server")
+	}
+
+	timeout := defaultResolverTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var servers []string
+	var dial func(ctx context.Context, server string) (net.Conn, error)
+
+	switch protocol := conf.Protocol; protocol {
+	case "", ResolverProtocolUDP, ResolverProtocolTCP:
+		if protocol == "" {
+			protocol = ResolverProtocolUDP
+		}
+		servers = withDefaultPort(conf.Servers, "53")
+		dial = func(ctx context.Context, server string) (net.Conn, error) {
+			return dialer.DialContext(ctx, protocol, server)
+		}
+	case ResolverProtocolTLS:
+		servers = withDefaultPort(conf.Servers, "853")
+		dial = func(ctx context.Context, server string) (net.Conn, error) {
+			serverName := conf.TLSServerName
+			if serverName == "" {
+				serverName, _, _ = net.SplitHostPort(server)
+			}
+
+			tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: serverName}}
+			return tlsDialer.DialContext(ctx, "tcp", server)
+		}
+	case ResolverProtocolHTTPS:
+		for _, server := range conf.Servers {
+			if u, err := url.Parse(server); err != nil || u.Scheme != "https" || u.Host == "" {
+				return nil, fmt.Errorf("invalid DNS over HTTPS server %q", server)
+			}
+		}
+		servers = conf.Servers
+		client := &http.Client{Timeout: timeout}
+		dial = func(ctx context.Context, server string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, client: client, url: server}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DNS resolver protocol %q", protocol)
+	}
+
+	ndots := defaultResolverNdots
+	if conf.Ndots > 0 {
+		ndots = conf.Ndots
+	}
+
+	searchDomains := make([]string, 0, len(conf.SearchDomains))
+	for _, domain := range conf.SearchDomains {
+		if domain = strings.Trim(domain, "."); domain != "" {
+			searchDomains = append(searchDomains, domain)
+		}
+	}
+
+	resolvers := make([]*net.Resolver, 0, len(servers))
+	for _, server := range servers {
+		resolvers = append(resolvers, &net.Resolver{
+			PreferGo: true,
+			// the address is the one of the host resolver, the configured server is queried instead
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dial(ctx, server)
+			},
+		})
+	}
+
+	return &Resolver{
+		resolvers:     resolvers,
+		timeout:       timeout,
+		searchDomains: searchDomains,
+		ndots:         ndots,
+	}, nil
+}
+
+func withDefaultPort(servers []string, port string) []string {
+	withPort := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), port)
+		}
+		withPort = append(withPort, server)
+	}
+
+	return withPort
+}
+
+// LookupHost returns the addresses of a host, trying the names of the search domains in turn.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if r == nil {
+		return net.DefaultResolver.LookupHost(ctx, host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{host}, nil
+	}
+
+	var err error
+	for _, name := range r.searchNames(host) {
+		var addrs []string
+		if addrs, err = r.lookupHost(ctx, name); err == nil {
+			return addrs, nil
+		}
+	}
+
+	return nil, err
+}
+
+// lookupHost queries the servers in turn for a name, moving to the next server when a server
+// doesn't answer in time or fails to answer. The answer that the name doesn't exist is final.
+func (r *Resolver) lookupHost(ctx context.Context, name string) (addrs []string, err error) {
+	for _, resolver := range r.resolvers {
+		queryCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		addrs, err = resolver.LookupHost(queryCtx, name)
+		cancel()
+
+		var dnsErr *net.DNSError
+		if err == nil || errors.As(err, &dnsErr) && dnsErr.IsNotFound || ctx.Err() != nil {
+			return addrs, err
+		}
+	}
+
+	return nil, err
+}
+
+// searchNames returns the fully qualified names to query for a host, in order. The names are
+// fully qualified so that the search domains of the host resolver aren't applied.
+func (r *Resolver) searchNames(host string) []string {
+	if strings.HasSuffix(host, ".") {
+		return []string{host}
+	}
+
+	names := make([]string, 0, len(r.searchDomains)+1)
+	for _, domain := range r.searchDomains {
+		names = append(names, host+"."+domain+".")
+	}
+
+	if strings.Count(host, ".") >= r.ndots {
+		return append([]string{host + "."}, names...)
+	}
+
+	return append(names, host+".")
+}
+
+// WrapDialer returns the DialContext func of the dialer dialing the addresses resolved by the resolver.
+func (r *Resolver) WrapDialer(dialer *net.Dialer) DialContextFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return r.dial(dialer, ctx, network, address)
+	}
+}
+
+func (r *Resolver) dial(d *net.Dialer, ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if r == nil || err != nil || net.ParseIP(host) != nil {
+		return d.DialContext(ctx, network, address)
+	}
+
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	for _, addr := range addrs {
+		if conn, err = d.DialContext(ctx, network, net.JoinHostPort(addr, port)); err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// dohConn is a DNS over HTTPS connection. It takes the length-prefixed DNS messages of a stream
+// connection, which the Go resolver writes to connections which aren't a net.PacketConn.
+type dohConn struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+
+	query    bytes.Buffer
+	response bytes.Buffer
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.query.Write(b)
+
+	for c.query.Len() >= 2 {
+		size := int(binary.BigEndian.Uint16(c.query.Bytes()[:2]))
+		if c.query.Len() < size+2 {
+			break
+		}
+
+		c.query.Next(2)
+		if err := c.roundTrip(c.query.Next(size)); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+func (c *dohConn) roundTrip(msg []byte) error {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DNS over HTTPS server %s responded with status %d", c.url, resp.StatusCode)
+	}
+
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return err
+	}
+
+	var size [2]byte
+	binary.BigEndian.PutUint16(size[:], uint16(len(answer)))
+	c.response.Write(size[:])
+	c.response.Write(answer)
+	return nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.response.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.response.Read(b)
+}
+
+func (c *dohConn) Close() error                     { return nil }
+func (c *dohConn) LocalAddr() net.Addr              { return dohAddr(c.url) }
+func (c *dohConn) RemoteAddr() net.Addr             { return dohAddr(c.url) }
+func (c *dohConn) SetDeadline(time.Time) error      { return nil }
+func (c *dohConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(time.Time) error { return nil }
+
+type dohAddr string
+
+func (a dohAddr) Network() string { return ResolverProtocolHTTPS }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,197 @@
+package dnscache
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+var resolverRecords = map[string]string{
+	"svc.corp.example.": "10.0.0.1",
+	"api.example.com.":  "10.0.0.2",
+	"upstream.example.": "127.0.0.1",
+}
+
+func resolverAnswer(req *dns.Msg) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+
+	addr, ok := resolverRecords[req.Question[0].Name]
+	if !ok {
+		msg.Rcode = dns.RcodeNameError
+		return msg
+	}
+
+	if req.Question[0].Qtype == dns.TypeA {
+		msg.Answer = append(msg.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP(addr),
+		})
+	}
+
+	return msg
+}
+
+func startResolverServer(t *testing.T, network string) string {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		w.WriteMsg(resolverAnswer(req))
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{Handler: handler, NotifyStartedFunc: func() { close(started) }}
+
+	var addr string
+	if network == "udp" {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server.PacketConn, addr = conn, conn.LocalAddr().String()
+	} else {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server.Listener, addr = listener, listener.Addr().String()
+	}
+
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+
+	return addr
+}
+
+func TestResolver(t *testing.T) {
+	for _, network := range []string{ResolverProtocolUDP, ResolverProtocolTCP} {
+		t.Run(network, func(t *testing.T) {
+			resolver, err := NewResolver(config.DNSResolverConfig{
+				Servers:       []string{startResolverServer(t, network)},
+				Protocol:      network,
+				SearchDomains: []string{"corp.example."},
+			})
+			require.NoError(t, err)
+
+			addrs, err := resolver.LookupHost(context.Background(), "svc")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"10.0.0.1"}, addrs)
+
+			addrs, err = resolver.LookupHost(context.Background(), "api.example.com")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"10.0.0.2"}, addrs)
+
+			_, err = resolver.LookupHost(context.Background(), "unknown")
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("dial", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		go func() {
+			if conn, err := listener.Accept(); err == nil {
+				conn.Close()
+			}
+		}()
+
+		resolver, err := NewResolver(config.DNSResolverConfig{Servers: []string{startResolverServer(t, "udp")}})
+		require.NoError(t, err)
+
+		_, port, _ := net.SplitHostPort(listener.Addr().String())
+		conn, err := resolver.WrapDialer(&net.Dialer{})(context.Background(), "tcp", net.JoinHostPort("upstream.example", port))
+		require.NoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("failover", func(t *testing.T) {
+		// the first server doesn't answer, the second one fails
+		unreachable, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer unreachable.Close()
+
+		failing := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			msg := new(dns.Msg)
+			msg.SetRcode(req, dns.RcodeServerFailure)
+			w.WriteMsg(msg)
+		})
+		failingConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		failingServer := &dns.Server{PacketConn: failingConn, Handler: failing}
+		go failingServer.ActivateAndServe()
+		defer failingServer.Shutdown()
+
+		resolver, err := NewResolver(config.DNSResolverConfig{
+			Servers: []string{unreachable.LocalAddr().String(), failingConn.LocalAddr().String(), startResolverServer(t, "udp")},
+			Timeout: 1,
+		})
+		require.NoError(t, err)
+
+		addrs, err := resolver.LookupHost(context.Background(), "api.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.2"}, addrs)
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		_, err := NewResolver(config.DNSResolverConfig{})
+		assert.Error(t, err)
+
+		_, err = NewResolver(config.DNSResolverConfig{Servers: []string{"10.0.0.53"}, Protocol: "quic"})
+		assert.Error(t, err)
+
+		_, err = NewResolver(config.DNSResolverConfig{Servers: []string{"10.0.0.53"}, Protocol: ResolverProtocolHTTPS})
+		assert.Error(t, err)
+	})
+}
+
+func TestResolver_searchNames(t *testing.T) {
+	resolver, err := NewResolver(config.DNSResolverConfig{
+		Servers:       []string{"10.0.0.53"},
+		SearchDomains: []string{"corp.example", "example.com"},
+		Ndots:         2,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"svc.corp.example.", "svc.example.com.", "svc."}, resolver.searchNames("svc"))
+	assert.Equal(t, []string{"svc.ns.corp.example.", "svc.ns.example.com.", "svc.ns."}, resolver.searchNames("svc.ns"))
+	assert.Equal(t, []string{"a.b.c.", "a.b.c.corp.example.", "a.b.c.example.com."}, resolver.searchNames("a.b.c"))
+	assert.Equal(t, []string{"svc."}, resolver.searchNames("svc."))
+}
+
+func TestResolver_DNSOverHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, dohContentType, r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := new(dns.Msg)
+		require.NoError(t, req.Unpack(body))
+
+		answer, err := resolverAnswer(req).Pack()
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", dohContentType)
+		w.Write(answer)
+	}))
+	defer server.Close()
+
+	resolver, err := NewResolver(config.DNSResolverConfig{Servers: []string{server.URL}, Protocol: ResolverProtocolHTTPS})
+	require.NoError(t, err)
+
+	// trust the test server
+	resolver.resolvers[0].Dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return &dohConn{ctx: ctx, client: server.Client(), url: server.URL}, nil
+	}
+
+	addrs, err := resolver.LookupHost(context.Background(), "api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, addrs)
+}
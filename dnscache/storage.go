@@ -1,7 +1,7 @@
 package dnscache
 
 import (
-	"net"
+	"context"
 	"time"
 
 	"fmt"
@@ -17,11 +17,12 @@ type DnsCacheItem struct {
 
 // DnsCacheStorage is an in-memory cache of auto-purged dns query ip responses
 type DnsCacheStorage struct {
-	cache *cache.Cache
+	cache    *cache.Cache
+	resolver *Resolver
 }
 
 func NewDnsCacheStorage(expiration, checkInterval time.Duration) *DnsCacheStorage {
-	storage := DnsCacheStorage{cache.New(expiration, checkInterval)}
+	storage := DnsCacheStorage{cache: cache.New(expiration, checkInterval)}
 	return &storage
 }
 
@@ -89,5 +90,5 @@ func (dc *DnsCacheStorage) Clear() {
 }
 
 func (dc *DnsCacheStorage) resolveDNSRecord(host string) ([]string, error) {
-	return net.LookupHost(host)
+	return dc.resolver.LookupHost(context.Background(), host)
 }
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	defaultAirGappedImportInterval = 10
+	airGappedImportedDir           = "imported"
+)
+
+// airGappedKeys is the content of the files keys are imported from in air-gapped mode.
+type airGappedKeys struct {
+	// Sessions are the keys created or updated, by key.
+	Sessions map[string]*user.SessionState `json:"sessions"`
+	// Deleted are the keys deleted.
+	Deleted []string `json:"deleted"`
+	// Hashed is true when the keys are given hashed.
+	Hashed bool `json:"hashed"`
+}
+
+// exportAirGapped writes the payload of an RPC function shipping data to MDCB to a file of the
+// export path, named after the function.
+func (gw *Gateway) exportAirGapped(funcName, data string) error {
+	dir := gw.GetConfig().AirGapped.ExportPath
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	ext := ".json"
+	if funcName == "PurgeAnalyticsDataProtobuf" {
+		ext = ".pb"
+	}
+	name := fmt.Sprintf("%s-%d%s", funcName, time.Now().UnixNano(), ext)
+
+	// write to a temporary file first so that transfer processes never pick up partial files
+	tmp := filepath.Join(dir, "."+name)
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+// importAirGappedKeys imports the keys of the JSON files of the import path, in name order.
+// Imported files are moved to the imported directory, files which fail to import are left
+// in place to be retried.
+func (gw *Gateway) importAirGappedKeys() error {
+	dir := gw.GetConfig().AirGapped.ImportPath
+	if dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := gw.importAirGappedKeysFile(path); err != nil {
+			log.WithError(err).WithField("file", path).Error("Couldn't import keys")
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Join(dir, airGappedImportedDir), 0o755); err != nil {
+			return err
+		}
+
+		if err := os.Rename(path, filepath.Join(dir, airGappedImportedDir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gw *Gateway) importAirGappedKeysFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var keys airGappedKeys
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+
+	for keyName, session := range keys.Sessions {
+		if session == nil {
+			continue
+		}
+
+		session.KeyID = keyName
+		if err := gw.doAddOrUpdate(keyName, session, false, keys.Hashed); err != nil {
+			return err
+		}
+	}
+
+	for _, keyName := range keys.Deleted {
+		if keys.Hashed {
+			gw.handleDeleteHashedKey(keyName, "", "", false)
+		} else {
+			gw.handleDeleteKey(keyName, "", "-1", false)
+		}
+	}
+
+	log.WithField("file", path).WithField("updated", len(keys.Sessions)).WithField("deleted", len(keys.Deleted)).Info("Imported keys")
+
+	return nil
+}
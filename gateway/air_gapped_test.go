@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestAirGapped(t *testing.T) {
+	importPath, exportPath := t.TempDir(), t.TempDir()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AirGapped = config.AirGappedConfig{
+			Enabled:    true,
+			ImportPath: importPath,
+			ExportPath: exportPath,
+		}
+	})
+	defer ts.Close()
+
+	t.Run("export", func(t *testing.T) {
+		require.NoError(t, ts.Gw.exportAirGapped("PurgeAnalyticsData", `[{"api_id":"api"}]`))
+
+		files, err := filepath.Glob(filepath.Join(exportPath, "PurgeAnalyticsData-*.json"))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		data, err := os.ReadFile(files[0])
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"api_id":"api"}]`, string(data))
+	})
+
+	t.Run("import", func(t *testing.T) {
+		_, deletedKey := ts.CreateSession()
+
+		session := CreateStandardSession()
+		session.Rate = 42
+		session.AccessRights = map[string]user.AccessDefinition{"test": {APIID: "test"}}
+		keyName := ts.Gw.generateToken(session.OrgID, "imported-key")
+
+		keys, err := json.Marshal(airGappedKeys{
+			Sessions: map[string]*user.SessionState{keyName: session},
+			Deleted:  []string{deletedKey},
+		})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(importPath, "keys-1.json"), keys, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(importPath, "keys-2.json"), []byte("invalid"), 0o644))
+
+		require.NoError(t, ts.Gw.importAirGappedKeys())
+
+		imported, found := ts.Gw.GlobalSessionManager.SessionDetail(session.OrgID, keyName, false)
+		require.True(t, found)
+		assert.Equal(t, float64(42), imported.Rate)
+
+		_, found = ts.Gw.GlobalSessionManager.SessionDetail("", deletedKey, false)
+		assert.False(t, found)
+
+		assert.FileExists(t, filepath.Join(importPath, airGappedImportedDir, "keys-1.json"))
+		assert.FileExists(t, filepath.Join(importPath, "keys-2.json"), "files failing to import are retried")
+	})
+}
@@ -1,7 +1,6 @@
 package gateway
 
 import (
-	"fmt"
 	mathrand "math/rand"
 	"strings"
 	"sync"
@@ -56,6 +55,15 @@ type RedisAnalyticsHandler struct {
 	mu                          sync.Mutex
 	analyticsSerializer         serializer.AnalyticsSerializer
 
+	// ShardsStore is where the number of analytics keys in use is published.
+	ShardsStore  storage.Handler
+	keyShards    atomic.Int64
+	recordedHits atomic.Int64
+
+	// encodedBytes and encodedRecords estimate the size of the analytics lists.
+	encodedBytes   atomic.Int64
+	encodedRecords atomic.Int64
+
 	// testing purposes
 	mockEnabled   bool
 	mockRecordHit func(record *analytics.AnalyticsRecord)
@@ -78,6 +86,10 @@ func (r *RedisAnalyticsHandler) Init() {
 	r.workerBufferSize = recordsBufferSize / uint64(ps)
 	log.WithField("workerBufferSize", r.workerBufferSize).Debug("Analytics pool worker buffer size")
 	r.enableMultipleAnalyticsKeys = r.globalConf.AnalyticsConfig.EnableMultipleAnalyticsKeys
+	r.initKeyShards()
+	if r.enableMultipleAnalyticsKeys && r.globalConf.AnalyticsConfig.AutoTuneAnalyticsKeyShards {
+		go r.tuneKeyShardsLoop(r.Gw.ctx)
+	}
 	r.analyticsSerializer = serializer.NewAnalyticsSerializer(r.globalConf.AnalyticsConfig.SerializerType)
 
 	r.Start()
@@ -126,6 +138,8 @@ func (r *RedisAnalyticsHandler) RecordHit(record *analytics.AnalyticsRecord) err
 		return nil
 	}
 
+	r.recordedHits.Add(1)
+
 	// just send record to channel consumed by pool of workers
 	// leave all data crunching and Redis I/O work for pool workers
 	r.mu.Lock()
@@ -146,11 +160,7 @@ func (r *RedisAnalyticsHandler) recordWorker() {
 	// read records from channel and process
 	lastSentTs := time.Now()
 	for {
-		analyticKey := analyticsKeyName
-		if r.enableMultipleAnalyticsKeys {
-			suffix := mathrand.Intn(10)
-			analyticKey = fmt.Sprintf("%v_%v", analyticKey, suffix)
-		}
+		analyticKey := r.analyticsKey()
 		serliazerSuffix := r.analyticsSerializer.GetSuffix()
 		analyticKey += serliazerSuffix
 
@@ -210,6 +220,8 @@ func (r *RedisAnalyticsHandler) recordWorker() {
 				log.WithError(err).Error("Error encoding analytics data")
 			} else {
 				recordsBuffer = append(recordsBuffer, encoded)
+				r.encodedBytes.Add(int64(len(encoded)))
+				r.encodedRecords.Add(1)
 			}
 
 			// identify that buffer is ready to be sent
@@ -222,7 +234,12 @@ func (r *RedisAnalyticsHandler) recordWorker() {
 
 		// send data to Redis and reset buffer
 		if len(recordsBuffer) > 0 && (readyToSend || time.Since(lastSentTs) >= recordsBufferForcedFlushInterval) {
-			r.Store.AppendToSetPipelined(analyticKey, recordsBuffer)
+			// back off while the analytics lists exceed their budget, until they're purged
+			if r.Gw.analyticsBudget.exceeded() {
+				log.WithField("records", len(recordsBuffer)).Debug("Analytics budget exceeded, dropping records")
+			} else {
+				r.Store.AppendToSetPipelined(analyticKey, recordsBuffer)
+			}
 			recordsBuffer = recordsBuffer[:0]
 			lastSentTs = time.Now()
 		}
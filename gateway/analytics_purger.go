@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gocraft/health"
+
+	"github.com/TykTechnologies/tyk/rpc"
+)
+
+const (
+	purgerBackendRPC   = "rpc"
+	purgerBackendKafka = "kafka"
+	purgerBackendHTTP  = "http"
+)
+
+// analyticsPurgerEnabled returns true when the analytics records are purged to a backend, instead
+// of being left in Redis for Tyk Pump.
+func (gw *Gateway) analyticsPurgerEnabled() bool {
+	conf := gw.GetConfig().AnalyticsConfig
+	return conf.Type == "rpc" || conf.PurgerBackend.Type != ""
+}
+
+// newAnalyticsPurgerBackend returns the backend of `analytics_config.purger_backend`. The records
+// shipped to MDCB are exported to files in air-gapped mode.
+func (gw *Gateway) newAnalyticsPurgerBackend() (rpc.PurgerBackend, error) {
+	conf := gw.GetConfig()
+
+	switch conf.AnalyticsConfig.PurgerBackend.Type {
+	case "", purgerBackendRPC:
+		if conf.AirGapped.Enabled {
+			mainLog.Info("Air-gapped mode, exporting analytics to ", conf.AirGapped.ExportPath)
+			return rpc.SinkBackend(gw.exportAirGapped), nil
+		}
+		return rpc.RPCBackend{}, nil
+	case purgerBackendKafka:
+		return rpc.NewKafkaBackend(conf.AnalyticsConfig.PurgerBackend.Kafka)
+	case purgerBackendHTTP:
+		return rpc.NewHTTPBackend(conf.AnalyticsConfig.PurgerBackend.HTTP)
+	default:
+		return nil, fmt.Errorf("unknown analytics purger backend %q", conf.AnalyticsConfig.PurgerBackend.Type)
+	}
+}
+
+// analyticsPurgerStall returns the time without a successful purge after which the
+// analytics purger is reported failing.
+func (gw *Gateway) analyticsPurgerStall() time.Duration {
+	conf := gw.GetConfig().AnalyticsConfig
+	if conf.PurgeStallThreshold > 0 {
+		return time.Duration(conf.PurgeStallThreshold) * time.Second
+	}
+
+	stall := time.Duration(6*conf.PurgeInterval) * time.Second
+	if stall < time.Minute {
+		stall = time.Minute
+	}
+	return stall
+}
+
+// analyticsPurgerHealth returns the health check of the analytics purger, failing when no purge
+// succeeded within the stall threshold.
+func (gw *Gateway) analyticsPurgerHealth(stats rpc.PurgerStatsSnapshot) HealthCheckItem {
+	item := HealthCheckItem{
+		Status:        Pass,
+		ComponentType: System,
+		Time:          time.Now().Format(time.RFC3339),
+	}
+
+	last := stats.LastPurge
+	if last.IsZero() {
+		last = stats.Started
+	}
+
+	if stall := gw.analyticsPurgerStall(); time.Since(last) > stall {
+		item.Status = Fail
+		item.Output = fmt.Sprintf("No analytics purged for %v", time.Since(last).Truncate(time.Second))
+		if stats.LastError != "" {
+			item.Output += ": " + stats.LastError
+		}
+	}
+
+	return item
+}
+
+// analyticsPurgerStatsHandler returns the counters of the analytics purger.
+func (gw *Gateway) analyticsPurgerStatsHandler(w http.ResponseWriter, _ *http.Request) {
+	stats := gw.analyticsPurgerStats.Load()
+	if stats == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("Analytics purger isn't enabled"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, stats.Snapshot())
+}
+
+// instrumentAnalyticsPurger sends the counters of the analytics purger to StatsD.
+func (gw *Gateway) instrumentAnalyticsPurger(metadata health.Kvs) {
+	stats := gw.analyticsPurgerStats.Load()
+	if stats == nil {
+		return
+	}
+
+	snapshot := stats.Snapshot()
+	last := snapshot.LastPurge
+	if last.IsZero() {
+		last = snapshot.Started
+	}
+
+	job := instrument.NewJob("AnalyticsPurger")
+	job.GaugeKv("records_purged", float64(snapshot.RecordsPurged), metadata)
+	job.GaugeKv("failed_decodes", float64(snapshot.FailedDecodes), metadata)
+	job.GaugeKv("ship_failures", float64(snapshot.ShipFailures), metadata)
+	job.GaugeKv("seconds_since_last_purge", time.Since(last).Seconds(), metadata)
+}
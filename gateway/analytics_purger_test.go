@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/rpc"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestNewAnalyticsPurgerBackend(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	backend := func(t *testing.T, set func(*config.Config)) (rpc.PurgerBackend, error) {
+		t.Helper()
+
+		conf := ts.Gw.GetConfig()
+		set(&conf)
+		ts.Gw.SetConfig(conf)
+		return ts.Gw.newAnalyticsPurgerBackend()
+	}
+
+	assert.False(t, ts.Gw.analyticsPurgerEnabled(), "records are left to the pump")
+
+	b, err := backend(t, func(c *config.Config) { c.AnalyticsConfig.Type = "rpc" })
+	assert.NoError(t, err)
+	assert.IsType(t, rpc.RPCBackend{}, b)
+	assert.True(t, ts.Gw.analyticsPurgerEnabled())
+
+	b, err = backend(t, func(c *config.Config) { c.AirGapped.Enabled = true })
+	assert.NoError(t, err)
+	assert.IsType(t, rpc.SinkBackend(nil), b)
+
+	b, err = backend(t, func(c *config.Config) {
+		c.AnalyticsConfig.Type = ""
+		c.AnalyticsConfig.PurgerBackend.Type = "http"
+		c.AnalyticsConfig.PurgerBackend.HTTP.URL = "http://localhost/analytics"
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &rpc.HTTPBackend{}, b)
+	assert.True(t, ts.Gw.analyticsPurgerEnabled())
+
+	b, err = backend(t, func(c *config.Config) {
+		c.AnalyticsConfig.PurgerBackend.Type = "kafka"
+		c.AnalyticsConfig.PurgerBackend.Kafka.Brokers = []string{"localhost:9092"}
+		c.AnalyticsConfig.PurgerBackend.Kafka.Topic = "analytics"
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &rpc.KafkaBackend{}, b)
+
+	_, err = backend(t, func(c *config.Config) { c.AnalyticsConfig.PurgerBackend.Type = "file" })
+	assert.Error(t, err)
+}
+
+func TestAnalyticsPurgerHealth(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AnalyticsConfig.PurgeInterval = 1
+	})
+	defer ts.Close()
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/tyk/debug/analytics-purger", AdminAuth: true, Code: http.StatusNotFound})
+
+	stats := rpc.NewPurgerStats()
+	ts.Gw.analyticsPurgerStats.Store(stats)
+
+	assert.Equal(t, time.Minute, ts.Gw.analyticsPurgerStall())
+	assert.EqualValues(t, Pass, ts.Gw.analyticsPurgerHealth(stats.Snapshot()).Status)
+
+	stalled := stats.Snapshot()
+	stalled.Started = time.Now().Add(-2 * time.Minute)
+	stalled.LastError = "unavailable"
+	item := ts.Gw.analyticsPurgerHealth(stalled)
+	assert.EqualValues(t, Fail, item.Status)
+	assert.Contains(t, item.Output, "unavailable")
+
+	ts.Gw.gatherHealthChecks()
+	assert.Contains(t, ts.Gw.getHealthCheckInfo(), "analytics")
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/tyk/debug/analytics-purger", AdminAuth: true, Code: http.StatusOK, BodyMatch: `"records_purged":0`})
+}
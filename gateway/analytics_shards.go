@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	mathrand "math/rand"
+	"strconv"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+// analyticsShardsKeyName is the key the number of analytics keys in use is published at, for
+// purgers to discover it.
+const analyticsShardsKeyName = "tyk-system-analytics-shards"
+
+const (
+	defaultAnalyticsKeyShards          = 10
+	defaultAnalyticsKeyShardThroughput = 1000
+	analyticsShardsTuneInterval        = 10 * time.Second
+)
+
+// maxAnalyticsKeyShards returns the number of analytics keys of the configuration.
+func maxAnalyticsKeyShards(conf config.AnalyticsConfigConfig) int {
+	if conf.AnalyticsKeyShards > 0 {
+		return conf.AnalyticsKeyShards
+	}
+	return defaultAnalyticsKeyShards
+}
+
+// analyticsShardKey returns the analytics key of a shard.
+func analyticsShardKey(shard int) string {
+	return fmt.Sprintf("%v_%v", analyticsKeyName, shard)
+}
+
+// discoverAnalyticsKeyShards returns the number of analytics keys to purge: the number
+// published by the gateways, or the number of the configuration when it's larger.
+func discoverAnalyticsKeyShards(store storage.Handler, conf config.AnalyticsConfigConfig) int {
+	shards := maxAnalyticsKeyShards(conf)
+
+	value, err := store.GetKey(analyticsShardsKeyName)
+	if err != nil {
+		return shards
+	}
+
+	published, err := strconv.Atoi(value)
+	if err != nil {
+		log.WithError(err).Warning("Invalid number of analytics keys published")
+		return shards
+	}
+
+	return max(shards, published)
+}
+
+// analyticsKey returns the key a batch of records is written to.
+func (r *RedisAnalyticsHandler) analyticsKey() string {
+	if !r.enableMultipleAnalyticsKeys {
+		return analyticsKeyName
+	}
+
+	return analyticsShardKey(mathrand.Intn(int(r.keyShards.Load())))
+}
+
+// initKeyShards sets the number of analytics keys in use to the maximum of the configuration.
+func (r *RedisAnalyticsHandler) initKeyShards() {
+	shards := maxAnalyticsKeyShards(r.globalConf.AnalyticsConfig)
+	r.keyShards.Store(int64(shards))
+
+	if r.enableMultipleAnalyticsKeys && r.ShardsStore != nil {
+		r.publishKeyShards(shards)
+	}
+}
+
+// tuneKeyShardsLoop adjusts the number of analytics keys to the throughput of the records.
+func (r *RedisAnalyticsHandler) tuneKeyShardsLoop(ctx context.Context) {
+	tick := time.NewTicker(analyticsShardsTuneInterval)
+	defer tick.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			r.tuneKeyShards(now.Sub(last))
+			last = now
+		}
+	}
+}
+
+func (r *RedisAnalyticsHandler) tuneKeyShards(elapsed time.Duration) {
+	conf := r.globalConf.AnalyticsConfig
+
+	throughput := conf.AnalyticsKeyShardThroughput
+	if throughput <= 0 {
+		throughput = defaultAnalyticsKeyShardThroughput
+	}
+
+	recordsPerSecond := float64(r.recordedHits.Swap(0)) / elapsed.Seconds()
+
+	shards := int(recordsPerSecond/float64(throughput)) + 1
+	shards = min(shards, maxAnalyticsKeyShards(conf))
+
+	if previous := r.keyShards.Swap(int64(shards)); previous != int64(shards) {
+		log.WithField("keys", shards).WithField("records_per_second", recordsPerSecond).Debug("Tuned the number of analytics keys")
+	}
+
+	if r.ShardsStore != nil {
+		r.publishKeyShards(shards)
+	}
+}
+
+// publishKeyShards publishes the number of analytics keys in use. Records may be left in keys
+// above a decreased number until they're purged or expire, so the published number is only
+// decreased once it expired.
+func (r *RedisAnalyticsHandler) publishKeyShards(shards int) {
+	expireAfter := r.globalConf.AnalyticsConfig.StorageExpirationTime
+	if expireAfter <= 0 {
+		expireAfter = 60
+	}
+	ttl := int64(expireAfter) + int64(analyticsShardsTuneInterval.Seconds())
+
+	if value, err := r.ShardsStore.GetKey(analyticsShardsKeyName); err == nil {
+		if published, err := strconv.Atoi(value); err == nil && published > shards {
+			return
+		}
+	}
+
+	if err := r.ShardsStore.SetKey(analyticsShardsKeyName, strconv.Itoa(shards), ttl); err != nil {
+		log.WithError(err).Warning("Couldn't publish the number of analytics keys")
+	}
+}
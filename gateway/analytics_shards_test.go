@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestAnalyticsKeyShards(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AnalyticsConfig.EnableMultipleAnalyticsKeys = true
+		globalConf.AnalyticsConfig.AnalyticsKeyShards = 4
+		globalConf.AnalyticsConfig.AnalyticsKeyShardThroughput = 10
+	})
+	defer ts.Close()
+
+	handler := &ts.Gw.Analytics
+	store := handler.ShardsStore
+	conf := ts.Gw.GetConfig().AnalyticsConfig
+
+	_ = store.DeleteKey(analyticsShardsKeyName)
+	defer func() {
+		_ = store.DeleteKey(analyticsShardsKeyName)
+	}()
+
+	t.Run("records are written to the configured keys", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			assert.Contains(t, []string{"tyk-system-analytics_0", "tyk-system-analytics_1", "tyk-system-analytics_2", "tyk-system-analytics_3"}, handler.analyticsKey())
+		}
+	})
+
+	t.Run("the number of keys follows the throughput", func(t *testing.T) {
+		handler.recordedHits.Store(50)
+		handler.tuneKeyShards(10 * time.Second)
+		assert.Equal(t, int64(1), handler.keyShards.Load())
+		assert.Equal(t, 4, discoverAnalyticsKeyShards(store, conf), "the configured number of keys should be purged")
+
+		handler.recordedHits.Store(1000)
+		handler.tuneKeyShards(10 * time.Second)
+		assert.Equal(t, int64(4), handler.keyShards.Load(), "the number of keys should be capped")
+	})
+
+	t.Run("purgers discover the published number of keys", func(t *testing.T) {
+		conf := conf
+		conf.AnalyticsKeyShards = 2
+		assert.Equal(t, 4, discoverAnalyticsKeyShards(store, conf))
+
+		// the published number isn't decreased before it expires
+		handler.recordedHits.Store(0)
+		handler.tuneKeyShards(10 * time.Second)
+		assert.Equal(t, 4, discoverAnalyticsKeyShards(store, conf))
+
+		_ = store.DeleteKey(analyticsShardsKeyName)
+		assert.Equal(t, 2, discoverAnalyticsKeyShards(store, conf))
+	})
+}
@@ -49,6 +49,7 @@ import (
 	"github.com/TykTechnologies/tyk/config"
 
 	"github.com/TykTechnologies/tyk/internal/otel"
+	"github.com/TykTechnologies/tyk/internal/policy"
 	"github.com/TykTechnologies/tyk/internal/redis"
 	"github.com/TykTechnologies/tyk/internal/uuid"
 
@@ -449,6 +450,11 @@ func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed b
 		return apiError("Request malformed"), http.StatusBadRequest
 	}
 
+	if err := validateQuotaAlignments(newSession.QuotaAlignment, newSession.AccessRights, newSession.QuotaBuckets); err != nil {
+		log.WithError(err).Error("Rejected key with an invalid quota alignment")
+		return apiError(err.Error()), http.StatusBadRequest
+	}
+
 	mw := &BaseMiddleware{Gw: gw}
 	// TODO: handle apply policies error
 	mw.ApplyPolicies(newSession)
@@ -507,7 +513,11 @@ func (gw *Gateway) handleAddOrUpdate(keyName string, r *http.Request, isHashed b
 		}
 	} else {
 		newSession.DateCreated = time.Now()
-		keyName = gw.generateToken(newSession.OrgID, keyName)
+		if keyName == "" {
+			keyName = gw.keyGen.GenerateAuthKey(newSession.OrgID)
+		} else {
+			keyName = gw.generateToken(newSession.OrgID, keyName)
+		}
 	}
 
 	//set the original expiry if the content in payload is a past time
@@ -914,6 +924,28 @@ func (gw *Gateway) handleGetPolicyList() (interface{}, int) {
 	return polIDList, http.StatusOK
 }
 
+// validateQuotaAlignments returns an error when a quota alignment of a key or a policy, of its
+// access rights or of its quota buckets, has an unknown timezone.
+func validateQuotaAlignments(alignment *user.QuotaAlignment, rights map[string]user.AccessDefinition, buckets []user.QuotaBucket) error {
+	if err := alignment.Validate(); err != nil {
+		return err
+	}
+
+	for _, access := range rights {
+		if err := access.Limit.QuotaAlignment.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, bucket := range buckets {
+		if err := bucket.QuotaAlignment.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (gw *Gateway) handleAddOrUpdatePolicy(polID string, r *http.Request) (interface{}, int) {
 	if gw.GetConfig().Policies.PolicySource == "service" {
 		log.Error("Rejected new policy due to PolicySource = service")
@@ -931,6 +963,16 @@ func (gw *Gateway) handleAddOrUpdatePolicy(polID string, r *http.Request) (inter
 		return apiError("Request ID does not match that in policy! For Update operations these must match."), http.StatusBadRequest
 	}
 
+	if err := validateQuotaAlignments(newPol.QuotaAlignment, newPol.AccessRights, newPol.QuotaBuckets); err != nil {
+		log.WithError(err).Error("Rejected policy with an invalid quota alignment")
+		return apiError(err.Error()), http.StatusBadRequest
+	}
+
+	if err := policy.ValidateDynamicLimits(*newPol); err != nil {
+		log.WithError(err).Error("Rejected policy with invalid dynamic limits")
+		return apiError(err.Error()), http.StatusBadRequest
+	}
+
 	// Create a filename
 	polFilePath := filepath.Join(gw.GetConfig().Policies.PolicyPath, newPol.ID+".json")
 
@@ -1046,6 +1088,59 @@ func (gw *Gateway) handleGetAPIOAS(apiID string, modePublic bool) (interface{},
 
 }
 
+// handleExportPublicAPIOAS returns the OAS document of an API as the Gateway exposes it.
+func (gw *Gateway) handleExportPublicAPIOAS(apiID string) (interface{}, int) {
+	spec := gw.getApiSpec(apiID)
+	if spec == nil {
+		return apiError(apidef.ErrAPINotFound.Error()), http.StatusNotFound
+	}
+
+	if !spec.IsOAS {
+		return apiError(apidef.ErrOASGetForOldAPI.Error()), http.StatusBadRequest
+	}
+
+	publicOAS, err := gw.publicAPIOAS(spec)
+	if err != nil {
+		log.WithError(err).WithField("apiID", apiID).Error("Couldn't export the public OAS document")
+		return apiError("Couldn't export the public OAS document"), http.StatusInternalServerError
+	}
+
+	return publicOAS, http.StatusOK
+}
+
+// handleExportPublicAPIListOAS returns the OAS documents of the OAS APIs as the Gateway exposes them.
+func (gw *Gateway) handleExportPublicAPIListOAS() (interface{}, int) {
+	gw.apisMu.RLock()
+	specs := make([]*APISpec, 0, len(gw.apisByID))
+	for _, spec := range gw.apisByID {
+		if spec.IsOAS {
+			specs = append(specs, spec)
+		}
+	}
+	gw.apisMu.RUnlock()
+
+	apisList := make([]*oas.OAS, 0, len(specs))
+	for _, spec := range specs {
+		publicOAS, err := gw.publicAPIOAS(spec)
+		if err != nil {
+			log.WithError(err).WithField("apiID", spec.APIID).Error("Couldn't export the public OAS document")
+			return apiError("Couldn't export the public OAS document"), http.StatusInternalServerError
+		}
+		apisList = append(apisList, publicOAS)
+	}
+
+	return apisList, http.StatusOK
+}
+
+// publicAPIOAS returns the OAS document of spec reflecting what the Gateway exposes: the listen
+// path, the authentication, the internal endpoints and the headers set by the Gateway.
+func (gw *Gateway) publicAPIOAS(spec *APISpec) (*oas.OAS, error) {
+	gw.apisMu.RLock()
+	defer gw.apisMu.RUnlock()
+
+	return spec.OAS.PublicDocument(*spec.APIDefinition, getAPIURL(*spec.APIDefinition, gw.GetConfig()))
+}
+
 func (gw *Gateway) handleAddApi(r *http.Request, fs afero.Fs, oasEndpoint bool) (interface{}, int) {
 	var (
 		newDef             apidef.APIDefinition
@@ -1176,6 +1271,11 @@ func (gw *Gateway) handleUpdateApi(apiID string, r *http.Request, fs afero.Fs, o
 		oasObj oas.OAS
 	)
 
+	effectiveFrom, err := parseEffectiveFrom(r.FormValue("effective_from"))
+	if err != nil {
+		return apiError(err.Error()), http.StatusBadRequest
+	}
+
 	if oasEndpoint {
 		if !spec.IsOAS {
 			return apiError(apidef.ErrAPINotMigrated.Error()), http.StatusBadRequest
@@ -1212,6 +1312,10 @@ func (gw *Gateway) handleUpdateApi(apiID string, r *http.Request, fs afero.Fs, o
 		updateOASServers(spec, gw.GetConfig(), &newDef, &oasObj)
 		newDef.IsOAS = true
 
+		if !effectiveFrom.IsZero() {
+			return gw.scheduleAPIChange(&newDef, &oasObj, effectiveFrom)
+		}
+
 		err, errCode := gw.writeOASAndAPIDefToFile(fs, &newDef, &oasObj)
 		if err != nil {
 			return apiError(err.Error()), errCode
@@ -1220,6 +1324,10 @@ func (gw *Gateway) handleUpdateApi(apiID string, r *http.Request, fs afero.Fs, o
 	} else if !oasEndpoint {
 		newDef.IsOAS = false
 
+		if !effectiveFrom.IsZero() {
+			return gw.scheduleAPIChange(&newDef, nil, effectiveFrom)
+		}
+
 		err, errCode := gw.writeToFile(fs, newDef, newDef.APIID)
 		if err != nil {
 			return apiError(err.Error()), errCode
@@ -1386,6 +1494,7 @@ func (gw *Gateway) polHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	gw.recordDefinitionChange(policyHistory, r, obj, code)
 	doJSONWrite(w, code, obj)
 }
 
@@ -1423,12 +1532,17 @@ func (gw *Gateway) apiHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodDelete:
 		if apiID != "" {
 			log.Debug("Deleting API definition for: ", apiID)
+			deleted := gw.newAPITrashItem(apiID)
 			obj, code = gw.handleDeleteAPI(apiID)
+			if code == http.StatusOK {
+				gw.trash(deleted)
+			}
 		} else {
 			obj, code = apiError("Must specify an apiID to delete"), http.StatusBadRequest
 		}
 	}
 
+	gw.recordDefinitionChange(apiHistory, r, obj, code)
 	doJSONWrite(w, code, obj)
 }
 
@@ -1466,6 +1580,7 @@ func (gw *Gateway) apiOASPostHandler(w http.ResponseWriter, r *http.Request) {
 	log.Debug("Creating new definition file")
 	obj, code = gw.handleAddApi(r, afero.NewOsFs(), true)
 
+	gw.recordDefinitionChange(apiHistory, r, obj, code)
 	doJSONWrite(w, code, obj)
 }
 
@@ -1482,6 +1597,7 @@ func (gw *Gateway) apiOASPutHandler(w http.ResponseWriter, r *http.Request) {
 		obj, code = apiError("Must specify an apiID to update"), http.StatusBadRequest
 	}
 
+	gw.recordDefinitionChange(apiHistory, r, obj, code)
 	doJSONWrite(w, code, obj)
 }
 
@@ -1515,6 +1631,7 @@ func (gw *Gateway) apiOASPatchHandler(w http.ResponseWriter, r *http.Request) {
 	if oasObj.GetTykExtension() != nil && tykExtensionConfigParams == nil {
 		r.Body = ioutil.NopCloser(bytes.NewReader(reqBodyInBytes))
 		obj, code := gw.handleUpdateApi(apiID, r, afero.NewOsFs(), true)
+		gw.recordDefinitionChange(apiHistory, r, obj, code)
 		doJSONWrite(w, code, obj)
 		return
 	}
@@ -1556,6 +1673,7 @@ func (gw *Gateway) apiOASPatchHandler(w http.ResponseWriter, r *http.Request) {
 	log.Debugf("PATCHing API: %q", apiID)
 	obj, code := gw.handleUpdateApi(apiID, r, afero.NewOsFs(), true)
 
+	gw.recordDefinitionChange(apiHistory, r, obj, code)
 	doJSONWrite(w, code, obj)
 }
 
@@ -1577,11 +1695,19 @@ func (gw *Gateway) apiOASExportHandler(w http.ResponseWriter, r *http.Request) {
 		fileName = baseFileNamePublic
 	}
 
-	if apiID != "" {
+	switch {
+	case apiID != "" && scopePublic:
+		log.Debugf("Requesting public API definition for %q", apiID)
+		obj, code = gw.handleExportPublicAPIOAS(apiID)
+		fileName += "-" + apiID
+	case apiID != "":
 		log.Debugf("Requesting API definition for %q", apiID)
 		obj, code = gw.handleGetAPIOAS(apiID, scopePublic)
 		fileName += "-" + apiID
-	} else {
+	case scopePublic:
+		log.Debug("Requesting public API list")
+		obj, code = gw.handleExportPublicAPIListOAS()
+	default:
 		log.Debug("Requesting API list")
 		obj, code = gw.handleGetAPIListOAS(scopePublic)
 	}
@@ -1648,6 +1774,11 @@ func (gw *Gateway) keyHandler(w http.ResponseWriter, r *http.Request) {
 
 	case http.MethodDelete:
 		// Remove a key
+		deleted := gw.newKeyTrashItem(keyName, orgID, isHashed)
+		if deleted == nil && hashKeyFunction != "" {
+			deleted = gw.newKeyTrashItem(origKeyName, orgID, isHashed)
+		}
+
 		if !isHashed {
 			obj, code = gw.handleDeleteKey(keyName, orgID, apiID, true)
 		} else {
@@ -1661,6 +1792,9 @@ func (gw *Gateway) keyHandler(w http.ResponseWriter, r *http.Request) {
 				obj, code = gw.handleDeleteHashedKeyWithLogs(origKeyName, orgID, apiID, true)
 			}
 		}
+		if code == http.StatusOK {
+			gw.trash(deleted)
+		}
 	}
 
 	doJSONWrite(w, code, obj)
@@ -1791,12 +1925,7 @@ func (gw *Gateway) handleOrgAddOrUpdate(orgID string, r *http.Request) (interfac
 	}
 
 	if r.URL.Query().Get("reset_quota") == "1" {
-		sessionManager.ResetQuota(orgID, newSession, false)
-		newSession.QuotaRenews = time.Now().Unix() + newSession.QuotaRenewalRate
-		rawKey := QuotaKeyPrefix + storage.HashKey(orgID, gw.GetConfig().HashKeys)
-
-		// manage quotas separately
-		gw.DefaultQuotaStore.RemoveSession(orgID, rawKey, false)
+		gw.resetOrgQuota(orgID, sessionManager, newSession)
 	}
 
 	newSession.LastUpdated = strconv.Itoa(int(time.Now().Unix()))
@@ -1960,6 +2089,12 @@ func (gw *Gateway) createKeyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateQuotaAlignments(newSession.QuotaAlignment, newSession.AccessRights, newSession.QuotaBuckets); err != nil {
+		log.WithError(err).Error("Key creation failed.")
+		doJSONWrite(w, http.StatusBadRequest, apiError("Failed to create key - "+err.Error()))
+		return
+	}
+
 	newKey := gw.keyGen.GenerateAuthKey(newSession.OrgID)
 	if newSession.HMACEnabled {
 		newSession.HmacSecret = gw.keyGen.GenerateHMACSecret()
@@ -2289,7 +2424,9 @@ func (gw *Gateway) createOauthClient(w http.ResponseWriter, r *http.Request) {
 	doJSONWrite(w, http.StatusOK, clientData)
 }
 
-func (gw *Gateway) rotateOauthClient(keyName, apiID string) (interface{}, int) {
+// rotateOauthClient sets a new secret to a client. The previous secret is still accepted for
+// gracePeriod seconds, so that the client can be updated without downtime.
+func (gw *Gateway) rotateOauthClient(keyName, apiID string, gracePeriod int64) (interface{}, int) {
 	// check API
 	apiSpec := gw.getApiSpec(apiID)
 	if apiSpec == nil {
@@ -2313,6 +2450,11 @@ func (gw *Gateway) rotateOauthClient(keyName, apiID string) (interface{}, int) {
 		Description:       client.GetDescription(),
 	}
 
+	if gracePeriod > 0 {
+		updatedClient.PreviousSecret = client.GetSecret()
+		updatedClient.PreviousSecretExpires = time.Now().Add(time.Duration(gracePeriod) * time.Second).Unix()
+	}
+
 	err = apiSpec.OAuthManager.OsinServer.Storage.SetClient(storageID, apiSpec.OrgID, &updatedClient, true)
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -2390,6 +2532,12 @@ func (gw *Gateway) updateOauthClient(keyName, apiID string, r *http.Request) (in
 		Description:       updateClientData.Description,       // update
 	}
 
+	if prev, ok := client.(*OAuthClient); ok {
+		// keep the grace period of a rotation
+		updatedClient.PreviousSecret = prev.PreviousSecret
+		updatedClient.PreviousSecretExpires = prev.PreviousSecretExpires
+	}
+
 	err = apiSpec.OAuthManager.OsinServer.Storage.SetClient(storageID, apiSpec.OrgID, &updatedClient, true)
 	if err != nil {
 		log.WithFields(logrus.Fields{
@@ -2489,7 +2637,17 @@ func (gw *Gateway) rotateOauthClientHandler(w http.ResponseWriter, r *http.Reque
 	apiID := mux.Vars(r)["apiID"]
 	keyName := mux.Vars(r)["keyName"]
 
-	obj, code := gw.rotateOauthClient(keyName, apiID)
+	var gracePeriod int64
+	if grace := r.URL.Query().Get("grace_period"); grace != "" {
+		var err error
+		gracePeriod, err = strconv.ParseInt(grace, 10, 64)
+		if err != nil || gracePeriod < 0 {
+			doJSONWrite(w, http.StatusBadRequest, apiError("grace_period must be a number of seconds"))
+			return
+		}
+	}
+
+	obj, code := gw.rotateOauthClient(keyName, apiID, gracePeriod)
 
 	doJSONWrite(w, code, obj)
 }
@@ -3013,11 +3171,56 @@ func (gw *Gateway) validateOAS(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		synced, err := gw.checkOASSecuritySchemes(r, oasObj)
+		if err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+			return
+		}
+
+		if synced {
+			if reqBodyInBytes, err = oasObj.MarshalJSON(); err != nil {
+				doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+				return
+			}
+		}
+
 		r.Body = ioutil.NopCloser(bytes.NewReader(reqBodyInBytes))
 		next.ServeHTTP(w, r)
 	}
 }
 
+// checkOASSecuritySchemes checks that the security schemes of an OAS API match its Tyk
+// authentication. The mismatches are fixed with the syncSecurity query parameter, and otherwise
+// rejected when the security scheme validation is enabled, or logged. It returns true when the
+// OAS API was changed.
+func (gw *Gateway) checkOASSecuritySchemes(r *http.Request, oasObj *oas.OAS) (bool, error) {
+	if oasObj.GetTykExtension() == nil {
+		return false, nil
+	}
+
+	mismatches := oasObj.ValidateSecuritySchemes()
+	if len(mismatches) == 0 {
+		return false, nil
+	}
+
+	synced := false
+	if sync, _ := strconv.ParseBool(r.URL.Query().Get("syncSecurity")); sync {
+		mismatches = oasObj.SyncSecuritySchemes()
+		synced = true
+	}
+
+	err := oas.SecurityMismatchError(mismatches)
+	if err != nil && gw.GetConfig().OAS.ValidateSecuritySchemes {
+		return synced, err
+	}
+
+	if err != nil {
+		log.WithError(err).Warning("OAS API security schemes don't match the Tyk authentication")
+	}
+
+	return synced, nil
+}
+
 func (gw *Gateway) blockInDashboardMode(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if gw.GetConfig().UseDBAppConfigs {
@@ -3050,6 +3253,11 @@ func (gw *Gateway) makeImportedOASTykAPI(next http.HandlerFunc) http.HandlerFunc
 
 		oasObj.GetTykExtension().Server.ListenPath.Strip = true
 
+		if _, err = gw.checkOASSecuritySchemes(r, oasObj); err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+			return
+		}
+
 		apiInBytes, err := oasObj.MarshalJSON()
 		if err != nil {
 			doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
@@ -26,6 +26,7 @@ import (
 
 	"github.com/TykTechnologies/tyk/internal/graphengine"
 	"github.com/TykTechnologies/tyk/internal/httputil"
+	"github.com/TykTechnologies/tyk/internal/rate"
 
 	"github.com/getkin/kin-openapi/routers/gorillamux"
 
@@ -136,7 +137,8 @@ const (
 // path is on any of the white, black or ignored lists. This is generated as part of the
 // configuration init
 type URLSpec struct {
-	spec *regexp.Regexp
+	spec    *regexp.Regexp
+	pattern string
 
 	Status                    URLStatus
 	MethodActions             map[string]apidef.EndpointMethodMeta
@@ -228,6 +230,20 @@ type APISpec struct {
 	HasMock            bool
 	HasValidateRequest bool
 	OASRouter          routers.Router
+
+	namedInternalEndpoints map[string]*namedInternalEndpoint
+	allowedMethods         *allowedMethodsSpec
+	faults                 []compiledFault
+	upstreamRoutes         []*compiledUpstreamRoute
+	experiments            []*compiledExperiment
+	debugHeaders           *debugHeadersAllowList
+	upstreamTemplate       *texttemplate.Template
+	tokenizer              *tokenizer
+	fieldFilters           []compiledFieldFilter
+	statusRules            []compiledStatusRule
+	metering               *compiledMetering
+	upstreamErrorBudget    *upstreamErrorBudget
+	regexpErrors           []error
 }
 
 // GetSessionLifetimeRespectsKeyExpiration returns a boolean to tell whether session lifetime should respect to key expiration or not.
@@ -287,6 +303,10 @@ func (s *APISpec) Unload() {
 
 // Validate returns nil if s is a valid spec and an error stating why the spec is not valid.
 func (s *APISpec) Validate(oasConfig config.OASConfig) error {
+	if s.GlobalConfig.RegexpSafeMode.Enabled && len(s.regexpErrors) > 0 {
+		return fmt.Errorf("regular expressions rejected by the safe mode: %w", errors.Join(s.regexpErrors...))
+	}
+
 	if s.IsOAS {
 		err := s.OAS.Validate(context.Background(), oas.GetValidationOptionsFromConfig(oasConfig)...)
 		if err != nil {
@@ -350,6 +370,10 @@ func (a APIDefinitionLoader) MakeSpec(def *model.MergedAPI, logger *logrus.Entry
 		return currSpec, nil
 	}
 
+	if cachedSpec, ok := a.Gw.chainCache.spec(spec); ok {
+		return cachedSpec, nil
+	}
+
 	if logger == nil {
 		logger = logrus.NewEntry(log)
 	}
@@ -441,6 +465,28 @@ func (a APIDefinitionLoader) MakeSpec(def *model.MergedAPI, logger *logrus.Entry
 		spec.WhiteListEnabled[v.Name] = whiteListSpecs
 	}
 
+	spec.regexpErrors = compileRegexps(spec, a.Gw.GetConfig())
+	for _, err := range spec.regexpErrors {
+		logger.WithError(err).WithField("api_id", spec.APIID).Error("Invalid regular expression")
+	}
+
+	spec.namedInternalEndpoints = compileNamedInternalEndpoints(spec.APIDefinition, logger)
+	spec.allowedMethods = compileAllowedMethods(spec.AllowedMethods, a.Gw.GetConfig(), logger)
+	spec.faults = compileFaults(spec.FaultInjection, a.Gw.GetConfig(), logger)
+	spec.upstreamRoutes = compileUpstreamRoutes(spec.UpstreamRouting, logger)
+	spec.experiments = compileExperiments(spec.Experiments, spec.Protocol, logger)
+	spec.debugHeaders = compileDebugHeaders(spec.DebugHeaders, logger)
+	spec.upstreamTemplate = compileTenantUpstreamTemplate(spec.TenantIsolation, logger)
+	spec.tokenizer = compileTokenizer(spec.Tokenization, logger)
+	spec.fieldFilters = compileFieldFilters(spec.ResponseFieldFilters, a.Gw.GetConfig(), logger)
+	spec.statusRules = compileStatusRules(spec.ResponseStatusMapping, a.Gw.GetConfig(), logger)
+	spec.metering = compileMetering(spec.Metering, a.Gw.GetConfig(), logger)
+	spec.upstreamErrorBudget = a.Gw.loadUpstreamErrorBudget(spec)
+
+	if spec.RateLimiter != "" && !rate.IsLimiter(spec.RateLimiter) {
+		logger.Errorf("Unknown rate limiter %q, the rate limiter of the Gateway applies instead", spec.RateLimiter)
+	}
+
 	if spec.IsOAS && def.OAS != nil {
 		loader := openapi3.NewLoader()
 		if err := loader.ResolveRefsIn(&def.OAS.T, nil); err != nil {
@@ -699,6 +745,7 @@ func (a APIDefinitionLoader) prepareSpecs(apiDefs []model.MergedAPI, gwConfig co
 
 		spec, err := a.MakeSpec(&def, nil)
 		if err != nil {
+			a.Gw.recordLoadFailure(&def, loadStageSpec, err)
 			continue
 		}
 
@@ -778,7 +825,12 @@ func (a APIDefinitionLoader) loadDefFromFilePath(filePath string) (*APISpec, err
 		}
 	}
 
-	return a.MakeSpec(&nestDef, nil)
+	spec, err := a.MakeSpec(&nestDef, nil)
+	if err != nil {
+		a.Gw.recordLoadFailure(&nestDef, loadStageSpec, err)
+	}
+
+	return spec, err
 }
 
 func (a APIDefinitionLoader) getPathSpecs(apiVersionDef apidef.VersionInfo, conf config.Config) ([]URLSpec, bool) {
@@ -816,6 +868,7 @@ func (a APIDefinitionLoader) generateRegex(stringSpec string, newSpec *URLSpec,
 
 	newSpec.Status = specType
 	newSpec.spec = asRegex
+	newSpec.pattern = pattern
 }
 
 func (a APIDefinitionLoader) compilePathSpec(paths []string, specType URLStatus, conf config.Config) []URLSpec {
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+)
+
+const (
+	singleLabelWildcard = "*"
+	deepWildcard        = "**"
+)
+
+// apiHost is a host an API is routed on.
+type apiHost struct {
+	// Host is the hostname, empty for any host.
+	Host string `json:"host"`
+	// Template is the gorilla/mux host template the hostname is routed with.
+	Template string `json:"template"`
+	// Certificates are the certificates of the domain.
+	Certificates []string `json:"certificates,omitempty"`
+}
+
+// apiHosts returns the hosts a spec is routed on, the exact hostnames first. Custom domains
+// replace the hostname of the gateway, a single host with an empty hostname routes every host.
+func apiHosts(spec *APISpec, conf config.Config) []apiHost {
+	if !conf.EnableCustomDomains || (spec.Domain == "" && len(spec.Domains) == 0) {
+		return []apiHost{{Host: conf.HostName, Template: conf.HostName}}
+	}
+
+	if spec.DomainDisabled {
+		return []apiHost{{}}
+	}
+
+	var hosts []apiHost
+	if spec.Domain != "" {
+		hosts = append(hosts, apiHost{Host: spec.Domain, Template: hostTemplate(spec.Domain)})
+	}
+
+	for _, domain := range spec.Domains {
+		if domain.Host == "" {
+			continue
+		}
+		hosts = append(hosts, apiHost{
+			Host:         domain.Host,
+			Template:     hostTemplate(domain.Host),
+			Certificates: domain.Certificates,
+		})
+	}
+
+	if len(hosts) == 0 {
+		return []apiHost{{Host: conf.HostName, Template: conf.HostName}}
+	}
+
+	// wildcards would shadow the exact hostnames of the API if routed first
+	sort.SliceStable(hosts, func(i, j int) bool {
+		return !isWildcardHost(hosts[i].Host) && isWildcardHost(hosts[j].Host)
+	})
+
+	return hosts
+}
+
+// routingDomain returns the domains of the spec, to tell the APIs with the same listen path apart.
+func routingDomain(spec *APISpec) string {
+	domain := spec.GetAPIDomain()
+	if spec.DomainDisabled {
+		return domain
+	}
+	for _, d := range spec.Domains {
+		domain += "," + d.Host
+	}
+	return domain
+}
+
+// hostTemplate returns the gorilla/mux host template of a hostname, its wildcard labels
+// become variables.
+func hostTemplate(host string) string {
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		switch label {
+		case singleLabelWildcard:
+			labels[i] = "{wildcard" + strconv.Itoa(i) + ":[^.]+}"
+		case deepWildcard:
+			labels[i] = "{wildcard" + strconv.Itoa(i) + ":.+}"
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// isWildcardHost returns true for hostnames with wildcard labels.
+func isWildcardHost(host string) bool {
+	for _, label := range strings.Split(host, ".") {
+		if label == singleLabelWildcard || label == deepWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWildcardDomain returns true when the spec is routed on a wildcard domain.
+func hasWildcardDomain(spec *APISpec) bool {
+	if isWildcardHost(spec.Domain) {
+		return true
+	}
+	for _, domain := range spec.Domains {
+		if isWildcardHost(domain.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost returns true when the hostname matches the host of a domain.
+func matchHost(pattern, host string) bool {
+	return matchLabels(strings.Split(pattern, "."), strings.Split(strings.ToLower(host), "."))
+}
+
+func matchLabels(pattern, labels []string) bool {
+	if len(pattern) == 0 {
+		return len(labels) == 0
+	}
+
+	switch pattern[0] {
+	case deepWildcard:
+		for n := 1; n <= len(labels); n++ {
+			if matchLabels(pattern[1:], labels[n:]) {
+				return true
+			}
+		}
+		return false
+	case singleLabelWildcard:
+		return len(labels) > 0 && labels[0] != "" && matchLabels(pattern[1:], labels[1:])
+	default:
+		return len(labels) > 0 && strings.EqualFold(pattern[0], labels[0]) && matchLabels(pattern[1:], labels[1:])
+	}
+}
+
+// domainCertificates returns the certificates of the first domain of the spec matching the
+// hostname.
+func domainCertificates(domains []apidef.APIDomain, host string) []string {
+	for _, domain := range domains {
+		if len(domain.Certificates) > 0 && matchHost(domain.Host, host) {
+			return domain.Certificates
+		}
+	}
+	return nil
+}
+
+// sortSpecsByRoutingOrder sorts the specs in the order they're routed: the APIs on wildcard
+// domains last, so that they don't shadow the APIs of the hostnames they match, then by
+// domain and listen path from longer to shorter, so that /foo doesn't break /foo-bar.
+func sortSpecsByRoutingOrder(specs []*APISpec) {
+	sort.Slice(specs, func(i, j int) bool {
+		if wi, wj := hasWildcardDomain(specs[i]), hasWildcardDomain(specs[j]); wi != wj {
+			return wj
+		}
+		if specs[i].Domain != specs[j].Domain {
+			return len(specs[i].Domain) > len(specs[j].Domain)
+		}
+		return len(specs[i].Proxy.ListenPath) > len(specs[j].Proxy.ListenPath)
+	})
+}
+
+// apiRoute is a route of the host routing table.
+type apiRoute struct {
+	apiHost
+	APIID      string `json:"api_id"`
+	Name       string `json:"name"`
+	ListenPath string `json:"listen_path"`
+	Port       int    `json:"port"`
+}
+
+// domainsHandler lists the host routing table, in routing order.
+func (gw *Gateway) domainsHandler(w http.ResponseWriter, _ *http.Request) {
+	conf := gw.GetConfig()
+
+	gw.apisMu.RLock()
+	specs := make([]*APISpec, len(gw.apiSpecs))
+	copy(specs, gw.apiSpecs)
+	gw.apisMu.RUnlock()
+
+	sortSpecsByRoutingOrder(specs)
+
+	routes := []apiRoute{}
+	for _, spec := range specs {
+		if spec.Protocol == "tcp" || spec.Protocol == "tls" {
+			continue
+		}
+
+		port := conf.ListenPort
+		if spec.ListenPort != 0 {
+			port = spec.ListenPort
+		}
+
+		for _, host := range apiHosts(spec, conf) {
+			routes = append(routes, apiRoute{
+				apiHost:    host,
+				APIID:      spec.APIID,
+				Name:       spec.Name,
+				ListenPath: spec.Proxy.ListenPath,
+				Port:       port,
+			})
+		}
+	}
+
+	doJSONWrite(w, http.StatusOK, routes)
+}
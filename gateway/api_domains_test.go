@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/crypto"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestMatchHost(t *testing.T) {
+	tcs := []struct {
+		pattern, host string
+		match         bool
+	}{
+		{"api.example.com", "API.example.com", true},
+		{"api.example.com", "eu.api.example.com", false},
+		{"*.api.example.com", "eu.api.example.com", true},
+		{"*.api.example.com", "a.eu.api.example.com", false},
+		{"*.api.example.com", "api.example.com", false},
+		{"**.api.example.com", "eu.api.example.com", true},
+		{"**.api.example.com", "a.eu.api.example.com", true},
+		{"**.api.example.com", "api.example.com", false},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.match, matchHost(tc.pattern, tc.host), "%s %s", tc.pattern, tc.host)
+	}
+}
+
+func TestMultipleDomains(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnableCustomDomains = true
+	})
+	defer ts.Close()
+
+	localClient := test.NewClientLocal()
+
+	ts.Gw.BuildAndLoadAPI(
+		func(spec *APISpec) {
+			spec.APIID = "wildcard"
+			spec.Proxy.ListenPath = "/"
+			spec.Domain = "example.com"
+			spec.Domains = []apidef.APIDomain{
+				{Host: "*.api.example.com"},
+				{Host: "**.deep.example.com"},
+			}
+		},
+		func(spec *APISpec) {
+			spec.APIID = "exact"
+			spec.Proxy.ListenPath = "/"
+			spec.Domains = []apidef.APIDomain{{Host: "eu.api.example.com"}}
+			spec.GlobalRateLimit = apidef.GlobalRateLimit{Rate: 1, Per: 60}
+		},
+	)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Client: localClient, Domain: "example.com", Path: "/", Code: http.StatusOK},
+		{Client: localClient, Domain: "us.api.example.com", Path: "/", Code: http.StatusOK},
+		{Client: localClient, Domain: "a.us.api.example.com", Path: "/", Code: http.StatusNotFound},
+		{Client: localClient, Domain: "a.b.deep.example.com", Path: "/", Code: http.StatusOK},
+		// the exact domain is routed before the wildcard matching it
+		{Client: localClient, Domain: "eu.api.example.com", Path: "/", Code: http.StatusOK},
+		{Client: localClient, Domain: "eu.api.example.com", Path: "/", Code: http.StatusTooManyRequests},
+		{
+			AdminAuth: true, Path: "/tyk/debug/domains", Code: http.StatusOK,
+			BodyMatch: `"host":"eu.api.example.com","template":"eu.api.example.com".*"api_id":"exact".*"host":"\*.api.example.com","template":"{wildcard0:\[\^.\]\+}.api.example.com".*"api_id":"wildcard"`,
+		},
+	}...)
+}
+
+func TestDomainCertificates(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HttpServerOptions.UseSSL = true
+		globalConf.HttpServerOptions.SSLCertificates = []string{}
+		globalConf.EnableCustomDomains = true
+	})
+	defer ts.Close()
+
+	_, _, apiPEM, _ := crypto.GenServerCertificate()
+	apiCertID, _ := ts.Gw.CertificateManager.Add(apiPEM, "")
+	defer ts.Gw.CertificateManager.Delete(apiCertID, "")
+
+	_, _, domainPEM, domainCert := crypto.GenCertificate(&x509.Certificate{DNSNames: []string{"*.api.example.com"}}, true)
+	domainCertID, _ := ts.Gw.CertificateManager.Add(domainPEM, "")
+	defer ts.Gw.CertificateManager.Delete(domainCertID, "")
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Domain = "localhost"
+		spec.Certificates = []string{apiCertID}
+		spec.Domains = []apidef.APIDomain{{Host: "*.api.example.com", Certificates: []string{domainCertID}}}
+	})
+
+	client := test.NewClientLocal(test.WithTransport(test.NewTransport(test.WithLocalDialer(), func(transport *http.Transport) {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	})))
+
+	resp, err := ts.Run(t, test.TestCase{Client: client, Domain: "eu.api.example.com", Code: http.StatusOK})
+	require.NoError(t, err)
+	assert.Equal(t, domainCert.Certificate[0], resp.TLS.PeerCertificates[0].Raw)
+
+	resp, err = ts.Run(t, test.TestCase{Client: client, Domain: "localhost", Code: http.StatusOK})
+	require.NoError(t, err)
+	assert.NotEqual(t, domainCert.Certificate[0], resp.TLS.PeerCertificates[0].Raw)
+}
@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/internal/reflect"
+)
+
+const (
+	exportArchiveName = "tyk-apis.zip"
+	exportAPIsDir     = "apis"
+	exportPoliciesDir = "policies"
+)
+
+// exportedAPIs returns the loaded APIs in OAS format, by API ID. Classic APIs are migrated,
+// so an API with versions yields an API for every version.
+func (gw *Gateway) exportedAPIs() map[string]*oas.OAS {
+	gw.apisMu.RLock()
+	defer gw.apisMu.RUnlock()
+
+	apis := make(map[string]*oas.OAS, len(gw.apisByID))
+
+	for apiID, spec := range gw.apisByID {
+		if spec.IsOAS {
+			oasObj := spec.OAS
+			oasObj.Fill(*spec.APIDefinition)
+			apis[apiID] = &oasObj
+			continue
+		}
+
+		// migration changes the definition, so work on a copy
+		def, err := reflect.Cast[apidef.APIDefinition](spec.APIDefinition)
+		if err == nil {
+			var base oas.APIDef
+			var versions []oas.APIDef
+
+			base, versions, err = oas.MigrateAndFillOAS(def)
+			if err == nil {
+				for _, api := range append([]oas.APIDef{base}, versions...) {
+					apis[api.Classic.APIID] = api.OAS
+				}
+				continue
+			}
+		}
+
+		log.WithFields(logrus.Fields{
+			"prefix": "api",
+			"api_id": apiID,
+		}).WithError(err).Warning("Couldn't convert API to OAS, skipping it from export")
+	}
+
+	return apis
+}
+
+// buildAPIExport writes a zip archive of the given APIs and of the policies granting
+// access to any of them, one JSON file per object.
+func (gw *Gateway) buildAPIExport(apis map[string]*oas.OAS) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	writeFile := func(name string, obj interface{}) error {
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(append(data, '\n'))
+		return err
+	}
+
+	apiIDs := make([]string, 0, len(apis))
+	for apiID := range apis {
+		apiIDs = append(apiIDs, apiID)
+	}
+	sort.Strings(apiIDs)
+
+	for _, apiID := range apiIDs {
+		if err := writeFile(path.Join(exportAPIsDir, apiID+".json"), apis[apiID]); err != nil {
+			return nil, fmt.Errorf("couldn't write API %s: %w", apiID, err)
+		}
+	}
+
+	polIDs := gw.PolicyIDs()
+	sort.Strings(polIDs)
+
+	for _, polID := range polIDs {
+		pol, ok := gw.PolicyByID(polID)
+		if !ok {
+			continue
+		}
+
+		for apiID := range pol.AccessRights {
+			if _, ok := apis[apiID]; !ok {
+				continue
+			}
+
+			if err := writeFile(path.Join(exportPoliciesDir, polID+".json"), pol); err != nil {
+				return nil, fmt.Errorf("couldn't write policy %s: %w", polID, err)
+			}
+			break
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// apiExportHandler exports all loaded APIs in OAS format along with the policies
+// referencing them, as a zip archive.
+func (gw *Gateway) apiExportHandler(w http.ResponseWriter, _ *http.Request) {
+	data, err := gw.buildAPIExport(gw.exportedAPIs())
+	if err != nil {
+		log.WithError(err).Error("Couldn't export APIs")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Export failed"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=%q", exportArchiveName))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestAPIExport(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "classic-api"
+		spec.Name = "classic"
+		spec.Proxy.ListenPath = "/classic/"
+	}, func(spec *APISpec) {
+		spec.APIID = "oas-api"
+		spec.Name = "oas"
+		spec.Proxy.ListenPath = "/oas/"
+		spec.IsOAS = true
+		spec.OAS = oas.OAS{T: openapi3.T{
+			OpenAPI: "3.0.3",
+			Info:    &openapi3.Info{Title: "oas", Version: "1"},
+			Paths:   make(openapi3.Paths),
+		}}
+		spec.OAS.Fill(*spec.APIDefinition)
+	})
+
+	ts.Gw.SetPoliciesByID(
+		user.Policy{ID: "referencing", AccessRights: map[string]user.AccessDefinition{"oas-api": {APIID: "oas-api"}}},
+		user.Policy{ID: "unrelated", AccessRights: map[string]user.AccessDefinition{"other-api": {APIID: "other-api"}}},
+	)
+
+	resp, _ := ts.Run(t, test.TestCase{Method: http.MethodGet, Path: "/tyk/apis/export", AdminAuth: true, Code: http.StatusOK,
+		HeadersMatch: map[string]string{"Content-Type": "application/zip"}})
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+
+	require.Len(t, files, 3)
+	require.Contains(t, files, "policies/referencing.json")
+
+	for _, name := range []string{"apis/classic-api.json", "apis/oas-api.json"} {
+		var api oas.OAS
+		require.Contains(t, files, name)
+		require.NoError(t, json.Unmarshal(files[name], &api))
+		assert.NotNil(t, api.GetTykExtension(), name)
+	}
+}
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,11 +10,11 @@ import (
 	"path"
 	"path/filepath"
 	"runtime/debug"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	texttemplate "text/template"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
@@ -38,6 +39,8 @@ type ChainObject struct {
 	RateLimitChain http.Handler
 	Open           bool
 	Skip           bool
+	// loadErr is the error the spec was skipped with.
+	loadErr error
 }
 
 func (gw *Gateway) prepareStorage() generalStores {
@@ -52,30 +55,26 @@ func (gw *Gateway) prepareStorage() generalStores {
 	return gs
 }
 
-func (gw *Gateway) skipSpecBecauseInvalid(spec *APISpec, logger *logrus.Entry) bool {
-
+// validateSpecForLoading returns an error when the spec can't be loaded.
+func (gw *Gateway) validateSpecForLoading(spec *APISpec) error {
 	switch spec.Protocol {
 	case "", "http", "https":
 		if spec.Proxy.ListenPath == "" {
-			logger.Error("Listen path is empty")
-			return true
+			return errors.New("Listen path is empty")
 		}
 		if strings.Contains(spec.Proxy.ListenPath, " ") {
-			logger.Error("Listen path contains spaces, is invalid")
-			return true
+			return errors.New("Listen path contains spaces, is invalid")
 		}
 	}
 	if val, err := gw.kvStore(spec.Proxy.TargetURL); err == nil {
 		spec.Proxy.TargetURL = val
 	}
 
-	_, err := url.Parse(spec.Proxy.TargetURL)
-	if err != nil {
-		logger.Error("couldn't parse target URL: ", err)
-		return true
+	if _, err := url.Parse(spec.Proxy.TargetURL); err != nil {
+		return fmt.Errorf("couldn't parse target URL: %w", err)
 	}
 
-	return false
+	return nil
 }
 
 func generateDomainPath(hostname, listenPath string) string {
@@ -86,7 +85,7 @@ func countApisByListenHash(specs []*APISpec) map[string]int {
 	count := make(map[string]int, len(specs))
 	// We must track the hostname no matter what
 	for _, spec := range specs {
-		domain := spec.GetAPIDomain()
+		domain := routingDomain(spec)
 		domainHash := generateDomainPath(domain, spec.Proxy.ListenPath)
 		if count[domainHash] == 0 {
 			if domain == "" {
@@ -171,9 +170,11 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		spec.TagHeaders = lowerCaseHeaders
 	}
 
-	if gw.skipSpecBecauseInvalid(spec, logger) {
+	if err := gw.validateSpecForLoading(spec); err != nil {
+		logger.Error(err)
 		logger.Warning("Spec not valid, skipped!")
 		chainDef.Skip = true
+		chainDef.loadErr = err
 		return &chainDef
 	}
 
@@ -184,7 +185,7 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 
 	pathModified := false
 	for {
-		domain := spec.GetAPIDomain()
+		domain := routingDomain(spec)
 		hash := generateDomainPath(domain, spec.Proxy.ListenPath)
 
 		if apisByListen[hash] < 2 {
@@ -299,6 +300,10 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		logger.Info("Checking security policy: Open")
 	}
 
+	gw.mwAppendEnabled(&chainArray, &DebugHeadersMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &SlowRequestMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &AllowedMethodsMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &HeaderPolicyMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &VersionCheck{BaseMiddleware: baseMid})
 
 	for _, obj := range mwPreFuncs {
@@ -323,9 +328,12 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 	gw.mwAppendEnabled(&chainArray, &RateCheckMW{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &IPWhiteListMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &IPBlackListMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &BruteForceProtectionMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &CertificateCheckMW{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &OrganizationMonitor{BaseMiddleware: baseMid, mon: Monitor{Gw: gw}})
 	gw.mwAppendEnabled(&chainArray, &RequestSizeLimitMiddleware{baseMid})
+	gw.mwAppendEnabled(&chainArray, &WSSecurityMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &FAPIMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &MiddlewareContextVars{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &TrackEndpointMiddleware{baseMid})
 
@@ -421,10 +429,18 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 		gw.mwAppendEnabled(&chainArray, &KeyExpired{baseMid})
 		gw.mwAppendEnabled(&chainArray, &AccessRightsCheck{baseMid})
 		gw.mwAppendEnabled(&chainArray, &GranularAccessMiddleware{baseMid})
+		// denied requests don't count against the rate limit and quota of the key
+		gw.mwAppendEnabled(&chainArray, &RequestPolicyMiddleware{BaseMiddleware: baseMid})
 		gw.mwAppendEnabled(&chainArray, &RateLimitAndQuotaCheck{baseMid})
+	} else {
+		gw.mwAppendEnabled(&chainArray, &RequestPolicyMiddleware{BaseMiddleware: baseMid})
 	}
 
+	gw.mwAppendEnabled(&chainArray, &ExternalAuthorizationMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &InboundDedupMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &PolicyBundleMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &RateLimitForAPI{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &TokenizationMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &GraphQLMiddleware{BaseMiddleware: baseMid})
 
 	if streamMw := getStreamingMiddleware(baseMid); streamMw != nil {
@@ -452,11 +468,16 @@ func (gw *Gateway) processSpec(spec *APISpec, apisByListen map[string]int,
 	gw.mwAppendEnabled(&chainArray, &TransformHeaders{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &URLRewriteMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &TransformMethod{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &UpstreamRoutingMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &ExperimentsMiddleware{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &TenantIsolationMiddleware{BaseMiddleware: baseMid})
 
 	// Earliest we can respond with cache get 200 ok
 	gw.mwAppendEnabled(&chainArray, &RedisCacheMiddleware{BaseMiddleware: baseMid, store: &cacheStore})
 
+	gw.mwAppendEnabled(&chainArray, &FaultInjectionMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &VirtualEndpoint{BaseMiddleware: baseMid})
+	gw.mwAppendEnabled(&chainArray, &InternalTokenMiddleware{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &RequestSigning{BaseMiddleware: baseMid})
 	gw.mwAppendEnabled(&chainArray, &GoPluginMiddleware{BaseMiddleware: baseMid})
 
@@ -606,6 +627,7 @@ func (d *DummyProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var handler http.Handler
+		targetSpec := d.SH.Spec
 		if r.URL.Hostname() == "self" {
 			if h, found := d.Gw.apisHandlesByID.Load(d.SH.Spec.APIID); found {
 				if chain, ok := h.(*ChainObject); ok {
@@ -618,6 +640,7 @@ func (d *DummyProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			ctxSetVersionInfo(r, nil)
 
 			if targetAPI := d.Gw.fuzzyFindAPI(r.URL.Hostname()); targetAPI != nil {
+				targetSpec = targetAPI
 				if h, found := d.Gw.apisHandlesByID.Load(targetAPI.APIID); found {
 					if chain, ok := h.(*ChainObject); ok {
 						handler = chain.ThisHandler
@@ -636,12 +659,35 @@ func (d *DummyProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		loopLevelLimit, _ := strconv.Atoi(r.URL.Query().Get("loop_limit"))
 		ctxSetCheckLoopLimits(r, r.URL.Query().Get("check_limits") == "true")
 
+		var endpointParams url.Values
+		if name, ok := namedInternalEndpointName(r.URL); ok {
+			params, err := rewriteToNamedInternalEndpoint(r, targetSpec, name)
+			if err != nil {
+				code := http.StatusBadRequest
+				if errors.Is(err, errInternalEndpointNotFound) {
+					code = http.StatusInternalServerError
+				}
+				handler := ErrorHandler{d.SH.Base()}
+				handler.HandleError(w, r, err.Error(), code, true)
+				return
+			}
+			endpointParams = params
+		}
+
 		if origURL := ctxGetOrigRequestURL(r); origURL != nil {
 			r.URL.Host = origURL.Host
 			r.URL.RawQuery = origURL.RawQuery
 			ctxSetOrigRequestURL(r, nil)
 		}
 
+		if len(endpointParams) > 0 {
+			query := r.URL.Query()
+			for name, values := range endpointParams {
+				query[name] = values
+			}
+			r.URL.RawQuery = query.Encode()
+		}
+
 		ctxIncLoopLevel(r, loopLevelLimit)
 		handler.ServeHTTP(w, r)
 		return
@@ -661,6 +707,10 @@ func (d *DummyProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if recorder := ctxGetTraceRecorder(r); recorder != nil {
+		defer recorder.record("proxy", otlpSpanKindClient, time.Now(), otlpStatus{}, otlpString("http.url", d.SH.Spec.Proxy.TargetURL))
+	}
+
 	d.SH.ServeHTTP(w, r)
 }
 
@@ -772,25 +822,22 @@ func (gw *Gateway) loadHTTPService(spec *APISpec, apisByListen map[string]int, g
 		muxer.setRouter(port, spec.Protocol, router, gwConfig)
 	}
 
-	hostname := gwConfig.HostName
-	if gwConfig.EnableCustomDomains && spec.Domain != "" {
-		hostname = spec.GetAPIDomain()
-	}
-
-	if hostname != "" {
-		mainLog.Info("API hostname set: ", hostname)
-		router = router.Host(hostname).Subrouter()
-	}
-
 	var chainObj *ChainObject
 	if curSpec := gw.getApiSpec(spec.APIID); !shouldReloadSpec(curSpec, spec) {
 		if chain, found := gw.apisHandlesByID.Load(spec.APIID); found {
 			chainObj = chain.(*ChainObject)
 		}
+	} else if chain, found := gw.chainCache.take(spec); found {
+		mainLog.Debugf("Reusing the cached middleware chain of spec %s", spec.APIID)
+		chainObj = chain
 	} else {
 		chainObj = gw.processSpec(spec, apisByListen, gs, logrus.NewEntry(log))
 	}
 
+	if chainObj.loadErr != nil {
+		gw.recordLoadFailure(spec.mergedAPI(), loadStageValidation, chainObj.loadErr)
+	}
+
 	if chainObj.Skip {
 		return chainObj
 	}
@@ -803,20 +850,28 @@ func (gw *Gateway) loadHTTPService(spec *APISpec, apisByListen map[string]int, g
 		spec.Proxy.ListenPath,
 	}
 
-	// Register routes for each prefix
-	for _, prefix := range prefixes {
-		subrouter := router.PathPrefix(prefix).Subrouter()
+	// Register routes for each host and prefix
+	for _, host := range apiHosts(spec, gwConfig) {
+		hostRouter := router
+		if host.Template != "" {
+			mainLog.Info("API hostname set: ", host.Host)
+			hostRouter = router.Host(host.Template).Subrouter()
+		}
 
-		gw.generateSubRoutes(spec, subrouter, logrus.NewEntry(log))
+		for _, prefix := range prefixes {
+			subrouter := hostRouter.PathPrefix(prefix).Subrouter()
 
-		if !chainObj.Open {
-			subrouter.Handle(rateLimitEndpoint, chainObj.RateLimitChain)
-		}
+			gw.generateSubRoutes(spec, subrouter, logrus.NewEntry(log))
 
-		httpHandler := explicitRouteSubpaths(prefix, chainObj.ThisHandler, gwConfig.HttpServerOptions.EnableStrictRoutes)
+			if !chainObj.Open {
+				subrouter.Handle(rateLimitEndpoint, chainObj.RateLimitChain)
+			}
 
-		// Attach handlers
-		subrouter.NewRoute().Handler(httpHandler)
+			httpHandler := explicitRouteSubpaths(prefix, chainObj.ThisHandler, gwConfig.HttpServerOptions.EnableStrictRoutes)
+
+			// Attach handlers
+			subrouter.NewRoute().Handler(httpHandler)
+		}
 	}
 
 	return chainObj
@@ -931,14 +986,7 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 	tmpSpecRegister := make(map[string]*APISpec)
 	tmpSpecHandles := new(sync.Map)
 
-	// sort by listen path from longer to shorter, so that /foo
-	// doesn't break /foo-bar
-	sort.Slice(specs, func(i, j int) bool {
-		if specs[i].Domain != specs[j].Domain {
-			return len(specs[i].Domain) > len(specs[j].Domain)
-		}
-		return len(specs[i].Proxy.ListenPath) > len(specs[j].Proxy.ListenPath)
-	})
+	sortSpecsByRoutingOrder(specs)
 
 	// Create a new handler for each API spec
 	apisByListen := countApisByListenHash(specs)
@@ -1011,6 +1059,29 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 
 	gw.apisMu.Lock()
 
+	chainCacheTTL := time.Duration(gw.GetConfig().ChainCacheTTL) * time.Second
+	if chainCacheTTL > 0 {
+		// the specs unloaded are kept with their chains, in case they're loaded back unchanged,
+		// while the specs replaced by a changed definition are unloaded right away below
+		now := time.Now()
+		for apiID, curSpec := range gw.apisByID {
+			if curSpec == nil || tmpSpecRegister[apiID] == curSpec {
+				continue
+			}
+
+			if newSpec := tmpSpecRegister[apiID]; newSpec != nil && shouldReloadSpec(curSpec, newSpec) {
+				continue
+			}
+
+			var chain *ChainObject
+			if h, found := gw.apisHandlesByID.Load(apiID); found {
+				chain, _ = h.(*ChainObject)
+			}
+			gw.chainCache.retire(curSpec, chain, now)
+		}
+		gw.chainCache.prune(tmpSpecRegister, now, chainCacheTTL)
+	}
+
 	for _, spec := range specs {
 		curSpec, ok := gw.apisByID[spec.APIID]
 		if ok && curSpec != nil && shouldReloadSpec(curSpec, spec) {
@@ -1030,12 +1101,16 @@ func (gw *Gateway) loadApps(specs []*APISpec) {
 	gw.apisHandlesByID = tmpSpecHandles
 
 	gw.apisMu.Unlock()
+	gw.invalidateFingerprint()
 
 	for _, spec := range specsToUnload {
 		mainLog.Debugf("Unloading spec %s", spec.APIID)
 		spec.Unload()
 	}
 
+	gw.syncCacheWarmers(specs)
+	gw.pruneUpstreamErrorBudgets(specs)
+
 	mainLog.Debug("Checker host list")
 
 	// Kick off our host checkers
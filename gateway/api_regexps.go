@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+// compileRegexps precompiles the URL rewrite patterns and triggers of the spec, so that none are
+// compiled while serving requests, and returns the errors of the regular expressions of the spec:
+// paths and URL rewrites which don't compile, and the expressions rejected by the safe mode.
+func compileRegexps(spec *APISpec, conf config.Config) []error {
+	safeMode := conf.RegexpSafeMode
+	limits := regexp.SafeLimits{
+		MaxRepeat:      safeMode.MaxRepeat,
+		MaxProgramSize: safeMode.MaxProgramSize,
+	}
+
+	var errs []error
+	check := func(kind, expr string) {
+		if !safeMode.Enabled || expr == "" {
+			return
+		}
+		if err := regexp.CheckSafe(expr, limits); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", kind, err))
+		}
+	}
+
+	for version, urlSpecs := range spec.RxPaths {
+		for i := range urlSpecs {
+			urlSpec := &urlSpecs[i]
+
+			if urlSpec.spec == nil {
+				errs = append(errs, fmt.Errorf("version %q: path pattern %q doesn't compile", version, urlSpec.pattern))
+			} else {
+				check(fmt.Sprintf("version %q: path", version), urlSpec.pattern)
+			}
+
+			if urlSpec.URLRewrite != nil {
+				errs = append(errs, compileURLRewrite(urlSpec.URLRewrite, check)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// compileURLRewrite compiles the match pattern and the triggers of a URL rewrite.
+func compileURLRewrite(meta *apidef.URLRewriteMeta, check func(kind, expr string)) []error {
+	var errs []error
+
+	kind := fmt.Sprintf("URL rewrite %s %s", meta.Method, meta.Path)
+
+	re, err := regexp.Compile(meta.MatchPattern)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: match pattern %q doesn't compile: %w", kind, meta.MatchPattern, err))
+	} else {
+		meta.MatchRegexp = re
+		check(kind, meta.MatchPattern)
+	}
+
+	initMatches := func(trigger int, matches map[string]apidef.StringRegexMap) {
+		for key, match := range matches {
+			if err := match.Init(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: trigger %d: pattern %q doesn't compile: %w", kind, trigger, match.MatchPattern, err))
+				continue
+			}
+			check(fmt.Sprintf("%s: trigger %d", kind, trigger), match.MatchPattern)
+			matches[key] = match
+		}
+	}
+
+	for i := range meta.Triggers {
+		options := &meta.Triggers[i].Options
+
+		initMatches(i, options.HeaderMatches)
+		initMatches(i, options.QueryValMatches)
+		initMatches(i, options.PathPartMatches)
+		initMatches(i, options.SessionMetaMatches)
+		initMatches(i, options.RequestContextMatches)
+		initMatches(i, options.ClaimMatches)
+
+		if options.PayloadMatches.MatchPattern != "" {
+			payload := map[string]apidef.StringRegexMap{"": options.PayloadMatches}
+			initMatches(i, payload)
+			options.PayloadMatches = payload[""]
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestRegexpSafeMode(t *testing.T) {
+	rewrite := func(listenPath, pattern string) func(*APISpec) {
+		return func(spec *APISpec) {
+			spec.APIID = listenPath
+			spec.Proxy.ListenPath = "/" + listenPath + "/"
+			UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+				v.UseExtendedPaths = true
+				v.ExtendedPaths.URLRewrite = []apidef.URLRewriteMeta{{
+					Path:         "/rewrite",
+					Method:       http.MethodGet,
+					MatchPattern: pattern,
+					RewriteTo:    "/rewritten",
+				}}
+			})
+		}
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		ts := StartTest(func(globalConf *config.Config) {
+			globalConf.RegexpSafeMode.Enabled = true
+		})
+		defer ts.Close()
+
+		ts.Gw.BuildAndLoadAPI(rewrite("safe", "/rewrite"), rewrite("unsafe", "(a+)+$"))
+
+		assert.NotNil(t, ts.Gw.getApiSpec("safe"))
+		assert.Nil(t, ts.Gw.getApiSpec("unsafe"))
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/safe/rewrite", Code: http.StatusOK, BodyMatch: `"Url":"/rewritten"`},
+			{Path: "/unsafe/rewrite", Code: http.StatusNotFound},
+		}...)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ts := StartTest(nil)
+		defer ts.Close()
+
+		ts.Gw.BuildAndLoadAPI(rewrite("unsafe", "(a+)+$"), rewrite("invalid", "(rewrite"))
+
+		spec := ts.Gw.getApiSpec("unsafe")
+		assert.NotNil(t, spec)
+		assert.Empty(t, spec.regexpErrors)
+		assert.NotNil(t, spec.RxPaths["v1"][0].URLRewrite.MatchRegexp, "the pattern is precompiled")
+
+		assert.Len(t, ts.Gw.getApiSpec("invalid").regexpErrors, 1)
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/invalid/rewrite", Code: http.StatusInternalServerError})
+	})
+}
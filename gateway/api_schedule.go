@@ -0,0 +1,249 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	// scheduledAPIChangesPrefix prefixes the Redis keys of staged API definition changes.
+	scheduledAPIChangesPrefix = "scheduled-api-change-"
+	// scheduledAPIChangesIndex is the Redis set holding the IDs of the APIs with a staged change,
+	// kept out of the prefix of the changes so that no API ID clashes with it.
+	scheduledAPIChangesIndex = "scheduled-api-changes-index"
+	// scheduledAPIActivationPrefix prefixes the Redis keys claiming the activation of a change.
+	scheduledAPIActivationPrefix = "scheduled-api-activation-"
+	// scheduledAPIChangesInterval is how often the Gateway checks for staged changes which are due.
+	scheduledAPIChangesInterval = time.Second
+)
+
+var errEffectiveFromInPast = errors.New("effective_from must be in the future")
+
+// scheduledAPIChange is an API definition update staged until EffectiveFrom.
+type scheduledAPIChange struct {
+	APIID         string                `json:"api_id"`
+	EffectiveFrom time.Time             `json:"effective_from"`
+	APIDefinition *apidef.APIDefinition `json:"api_definition"`
+	OAS           *oas.OAS              `json:"oas,omitempty"`
+}
+
+func (gw *Gateway) scheduledAPIChangesStore() *storage.RedisCluster {
+	return &storage.RedisCluster{KeyPrefix: scheduledAPIChangesPrefix, ConnectionHandler: gw.StorageConnectionHandler}
+}
+
+func (gw *Gateway) scheduledAPIChangesIndexStore() *storage.RedisCluster {
+	return &storage.RedisCluster{ConnectionHandler: gw.StorageConnectionHandler}
+}
+
+// parseEffectiveFrom parses the effective_from parameter of API updates. It returns the
+// zero time if the parameter is empty, in which case the update is applied immediately.
+func parseEffectiveFrom(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	effectiveFrom, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("effective_from must be an RFC3339 timestamp")
+	}
+
+	if !effectiveFrom.After(time.Now()) {
+		return time.Time{}, errEffectiveFromInPast
+	}
+
+	return effectiveFrom, nil
+}
+
+// scheduleAPIChange stages an API definition update in Redis, replacing any change
+// already staged for the API.
+func (gw *Gateway) scheduleAPIChange(apiDef *apidef.APIDefinition, oasObj *oas.OAS, effectiveFrom time.Time) (interface{}, int) {
+	change := scheduledAPIChange{
+		APIID:         apiDef.APIID,
+		EffectiveFrom: effectiveFrom.UTC(),
+		APIDefinition: apiDef,
+		OAS:           oasObj,
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		log.WithError(err).Error("Couldn't marshal scheduled API change")
+		return apiError("Marshalling failed"), http.StatusInternalServerError
+	}
+
+	store := gw.scheduledAPIChangesStore()
+	if err := store.SetKey(change.APIID, string(data), 0); err != nil {
+		log.WithError(err).Error("Couldn't store scheduled API change")
+		return apiError("Scheduling failed"), http.StatusInternalServerError
+	}
+	gw.scheduledAPIChangesIndexStore().AddToSet(scheduledAPIChangesIndex, change.APIID)
+
+	log.WithFields(logrus.Fields{
+		"prefix":         "api",
+		"api_id":         change.APIID,
+		"effective_from": change.EffectiveFrom.Format(time.RFC3339),
+	}).Info("API definition change scheduled")
+
+	return apiModifyKeySuccess{
+		Key:    change.APIID,
+		Status: "ok",
+		Action: "scheduled",
+	}, http.StatusOK
+}
+
+func (gw *Gateway) getScheduledAPIChange(apiID string) (*scheduledAPIChange, error) {
+	data, err := gw.scheduledAPIChangesStore().GetKey(apiID)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &scheduledAPIChange{}
+	if err := json.Unmarshal([]byte(data), change); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// activateScheduledAPIChanges publishes the staged changes which are due. The node
+// which claims a change in Redis removes it from the staging area and notifies the
+// cluster, so all nodes apply it together.
+func (gw *Gateway) activateScheduledAPIChanges() error {
+	store, index := gw.scheduledAPIChangesStore(), gw.scheduledAPIChangesIndexStore()
+
+	apiIDs, err := index.GetSet(scheduledAPIChangesIndex)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, apiID := range apiIDs {
+		change, err := gw.getScheduledAPIChange(apiID)
+		if err != nil {
+			if errors.Is(err, storage.ErrKeyNotFound) {
+				index.RemoveFromSet(scheduledAPIChangesIndex, apiID)
+				continue
+			}
+			log.WithError(err).WithField("api_id", apiID).Error("Couldn't load scheduled API change")
+			continue
+		}
+
+		if change.EffectiveFrom.After(now) {
+			continue
+		}
+
+		// Lock doesn't prefix its key, so the claim is made through the unprefixed store
+		claimed, err := index.Lock(scheduledAPIActivationPrefix+apiID+"-"+strconv.FormatInt(change.EffectiveFrom.UnixNano(), 10), time.Minute)
+		if err != nil || !claimed {
+			continue
+		}
+
+		payload, err := json.Marshal(change)
+		if err != nil {
+			log.WithError(err).WithField("api_id", apiID).Error("Couldn't marshal scheduled API change")
+			continue
+		}
+
+		store.DeleteKey(apiID)
+		index.RemoveFromSet(scheduledAPIChangesIndex, apiID)
+
+		revision := definitionRevision{Action: "scheduled"}
+		revision.Definition, _ = json.Marshal(change.APIDefinition)
+		if change.OAS != nil {
+			revision.OAS, _ = json.Marshal(change.OAS)
+		}
+		gw.recordRevision(apiHistory, apiID, "scheduler", revision)
+
+		gw.MainNotifier.Notify(Notification{Command: NoticeScheduledAPIChange, Payload: string(payload), Gw: gw})
+	}
+
+	return nil
+}
+
+// applyScheduledAPIChange writes the definition of an activated change to the app path
+// of this node, when the node loads its APIs from files. The definition is validated
+// before it is written. The caller reloads the APIs afterwards.
+func (gw *Gateway) applyScheduledAPIChange(payload string, fs afero.Fs) {
+	var change scheduledAPIChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil || change.APIDefinition == nil {
+		pubSubLog.Error("Scheduled API change malformed: ", err)
+		return
+	}
+
+	logger := pubSubLog.WithField("api_id", change.APIID)
+
+	conf := gw.GetConfig()
+	if conf.UseDBAppConfigs || conf.SlaveOptions.UseRPC || conf.AppPath == "" {
+		// the APIs of the node are loaded from the Dashboard or from MDCB
+		logger.Debug("Scheduled API change ignored, the APIs aren't loaded from files")
+		return
+	}
+
+	if change.APIID == "" || change.APIID != change.APIDefinition.APIID || filepath.Base(change.APIID) != change.APIID {
+		logger.Error("Scheduled API change rejected, its API ID doesn't match its definition")
+		return
+	}
+
+	if validationErr := validateAPIDef(change.APIDefinition); validationErr != nil {
+		logger.Error("Scheduled API change rejected: ", validationErr.Message)
+		return
+	}
+
+	if change.APIDefinition.IsOAS && change.OAS != nil {
+		if err := change.OAS.Validate(context.Background(), oas.GetValidationOptionsFromConfig(conf.OAS)...); err != nil {
+			logger.WithError(err).Error("Scheduled API change rejected")
+			return
+		}
+	}
+
+	var err error
+	if change.APIDefinition.IsOAS && change.OAS != nil {
+		err, _ = gw.writeOASAndAPIDefToFile(fs, change.APIDefinition, change.OAS)
+	} else {
+		err, _ = gw.writeToFile(fs, change.APIDefinition, change.APIID)
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("Couldn't apply scheduled API change")
+		return
+	}
+
+	logger.Info("Scheduled API change activated")
+}
+
+// scheduledAPIChangeHandler returns or cancels the change staged for an API.
+func (gw *Gateway) scheduledAPIChangeHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	change, err := gw.getScheduledAPIChange(apiID)
+	if err != nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("No change scheduled for this API"))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		gw.scheduledAPIChangesStore().DeleteKey(apiID)
+		gw.scheduledAPIChangesIndexStore().RemoveFromSet(scheduledAPIChangesIndex, apiID)
+
+		doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{
+			Key:    apiID,
+			Status: "ok",
+			Action: "cancelled",
+		})
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, change)
+}
@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestScheduledAPIChange(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "scheduled"
+		spec.Proxy.ListenPath = "/before/"
+	})[0]
+
+	updated := *spec.APIDefinition
+	updated.Proxy.ListenPath = "/after/"
+
+	schedulePath := func(effectiveFrom string) string {
+		return "/tyk/apis/scheduled?effective_from=" + url.QueryEscape(effectiveFrom)
+	}
+
+	t.Run("invalid effective_from", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPut, Path: schedulePath("tomorrow"), Data: updated, AdminAuth: true,
+				Code: http.StatusBadRequest, BodyMatch: "RFC3339"},
+			{Method: http.MethodPut, Path: schedulePath(time.Now().Add(-time.Hour).Format(time.RFC3339)), Data: updated, AdminAuth: true,
+				Code: http.StatusBadRequest, BodyMatch: errEffectiveFromInPast.Error()},
+		}...)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPut, Path: schedulePath(time.Now().Add(time.Hour).Format(time.RFC3339)), Data: updated, AdminAuth: true,
+				Code: http.StatusOK, BodyMatch: `"action":"scheduled"`},
+			{Method: http.MethodDelete, Path: "/tyk/apis/scheduled/scheduled", AdminAuth: true,
+				Code: http.StatusOK, BodyMatch: `"action":"cancelled"`},
+			{Method: http.MethodGet, Path: "/tyk/apis/scheduled/scheduled", AdminAuth: true, Code: http.StatusNotFound},
+		}...)
+	})
+
+	t.Run("api named after the index", func(t *testing.T) {
+		named := updated
+		named.APIID = "index"
+
+		_, code := ts.Gw.scheduleAPIChange(&named, nil, time.Now().Add(time.Hour))
+		require.Equal(t, http.StatusOK, code)
+		defer ts.Gw.scheduledAPIChangesStore().DeleteKey("index")
+		defer ts.Gw.scheduledAPIChangesIndexStore().RemoveFromSet(scheduledAPIChangesIndex, "index")
+
+		apiIDs, err := ts.Gw.scheduledAPIChangesIndexStore().GetSet(scheduledAPIChangesIndex)
+		require.NoError(t, err)
+		assert.Contains(t, apiIDs, "index")
+	})
+
+	t.Run("rejected changes", func(t *testing.T) {
+		apply := func(t *testing.T, def *apidef.APIDefinition) bool {
+			t.Helper()
+
+			payload, err := json.Marshal(scheduledAPIChange{APIID: "rejected", APIDefinition: def})
+			require.NoError(t, err)
+
+			path := filepath.Join(ts.Gw.GetConfig().AppPath, "rejected.json")
+			defer os.Remove(path)
+
+			ts.Gw.applyScheduledAPIChange(string(payload), afero.NewOsFs())
+			_, err = os.Stat(path)
+			return err == nil
+		}
+
+		valid := updated
+		valid.APIID = "rejected"
+		require.True(t, apply(t, &valid))
+
+		invalid := valid
+		invalid.EnableIpWhiteListing = true
+		invalid.AllowedIPs = []string{"not an IP"}
+		assert.False(t, apply(t, &invalid), "an invalid definition shouldn't be written")
+
+		mismatched := valid
+		mismatched.APIID = "other"
+		assert.False(t, apply(t, &mismatched), "a definition of another API shouldn't be written")
+
+		globalConf := ts.Gw.GetConfig()
+		globalConf.UseDBAppConfigs = true
+		ts.Gw.SetConfig(globalConf)
+		defer func() {
+			globalConf.UseDBAppConfigs = false
+			ts.Gw.SetConfig(globalConf)
+		}()
+		assert.False(t, apply(t, &valid), "the nodes loading their APIs from the Dashboard shouldn't write definitions")
+	})
+
+	t.Run("activation", func(t *testing.T) {
+		ts.Gw.ReloadTestCase.Enable()
+		defer ts.Gw.ReloadTestCase.Disable()
+
+		effectiveFrom := time.Now().Add(time.Second).Truncate(time.Second).Add(time.Second)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPut, Path: schedulePath(effectiveFrom.Format(time.RFC3339)), Data: updated, AdminAuth: true,
+				Code: http.StatusOK, BodyMatch: `"action":"scheduled"`},
+			{Method: http.MethodGet, Path: "/tyk/apis/scheduled/scheduled", AdminAuth: true,
+				Code: http.StatusOK, BodyMatch: `"listen_path":"/after/"`},
+		}...)
+
+		require.NoError(t, ts.Gw.activateScheduledAPIChanges())
+		_, _ = ts.Run(t, test.TestCase{Path: "/before/", Code: http.StatusOK})
+
+		time.Sleep(time.Until(effectiveFrom))
+		require.NoError(t, ts.Gw.activateScheduledAPIChanges())
+		ts.Gw.ReloadTestCase.TickOk(t)
+
+		spec := ts.Gw.getApiSpec("scheduled")
+		require.NotNil(t, spec)
+		assert.Equal(t, "/after/", spec.Proxy.ListenPath)
+
+		store := &storage.RedisCluster{ConnectionHandler: ts.Gw.StorageConnectionHandler}
+		_, err := store.GetRawKey(scheduledAPIActivationPrefix + "scheduled-" + strconv.FormatInt(effectiveFrom.UnixNano(), 10))
+		assert.NoError(t, err, "the activation is claimed under its own prefix")
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/after/", Code: http.StatusOK},
+			{Method: http.MethodGet, Path: "/tyk/apis/scheduled/scheduled", AdminAuth: true, Code: http.StatusNotFound},
+		}...)
+	})
+}
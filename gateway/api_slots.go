@@ -0,0 +1,261 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+)
+
+// API definition slots. Next to the active definition in the app path, every API can
+// have a staged definition, stored in a sub directory so it isn't loaded. Swapping
+// exchanges the two, so the previously active definition stays staged and the swap
+// can be rolled back until a new definition is staged.
+const (
+	apiSlotsDir       = "slots"
+	apiStagedSlot     = "staged"
+	apiSlotSwapMarker = "swapped"
+)
+
+var (
+	errNoStagedDefinition = errors.New("No staged definition for this API")
+	errNoSwapToRollback   = errors.New("No swap to roll back")
+)
+
+func (gw *Gateway) apiSlotPath(apiID, name string) string {
+	return filepath.Join(gw.GetConfig().AppPath, apiSlotsDir, apiID, name)
+}
+
+// apiDefinitionFiles returns the paths of the definition file and the OAS file of a slot,
+// without the extension. An empty slot name stands for the active definition.
+func (gw *Gateway) apiDefinitionFiles(apiID, slot string) []string {
+	base := filepath.Join(gw.GetConfig().AppPath, apiID)
+	if slot != "" {
+		base = gw.apiSlotPath(apiID, slot)
+	}
+
+	return []string{base + ".json", base + "-oas.json"}
+}
+
+func (gw *Gateway) handleStageAPI(spec *APISpec, r *http.Request, fs afero.Fs) (interface{}, int) {
+	var (
+		newDef apidef.APIDefinition
+		oasObj oas.OAS
+	)
+
+	if spec.IsOAS {
+		if err := json.NewDecoder(r.Body).Decode(&oasObj); err != nil {
+			log.Error("Couldn't decode new OAS object: ", err)
+			return apiError("Request malformed"), http.StatusBadRequest
+		}
+
+		oasObj.ExtractTo(&newDef)
+	} else if err := json.NewDecoder(r.Body).Decode(&newDef); err != nil {
+		log.Error("Couldn't decode new API Definition object: ", err)
+		return apiError("Request malformed"), http.StatusBadRequest
+	}
+
+	if newDef.APIID != spec.APIID {
+		return apiError("Request APIID does not match that in Definition! For Update operations these must match."), http.StatusBadRequest
+	}
+
+	if validationErr := validateAPIDef(&newDef); validationErr != nil {
+		return *validationErr, http.StatusBadRequest
+	}
+
+	slotDir := filepath.Dir(gw.apiSlotPath(spec.APIID, apiStagedSlot))
+	if err := fs.MkdirAll(slotDir, 0755); err != nil {
+		log.WithError(err).Error("Couldn't create API slot directory")
+		return apiError("file object creation failed, write error"), http.StatusInternalServerError
+	}
+
+	for _, path := range gw.apiDefinitionFiles(spec.APIID, apiStagedSlot) {
+		_ = fs.Remove(path)
+	}
+	_ = fs.Remove(gw.apiSlotPath(spec.APIID, apiSlotSwapMarker))
+
+	filename := filepath.Join(apiSlotsDir, spec.APIID, apiStagedSlot)
+
+	var err error
+	var errCode int
+	if spec.IsOAS {
+		updateOASServers(spec, gw.GetConfig(), &newDef, &oasObj)
+		newDef.IsOAS = true
+
+		if err, errCode = gw.writeToFile(fs, &newDef, filename); err == nil {
+			err, errCode = gw.writeToFile(fs, &oasObj, filename+"-oas")
+		}
+	} else {
+		newDef.IsOAS = false
+		err, errCode = gw.writeToFile(fs, newDef, filename)
+	}
+
+	if err != nil {
+		return apiError(err.Error()), errCode
+	}
+
+	return apiModifyKeySuccess{
+		Key:    spec.APIID,
+		Status: "ok",
+		Action: "staged",
+	}, http.StatusOK
+}
+
+// swapAPISlots exchanges the active and the staged definition files of an API.
+func (gw *Gateway) swapAPISlots(apiID string, fs afero.Fs) error {
+	active := gw.apiDefinitionFiles(apiID, "")
+	staged := gw.apiDefinitionFiles(apiID, apiStagedSlot)
+
+	if _, err := fs.Stat(staged[0]); err != nil {
+		return errNoStagedDefinition
+	}
+
+	for i := range active {
+		if err := swapFiles(fs, active[i], staged[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// swapFiles exchanges two files, either of which may not exist.
+func swapFiles(fs afero.Fs, a, b string) error {
+	tmp := b + ".swap"
+
+	_, errA := fs.Stat(a)
+	_, errB := fs.Stat(b)
+
+	if errA == nil {
+		if err := fs.Rename(a, tmp); err != nil {
+			return err
+		}
+	}
+
+	if errB == nil {
+		if err := fs.Rename(b, a); err != nil {
+			return err
+		}
+	}
+
+	if errA == nil {
+		return fs.Rename(tmp, b)
+	}
+
+	return nil
+}
+
+func (gw *Gateway) apiStagedSlotHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError(apidef.ErrAPINotFound.Error()))
+		return
+	}
+
+	fs := afero.NewOsFs()
+	files := gw.apiDefinitionFiles(apiID, apiStagedSlot)
+
+	switch r.Method {
+	case http.MethodPut:
+		obj, code := gw.handleStageAPI(spec, r, fs)
+		doJSONWrite(w, code, obj)
+	case http.MethodGet:
+		path := files[0]
+		if spec.IsOAS {
+			path = files[1]
+		}
+
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			doJSONWrite(w, http.StatusNotFound, apiError(errNoStagedDefinition.Error()))
+			return
+		}
+
+		doJSONWrite(w, http.StatusOK, json.RawMessage(data))
+	case http.MethodDelete:
+		if _, err := fs.Stat(files[0]); err != nil {
+			doJSONWrite(w, http.StatusNotFound, apiError(errNoStagedDefinition.Error()))
+			return
+		}
+
+		_ = fs.RemoveAll(filepath.Dir(files[0]))
+		doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{
+			Key:    apiID,
+			Status: "ok",
+			Action: "deleted",
+		})
+	}
+}
+
+// apiSlotSwapHandler activates the staged definition of an API and reloads the APIs.
+func (gw *Gateway) apiSlotSwapHandler(w http.ResponseWriter, r *http.Request) {
+	gw.handleAPISlotSwap(w, r, false)
+}
+
+// apiSlotRollbackHandler reverts the last swap of an API, provided no definition was staged since.
+func (gw *Gateway) apiSlotRollbackHandler(w http.ResponseWriter, r *http.Request) {
+	gw.handleAPISlotSwap(w, r, true)
+}
+
+func (gw *Gateway) handleAPISlotSwap(w http.ResponseWriter, r *http.Request, rollback bool) {
+	apiID := mux.Vars(r)["apiID"]
+	if gw.getApiSpec(apiID) == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError(apidef.ErrAPINotFound.Error()))
+		return
+	}
+
+	fs := afero.NewOsFs()
+	marker := gw.apiSlotPath(apiID, apiSlotSwapMarker)
+
+	if rollback {
+		if _, err := fs.Stat(marker); err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError(errNoSwapToRollback.Error()))
+			return
+		}
+	}
+
+	if err := gw.swapAPISlots(apiID, fs); err != nil {
+		if errors.Is(err, errNoStagedDefinition) {
+			doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+			return
+		}
+
+		log.WithError(err).WithField("api_id", apiID).Error("Couldn't swap API definition slots")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Swap failed"))
+		return
+	}
+
+	action := "swapped"
+	if rollback {
+		action = "rolled back"
+		_ = fs.Remove(marker)
+	} else if err := afero.WriteFile(fs, marker, nil, 0644); err != nil && !os.IsExist(err) {
+		log.WithError(err).WithField("api_id", apiID).Warning("Couldn't record API definition swap")
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix": "api",
+		"api_id": apiID,
+		"action": action,
+	}).Info("API definition slots swapped")
+
+	gw.recordFileRevision(apiHistory, apiID, action, requestAuthor(r))
+
+	gw.reloadURLStructure(nil)
+
+	doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{
+		Key:    apiID,
+		Status: "ok",
+		Action: action,
+	})
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestAPIDefinitionSlots(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.ReloadTestCase.Enable()
+	defer ts.Gw.ReloadTestCase.Disable()
+
+	blue := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "slots"
+		spec.Proxy.ListenPath = "/blue/"
+	})[0].APIDefinition
+
+	green := *blue
+	green.Proxy.ListenPath = "/green/"
+
+	_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/apis", Data: blue, AdminAuth: true, Code: http.StatusOK})
+	_, _ = ts.Run(t, test.TestCase{Method: http.MethodGet, Path: "/tyk/reload", AdminAuth: true, Code: http.StatusOK})
+	ts.Gw.ReloadTestCase.TickOk(t)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/blue/", Code: http.StatusOK},
+		{Method: http.MethodPost, Path: "/tyk/apis/slots/slots/swap", AdminAuth: true,
+			Code: http.StatusBadRequest, BodyMatch: errNoStagedDefinition.Error()},
+		{Method: http.MethodPost, Path: "/tyk/apis/slots/slots/rollback", AdminAuth: true,
+			Code: http.StatusBadRequest, BodyMatch: errNoSwapToRollback.Error()},
+		{Method: http.MethodPut, Path: "/tyk/apis/slots/slots/staged", Data: green, AdminAuth: true,
+			Code: http.StatusOK, BodyMatch: `"action":"staged"`},
+		{Method: http.MethodGet, Path: "/tyk/apis/slots/slots/staged", AdminAuth: true,
+			Code: http.StatusOK, BodyMatch: `"listen_path":"/green/"`},
+		{Method: http.MethodPost, Path: "/tyk/apis/slots/slots/swap", AdminAuth: true,
+			Code: http.StatusOK, BodyMatch: `"action":"swapped"`},
+	}...)
+	ts.Gw.ReloadTestCase.TickOk(t)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/green/", Code: http.StatusOK},
+		{Path: "/blue/", Code: http.StatusNotFound},
+		{Method: http.MethodGet, Path: "/tyk/apis/slots/slots/staged", AdminAuth: true,
+			Code: http.StatusOK, BodyMatch: `"listen_path":"/blue/"`},
+		{Method: http.MethodPost, Path: "/tyk/apis/slots/slots/rollback", AdminAuth: true,
+			Code: http.StatusOK, BodyMatch: `"action":"rolled back"`},
+	}...)
+	ts.Gw.ReloadTestCase.TickOk(t)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/blue/", Code: http.StatusOK},
+		{Path: "/green/", Code: http.StatusNotFound},
+		{Method: http.MethodPost, Path: "/tyk/apis/slots/slots/rollback", AdminAuth: true,
+			Code: http.StatusBadRequest, BodyMatch: errNoSwapToRollback.Error()},
+		{Method: http.MethodDelete, Path: "/tyk/apis/slots/slots/staged", AdminAuth: true, Code: http.StatusOK},
+		{Method: http.MethodGet, Path: "/tyk/apis/slots/slots/staged", AdminAuth: true, Code: http.StatusNotFound},
+	}...)
+}
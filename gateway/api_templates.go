@@ -0,0 +1,325 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/regexp"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	// templatesPrefix prefixes the Redis keys of key and policy templates.
+	templatesPrefix = "resource-template-"
+	// templatesIndex is the Redis set holding the names of the templates, kept out of the
+	// prefix of the templates so that no template name clashes with it.
+	templatesIndex = "resource-templates-index"
+
+	templateKindKey    = "key"
+	templateKindPolicy = "policy"
+)
+
+var (
+	templateNameRe        = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	templatePlaceholderRe = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
+	errTemplateNotFound = errors.New("Template not found")
+)
+
+// resourceTemplate is a named key or policy, whose body contains `${param}` placeholders
+// replaced when the template is instantiated.
+type resourceTemplate struct {
+	Name        string                       `json:"name"`
+	Kind        string                       `json:"kind"`
+	Description string                       `json:"description,omitempty"`
+	Parameters  map[string]templateParameter `json:"parameters,omitempty"`
+	Body        json.RawMessage              `json:"body"`
+}
+
+// templateParameter is a parameter of a template. Parameters without default are required.
+type templateParameter struct {
+	Description string          `json:"description,omitempty"`
+	Default     json.RawMessage `json:"default,omitempty"`
+}
+
+// templateInstantiation are the parameters a template is instantiated with.
+type templateInstantiation struct {
+	Params map[string]json.RawMessage `json:"params"`
+}
+
+func (gw *Gateway) templatesStore() *storage.RedisCluster {
+	return &storage.RedisCluster{KeyPrefix: templatesPrefix, ConnectionHandler: gw.StorageConnectionHandler}
+}
+
+func (gw *Gateway) templatesIndexStore() *storage.RedisCluster {
+	return &storage.RedisCluster{ConnectionHandler: gw.StorageConnectionHandler}
+}
+
+// validate checks the template can be instantiated: its kind is known, its body is a JSON
+// object and all of its placeholders are declared parameters.
+func (t *resourceTemplate) validate() error {
+	if !templateNameRe.MatchString(t.Name) {
+		return errors.New("template name must only contain letters, digits, '.', '_' and '-'")
+	}
+
+	if t.Kind != templateKindKey && t.Kind != templateKindPolicy {
+		return fmt.Errorf("template kind must be %q or %q", templateKindKey, templateKindPolicy)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(t.Body, &body); err != nil {
+		return errors.New("template body must be a JSON object")
+	}
+
+	for _, match := range templatePlaceholderRe.FindAllSubmatch(t.Body, -1) {
+		if _, ok := t.Parameters[string(match[1])]; !ok {
+			return fmt.Errorf("placeholder ${%s} is not a declared parameter", match[1])
+		}
+	}
+
+	for name, param := range t.Parameters {
+		if len(param.Default) > 0 && !json.Valid(param.Default) {
+			return fmt.Errorf("default of parameter %q must be a JSON value", name)
+		}
+	}
+
+	return nil
+}
+
+// render replaces the placeholders of the template body with the values of params, or the
+// defaults of the parameters. A string consisting of a single placeholder is replaced by the
+// value itself, so numbers and objects keep their type. Placeholders within strings and
+// object keys are replaced by the text of the value.
+func (t *resourceTemplate) render(params map[string]json.RawMessage) ([]byte, error) {
+	values := make(map[string]interface{}, len(t.Parameters))
+	for name, param := range t.Parameters {
+		raw, ok := params[name]
+		if !ok {
+			raw = param.Default
+		}
+
+		if len(raw) == 0 {
+			return nil, fmt.Errorf("missing parameter %q", name)
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("parameter %q must be a JSON value", name)
+		}
+		values[name] = value
+	}
+
+	for name := range params {
+		if _, ok := t.Parameters[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(t.Body, &body); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(substitutePlaceholders(body, values))
+}
+
+func substitutePlaceholders(node interface{}, values map[string]interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			out[substituteString(key, values)] = substitutePlaceholders(value, values)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = substitutePlaceholders(v[i], values)
+		}
+		return v
+	case string:
+		if match := templatePlaceholderRe.FindStringSubmatch(v); match != nil && match[0] == v {
+			return values[match[1]]
+		}
+		return substituteString(v, values)
+	default:
+		return v
+	}
+}
+
+func substituteString(s string, values map[string]interface{}) string {
+	return templatePlaceholderRe.ReplaceAllStringFunc(s, func(placeholder string) string {
+		value := values[placeholder[2:len(placeholder)-1]]
+		if str, ok := value.(string); ok {
+			return str
+		}
+
+		text, _ := json.Marshal(value)
+		return string(text)
+	})
+}
+
+func (gw *Gateway) getTemplate(name string) (*resourceTemplate, error) {
+	data, err := gw.templatesStore().GetKey(name)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return nil, errTemplateNotFound
+		}
+		return nil, err
+	}
+
+	template := &resourceTemplate{}
+	if err := json.Unmarshal([]byte(data), template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (gw *Gateway) handleGetTemplateList() (interface{}, int) {
+	names, err := gw.templatesIndexStore().GetSet(templatesIndex)
+	if err != nil {
+		return apiError("Couldn't list templates"), http.StatusInternalServerError
+	}
+
+	templates := []*resourceTemplate{}
+	for _, name := range names {
+		template, err := gw.getTemplate(name)
+		if err != nil {
+			continue
+		}
+		templates = append(templates, template)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates, http.StatusOK
+}
+
+func (gw *Gateway) handleAddOrUpdateTemplate(name string, r *http.Request) (interface{}, int) {
+	template := &resourceTemplate{}
+	if err := json.NewDecoder(r.Body).Decode(template); err != nil {
+		return apiError("Request malformed"), http.StatusBadRequest
+	}
+
+	if name != "" && template.Name != "" && template.Name != name {
+		return apiError("Request name does not match that in template!"), http.StatusBadRequest
+	}
+
+	if name != "" {
+		template.Name = name
+	}
+
+	if err := template.validate(); err != nil {
+		return apiError(err.Error()), http.StatusBadRequest
+	}
+
+	data, err := json.Marshal(template)
+	if err != nil {
+		return apiError("Marshalling failed"), http.StatusInternalServerError
+	}
+
+	store := gw.templatesStore()
+	if err := store.SetKey(template.Name, string(data), 0); err != nil {
+		log.WithError(err).Error("Couldn't store template")
+		return apiError("Failed to store template"), http.StatusInternalServerError
+	}
+	gw.templatesIndexStore().AddToSet(templatesIndex, template.Name)
+
+	action := "modified"
+	if r.Method == http.MethodPost {
+		action = "added"
+	}
+
+	return apiModifyKeySuccess{Key: template.Name, Status: "ok", Action: action}, http.StatusOK
+}
+
+// templatesHandler lists, returns, stores and deletes key and policy templates.
+func (gw *Gateway) templatesHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var obj interface{}
+	var code int
+
+	switch r.Method {
+	case http.MethodGet:
+		if name == "" {
+			obj, code = gw.handleGetTemplateList()
+			break
+		}
+
+		template, err := gw.getTemplate(name)
+		if err != nil {
+			obj, code = apiError(errTemplateNotFound.Error()), http.StatusNotFound
+			break
+		}
+		obj, code = template, http.StatusOK
+	case http.MethodPost, http.MethodPut:
+		obj, code = gw.handleAddOrUpdateTemplate(name, r)
+	case http.MethodDelete:
+		store := gw.templatesStore()
+		if !store.DeleteKey(name) {
+			obj, code = apiError(errTemplateNotFound.Error()), http.StatusNotFound
+			break
+		}
+		gw.templatesIndexStore().RemoveFromSet(templatesIndex, name)
+		obj, code = apiModifyKeySuccess{Key: name, Status: "ok", Action: "deleted"}, http.StatusOK
+	}
+
+	doJSONWrite(w, code, obj)
+}
+
+// templateInstantiateHandler renders a template with the parameters of the request and
+// creates the key or the policy, as the key and policy creation endpoints do.
+func (gw *Gateway) templateInstantiateHandler(w http.ResponseWriter, r *http.Request) {
+	template, err := gw.getTemplate(mux.Vars(r)["name"])
+	if err != nil {
+		doJSONWrite(w, http.StatusNotFound, apiError(errTemplateNotFound.Error()))
+		return
+	}
+
+	var instantiation templateInstantiation
+	if err := json.NewDecoder(r.Body).Decode(&instantiation); err != nil && !errors.Is(err, io.EOF) {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	body, err := template.render(instantiation.Params)
+	if err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	if template.Kind == templateKindPolicy {
+		// policies are stored by ID, so instances of templates without ID get a new one
+		var policy map[string]interface{}
+		_ = json.Unmarshal(body, &policy)
+		if id, _ := policy["id"].(string); strings.TrimSpace(id) == "" {
+			policy["id"] = uuid.NewHex()
+			body, _ = json.Marshal(policy)
+		}
+	}
+
+	create := r.Clone(r.Context())
+	create.Method = http.MethodPost
+	create.Body = io.NopCloser(bytes.NewReader(body))
+	create.ContentLength = int64(len(body))
+	create = mux.SetURLVars(create, map[string]string{})
+
+	if template.Kind == templateKindPolicy {
+		gw.polHandler(w, create)
+		return
+	}
+
+	gw.createKeyHandler(w, create)
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestTemplates(t *testing.T) {
+	policyPath := t.TempDir()
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Policies.PolicyPath = policyPath
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "bronze-api"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/bronze/"
+	})
+
+	keyTemplate := `{
+		"kind": "key",
+		"parameters": {"api_id": {}, "rate": {"default": 10}},
+		"body": {
+			"rate": "${rate}",
+			"per": 60,
+			"tags": ["tier-bronze", "rate-${rate}"],
+			"access_rights": {"${api_id}": {"api_id": "${api_id}", "versions": ["Default"]}}
+		}
+	}`
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates", Data: `{"name": "invalid name", "kind": "key", "body": {}}`, Code: http.StatusBadRequest},
+		{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates", Data: `{"name": "undeclared", "kind": "key", "body": {"rate": "${rate}"}}`,
+			BodyMatch: `not a declared parameter`, Code: http.StatusBadRequest},
+		{AdminAuth: true, Method: http.MethodPut, Path: "/tyk/templates/bronze-tier", Data: keyTemplate, Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodGet, Path: "/tyk/templates", BodyMatch: `"name":"bronze-tier"`, Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates/bronze-tier/instantiate", Data: `{"params": {}}`,
+			BodyMatch: `missing parameter \\"api_id\\"`, Code: http.StatusBadRequest},
+		{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates/bronze-tier/instantiate", Data: `{"params": {"api_id": "bronze-api", "other": 1}}`,
+			BodyMatch: `unknown parameter`, Code: http.StatusBadRequest},
+		{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates/unknown/instantiate", Code: http.StatusNotFound},
+		// the index of the templates isn't overwritten by a template named after it
+		{AdminAuth: true, Method: http.MethodPut, Path: "/tyk/templates/index", Data: `{"kind": "key", "body": {}}`, Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodGet, Path: "/tyk/templates", BodyMatch: `"name":"bronze-tier"`, Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/templates/index", Code: http.StatusOK},
+	}...)
+
+	t.Run("key", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates/bronze-tier/instantiate",
+			Data: `{"params": {"api_id": "bronze-api"}}`, Code: http.StatusOK})
+
+		var created apiModifyKeySuccess
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+		session, found := ts.Gw.GlobalSessionManager.SessionDetail("", created.Key, false)
+		require.True(t, found)
+		assert.Equal(t, float64(10), session.Rate)
+		assert.Contains(t, session.AccessRights, "bronze-api")
+		assert.Contains(t, session.Tags, "rate-10")
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates",
+			Data: `{"name": "gold", "kind": "policy", "parameters": {"quota": {}}, "body": {"name": "gold", "quota_max": "${quota}"}}`, Code: http.StatusOK})
+
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/templates/gold/instantiate",
+			Data: `{"params": {"quota": 1000}}`, Code: http.StatusOK})
+
+		var created apiModifyKeySuccess
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		require.NotEmpty(t, created.Key)
+
+		data, err := os.ReadFile(filepath.Join(policyPath, created.Key+".json"))
+		require.NoError(t, err)
+
+		var policy user.Policy
+		require.NoError(t, json.Unmarshal(data, &policy))
+		assert.Equal(t, int64(1000), policy.QuotaMax)
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/templates/gold", Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodGet, Path: "/tyk/templates/gold", Code: http.StatusNotFound},
+	}...)
+}
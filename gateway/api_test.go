@@ -128,6 +128,60 @@ func TestPolicyAPI(t *testing.T) {
 	})
 }
 
+func TestQuotaAlignmentValidation(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Policies.PolicyPath = t.TempDir()
+		globalConf.Policies.PolicySource = "file"
+	})
+	defer ts.Close()
+	ts.Gw.BuildAndLoadAPI()
+
+	alignment := &user.QuotaAlignment{Period: user.QuotaPeriodDay, Timezone: "Invalid/Zone"}
+
+	session := CreateStandardSession()
+	session.AccessRights = map[string]user.AccessDefinition{"test": {
+		APIID: "test", Versions: []string{"v1"}, Limit: user.APILimit{QuotaMax: 10, QuotaAlignment: alignment},
+	}}
+	sessionJSON := test.MarshalJSON(t)(session)
+
+	policy := user.Policy{ID: "aligned", QuotaMax: 10, QuotaAlignment: alignment}
+	policyJSON := test.MarshalJSON(t)(policy)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/tyk/keys/create", Data: string(sessionJSON), AdminAuth: true, Code: http.StatusBadRequest, BodyMatch: "Invalid/Zone"},
+		{Method: http.MethodPost, Path: "/tyk/keys/aligned", Data: string(sessionJSON), AdminAuth: true, Code: http.StatusBadRequest, BodyMatch: "Invalid/Zone"},
+		{Method: http.MethodPost, Path: "/tyk/policies/aligned", Data: string(policyJSON), AdminAuth: true, Code: http.StatusBadRequest, BodyMatch: "Invalid/Zone"},
+	}...)
+}
+
+func TestDynamicLimitsValidation(t *testing.T) {
+	dir := t.TempDir()
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Policies.PolicyPath = dir
+		globalConf.Policies.PolicySource = "file"
+	})
+	defer ts.Close()
+
+	valid := user.Policy{ID: "valid", DynamicLimits: []user.DynamicLimit{{When: `meta.tier == "gold"`, Rate: 100}}}
+	invalid := user.Policy{ID: "invalid", DynamicLimits: []user.DynamicLimit{{When: `meta.tier ==`, Rate: 100}}}
+
+	_, _ = ts.Run(t, test.TestCase{
+		Method: http.MethodPost, Path: "/tyk/policies/invalid", Data: string(test.MarshalJSON(t)(invalid)), AdminAuth: true,
+		Code: http.StatusBadRequest, BodyMatch: "invalid dynamic limit expression",
+	})
+
+	for _, pol := range []user.Policy{valid, invalid} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, pol.ID+".json"), test.MarshalJSON(t)(pol), 0o644))
+	}
+
+	count, err := ts.Gw.syncPolicies()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, found := ts.Gw.PolicyByID("invalid")
+	assert.False(t, found, "the policy with an invalid expression shouldn't be loaded")
+}
+
 func TestHealthCheckEndpoint(t *testing.T) {
 	ts := StartTest(nil)
 	defer ts.Close()
@@ -3624,6 +3678,42 @@ func testImportOAS(t *testing.T, ts *Test, testCase test.TestCase) string {
 	return importResp.Key
 }
 
+func TestOASSecuritySchemesSync(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.OAS.ValidateSecuritySchemes = true
+	})
+	defer ts.Close()
+
+	const apiID = "security-sync"
+
+	oasAPI := oas.OAS{T: openapi3.T{
+		OpenAPI: "3.0.3",
+		Info:    &openapi3.Info{Title: "security sync", Version: "1"},
+		Paths:   openapi3.Paths{},
+		Components: &openapi3.Components{SecuritySchemes: openapi3.SecuritySchemes{
+			"token": {Value: &openapi3.SecurityScheme{Type: "apiKey", In: "header", Name: "Authorization"}},
+		}},
+		Security: openapi3.SecurityRequirements{{"token": []string{}}},
+	}}
+	oasAPI.SetTykExtension(&oas.XTykAPIGateway{
+		Info:     oas.Info{Name: "security sync", ID: apiID, State: oas.State{Active: true}},
+		Upstream: oas.Upstream{URL: TestHttpAny},
+		Server:   oas.Server{ListenPath: oas.ListenPath{Value: "/security-sync/"}},
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/apis/oas", Data: &oasAPI,
+			BodyMatch: oas.ErrSecuritySchemesMismatch.Error(), Code: http.StatusBadRequest},
+		{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/apis/oas", Data: &oasAPI,
+			QueryParams: map[string]string{"syncSecurity": "true"}, BodyMatch: `"action":"added"`, Code: http.StatusOK},
+	}...)
+
+	ts.Gw.DoReload()
+
+	synced := testGetOASAPI(t, ts, apiID, "security sync", "security sync")
+	assert.Empty(t, synced.Security)
+}
+
 func TestGetAPI_WithVersionBaseID(t *testing.T) {
 	ts := StartTest(nil)
 	defer ts.Close()
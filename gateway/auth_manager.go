@@ -77,6 +77,9 @@ func (b *DefaultSessionManager) ResetQuota(keyName string, session *user.Session
 	// Fix the raw key
 	defaultKeys := []string{rateLimiterSentinelKey, rawKey}
 	keys := rawKeysWithAllowanceScope(defaultKeys, keyName, session)
+	for _, bucket := range session.QuotaBuckets {
+		keys = append(keys, quotaBucketPrefix(bucket.Name)+keyName)
+	}
 	b.store.DeleteRawKeys(keys)
 }
 
@@ -224,6 +227,9 @@ func (b *DefaultSessionManager) Sessions(filter string) []string {
 
 type DefaultKeyGenerator struct {
 	Gw *Gateway `json:"-"`
+
+	// plugin is the key generator Go plugin function.
+	plugin func(orgID string) string
 }
 
 func (gw *Gateway) generateToken(orgID, keyID string, customHashKeyFunction ...string) string {
@@ -247,6 +253,24 @@ func (gw *Gateway) generateToken(orgID, keyID string, customHashKeyFunction ...s
 
 // GenerateAuthKey is a utility function for generating new auth keys. Returns the storage key name and the actual key
 func (d DefaultKeyGenerator) GenerateAuthKey(orgID string) string {
+	if d.plugin != nil {
+		if key := d.plugin(orgID); key != "" {
+			return key
+		}
+	}
+
+	if format := d.Gw.keyFormat(orgID); format.Enabled() {
+		key, err := generateFormattedKey(format)
+		if err == nil {
+			return key
+		}
+
+		log.WithFields(logrus.Fields{
+			"prefix": "auth-mgr",
+			"orgID":  orgID,
+		}).WithError(err).Warning("Issue during formatted key generation")
+	}
+
 	return d.Gw.generateToken(orgID, "")
 }
 
@@ -0,0 +1,332 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/header"
+)
+
+const (
+	defaultCacheWarmUpRateLimit = 1
+	defaultCacheWarmUpTimeout   = 60
+	cacheWarmUpRemoteAddr       = "127.0.0.1"
+)
+
+var (
+	errCacheWarmUpUnsafeMethod = errors.New("only safe methods are warmed up")
+	errCacheWarmUpRateLimited  = errors.New("rate limited by the upstream")
+	errCacheWarmUpNotCacheable = errors.New("response status code isn't cached")
+	errCacheWarmUpNotLoaded    = errors.New("API isn't loaded")
+)
+
+// cacheWarmers runs the cache warm-up of the APIs prefetching endpoints into their cache.
+type cacheWarmers struct {
+	mu   sync.Mutex
+	apis map[string]*cacheWarmer
+}
+
+// cacheWarmer prefetches the warm-up endpoints of an API into its cache at interval. It is kept
+// across reloads of the API, warming up the endpoints of the last loaded spec.
+type cacheWarmer struct {
+	gw     *Gateway
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	spec      *APISpec
+	nextRun   time.Time
+	endpoints []cacheWarmUpStatus
+}
+
+// cacheWarmUpStatus is the warm-up status of an endpoint.
+type cacheWarmUpStatus struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	LastRun    int64  `json:"last_run,omitempty"`
+	LastStatus int    `json:"last_status,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+	Warmed     uint64 `json:"warmed"`
+	Failures   uint64 `json:"failures"`
+}
+
+// cacheWarmUpSnapshot is the warm-up status of an API.
+type cacheWarmUpSnapshot struct {
+	Interval  int64               `json:"interval"`
+	NextRun   int64               `json:"next_run,omitempty"`
+	Endpoints []cacheWarmUpStatus `json:"endpoints"`
+}
+
+// cacheWarmUpEnabled returns true if the API warms up endpoints. Only keyless APIs are warmed
+// up, as the responses of keyed APIs may vary by consumer.
+func cacheWarmUpEnabled(spec *APISpec) bool {
+	return spec.UseKeylessAccess && spec.CacheOptions.EnableCache && spec.CacheOptions.WarmUp.Enabled && len(spec.CacheOptions.WarmUp.Endpoints) > 0
+}
+
+func cacheWarmUpMethod(endpoint apidef.CacheWarmUpEndpoint) string {
+	if endpoint.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(endpoint.Method)
+}
+
+// cacheWarmUpURI returns the request URI of a warm-up endpoint, as requested by clients.
+func cacheWarmUpURI(spec *APISpec, endpoint apidef.CacheWarmUpEndpoint) string {
+	return singleJoiningSlash(spec.Proxy.ListenPath, endpoint.Path, false)
+}
+
+// isCacheWarmedUp returns true if the request is for a warm-up endpoint of the API. The
+// responses of these endpoints are cached for all the clients of the API.
+func (s *APISpec) isCacheWarmedUp(r *http.Request) bool {
+	if !cacheWarmUpEnabled(s) {
+		return false
+	}
+
+	uri := r.URL.RequestURI()
+	return slices.ContainsFunc(s.CacheOptions.WarmUp.Endpoints, func(endpoint apidef.CacheWarmUpEndpoint) bool {
+		return cacheWarmUpMethod(endpoint) == r.Method && cacheWarmUpURI(s, endpoint) == uri
+	})
+}
+
+// syncCacheWarmers starts the cache warm-up of the loaded APIs warming up endpoints, updates the
+// warm-up of the APIs reloaded, and stops the warm-up of the APIs unloaded.
+func (gw *Gateway) syncCacheWarmers(specs []*APISpec) {
+	gw.cacheWarmers.mu.Lock()
+	defer gw.cacheWarmers.mu.Unlock()
+
+	if gw.cacheWarmers.apis == nil {
+		gw.cacheWarmers.apis = map[string]*cacheWarmer{}
+	}
+
+	loaded := map[string]*APISpec{}
+	for _, spec := range specs {
+		if cacheWarmUpEnabled(spec) {
+			loaded[spec.APIID] = spec
+		}
+	}
+
+	for apiID, warmer := range gw.cacheWarmers.apis {
+		if _, ok := loaded[apiID]; !ok {
+			warmer.cancel()
+			delete(gw.cacheWarmers.apis, apiID)
+		}
+	}
+
+	for apiID, spec := range loaded {
+		if warmer, ok := gw.cacheWarmers.apis[apiID]; ok {
+			warmer.setSpec(spec)
+			continue
+		}
+
+		warmer := gw.newCacheWarmer(spec)
+		gw.cacheWarmers.apis[apiID] = warmer
+
+		ctx, cancel := context.WithCancel(gw.ctx)
+		warmer.cancel = cancel
+		go warmer.run(ctx)
+	}
+}
+
+func (gw *Gateway) newCacheWarmer(spec *APISpec) *cacheWarmer {
+	warmer := &cacheWarmer{gw: gw}
+	warmer.setSpec(spec)
+	return warmer
+}
+
+// setSpec sets the spec warmed up, keeping the warm-up status of the endpoints unless they changed.
+func (w *cacheWarmer) setSpec(spec *APISpec) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	endpoints := spec.CacheOptions.WarmUp.Endpoints
+	if w.spec == nil || !reflect.DeepEqual(w.spec.CacheOptions.WarmUp.Endpoints, endpoints) {
+		w.endpoints = make([]cacheWarmUpStatus, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			w.endpoints = append(w.endpoints, cacheWarmUpStatus{Method: cacheWarmUpMethod(endpoint), Path: endpoint.Path})
+		}
+	}
+
+	w.spec = spec
+}
+
+func (w *cacheWarmer) currentSpec() *APISpec {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.spec
+}
+
+func cacheWarmUpInterval(spec *APISpec) time.Duration {
+	if interval := spec.CacheOptions.WarmUp.Interval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	if timeout := spec.CacheOptions.CacheTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return defaultCacheWarmUpTimeout * time.Second
+}
+
+func (w *cacheWarmer) run(ctx context.Context) {
+	for {
+		spec := w.currentSpec()
+		wait := cacheWarmUpInterval(spec)
+		if retryAfter := w.warmUp(ctx, spec); retryAfter > wait {
+			wait = retryAfter
+		}
+
+		w.mu.Lock()
+		w.nextRun = time.Now().Add(wait)
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// warmUp prefetches the warm-up endpoints, at the rate limit of the warm-up. It stops when
+// the upstream rate limits the warm-up, returning the time the upstream asked to wait for.
+func (w *cacheWarmer) warmUp(ctx context.Context, spec *APISpec) time.Duration {
+	rate := spec.CacheOptions.WarmUp.RateLimit
+	if rate <= 0 {
+		rate = defaultCacheWarmUpRateLimit
+	}
+	pause := time.Duration(float64(time.Second) / rate)
+
+	for i, endpoint := range spec.CacheOptions.WarmUp.Endpoints {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return 0
+			case <-time.After(pause):
+			}
+		}
+
+		code, retryAfter, err := w.warmUpEndpoint(ctx, spec, endpoint)
+
+		w.mu.Lock()
+		// the endpoints may have changed on reload while warming up
+		if i < len(w.endpoints) && w.endpoints[i].Method == cacheWarmUpMethod(endpoint) && w.endpoints[i].Path == endpoint.Path {
+			status := &w.endpoints[i]
+			status.LastRun = time.Now().Unix()
+			status.LastStatus = code
+			status.LastError = ""
+			if err != nil {
+				status.LastError = err.Error()
+				status.Failures++
+			} else {
+				status.Warmed++
+			}
+		}
+		w.mu.Unlock()
+
+		if err != nil {
+			log.WithError(err).WithField("api_id", spec.APIID).WithField("path", endpoint.Path).Debug("Cache warm-up failed")
+		}
+
+		if code == http.StatusTooManyRequests {
+			return retryAfter
+		}
+	}
+
+	return 0
+}
+
+// warmUpEndpoint serves an endpoint through the middleware chain of the API, which fetches the
+// response from the upstream and stores it in the cache, under the key shared by the clients.
+func (w *cacheWarmer) warmUpEndpoint(ctx context.Context, spec *APISpec, endpoint apidef.CacheWarmUpEndpoint) (int, time.Duration, error) {
+	method := cacheWarmUpMethod(endpoint)
+	if !isSafeMethod(method) {
+		return 0, 0, errCacheWarmUpUnsafeMethod
+	}
+
+	w.gw.apisMu.RLock()
+	chain, found := w.gw.apisHandlesByID.Load(spec.APIID)
+	w.gw.apisMu.RUnlock()
+	if !found {
+		return 0, 0, errCacheWarmUpNotLoaded
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, cacheWarmUpURI(spec, endpoint), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.RemoteAddr = cacheWarmUpRemoteAddr
+
+	for name, value := range endpoint.Headers {
+		req.Header.Set(name, value)
+	}
+
+	ctxSetCacheWarmUp(req)
+	ctxSetDoNotTrack(req, true)
+
+	res := httptest.NewRecorder()
+	chain.(*ChainObject).ThisHandler.ServeHTTP(res, req)
+
+	if res.Code == http.StatusTooManyRequests {
+		retryAfter, _ := strconv.Atoi(res.Header().Get(header.RetryAfter))
+		return res.Code, time.Duration(retryAfter) * time.Second, errCacheWarmUpRateLimited
+	}
+
+	if !w.cacheable(spec, res.Code) {
+		return res.Code, 0, errCacheWarmUpNotCacheable
+	}
+
+	return res.Code, 0, nil
+}
+
+// cacheable returns true if responses with the status code are cached. When the API doesn't
+// restrict the cached status codes, only the successful responses are cached.
+func (w *cacheWarmer) cacheable(spec *APISpec, code int) bool {
+	if codes := spec.CacheOptions.CacheOnlyResponseCodes; len(codes) > 0 {
+		return slices.Contains(codes, code)
+	}
+	return code >= http.StatusOK && code < http.StatusMultipleChoices
+}
+
+func (w *cacheWarmer) snapshot() cacheWarmUpSnapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshot := cacheWarmUpSnapshot{
+		Interval:  int64(cacheWarmUpInterval(w.spec) / time.Second),
+		Endpoints: slices.Clone(w.endpoints),
+	}
+
+	if !w.nextRun.IsZero() {
+		snapshot.NextRun = w.nextRun.Unix()
+	}
+
+	return snapshot
+}
+
+// cacheWarmUpHandler returns the cache warm-up status of the APIs warming up endpoints.
+func (gw *Gateway) cacheWarmUpHandler(w http.ResponseWriter, _ *http.Request) {
+	gw.cacheWarmers.mu.Lock()
+	defer gw.cacheWarmers.mu.Unlock()
+
+	status := map[string]cacheWarmUpSnapshot{}
+	for apiID, warmer := range gw.cacheWarmers.apis {
+		status[apiID] = warmer.snapshot()
+	}
+
+	doJSONWrite(w, http.StatusOK, status)
+}
+
+func ctxSetCacheWarmUp(r *http.Request) {
+	setCtxValue(r, ctx.CacheWarmUp, true)
+}
+
+func ctxGetCacheWarmUp(r *http.Request) bool {
+	return r.Context().Value(ctx.CacheWarmUp) == true
+}
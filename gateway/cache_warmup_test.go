@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestCacheWarmUp(t *testing.T) {
+	var hits int32
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		assert.Equal(t, "/popular", r.URL.Path)
+		assert.Equal(t, "page=1", r.URL.RawQuery)
+		assert.Equal(t, "1", r.Header.Get("X-Warm"))
+		_, _ = w.Write([]byte("warmed"))
+	}))
+	defer upstream.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	warmAPI := func(spec *APISpec) {
+		spec.APIID = "warm-api"
+		spec.UseKeylessAccess = true
+		spec.Proxy.ListenPath = "/warm/"
+		spec.Proxy.StripListenPath = true
+		spec.Proxy.TargetURL = upstream.URL
+		spec.CacheOptions = apidef.CacheOptions{
+			EnableCache:  true,
+			CacheTimeout: 120,
+			WarmUp: apidef.CacheWarmUp{
+				Enabled:   true,
+				Interval:  3600,
+				Endpoints: []apidef.CacheWarmUpEndpoint{{Path: "/popular?page=1", Headers: map[string]string{"X-Warm": "1"}}},
+			},
+		}
+	}
+
+	ts.Gw.BuildAndLoadAPI(warmAPI)
+
+	warmer := func() *cacheWarmer {
+		ts.Gw.cacheWarmers.mu.Lock()
+		defer ts.Gw.cacheWarmers.mu.Unlock()
+		return ts.Gw.cacheWarmers.apis["warm-api"]
+	}
+
+	assert.Eventually(t, func() bool {
+		w := warmer()
+		return w != nil && w.snapshot().Endpoints[0].Warmed == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Path: "/tyk/debug/cache-warmup", BodyMatch: `"warm-api":{"interval":3600`, Code: http.StatusOK})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/warm/popular?page=1", BodyMatch: "warmed", HeadersMatch: map[string]string{cachedResponseHeader: "1"}, Code: http.StatusOK},
+		{Path: "/warm/popular?page=1", BodyMatch: "warmed", HeadersMatch: map[string]string{cachedResponseHeader: "1"}, Code: http.StatusOK},
+	}...)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits), "warmed up endpoints should be served from the cache")
+
+	t.Run("kept across reloads", func(t *testing.T) {
+		before := warmer()
+		ts.Gw.BuildAndLoadAPI(warmAPI)
+		assert.Same(t, before, warmer())
+		assert.Equal(t, uint64(1), warmer().snapshot().Endpoints[0].Warmed)
+	})
+
+	t.Run("keyed APIs aren't warmed up", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			warmAPI(spec)
+			spec.UseKeylessAccess = false
+		})
+
+		assert.Nil(t, warmer())
+
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"warm-api": {APIID: "warm-api"}}
+		})
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/warm/popular?page=1", Headers: map[string]string{header.Authorization: key, "X-Warm": "1"}, BodyMatch: "warmed", HeadersNotMatch: map[string]string{cachedResponseHeader: "1"}, Code: http.StatusOK})
+	})
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "other-api"
+	})
+
+	_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Path: "/tyk/debug/cache-warmup", BodyNotMatch: "warm-api", Code: http.StatusOK})
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// defaultCataloguePath is where the developer catalogue is served when no path is configured.
+const defaultCataloguePath = "/catalogue"
+
+// catalogueAPI is an API listed in the developer catalogue.
+type catalogueAPI struct {
+	APIID      string   `json:"api_id"`
+	Name       string   `json:"name"`
+	ListenPath string   `json:"listen_path"`
+	OAS        *oas.OAS `json:"oas"`
+}
+
+type catalogue struct {
+	APIs []catalogueAPI `json:"apis"`
+}
+
+// cataloguePath returns the path the developer catalogue is served at.
+func (gw *Gateway) cataloguePath() string {
+	if path := gw.GetConfig().DeveloperCatalogue.Path; path != "" {
+		return "/" + strings.TrimPrefix(path, "/")
+	}
+
+	return defaultCataloguePath
+}
+
+// catalogueHandler lists the loaded OAS APIs, except internal ones, with their public OAS
+// documents. When a key is required, only the APIs the key has access to are listed.
+func (gw *Gateway) catalogueHandler(w http.ResponseWriter, r *http.Request) {
+	var session *user.SessionState
+	if gw.GetConfig().DeveloperCatalogue.RequireKey {
+		var ok bool
+		if session, ok = gw.catalogueSession(r); !ok {
+			doJSONWrite(w, http.StatusForbidden, apiError("Access to this API has been disallowed"))
+			return
+		}
+	}
+
+	gw.apisMu.RLock()
+	specs := make([]*APISpec, 0, len(gw.apisByID))
+	for _, spec := range gw.apisByID {
+		specs = append(specs, spec)
+	}
+	gw.apisMu.RUnlock()
+
+	list := catalogue{APIs: []catalogueAPI{}}
+	for _, spec := range specs {
+		if !spec.IsOAS || spec.Internal {
+			continue
+		}
+
+		if session != nil {
+			if _, ok := session.AccessRights[spec.APIID]; !ok {
+				continue
+			}
+		}
+
+		publicOAS, err := gw.catalogueOAS(spec)
+		if err != nil {
+			log.WithError(err).WithField("apiID", spec.APIID).Error("Couldn't add API to the catalogue")
+			continue
+		}
+
+		list.APIs = append(list.APIs, catalogueAPI{
+			APIID:      spec.APIID,
+			Name:       spec.Name,
+			ListenPath: spec.Proxy.ListenPath,
+			OAS:        publicOAS,
+		})
+	}
+
+	sort.Slice(list.APIs, func(i, j int) bool {
+		return list.APIs[i].APIID < list.APIs[j].APIID
+	})
+
+	doJSONWrite(w, http.StatusOK, list)
+}
+
+// catalogueSession returns the active session of the key in the Authorization header.
+func (gw *Gateway) catalogueSession(r *http.Request) (*user.SessionState, bool) {
+	key := strings.TrimSpace(strings.TrimPrefix(r.Header.Get(header.Authorization), "Bearer "))
+	if key == "" {
+		return nil, false
+	}
+
+	session, ok := gw.GlobalSessionManager.SessionDetail("", key, false)
+	if !ok || session.IsInactive || gw.GlobalSessionManager.KeyExpired(&session) {
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// catalogueOAS returns the public OAS document of spec without security details, as API
+// consumers browsing the catalogue don't need them.
+func (gw *Gateway) catalogueOAS(spec *APISpec) (*oas.OAS, error) {
+	publicOAS, err := gw.publicAPIOAS(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	publicOAS.Security = nil
+	if publicOAS.Components != nil {
+		publicOAS.Components.SecuritySchemes = nil
+	}
+
+	for _, pathItem := range publicOAS.Paths {
+		for _, operation := range pathItem.Operations() {
+			operation.Security = nil
+		}
+	}
+
+	return publicOAS, nil
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestDeveloperCatalogue(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.DeveloperCatalogue.Enabled = true
+	})
+	defer ts.Close()
+
+	oasAPI := func(id string) func(spec *APISpec) {
+		return func(spec *APISpec) {
+			spec.APIID = id
+			spec.Name = id
+			spec.Proxy.ListenPath = "/" + id + "/"
+			spec.IsOAS = true
+			spec.OAS = oas.OAS{T: openapi3.T{
+				OpenAPI:  "3.0.3",
+				Info:     &openapi3.Info{Title: id, Version: "1"},
+				Servers:  openapi3.Servers{{URL: "https://internal.example.com"}},
+				Security: openapi3.SecurityRequirements{{"api_key": {}}},
+				Components: &openapi3.Components{SecuritySchemes: openapi3.SecuritySchemes{
+					"api_key": {Value: openapi3.NewSecurityScheme().WithType("apiKey").WithName("Authorization").WithIn("header")},
+				}},
+				Paths: openapi3.Paths{"/pets": &openapi3.PathItem{Get: &openapi3.Operation{
+					Security:  &openapi3.SecurityRequirements{{"api_key": {}}},
+					Responses: openapi3.NewResponses(),
+				}}},
+			}}
+			spec.OAS.Fill(*spec.APIDefinition)
+		}
+	}
+
+	ts.Gw.BuildAndLoadAPI(oasAPI("pets"), oasAPI("stores"), func(spec *APISpec) {
+		spec.APIID = "classic"
+		spec.Proxy.ListenPath = "/classic/"
+	}, func(spec *APISpec) {
+		oasAPI("internal")(spec)
+		spec.Internal = true
+	})
+
+	list := func(t *testing.T, headers map[string]string, code int) catalogue {
+		t.Helper()
+
+		resp, _ := ts.Run(t, test.TestCase{Path: defaultCataloguePath, Headers: headers, Code: code})
+
+		var list catalogue
+		if code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+		}
+		return list
+	}
+
+	t.Run("public", func(t *testing.T) {
+		apis := list(t, nil, http.StatusOK).APIs
+		require.Len(t, apis, 2)
+		assert.Equal(t, "pets", apis[0].APIID)
+		assert.Equal(t, "stores", apis[1].APIID)
+
+		doc := apis[0].OAS
+		assert.Nil(t, doc.GetTykExtension())
+		assert.Empty(t, doc.Security)
+		assert.Empty(t, doc.Components.SecuritySchemes)
+		assert.Nil(t, doc.Paths["/pets"].Get.Security)
+		require.Len(t, doc.Servers, 1)
+		assert.Equal(t, getAPIURL(*ts.Gw.getApiSpec("pets").APIDefinition, ts.Gw.GetConfig()), doc.Servers[0].URL)
+
+		// the loaded API is left untouched
+		assert.NotNil(t, ts.Gw.getApiSpec("pets").OAS.GetTykExtension())
+		assert.Len(t, ts.Gw.getApiSpec("pets").OAS.Security, 1)
+	})
+
+	t.Run("authenticated", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.DeveloperCatalogue.RequireKey = true
+		ts.Gw.SetConfig(globalConf)
+
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"stores": {APIID: "stores"}}
+		})
+
+		list(t, nil, http.StatusForbidden)
+		list(t, map[string]string{"Authorization": "unknown"}, http.StatusForbidden)
+
+		apis := list(t, map[string]string{"Authorization": "Bearer " + key}, http.StatusOK).APIs
+		require.Len(t, apis, 1)
+		assert.Equal(t, "stores", apis[0].APIID)
+	})
+}
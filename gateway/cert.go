@@ -119,6 +119,9 @@ func (gw *Gateway) verifyPeerCertificatePinnedCheck(spec *APISpec, tlsConfig *tl
 		return nil
 	}
 
+	// the chain is verified manually below as pinning requires InsecureSkipVerify
+	roots := tlsConfig.RootCAs
+	verifyChain := roots != nil && !tlsConfig.InsecureSkipVerify
 	tlsConfig.InsecureSkipVerify = true
 
 	whitelist := gw.getPinnedPublicKeys("*", spec, gw.GetConfig())
@@ -127,6 +130,12 @@ func (gw *Gateway) verifyPeerCertificatePinnedCheck(spec *APISpec, tlsConfig *tl
 	}
 
 	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if verifyChain {
+			if err := verifyUpstreamChain(rawCerts, roots, ""); err != nil {
+				return err
+			}
+		}
+
 		certLog.Debug("Checking certificate public key")
 
 		for _, rawCert := range rawCerts {
@@ -215,6 +224,24 @@ func (gw *Gateway) customDialTLSCheck(spec *APISpec, tc *tls.Config) func(networ
 
 		host, _, _ := net.SplitHostPort(addr)
 
+		// a per API CA pool is still enforced when the default verification is bypassed
+		if tc.RootCAs != nil && !tc.InsecureSkipVerify {
+			var rawCerts [][]byte
+			for _, cert := range c.ConnectionState().PeerCertificates {
+				rawCerts = append(rawCerts, cert.Raw)
+			}
+
+			dnsName := host
+			if checkCommonName {
+				dnsName = ""
+			}
+
+			if err := verifyUpstreamChain(rawCerts, tc.RootCAs, dnsName); err != nil {
+				c.Close()
+				return nil, err
+			}
+		}
+
 		if checkPinnedKeys {
 			isValid := gw.validatePublicKeys(host, c, spec)
 			if !isValid {
@@ -235,6 +262,50 @@ func (gw *Gateway) customDialTLSCheck(spec *APISpec, tc *tls.Config) func(networ
 	}
 }
 
+// upstreamCertPool returns the CA pool configured for the upstream of the API, or nil
+// when the API relies on the system roots.
+func (gw *Gateway) upstreamCertPool(spec *APISpec) *x509.CertPool {
+	if spec == nil || len(spec.Proxy.Transport.SSLCACertificates) == 0 {
+		return nil
+	}
+
+	return gw.CertificateManager.CertPool(spec.Proxy.Transport.SSLCACertificates)
+}
+
+// verifyUpstreamChain verifies the certificates presented by an upstream against roots.
+// Hostname verification is skipped when dnsName is empty.
+func verifyUpstreamChain(rawCerts [][]byte, roots *x509.CertPool, dnsName string) error {
+	if len(rawCerts) == 0 {
+		return errors.New("upstream didn't present a certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       dnsName,
+		Intermediates: x509.NewCertPool(),
+	}
+
+	var leaf *x509.Certificate
+	for i, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return errors.New("failed to parse certificate from upstream: " + err.Error())
+		}
+
+		if i == 0 {
+			leaf = cert
+			continue
+		}
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(opts); err != nil {
+		return errors.New("upstream certificate is not signed by a trusted CA of the API: " + err.Error())
+	}
+
+	return nil
+}
+
 func (gw *Gateway) getPinnedPublicKeys(host string, spec *APISpec, conf config.Config) (fingerprint []string) {
 	var keyIDs string
 
@@ -309,44 +380,15 @@ func getClientValidator(helloInfo *tls.ClientHelloInfo, certPool *x509.CertPool)
 
 func (gw *Gateway) getTLSConfigForClient(baseConfig *tls.Config, listenPort int) func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
 	gwConfig := gw.GetConfig()
-	// Supporting legacy certificate configuration
-	serverCerts := []tls.Certificate{}
-	certNameMap := map[string]*tls.Certificate{}
-
-	for _, certData := range gwConfig.HttpServerOptions.Certificates {
-		cert, err := tls.LoadX509KeyPair(certData.CertFile, certData.KeyFile)
-		if err != nil {
-			log.Errorf("Server error: loadkeys: %s", err)
-			continue
-		}
-		serverCerts = append(serverCerts, cert)
-		certNameMap[certData.Name] = &cert
-	}
-
-	if len(gwConfig.HttpServerOptions.SSLCertificates) > 0 {
-		var waitingRedisLog sync.Once
-		// ensure that we are connected to redis
-		for {
-			if gw.StorageConnectionHandler.Connected() {
-				break
-			}
 
-			waitingRedisLog.Do(func() {
-				log.Warning("Redis is not ready. Waiting for a living connection")
-			})
-			time.Sleep(10 * time.Millisecond)
-		}
-	}
-	for _, cert := range gw.CertificateManager.List(gwConfig.HttpServerOptions.SSLCertificates, certs.CertificatePrivate) {
-		if cert != nil {
-			serverCerts = append(serverCerts, *cert)
-		}
-	}
+	// the server certificates are swapped by reloadServerCertificates when they change
+	serverCerts := gw.loadServerCertificates(gwConfig, nil)
+	gw.serverCertificates.Store(serverCerts)
 
-	baseConfig.Certificates = serverCerts
+	baseConfig.Certificates = serverCerts.certificates
 	baseConfig.BuildNameToCertificate()
 
-	for name, cert := range certNameMap {
+	for name, cert := range serverCerts.names {
 		baseConfig.NameToCertificate[name] = cert
 	}
 
@@ -358,14 +400,15 @@ func (gw *Gateway) getTLSConfigForClient(baseConfig *tls.Config, listenPort int)
 		}
 
 		newConfig := baseConfig.Clone()
+		serverCerts := gw.serverCertificates.Load()
 
 		// Avoiding Race
 		newConfig.Certificates = []tls.Certificate{}
-		for _, cert := range baseConfig.Certificates {
+		for _, cert := range serverCerts.certificates {
 			newConfig.Certificates = append(newConfig.Certificates, cert)
 		}
 		newConfig.BuildNameToCertificate()
-		for name, cert := range certNameMap {
+		for name, cert := range serverCerts.names {
 			newConfig.NameToCertificate[name] = cert
 		}
 
@@ -389,6 +432,8 @@ func (gw *Gateway) getTLSConfigForClient(baseConfig *tls.Config, listenPort int)
 		newConfig.ClientCAs = x509.NewCertPool()
 		domainRequireCert := map[string]tls.ClientAuthType{}
 
+		var domainCert *tls.Certificate
+
 		directMTLSDomainMatch := false
 		for _, spec := range gw.apiSpecs {
 			if spec.UseMutualTLSAuth && spec.Domain == hello.ServerName {
@@ -454,6 +499,21 @@ func (gw *Gateway) getTLSConfigForClient(baseConfig *tls.Config, listenPort int)
 					}
 				}
 			}
+
+			// The certificates of the custom domain matching the hostname are preferred
+			if domainCert == nil && !spec.DomainDisabled && gwConfig.EnableCustomDomains {
+				for _, cert := range gw.CertificateManager.List(domainCertificates(spec.Domains, hello.ServerName), certs.CertificatePrivate) {
+					if cert != nil {
+						newConfig.Certificates = append(newConfig.Certificates, *cert)
+						domainCert = cert
+						break
+					}
+				}
+			}
+		}
+
+		if domainCert != nil {
+			newConfig.NameToCertificate[strings.ToLower(hello.ServerName)] = domainCert
 		}
 
 		if clientAuth, found := domainRequireCert[hello.ServerName]; found {
@@ -0,0 +1,240 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// serverCertificates are the certificates served by the TLS listeners.
+type serverCertificates struct {
+	certificates []tls.Certificate
+	// names are the certificates of the legacy certificates configuration, by server name.
+	names map[string]*tls.Certificate
+	// loaded are the certificates by the file or certificate store entry they were loaded from.
+	loaded map[string]*tls.Certificate
+	// sources are the hashes of the files and certificate store entries the certificates were
+	// loaded from, to detect their changes.
+	sources map[string]string
+}
+
+// loadServerCertificates loads the server certificates from the legacy certificates configuration
+// and from `http_server_options.ssl_certificates`. The certificates which fail to load are taken
+// from prev when they were loaded before, so a bad file doesn't drop a served certificate.
+func (gw *Gateway) loadServerCertificates(gwConfig config.Config, prev *serverCertificates) *serverCertificates {
+	loaded := &serverCertificates{
+		certificates: []tls.Certificate{},
+		names:        map[string]*tls.Certificate{},
+		loaded:       map[string]*tls.Certificate{},
+		sources:      gw.serverCertificateSources(gwConfig),
+	}
+
+	previous := func(source string) *tls.Certificate {
+		if prev == nil || prev.loaded[source] == nil {
+			return nil
+		}
+		log.WithField("source", source).Warning("Keeping the previously loaded server certificate")
+		return prev.loaded[source]
+	}
+
+	// Supporting legacy certificate configuration
+	for _, certData := range gwConfig.HttpServerOptions.Certificates {
+		cert, err := tls.LoadX509KeyPair(certData.CertFile, certData.KeyFile)
+		if err != nil {
+			log.Errorf("Server error: loadkeys: %s", err)
+			prevCert := previous(certData.CertFile)
+			if prevCert == nil {
+				continue
+			}
+			cert = *prevCert
+		}
+		loaded.certificates = append(loaded.certificates, cert)
+		loaded.names[certData.Name] = &cert
+		loaded.loaded[certData.CertFile] = &cert
+	}
+
+	if len(gwConfig.HttpServerOptions.SSLCertificates) > 0 {
+		var waitingRedisLog sync.Once
+		// ensure that we are connected to redis
+		for {
+			if gw.StorageConnectionHandler.Connected() {
+				break
+			}
+
+			waitingRedisLog.Do(func() {
+				log.Warning("Redis is not ready. Waiting for a living connection")
+			})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	for _, id := range gwConfig.HttpServerOptions.SSLCertificates {
+		// List skips the certificates without a private key, so the entries are listed one by one
+		var cert *tls.Certificate
+		if list := gw.CertificateManager.List([]string{id}, certs.CertificatePrivate); len(list) > 0 {
+			cert = list[0]
+		}
+		if cert == nil {
+			if cert = previous(id); cert == nil {
+				continue
+			}
+		}
+		loaded.certificates = append(loaded.certificates, *cert)
+		loaded.loaded[id] = cert
+	}
+
+	return loaded
+}
+
+// serverCertificateSources hashes the certificate files and certificate store entries of the
+// server certificates.
+func (gw *Gateway) serverCertificateSources(gwConfig config.Config) map[string]string {
+	sources := map[string]string{}
+
+	hashFile := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		return hashCertificateSource(data)
+	}
+
+	for _, certData := range gwConfig.HttpServerOptions.Certificates {
+		sources[certData.CertFile] = hashFile(certData.CertFile)
+		sources[certData.KeyFile] = hashFile(certData.KeyFile)
+	}
+
+	for _, id := range gwConfig.HttpServerOptions.SSLCertificates {
+		// the entries are certificate IDs or paths to files, as for certs.CertificateManager.List
+		if raw, err := gw.CertificateManager.GetRaw(id); err == nil {
+			sources[id] = hashCertificateSource([]byte(raw))
+			continue
+		}
+		sources[id] = hashFile(id)
+	}
+
+	return sources
+}
+
+func hashCertificateSource(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// reloadServerCertificates swaps the server certificates into the running TLS listeners when
+// their files or certificate store entries changed, and fires EventServerCertificatesChanged.
+// It runs every `http_server_options.certificate_reload_interval` seconds; a certificate which
+// fails to load keeps being served as it was until a later check loads it.
+func (gw *Gateway) reloadServerCertificates() error {
+	current := gw.serverCertificates.Load()
+	if current == nil {
+		// no TLS listener started yet
+		return nil
+	}
+
+	gwConfig := gw.GetConfig()
+	if maps.Equal(current.sources, gw.serverCertificateSources(gwConfig)) {
+		return nil
+	}
+
+	// the certificate manager caches the certificates by ID, the files included
+	gw.CertificateManager.FlushCache()
+
+	next := gw.loadServerCertificates(gwConfig, current)
+	gw.serverCertificates.Store(next)
+	tlsConfigCache.Flush()
+
+	added, removed, changed := diffServerCertificates(current, next)
+	if len(added) == 0 && len(removed) == 0 && len(changed) == 0 {
+		return nil
+	}
+
+	log.WithFields(logrus.Fields{
+		"added":   added,
+		"removed": removed,
+		"changed": changed,
+	}).Info("Server certificates changed, swapped into the listeners")
+
+	gw.FireSystemEvent(EventServerCertificatesChanged, EventServerCertificatesChangedMeta{
+		EventMetaDefault: EventMetaDefault{Message: "Server certificates changed."},
+		Added:            added,
+		Removed:          removed,
+		Changed:          changed,
+	})
+
+	return nil
+}
+
+// serverNames returns the fingerprints of the served certificates by server name.
+func (s *serverCertificates) serverNames() map[string]string {
+	names := map[string]string{}
+
+	for i := range s.certificates {
+		cert := &s.certificates[i]
+		if len(cert.Certificate) == 0 {
+			continue
+		}
+
+		leaf := cert.Leaf
+		if leaf == nil {
+			var err error
+			if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				continue
+			}
+		}
+
+		fingerprint := hashCertificateSource(cert.Certificate[0])
+		if leaf.Subject.CommonName != "" {
+			names[strings.ToLower(leaf.Subject.CommonName)] = fingerprint
+		}
+		for _, san := range leaf.DNSNames {
+			names[strings.ToLower(san)] = fingerprint
+		}
+	}
+
+	for name, cert := range s.names {
+		if len(cert.Certificate) > 0 {
+			names[strings.ToLower(name)] = hashCertificateSource(cert.Certificate[0])
+		}
+	}
+
+	return names
+}
+
+// diffServerCertificates returns the server names whose certificate was added, removed or
+// changed between two sets of server certificates.
+func diffServerCertificates(prev, next *serverCertificates) (added, removed, changed []string) {
+	prevNames, nextNames := prev.serverNames(), next.serverNames()
+
+	for name, fingerprint := range nextNames {
+		prevFingerprint, ok := prevNames[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case prevFingerprint != fingerprint:
+			changed = append(changed, name)
+		}
+	}
+
+	for name := range prevNames {
+		if _, ok := nextNames[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
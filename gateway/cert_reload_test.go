@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/crypto"
+)
+
+func TestReloadServerCertificates(t *testing.T) {
+	genCert := func(names ...string) []byte {
+		_, _, combinedPEM, _ := crypto.GenCertificate(&x509.Certificate{DNSNames: names}, false)
+		return combinedPEM
+	}
+
+	certPath := filepath.Join(t.TempDir(), "server.pem")
+	require.NoError(t, os.WriteFile(certPath, genCert("localhost"), 0o600))
+
+	events := make(chan EventServerCertificatesChangedMeta, 1)
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HttpServerOptions.UseSSL = true
+		globalConf.HttpServerOptions.SSLCertificates = []string{certPath}
+		globalConf.SetEventTriggers(map[apidef.TykEvent][]config.TykEventHandler{
+			EventServerCertificatesChanged: {&testEventHandler{func(em config.EventMessage) {
+				events <- em.Meta.(EventServerCertificatesChangedMeta)
+			}}},
+		})
+	})
+	defer ts.Close()
+	defer func() {
+		ts.Gw.CertificateManager.FlushCache()
+		tlsConfigCache.Flush()
+	}()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+	})
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         "localhost",
+	}}}
+
+	servedCert := func() *x509.Certificate {
+		client.CloseIdleConnections()
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.NotEmpty(t, resp.TLS.PeerCertificates)
+		return resp.TLS.PeerCertificates[0]
+	}
+
+	before := servedCert()
+
+	t.Run("unchanged", func(t *testing.T) {
+		require.NoError(t, ts.Gw.reloadServerCertificates())
+		assert.Empty(t, events)
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(certPath, genCert("localhost", "example.com"), 0o600))
+		require.NoError(t, ts.Gw.reloadServerCertificates())
+
+		meta := <-events
+		assert.Equal(t, []string{"example.com"}, meta.Added)
+		assert.Equal(t, []string{"localhost"}, meta.Changed)
+		assert.Empty(t, meta.Removed)
+
+		after := servedCert()
+		assert.False(t, bytes.Equal(before.Raw, after.Raw), "the changed certificate should be served")
+		assert.Equal(t, []string{"localhost", "example.com"}, after.DNSNames)
+	})
+
+	t.Run("failed to load", func(t *testing.T) {
+		served := servedCert()
+
+		require.NoError(t, os.WriteFile(certPath, []byte("half written"), 0o600))
+		require.NoError(t, ts.Gw.reloadServerCertificates())
+		assert.Empty(t, events)
+
+		assert.Equal(t, served.Raw, servedCert().Raw, "the previous certificate should be kept")
+	})
+}
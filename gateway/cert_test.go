@@ -1046,6 +1046,66 @@ func TestSSLForceCommonName(t *testing.T) {
 	})
 }
 
+func TestUpstreamCACertificates(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	serverCertPem, _, _, serverCert := crypto.GenServerCertificate()
+	otherCertPem, _, _, _ := crypto.GenServerCertificate()
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	upstream.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		MaxVersion:   tls.VersionTLS12,
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	caID, _ := ts.Gw.CertificateManager.Add(serverCertPem, "")
+	defer ts.Gw.CertificateManager.Delete(caID, "")
+
+	otherCAID, _ := ts.Gw.CertificateManager.Add(otherCertPem, "")
+	defer ts.Gw.CertificateManager.Delete(otherCAID, "")
+
+	pubID, err := ts.Gw.uploadCertPublicKey(serverCert)
+	assert.NoError(t, err)
+	defer ts.Gw.CertificateManager.Delete(pubID, "")
+
+	load := func(caIDs []string, pins map[string]string) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.Proxy.Transport.SSLCACertificates = caIDs
+			spec.PinnedPublicKeys = pins
+		})
+	}
+
+	t.Run("system roots", func(t *testing.T) {
+		load(nil, nil)
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusInternalServerError})
+	})
+
+	t.Run("trusted by API CA", func(t *testing.T) {
+		load([]string{caID}, nil)
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusOK})
+	})
+
+	t.Run("not trusted by API CA", func(t *testing.T) {
+		load([]string{otherCAID}, nil)
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusInternalServerError})
+	})
+
+	t.Run("pinned key and trusted CA", func(t *testing.T) {
+		load([]string{caID}, map[string]string{"127.0.0.1": pubID})
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusOK})
+	})
+
+	t.Run("pinned key with untrusted CA", func(t *testing.T) {
+		load([]string{otherCAID}, map[string]string{"127.0.0.1": pubID})
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusInternalServerError})
+	})
+}
+
 func TestKeyWithCertificateTLS(t *testing.T) {
 	test.Flaky(t) // TODO TT-5112
 
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// chainCache keeps the specs and middleware chains of the APIs unloaded by reloads, keyed by
+// the checksum of their definition, so that definitions loaded back unchanged reuse their
+// compiled regexes, templates, JSVM programs and middleware chains.
+type chainCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedChain
+}
+
+type cachedChain struct {
+	spec    *APISpec
+	chain   *ChainObject
+	retired time.Time
+}
+
+// retire keeps the spec and its chain, the spec is unloaded once it expires.
+func (c *chainCache) retire(spec *APISpec, chain *ChainObject, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]*cachedChain{}
+	}
+
+	if previous, ok := c.entries[spec.Checksum]; ok && previous.spec != spec {
+		previous.spec.Unload()
+	}
+
+	c.entries[spec.Checksum] = &cachedChain{spec: spec, chain: chain, retired: now}
+}
+
+// spec returns the cached spec of the definition of spec, if it can be reused.
+func (c *chainCache) spec(spec *APISpec) (*APISpec, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[spec.Checksum]
+	if !ok || entry.spec.APIID != spec.APIID || shouldReloadSpec(entry.spec, spec) {
+		return nil, false
+	}
+
+	return entry.spec, true
+}
+
+// take returns the chain of the cached spec, which is loaded again.
+func (c *chainCache) take(spec *APISpec) (*ChainObject, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[spec.Checksum]
+	if !ok || entry.spec != spec {
+		return nil, false
+	}
+
+	delete(c.entries, spec.Checksum)
+	return entry.chain, entry.chain != nil
+}
+
+// prune forgets the specs loaded again, and unloads the specs retired for longer than ttl.
+func (c *chainCache) prune(live map[string]*APISpec, now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for checksum, entry := range c.entries {
+		if live[entry.spec.APIID] == entry.spec {
+			delete(c.entries, checksum)
+			continue
+		}
+
+		if now.Sub(entry.retired) >= ttl {
+			delete(c.entries, checksum)
+			entry.spec.Unload()
+		}
+	}
+}
+
+// len returns the number of cached chains.
+func (c *chainCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
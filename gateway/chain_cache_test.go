@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestChainCache(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.ChainCacheTTL = 60
+	})
+	defer ts.Close()
+
+	apiA := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "a"
+		spec.Proxy.ListenPath = "/a/"
+		spec.UseKeylessAccess = true
+	})[0]
+	apiB := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "b"
+		spec.Proxy.ListenPath = "/b/"
+		spec.UseKeylessAccess = true
+	})[0]
+
+	ts.Gw.LoadAPI(apiA, apiB)
+	loadedSpec := ts.Gw.getApiSpec("a")
+	chain, _ := ts.Gw.apisHandlesByID.Load("a")
+
+	ts.Gw.LoadAPI(apiB)
+	assert.Nil(t, ts.Gw.getApiSpec("a"))
+	assert.Equal(t, 1, ts.Gw.chainCache.len())
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/a/", Code: http.StatusNotFound})
+
+	ts.Gw.LoadAPI(apiA, apiB)
+	assert.Same(t, loadedSpec, ts.Gw.getApiSpec("a"))
+	reloadedChain, _ := ts.Gw.apisHandlesByID.Load("a")
+	assert.Same(t, chain, reloadedChain)
+	assert.Equal(t, 0, ts.Gw.chainCache.len())
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/a/", Code: http.StatusOK})
+
+	t.Run("changed definitions aren't reused", func(t *testing.T) {
+		ts.Gw.LoadAPI(apiB)
+
+		changed := BuildAPI(func(spec *APISpec) {
+			spec.APIID = "a"
+			spec.Proxy.ListenPath = "/changed/"
+			spec.UseKeylessAccess = true
+		})[0]
+		ts.Gw.LoadAPI(changed, apiB)
+
+		assert.NotSame(t, loadedSpec, ts.Gw.getApiSpec("a"))
+		_, _ = ts.Run(t, test.TestCase{Path: "/changed/", Code: http.StatusOK})
+	})
+
+	t.Run("changed definitions are unloaded right away", func(t *testing.T) {
+		ts.Gw.chainCache.prune(nil, time.Now(), 0)
+
+		changed := BuildAPI(func(spec *APISpec) {
+			spec.APIID = "a"
+			spec.Proxy.ListenPath = "/changed-again/"
+			spec.UseKeylessAccess = true
+		})[0]
+		ts.Gw.LoadAPI(changed, apiB)
+
+		assert.Equal(t, 0, ts.Gw.chainCache.len())
+		_, _ = ts.Run(t, test.TestCase{Path: "/changed-again/", Code: http.StatusOK})
+	})
+
+	t.Run("expired chains are unloaded", func(t *testing.T) {
+		ts.Gw.chainCache.prune(nil, time.Now(), 0)
+		assert.Equal(t, 0, ts.Gw.chainCache.len())
+	})
+}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+const (
+	defaultMaxConfigBackups = 10
+	defaultConfigBackupDir  = "config_backups"
+	// configBackupPrefix and configBackupSuffix tell the backups apart from the other files of
+	// the directory, which are never listed nor pruned.
+	configBackupPrefix = "tyk-config-backup-"
+	configBackupSuffix = ".conf"
+	// configBackupTimeFormat names the backups so that they sort by creation time.
+	configBackupTimeFormat = "2006-01-02T15-04-05.000000000"
+	// configBackupRestoreTag tags the backups made before a restore, followed by the creation
+	// time of the restored backup.
+	configBackupRestoreTag = "-before-restoring-"
+)
+
+var errConfigBackupNotFound = errors.New("Configuration backup not found")
+
+// configBackup is a backup of the configuration.
+type configBackup struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+func (gw *Gateway) configBackupDir() string {
+	if dir := gw.GetConfig().ConfigBackups.Path; dir != "" {
+		return dir
+	}
+	return defaultConfigBackupDir
+}
+
+// isConfigBackup returns true if name is the name of a configuration backup.
+func isConfigBackup(name string) bool {
+	return filepath.Base(name) == name && strings.HasPrefix(name, configBackupPrefix) && strings.HasSuffix(name, configBackupSuffix)
+}
+
+// configBackupTimes returns the creation time of a backup and, for the backups made before a
+// restore, the creation time of the restored backup, as formatted in their names.
+func configBackupTimes(name string) (created, restored string) {
+	name = strings.TrimSuffix(strings.TrimPrefix(name, configBackupPrefix), configBackupSuffix)
+	created, restored, _ = strings.Cut(name, configBackupRestoreTag)
+	return created, restored
+}
+
+// backupConfiguration writes the current configuration to a backup and prunes the oldest backups.
+func (gw *Gateway) backupConfiguration() error {
+	return gw.writeConfigBackup("")
+}
+
+// writeConfigBackup writes the current configuration to a backup, tagged with the backup about to
+// be restored if any, and prunes the oldest backups.
+func (gw *Gateway) writeConfigBackup(restoring string) error {
+	oldConfig, err := json.MarshalIndent(gw.GetConfig(), "", "    ")
+	if err != nil {
+		return err
+	}
+
+	dir := gw.configBackupDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	name := configBackupPrefix + time.Now().UTC().Format(configBackupTimeFormat)
+	if restoring != "" {
+		created, _ := configBackupTimes(restoring)
+		name += configBackupRestoreTag + created
+	}
+	name += configBackupSuffix
+	if err := os.WriteFile(filepath.Join(dir, name), oldConfig, 0o644); err != nil {
+		return err
+	}
+
+	return gw.pruneConfigBackups()
+}
+
+// listConfigBackups returns the backups, the newest first.
+func (gw *Gateway) listConfigBackups() ([]configBackup, error) {
+	entries, err := os.ReadDir(gw.configBackupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []configBackup{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []configBackup{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfigBackup(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, configBackup{Name: entry.Name(), Size: info.Size(), Created: info.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Created.Equal(backups[j].Created) {
+			return backups[i].Created.After(backups[j].Created)
+		}
+		return backups[i].Name > backups[j].Name
+	})
+
+	return backups, nil
+}
+
+// pruneConfigBackups removes the oldest backups above the maximum number of backups.
+func (gw *Gateway) pruneConfigBackups() error {
+	maxBackups := gw.GetConfig().ConfigBackups.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxConfigBackups
+	}
+
+	backups, err := gw.listConfigBackups()
+	if err != nil || len(backups) <= maxBackups {
+		return err
+	}
+
+	for _, backup := range backups[maxBackups:] {
+		if err := os.Remove(filepath.Join(gw.configBackupDir(), backup.Name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollbackConfigBackup returns the backup restored by a rollback to the previous configuration:
+// the newest backup, skipping the backups made before a restore and the backups from the restored
+// one on, so that consecutive rollbacks walk back the backups instead of undoing each other.
+func rollbackConfigBackup(backups []configBackup) (string, bool) {
+	before := ""
+	for _, backup := range backups {
+		created, restored := configBackupTimes(backup.Name)
+		if before != "" && created >= before {
+			continue
+		}
+
+		if restored != "" {
+			before = restored
+			continue
+		}
+
+		return backup.Name, true
+	}
+
+	return "", false
+}
+
+// restoreConfigBackup replaces the configuration file with a backup, after backing up the
+// current configuration so that the restore can be undone. That backup is tagged with the
+// restored one, for rollbackConfigBackup to skip them.
+func (gw *Gateway) restoreConfigBackup(name string) error {
+	if !isConfigBackup(name) {
+		return errConfigBackupNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(gw.configBackupDir(), name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errConfigBackupNotFound
+		}
+		return err
+	}
+
+	var conf config.Config
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return err
+	}
+
+	if err := gw.writeConfigBackup(name); err != nil {
+		return err
+	}
+
+	return writeNewConfiguration(ConfigPayload{Configuration: conf})
+}
+
+// configBackupsHandler lists the backups of the configuration.
+func (gw *Gateway) configBackupsHandler(w http.ResponseWriter, _ *http.Request) {
+	backups, err := gw.listConfigBackups()
+	if err != nil {
+		log.WithError(err).Error("Couldn't list configuration backups")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't list configuration backups"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, backups)
+}
+
+// configBackupRestoreHandler restores a backup of the configuration and reloads the Gateway.
+func (gw *Gateway) configBackupRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := gw.restoreConfigBackup(name); err != nil {
+		if errors.Is(err, errConfigBackupNotFound) {
+			doJSONWrite(w, http.StatusNotFound, apiError(err.Error()))
+			return
+		}
+		log.WithError(err).WithField("backup", name).Error("Couldn't restore configuration backup")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't restore configuration backup"))
+		return
+	}
+
+	log.WithField("backup", name).Info("Restored configuration backup")
+	go gw.reloadProcess()
+
+	doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{Key: name, Status: "ok", Action: "restored"})
+}
@@ -0,0 +1,205 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestConfigBackups(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.ConfigBackups = config.ConfigBackupsConfig{Path: filepath.Join(dir, "backups"), MaxBackups: 3}
+	})
+	defer ts.Close()
+
+	confFile := filepath.Join(dir, "tyk.conf")
+	oldConfPaths := confPaths
+	confPaths = []string{confFile}
+	defer func() { confPaths = oldConfPaths }()
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/tyk/config/backups", AdminAuth: true, Code: http.StatusOK, BodyMatch: `^\[\]`})
+
+	// the other files of the directory aren't backups
+	other := filepath.Join(dir, "backups", "other.tyk.conf")
+	require.NoError(t, os.MkdirAll(filepath.Dir(other), 0o755))
+	require.NoError(t, os.WriteFile(other, []byte(`{}`), 0o644))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, ts.Gw.backupConfiguration())
+	}
+
+	backups, err := ts.Gw.listConfigBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+
+	resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/config/backups", AdminAuth: true, Code: http.StatusOK})
+	var listed []configBackup
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Equal(t, backups[0].Name, listed[0].Name)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/tyk/config/backups/" + backups[1].Name + "/restore", Method: http.MethodPost, AdminAuth: true,
+			Code: http.StatusOK, BodyMatch: `"action":"restored"`},
+		{Path: "/tyk/config/backups/" + configBackupPrefix + "missing.conf/restore", Method: http.MethodPost, AdminAuth: true, Code: http.StatusNotFound},
+		{Path: "/tyk/config/backups/other.tyk.conf/restore", Method: http.MethodPost, AdminAuth: true, Code: http.StatusNotFound},
+	}...)
+
+	var restored config.Config
+	data, err := os.ReadFile(confFile)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &restored))
+	assert.Equal(t, ts.Gw.GetConfig().ListenPort, restored.ListenPort)
+
+	// the configuration is backed up before the restore
+	restoredBackups, err := ts.Gw.listConfigBackups()
+	require.NoError(t, err)
+	require.Len(t, restoredBackups, 3)
+	assert.NotEqual(t, backups[0].Name, restoredBackups[0].Name)
+	assert.Equal(t, backups[:2], restoredBackups[1:])
+
+	assert.FileExists(t, other, "the other files aren't pruned")
+}
+
+func TestConfigRollback(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.ConfigBackups.Path = filepath.Join(dir, "backups")
+	})
+	defer ts.Close()
+
+	confFile := filepath.Join(dir, "tyk.conf")
+	oldConfPaths := confPaths
+	confPaths = []string{confFile}
+	defer func() { confPaths = oldConfPaths }()
+
+	payload, err := json.Marshal(ConfigRollbackPayload{ForNodeID: ts.Gw.GetNodeID()})
+	require.NoError(t, err)
+
+	readPort := func() int {
+		var restored config.Config
+		data, err := os.ReadFile(confFile)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &restored))
+		return restored.ListenPort
+	}
+
+	require.NoError(t, ts.Gw.backupConfiguration())
+	require.NoError(t, os.WriteFile(confFile, []byte(`{"listen_port": 1}`), 0o644))
+
+	t.Run("remote configuration not allowed", func(t *testing.T) {
+		ts.Gw.handleConfigRollback(string(payload))
+		assert.Equal(t, 1, readPort())
+	})
+
+	globalConf := ts.Gw.GetConfig()
+	globalConf.AllowRemoteConfig = true
+	ts.Gw.SetConfig(globalConf)
+
+	t.Run("other node", func(t *testing.T) {
+		ts.Gw.handleConfigRollback(`{"ForNodeID":"other"}`)
+		assert.Equal(t, 1, readPort())
+
+		ts.Gw.handleConfigRollback(`{"ForGroup":"other","ForTags":["other"]}`)
+		assert.Equal(t, 1, readPort())
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.RemoteConfigSignature.Secret = "secret"
+		ts.Gw.SetConfig(globalConf)
+		defer func() {
+			globalConf.RemoteConfigSignature.Secret = ""
+			ts.Gw.SetConfig(globalConf)
+		}()
+
+		ts.Gw.handleConfigRollback(string(payload))
+		assert.Equal(t, 1, readPort(), "unsigned rollbacks are ignored")
+
+		signed := ConfigRollbackPayload{ForNodeID: ts.Gw.GetNodeID(), TimeStamp: time.Now().Unix()}
+		require.NoError(t, signed.SignHMAC("other"))
+		data, err := json.Marshal(signed)
+		require.NoError(t, err)
+		ts.Gw.handleConfigRollback(string(data))
+		assert.Equal(t, 1, readPort(), "rollbacks with an invalid signature are ignored")
+	})
+
+	t.Run("most recent backup", func(t *testing.T) {
+		ts.Gw.handleConfigRollback(string(payload))
+		assert.Equal(t, ts.Gw.GetConfig().ListenPort, readPort())
+
+		backups, err := ts.Gw.listConfigBackups()
+		require.NoError(t, err)
+		assert.Len(t, backups, 2, "the configuration is backed up before the rollback")
+	})
+
+	t.Run("consecutive rollbacks", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, "backups")))
+		defer ts.Gw.SetConfig(globalConf)
+
+		setPort := func(port int) {
+			conf := ts.Gw.GetConfig()
+			conf.ListenPort = port
+			ts.Gw.SetConfig(conf)
+		}
+
+		// the configuration went through the ports 1001, 1002, then 1003
+		for _, port := range []int{1001, 1002} {
+			setPort(port)
+			require.NoError(t, ts.Gw.backupConfiguration())
+		}
+		setPort(1003)
+
+		ts.Gw.handleConfigRollback(string(payload))
+		require.Equal(t, 1002, readPort())
+		setPort(1002)
+
+		ts.Gw.handleConfigRollback(string(payload))
+		assert.Equal(t, 1001, readPort(), "the second rollback goes back past the first one")
+		setPort(1001)
+
+		ts.Gw.handleConfigRollback(string(payload))
+		assert.Equal(t, 1001, readPort(), "there's no older backup")
+	})
+}
+
+func TestRollbackConfigBackup(t *testing.T) {
+	backup := func(created, restored string) configBackup {
+		name := configBackupPrefix + created
+		if restored != "" {
+			name += configBackupRestoreTag + restored
+		}
+		return configBackup{Name: name + configBackupSuffix}
+	}
+
+	for name, tc := range map[string]struct {
+		backups  []configBackup
+		expected string
+	}{
+		"none":                       {},
+		"newest":                     {backups: []configBackup{backup("2", ""), backup("1", "")}, expected: backup("2", "").Name},
+		"after a restore":            {backups: []configBackup{backup("3", "2"), backup("2", ""), backup("1", "")}, expected: backup("1", "").Name},
+		"after restoring the oldest": {backups: []configBackup{backup("4", "1"), backup("3", "2"), backup("2", ""), backup("1", "")}},
+		"updated after a restore": {backups: []configBackup{backup("4", ""), backup("3", "2"), backup("2", ""), backup("1", "")},
+			expected: backup("4", "").Name},
+		"restore undone": {backups: []configBackup{backup("4", "3"), backup("3", "2"), backup("2", ""), backup("1", "")},
+			expected: backup("2", "").Name},
+	} {
+		t.Run(name, func(t *testing.T) {
+			restored, found := rollbackConfigBackup(tc.backups)
+			assert.Equal(t, tc.expected != "", found)
+			assert.Equal(t, tc.expected, restored)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/gocraft/health"
+)
+
+// connectionMetrics collects the metrics of client and upstream connections, which explain
+// latencies not visible in request analytics: TLS handshakes, TLS session resumptions,
+// connection reuse and connection churn.
+type connectionMetrics struct {
+	mu        sync.Mutex
+	client    clientConnectionStats
+	upstreams map[string]*upstreamConnectionStats
+}
+
+// durationStats summarizes the durations of an operation.
+type durationStats struct {
+	Count     uint64  `json:"count"`
+	AverageMs float64 `json:"average_ms"`
+	MaxMs     float64 `json:"max_ms"`
+
+	total time.Duration
+}
+
+func (s *durationStats) observe(d time.Duration) {
+	s.Count++
+	s.total += d
+	s.AverageMs = float64(s.total.Microseconds()) / float64(s.Count) / 1000
+
+	if ms := float64(d.Microseconds()) / 1000; ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+}
+
+// tlsStats summarizes TLS handshakes.
+type tlsStats struct {
+	Handshakes     durationStats `json:"handshakes"`
+	Failures       uint64        `json:"failures"`
+	Resumed        uint64        `json:"resumed"`
+	ResumptionRate float64       `json:"resumption_rate"`
+}
+
+func (s *tlsStats) observe(d time.Duration, resumed bool) {
+	s.Handshakes.observe(d)
+	if resumed {
+		s.Resumed++
+	}
+	s.ResumptionRate = float64(s.Resumed) / float64(s.Handshakes.Count)
+}
+
+type clientConnectionStats struct {
+	Opened uint64   `json:"opened"`
+	Closed uint64   `json:"closed"`
+	Active int64    `json:"active"`
+	TLS    tlsStats `json:"tls"`
+}
+
+type upstreamConnectionStats struct {
+	// Requests is the number of connections obtained for requests, new or reused.
+	Requests  uint64        `json:"requests"`
+	Reused    uint64        `json:"reused"`
+	ReuseRate float64       `json:"reuse_rate"`
+	Dials     durationStats `json:"dials"`
+	DialFails uint64        `json:"dial_failures"`
+	TLS       tlsStats      `json:"tls"`
+}
+
+type connectionMetricsSnapshot struct {
+	Client    clientConnectionStats              `json:"client"`
+	Upstreams map[string]upstreamConnectionStats `json:"upstreams"`
+}
+
+func newConnectionMetrics() *connectionMetrics {
+	return &connectionMetrics{upstreams: map[string]*upstreamConnectionStats{}}
+}
+
+func (m *connectionMetrics) snapshot() connectionMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := connectionMetricsSnapshot{
+		Client:    m.client,
+		Upstreams: make(map[string]upstreamConnectionStats, len(m.upstreams)),
+	}
+	for host, stats := range m.upstreams {
+		snapshot.Upstreams[host] = *stats
+	}
+
+	return snapshot
+}
+
+// clientConnState counts the client connections opened and closed, as http.Server ConnState.
+func (m *connectionMetrics) clientConnState(_ net.Conn, state http.ConnState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch state {
+	case http.StateNew:
+		m.client.Opened++
+		m.client.Active++
+	case http.StateHijacked, http.StateClosed:
+		m.client.Closed++
+		m.client.Active--
+	}
+}
+
+// instrumentTLS measures the TLS handshakes of clients, from the client hello to the
+// verification of the connection, which happens for resumed sessions too.
+func (m *connectionMetrics) instrumentTLS(getConfig func(*tls.ClientHelloInfo) (*tls.Config, error)) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		start := time.Now()
+
+		config, err := getConfig(hello)
+		if err != nil || config == nil {
+			m.mu.Lock()
+			m.client.TLS.Failures++
+			m.mu.Unlock()
+			return config, err
+		}
+
+		config = config.Clone()
+		verify := config.VerifyConnection
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(state); err != nil {
+					m.mu.Lock()
+					m.client.TLS.Failures++
+					m.mu.Unlock()
+					return err
+				}
+			}
+
+			elapsed := time.Since(start)
+			m.mu.Lock()
+			m.client.TLS.observe(elapsed, state.DidResume)
+			m.mu.Unlock()
+
+			if instrumentationEnabled {
+				instrument.NewJob("ClientConnection").Timing("tls_handshake", elapsed.Nanoseconds())
+			}
+			return nil
+		}
+
+		return config, nil
+	}
+}
+
+func (m *connectionMetrics) upstream(host string) *upstreamConnectionStats {
+	stats, ok := m.upstreams[host]
+	if !ok {
+		stats = &upstreamConnectionStats{}
+		m.upstreams[host] = stats
+	}
+
+	return stats
+}
+
+// upstreamTrace returns the trace recording the connections of a request to an upstream.
+func (m *connectionMetrics) upstreamTrace(host string) *httptrace.ClientTrace {
+	// dials to the addresses of a host can be concurrent
+	var dialStarts sync.Map
+	var handshakeStart time.Time
+
+	record := func(event string, fn func(*upstreamConnectionStats)) {
+		m.mu.Lock()
+		fn(m.upstream(host))
+		m.mu.Unlock()
+
+		if instrumentationEnabled {
+			instrument.NewJob("UpstreamConnection").EventKv(event, health.Kvs{"upstream": host})
+		}
+	}
+
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			event := "new"
+			if info.Reused {
+				event = "reused"
+			}
+
+			record(event, func(stats *upstreamConnectionStats) {
+				stats.Requests++
+				if info.Reused {
+					stats.Reused++
+				}
+				stats.ReuseRate = float64(stats.Reused) / float64(stats.Requests)
+			})
+		},
+		ConnectStart: func(network, addr string) {
+			dialStarts.Store(network+addr, time.Now())
+		},
+		ConnectDone: func(network, addr string, err error) {
+			start, ok := dialStarts.LoadAndDelete(network + addr)
+			if !ok {
+				return
+			}
+
+			elapsed := time.Since(start.(time.Time))
+			if err != nil {
+				record("dial_failure", func(stats *upstreamConnectionStats) { stats.DialFails++ })
+				return
+			}
+
+			record("dial", func(stats *upstreamConnectionStats) { stats.Dials.observe(elapsed) })
+		},
+		TLSHandshakeStart: func() {
+			handshakeStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			elapsed := time.Since(handshakeStart)
+			if err != nil {
+				record("tls_failure", func(stats *upstreamConnectionStats) { stats.TLS.Failures++ })
+				return
+			}
+
+			record("tls_handshake", func(stats *upstreamConnectionStats) { stats.TLS.observe(elapsed, state.DidResume) })
+		},
+	}
+}
+
+// connectionMetricsHandler lists the connection metrics collected by the Gateway node.
+func (gw *Gateway) connectionMetricsHandler(w http.ResponseWriter, _ *http.Request) {
+	doJSONWrite(w, http.StatusOK, gw.connectionMetrics.snapshot())
+}
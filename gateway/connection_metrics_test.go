@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestConnectionMetrics(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnableConnectionMetrics = true
+	})
+	defer ts.Close()
+
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.Proxy.Transport.SSLInsecureSkipVerify = true
+	})
+
+	for i := 0; i < 3; i++ {
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusOK})
+	}
+
+	resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/debug/connections", AdminAuth: true, Code: http.StatusOK})
+
+	var metrics connectionMetricsSnapshot
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&metrics))
+
+	stats := metrics.Upstreams[strings.TrimPrefix(upstream.URL, "https://")]
+	assert.Equal(t, uint64(3), stats.Requests)
+	assert.Equal(t, uint64(2), stats.Reused)
+	assert.Equal(t, uint64(1), stats.Dials.Count)
+	assert.Equal(t, uint64(1), stats.TLS.Handshakes.Count)
+	assert.InDelta(t, 2.0/3, stats.ReuseRate, 0.001)
+
+	assert.NotZero(t, metrics.Client.Opened)
+}
+
+func TestConnectionMetrics_ClientTLS(t *testing.T) {
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	serverConfig := &tls.Config{Certificates: certServer.TLS.Certificates}
+	clientConfig := certServer.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	certServer.Close()
+
+	metrics := newConnectionMetrics()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		GetConfigForClient: metrics.instrumentTLS(func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return serverConfig, nil
+		}),
+		Certificates: serverConfig.Certificates,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+				_, _ = conn.Write([]byte("x"))
+			}(conn)
+		}
+	}()
+
+	clientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	clientConfig.MaxVersion = tls.VersionTLS12
+	for i := 0; i < 2; i++ {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+		require.NoError(t, err)
+		_, _ = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+
+	snapshot := metrics.snapshot()
+	assert.Equal(t, uint64(2), snapshot.Client.TLS.Handshakes.Count)
+	assert.Equal(t, uint64(1), snapshot.Client.TLS.Resumed)
+	assert.Equal(t, 0.5, snapshot.Client.TLS.ResumptionRate)
+}
@@ -0,0 +1,383 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const defaultMaxRevisions = 10
+
+// historyKind is the type of definition a revision history is kept for.
+type historyKind string
+
+const (
+	apiHistory    historyKind = "api"
+	policyHistory historyKind = "policy"
+)
+
+// definitionRevision is a stored revision of an API definition or a policy. Definition
+// and OAS are empty for deletions.
+type definitionRevision struct {
+	Revision   int             `json:"revision"`
+	Action     string          `json:"action"`
+	Author     string          `json:"author"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Definition json.RawMessage `json:"definition,omitempty"`
+	OAS        json.RawMessage `json:"oas,omitempty"`
+}
+
+// definitionChange is a difference between two revisions. Path is a JSON pointer.
+type definitionChange struct {
+	Op       string      `json:"op"`
+	Path     string      `json:"path"`
+	OldValue interface{} `json:"old_value,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+}
+
+type definitionDiff struct {
+	From    int                `json:"from"`
+	To      int                `json:"to"`
+	Changes []definitionChange `json:"changes"`
+}
+
+const (
+	definitionHistoryPrefix  = "definition-history-"
+	definitionRevisionPrefix = "definition-revision-"
+)
+
+func (gw *Gateway) definitionHistoryStore() *storage.RedisCluster {
+	return &storage.RedisCluster{ConnectionHandler: gw.StorageConnectionHandler}
+}
+
+func historyKey(kind historyKind, id string) string {
+	return string(kind) + "-" + id
+}
+
+// definitionHistory returns the stored revisions of a definition, oldest first.
+func (gw *Gateway) definitionHistory(kind historyKind, id string) ([]definitionRevision, error) {
+	conn, err := gw.definitionHistoryStore().Client()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := conn.LRange(context.Background(), definitionHistoryPrefix+historyKey(kind, id), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]definitionRevision, 0, len(entries))
+	for _, entry := range entries {
+		var revision definitionRevision
+		if err := json.Unmarshal([]byte(entry), &revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	// concurrent changes get their revision numbers in order, but may be pushed out of order
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+
+	return revisions, nil
+}
+
+// definitionFiles returns the paths of the files the definition is stored in on this node.
+func (gw *Gateway) definitionFiles(kind historyKind, id string) (def, oasDef string) {
+	conf := gw.GetConfig()
+	if kind == policyHistory {
+		return filepath.Join(conf.Policies.PolicyPath, id+".json"), ""
+	}
+
+	return filepath.Join(conf.AppPath, id+".json"), filepath.Join(conf.AppPath, id+"-oas.json")
+}
+
+// recordDefinitionChange adds a revision to the history of the definition changed by a
+// successful Gateway API call.
+func (gw *Gateway) recordDefinitionChange(kind historyKind, r *http.Request, obj interface{}, code int) {
+	res, ok := obj.(apiModifyKeySuccess)
+	if code != http.StatusOK || !ok {
+		return
+	}
+
+	switch res.Action {
+	case "added", "modified", "deleted":
+	default:
+		return
+	}
+
+	gw.recordFileRevision(kind, res.Key, res.Action, requestAuthor(r))
+}
+
+// recordFileRevision adds a revision with the definition currently stored on this node.
+func (gw *Gateway) recordFileRevision(kind historyKind, id, action, author string) {
+	revision := definitionRevision{Action: action}
+	if action != "deleted" {
+		defPath, oasPath := gw.definitionFiles(kind, id)
+		revision.Definition, _ = os.ReadFile(defPath)
+		if oasPath != "" {
+			revision.OAS, _ = os.ReadFile(oasPath)
+		}
+	}
+
+	gw.recordRevision(kind, id, author, revision)
+}
+
+// requestAuthor identifies the author of a change made through the Gateway API.
+func requestAuthor(r *http.Request) string {
+	if author := r.Header.Get(header.XTykAuthor); author != "" {
+		return author
+	}
+
+	return requestIPHops(r)
+}
+
+// recordRevision appends a revision to a history, dropping the oldest revisions
+// exceeding the configured maximum.
+func (gw *Gateway) recordRevision(kind historyKind, id, author string, revision definitionRevision) {
+	conf := gw.GetConfig().DefinitionHistory
+	if !conf.Enabled {
+		return
+	}
+
+	logger := log.WithField("prefix", "history").WithField(string(kind)+"_id", id)
+
+	conn, err := gw.definitionHistoryStore().Client()
+	if err != nil {
+		logger.WithError(err).Error("Couldn't store revision history")
+		return
+	}
+
+	ctx := context.Background()
+	key := historyKey(kind, id)
+
+	// the revision counter is kept apart from the list so trimming doesn't reset it
+	number, err := conn.Incr(ctx, definitionRevisionPrefix+key).Result()
+	if err != nil {
+		logger.WithError(err).Error("Couldn't number revision")
+		return
+	}
+
+	revision.Revision = int(number)
+	revision.Author = author
+	revision.Timestamp = time.Now().UTC()
+
+	data, err := json.Marshal(revision)
+	if err != nil {
+		logger.WithError(err).Error("Couldn't marshal revision")
+		return
+	}
+
+	maxRevisions := conf.MaxRevisions
+	if maxRevisions <= 0 {
+		maxRevisions = defaultMaxRevisions
+	}
+
+	_, err = conn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, definitionHistoryPrefix+key, data)
+		pipe.LTrim(ctx, definitionHistoryPrefix+key, int64(-maxRevisions), -1)
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Couldn't store revision history")
+	}
+}
+
+func findRevision(revisions []definitionRevision, revision int) (definitionRevision, bool) {
+	for _, rev := range revisions {
+		if rev.Revision == revision {
+			return rev, true
+		}
+	}
+
+	return definitionRevision{}, false
+}
+
+// diffRevisions lists the changes between two revisions, the definition and the OAS
+// document being compared under the `/definition` and `/oas` paths.
+func diffRevisions(from, to definitionRevision) ([]definitionChange, error) {
+	changes := []definitionChange{}
+
+	for _, part := range []struct {
+		path     string
+		from, to json.RawMessage
+	}{
+		{"/definition", from.Definition, to.Definition},
+		{"/oas", from.OAS, to.OAS},
+	} {
+		var a, b interface{}
+		if len(part.from) > 0 {
+			if err := json.Unmarshal(part.from, &a); err != nil {
+				return nil, err
+			}
+		}
+		if len(part.to) > 0 {
+			if err := json.Unmarshal(part.to, &b); err != nil {
+				return nil, err
+			}
+		}
+
+		changes = diffJSON(part.path, a, b, changes)
+	}
+
+	return changes, nil
+}
+
+func diffJSON(path string, a, b interface{}, changes []definitionChange) []definitionChange {
+	switch {
+	case a == nil && b == nil:
+		return changes
+	case a == nil:
+		return append(changes, definitionChange{Op: "add", Path: path, Value: b})
+	case b == nil:
+		return append(changes, definitionChange{Op: "remove", Path: path, OldValue: a})
+	}
+
+	switch aVal := a.(type) {
+	case map[string]interface{}:
+		bVal, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(aVal)+len(bVal))
+		for k := range aVal {
+			keys = append(keys, k)
+		}
+		for k := range bVal {
+			if _, ok := aVal[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			changes = diffJSON(path+"/"+escapeJSONPointer(k), aVal[k], bVal[k], changes)
+		}
+		return changes
+	case []interface{}:
+		bVal, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+
+		for i := 0; i < len(aVal) || i < len(bVal); i++ {
+			var x, y interface{}
+			if i < len(aVal) {
+				x = aVal[i]
+			}
+			if i < len(bVal) {
+				y = bVal[i]
+			}
+			changes = diffJSON(path+"/"+strconv.Itoa(i), x, y, changes)
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		changes = append(changes, definitionChange{Op: "replace", Path: path, OldValue: a, Value: b})
+	}
+
+	return changes
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// definitionHistoryHandler lists the revisions of an API definition or a policy, or
+// returns a single revision with its definition.
+func (gw *Gateway) definitionHistoryHandler(kind historyKind, idVar string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		revisions, err := gw.definitionHistory(kind, vars[idVar])
+		if err != nil {
+			doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't load revision history"))
+			return
+		}
+
+		if revisionVar, ok := vars["revision"]; ok {
+			revisionID, _ := strconv.Atoi(revisionVar)
+			revision, found := findRevision(revisions, revisionID)
+			if !found {
+				doJSONWrite(w, http.StatusNotFound, apiError("Revision not found"))
+				return
+			}
+
+			doJSONWrite(w, http.StatusOK, revision)
+			return
+		}
+
+		for i := range revisions {
+			revisions[i].Definition = nil
+			revisions[i].OAS = nil
+		}
+
+		doJSONWrite(w, http.StatusOK, revisions)
+	}
+}
+
+// definitionDiffHandler returns the changes between two revisions. By default the
+// latest revision is compared to the one before it.
+func (gw *Gateway) definitionDiffHandler(kind historyKind, idVar string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		revisions, err := gw.definitionHistory(kind, mux.Vars(r)[idVar])
+		if err != nil {
+			doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't load revision history"))
+			return
+		}
+
+		if len(revisions) == 0 {
+			doJSONWrite(w, http.StatusNotFound, apiError("No revisions recorded"))
+			return
+		}
+
+		to := revisions[len(revisions)-1].Revision
+		from := to - 1
+
+		for param, target := range map[string]*int{"from": &from, "to": &to} {
+			if value := r.URL.Query().Get(param); value != "" {
+				if *target, err = strconv.Atoi(value); err != nil {
+					doJSONWrite(w, http.StatusBadRequest, apiError(fmt.Sprintf("Invalid %s revision", param)))
+					return
+				}
+			}
+		}
+
+		fromRevision, found := findRevision(revisions, from)
+		if !found && from != 0 {
+			doJSONWrite(w, http.StatusNotFound, apiError(fmt.Sprintf("Revision %d not found", from)))
+			return
+		}
+
+		toRevision, found := findRevision(revisions, to)
+		if !found {
+			doJSONWrite(w, http.StatusNotFound, apiError(fmt.Sprintf("Revision %d not found", to)))
+			return
+		}
+
+		changes, err := diffRevisions(fromRevision, toRevision)
+		if err != nil {
+			doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't compare revisions"))
+			return
+		}
+
+		doJSONWrite(w, http.StatusOK, definitionDiff{From: from, To: to, Changes: changes})
+	}
+}
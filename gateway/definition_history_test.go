@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestDefinitionHistory(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.DefinitionHistory.Enabled = true
+		globalConf.DefinitionHistory.MaxRevisions = 2
+	})
+	defer ts.Close()
+
+	t.Run("API definition", func(t *testing.T) {
+		apiID := uuid.NewHex()
+		api := BuildAPI(func(spec *APISpec) {
+			spec.APIID = apiID
+			spec.Proxy.ListenPath = "/v1/"
+		})[0].APIDefinition
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/apis", Data: api, AdminAuth: true, Code: http.StatusOK})
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodGet, Path: "/tyk/reload", AdminAuth: true, Code: http.StatusOK})
+		ts.Gw.DoReload()
+
+		for _, listenPath := range []string{"/v2/", "/v3/"} {
+			api.Proxy.ListenPath = listenPath
+			_, _ = ts.Run(t, test.TestCase{Method: http.MethodPut, Path: "/tyk/apis/" + apiID, Data: api, AdminAuth: true,
+				Headers: map[string]string{"X-Tyk-Author": "alice"}, Code: http.StatusOK})
+		}
+
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodGet, Path: "/tyk/apis/" + apiID + "/history", AdminAuth: true, Code: http.StatusOK})
+
+		var revisions []definitionRevision
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&revisions))
+		require.Len(t, revisions, 2)
+		assert.Equal(t, 2, revisions[0].Revision)
+		assert.Equal(t, 3, revisions[1].Revision)
+		assert.Equal(t, "modified", revisions[1].Action)
+		assert.Equal(t, "alice", revisions[1].Author)
+		assert.Empty(t, revisions[1].Definition)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodGet, Path: "/tyk/apis/" + apiID + "/history/3", AdminAuth: true,
+				Code: http.StatusOK, BodyMatch: `"listen_path":\s*"/v3/"`},
+			{Method: http.MethodGet, Path: "/tyk/apis/" + apiID + "/history/1", AdminAuth: true, Code: http.StatusNotFound},
+			{Method: http.MethodGet, Path: "/tyk/apis/" + apiID + "/history/diff?from=1", AdminAuth: true, Code: http.StatusNotFound},
+		}...)
+
+		resp, _ = ts.Run(t, test.TestCase{Method: http.MethodGet, Path: "/tyk/apis/" + apiID + "/history/diff", AdminAuth: true, Code: http.StatusOK})
+
+		var diff definitionDiff
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&diff))
+		assert.Equal(t, 2, diff.From)
+		assert.Equal(t, 3, diff.To)
+		assert.Equal(t, []definitionChange{
+			{Op: "replace", Path: "/definition/proxy/listen_path", OldValue: "/v2/", Value: "/v3/"},
+		}, diff.Changes)
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.Policies.PolicyPath = t.TempDir()
+		globalConf.Policies.PolicySource = "file"
+		ts.Gw.SetConfig(globalConf)
+
+		polID := uuid.NewHex()
+		pol := user.Policy{ID: polID, Rate: 10, Per: 1}
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/policies/" + polID, Data: pol, AdminAuth: true, Code: http.StatusOK})
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodDelete, Path: "/tyk/policies/" + polID, AdminAuth: true, Code: http.StatusOK})
+
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodGet, Path: "/tyk/policies/" + polID + "/history/diff", AdminAuth: true, Code: http.StatusOK})
+
+		var diff definitionDiff
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&diff))
+		require.Len(t, diff.Changes, 1)
+		assert.Equal(t, "remove", diff.Changes[0].Op)
+		assert.Equal(t, "/definition", diff.Changes[0].Path)
+	})
+
+	t.Run("concurrent changes", func(t *testing.T) {
+		apiID := uuid.NewHex()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ts.Gw.recordRevision(apiHistory, apiID, "alice", definitionRevision{Action: "modified"})
+			}()
+		}
+		wg.Wait()
+
+		revisions, err := ts.Gw.definitionHistory(apiHistory, apiID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		// every change got its own revision number
+		assert.Less(t, revisions[0].Revision, revisions[1].Revision)
+		assert.LessOrEqual(t, revisions[1].Revision, 5)
+	})
+}
+
+func TestDiffJSON(t *testing.T) {
+	var a, b interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"a": 1, "b": [1, 2], "c/d": {"e": true}}`), &a))
+	require.NoError(t, json.Unmarshal([]byte(`{"a": 2, "b": [1], "c/d": {"e": true}, "f": "x"}`), &b))
+
+	assert.Equal(t, []definitionChange{
+		{Op: "replace", Path: "/a", OldValue: 1.0, Value: 2.0},
+		{Op: "remove", Path: "/b/1", OldValue: 2.0},
+		{Op: "add", Path: "/f", Value: "x"},
+	}, diffJSON("", a, b, nil))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/TykTechnologies/tyk/storage"
 )
 
+// invalidateAPICache deletes the cached responses of an API, with their stale copies.
 func (gw *Gateway) invalidateAPICache(apiID string) bool {
 	store := storage.RedisCluster{IsCache: true, ConnectionHandler: gw.StorageConnectionHandler}
 	return store.DeleteScanMatch(fmt.Sprintf("cache-%s*", apiID))
@@ -2,19 +2,32 @@ package gateway
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/TykTechnologies/drl"
+
+	"github.com/TykTechnologies/tyk/config"
 )
 
 func (gw *Gateway) startRateLimitNotifications() {
-	notificationFreq := gw.GetConfig().DRLNotificationFrequency
+	conf := gw.GetConfig()
+	notificationFreq := conf.DRLNotificationFrequency
 	if notificationFreq == 0 {
 		notificationFreq = 2
 	}
 
+	if conf.DRLAdaptiveNotifications.Enabled {
+		gw.startAdaptiveRateLimitNotifications(newDRLNotificationBackoff(conf.RateLimit))
+		return
+	}
+
 	go func() {
 		log.Info("Starting gateway rate limiter notifications...")
 		for {
@@ -35,6 +48,129 @@ func (gw *Gateway) startRateLimitNotifications() {
 	}()
 }
 
+// startAdaptiveRateLimitNotifications checks the request rate at the minimum frequency, and
+// notifies when the backoff says the notification is due.
+func (gw *Gateway) startAdaptiveRateLimitNotifications(backoff *drlNotificationBackoff) {
+	go func() {
+		log.WithFields(logrus.Fields{
+			"min_frequency": backoff.min,
+			"max_frequency": backoff.max,
+		}).Info("Starting gateway rate limiter notifications with an adaptive frequency...")
+
+		ticker := time.NewTicker(backoff.min)
+		defer ticker.Stop()
+
+		for {
+			if gw.GetNodeID() == "" {
+				log.Warning("Node not registered yet, skipping DRL Notification")
+			} else if backoff.due(time.Now(), GlobalRate.Rate()) {
+				gw.NotifyCurrentServerStatus()
+			}
+
+			select {
+			case <-gw.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+const (
+	// drlServersExpiry is the fixed expiry of the servers cache of the DRL, after which it forgets
+	// the servers which stopped notifying.
+	drlServersExpiry = 4 * time.Second
+
+	defaultDRLNotificationMinFrequency = time.Second
+	// maxDRLNotificationFrequency is under the expiry after which the DRL forgets a server.
+	maxDRLNotificationFrequency           = 3 * time.Second
+	defaultDRLNotificationChangeThreshold = 0.2
+	// minDRLNotificationRate is the rate the changes are relative to at least, so that the
+	// changes of the rate of an idle Gateway don't count as rapid.
+	minDRLNotificationRate = 10
+)
+
+// drlNotificationBackoff adapts the interval between the DRL notifications to the load: the
+// interval drops to the minimum when the rate changed by more than the threshold since the last
+// notification, and doubles up to the maximum while the rate is steady.
+type drlNotificationBackoff struct {
+	min       time.Duration
+	max       time.Duration
+	threshold float64
+
+	interval   time.Duration
+	lastRate   int64
+	lastNotify time.Time
+}
+
+func newDRLNotificationBackoff(conf config.RateLimit) *drlNotificationBackoff {
+	adaptive := conf.DRLAdaptiveNotifications
+
+	// the DRL forgets the servers after 4 seconds even when they're evicted after drl_server_ttl,
+	// the Gateway would stop counting itself
+	limit := maxDRLNotificationFrequency
+	if ttl := time.Duration(conf.DRLServerTTL) * time.Second / 2; ttl > 0 && ttl < limit {
+		limit = ttl
+	}
+
+	b := &drlNotificationBackoff{
+		min:       time.Duration(adaptive.MinFrequency) * time.Second,
+		max:       time.Duration(adaptive.MaxFrequency) * time.Second,
+		threshold: adaptive.ChangeThreshold,
+	}
+
+	if b.min <= 0 {
+		b.min = defaultDRLNotificationMinFrequency
+	}
+	if b.max <= 0 {
+		b.max = limit
+	} else if b.max > limit {
+		log.Warningf("DRL notification max frequency of %v is too long for the servers not to be forgotten, using %v", b.max, limit)
+		b.max = limit
+	}
+	if b.max < b.min {
+		b.max = b.min
+	}
+	if b.threshold <= 0 {
+		b.threshold = defaultDRLNotificationChangeThreshold
+	}
+
+	b.interval = b.min
+	return b
+}
+
+// due returns whether the status of the server is to be notified, at a time and with the request
+// rate of the Gateway at that time, and adapts the interval to the next notification.
+func (b *drlNotificationBackoff) due(now time.Time, rate int64) bool {
+	changed := b.rateChanged(rate)
+	if !changed && now.Sub(b.lastNotify) < b.interval {
+		return false
+	}
+
+	if changed {
+		b.interval = b.min
+	} else if b.interval *= 2; b.interval > b.max {
+		b.interval = b.max
+	}
+
+	b.lastRate = rate
+	b.lastNotify = now
+	return true
+}
+
+func (b *drlNotificationBackoff) rateChanged(rate int64) bool {
+	if b.lastNotify.IsZero() {
+		return true
+	}
+
+	base := float64(b.lastRate)
+	if base < minDRLNotificationRate {
+		base = minDRLNotificationRate
+	}
+
+	return math.Abs(float64(rate-b.lastRate))/base >= b.threshold
+}
+
 func (gw *Gateway) getTagHash() string {
 	th := ""
 	for _, tag := range gw.GetConfig().DBAppConfOptions.Tags {
@@ -83,6 +219,11 @@ func (gw *Gateway) onServerStatusReceivedHandler(payload string) {
 		return
 	}
 
+	// the DRL isn't initialised when the Redis rate limiters are used
+	if gw.DRLManager.Servers == nil {
+		return
+	}
+
 	serverData := drl.Server{}
 	if err := json.Unmarshal([]byte(payload), &serverData); err != nil {
 		log.WithFields(logrus.Fields{
@@ -92,10 +233,279 @@ func (gw *Gateway) onServerStatusReceivedHandler(payload string) {
 		return
 	}
 
-	if err := gw.DRLManager.AddOrUpdateServer(serverData); err != nil {
+	err := gw.DRLManager.AddOrUpdateServer(serverData)
+	gw.drlServers.seen(serverData, err)
+	if err != nil {
 		log.WithError(err).
 			WithField("serverData", serverData).
 			Debug("AddOrUpdateServer error. Seems like you running multiple segmented Tyk groups in same Redis.")
 		return
 	}
+
+	if gw.drlServers.evicting() {
+		gw.rebalanceDRL()
+	}
+}
+
+// evictStaleDRLServers evicts the servers which stopped notifying for longer than
+// `drl_server_ttl`, and fires EventDRLServerEvicted for each of them.
+func (gw *Gateway) evictStaleDRLServers() error {
+	evicted := gw.drlServers.evict(time.Now())
+	if len(evicted) == 0 {
+		return nil
+	}
+
+	for _, seen := range evicted {
+		log.WithFields(logrus.Fields{
+			"server_id": seen.server.ID,
+			"hostname":  seen.server.HostName,
+			"last_seen": seen.lastSeen,
+		}).Warning("DRL server stopped notifying, evicted")
+
+		gw.FireSystemEvent(EventDRLServerEvicted, EventDRLServerEvictedMeta{
+			EventMetaDefault: EventMetaDefault{Message: "DRL server stopped notifying and was evicted."},
+			ServerID:         seen.server.ID,
+			HostName:         seen.server.HostName,
+			LastSeen:         seen.lastSeen,
+		})
+	}
+
+	gw.rebalanceDRL()
+	return nil
+}
+
+// rebalanceDRL shares the rate evenly between the active servers of the registry, as the DRL
+// does between the servers of its cache, so that the evicted servers stop counting.
+func (gw *Gateway) rebalanceDRL() {
+	manager := gw.DRLManager
+	active := gw.drlServers.active()
+	if manager == nil || active == 0 {
+		return
+	}
+
+	manager.SetCurrentTokenValue(int64(drl.Round(float64(manager.RequestTokenValue)*float64(active), .5, 0)))
+}
+
+// drlServerEvictTTL returns the eviction TTL of `drl_server_ttl`. It returns an error when the TTL
+// is longer than the expiry of the DRL, as the DRL forgets the servers after it anyway.
+func drlServerEvictTTL(seconds int) (time.Duration, error) {
+	ttl := time.Duration(seconds) * time.Second
+	if ttl > drlServersExpiry {
+		return 0, fmt.Errorf("drl_server_ttl of %v is longer than the distributed rate limiter keeps the servers, it must be at most %v", ttl, drlServersExpiry)
+	}
+
+	return ttl, nil
+}
+
+// drlServerTTL is how long the servers which stopped notifying are kept by the registry, when
+// they aren't evicted after `drl_server_ttl`.
+const drlServerTTL = time.Minute
+
+// drlServerRegistry keeps the last notification received from each server by the DRL, as the
+// DRL doesn't expose the servers it knows.
+type drlServerRegistry struct {
+	mu      sync.Mutex
+	servers map[string]drlServerSeen
+	// evictTTL is `drl_server_ttl`. It is zero when the servers aren't evicted, and are pruned
+	// silently after drlServerTTL instead.
+	evictTTL time.Duration
+}
+
+type drlServerSeen struct {
+	server   drl.Server
+	lastSeen time.Time
+	err      error
+}
+
+func (r *drlServerRegistry) seen(server drl.Server, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.servers == nil {
+		r.servers = make(map[string]drlServerSeen)
+	}
+
+	now := time.Now()
+	if r.evictTTL == 0 {
+		for id, seen := range r.servers {
+			if now.Sub(seen.lastSeen) > drlServerTTL {
+				delete(r.servers, id)
+			}
+		}
+	}
+
+	r.servers[drlServerID(server)] = drlServerSeen{server: server, lastSeen: now, err: err}
+}
+
+func (r *drlServerRegistry) setEvictTTL(ttl time.Duration) {
+	r.mu.Lock()
+	r.evictTTL = ttl
+	r.mu.Unlock()
+}
+
+func (r *drlServerRegistry) evicting() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.evictTTL > 0
+}
+
+// evict removes the servers which stopped notifying for longer than the eviction TTL, and
+// returns them.
+func (r *drlServerRegistry) evict(now time.Time) []drlServerSeen {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.evictTTL == 0 {
+		return nil
+	}
+
+	var evicted []drlServerSeen
+	for id, seen := range r.servers {
+		if now.Sub(seen.lastSeen) > r.evictTTL {
+			evicted = append(evicted, seen)
+			delete(r.servers, id)
+		}
+	}
+
+	sort.Slice(evicted, func(i, j int) bool {
+		return drlServerID(evicted[i].server) < drlServerID(evicted[j].server)
+	})
+
+	return evicted
+}
+
+// count returns the number of servers the rate is shared between: the active servers of the
+// registry when they're evicted by it, the servers of the DRL otherwise.
+func (r *drlServerRegistry) count(manager *drl.DRL) int {
+	if r != nil && r.evicting() {
+		return r.active()
+	}
+
+	return manager.Servers.Count()
+}
+
+// active returns the number of servers of the tag group of this server which weren't evicted.
+func (r *drlServerRegistry) active() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	active := 0
+	for _, seen := range r.servers {
+		if seen.err == nil {
+			active++
+		}
+	}
+	return active
+}
+
+func (r *drlServerRegistry) list() []drlServerSeen {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	servers := make([]drlServerSeen, 0, len(r.servers))
+	for _, seen := range r.servers {
+		servers = append(servers, seen)
+	}
+	return servers
+}
+
+// drlServerID is the ID of a server in the DRL.
+func drlServerID(server drl.Server) string {
+	return server.ID + "|" + server.HostName
+}
+
+// DRLServerState is the state of a server as known by the DRL of this Gateway.
+type DRLServerState struct {
+	ID         string    `json:"id"`
+	HostName   string    `json:"hostname"`
+	LoadPerSec int64     `json:"load_per_sec"`
+	Percentage float64   `json:"percentage"`
+	TagHash    string    `json:"tag_hash"`
+	LastSeen   time.Time `json:"last_seen"`
+	// Active is true when the server is counted by the DRL, it stops being counted when it
+	// stopped notifying for a few seconds.
+	Active bool `json:"active"`
+	// ThisServer is true for the server of this Gateway.
+	ThisServer bool `json:"this_server"`
+	// Error is why the DRL ignored the last notification of the server, such as a different tag group.
+	Error string `json:"error,omitempty"`
+}
+
+// DRLState is the state of the DRL of this Gateway.
+type DRLState struct {
+	// Enabled is false when the DRL isn't used, such as with the Redis rate limiters.
+	Enabled bool `json:"enabled"`
+	Ready   bool `json:"ready"`
+	// ThisServerID and TagHash identify this Gateway and its tag group.
+	ThisServerID string `json:"this_server_id"`
+	TagHash      string `json:"tag_hash"`
+	// TokenValue is the token bucket value of this Gateway, derived from RequestTokenValue and
+	// the share of the rate of this Gateway.
+	TokenValue        int64 `json:"token_value"`
+	RequestTokenValue int   `json:"request_token_value"`
+	// CurrentTotal is the load per second of the active servers.
+	CurrentTotal  int64            `json:"current_total"`
+	ActiveServers int              `json:"active_servers"`
+	Servers       []DRLServerState `json:"servers"`
+}
+
+// drlState returns the state of the DRL.
+func (gw *Gateway) drlState() DRLState {
+	state := DRLState{TagHash: gw.getTagHash(), Servers: []DRLServerState{}}
+
+	manager := gw.DRLManager
+	if manager == nil || manager.Servers == nil {
+		return state
+	}
+
+	state.Enabled = true
+	state.Ready = manager.Ready()
+	state.ThisServerID = manager.ThisServerID
+	state.TokenValue = manager.CurrentTokenValue()
+	state.RequestTokenValue = manager.RequestTokenValue
+	state.ActiveServers = gw.drlServers.count(manager)
+	evicting := gw.drlServers.evicting()
+
+	for _, seen := range gw.drlServers.list() {
+		id := drlServerID(seen.server)
+		server := DRLServerState{
+			ID:         seen.server.ID,
+			HostName:   seen.server.HostName,
+			LoadPerSec: seen.server.LoadPerSec,
+			TagHash:    seen.server.TagHash,
+			LastSeen:   seen.lastSeen,
+			ThisServer: id == manager.ThisServerID,
+		}
+
+		if seen.err != nil {
+			server.Error = seen.err.Error()
+		}
+
+		if evicting {
+			// the servers are active until they're evicted
+			server.Active = seen.err == nil
+		} else if active, found := manager.Servers.GetNoExtend(id); found {
+			server.Active = true
+			server.LoadPerSec = active.LoadPerSec
+		}
+
+		if server.Active {
+			// the DRL shares the rate evenly between the active servers
+			server.Percentage = 1 / float64(state.ActiveServers)
+			state.CurrentTotal += server.LoadPerSec
+		}
+
+		state.Servers = append(state.Servers, server)
+	}
+
+	sort.Slice(state.Servers, func(i, j int) bool {
+		return state.Servers[i].ID+state.Servers[i].HostName < state.Servers[j].ID+state.Servers[j].HostName
+	})
+
+	return state
+}
+
+func (gw *Gateway) drlDebugHandler(w http.ResponseWriter, _ *http.Request) {
+	doJSONWrite(w, http.StatusOK, gw.drlState())
 }
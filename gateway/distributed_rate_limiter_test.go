@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/drl"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestDRLDebugHandler(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	require.NotNil(t, ts.Gw.DRLManager.Servers, "the DRL is enabled")
+
+	notify := func(server drl.Server) {
+		payload, err := json.Marshal(server)
+		require.NoError(t, err)
+		ts.Gw.onServerStatusReceivedHandler(string(payload))
+	}
+
+	this := drl.Server{ID: ts.Gw.GetNodeID(), HostName: ts.Gw.hostDetails.Hostname, LoadPerSec: 10, TagHash: ts.Gw.getTagHash()}
+	notify(this)
+	notify(drl.Server{ID: "other", HostName: "host", LoadPerSec: 5, TagHash: this.TagHash})
+	notify(drl.Server{ID: "segmented", HostName: "host", LoadPerSec: 1, TagHash: "other-group"})
+
+	resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodGet, Path: "/tyk/debug/drl", Code: http.StatusOK})
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var state DRLState
+	require.NoError(t, json.Unmarshal(body, &state))
+
+	assert.True(t, state.Enabled)
+	assert.Equal(t, drlServerID(this), state.ThisServerID)
+	assert.Equal(t, 2, state.ActiveServers)
+	assert.EqualValues(t, 15, state.CurrentTotal)
+	require.Len(t, state.Servers, 3)
+
+	byID := map[string]DRLServerState{}
+	for _, server := range state.Servers {
+		byID[server.ID] = server
+	}
+
+	assert.True(t, byID[this.ID].ThisServer)
+	assert.True(t, byID["other"].Active)
+	assert.Equal(t, 0.5, byID["other"].Percentage)
+	assert.False(t, byID["other"].LastSeen.IsZero())
+	assert.False(t, byID["segmented"].Active)
+	assert.NotEmpty(t, byID["segmented"].Error, "the notifications of other tag groups are ignored")
+}
+
+func TestDRLServerEviction(t *testing.T) {
+	events := make(chan EventDRLServerEvictedMeta, 1)
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.DRLServerTTL = 10
+		globalConf.SetEventTriggers(map[apidef.TykEvent][]config.TykEventHandler{
+			EventDRLServerEvicted: {&testEventHandler{func(em config.EventMessage) {
+				events <- em.Meta.(EventDRLServerEvictedMeta)
+			}}},
+		})
+	})
+	defer ts.Close()
+
+	notify := func(server drl.Server) {
+		payload, err := json.Marshal(server)
+		require.NoError(t, err)
+		ts.Gw.onServerStatusReceivedHandler(string(payload))
+	}
+
+	this := drl.Server{ID: ts.Gw.GetNodeID(), HostName: ts.Gw.hostDetails.Hostname, LoadPerSec: 10, TagHash: ts.Gw.getTagHash()}
+	other := drl.Server{ID: "other", HostName: "host", LoadPerSec: 5, TagHash: this.TagHash}
+	notify(this)
+	notify(other)
+
+	manager := ts.Gw.DRLManager
+	assert.Equal(t, 2, ts.Gw.drlState().ActiveServers)
+	assert.EqualValues(t, 2*manager.RequestTokenValue, manager.CurrentTokenValue())
+
+	// the other server stopped notifying
+	ts.Gw.drlServers.mu.Lock()
+	seen := ts.Gw.drlServers.servers[drlServerID(other)]
+	seen.lastSeen = time.Now().Add(-time.Minute)
+	ts.Gw.drlServers.servers[drlServerID(other)] = seen
+	ts.Gw.drlServers.mu.Unlock()
+
+	require.NoError(t, ts.Gw.evictStaleDRLServers())
+
+	select {
+	case meta := <-events:
+		assert.Equal(t, other.ID, meta.ServerID)
+		assert.Equal(t, other.HostName, meta.HostName)
+	case <-time.After(time.Second):
+		t.Fatal("the eviction event wasn't fired")
+	}
+
+	state := ts.Gw.drlState()
+	assert.Equal(t, 1, state.ActiveServers)
+	require.Len(t, state.Servers, 1)
+	assert.True(t, state.Servers[0].ThisServer)
+	assert.EqualValues(t, manager.RequestTokenValue, manager.CurrentTokenValue())
+
+	// the DRL still counts the evicted server until it forgets it, the rate limiter doesn't
+	assert.Equal(t, 2, manager.Servers.Count())
+	assert.Equal(t, 1, ts.Gw.SessionLimiter.drlServers.count(manager))
+}
+
+func TestDRLServerEvictTTL(t *testing.T) {
+	ttl, err := drlServerEvictTTL(0)
+	assert.NoError(t, err)
+	assert.Zero(t, ttl)
+
+	ttl, err = drlServerEvictTTL(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Second, ttl)
+
+	_, err = drlServerEvictTTL(10)
+	assert.Error(t, err, "the DRL forgets the servers after its expiry anyway")
+}
+
+func TestDRLNotificationBackoff(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		b := newDRLNotificationBackoff(config.RateLimit{})
+		assert.Equal(t, time.Second, b.min)
+		assert.Equal(t, maxDRLNotificationFrequency, b.max)
+		assert.Equal(t, defaultDRLNotificationChangeThreshold, b.threshold)
+
+		b = newDRLNotificationBackoff(config.RateLimit{
+			DRLServerTTL:             30,
+			DRLAdaptiveNotifications: config.DRLAdaptiveNotificationsConfig{MaxFrequency: 60},
+		})
+		assert.Equal(t, maxDRLNotificationFrequency, b.max, "the DRL forgets the servers after 4 seconds whatever the server TTL")
+
+		b = newDRLNotificationBackoff(config.RateLimit{
+			DRLServerTTL:             4,
+			DRLAdaptiveNotifications: config.DRLAdaptiveNotificationsConfig{MaxFrequency: 60},
+		})
+		assert.Equal(t, 2*time.Second, b.max, "the max frequency is capped under the server TTL")
+	})
+
+	t.Run("adapts to the load", func(t *testing.T) {
+		b := newDRLNotificationBackoff(config.RateLimit{
+			DRLAdaptiveNotifications: config.DRLAdaptiveNotificationsConfig{MinFrequency: 1, MaxFrequency: 8},
+		})
+
+		now := time.Now()
+		at := func(seconds int) time.Time { return now.Add(time.Duration(seconds) * time.Second) }
+
+		assert.True(t, b.due(at(0), 100), "the first notification is due")
+
+		// the rate is steady, the interval doubles up to the max
+		assert.False(t, b.due(at(0), 105))
+		assert.True(t, b.due(at(1), 105))
+		assert.False(t, b.due(at(2), 100))
+		assert.True(t, b.due(at(3), 100))
+		assert.Equal(t, maxDRLNotificationFrequency, b.interval, "the max frequency is capped")
+		assert.False(t, b.due(at(5), 100))
+		assert.True(t, b.due(at(6), 100))
+
+		// the rate changed rapidly, the notification is due right away
+		assert.True(t, b.due(at(16), 200))
+		assert.Equal(t, time.Second, b.interval)
+
+		// the changes of an idle Gateway aren't rapid
+		assert.True(t, b.due(at(17), 0))
+		assert.True(t, b.due(at(18), 1))
+		assert.False(t, b.due(at(19), 0))
+	})
+}
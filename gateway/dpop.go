@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	dpopProofType            = "dpop+jwt"
+	defaultDPoPProofLifetime = 300
+	dpopReplayPrefix         = "dpop-jti-"
+)
+
+var (
+	errDPoPProofMissing  = errors.New("DPoP proof missing")
+	errDPoPInvalidProof  = errors.New("DPoP proof invalid")
+	errDPoPTokenNotBound = errors.New("access token is not DPoP bound")
+	errDPoPNonceRequired = errors.New("use_dpop_nonce")
+)
+
+// hasDPoPScheme tells whether an authorization header value uses the DPoP scheme.
+func hasDPoPScheme(authorization string) bool {
+	return len(authorization) > len(header.DPoP) && strings.EqualFold(authorization[:len(header.DPoP)+1], header.DPoP+" ")
+}
+
+// stripDPoP removes the DPoP scheme from an authorization header value.
+func stripDPoP(authorization string) string {
+	if hasDPoPScheme(authorization) {
+		return authorization[len(header.DPoP)+1:]
+	}
+
+	return authorization
+}
+
+func (s *APISpec) dpopProofLifetime() time.Duration {
+	if s.DPoP.ProofLifetime > 0 {
+		return time.Duration(s.DPoP.ProofLifetime) * time.Second
+	}
+
+	return defaultDPoPProofLifetime * time.Second
+}
+
+// dpopNonce returns the nonce of an API for a time window. Nonces are derived from the
+// gateway secret so that any gateway of the cluster accepts them.
+func (gw *Gateway) dpopNonce(spec *APISpec, window int64) string {
+	mac := hmac.New(sha256.New, []byte(gw.GetConfig().Secret))
+	fmt.Fprintf(mac, "%s:%d", spec.APIID, window)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (gw *Gateway) validDPoPNonce(spec *APISpec, nonce string) bool {
+	window := time.Now().Unix() / int64(spec.dpopProofLifetime()/time.Second)
+	for _, w := range []int64{window, window - 1} {
+		if hmac.Equal([]byte(nonce), []byte(gw.dpopNonce(spec, w))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDPoP validates the DPoP proof of a request authorized by an access token with the
+// given claims, and passes the key thumbprint to the upstream. The authorization value
+// is the value the token was read from, with its scheme.
+func (gw *Gateway) checkDPoP(spec *APISpec, w http.ResponseWriter, r *http.Request, authorization, accessToken string, claims jwt.MapClaims) (error, int) {
+	thumbprintHeader := spec.DPoP.ThumbprintHeader
+	if thumbprintHeader == "" {
+		thumbprintHeader = header.XTykDPoPThumbprint
+	}
+	r.Header.Del(thumbprintHeader)
+
+	if !spec.DPoP.Enabled {
+		return nil, http.StatusOK
+	}
+
+	var jkt string
+	if cnf, ok := claims["cnf"].(map[string]interface{}); ok {
+		jkt, _ = cnf["jkt"].(string)
+	}
+
+	if jkt == "" {
+		if spec.DPoP.Required || hasDPoPScheme(authorization) {
+			return errDPoPTokenNotBound, http.StatusUnauthorized
+		}
+		return nil, http.StatusOK
+	}
+
+	if !hasDPoPScheme(authorization) {
+		return errors.New("DPoP bound access token must use the DPoP authorization scheme"), http.StatusUnauthorized
+	}
+
+	proofs := r.Header.Values(header.DPoP)
+	if len(proofs) != 1 {
+		return errDPoPProofMissing, http.StatusUnauthorized
+	}
+
+	proof, err := gw.parseDPoPProof(spec, r, proofs[0], accessToken, jkt)
+	if err != nil {
+		log.WithField("api_id", spec.APIID).WithError(err).Debug("DPoP proof rejected")
+		return errDPoPInvalidProof, http.StatusUnauthorized
+	}
+
+	if spec.DPoP.RequireNonce {
+		if nonce, _ := proof["nonce"].(string); !gw.validDPoPNonce(spec, nonce) {
+			w.Header().Set(header.DPoPNonce, gw.dpopNonce(spec, time.Now().Unix()/int64(spec.dpopProofLifetime()/time.Second)))
+			w.Header().Set(header.WWWAuthenticate, `DPoP error="use_dpop_nonce", error_description="Resource server requires nonce in DPoP proof"`)
+			return errDPoPNonceRequired, http.StatusUnauthorized
+		}
+	}
+
+	// a proof is accepted once, for as long as it is fresh
+	jti := sha256.Sum256([]byte(proof["jti"].(string)))
+	store := &storage.RedisCluster{KeyPrefix: dpopReplayPrefix, ConnectionHandler: gw.StorageConnectionHandler}
+	fresh, err := store.Lock(dpopReplayPrefix+spec.APIID+"-"+base64.RawURLEncoding.EncodeToString(jti[:]), 2*spec.dpopProofLifetime())
+	if err != nil {
+		return errors.New("couldn't check DPoP proof replay"), http.StatusInternalServerError
+	}
+	if !fresh {
+		return errDPoPInvalidProof, http.StatusUnauthorized
+	}
+
+	r.Header.Set(thumbprintHeader, jkt)
+
+	return nil, http.StatusOK
+}
+
+// parseDPoPProof verifies a proof is signed by the key the access token is bound to and
+// was made for this request, and returns its claims.
+func (gw *Gateway) parseDPoPProof(spec *APISpec, r *http.Request, rawProof, accessToken, jkt string) (jwt.MapClaims, error) {
+	var thumbprint string
+
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.Parse(rawProof, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); !strings.EqualFold(typ, dpopProofType) {
+			return nil, fmt.Errorf("unexpected proof type %q", typ)
+		}
+
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		default:
+			return nil, fmt.Errorf("unexpected proof signing method %q", token.Method.Alg())
+		}
+
+		rawJWK, err := json.Marshal(token.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+
+		var jwk jose.JSONWebKey
+		if err := jwk.UnmarshalJSON(rawJWK); err != nil {
+			return nil, fmt.Errorf("invalid proof key: %w", err)
+		}
+		if !jwk.IsPublic() {
+			return nil, errors.New("proof key is not a public key")
+		}
+
+		sum, err := jwk.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		thumbprint = base64.RawURLEncoding.EncodeToString(sum)
+
+		return jwk.Key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	if thumbprint != jkt {
+		return nil, errors.New("proof key does not match the access token binding")
+	}
+
+	if jti, _ := claims["jti"].(string); jti == "" {
+		return nil, errors.New("proof has no jti")
+	}
+
+	if htm, _ := claims["htm"].(string); htm != r.Method {
+		return nil, fmt.Errorf("proof made for method %q", htm)
+	}
+
+	htu, _ := claims["htu"].(string)
+	if !dpopURIMatches(htu, r) {
+		return nil, fmt.Errorf("proof made for URI %q", htu)
+	}
+
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return nil, errors.New("proof has no iat")
+	}
+	if age := time.Since(time.Unix(int64(iat), 0)); age > spec.dpopProofLifetime() || age < -spec.dpopProofLifetime() {
+		return nil, errors.New("proof is not fresh")
+	}
+
+	ath := sha256.Sum256([]byte(accessToken))
+	if claimed, _ := claims["ath"].(string); claimed != base64.RawURLEncoding.EncodeToString(ath[:]) {
+		return nil, errors.New("proof does not match the access token")
+	}
+
+	return claims, nil
+}
+
+// dpopURIMatches compares the URI of a proof with the request, ignoring the query and fragment.
+func dpopURIMatches(htu string, r *http.Request) bool {
+	u, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := r.Header.Get(header.XForwardProto); forwarded != "" {
+		scheme = forwarded
+	}
+
+	hostWithoutDefaultPort := func(scheme, host string) string {
+		host = strings.ToLower(host)
+		if scheme == "https" {
+			return strings.TrimSuffix(host, ":443")
+		}
+		return strings.TrimSuffix(host, ":80")
+	}
+
+	return strings.EqualFold(u.Scheme, scheme) &&
+		hostWithoutDefaultPort(strings.ToLower(u.Scheme), u.Host) == hostWithoutDefaultPort(scheme, r.Host) &&
+		u.Path == r.URL.Path
+}
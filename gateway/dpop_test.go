@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+type dpopTestKey struct {
+	key *ecdsa.PrivateKey
+	jwk map[string]interface{}
+	jkt string
+}
+
+func newDPoPTestKey(t *testing.T) dpopTestKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	public := jose.JSONWebKey{Key: &key.PublicKey}
+	sum, err := public.Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+
+	raw, err := public.MarshalJSON()
+	require.NoError(t, err)
+
+	var jwk map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &jwk))
+
+	return dpopTestKey{key: key, jwk: jwk, jkt: base64.RawURLEncoding.EncodeToString(sum)}
+}
+
+func (k dpopTestKey) proof(t *testing.T, method, htu, accessToken string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	ath := sha256.Sum256([]byte(accessToken))
+	proofClaims := jwt.MapClaims{
+		"jti": uuid.NewHex(),
+		"htm": method,
+		"htu": htu,
+		"iat": time.Now().Unix(),
+		"ath": base64.RawURLEncoding.EncodeToString(ath[:]),
+	}
+	for k, v := range claims {
+		proofClaims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, proofClaims)
+	token.Header["typ"] = dpopProofType
+	token.Header["jwk"] = k.jwk
+
+	proof, err := token.SignedString(k.key)
+	require.NoError(t, err)
+	return proof
+}
+
+func TestDPoP(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	tokenKID := testKey(t.Name(), "token")
+	require.NoError(t, ts.Gw.GlobalSessionManager.UpdateSession(tokenKID, createJWTSession(), 60, false))
+
+	spec := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = uuid.NewHex()
+		spec.UseKeylessAccess = false
+		spec.EnableJWT = true
+		spec.JWTSigningMethod = HMACSign
+		spec.Proxy.ListenPath = "/dpop/"
+		spec.DPoP.Enabled = true
+	})[0]
+
+	key := newDPoPTestKey(t)
+	boundToken := createJWKTokenHMAC(func(token *jwt.Token) {
+		token.Header[KID] = tokenKID
+		token.Claims.(jwt.MapClaims)["exp"] = time.Now().Add(time.Hour).Unix()
+		token.Claims.(jwt.MapClaims)["cnf"] = map[string]interface{}{"jkt": key.jkt}
+	})
+	bearerToken := createJWKTokenHMAC(func(token *jwt.Token) {
+		token.Header[KID] = tokenKID
+		token.Claims.(jwt.MapClaims)["exp"] = time.Now().Add(time.Hour).Unix()
+	})
+
+	htu := ts.URL + "/dpop/orders"
+	dpopRequest := func(accessToken, proof string) map[string]string {
+		return map[string]string{header.Authorization: "DPoP " + accessToken, header.DPoP: proof}
+	}
+
+	proof := key.proof(t, http.MethodGet, htu, boundToken, nil)
+	otherKey := newDPoPTestKey(t)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/dpop/orders", Headers: map[string]string{header.Authorization: "Bearer " + bearerToken}, Code: http.StatusOK},
+		{Path: "/dpop/orders", Headers: dpopRequest(boundToken, proof), Code: http.StatusOK,
+			BodyMatch: `"X-Tyk-Dpop-Thumbprint":"` + key.jkt + `"`},
+		// replayed proof
+		{Path: "/dpop/orders", Headers: dpopRequest(boundToken, proof), Code: http.StatusUnauthorized},
+		{Path: "/dpop/orders", Headers: map[string]string{header.Authorization: "Bearer " + boundToken}, Code: http.StatusUnauthorized},
+		{Path: "/dpop/orders", Headers: map[string]string{header.Authorization: "DPoP " + boundToken}, Code: http.StatusUnauthorized},
+		{Path: "/dpop/orders", Headers: dpopRequest(boundToken, otherKey.proof(t, http.MethodGet, htu, boundToken, nil)), Code: http.StatusUnauthorized},
+		{Path: "/dpop/orders", Headers: dpopRequest(boundToken, key.proof(t, http.MethodPost, htu, boundToken, nil)), Code: http.StatusUnauthorized},
+		{Path: "/dpop/orders", Headers: dpopRequest(boundToken, key.proof(t, http.MethodGet, ts.URL+"/dpop/other", boundToken, nil)), Code: http.StatusUnauthorized},
+		{Path: "/dpop/orders", Headers: dpopRequest(boundToken, key.proof(t, http.MethodGet, htu, bearerToken, nil)), Code: http.StatusUnauthorized},
+		{Path: "/dpop/orders", Headers: dpopRequest(boundToken, key.proof(t, http.MethodGet, htu, boundToken,
+			jwt.MapClaims{"iat": time.Now().Add(-time.Hour).Unix()})), Code: http.StatusUnauthorized},
+	}...)
+
+	t.Run("nonce", func(t *testing.T) {
+		spec.DPoP.RequireNonce = true
+		ts.Gw.LoadAPI(spec)
+
+		resp, _ := ts.Run(t, test.TestCase{Path: "/dpop/orders", Headers: dpopRequest(boundToken, key.proof(t, http.MethodGet, htu, boundToken, nil)),
+			Code: http.StatusUnauthorized, BodyMatch: "use_dpop_nonce"})
+
+		nonce := resp.Header.Get(header.DPoPNonce)
+		require.NotEmpty(t, nonce)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/dpop/orders", Headers: dpopRequest(boundToken, key.proof(t, http.MethodGet, htu, boundToken, jwt.MapClaims{"nonce": nonce})), Code: http.StatusOK},
+			{Path: "/dpop/orders", Headers: dpopRequest(boundToken, key.proof(t, http.MethodGet, htu, boundToken, jwt.MapClaims{"nonce": "invalid"})), Code: http.StatusUnauthorized},
+		}...)
+	})
+
+	t.Run("required", func(t *testing.T) {
+		spec.DPoP.RequireNonce = false
+		spec.DPoP.Required = true
+		ts.Gw.LoadAPI(spec)
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/dpop/orders", Headers: map[string]string{header.Authorization: "Bearer " + bearerToken}, Code: http.StatusUnauthorized})
+	})
+}
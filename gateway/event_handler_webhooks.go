@@ -174,15 +174,23 @@ func (w *WebHookHandler) checkURL(r string) bool {
 }
 
 func (w *WebHookHandler) Checksum(reqBody string) (string, error) {
+	return w.checksum(w.conf.TargetPath, reqBody)
+}
+
+func (w *WebHookHandler) checksum(target, reqBody string) (string, error) {
 	// We do this twice because fuck it.
-	localRequest, _ := http.NewRequest(string(w.getRequestMethod(w.conf.Method)), w.conf.TargetPath, strings.NewReader(reqBody))
+	localRequest, _ := http.NewRequest(string(w.getRequestMethod(w.conf.Method)), target, strings.NewReader(reqBody))
 	h := md5.New()
 	localRequest.Write(h)
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func (w *WebHookHandler) BuildRequest(reqBody string) (*http.Request, error) {
-	req, err := http.NewRequest(string(w.getRequestMethod(w.conf.Method)), w.conf.TargetPath, strings.NewReader(reqBody))
+	return w.buildRequest(w.conf.TargetPath, reqBody)
+}
+
+func (w *WebHookHandler) buildRequest(target, reqBody string) (*http.Request, error) {
+	req, err := http.NewRequest(string(w.getRequestMethod(w.conf.Method)), target, strings.NewReader(reqBody))
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"prefix": "webhooks",
@@ -229,18 +237,49 @@ func (w *WebHookHandler) HandleEvent(em config.EventMessage) {
 		return
 	}
 
+	sent := false
+	for _, target := range w.targets(em) {
+		if w.send(target, reqBody) {
+			sent = true
+		}
+	}
+
+	if !sent {
+		return
+	}
+
+	if w.dashboardService != nil && em.Type == EventTriggerExceeded {
+		w.dashboardService.NotifyDashboardOfEvent(em.Meta)
+	}
+}
+
+// targets returns the URLs the event is sent to: the webhook contacts of the API owners
+// when the handler routes to them, otherwise the target path.
+func (w *WebHookHandler) targets(em config.EventMessage) []string {
+	if w.conf.RouteToOwner {
+		if urls := em.Ownership.WebhookURLs(); len(urls) > 0 {
+			return urls
+		}
+	}
+
+	return []string{w.conf.TargetPath}
+}
+
+// send sends the webhook to target, unless it was sent within the cool-down. It returns
+// false if the webhook wasn't sent.
+func (w *WebHookHandler) send(target, reqBody string) bool {
 	// Construct request (method, body, params)
-	req, err := w.BuildRequest(reqBody)
+	req, err := w.buildRequest(target, reqBody)
 	if err != nil {
-		return
+		return false
 	}
 
 	// Generate signature for request
-	reqChecksum, _ := w.Checksum(reqBody)
+	reqChecksum, _ := w.checksum(target, reqBody)
 
 	// Check request velocity for this hook (wasHookFired())
 	if w.WasHookFired(reqChecksum) {
-		return
+		return false
 	}
 
 	cli := &http.Client{Timeout: 30 * time.Second}
@@ -273,9 +312,6 @@ func (w *WebHookHandler) HandleEvent(em config.EventMessage) {
 		}
 	}
 
-	if w.dashboardService != nil && em.Type == EventTriggerExceeded {
-		w.dashboardService.NotifyDashboardOfEvent(em.Meta)
-	}
-
 	w.setHookFired(reqChecksum)
+	return true
 }
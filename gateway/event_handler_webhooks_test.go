@@ -1,13 +1,17 @@
 package gateway
 
 import (
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/header"
 )
@@ -334,3 +338,61 @@ func TestWebhookContentTypeHeader(t *testing.T) {
 	}
 
 }
+
+func TestWebhookRouteToOwner(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	received := make(chan string, 2)
+	receiver := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received <- name + " " + string(body)
+		}))
+	}
+
+	fallback := receiver("fallback")
+	defer fallback.Close()
+	team := receiver("team")
+	defer team.Close()
+
+	templatePath := filepath.Join(t.TempDir(), "owner.tmpl")
+	assert.NoError(t, os.WriteFile(templatePath, []byte("{{.Type}} {{.Ownership.Team}}"), 0600))
+
+	hook := &WebHookHandler{Gw: ts.Gw}
+	assert.NoError(t, hook.Init(apidef.WebHookHandlerConf{
+		Method:       http.MethodPost,
+		TargetPath:   fallback.URL,
+		TemplatePath: templatePath,
+		RouteToOwner: true,
+	}))
+
+	ownership := apidef.APIOwnership{
+		Team: "payments",
+		Contacts: []apidef.APIContact{
+			{Type: apidef.APIContactEmail, Address: "payments@example.com"},
+			{Type: apidef.APIContactSlack, Address: team.URL},
+		},
+	}
+
+	hook.HandleEvent(config.EventMessage{Type: EventBreakerTripped, Ownership: ownership, TimeStamp: "1"})
+	assert.Equal(t, "team BreakerTripped payments", <-received)
+
+	hook.HandleEvent(config.EventMessage{Type: EventBreakerTripped, TimeStamp: "2"})
+	assert.Equal(t, "fallback BreakerTripped ", <-received)
+}
+
+func TestFireEventOwnership(t *testing.T) {
+	ownership := apidef.APIOwnership{Owner: "jane", Team: "payments"}
+
+	fired := make(chan config.EventMessage, 1)
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{Ownership: ownership}}
+	spec.EventPaths = map[apidef.TykEvent][]config.TykEventHandler{
+		EventBreakerTripped: {&testEventHandler{func(em config.EventMessage) { fired <- em }}},
+	}
+
+	spec.FireEvent(EventBreakerTripped, EventCurcuitBreakerMeta{APIID: "api"})
+	assert.Equal(t, ownership, (<-fired).Ownership)
+
+	assert.Equal(t, []string{"tag", "owner-jane", "team-payments"}, tagOwnership(spec, []string{"tag"}))
+}
@@ -24,6 +24,8 @@ const (
 const (
 	// EventQuotaExceeded is an alias maintained for backwards compatibility.
 	EventQuotaExceeded = event.QuotaExceeded
+	// EventQuotaThresholdReached is an alias maintained for backwards compatibility.
+	EventQuotaThresholdReached = event.QuotaThresholdReached
 	// RateLimitExceeded is an alias maintained for backwards compatibility.
 	EventRateLimitExceeded = event.RateLimitExceeded
 	// EventAuthFailure is an alias maintained for backwards compatibility.
@@ -54,6 +56,28 @@ const (
 	EventTokenUpdated = event.TokenUpdated
 	// EventTokenDeleted is an alias maintained for backwards compatibility.
 	EventTokenDeleted = event.TokenDeleted
+	// EventKeyLeaked is an alias maintained for backwards compatibility.
+	EventKeyLeaked = event.KeyLeaked
+	// EventBruteForceLockout is an alias maintained for backwards compatibility.
+	EventBruteForceLockout = event.BruteForceLockout
+	// EventTokenRestored is an alias maintained for backwards compatibility.
+	EventTokenRestored = event.TokenRestored
+	// EventAPIRestored is an alias maintained for backwards compatibility.
+	EventAPIRestored = event.APIRestored
+	// EventStorageBudgetApproached is an alias maintained for backwards compatibility.
+	EventStorageBudgetApproached = event.StorageBudgetApproached
+	// EventStorageBudgetExceeded is an alias maintained for backwards compatibility.
+	EventStorageBudgetExceeded = event.StorageBudgetExceeded
+	// EventOAuthRefreshTokenReused is an alias maintained for backwards compatibility.
+	EventOAuthRefreshTokenReused = event.OAuthRefreshTokenReused
+	// EventServerCertificatesChanged is an alias maintained for backwards compatibility.
+	EventServerCertificatesChanged = event.ServerCertificatesChanged
+	// EventDRLServerEvicted is an alias maintained for backwards compatibility.
+	EventDRLServerEvicted = event.DRLServerEvicted
+	// EventUpstreamDegraded is an alias maintained for backwards compatibility.
+	EventUpstreamDegraded = event.UpstreamDegraded
+	// EventUpstreamRecovered is an alias maintained for backwards compatibility.
+	EventUpstreamRecovered = event.UpstreamRecovered
 )
 
 type EventHostStatusMeta struct {
@@ -95,12 +119,102 @@ type EventTriggerExceededMeta struct {
 	UsagePercentage int64  `json:"usage_percentage"`
 }
 
+// EventQuotaThresholdMeta is the metadata structure for a key whose quota usage reached a
+// threshold of the API. Threshold is the percentage of the quota reached.
+type EventQuotaThresholdMeta struct {
+	EventMetaDefault
+	Path           string  `json:"path"`
+	Origin         string  `json:"origin"`
+	Key            string  `json:"key"`
+	APIID          string  `json:"api_id"`
+	Threshold      float64 `json:"threshold"`
+	QuotaMax       int64   `json:"quota_max"`
+	QuotaRemaining int64   `json:"quota_remaining"`
+	QuotaRenews    int64   `json:"quota_renews"`
+}
+
 type EventTokenMeta struct {
 	EventMetaDefault
 	Org string
 	Key string
 }
 
+// EventKeyLeakedMeta is the metadata structure for a key reported as leaked by secret scanning.
+type EventKeyLeakedMeta struct {
+	EventMetaDefault
+	Org       string `json:"org_id"`
+	KeyID     string `json:"key_id"`
+	URL       string `json:"url"`
+	Source    string `json:"source"`
+	Suspended bool   `json:"suspended"`
+}
+
+// EventBruteForceLockoutMeta is the metadata structure for a client banned after repeated
+// authentication failures. Key is the hash of the presented credential, empty for an IP lockout.
+type EventBruteForceLockoutMeta struct {
+	EventMetaDefault
+	APIID       string `json:"api_id"`
+	Origin      string `json:"origin"`
+	Key         string `json:"key"`
+	BanDuration int64  `json:"ban_duration"`
+}
+
+// EventAPIRestoredMeta is the metadata structure for an API restored from the trash.
+type EventAPIRestoredMeta struct {
+	EventMetaDefault
+	Org   string `json:"org_id"`
+	APIID string `json:"api_id"`
+}
+
+// EventStorageBudgetMeta is the metadata structure for a Redis byte budget alert.
+type EventStorageBudgetMeta struct {
+	EventMetaDefault
+	// Budget is the budget, analytics or cache.
+	Budget     string `json:"budget"`
+	UsedBytes  int64  `json:"used_bytes"`
+	LimitBytes int64  `json:"limit_bytes"`
+}
+
+// EventOAuthRefreshTokenReusedMeta is the metadata structure for a rotated OAuth refresh token
+// presented again.
+type EventOAuthRefreshTokenReusedMeta struct {
+	EventMetaDefault
+	ClientID string `json:"client_id"`
+	// Revoked is set when the tokens of the token family were revoked.
+	Revoked bool `json:"revoked"`
+}
+
+// EventServerCertificatesChangedMeta is the metadata structure for the server certificates swapped
+// into the listeners, listing the server names whose certificate was added, removed or changed.
+type EventServerCertificatesChangedMeta struct {
+	EventMetaDefault
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// EventDRLServerEvictedMeta is the metadata structure for a Gateway evicted from the distributed
+// rate limiter as it stopped sending notifications.
+type EventDRLServerEvictedMeta struct {
+	EventMetaDefault
+	ServerID string    `json:"server_id"`
+	HostName string    `json:"hostname"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// EventUpstreamErrorBudgetMeta is the metadata structure for an API degraded as its upstream
+// exceeded its error budget, and for its recovery.
+type EventUpstreamErrorBudgetMeta struct {
+	EventMetaDefault
+	APIID string `json:"api_id"`
+	// Requests and Failures are the requests and the connect failures of the window which
+	// exceeded the budget.
+	Requests int `json:"requests,omitempty"`
+	Failures int `json:"failures,omitempty"`
+	// DegradedSince is when the API was degraded.
+	DegradedSince time.Time `json:"degraded_since"`
+}
+
 // EventHandlerByName is a convenience function to get event handler instances from an API Definition
 func (gw *Gateway) EventHandlerByName(handlerConf apidef.EventHandlerTriggerConfig, spec *APISpec) (config.TykEventHandler, error) {
 
@@ -140,7 +254,7 @@ func (gw *Gateway) EventHandlerByName(handlerConf apidef.EventHandlerTriggerConf
 	return nil, errors.New("Handler not found")
 }
 
-func fireEvent(name apidef.TykEvent, meta interface{}, handlers map[apidef.TykEvent][]config.TykEventHandler) {
+func fireEvent(name apidef.TykEvent, meta interface{}, handlers map[apidef.TykEvent][]config.TykEventHandler, ownership apidef.APIOwnership) {
 	log.Debug("EVENT FIRED: ", name)
 	if handlers, e := handlers[name]; e {
 		log.Debugf("FOUND %d EVENT HANDLERS", len(handlers))
@@ -148,6 +262,7 @@ func fireEvent(name apidef.TykEvent, meta interface{}, handlers map[apidef.TykEv
 			Meta:      meta,
 			Type:      name,
 			TimeStamp: time.Now().Local().String(),
+			Ownership: ownership,
 		}
 		for _, handler := range handlers {
 			log.Debug("FIRING HANDLER: ", handler)
@@ -157,11 +272,11 @@ func fireEvent(name apidef.TykEvent, meta interface{}, handlers map[apidef.TykEv
 }
 
 func (s *APISpec) FireEvent(name apidef.TykEvent, meta interface{}) {
-	fireEvent(name, meta, s.EventPaths)
+	fireEvent(name, meta, s.EventPaths, s.Ownership)
 }
 
 func (gw *Gateway) FireSystemEvent(name apidef.TykEvent, meta interface{}) {
-	fireEvent(name, meta, gw.GetConfig().GetEventTriggers())
+	fireEvent(name, meta, gw.GetConfig().GetEventTriggers(), apidef.APIOwnership{})
 }
 
 // LogMessageEventHandler is a sample Event Handler
@@ -0,0 +1,248 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// extAuthzCheckMethod is the method of the Envoy ext_authz gRPC services.
+const extAuthzCheckMethod = "/envoy.service.auth.v3.Authorization/Check"
+
+// rawCodec passes encoded protobuf messages through as they are, the messages of the Envoy
+// ext_authz API are encoded with protowire rather than with generated types.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case *[]byte:
+		return *v, nil
+	}
+	return nil, fmt.Errorf("raw codec can't marshal %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec can't unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+func appendStringField(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessageField(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// encodeCheckRequest encodes an envoy.service.auth.v3.CheckRequest.
+func encodeCheckRequest(attrs *extAuthzRequest) []byte {
+	headers := attrs.Headers
+	if _, ok := headers["x-forwarded-for"]; !ok && attrs.ForwardedFor != "" {
+		// the forwarding header is sent with the headers, apart from the source address
+		headers = make(map[string]string, len(attrs.Headers)+1)
+		for name, value := range attrs.Headers {
+			headers[name] = value
+		}
+		headers["x-forwarded-for"] = attrs.ForwardedFor
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// AttributeContext.HttpRequest
+	var httpRequest []byte
+	httpRequest = appendStringField(httpRequest, 2, attrs.Method)
+	for _, name := range names {
+		entry := appendStringField(nil, 1, name)
+		entry = appendStringField(entry, 2, headers[name])
+		httpRequest = appendMessageField(httpRequest, 3, entry)
+	}
+	httpRequest = appendStringField(httpRequest, 4, attrs.Path)
+	httpRequest = appendStringField(httpRequest, 5, attrs.Host)
+	httpRequest = appendStringField(httpRequest, 6, attrs.Scheme)
+	httpRequest = appendVarintField(httpRequest, 9, uint64(len(attrs.Body)))
+	httpRequest = appendStringField(httpRequest, 10, attrs.Protocol)
+	if attrs.RawBody {
+		httpRequest = appendStringField(httpRequest, 12, string(attrs.Body))
+	} else {
+		httpRequest = appendStringField(httpRequest, 11, string(attrs.Body))
+	}
+
+	// AttributeContext.Request
+	request := appendMessageField(nil, 2, httpRequest)
+
+	// config.core.v3.SocketAddress in an Address in the AttributeContext.Peer of the source
+	socketAddress := appendStringField(nil, 2, attrs.RemoteAddr)
+	socketAddress = appendVarintField(socketAddress, 3, uint64(attrs.RemotePort))
+	source := appendMessageField(nil, 1, appendMessageField(nil, 1, socketAddress))
+
+	attributes := appendMessageField(nil, 1, source)
+	attributes = appendMessageField(attributes, 4, request)
+
+	return appendMessageField(nil, 1, attributes)
+}
+
+// protoFields calls fn for every field of an encoded message, with the value of varint fields
+// and the content of length-delimited fields.
+func protoFields(b []byte, fn func(num protowire.Number, v uint64, content []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var (
+			v       uint64
+			content []byte
+		)
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			content, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, v, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeHeaderValueOption decodes a config.core.v3.HeaderValueOption, append is true when the
+// value is appended to the header.
+func decodeHeaderValueOption(b []byte) (name, value string, appendValue bool, err error) {
+	err = protoFields(b, func(num protowire.Number, _ uint64, content []byte) error {
+		switch num {
+		case 1:
+			return protoFields(content, func(num protowire.Number, _ uint64, content []byte) error {
+				switch num {
+				case 1:
+					name = string(content)
+				case 2:
+					value = string(content)
+				}
+				return nil
+			})
+		case 2:
+			return protoFields(content, func(num protowire.Number, v uint64, _ []byte) error {
+				if num == 1 {
+					appendValue = v != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err == nil && name == "" {
+		err = errors.New("header without a name")
+	}
+	return name, value, appendValue, err
+}
+
+// decodeCheckResponse decodes an envoy.service.auth.v3.CheckResponse.
+func decodeCheckResponse(b []byte) (*extAuthzDecision, error) {
+	var (
+		code     uint64
+		decision = &extAuthzDecision{Headers: http.Header{}, AppendHeaders: http.Header{}}
+	)
+
+	err := protoFields(b, func(num protowire.Number, _ uint64, content []byte) error {
+		switch num {
+		case 1: // google.rpc.Status
+			return protoFields(content, func(num protowire.Number, v uint64, _ []byte) error {
+				if num == 1 {
+					code = v
+				}
+				return nil
+			})
+		case 2: // DeniedHttpResponse
+			return protoFields(content, func(num protowire.Number, _ uint64, content []byte) error {
+				switch num {
+				case 1:
+					return protoFields(content, func(num protowire.Number, v uint64, _ []byte) error {
+						if num == 1 {
+							decision.Status = int(v)
+						}
+						return nil
+					})
+				case 2:
+					name, value, _, err := decodeHeaderValueOption(content)
+					if err != nil {
+						return err
+					}
+					decision.Headers.Add(name, value)
+				case 3:
+					decision.Body = append([]byte(nil), content...)
+				}
+				return nil
+			})
+		case 3: // OkHttpResponse
+			return protoFields(content, func(num protowire.Number, _ uint64, content []byte) error {
+				switch num {
+				case 2:
+					name, value, appendValue, err := decodeHeaderValueOption(content)
+					if err != nil {
+						return err
+					}
+					if appendValue {
+						decision.AppendHeaders.Add(name, value)
+					} else {
+						decision.Headers.Add(name, value)
+					}
+				case 5:
+					decision.RemoveHeaders = append(decision.RemoveHeaders, string(content))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	decision.Allowed = code == 0
+	if decision.Allowed {
+		decision.Status = 0
+		decision.Body = nil
+	}
+
+	return decision, nil
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// fingerprintSecretFields are the names of the configuration fields holding secrets, left out of
+// the fingerprint at any depth. The header maps are left out too, as they carry credentials.
+var fingerprintSecretFields = map[string]bool{
+	"secret":                              true,
+	"node_secret":                         true,
+	"password":                            true,
+	"sentinel_password":                   true,
+	"sasl_password":                       true,
+	"api_key":                             true,
+	"token":                               true,
+	"license_key":                         true,
+	"private_certificate_encoding_secret": true,
+	"secrets":                             true,
+	"headers":                             true,
+}
+
+// stateFingerprint identifies the state a Gateway converged to: the loaded APIs, the loaded
+// policies and the configuration without its secrets. Gateways of a group with the same
+// state have the same fingerprint.
+type stateFingerprint struct {
+	Fingerprint string `json:"fingerprint"`
+	APIs        string `json:"apis"`
+	APICount    int    `json:"api_count"`
+	Policies    string `json:"policies"`
+	PolicyCount int    `json:"policy_count"`
+	Config      string `json:"config"`
+
+	// generation is the state generation the fingerprint was computed for.
+	generation uint64
+}
+
+// fingerprint returns the fingerprint of the Gateway state. It's cached until the APIs, the
+// policies or the configuration change.
+func (gw *Gateway) fingerprint() *stateFingerprint {
+	generation := gw.fingerprintGeneration.Load()
+	if cached := gw.fingerprintCache.Load(); cached != nil && cached.generation == generation {
+		return cached
+	}
+
+	gw.apisMu.RLock()
+	apis := make(map[string]interface{}, len(gw.apisByID))
+	for id, spec := range gw.apisByID {
+		apis[id] = spec.mergedAPI()
+	}
+	apisHash := fingerprintHash(apis)
+	gw.apisMu.RUnlock()
+
+	gw.policiesMu.RLock()
+	policiesHash := fingerprintHash(gw.policiesByID)
+	policyCount := len(gw.policiesByID)
+	gw.policiesMu.RUnlock()
+
+	fp := &stateFingerprint{
+		APIs:        apisHash,
+		APICount:    len(apis),
+		Policies:    policiesHash,
+		PolicyCount: policyCount,
+		Config:      fingerprintHash(gw.fingerprintConfig()),
+		generation:  generation,
+	}
+	fp.Fingerprint = fingerprintHash([]string{fp.APIs, fp.Policies, fp.Config})
+
+	gw.fingerprintCache.Store(fp)
+	return fp
+}
+
+// fingerprintConfig returns the effective configuration without its secrets.
+func (gw *Gateway) fingerprintConfig() map[string]interface{} {
+	data, err := json.Marshal(gw.GetConfig())
+	if err != nil {
+		return nil
+	}
+
+	var conf map[string]interface{}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil
+	}
+
+	dropSecretFields(conf)
+	return conf
+}
+
+// dropSecretFields deletes the secret fields of a configuration object and of its nested objects.
+func dropSecretFields(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			if fingerprintSecretFields[name] {
+				delete(v, name)
+				continue
+			}
+			dropSecretFields(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			dropSecretFields(value)
+		}
+	}
+}
+
+// invalidateFingerprint invalidates the cached fingerprint after a state change, including the
+// fingerprint being computed.
+func (gw *Gateway) invalidateFingerprint() {
+	gw.fingerprintGeneration.Add(1)
+}
+
+// fingerprintHash hashes the JSON encoding of v, stable as the encoding sorts the map keys.
+func fingerprintHash(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.WithError(err).Error("Couldn't compute the fingerprint")
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprintHandler returns the fingerprint of the Gateway state, with the fingerprints of the
+// APIs, the policies and the configuration it's computed from.
+func (gw *Gateway) fingerprintHandler(w http.ResponseWriter, _ *http.Request) {
+	doJSONWrite(w, http.StatusOK, gw.fingerprint())
+}
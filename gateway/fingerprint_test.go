@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestFingerprint(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	get := func(t *testing.T) stateFingerprint {
+		t.Helper()
+
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/fingerprint", AdminAuth: true, Code: http.StatusOK})
+		var fp stateFingerprint
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&fp))
+		return fp
+	}
+
+	loadAPIs := func(listenPath string) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "first"
+			spec.Name = "first"
+			spec.Proxy.ListenPath = "/first/"
+		}, func(spec *APISpec) {
+			spec.APIID = "second"
+			spec.Name = "second"
+			spec.Proxy.ListenPath = listenPath
+		})
+	}
+
+	loadAPIs("/second/")
+	first := get(t)
+	assert.Equal(t, 2, first.APICount)
+	assert.Len(t, first.Fingerprint, 64)
+
+	t.Run("stable", func(t *testing.T) {
+		loadAPIs("/second/")
+		assert.Equal(t, first, get(t))
+	})
+
+	t.Run("apis", func(t *testing.T) {
+		loadAPIs("/changed/")
+		fp := get(t)
+		assert.NotEqual(t, first.APIs, fp.APIs)
+		assert.NotEqual(t, first.Fingerprint, fp.Fingerprint)
+		assert.Equal(t, first.Config, fp.Config)
+
+		loadAPIs("/second/")
+		assert.Equal(t, first, get(t))
+	})
+
+	t.Run("policies", func(t *testing.T) {
+		ts.CreatePolicy(func(p *user.Policy) {
+			p.ID = "policy"
+		})
+		// the policies are followed by a reload of the APIs
+		loadAPIs("/second/")
+
+		fp := get(t)
+		assert.NotEqual(t, first.Policies, fp.Policies)
+		assert.Equal(t, first.APIs, fp.APIs)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		before := get(t)
+
+		globalConf := ts.Gw.GetConfig()
+		globalConf.NodeSecret = "other-secret"
+		ts.Gw.SetConfig(globalConf)
+		assert.Equal(t, before.Config, get(t).Config, "secrets aren't part of the fingerprint")
+
+		globalConf.Storage.Password = "other-password"
+		ts.Gw.SetConfig(globalConf)
+		assert.Equal(t, before.Config, get(t).Config, "nested secrets aren't part of the fingerprint")
+
+		globalConf.AnalyticsConfig.IgnoredIPs = []string{"10.0.0.1"}
+		ts.Gw.SetConfig(globalConf)
+		assert.NotEqual(t, before.Config, get(t).Config, "the fields not loading the APIs are part of the fingerprint")
+		before = get(t)
+
+		globalConf.HttpServerOptions.EnableStrictRoutes = !globalConf.HttpServerOptions.EnableStrictRoutes
+		ts.Gw.SetConfig(globalConf)
+
+		fp := get(t)
+		assert.NotEqual(t, before.Config, fp.Config)
+
+		resp, _ := ts.Run(t, test.TestCase{Path: "/hello", Code: http.StatusOK})
+		var health apidef.HealthCheckResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		assert.Equal(t, fp.Fingerprint, health.Fingerprint)
+	})
+}
@@ -90,6 +90,7 @@ type TemplateExecutor interface {
 // HandleError is the actual error handler and will store the error details in analytics if analytics processing is enabled.
 func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMsg string, errCode int, writeResponse bool) {
 	defer e.Base().UpdateRequestSession(r)
+	e.Gw.releaseInboundDedupKey(r, nil)
 	response := &http.Response{}
 
 	if writeResponse {
@@ -145,6 +146,9 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 
 		}
 
+		// Add the diagnostics of allow-listed clients
+		setDebugHeaders(w.Header(), e.Spec, r, ctxGetSession(r))
+
 		// If error is not customized write error in default way
 		if errMsg != errCustomBodyResponse.Error() {
 			w.WriteHeader(errCode)
@@ -170,6 +174,9 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 		}
 	}
 
+	// the upstream latency of the failed requests isn't known
+	e.checkSlowRequest(r, analytics.Latency{})
+
 	if e.Spec.DoNotTrack || ctxGetDoNotTrack(r) {
 		return
 	}
@@ -213,6 +220,12 @@ func (e *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, errMs
 		if len(e.Spec.Tags) > 0 {
 			tags = append(tags, e.Spec.Tags...)
 		}
+
+		tags = tagOwnership(e.Spec, tags)
+		tags = tagLooping(r, tags)
+		tags = tagUpstreamRoute(r, tags)
+		tags = tagExperiments(r, tags)
+		tags = tagSlowRequest(r, tags)
 		trackEP := false
 		trackedPath := r.URL.Path
 
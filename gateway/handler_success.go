@@ -121,6 +121,19 @@ func getSessionTags(session *user.SessionState) []string {
 	return tags
 }
 
+// tagOwnership adds the owner and the team of the API to the analytics tags.
+func tagOwnership(spec *APISpec, tags []string) []string {
+	if spec.Ownership.Owner != "" {
+		tags = append(tags, "owner-"+spec.Ownership.Owner)
+	}
+
+	if spec.Ownership.Team != "" {
+		tags = append(tags, "team-"+spec.Ownership.Team)
+	}
+
+	return tags
+}
+
 func recordGraphDetails(rec *analytics.AnalyticsRecord, r *http.Request, resp *http.Response, spec *APISpec) {
 	if !spec.GraphQL.Enabled || spec.GraphQL.ExecutionMode == apidef.GraphQLExecutionModeSubgraph {
 		return
@@ -202,6 +215,12 @@ func (s *SuccessHandler) RecordHit(r *http.Request, timing analytics.Latency, co
 			tags = append(tags, s.Spec.Tags...)
 		}
 
+		tags = tagOwnership(s.Spec, tags)
+		tags = tagLooping(r, tags)
+		tags = tagUpstreamRoute(r, tags)
+		tags = tagExperiments(r, tags)
+		tags = tagSlowRequest(r, tags)
+
 		if cached {
 			tags = append(tags, "cached-response")
 		}
@@ -374,6 +393,7 @@ func (s *SuccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) *http
 
 	t1 := time.Now()
 	resp := s.Proxy.ServeHTTP(w, r)
+	s.Gw.releaseInboundDedupKey(r, resp.Response)
 
 	millisec := DurationToMillisecond(time.Since(t1))
 	log.Debug("Upstream request took (ms): ", millisec)
@@ -383,6 +403,7 @@ func (s *SuccessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) *http
 			Total:    int64(millisec),
 			Upstream: int64(DurationToMillisecond(resp.UpstreamLatency)),
 		}
+		s.checkSlowRequest(r, latency)
 		s.RecordHit(r, latency, resp.Response.StatusCode, resp.Response, false)
 	}
 	log.Debug("Done proxy")
@@ -399,6 +420,7 @@ func (s *SuccessHandler) ServeHTTPWithCache(w http.ResponseWriter, r *http.Reque
 
 	t1 := time.Now()
 	inRes := s.Proxy.ServeHTTPForCache(w, r)
+	s.Gw.releaseInboundDedupKey(r, inRes.Response)
 	millisec := DurationToMillisecond(time.Since(t1))
 
 	addVersionHeader(w, r, s.Spec.GlobalConfig)
@@ -410,6 +432,7 @@ func (s *SuccessHandler) ServeHTTPWithCache(w http.ResponseWriter, r *http.Reque
 			Total:    int64(millisec),
 			Upstream: int64(DurationToMillisecond(inRes.UpstreamLatency)),
 		}
+		s.checkSlowRequest(r, latency)
 		s.RecordHit(r, latency, inRes.Response.StatusCode, inRes.Response, false)
 	}
 
@@ -163,6 +163,12 @@ func (gw *Gateway) gatherHealthChecks() {
 		}()
 	}
 
+	if stats := gw.analyticsPurgerStats.Load(); stats != nil {
+		allInfos.mux.Lock()
+		allInfos.info["analytics"] = gw.analyticsPurgerHealth(stats.Snapshot())
+		allInfos.mux.Unlock()
+	}
+
 	wg.Wait()
 
 	allInfos.mux.Lock()
@@ -183,6 +189,7 @@ func (gw *Gateway) liveCheckHandler(w http.ResponseWriter, r *http.Request) {
 		Version:     VERSION,
 		Description: "Tyk GW",
 		Details:     checks,
+		Fingerprint: gw.fingerprint().Fingerprint,
 	}
 
 	var failCount int
@@ -425,7 +425,7 @@ func (hc *HostCheckerManager) ListFromService(apiID string) ([]HostData, error)
 	if spec == nil {
 		return nil, errors.New("API ID not found in register")
 	}
-	sd := ServiceDiscovery{}
+	sd := ServiceDiscovery{client: hc.Gw.serviceDiscoveryClient()}
 	sd.Init(&spec.UptimeTests.Config.ServiceDiscovery)
 	data, err := sd.Target(spec.UptimeTests.Config.ServiceDiscovery.QueryEndpoint)
 
@@ -82,6 +82,7 @@ func (gw *Gateway) MonitorApplicationInstrumentation() {
 			job.GaugeKv("pauses_quantile_max", float64(applicationGCStats.PauseQuantiles[4].Nanoseconds()), metadata)
 
 			job_rl.GaugeKv("rps", float64(GlobalRate.Rate()), metadata)
+			gw.instrumentAnalyticsPurger(metadata)
 			time.Sleep(5 * time.Second)
 		}
 	}()
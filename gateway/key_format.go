@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"hash/crc32"
+	"math/big"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/goplugin"
+)
+
+const (
+	defaultKeyFormatLength   = 32
+	defaultKeyFormatAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	keyChecksumAlphabet      = defaultKeyFormatAlphabet
+	keyChecksumLength        = 6
+)
+
+// newKeyGenerator returns the key generator of the gateway, with the configured key
+// generator plugin if any.
+func (gw *Gateway) newKeyGenerator() DefaultKeyGenerator {
+	keyGen := DefaultKeyGenerator{Gw: gw}
+
+	conf := gw.GetConfig().KeyFormat.Plugin
+	if conf.Path == "" {
+		return keyGen
+	}
+
+	plugin, err := goplugin.GetKeyGenerator(conf.Path, conf.FuncName)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix":       "auth-mgr",
+			"mwPath":       conf.Path,
+			"mwSymbolName": conf.FuncName,
+		}).WithError(err).Error("Could not load key generator Go plugin, using the default key generator")
+		return keyGen
+	}
+
+	keyGen.plugin = plugin
+	return keyGen
+}
+
+// keyFormat returns the format of the keys of an organisation.
+func (gw *Gateway) keyFormat(orgID string) config.KeyFormat {
+	conf := gw.GetConfig().KeyFormat
+	if format, ok := conf.OrgFormats[orgID]; ok {
+		return format
+	}
+
+	return conf.KeyFormat
+}
+
+// generateFormattedKey generates a random key in the given format.
+func generateFormattedKey(format config.KeyFormat) (string, error) {
+	length := format.Length
+	if length <= 0 {
+		length = defaultKeyFormatLength
+	}
+
+	alphabet := format.Alphabet
+	if alphabet == "" {
+		alphabet = defaultKeyFormatAlphabet
+	}
+
+	random, err := randomString(alphabet, length)
+	if err != nil {
+		return "", err
+	}
+
+	key := format.Prefix + random
+	if format.Checksum {
+		key += keyChecksum(key)
+	}
+
+	return key, nil
+}
+
+// keyChecksum returns the CRC32 checksum of a key, base62 encoded on 6 characters.
+func keyChecksum(key string) string {
+	sum := uint64(crc32.ChecksumIEEE([]byte(key)))
+	base := uint64(len(keyChecksumAlphabet))
+
+	checksum := make([]byte, keyChecksumLength)
+	for i := keyChecksumLength - 1; i >= 0; i-- {
+		checksum[i] = keyChecksumAlphabet[sum%base]
+		sum /= base
+	}
+
+	return string(checksum)
+}
+
+func randomString(alphabet string, length int) (string, error) {
+	var b strings.Builder
+	b.Grow(length)
+
+	max := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(alphabet[n.Int64()])
+	}
+
+	return b.String(), nil
+}
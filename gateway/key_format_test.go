@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestGenerateFormattedKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  config.KeyFormat
+		pattern string
+	}{
+		{name: "defaults", format: config.KeyFormat{Prefix: "sk_"}, pattern: `^sk_[0-9A-Za-z]{32}$`},
+		{name: "length and alphabet", format: config.KeyFormat{Length: 12, Alphabet: "abc"}, pattern: `^[abc]{12}$`},
+		{name: "checksum", format: config.KeyFormat{Prefix: "sk_live_", Length: 30, Checksum: true}, pattern: `^sk_live_[0-9A-Za-z]{36}$`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := generateFormattedKey(tc.format)
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(tc.pattern), key)
+
+			other, err := generateFormattedKey(tc.format)
+			require.NoError(t, err)
+			assert.NotEqual(t, key, other)
+
+			if tc.format.Checksum {
+				split := len(key) - keyChecksumLength
+				assert.Equal(t, keyChecksum(key[:split]), key[split:])
+			}
+		})
+	}
+}
+
+func TestKeyChecksum(t *testing.T) {
+	assert.Len(t, keyChecksum("sk_live_"), keyChecksumLength)
+	assert.Equal(t, keyChecksum("sk_live_abc"), keyChecksum("sk_live_abc"))
+	assert.NotEqual(t, keyChecksum("sk_live_abc"), keyChecksum("sk_live_abd"))
+}
+
+func TestDefaultKeyGenerator_KeyFormat(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.KeyFormat = config.KeyFormatConfig{
+			KeyFormat: config.KeyFormat{Prefix: "sk_live_", Checksum: true},
+			OrgFormats: map[string]config.KeyFormat{
+				"acme": {Prefix: "acme_", Length: 16},
+			},
+		}
+	})
+	defer ts.Close()
+
+	assert.Regexp(t, `^sk_live_[0-9A-Za-z]{38}$`, ts.Gw.keyGen.GenerateAuthKey("default"))
+	assert.Regexp(t, `^acme_[0-9A-Za-z]{16}$`, ts.Gw.keyGen.GenerateAuthKey("acme"))
+
+	t.Run("plugin", func(t *testing.T) {
+		keyGen := DefaultKeyGenerator{Gw: ts.Gw, plugin: func(orgID string) string {
+			if orgID == "plugin" {
+				return "plugin-key"
+			}
+			return ""
+		}}
+
+		assert.Equal(t, "plugin-key", keyGen.GenerateAuthKey("plugin"))
+		assert.Regexp(t, `^sk_live_`, keyGen.GenerateAuthKey("default"))
+	})
+
+	t.Run("created keys authenticate", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "formatted"
+			spec.UseKeylessAccess = false
+			spec.Proxy.ListenPath = "/formatted/"
+		})
+
+		session := CreateStandardSession()
+		session.AccessRights = map[string]user.AccessDefinition{"formatted": {APIID: "formatted", Versions: []string{"v1"}}}
+
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/keys", Data: session, Code: http.StatusOK})
+
+		var created apiModifyKeySuccess
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		assert.Regexp(t, `^sk_live_`, created.Key)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/formatted/", Headers: map[string]string{header.Authorization: created.Key}, Code: http.StatusOK},
+			{AdminAuth: true, Path: "/tyk/keys/" + created.Key, Code: http.StatusOK},
+			{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/keys/" + created.Key, Code: http.StatusOK},
+			{Path: "/formatted/", Headers: map[string]string{header.Authorization: created.Key}, Code: http.StatusForbidden},
+		}...)
+	})
+}
@@ -0,0 +1,497 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/goverify"
+	"github.com/gorilla/mux"
+
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	// keySpaceSnapshotVersion is the version of the format of the snapshots. Snapshots of a newer
+	// format aren't restored.
+	keySpaceSnapshotVersion = 1
+
+	defaultKeySpaceSnapshotInterval = 60 * 60
+	defaultMaxKeySpaceSnapshots     = 24
+
+	keySpaceSnapshotPrefix = "keyspace-"
+	keySpaceSnapshotSuffix = ".json"
+)
+
+var (
+	errKeySpaceSnapshotNotSigned        = errors.New("key space snapshots require a secret or a private key to be signed")
+	errKeySpaceSnapshotNoVerification   = errors.New("key space snapshots require a secret or a public key to be verified")
+	errKeySpaceSnapshotInvalidSignature = errors.New("key space snapshot signature is invalid")
+	errKeySpaceSnapshotIncompatible     = errors.New("key space snapshot is incompatible with the cluster")
+	errKeySpaceSnapshotKeysNotHashed    = errors.New("key space snapshots require hash_keys, so that the keys aren't exported")
+)
+
+// keySpaceSnapshot is a snapshot of the key space: the sessions of the keys as they are stored,
+// referencing their policies, and their quota counters.
+type keySpaceSnapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	NodeID    string    `json:"node_id"`
+	// HashKeys is the `hash_keys` setting of the cluster the snapshot was exported from. The keys
+	// are stored by their hash, snapshots are only exported by the clusters hashing the keys.
+	HashKeys bool               `json:"hash_keys"`
+	Sessions []keySpaceSession  `json:"sessions"`
+	Quotas   []rateLimitCounter `json:"quotas"`
+}
+
+// keySpaceSession is a stored session of the key space.
+type keySpaceSession struct {
+	// Key is the name the session is stored by, the hash of the key when keys are hashed.
+	Key     string          `json:"key"`
+	Session json.RawMessage `json:"session"`
+	// ExpiresAt is the expiry of the stored session, absolute so that restoring it later doesn't
+	// extend the life time of the key.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// signedKeySpaceSnapshot is a snapshot as it's stored, with the signature of the snapshot JSON.
+type signedKeySpaceSnapshot struct {
+	Snapshot  json.RawMessage `json:"snapshot"`
+	Signature string          `json:"signature"`
+}
+
+// keySpaceSnapshotResult reports an exported or a restored snapshot.
+type keySpaceSnapshotResult struct {
+	Status   string `json:"status"`
+	Name     string `json:"name"`
+	Sessions int    `json:"sessions"`
+	Quotas   int    `json:"quotas"`
+	// Skipped is the number of the sessions and the quota counters which expired since they
+	// were exported, which aren't restored.
+	Skipped int `json:"skipped"`
+}
+
+// isKeySpaceSnapshotName returns true if name is the name of a snapshot.
+func isKeySpaceSnapshotName(name string) bool {
+	return strings.HasPrefix(name, keySpaceSnapshotPrefix) && strings.HasSuffix(name, keySpaceSnapshotSuffix) &&
+		!strings.ContainsAny(name, `/\`)
+}
+
+func (gw *Gateway) keySpaceSnapshotStore(ctx context.Context) (keySpaceSnapshotStore, error) {
+	return newKeySpaceSnapshotStore(ctx, gw.GetConfig().KeySpaceSnapshots)
+}
+
+// runKeySpaceSnapshot exports a snapshot of the key space periodically. A single Gateway of the
+// cluster exports a snapshot per interval, the lock being held for most of the interval.
+func (gw *Gateway) runKeySpaceSnapshot() error {
+	interval := gw.keySpaceSnapshotInterval()
+
+	store := &storage.RedisCluster{KeyPrefix: "", HashKeys: false, ConnectionHandler: gw.StorageConnectionHandler}
+	ok, err := store.Lock("key-space-snapshot-lock", interval*9/10)
+	if err != nil {
+		log.WithError(err).Error("error acquiring lock to export the key space snapshot")
+		return err
+	}
+
+	if !ok {
+		log.Debug("key space snapshot lock not acquired, skipping export")
+		return nil
+	}
+
+	result, err := gw.exportKeySpaceSnapshot(gw.ctx)
+	if err != nil {
+		log.WithError(err).Error("Couldn't export the key space snapshot")
+		return err
+	}
+
+	log.WithField("name", result.Name).
+		WithField("sessions", result.Sessions).
+		WithField("quotas", result.Quotas).
+		Info("Exported the key space snapshot")
+
+	return nil
+}
+
+func (gw *Gateway) keySpaceSnapshotInterval() time.Duration {
+	if interval := gw.GetConfig().KeySpaceSnapshots.Interval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return defaultKeySpaceSnapshotInterval * time.Second
+}
+
+// exportKeySpaceSnapshot writes a signed snapshot of the key space to the snapshot storage, and
+// prunes the oldest snapshots. The clusters which don't hash the keys don't export snapshots, as
+// the sessions and the quota counters are stored by the keys themselves.
+func (gw *Gateway) exportKeySpaceSnapshot(ctx context.Context) (*keySpaceSnapshotResult, error) {
+	if !gw.GetConfig().HashKeys {
+		return nil, errKeySpaceSnapshotKeysNotHashed
+	}
+
+	snapshotStore, err := gw.keySpaceSnapshotStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := gw.keySpaceSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := gw.signKeySpaceSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	name := keySpaceSnapshotPrefix + snapshot.CreatedAt.Format(configBackupTimeFormat) + keySpaceSnapshotSuffix
+	if err := snapshotStore.Put(ctx, name, data); err != nil {
+		return nil, err
+	}
+
+	if err := gw.pruneKeySpaceSnapshots(ctx, snapshotStore); err != nil {
+		log.WithError(err).Warning("Couldn't prune the key space snapshots")
+	}
+
+	return &keySpaceSnapshotResult{Status: "ok", Name: name, Sessions: len(snapshot.Sessions), Quotas: len(snapshot.Quotas)}, nil
+}
+
+// keySpaceSnapshot returns the snapshot of the stored sessions and of the quota counters.
+func (gw *Gateway) keySpaceSnapshot(ctx context.Context) (*keySpaceSnapshot, error) {
+	snapshot := &keySpaceSnapshot{
+		Version:   keySpaceSnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		NodeID:    gw.GetNodeID(),
+		HashKeys:  gw.GetConfig().HashKeys,
+		Sessions:  []keySpaceSession{},
+		Quotas:    []rateLimitCounter{},
+	}
+
+	conn, err := (&storage.RedisCluster{ConnectionHandler: gw.StorageConnectionHandler}).Client()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := scanRedisKeys(ctx, conn, sessionKeyPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, sessionKeyPrefix)
+		if isRateLimitCounterKey(name) {
+			continue
+		}
+
+		session, err := exportKeySpaceSession(ctx, conn, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if session != nil {
+			session.Key = name
+			snapshot.Sessions = append(snapshot.Sessions, *session)
+		}
+	}
+
+	if limiterConn := gw.SessionLimiter.limiterStorage; limiterConn != nil {
+		quotaKeys, err := scanRedisKeys(ctx, limiterConn, QuotaKeyPrefix+"*")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range quotaKeys {
+			counter, err := exportRateLimitCounter(ctx, limiterConn, key)
+			if err != nil {
+				return nil, err
+			}
+
+			if counter != nil {
+				snapshot.Quotas = append(snapshot.Quotas, *counter)
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+// exportKeySpaceSession returns the stored session at key, nil when it expired since it was
+// scanned or isn't a session.
+func exportKeySpaceSession(ctx context.Context, conn redis.UniversalClient, key string) (*keySpaceSession, error) {
+	value, err := conn.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 || value[0] != '{' || !json.Valid(value) {
+		return nil, nil
+	}
+
+	session := &keySpaceSession{Session: value}
+
+	ttl, err := conn.PTTL(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl).UTC()
+		session.ExpiresAt = &expiresAt
+	}
+
+	return session, nil
+}
+
+// signKeySpaceSnapshot returns the stored snapshot, signed with the private key when it's
+// configured, or with the shared secret.
+func (gw *Gateway) signKeySpaceSnapshot(snapshot *keySpaceSnapshot) ([]byte, error) {
+	conf := gw.GetConfig().KeySpaceSnapshots
+
+	message, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := signedKeySpaceSnapshot{Snapshot: message}
+
+	switch {
+	case conf.PrivateKeyPath != "":
+		signer, err := goverify.LoadPrivateKeyFromFile(conf.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if signed.Signature, err = configPayloadRSA(signer, message); err != nil {
+			return nil, err
+		}
+	case conf.Secret != "":
+		signed.Signature = base64.StdEncoding.EncodeToString(configPayloadHMAC(conf.Secret, message))
+	default:
+		return nil, errKeySpaceSnapshotNotSigned
+	}
+
+	return json.Marshal(signed)
+}
+
+// verifyKeySpaceSnapshot returns the snapshot of a stored snapshot, after verifying its signature
+// with the public key when it's configured, or with the shared secret.
+func (gw *Gateway) verifyKeySpaceSnapshot(data []byte) (*keySpaceSnapshot, error) {
+	conf := gw.GetConfig().KeySpaceSnapshots
+
+	var signed signedKeySpaceSnapshot
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil || len(signature) == 0 {
+		return nil, errKeySpaceSnapshotInvalidSignature
+	}
+
+	switch {
+	case conf.PublicKeyPath != "":
+		verifier, err := goverify.LoadPublicKeyFromFile(conf.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifier.Verify(signed.Snapshot, signature); err != nil {
+			return nil, errKeySpaceSnapshotInvalidSignature
+		}
+	case conf.Secret != "":
+		if !hmac.Equal(signature, configPayloadHMAC(conf.Secret, signed.Snapshot)) {
+			return nil, errKeySpaceSnapshotInvalidSignature
+		}
+	default:
+		return nil, errKeySpaceSnapshotNoVerification
+	}
+
+	snapshot := &keySpaceSnapshot{}
+	if err := json.Unmarshal(signed.Snapshot, snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// restoreKeySpaceSnapshot restores the sessions and the quota counters of a snapshot, replacing
+// the existing ones. The sessions and the counters which expired since they were exported are
+// skipped.
+func (gw *Gateway) restoreKeySpaceSnapshot(ctx context.Context, name string) (*keySpaceSnapshotResult, error) {
+	if !isKeySpaceSnapshotName(name) {
+		return nil, errKeySpaceSnapshotNotFound
+	}
+
+	snapshotStore, err := gw.keySpaceSnapshotStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := snapshotStore.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := gw.verifyKeySpaceSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.Version > keySpaceSnapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errKeySpaceSnapshotIncompatible, snapshot.Version)
+	}
+
+	if snapshot.HashKeys != gw.GetConfig().HashKeys {
+		return nil, fmt.Errorf("%w: exported with a different hash_keys setting", errKeySpaceSnapshotIncompatible)
+	}
+
+	conn, err := (&storage.RedisCluster{ConnectionHandler: gw.StorageConnectionHandler}).Client()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &keySpaceSnapshotResult{Status: "ok", Name: name}
+	now := time.Now()
+
+	for _, session := range snapshot.Sessions {
+		if session.ExpiresAt != nil && !session.ExpiresAt.After(now) {
+			result.Skipped++
+			continue
+		}
+
+		key := sessionKeyPrefix + session.Key
+		_, err := conn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, []byte(session.Session), 0)
+			if session.ExpiresAt != nil {
+				pipe.PExpireAt(ctx, key, *session.ExpiresAt)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		gw.SessionCache.Delete(session.Key)
+		result.Sessions++
+	}
+
+	if limiterConn := gw.SessionLimiter.limiterStorage; limiterConn != nil && len(snapshot.Quotas) > 0 {
+		quotas := &rateLimitState{Counters: snapshot.Quotas}
+		if err := quotas.validate(); err != nil {
+			return nil, err
+		}
+
+		imported, err := importRateLimitState(ctx, limiterConn, quotas)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Quotas = imported.Imported
+		result.Skipped += imported.Skipped
+	}
+
+	return result, nil
+}
+
+// listKeySpaceSnapshots returns the stored snapshots, the newest first. The names sort by the
+// creation time of the snapshots.
+func listKeySpaceSnapshots(ctx context.Context, snapshotStore keySpaceSnapshotStore) ([]keySpaceSnapshotInfo, error) {
+	snapshots, err := snapshotStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name > snapshots[j].Name
+	})
+
+	return snapshots, nil
+}
+
+// pruneKeySpaceSnapshots removes the oldest snapshots above the maximum number of snapshots.
+func (gw *Gateway) pruneKeySpaceSnapshots(ctx context.Context, snapshotStore keySpaceSnapshotStore) error {
+	maxSnapshots := gw.GetConfig().KeySpaceSnapshots.MaxSnapshots
+	if maxSnapshots <= 0 {
+		maxSnapshots = defaultMaxKeySpaceSnapshots
+	}
+
+	snapshots, err := listKeySpaceSnapshots(ctx, snapshotStore)
+	if err != nil || len(snapshots) <= maxSnapshots {
+		return err
+	}
+
+	for _, snapshot := range snapshots[maxSnapshots:] {
+		if err := snapshotStore.Delete(ctx, snapshot.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keySpaceSnapshotsHandler lists the stored snapshots with GET, and exports a snapshot with POST.
+func (gw *Gateway) keySpaceSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		result, err := gw.exportKeySpaceSnapshot(r.Context())
+		if errors.Is(err, errKeySpaceSnapshotKeysNotHashed) {
+			doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+			return
+		}
+		if err != nil {
+			log.WithError(err).Error("Couldn't export the key space snapshot")
+			doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't export the key space snapshot: "+err.Error()))
+			return
+		}
+
+		doJSONWrite(w, http.StatusOK, result)
+		return
+	}
+
+	snapshotStore, err := gw.keySpaceSnapshotStore(r.Context())
+	if err == nil {
+		var snapshots []keySpaceSnapshotInfo
+		if snapshots, err = listKeySpaceSnapshots(r.Context(), snapshotStore); err == nil {
+			doJSONWrite(w, http.StatusOK, snapshots)
+			return
+		}
+	}
+
+	log.WithError(err).Error("Couldn't list the key space snapshots")
+	doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't list the key space snapshots"))
+}
+
+// keySpaceSnapshotRestoreHandler restores a stored snapshot, after verifying its signature.
+func (gw *Gateway) keySpaceSnapshotRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	result, err := gw.restoreKeySpaceSnapshot(r.Context(), name)
+	switch {
+	case errors.Is(err, errKeySpaceSnapshotNotFound):
+		doJSONWrite(w, http.StatusNotFound, apiError(err.Error()))
+		return
+	case errors.Is(err, errKeySpaceSnapshotInvalidSignature), errors.Is(err, errKeySpaceSnapshotNoVerification),
+		errors.Is(err, errKeySpaceSnapshotIncompatible):
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	case err != nil:
+		log.WithError(err).WithField("name", name).Error("Couldn't restore the key space snapshot")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't restore the key space snapshot: "+err.Error()))
+		return
+	}
+
+	log.WithField("name", name).
+		WithField("sessions", result.Sessions).
+		WithField("quotas", result.Quotas).
+		WithField("skipped", result.Skipped).
+		Info("Restored the key space snapshot")
+
+	doJSONWrite(w, http.StatusOK, result)
+}
@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+const (
+	keySpaceSnapshotStorageFile = "file"
+	keySpaceSnapshotStorageS3   = "s3"
+)
+
+var errKeySpaceSnapshotNotFound = errors.New("Key space snapshot not found")
+
+// keySpaceSnapshotInfo is a stored snapshot of the key space.
+type keySpaceSnapshotInfo struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// keySpaceSnapshotStore stores the signed snapshots of the key space by name.
+type keySpaceSnapshotStore interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	List(ctx context.Context) ([]keySpaceSnapshotInfo, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// newKeySpaceSnapshotStore returns the store of the snapshots of the configured storage.
+func newKeySpaceSnapshotStore(ctx context.Context, conf config.KeySpaceSnapshotsConfig) (keySpaceSnapshotStore, error) {
+	switch conf.Storage {
+	case "", keySpaceSnapshotStorageFile:
+		dir := conf.Path
+		if dir == "" {
+			dir = "."
+		}
+		return &fileSnapshotStore{dir: dir}, nil
+	case keySpaceSnapshotStorageS3:
+		return newS3SnapshotStore(ctx, conf.S3)
+	}
+
+	return nil, errors.New("unsupported key space snapshot storage " + conf.Storage)
+}
+
+// fileSnapshotStore stores the snapshots in the files of a directory.
+type fileSnapshotStore struct {
+	dir string
+}
+
+func (s *fileSnapshotStore) Put(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that a restore never reads a partial snapshot
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+func (s *fileSnapshotStore) Get(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil, errKeySpaceSnapshotNotFound
+	}
+	return data, err
+}
+
+func (s *fileSnapshotStore) List(_ context.Context) ([]keySpaceSnapshotInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []keySpaceSnapshotInfo{}, nil
+		}
+		return nil, err
+	}
+
+	snapshots := []keySpaceSnapshotInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isKeySpaceSnapshotName(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, keySpaceSnapshotInfo{Name: entry.Name(), Size: info.Size(), Created: info.ModTime()})
+	}
+
+	return snapshots, nil
+}
+
+func (s *fileSnapshotStore) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// s3SnapshotStore stores the snapshots in the objects of an S3 bucket.
+type s3SnapshotStore struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3SnapshotStore(ctx context.Context, conf config.KeySpaceSnapshotsS3Config) (*s3SnapshotStore, error) {
+	if conf.Bucket == "" {
+		return nil, errors.New("the s3 key space snapshot storage requires a bucket")
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if conf.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(conf.Region))
+	}
+
+	awsConf, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(awsConf, func(o *s3.Options) {
+		if conf.Endpoint != "" {
+			o.BaseEndpoint = aws.String(conf.Endpoint)
+		}
+		o.UsePathStyle = conf.UsePathStyle
+	})
+
+	return &s3SnapshotStore{client: client, bucket: conf.Bucket, prefix: conf.Prefix}, nil
+}
+
+func (s *s3SnapshotStore) key(name string) string {
+	return path.Join(s.prefix, name)
+}
+
+func (s *s3SnapshotStore) Put(ctx context.Context, name string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key(name)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s *s3SnapshotStore) Get(ctx context.Context, name string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if err != nil {
+		var notFound *s3types.NoSuchKey
+		if errors.As(err, &notFound) {
+			return nil, errKeySpaceSnapshotNotFound
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
+
+func (s *s3SnapshotStore) List(ctx context.Context) ([]keySpaceSnapshotInfo, error) {
+	prefix := s.prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	snapshots := []keySpaceSnapshotInfo{}
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			name := path.Base(aws.ToString(object.Key))
+			if !isKeySpaceSnapshotName(name) {
+				continue
+			}
+
+			snapshots = append(snapshots, keySpaceSnapshotInfo{
+				Name:    name,
+				Size:    aws.ToInt64(object.Size),
+				Created: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (s *s3SnapshotStore) Delete(ctx context.Context, name string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	return err
+}
@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestKeySpaceSnapshots(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.KeySpaceSnapshots.Path = dir
+		globalConf.KeySpaceSnapshots.Secret = "snapshot-secret"
+		globalConf.KeySpaceSnapshots.MaxSnapshots = 2
+		globalConf.HashKeys = true
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "snapshot"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/snapshot/"
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"snapshot": {APIID: "snapshot"}}
+		s.QuotaMax, s.QuotaRenewalRate = 3, 3600
+	})
+	authHeaders := map[string]string{header.Authorization: key}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/snapshot/", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/snapshot/", Headers: authHeaders, Code: http.StatusOK},
+	}...)
+
+	export := func(t *testing.T) *keySpaceSnapshotResult {
+		t.Helper()
+
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/key-space/snapshots", Code: http.StatusOK})
+
+		result := &keySpaceSnapshotResult{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+		return result
+	}
+
+	restore := func(name string, code int) test.TestCase {
+		return test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/key-space/snapshots/" + name + "/restore", Code: code}
+	}
+
+	t.Run("export and restore", func(t *testing.T) {
+		exported := export(t)
+		assert.NotZero(t, exported.Sessions)
+		assert.NotZero(t, exported.Quotas)
+
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Path: "/tyk/key-space/snapshots", Code: http.StatusOK})
+		var snapshots []keySpaceSnapshotInfo
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshots))
+		require.NotEmpty(t, snapshots)
+		assert.Equal(t, exported.Name, snapshots[0].Name)
+
+		// the standby cluster has neither the key nor its quota
+		keyHash := storage.HashKey(key, ts.Gw.GetConfig().HashKeys)
+		ts.Gw.GlobalSessionManager.RemoveSession("default", key, false)
+		require.NoError(t, ts.Gw.SessionLimiter.limiterStorage.Del(context.Background(), QuotaKeyPrefix+keyHash).Err())
+		_, _ = ts.Run(t, test.TestCase{Path: "/snapshot/", Headers: authHeaders, Code: http.StatusForbidden})
+
+		resp, _ = ts.Run(t, restore(exported.Name, http.StatusOK))
+		restored := &keySpaceSnapshotResult{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(restored))
+		assert.Equal(t, exported.Sessions, restored.Sessions+restored.Skipped)
+
+		// the restored quota has a single request left
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/snapshot/", Headers: authHeaders, Code: http.StatusOK},
+			{Path: "/snapshot/", Headers: authHeaders, Code: http.StatusForbidden},
+		}...)
+	})
+
+	t.Run("tampered snapshot", func(t *testing.T) {
+		exported := export(t)
+
+		path := filepath.Join(dir, exported.Name)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var signed signedKeySpaceSnapshot
+		require.NoError(t, json.Unmarshal(data, &signed))
+		signed.Signature = "dGFtcGVyZWQ="
+		data, err = json.Marshal(signed)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		_, _ = ts.Run(t, restore(exported.Name, http.StatusBadRequest))
+	})
+
+	t.Run("pruned snapshots", func(t *testing.T) {
+		export(t)
+		export(t)
+
+		snapshots, err := listKeySpaceSnapshots(context.Background(), &fileSnapshotStore{dir: dir})
+		require.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+	})
+
+	t.Run("unknown snapshot", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			restore("keyspace-unknown.json", http.StatusNotFound),
+			restore("other.json", http.StatusNotFound),
+		}...)
+	})
+
+	t.Run("keys not hashed", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.HashKeys = false
+		ts.Gw.SetConfig(globalConf)
+		defer func() {
+			globalConf.HashKeys = true
+			ts.Gw.SetConfig(globalConf)
+		}()
+
+		_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/key-space/snapshots",
+			Code: http.StatusBadRequest, BodyMatch: "require hash_keys"})
+	})
+}
+
+func TestKeySpaceSnapshot_signature(t *testing.T) {
+	gw := &Gateway{}
+	gw.SetConfig(config.Config{})
+
+	snapshot := &keySpaceSnapshot{Version: keySpaceSnapshotVersion, Sessions: []keySpaceSession{{Key: "key", Session: json.RawMessage(`{"org_id":"default"}`)}}}
+
+	_, err := gw.signKeySpaceSnapshot(snapshot)
+	assert.ErrorIs(t, err, errKeySpaceSnapshotNotSigned)
+
+	gw.SetConfig(config.Config{KeySpaceSnapshots: config.KeySpaceSnapshotsConfig{Secret: "secret"}})
+	data, err := gw.signKeySpaceSnapshot(snapshot)
+	require.NoError(t, err)
+
+	verified, err := gw.verifyKeySpaceSnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Sessions, verified.Sessions)
+
+	gw.SetConfig(config.Config{KeySpaceSnapshots: config.KeySpaceSnapshotsConfig{Secret: "other"}})
+	_, err = gw.verifyKeySpaceSnapshot(data)
+	assert.ErrorIs(t, err, errKeySpaceSnapshotInvalidSignature)
+
+	gw.SetConfig(config.Config{})
+	_, err = gw.verifyKeySpaceSnapshot(data)
+	assert.ErrorIs(t, err, errKeySpaceSnapshotNoVerification)
+}
@@ -0,0 +1,501 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk/internal/model"
+	"github.com/TykTechnologies/tyk/internal/policy"
+	"github.com/TykTechnologies/tyk/internal/rate"
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// maxSimulatedRequests is the maximum number of requests of a simulation profile.
+const maxSimulatedRequests = 1000000
+
+// limitSimulationRequest is a proposed session or policies and a profile of requests, to
+// simulate the rate limit and the quota of an API with.
+type limitSimulationRequest struct {
+	APIID string `json:"api_id"`
+	// Key is an existing key. Its session applies when no session is proposed, and the quota it
+	// used in the current period counts in the simulation.
+	Key string `json:"key"`
+	// Session is the proposed session, its policies are applied.
+	Session *user.SessionState `json:"session"`
+	// Policies are proposed policies, applied instead of the stored policies with the same IDs.
+	Policies []user.Policy          `json:"policies"`
+	Profile  limitSimulationProfile `json:"profile"`
+}
+
+// limitSimulationProfile is a constant rate of requests to the cluster.
+type limitSimulationProfile struct {
+	// RPS is the number of requests per second.
+	RPS float64 `json:"rps"`
+	// Duration is the number of seconds the requests are sent for.
+	Duration float64 `json:"duration"`
+}
+
+// limitSimulationResponse reports whether and when the limits of the API trigger for the profile.
+type limitSimulationResponse struct {
+	APIID string `json:"api_id"`
+	// RateLimiter is the rate limiter simulated. The distributed rate limiter is simulated as the
+	// sliding log over the DRL threshold, like it falls back to it.
+	RateLimiter string `json:"rate_limiter"`
+	// Gateways is the number of the Gateways the distributed rate limiter shares the rate
+	// between, the requests being balanced evenly across them.
+	Gateways int `json:"gateways"`
+
+	Rate           float64 `json:"rate"`
+	Per            float64 `json:"per"`
+	QuotaMax       int64   `json:"quota_max"`
+	QuotaRemaining int64   `json:"quota_remaining"`
+
+	Requests  int                   `json:"requests"`
+	Allowed   int                   `json:"allowed"`
+	RateLimit limitSimulationResult `json:"rate_limit"`
+	Quota     limitSimulationResult `json:"quota"`
+}
+
+// limitSimulationResult reports when a limit triggers.
+type limitSimulationResult struct {
+	Triggered bool `json:"triggered"`
+	// TriggeredAfter is the number of seconds after which the first request is blocked.
+	TriggeredAfter float64 `json:"triggered_after"`
+	// TriggeredAt is the number of the first request blocked, from 1.
+	TriggeredAt int `json:"triggered_at"`
+	// Blocked is the number of requests blocked.
+	Blocked int `json:"blocked"`
+}
+
+func (r *limitSimulationResult) block(n int, t float64) {
+	if !r.Triggered {
+		r.Triggered = true
+		r.TriggeredAt = n
+		r.TriggeredAfter = t
+	}
+	r.Blocked++
+}
+
+// simulationPolicies provides the proposed policies ahead of the stored policies.
+type simulationPolicies struct {
+	model.PolicyProvider
+	proposed map[string]user.Policy
+}
+
+func (p *simulationPolicies) PolicyByID(id string) (user.Policy, bool) {
+	if pol, ok := p.proposed[id]; ok {
+		return pol, true
+	}
+	return p.PolicyProvider.PolicyByID(id)
+}
+
+func (p *simulationPolicies) PolicyIDs() []string {
+	ids := p.PolicyProvider.PolicyIDs()
+	for id := range p.proposed {
+		if _, ok := p.PolicyProvider.PolicyByID(id); !ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (p *simulationPolicies) PolicyCount() int {
+	return len(p.PolicyIDs())
+}
+
+// simulatedLimiter is a model of a rate limiter, allowing the requests at seconds t.
+type simulatedLimiter interface {
+	allow(t float64) bool
+}
+
+// simulatedSlidingLog counts the requests, blocked or not, over the last period, like the
+// Redis Rate Limiter. With sentinel set, the requests are blocked for the period from the
+// request reaching the rate less one, the sentinel being set preemptively after it's allowed.
+type simulatedSlidingLog struct {
+	rate, per    float64
+	sentinel     bool
+	log          []float64
+	blockedUntil float64
+}
+
+func (l *simulatedSlidingLog) allow(t float64) bool {
+	for len(l.log) > 0 && l.log[0] <= t-l.per {
+		l.log = l.log[1:]
+	}
+	count := float64(len(l.log))
+	l.log = append(l.log, t)
+
+	if !l.sentinel {
+		return count < l.rate
+	}
+
+	if t < l.blockedUntil {
+		return false
+	}
+	if count >= l.rate-1 {
+		l.blockedUntil = t + l.per
+	}
+	return true
+}
+
+// simulatedFixedWindow counts the requests over windows of the period aligned on the clock.
+type simulatedFixedWindow struct {
+	rate, per, start float64
+	window           float64
+	count            float64
+}
+
+func (l *simulatedFixedWindow) allow(t float64) bool {
+	window := math.Floor((l.start + t) / l.per)
+	if window != l.window {
+		l.window, l.count = window, 0
+	}
+
+	if l.count >= l.rate {
+		return false
+	}
+	l.count++
+	return true
+}
+
+// simulatedSlidingWindow weights the count of the previous window by the part of it in the
+// sliding window, counting the blocked requests.
+type simulatedSlidingWindow struct {
+	rate, per, start float64
+	window           float64
+	prev, curr       float64
+}
+
+func (l *simulatedSlidingWindow) allow(t float64) bool {
+	now := l.start + t
+	window := math.Floor(now / l.per)
+	switch window {
+	case l.window:
+	case l.window + 1:
+		l.prev, l.curr = l.curr, 0
+	default:
+		l.prev, l.curr = 0, 0
+	}
+	l.window = window
+	l.curr++
+
+	elapsed := now - window*l.per
+	total := l.prev*(l.per-elapsed)/l.per + l.curr
+	return total-l.rate < 1e-9
+}
+
+// simulatedTokenBucket refills a token every period, up to the rate.
+type simulatedTokenBucket struct {
+	rate, per float64
+	available float64
+	last      float64
+}
+
+func (l *simulatedTokenBucket) allow(t float64) bool {
+	l.available = math.Min(l.rate, l.available+math.Floor((t-l.last)/l.per))
+	if t-l.last >= l.per {
+		l.last = t - math.Mod(t-l.last, l.per)
+	}
+
+	if l.available < 1 {
+		return false
+	}
+	l.available--
+	return true
+}
+
+// simulatedGCRA allows a request every period divided by the rate, with bursts of the rate.
+// It also simulates the leaky bucket, which delays the requests it allows to the rate.
+type simulatedGCRA struct {
+	interval, tolerance float64
+	tat                 float64
+}
+
+func (l *simulatedGCRA) allow(t float64) bool {
+	tat := math.Max(l.tat, t)
+	if tat-l.tolerance > t+1e-9 {
+		return false
+	}
+	l.tat = tat + l.interval
+	return true
+}
+
+// simulatedDRL shares the rate between the Gateways, each counting the requests it receives
+// in a bucket emptied every period from the request which created it.
+type simulatedDRL struct {
+	capacity, per float64
+	buckets       []simulatedDRLBucket
+	next          int
+}
+
+type simulatedDRLBucket struct {
+	reset, count float64
+}
+
+func (l *simulatedDRL) allow(t float64) bool {
+	bucket := &l.buckets[l.next]
+	l.next = (l.next + 1) % len(l.buckets)
+
+	if t >= bucket.reset {
+		bucket.reset, bucket.count = t+l.per, 0
+	}
+
+	if bucket.count >= l.capacity {
+		return false
+	}
+	bucket.count++
+	return true
+}
+
+// simulatedRateLimiter returns the name and the model of the rate limiter of the API.
+func (gw *Gateway) simulatedRateLimiter(api *APISpec, rateLimit, per float64, gateways int, start float64) (string, simulatedLimiter) {
+	conf := gw.GetConfig()
+
+	name := gw.SessionLimiter.rateLimiterName(api)
+	if name == "" {
+		kind, ok := rate.LimiterKind(&conf)
+		switch {
+		case ok:
+			name = kind
+		case conf.EnableSentinelRateLimiter:
+			name = rate.LimitSentinel
+		case conf.EnableRedisRollingLimiter:
+			name = rate.LimitSlidingLog
+		default:
+			name = rate.LimitDRL
+		}
+	}
+
+	if name == rate.LimitDRL {
+		threshold := conf.DRLThreshold
+		if threshold == 0 {
+			threshold = 5
+		}
+
+		n := float64(gateways)
+		if n > 1 && n*threshold >= rateLimit/per {
+			name = rate.LimitSlidingLog
+		}
+	}
+
+	switch name {
+	case rate.LimitDRL:
+		// the cost of a bucket is the rate in tokens, every request takes a token per Gateway
+		capacity := math.Max(1, math.Floor(rateLimit/float64(gateways)))
+		return name, &simulatedDRL{capacity: capacity, per: per, buckets: make([]simulatedDRLBucket, gateways)}
+	case rate.LimitSentinel:
+		return name, &simulatedSlidingLog{rate: rateLimit, per: per, sentinel: true}
+	case rate.LimitFixedWindow:
+		return name, &simulatedFixedWindow{rate: rateLimit, per: per, start: start, window: -1}
+	case rate.LimitSlidingWindow:
+		return name, &simulatedSlidingWindow{rate: rateLimit, per: per, start: start, window: -2}
+	case rate.LimitTokenBucket:
+		return name, &simulatedTokenBucket{rate: math.Floor(rateLimit), per: per, available: math.Floor(rateLimit)}
+	case rate.LimitGCRA, rate.LimitLeakyBucket:
+		interval := per / rateLimit
+		return name, &simulatedGCRA{interval: interval, tolerance: interval * (rateLimit - 1), tat: math.Inf(-1)}
+	}
+
+	return rate.LimitSlidingLog, &simulatedSlidingLog{rate: rateLimit, per: per}
+}
+
+// simulatedQuota is the quota of the session, renewed at renews seconds, every renewal seconds.
+type simulatedQuota struct {
+	max, remaining  int64
+	renews, renewal float64
+}
+
+func (q *simulatedQuota) allow(t float64) bool {
+	if q.max <= 0 {
+		return true
+	}
+
+	if q.renewal > 0 && t >= q.renews {
+		q.remaining = q.max
+		for q.renews <= t {
+			q.renews += q.renewal
+		}
+	}
+
+	if q.remaining <= 0 {
+		return false
+	}
+	q.remaining--
+	return true
+}
+
+// simulateLimits sends the requests of the profile through the rate limiter and the quota.
+// The requests blocked by the rate limiter aren't counted in the quota.
+func simulateLimits(profile limitSimulationProfile, limiter simulatedLimiter, quota *simulatedQuota, result *limitSimulationResponse) {
+	result.Requests = int(profile.RPS * profile.Duration)
+
+	for i := 0; i < result.Requests; i++ {
+		t := float64(i) / profile.RPS
+
+		if limiter != nil && !limiter.allow(t) {
+			result.RateLimit.block(i+1, t)
+			continue
+		}
+
+		if !quota.allow(t) {
+			result.Quota.block(i+1, t)
+			continue
+		}
+
+		result.Allowed++
+	}
+}
+
+// simulationSession returns the session of the simulation, with the policies applied.
+func (gw *Gateway) simulationSession(simReq *limitSimulationRequest, spec *APISpec) (*user.SessionState, error) {
+	var session *user.SessionState
+
+	switch {
+	case simReq.Session != nil:
+		session = simReq.Session
+	case simReq.Key != "":
+		existing, ok := gw.GlobalSessionManager.SessionDetail(spec.OrgID, simReq.Key, false)
+		if !ok {
+			return nil, errors.New("Key not found")
+		}
+		clone := existing.Clone()
+		session = &clone
+	default:
+		return nil, errors.New("A session or a key is required")
+	}
+
+	provider := &simulationPolicies{PolicyProvider: gw, proposed: map[string]user.Policy{}}
+	for _, pol := range simReq.Policies {
+		if pol.ID == "" {
+			return nil, errors.New("Proposed policies require an ID")
+		}
+		provider.proposed[pol.ID] = pol
+	}
+
+	if err := policy.New(&spec.OrgID, provider, log).Apply(session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// simulationQuotaUsed returns the quota used by the key in the current period and the seconds
+// before it renews, 0 when the key has no counter.
+func (gw *Gateway) simulationQuotaUsed(ctx context.Context, key string, session *user.SessionState, scope string) (int64, float64) {
+	conn := gw.SessionLimiter.limiterStorage
+	if key == "" || conn == nil {
+		return 0, 0
+	}
+
+	if session.KeyID == "" {
+		session.KeyID = key
+	}
+	quotaKey := quotaScopeKey(session, "", scope, gw.GetConfig().HashKeys)
+
+	used, err := conn.Get(ctx, quotaKey).Int64()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.WithError(err).Warning("Couldn't get the quota used by the simulated key")
+		}
+		return 0, 0
+	}
+
+	ttl, err := conn.PTTL(ctx, quotaKey).Result()
+	if err != nil || ttl < 0 {
+		return used, 0
+	}
+
+	return used, ttl.Seconds()
+}
+
+// limitSimulationHandler simulates the rate limit and the quota of an API for a proposed
+// session or policies, and a profile of requests to the cluster, without counting them.
+func (gw *Gateway) limitSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	var simReq limitSimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&simReq); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	profile := simReq.Profile
+	if profile.RPS <= 0 || profile.Duration <= 0 {
+		doJSONWrite(w, http.StatusBadRequest, apiError("The profile requires a positive rps and duration"))
+		return
+	}
+	if profile.RPS*profile.Duration > maxSimulatedRequests {
+		doJSONWrite(w, http.StatusBadRequest, apiError("The profile exceeds the maximum number of simulated requests"))
+		return
+	}
+
+	spec := gw.getApiSpec(simReq.APIID)
+	if spec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	session, err := gw.simulationSession(&simReq, spec)
+	if err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	accessDef, scope, err := GetAccessDefinitionByAPIIDOrSession(session, spec)
+	if err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("The session doesn't have access to the API"))
+		return
+	}
+	limit := accessDef.Limit
+
+	gateways := gw.drlState().ActiveServers
+	if gateways < 1 {
+		gateways = 1
+	}
+
+	result := &limitSimulationResponse{
+		APIID:    spec.APIID,
+		Gateways: gateways,
+		Rate:     limit.Rate,
+		Per:      limit.Per,
+		QuotaMax: limit.QuotaMax,
+	}
+
+	now := time.Now()
+
+	var limiter simulatedLimiter
+	if !spec.DisableRateLimit && limit.Rate > 0 && limit.Per > 0 {
+		result.RateLimiter, limiter = gw.simulatedRateLimiter(spec, limit.Rate, limit.Per, gateways, float64(now.UnixNano())/float64(time.Second))
+	}
+
+	quota := &simulatedQuota{max: limit.QuotaMax, remaining: limit.QuotaMax, renewal: float64(limit.QuotaRenewalRate)}
+	if spec.DisableQuota {
+		quota.max = 0
+	}
+
+	if quota.max > 0 {
+		used, renews := gw.simulationQuotaUsed(r.Context(), simReq.Key, session, scope)
+		quota.remaining = quota.max - used
+		switch {
+		case renews > 0:
+			quota.renews = renews
+		case used > 0:
+			// the counter doesn't expire
+			quota.renewal = 0
+		default:
+			// the counter is created by the first request, and renews after the renewal rate
+			quota.renews = quota.renewal
+		}
+
+		if quota.remaining < 0 {
+			quota.remaining = 0
+		}
+		result.QuotaRemaining = quota.remaining
+	}
+
+	simulateLimits(profile, limiter, quota, result)
+	doJSONWrite(w, http.StatusOK, result)
+}
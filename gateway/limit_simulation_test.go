@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestSimulateLimits(t *testing.T) {
+	simulate := func(profile limitSimulationProfile, limiter simulatedLimiter, quota *simulatedQuota) *limitSimulationResponse {
+		result := &limitSimulationResponse{}
+		simulateLimits(profile, limiter, quota, result)
+		return result
+	}
+
+	t.Run("sliding log", func(t *testing.T) {
+		result := simulate(limitSimulationProfile{RPS: 1, Duration: 20}, &simulatedSlidingLog{rate: 10, per: 60}, &simulatedQuota{})
+
+		assert.Equal(t, 20, result.Requests)
+		assert.Equal(t, 10, result.Allowed)
+		assert.Equal(t, limitSimulationResult{Triggered: true, TriggeredAfter: 10, TriggeredAt: 11, Blocked: 10}, result.RateLimit)
+		assert.False(t, result.Quota.Triggered)
+	})
+
+	t.Run("sliding log counts the blocked requests", func(t *testing.T) {
+		result := simulate(limitSimulationProfile{RPS: 2, Duration: 3}, &simulatedSlidingLog{rate: 1, per: 1}, &simulatedQuota{})
+		assert.Equal(t, 1, result.Allowed, "the blocked requests keep the log full")
+	})
+
+	t.Run("sentinel", func(t *testing.T) {
+		result := simulate(limitSimulationProfile{RPS: 1, Duration: 10}, &simulatedSlidingLog{rate: 2, per: 5, sentinel: true}, &simulatedQuota{})
+
+		// the second request sets the sentinel, which blocks until the seventh
+		assert.Equal(t, 3, result.RateLimit.TriggeredAt)
+		assert.Equal(t, 3, result.Allowed)
+	})
+
+	t.Run("distributed rate limiter", func(t *testing.T) {
+		limiter := &simulatedDRL{capacity: 5, per: 60, buckets: make([]simulatedDRLBucket, 2)}
+		result := simulate(limitSimulationProfile{RPS: 1, Duration: 20}, limiter, &simulatedQuota{})
+
+		assert.Equal(t, 10, result.Allowed, "the rate is shared between the Gateways")
+		assert.Equal(t, 11, result.RateLimit.TriggeredAt)
+	})
+
+	t.Run("gcra", func(t *testing.T) {
+		limiter := &simulatedGCRA{interval: 1, tolerance: 4}
+		result := simulate(limitSimulationProfile{RPS: 10, Duration: 2}, limiter, &simulatedQuota{})
+
+		// a burst of the rate, then a request per interval
+		assert.Equal(t, 6, result.RateLimit.TriggeredAt)
+		assert.Equal(t, 6, result.Allowed)
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		result := simulate(limitSimulationProfile{RPS: 1, Duration: 10}, nil, &simulatedQuota{max: 10, remaining: 3, renews: 5, renewal: 100})
+
+		assert.False(t, result.RateLimit.Triggered)
+		assert.Equal(t, limitSimulationResult{Triggered: true, TriggeredAfter: 3, TriggeredAt: 4, Blocked: 2}, result.Quota)
+		assert.Equal(t, 8, result.Allowed, "the quota renews after 5 seconds")
+	})
+
+	t.Run("rate limited requests don't use the quota", func(t *testing.T) {
+		result := simulate(limitSimulationProfile{RPS: 1, Duration: 10}, &simulatedSlidingLog{rate: 2, per: 60}, &simulatedQuota{max: 5, remaining: 5})
+
+		assert.Equal(t, 2, result.Allowed)
+		assert.False(t, result.Quota.Triggered)
+	})
+}
+
+func TestLimitSimulation(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "simulated"
+		spec.OrgID = "default"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/simulated/"
+	})
+
+	accessRights := map[string]user.AccessDefinition{"simulated": {APIID: "simulated"}}
+
+	simulate := func(t *testing.T, body interface{}) *limitSimulationResponse {
+		t.Helper()
+
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/simulate", Data: body, Code: http.StatusOK})
+
+		result := &limitSimulationResponse{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+		return result
+	}
+
+	t.Run("proposed session", func(t *testing.T) {
+		session := user.NewSessionState()
+		session.OrgID = "default"
+		session.AccessRights = accessRights
+		session.Rate, session.Per = 10, 60
+
+		result := simulate(t, limitSimulationRequest{
+			APIID:   "simulated",
+			Session: session,
+			Profile: limitSimulationProfile{RPS: 2, Duration: 10},
+		})
+
+		assert.Equal(t, 20, result.Requests)
+		assert.Equal(t, 10, result.Allowed)
+		assert.Equal(t, 1, result.Gateways)
+		assert.Equal(t, 11, result.RateLimit.TriggeredAt)
+		assert.Equal(t, 5.0, result.RateLimit.TriggeredAfter)
+	})
+
+	t.Run("proposed policy", func(t *testing.T) {
+		session := user.NewSessionState()
+		session.OrgID = "default"
+		session.ApplyPolicies = []string{"proposed"}
+
+		result := simulate(t, limitSimulationRequest{
+			APIID:    "simulated",
+			Session:  session,
+			Policies: []user.Policy{{ID: "proposed", OrgID: "default", Rate: 100, Per: 1, QuotaMax: 5, QuotaRenewalRate: 3600, AccessRights: accessRights}},
+			Profile:  limitSimulationProfile{RPS: 1, Duration: 10},
+		})
+
+		assert.Equal(t, 100.0, result.Rate)
+		assert.False(t, result.RateLimit.Triggered)
+		assert.Equal(t, int64(5), result.QuotaRemaining)
+		assert.Equal(t, 6, result.Quota.TriggeredAt)
+	})
+
+	t.Run("quota used by the key", func(t *testing.T) {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.OrgID = "default"
+			s.AccessRights = accessRights
+			s.QuotaMax, s.QuotaRenewalRate = 5, 3600
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/simulated/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusOK},
+			{Path: "/simulated/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusOK},
+		}...)
+
+		result := simulate(t, limitSimulationRequest{APIID: "simulated", Key: key, Profile: limitSimulationProfile{RPS: 1, Duration: 10}})
+
+		assert.Equal(t, int64(3), result.QuotaRemaining)
+		assert.Equal(t, 4, result.Quota.TriggeredAt)
+		assert.Equal(t, 3, result.Allowed)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/simulate", Data: `{"api_id": "unknown", "key": "abc", "profile": {"rps": 1, "duration": 1}}`, Code: http.StatusNotFound},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/simulate", Data: `{"api_id": "simulated", "key": "abc", "profile": {"rps": 0, "duration": 1}}`, Code: http.StatusBadRequest},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/simulate", Data: `{"api_id": "simulated", "key": "abc", "profile": {"rps": 100000, "duration": 3600}}`, Code: http.StatusBadRequest},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/simulate", Data: `{"api_id": "simulated", "key": "unknown", "profile": {"rps": 1, "duration": 1}}`, Code: http.StatusBadRequest},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/simulate", Data: `{"api_id": "simulated", "profile": {"rps": 1, "duration": 1}}`, Code: http.StatusBadRequest},
+		}...)
+	})
+}
@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TykTechnologies/tyk/internal/model"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+// loadDiagnosticsID matches the IDs of the bundles, the first 8 bytes of their hash in hex.
+var loadDiagnosticsID = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+const (
+	defaultMaxLoadDiagnostics = 20
+
+	loadStageSpec       = "spec"
+	loadStageValidation = "validation"
+)
+
+// loadDiagnosticsConfigFields are the configuration fields kept in the bundles, the fields the
+// loading of the APIs depends on. The other fields are dropped, as they may hold secrets.
+var loadDiagnosticsConfigFields = []string{
+	"listen_address",
+	"listen_port",
+	"control_api_hostname",
+	"control_api_port",
+	"allow_insecure_configs",
+	"http_server_options",
+	"version_header",
+	"hash_keys",
+	"hash_key_function",
+	"template_path",
+	"app_path",
+	"use_db_app_configs",
+	"enable_custom_domains",
+	"service_discovery",
+	"proxy_enable_http",
+	"proxy_ssl_insecure_skip_verify",
+	"proxy_ssl_min_version",
+	"proxy_ssl_max_version",
+	"proxy_ssl_ciphers",
+	"proxy_default_timeout",
+	"enable_bundle_downloader",
+	"bundle_base_url",
+	"bundle_insecure_skip_verify",
+	"enable_jsvm",
+	"jsvm_timeout",
+	"disable_virtual_path_blobs",
+	"tyk_js_path",
+	"middleware_path",
+	"ignore_endpoint_case",
+	"disable_regexp_cache",
+	"regexp_cache_expire",
+	"regexp_safe_mode",
+	"enable_analytics",
+	"oas_config",
+	"streaming",
+	"log_level",
+}
+
+// loadDiagnostics is the diagnostics bundle of an API which failed to load, with what's needed
+// to reproduce the failure.
+type loadDiagnostics struct {
+	ID      string `json:"id"`
+	APIID   string `json:"api_id"`
+	APIName string `json:"api_name"`
+	OrgID   string `json:"org_id"`
+	// Stage is the loading stage which failed, `spec` or `validation`.
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+	// Occurrences counts the reloads which failed the same way.
+	Occurrences int       `json:"occurrences"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+
+	Gateway loadDiagnosticsGateway `json:"gateway"`
+	// Definition is the API definition, with its OAS document for OAS APIs.
+	Definition *model.MergedAPI `json:"definition,omitempty"`
+	// Config is the Gateway configuration, limited to the fields the loading of the APIs depends on.
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+type loadDiagnosticsGateway struct {
+	Version  string `json:"version"`
+	NodeID   string `json:"node_id"`
+	Hostname string `json:"hostname"`
+}
+
+// loadDiagnosticsStore keeps the latest diagnostics bundles, the oldest first.
+type loadDiagnosticsStore struct {
+	mu      sync.RWMutex
+	bundles []*loadDiagnostics
+}
+
+// mergedAPI returns the definition of the spec, with its OAS document for OAS APIs.
+func (s *APISpec) mergedAPI() *model.MergedAPI {
+	def := &model.MergedAPI{APIDefinition: s.APIDefinition}
+	if s.IsOAS {
+		def.OAS = &s.OAS
+	}
+	return def
+}
+
+// recordLoadFailure captures the diagnostics bundle of an API which failed to load. Repeated
+// failures of the same definition with the same error update the same bundle.
+func (gw *Gateway) recordLoadFailure(def *model.MergedAPI, stage string, loadErr error) {
+	if def == nil || def.APIDefinition == nil {
+		return
+	}
+
+	definition, err := json.Marshal(def)
+	if err != nil {
+		log.WithError(err).Error("Couldn't capture load failure diagnostics")
+		return
+	}
+
+	sum := sha256.Sum256(append(append(definition, stage...), loadErr.Error()...))
+	id := hex.EncodeToString(sum[:8])
+	now := time.Now()
+
+	store := &gw.loadDiagnostics
+	store.mu.Lock()
+
+	var bundle *loadDiagnostics
+	for i, existing := range store.bundles {
+		if existing.ID == id {
+			bundle = existing
+			store.bundles = append(store.bundles[:i], store.bundles[i+1:]...)
+			break
+		}
+	}
+
+	if bundle == nil {
+		bundle = &loadDiagnostics{
+			ID:         id,
+			APIID:      def.APIID,
+			APIName:    def.Name,
+			OrgID:      def.OrgID,
+			Stage:      stage,
+			Error:      loadErr.Error(),
+			FirstSeen:  now,
+			Gateway:    loadDiagnosticsGateway{Version: VERSION, NodeID: gw.GetNodeID(), Hostname: gw.hostDetails.Hostname},
+			Definition: &model.MergedAPI{},
+			Config:     gw.sanitizedConfig(),
+		}
+		// keep a copy, the definition can change after the failure
+		if err := json.Unmarshal(definition, bundle.Definition); err != nil {
+			bundle.Definition = nil
+		}
+	}
+	bundle.Occurrences++
+	bundle.LastSeen = now
+
+	store.bundles = append(store.bundles, bundle)
+
+	maxBundles := gw.GetConfig().LoadDiagnostics.MaxBundles
+	if maxBundles <= 0 {
+		maxBundles = defaultMaxLoadDiagnostics
+	}
+	if len(store.bundles) > maxBundles {
+		store.bundles = store.bundles[len(store.bundles)-maxBundles:]
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "    ")
+	store.mu.Unlock()
+
+	if dir := gw.GetConfig().LoadDiagnostics.Path; dir != "" && err == nil {
+		if err := gw.writeLoadDiagnostics(dir, id, data, maxBundles); err != nil {
+			log.WithError(err).Error("Couldn't write load failure diagnostics")
+		}
+	}
+
+	log.WithField("api_id", def.APIID).WithField("diagnostics", id).Warning("Captured load failure diagnostics")
+}
+
+// writeLoadDiagnostics writes a bundle to dir, and prunes the oldest bundles of the directory
+// above maxBundles.
+func (gw *Gateway) writeLoadDiagnostics(dir, id string, data []byte, maxBundles int) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that readers never see partial bundles
+	tmp := filepath.Join(dir, "."+id+".json")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, filepath.Join(dir, id+".json")); err != nil {
+		return err
+	}
+
+	return pruneLoadDiagnostics(dir, maxBundles)
+}
+
+// pruneLoadDiagnostics removes the oldest bundles of dir above maxBundles. The files which
+// aren't named after a bundle ID are left alone.
+func pruneLoadDiagnostics(dir string, maxBundles int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	type bundleFile struct {
+		name     string
+		modified time.Time
+	}
+
+	var files []bundleFile
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok || !loadDiagnosticsID.MatchString(id) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, bundleFile{name: entry.Name(), modified: info.ModTime()})
+	}
+
+	if len(files) <= maxBundles {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modified.After(files[j].modified)
+	})
+
+	for _, file := range files[maxBundles:] {
+		if err := os.Remove(filepath.Join(dir, file.name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sanitizedConfig returns the fields of the configuration kept in the bundles.
+func (gw *Gateway) sanitizedConfig() map[string]interface{} {
+	data, err := json.Marshal(gw.GetConfig())
+	if err != nil {
+		return nil
+	}
+
+	var conf map[string]interface{}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil
+	}
+
+	sanitized := make(map[string]interface{}, len(loadDiagnosticsConfigFields))
+	for _, field := range loadDiagnosticsConfigFields {
+		if value, ok := conf[field]; ok {
+			sanitized[field] = value
+		}
+	}
+	return sanitized
+}
+
+// loadFailuresHandler lists the diagnostics bundles of the load failures, the newest first,
+// without their definitions and configurations.
+func (gw *Gateway) loadFailuresHandler(w http.ResponseWriter, _ *http.Request) {
+	gw.loadDiagnostics.mu.RLock()
+	defer gw.loadDiagnostics.mu.RUnlock()
+
+	summaries := make([]loadDiagnostics, 0, len(gw.loadDiagnostics.bundles))
+	for i := len(gw.loadDiagnostics.bundles) - 1; i >= 0; i-- {
+		summary := *gw.loadDiagnostics.bundles[i]
+		summary.Definition = nil
+		summary.Config = nil
+		summaries = append(summaries, summary)
+	}
+
+	doJSONWrite(w, http.StatusOK, summaries)
+}
+
+// loadFailureHandler returns a diagnostics bundle.
+func (gw *Gateway) loadFailureHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	gw.loadDiagnostics.mu.RLock()
+	defer gw.loadDiagnostics.mu.RUnlock()
+
+	for _, bundle := range gw.loadDiagnostics.bundles {
+		if bundle.ID == id {
+			doJSONWrite(w, http.StatusOK, bundle)
+			return
+		}
+	}
+
+	doJSONWrite(w, http.StatusNotFound, apiError("Load failure not found"))
+}
@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestLoadDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.LoadDiagnostics.Path = dir
+		globalConf.CacheStorage.Password = "cache-password"
+		globalConf.AnalyticsStorage.Password = "analytics-password"
+		globalConf.NewRelic.LicenseKey = "license-key"
+	})
+	defer ts.Close()
+
+	apis := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "spaces"
+		spec.Proxy.ListenPath = "/with spaces/"
+	}, func(spec *APISpec) {
+		spec.APIID = "bundle"
+		spec.Proxy.ListenPath = "/bundle/"
+		spec.CustomMiddlewareBundle = "missing-bundle"
+	}, func(spec *APISpec) {
+		spec.APIID = "valid"
+		spec.Proxy.ListenPath = "/valid/"
+	})
+	ts.Gw.LoadAPI(apis...)
+	ts.Gw.LoadAPI(apis...)
+
+	resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/debug/load-failures", AdminAuth: true, Code: http.StatusOK})
+	var failures []loadDiagnostics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&failures))
+	require.Len(t, failures, 2)
+
+	byAPI := map[string]loadDiagnostics{}
+	for _, failure := range failures {
+		assert.Nil(t, failure.Definition)
+		byAPI[failure.APIID] = failure
+	}
+
+	assert.Equal(t, loadStageValidation, byAPI["spaces"].Stage)
+	assert.Equal(t, "Listen path contains spaces, is invalid", byAPI["spaces"].Error)
+	assert.Equal(t, 2, byAPI["spaces"].Occurrences)
+	assert.Equal(t, loadStageSpec, byAPI["bundle"].Stage)
+
+	id := byAPI["spaces"].ID
+	resp, _ = ts.Run(t, test.TestCase{Path: "/tyk/debug/load-failures/" + id, AdminAuth: true, Code: http.StatusOK})
+	var bundle loadDiagnostics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&bundle))
+	require.NotNil(t, bundle.Definition)
+	assert.Equal(t, "/with spaces/", bundle.Definition.Proxy.ListenPath)
+	assert.Contains(t, bundle.Config, "listen_port")
+	for _, field := range []string{"secret", "node_secret", "storage", "cache_storage", "analytics_storage", "kv", "secrets", "security", "newrelic", "analytics_config"} {
+		assert.NotContains(t, bundle.Config, field)
+	}
+
+	path := filepath.Join(dir, id+".json")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"occurrences": 2`)
+	assert.NotContains(t, string(data), "-password")
+	assert.NotContains(t, string(data), "license-key")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/tyk/debug/load-failures/unknown", AdminAuth: true, Code: http.StatusNotFound})
+}
+
+func TestLoadDiagnostics_syncAPISpecs(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.LoadDiagnostics.Path = t.TempDir()
+	})
+	defer ts.Close()
+
+	spec := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "no-port"
+		spec.Proxy.ListenPath = "/no-port/"
+		spec.Protocol = "tcp"
+	})[0]
+
+	data, err := json.Marshal(spec.APIDefinition)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(ts.Gw.GetConfig().AppPath, "no-port.json"), data, 0o644))
+
+	_, err = ts.Gw.syncAPISpecs()
+	require.NoError(t, err)
+
+	resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/debug/load-failures", AdminAuth: true, Code: http.StatusOK})
+	var failures []loadDiagnostics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&failures))
+	require.Len(t, failures, 1)
+	assert.Equal(t, "no-port", failures[0].APIID)
+	assert.Equal(t, loadStageValidation, failures[0].Stage)
+	assert.Equal(t, "missing listening port", failures[0].Error)
+}
+
+func TestPruneLoadDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+
+	now := time.Now()
+	ids := []string{"0000000000000001", "0000000000000002", "0000000000000003"}
+	for i, id := range ids {
+		path := filepath.Join(dir, id+".json")
+		require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o600))
+		modified := now.Add(time.Duration(i-len(ids)) * time.Minute)
+		require.NoError(t, os.Chtimes(path, modified, modified))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0o600))
+
+	require.NoError(t, pruneLoadDiagnostics(dir, 2))
+
+	assert.NoFileExists(t, filepath.Join(dir, ids[0]+".json"), "the oldest bundle should be pruned")
+	assert.FileExists(t, filepath.Join(dir, ids[1]+".json"))
+	assert.FileExists(t, filepath.Join(dir, ids[2]+".json"))
+	assert.FileExists(t, filepath.Join(dir, "other.json"), "the other files should be left alone")
+}
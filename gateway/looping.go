@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+// namedInternalEndpointPrefix marks a looping path addressing an internal
+// endpoint by name, e.g. `tyk://self/@user-lookup?id=1`.
+const namedInternalEndpointPrefix = "/@"
+
+// loopQueryParams are consumed by the looping machinery itself and are
+// never treated as internal endpoint parameters.
+var loopQueryParams = map[string]struct{}{
+	"method":       {},
+	"loop_limit":   {},
+	"check_limits": {},
+}
+
+var errInternalEndpointNotFound = errors.New("internal endpoint not found")
+
+// namedInternalEndpoint is an internal endpoint addressable by name, with its
+// parameter contract compiled.
+type namedInternalEndpoint struct {
+	apidef.InternalMeta
+	patterns map[string]*regexp.Regexp
+}
+
+// compileNamedInternalEndpoints indexes the named internal endpoints across
+// all versions of the API definition.
+func compileNamedInternalEndpoints(def *apidef.APIDefinition, logger *logrus.Entry) map[string]*namedInternalEndpoint {
+	endpoints := make(map[string]*namedInternalEndpoint)
+
+	for _, version := range def.VersionData.Versions {
+		for _, meta := range version.ExtendedPaths.Internal {
+			if meta.Disabled || meta.Name == "" {
+				continue
+			}
+
+			if _, ok := endpoints[meta.Name]; ok {
+				logger.WithField("name", meta.Name).Warning("Duplicate internal endpoint name, keeping the first one")
+				continue
+			}
+
+			endpoint, err := newNamedInternalEndpoint(meta)
+			if err != nil {
+				logger.WithError(err).WithField("name", meta.Name).Error("Couldn't compile internal endpoint contract")
+				continue
+			}
+
+			endpoints[meta.Name] = endpoint
+		}
+	}
+
+	return endpoints
+}
+
+func newNamedInternalEndpoint(meta apidef.InternalMeta) (*namedInternalEndpoint, error) {
+	endpoint := &namedInternalEndpoint{
+		InternalMeta: meta,
+		patterns:     make(map[string]*regexp.Regexp),
+	}
+
+	for _, p := range meta.Parameters {
+		if p.Pattern == "" {
+			continue
+		}
+
+		rx, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", p.Name, err)
+		}
+		endpoint.patterns[p.Name] = rx
+	}
+
+	return endpoint, nil
+}
+
+// resolve validates the looping query against the endpoint contract and
+// returns the endpoint path with placeholders filled, together with the
+// parameters which should be passed on as query values.
+func (e *namedInternalEndpoint) resolve(query url.Values) (string, url.Values, error) {
+	declared := make(map[string]struct{}, len(e.Parameters))
+	for _, p := range e.Parameters {
+		declared[p.Name] = struct{}{}
+
+		value := query.Get(p.Name)
+		if value == "" {
+			if p.Required {
+				return "", nil, fmt.Errorf("missing required parameter %q", p.Name)
+			}
+			continue
+		}
+
+		if rx, ok := e.patterns[p.Name]; ok && !rx.MatchString(value) {
+			return "", nil, fmt.Errorf("parameter %q does not match %q", p.Name, p.Pattern)
+		}
+	}
+
+	path := e.Path
+	rest := url.Values{}
+	for name, values := range query {
+		if _, ok := loopQueryParams[name]; ok {
+			continue
+		}
+
+		if _, ok := declared[name]; !ok && len(e.Parameters) > 0 {
+			return "", nil, fmt.Errorf("unexpected parameter %q", name)
+		}
+
+		placeholder := "{" + name + "}"
+		if strings.Contains(path, placeholder) && len(values) > 0 {
+			path = strings.ReplaceAll(path, placeholder, url.PathEscape(values[0]))
+			continue
+		}
+
+		rest[name] = values
+	}
+
+	return path, rest, nil
+}
+
+// namedInternalEndpointName returns the endpoint name of a looping URL
+// addressing an internal endpoint by name.
+func namedInternalEndpointName(u *url.URL) (string, bool) {
+	if !strings.HasPrefix(u.Path, namedInternalEndpointPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(u.Path, namedInternalEndpointPrefix), true
+}
+
+// rewriteToNamedInternalEndpoint points a looping request at the path and
+// method of the named internal endpoint of the target API. The parameters
+// which are not part of the path are returned so they can be merged into the
+// query after the original query string has been restored.
+func rewriteToNamedInternalEndpoint(r *http.Request, target *APISpec, name string) (url.Values, error) {
+	endpoint, ok := target.namedInternalEndpoints[name]
+	if !ok {
+		return nil, errInternalEndpointNotFound
+	}
+
+	path, params, err := endpoint.resolve(r.URL.Query())
+	if err != nil {
+		return nil, fmt.Errorf("internal endpoint %q: %w", name, err)
+	}
+
+	r.URL.Path = path
+	r.URL.RawPath = ""
+	if endpoint.Method != "" {
+		r.Method = endpoint.Method
+	}
+	ctxSetInternalEndpointName(r, name)
+
+	return params, nil
+}
+
+func ctxSetInternalEndpointName(r *http.Request, name string) {
+	setCtxValue(r, ctx.LoopInternalEndpoint, name)
+}
+
+func ctxGetInternalEndpointName(r *http.Request) string {
+	if v, ok := r.Context().Value(ctx.LoopInternalEndpoint).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// tagLooping adds the loop depth and the addressed internal endpoint to
+// the analytics tags of looped requests.
+func tagLooping(r *http.Request, tags []string) []string {
+	level := ctxLoopLevel(r)
+	if level == 0 {
+		return tags
+	}
+
+	tags = append(tags, "loop-level-"+strconv.Itoa(level))
+	if name := ctxGetInternalEndpointName(r); name != "" {
+		tags = append(tags, "internal-endpoint-"+name)
+	}
+
+	return tags
+}
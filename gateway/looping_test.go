@@ -6,6 +6,7 @@ package gateway
 
 import (
 	"encoding/json"
+	"net/http"
 	"sync"
 	"testing"
 
@@ -184,6 +185,76 @@ func TestLooping(t *testing.T) {
 		}...)
 	})
 
+	t.Run("Loop to named internal endpoint", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "users"
+			spec.Name = "users api"
+			spec.Proxy.ListenPath = "/users-api"
+			version := spec.VersionData.Versions["v1"]
+			json.Unmarshal([]byte(`{
+                "use_extended_paths": true,
+                "extended_paths": {
+                    "internal": [{
+                        "path": "/users/{id}",
+                        "method": "GET",
+                        "name": "user-lookup",
+                        "parameters": [
+                            {"name": "id", "required": true, "pattern": "^[0-9]+$"},
+                            {"name": "fields"}
+                        ]
+                    }]
+                }
+            }`), &version)
+			spec.VersionData.Versions["v1"] = version
+		}, func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/test"
+
+			version := spec.VersionData.Versions["v1"]
+			json.Unmarshal([]byte(`{
+                "use_extended_paths": true,
+                "extended_paths": {
+                    "url_rewrites": [{
+                        "path": "/lookup",
+                        "match_pattern": "/lookup",
+                        "method": "POST",
+                        "rewrite_to": "tyk://users/@user-lookup?id=42&fields=name"
+                    },{
+                        "path": "/missing",
+                        "match_pattern": "/missing",
+                        "method": "GET",
+                        "rewrite_to": "tyk://users/@user-lookup?fields=name"
+                    },{
+                        "path": "/mismatch",
+                        "match_pattern": "/mismatch",
+                        "method": "GET",
+                        "rewrite_to": "tyk://users/@user-lookup?id=abc"
+                    },{
+                        "path": "/unexpected",
+                        "match_pattern": "/unexpected",
+                        "method": "GET",
+                        "rewrite_to": "tyk://users/@user-lookup?id=1&other=1"
+                    },{
+                        "path": "/unknown",
+                        "match_pattern": "/unknown",
+                        "method": "GET",
+                        "rewrite_to": "tyk://users/@unknown"
+                    }]
+                }
+            }`), &version)
+			spec.VersionData.Versions["v1"] = version
+		})
+
+		ts.Run(t, []test.TestCase{
+			{Path: "/users-api/users/42", Code: http.StatusForbidden},
+			{Method: "POST", Path: "/test/lookup", Code: 200, BodyMatch: `"Url":"/users/42\?fields=name"`},
+			{Method: "POST", Path: "/test/lookup", Code: 200, BodyMatch: `"Method":"GET"`},
+			{Path: "/test/missing", Code: http.StatusBadRequest, BodyMatch: `missing required parameter`},
+			{Path: "/test/mismatch", Code: http.StatusBadRequest, BodyMatch: `does not match`},
+			{Path: "/test/unexpected", Code: http.StatusBadRequest, BodyMatch: `unexpected parameter`},
+			{Path: "/test/unknown", Code: http.StatusInternalServerError, BodyMatch: `internal endpoint not found`},
+		}...)
+	})
+
 	t.Run("VirtualEndpoint or plugins", func(t *testing.T) {
 		test.Flaky(t) // TT-10511
 
@@ -167,7 +167,18 @@ func (gw *Gateway) createMiddleware(actualMW TykMiddleware) func(http.Handler) h
 				return
 			}
 
+			if spec.skipConsumerScoped(mw.Name(), ctxGetSession(r)) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
 			err, errCode := mw.ProcessRequest(w, r, mwConf)
+			if recorder := ctxGetTraceRecorder(r); recorder != nil {
+				recorder.middleware(mw.Name(), startTime, err, errCode)
+			}
+			if diagnostics := ctxGetDebugDiagnostics(r); diagnostics != nil {
+				diagnostics.middleware(mw.Name(), time.Since(startTime))
+			}
 
 			if err != nil {
 				writeResponse := true
@@ -443,7 +454,7 @@ func (t *BaseMiddleware) CheckSessionAndIdentityForValidKey(originalKey string,
 		// If exists, assume it has been authorized and pass on
 		// cache it
 		if !t.Spec.GlobalConfig.LocalSessionCache.DisableCacheSessionState {
-			t.Gw.SessionCache.Set(cacheKey, session, cache.DefaultExpiration)
+			t.Gw.SessionCache.Set(cacheKey, session.Clone(), cache.DefaultExpiration)
 		}
 
 		// Check for a policy, if there is a policy, pull it and overwrite the session values
@@ -473,7 +484,7 @@ func (t *BaseMiddleware) CheckSessionAndIdentityForValidKey(originalKey string,
 
 		// cache it
 		if !t.Spec.GlobalConfig.LocalSessionCache.DisableCacheSessionState {
-			go t.Gw.SessionCache.Set(cacheKey, session, cache.DefaultExpiration)
+			go t.Gw.SessionCache.Set(cacheKey, session.Clone(), cache.DefaultExpiration)
 		}
 
 		// Check for a policy, if there is a policy, pull it and overwrite the session values
@@ -489,6 +500,22 @@ func (t *BaseMiddleware) CheckSessionAndIdentityForValidKey(originalKey string,
 		return session, found
 	}
 
+	// 3. If unknown to Tyk, look it up in the external identity store
+	if session, found := t.lookupExternalSession(key); found {
+		if t.Spec.GlobalConfig.HashKeys {
+			keyHash = storage.HashStr(key)
+		}
+		session := session.Clone()
+		session.SetKeyHash(keyHash)
+
+		if err := t.ApplyPolicies(&session); err != nil {
+			t.Logger().Error(err)
+			return session, false
+		}
+
+		return session, true
+	}
+
 	// session not found
 	session.KeyID = key
 	return session, false
@@ -496,7 +523,7 @@ func (t *BaseMiddleware) CheckSessionAndIdentityForValidKey(originalKey string,
 
 // FireEvent is added to the BaseMiddleware object so it is available across the entire stack
 func (t *BaseMiddleware) FireEvent(name apidef.TykEvent, meta interface{}) {
-	fireEvent(name, meta, t.Spec.EventPaths)
+	fireEvent(name, meta, t.Spec.EventPaths, t.Spec.Ownership)
 }
 
 // emitRateLimitEvents emits rate limit related events based on the request context.
@@ -648,6 +675,9 @@ func handleResponseChain(chain []TykResponseHandler, rw http.ResponseWriter, res
 
 	traceIsEnabled := trace.IsEnabled()
 	for _, rh := range chain {
+		if base := rh.Base(); base != nil && base.Spec != nil && base.Spec.skipConsumerScoped(rh.Name(), ses) {
+			continue
+		}
 		if err := handleResponse(rh, rw, res, req, ses, traceIsEnabled); err != nil {
 			// Abort the request if this handler is a response middleware hook:
 			if rh.Name() == "CustomMiddlewareResponseHook" {
@@ -660,6 +690,17 @@ func handleResponseChain(chain []TykResponseHandler, rw http.ResponseWriter, res
 	return false, nil
 }
 
+// skipConsumerScoped returns true when the middleware is consumer scoped and the session
+// doesn't enable it.
+func (a *APISpec) skipConsumerScoped(name string, session *user.SessionState) bool {
+	for _, scoped := range a.ConsumerScopedMiddleware {
+		if scoped == name {
+			return session == nil || !session.MiddlewareEnabled(name)
+		}
+	}
+	return false
+}
+
 func handleResponse(rh TykResponseHandler, rw http.ResponseWriter, res *http.Response, req *http.Request, ses *user.SessionState, shouldTrace bool) error {
 	if shouldTrace {
 		span, ctx := trace.Span(req.Context(), rh.Name())
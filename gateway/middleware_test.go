@@ -6,8 +6,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/TykTechnologies/tyk/apidef"
 	headers2 "github.com/TykTechnologies/tyk/header"
@@ -348,6 +350,79 @@ func TestSessionLimiter_RedisQuotaExceeded_PerAPI(t *testing.T) {
 	})
 }
 
+func TestSessionLimiter_RedisQuotaExceeded_Aligned(t *testing.T) {
+	g := StartTest(nil)
+	defer g.Close()
+
+	api := g.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = uuid.New()
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = fmt.Sprintf("/%s/", spec.APIID)
+	})[0]
+
+	alignment := &user.QuotaAlignment{Period: user.QuotaPeriodMonth, Timezone: "Europe/Paris", Prorate: true}
+
+	_, key := g.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+		s.QuotaMax = 1000
+		s.QuotaRenewalRate = 60
+		s.QuotaAlignment = alignment
+	})
+
+	_, _ = g.Run(t, test.TestCase{Path: api.Proxy.ListenPath, Headers: map[string]string{headers2.Authorization: key}, Code: http.StatusOK})
+
+	session, found := g.Gw.GlobalSessionManager.SessionDetail("", key, false)
+	require.True(t, found)
+
+	// the quota renews on the first of the next month, and is prorated until then
+	now := time.Now()
+	_, end := alignment.Bounds(now)
+	assert.Equal(t, end.Unix(), session.QuotaRenews)
+	assert.Equal(t, alignment.Quota(1000, session.DateCreated, now)-1, session.QuotaRemaining)
+}
+
+func TestSessionLimiter_QuotaBucketsExceeded(t *testing.T) {
+	g := StartTest(nil)
+	defer g.Close()
+
+	api := g.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = uuid.New()
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = fmt.Sprintf("/%s/", spec.APIID)
+	})[0]
+
+	_, key := g.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+		s.QuotaMax = -1
+		s.QuotaBuckets = []user.QuotaBucket{
+			{Name: "reads", Methods: []string{http.MethodGet}, QuotaMax: 2, QuotaRenewalRate: 60},
+			{Name: "orders", Methods: []string{http.MethodPost}, Path: "/orders", QuotaMax: 1, QuotaRenewalRate: 60},
+		}
+	})
+
+	headers := map[string]string{headers2.Authorization: key}
+	path := api.Proxy.ListenPath
+
+	_, _ = g.Run(t, []test.TestCase{
+		{Path: path + "pets", Headers: headers, Code: http.StatusOK},
+		{Path: path + "pets", Headers: headers, Code: http.StatusOK},
+		{Path: path + "pets", Headers: headers, Code: http.StatusForbidden},
+		{Method: http.MethodPost, Path: path + "orders", Headers: headers, Code: http.StatusOK},
+		{Method: http.MethodPost, Path: path + "orders", Headers: headers, Code: http.StatusForbidden},
+		// requests not matching any bucket are only counted by the key quota
+		{Method: http.MethodPost, Path: path + "pets", Headers: headers, Code: http.StatusOK},
+		{Method: http.MethodPost, Path: path + "pets", Headers: headers, Code: http.StatusOK},
+	}...)
+
+	session, found := g.Gw.GlobalSessionManager.SessionDetail("", key, false)
+	require.True(t, found)
+	assert.Equal(t, int64(0), session.QuotaBuckets[0].QuotaRemaining)
+	assert.NotZero(t, session.QuotaBuckets[0].QuotaRenews)
+
+	g.Gw.GlobalSessionManager.ResetQuota(key, &session, false)
+	_, _ = g.Run(t, test.TestCase{Path: path + "pets", Headers: headers, Code: http.StatusOK})
+}
+
 func TestCopyAllowedURLs(t *testing.T) {
 	testCases := []struct {
 		name  string
@@ -464,3 +539,47 @@ func TestQuotaNotAppliedWithURLRewrite(t *testing.T) {
 		},
 	}...)
 }
+
+func TestConsumerScopedMiddleware(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	api := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "scoped"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/scoped/"
+		spec.ConsumerScopedMiddleware = []string{"TransformHeaders", "HeaderInjector"}
+	})[0]
+	UpdateAPIVersion(api, "v1", func(v *apidef.VersionInfo) {
+		v.GlobalHeaders = map[string]string{"X-Pilot": "request"}
+		v.GlobalResponseHeaders = map[string]string{"X-Pilot-Response": "response"}
+	})
+	ts.Gw.LoadAPI(api)
+
+	accessRights := map[string]user.AccessDefinition{"scoped": {APIID: "scoped", Versions: []string{"v1"}}}
+
+	_, regularKey := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = accessRights
+	})
+	_, pilotKey := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = accessRights
+		s.MiddlewareFlags = map[string]bool{"TransformHeaders": true, "HeaderInjector": true}
+	})
+
+	pID := ts.CreatePolicy(func(p *user.Policy) {
+		p.AccessRights = accessRights
+		p.MiddlewareFlags = map[string]bool{"TransformHeaders": true}
+	})
+	_, policyKey := ts.CreateSession(func(s *user.SessionState) {
+		s.ApplyPolicies = []string{pID}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/scoped/", Headers: map[string]string{"Authorization": regularKey}, Code: http.StatusOK,
+			BodyNotMatch: "X-Pilot", HeadersNotMatch: map[string]string{"X-Pilot-Response": "response"}},
+		{Path: "/scoped/", Headers: map[string]string{"Authorization": pilotKey}, Code: http.StatusOK,
+			BodyMatch: `"X-Pilot":"request"`, HeadersMatch: map[string]string{"X-Pilot-Response": "response"}},
+		{Path: "/scoped/", Headers: map[string]string{"Authorization": policyKey}, Code: http.StatusOK,
+			BodyMatch: `"X-Pilot":"request"`, HeadersNotMatch: map[string]string{"X-Pilot-Response": "response"}},
+	}...)
+}
@@ -104,6 +104,11 @@ func (u *URLSpec) matchesMethod(method string) bool {
 // then it will match against the full URL including the listen path as provided.
 // APISpec to provide URL sanitization of the input is passed along.
 func (a *URLSpec) matchesPath(reqPath string, api *APISpec) bool {
+	if a.spec == nil {
+		// the path pattern failed to compile
+		return false
+	}
+
 	clean := api.StripListenPath(reqPath)
 	noVersion := api.StripVersionPath(clean)
 	// match /users
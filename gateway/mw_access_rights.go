@@ -36,6 +36,11 @@ func (a *AccessRightsCheck) ProcessRequest(w http.ResponseWriter, r *http.Reques
 		return errors.New("Access to this API has been disallowed"), http.StatusForbidden
 	}
 
+	if err, code := checkResourceScopes(a.Spec, w, versionList); err != nil {
+		a.Logger().WithError(err).Info("Attempted access to API without the required scopes")
+		return err, code
+	}
+
 	if a.Spec.VersionData.NotVersioned {
 		return nil, http.StatusOK
 	}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/httputil"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+// blockedMethods are rejected unless an allow list explicitly contains them.
+var blockedMethods = []string{http.MethodTrace, "TRACK", http.MethodConnect}
+
+// defaultAllowedMethods is advertised in the Allow header when the API has no allow list.
+var defaultAllowedMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// methodSet is a compiled method allow list.
+type methodSet struct {
+	methods map[string]struct{}
+	// allow is the value of the Allow header sent with 405 responses.
+	allow string
+}
+
+func newMethodSet(methods []string) *methodSet {
+	set := &methodSet{methods: make(map[string]struct{}, len(methods))}
+
+	list := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if _, ok := set.methods[method]; ok || method == "" {
+			continue
+		}
+
+		set.methods[method] = struct{}{}
+		list = append(list, method)
+	}
+
+	sort.Strings(list)
+	set.allow = strings.Join(list, ", ")
+
+	return set
+}
+
+func (m *methodSet) allows(method string) bool {
+	_, ok := m.methods[method]
+	return ok
+}
+
+type endpointMethodSet struct {
+	*methodSet
+	path URLSpec
+}
+
+// allowedMethodsSpec is the compiled form of apidef.AllowedMethods.
+type allowedMethodsSpec struct {
+	// api is nil when the API has no allow list, in which case only the blocked methods are rejected.
+	api       *methodSet
+	endpoints []endpointMethodSet
+}
+
+func compileAllowedMethods(conf apidef.AllowedMethods, gwConf config.Config, logger *logrus.Entry) *allowedMethodsSpec {
+	spec := &allowedMethodsSpec{}
+
+	if len(conf.Methods) > 0 {
+		spec.api = newMethodSet(conf.Methods)
+	}
+
+	for _, endpoint := range conf.Endpoints {
+		pattern := httputil.PreparePathRegexp(endpoint.Path, gwConf.HttpServerOptions.EnablePathPrefixMatching, gwConf.HttpServerOptions.EnablePathSuffixMatching)
+		if gwConf.IgnoreEndpointCase {
+			pattern = "(?i)" + pattern
+		}
+
+		rx, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.WithError(err).WithField("path", endpoint.Path).Error("Couldn't compile allowed methods path")
+			continue
+		}
+
+		spec.endpoints = append(spec.endpoints, endpointMethodSet{
+			methodSet: newMethodSet(endpoint.Methods),
+			path:      URLSpec{spec: rx},
+		})
+	}
+
+	return spec
+}
+
+// lookup returns the allow list applying to the request, or nil if none applies.
+func (s *allowedMethodsSpec) lookup(r *http.Request, api *APISpec) *methodSet {
+	if s == nil {
+		return nil
+	}
+
+	for _, endpoint := range s.endpoints {
+		if endpoint.path.matchesPath(r.URL.Path, api) {
+			return endpoint.methodSet
+		}
+	}
+
+	return s.api
+}
+
+// AllowedMethodsMiddleware rejects requests using methods the API doesn't accept.
+type AllowedMethodsMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *AllowedMethodsMiddleware) Name() string {
+	return "AllowedMethodsMiddleware"
+}
+
+// EnabledForSpec is always true, as the blocked methods are rejected for every API.
+func (m *AllowedMethodsMiddleware) EnabledForSpec() bool {
+	return true
+}
+
+func (m *AllowedMethodsMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	allowed := m.Spec.allowedMethods.lookup(r, m.Spec)
+	if allowed != nil {
+		if allowed.allows(r.Method) {
+			return nil, http.StatusOK
+		}
+
+		w.Header().Set("Allow", allowed.allow)
+		return errors.New(http.StatusText(http.StatusMethodNotAllowed)), http.StatusMethodNotAllowed
+	}
+
+	for _, method := range blockedMethods {
+		if r.Method == method {
+			w.Header().Set("Allow", strings.Join(defaultAllowedMethods, ", "))
+			return errors.New(http.StatusText(http.StatusMethodNotAllowed)), http.StatusMethodNotAllowed
+		}
+	}
+
+	return nil, http.StatusOK
+}
@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestAllowedMethodsMiddleware(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	t.Run("blocked by default", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodGet, Path: "/", Code: http.StatusOK},
+			{Method: http.MethodDelete, Path: "/", Code: http.StatusOK},
+			{Method: http.MethodTrace, Path: "/", Code: http.StatusMethodNotAllowed,
+				HeadersMatch: map[string]string{"Allow": "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"}},
+			{Method: "TRACK", Path: "/", Code: http.StatusMethodNotAllowed},
+		}...)
+	})
+
+	t.Run("API and endpoint allow lists", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/api/"
+			spec.AllowedMethods = apidef.AllowedMethods{
+				Methods: []string{"get", "POST", "TRACE"},
+				Endpoints: []apidef.EndpointMethods{
+					{Path: "/users/{id}", Methods: []string{http.MethodDelete, http.MethodGet}},
+				},
+			}
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodGet, Path: "/api/items", Code: http.StatusOK},
+			{Method: http.MethodTrace, Path: "/api/items", Code: http.StatusOK},
+			{Method: http.MethodPut, Path: "/api/items", Code: http.StatusMethodNotAllowed,
+				HeadersMatch: map[string]string{"Allow": "GET, POST, TRACE"}},
+			{Method: http.MethodDelete, Path: "/api/users/1", Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/api/users/1", Code: http.StatusMethodNotAllowed,
+				HeadersMatch: map[string]string{"Allow": "DELETE, GET"}},
+		}...)
+	})
+}
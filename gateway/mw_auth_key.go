@@ -266,6 +266,16 @@ func stripBearer(token string) string {
 
 // TODO: move this method to base middleware?
 func AuthFailed(m TykMiddleware, r *http.Request, token string) {
+	fireAuthFailure(m, r, token)
+
+	if m.Base().Spec.BruteForceProtection.Enabled {
+		m.Base().recordAuthFailure(r)
+	}
+}
+
+// fireAuthFailure fires the AuthFailure event. Unlike AuthFailed, it doesn't count the failure
+// towards the brute force protection, for the requests rejected without checking a credential.
+func fireAuthFailure(m TykMiddleware, r *http.Request, token string) {
 	m.Base().FireEvent(EventAuthFailure, EventKeyFailureMeta{
 		EventMetaDefault: EventMetaDefault{Message: "Auth Failure", OriginatingRequest: EncodeRequestToEvent(r)},
 		Path:             r.URL.Path,
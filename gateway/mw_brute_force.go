@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	bruteForcePrefix = "brute-force-"
+
+	defaultBruteForceMaxAttempts    = 5
+	defaultBruteForceWindow         = 60
+	defaultBruteForceBanDuration    = 60
+	defaultBruteForceMaxBanDuration = 3600
+)
+
+var errBruteForceBanned = errors.New("Too many authentication failures, try again later")
+
+// BruteForceProtectionMiddleware rejects requests of clients banned after repeated
+// authentication failures, by source IP or by presented credential.
+type BruteForceProtectionMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *BruteForceProtectionMiddleware) Name() string {
+	return "BruteForceProtectionMiddleware"
+}
+
+func (m *BruteForceProtectionMiddleware) EnabledForSpec() bool {
+	return m.Spec.BruteForceProtection.Enabled && !m.Spec.UseKeylessAccess
+}
+
+func (m *BruteForceProtectionMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	store := m.Gw.bruteForceStore()
+
+	for _, subject := range m.bruteForceSubjects(r) {
+		ttl, err := store.GetKeyTTL("ban-" + subject.id)
+		if err != nil || ttl <= 0 {
+			continue
+		}
+
+		w.Header().Set(header.RetryAfter, strconv.FormatInt(ttl, 10))
+		return errBruteForceBanned, http.StatusTooManyRequests
+	}
+
+	return nil, http.StatusOK
+}
+
+// presentedCredential returns the first credential found in the request for the auth
+// types of the API, without validating it.
+func (t *BaseMiddleware) presentedCredential(r *http.Request) string {
+	authTypes := make([]string, 0, len(t.Spec.AuthConfigs)+1)
+	for authType := range t.Spec.AuthConfigs {
+		authTypes = append(authTypes, authType)
+	}
+	sort.Strings(authTypes)
+	authTypes = append(authTypes, apidef.AuthTokenType)
+
+	for _, authType := range authTypes {
+		if credential, _ := t.getAuthToken(authType, r); credential != "" {
+			return credential
+		}
+	}
+
+	return ""
+}
+
+// bruteForceSubject is a source IP or a credential failures are counted against.
+type bruteForceSubject struct {
+	id      string
+	keyHash string
+}
+
+// bruteForceSubjects returns the subjects failures of a request are counted against.
+func (t *BaseMiddleware) bruteForceSubjects(r *http.Request) []bruteForceSubject {
+	var subjects []bruteForceSubject
+	if !t.Spec.BruteForceProtection.DisableIPTracking {
+		subjects = append(subjects, bruteForceSubject{id: t.Spec.APIID + "-ip-" + request.RealIP(r)})
+	}
+	if credential := t.presentedCredential(r); credential != "" {
+		keyHash := storage.HashStr(credential)
+		subjects = append(subjects, bruteForceSubject{id: t.Spec.APIID + "-key-" + keyHash, keyHash: keyHash})
+	}
+
+	return subjects
+}
+
+// recordAuthFailure counts an authentication failure of a request, and bans its source IP
+// or credential when it reaches the maximum number of attempts.
+func (t *BaseMiddleware) recordAuthFailure(r *http.Request) {
+	conf := t.Spec.BruteForceProtection
+	store := t.Gw.bruteForceStore()
+
+	maxAttempts := conf.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultBruteForceMaxAttempts
+	}
+	window := conf.Window
+	if window <= 0 {
+		window = defaultBruteForceWindow
+	}
+	memory := 2 * bruteForceMaxBanDuration(conf)
+
+	for _, subject := range t.bruteForceSubjects(r) {
+		if store.IncrememntWithExpire(bruteForcePrefix+"failures-"+subject.id, window) < maxAttempts {
+			continue
+		}
+		store.DeleteKey("failures-" + subject.id)
+
+		lockouts := store.IncrememntWithExpire(bruteForcePrefix+"lockouts-"+subject.id, memory)
+		_ = store.SetExp("lockouts-"+subject.id, memory)
+
+		ban := bruteForceBanDuration(conf, lockouts)
+		if err := store.SetKey("ban-"+subject.id, "1", ban); err != nil {
+			t.Logger().WithError(err).Error("Couldn't store brute force ban")
+			continue
+		}
+
+		origin := request.RealIP(r)
+		t.Logger().WithField("origin", origin).WithField("ban_duration", ban).Warning("Brute force lockout")
+
+		t.FireEvent(EventBruteForceLockout, EventBruteForceLockoutMeta{
+			EventMetaDefault: EventMetaDefault{Message: "Client banned after repeated authentication failures", OriginatingRequest: EncodeRequestToEvent(r)},
+			APIID:            t.Spec.APIID,
+			Origin:           origin,
+			Key:              subject.keyHash,
+			BanDuration:      ban,
+		})
+	}
+}
+
+// bruteForceBanDuration returns the duration of the nth lockout, in seconds.
+func bruteForceBanDuration(conf apidef.BruteForceProtection, lockouts int64) int64 {
+	ban := conf.BanDuration
+	if ban <= 0 {
+		ban = defaultBruteForceBanDuration
+	}
+	maxBan := bruteForceMaxBanDuration(conf)
+
+	for i := int64(1); i < lockouts && ban < maxBan; i++ {
+		ban *= 2
+	}
+	if ban > maxBan {
+		ban = maxBan
+	}
+
+	return ban
+}
+
+func bruteForceMaxBanDuration(conf apidef.BruteForceProtection) int64 {
+	if conf.MaxBanDuration > 0 {
+		return conf.MaxBanDuration
+	}
+
+	return defaultBruteForceMaxBanDuration
+}
+
+func (gw *Gateway) bruteForceStore() *storage.RedisCluster {
+	return &storage.RedisCluster{KeyPrefix: bruteForcePrefix, ConnectionHandler: gw.StorageConnectionHandler}
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestBruteForceProtection(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	lockouts := make(chan EventBruteForceLockoutMeta, 10)
+
+	load := func(disableIPTracking bool) *APISpec {
+		spec := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.UseKeylessAccess = false
+			spec.Proxy.ListenPath = "/"
+			spec.BruteForceProtection = apidef.BruteForceProtection{
+				Enabled:           true,
+				MaxAttempts:       2,
+				BanDuration:       30,
+				DisableIPTracking: disableIPTracking,
+			}
+		})[0]
+		spec.EventPaths = map[apidef.TykEvent][]config.TykEventHandler{
+			EventBruteForceLockout: {&testEventHandler{func(em config.EventMessage) {
+				lockouts <- em.Meta.(EventBruteForceLockoutMeta)
+			}}},
+		}
+
+		return spec
+	}
+
+	t.Run("ban by IP", func(t *testing.T) {
+		api := load(false)
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusOK},
+			{Path: "/", Headers: map[string]string{header.Authorization: "wrong-1"}, Code: http.StatusForbidden},
+			{Path: "/", Headers: map[string]string{header.Authorization: "wrong-2"}, Code: http.StatusForbidden},
+			{Path: "/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusTooManyRequests,
+				HeadersMatch: map[string]string{header.RetryAfter: "30"}},
+		}...)
+
+		meta := <-lockouts
+		assert.Equal(t, api.APIID, meta.APIID)
+		assert.Empty(t, meta.Key)
+		assert.Equal(t, int64(30), meta.BanDuration)
+	})
+
+	t.Run("ban by credential", func(t *testing.T) {
+		api := load(true)
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: map[string]string{header.Authorization: "stuffed"}, Code: http.StatusForbidden},
+			{Path: "/", Headers: map[string]string{header.Authorization: "stuffed"}, Code: http.StatusForbidden},
+			{Path: "/", Headers: map[string]string{header.Authorization: "stuffed"}, Code: http.StatusTooManyRequests},
+			{Path: "/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusOK},
+		}...)
+
+		meta := <-lockouts
+		assert.NotEmpty(t, meta.Key)
+	})
+
+	t.Run("IP list rejections aren't counted", func(t *testing.T) {
+		api := load(false)
+		api.EnableIpBlacklisting = true
+		api.BlacklistedIPs = []string{"10.0.0.9"}
+		ts.Gw.LoadAPI(api)
+
+		blacklisted := map[string]string{header.XRealIP: "10.0.0.9", header.Authorization: "wrong"}
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: blacklisted, Code: http.StatusForbidden},
+			{Path: "/", Headers: blacklisted, Code: http.StatusForbidden},
+			{Path: "/", Headers: blacklisted, Code: http.StatusForbidden},
+		}...)
+
+		_, err := ts.Gw.bruteForceStore().GetRawKey(bruteForcePrefix + "failures-" + api.APIID + "-ip-10.0.0.9")
+		assert.Error(t, err, "the blacklisted requests shouldn't count as authentication failures")
+	})
+}
+
+func TestBruteForceBanDuration(t *testing.T) {
+	conf := apidef.BruteForceProtection{BanDuration: 60, MaxBanDuration: 600}
+
+	assert.Equal(t, int64(60), bruteForceBanDuration(conf, 1))
+	assert.Equal(t, int64(120), bruteForceBanDuration(conf, 2))
+	assert.Equal(t, int64(480), bruteForceBanDuration(conf, 4))
+	assert.Equal(t, int64(600), bruteForceBanDuration(conf, 5))
+	assert.Equal(t, int64(600), bruteForceBanDuration(conf, 1000))
+
+	assert.Equal(t, int64(defaultBruteForceBanDuration), bruteForceBanDuration(apidef.BruteForceProtection{}, 1))
+}
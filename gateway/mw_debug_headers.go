@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// Cache statuses of the X-Tyk-Cache-Status debug header.
+const (
+	debugCacheHit    = "HIT"
+	debugCacheStale  = "STALE"
+	debugCacheMiss   = "MISS"
+	debugCacheBypass = "BYPASS"
+)
+
+// debugHeaderNames are the headers set by setDebugHeaders.
+var debugHeaderNames = []string{
+	header.XTykCacheStatus,
+	header.XTykRateLimitBucket,
+	header.XTykUpstreamTarget,
+	header.XTykMiddlewareTiming,
+}
+
+// debugHeadersAllowList holds the clients getting the debug headers of an API.
+type debugHeadersAllowList struct {
+	networks []*net.IPNet
+	keys     map[string]bool
+}
+
+func compileDebugHeaders(conf apidef.DebugHeaders, logger *logrus.Entry) *debugHeadersAllowList {
+	if !conf.Enabled {
+		return nil
+	}
+
+	allowList := &debugHeadersAllowList{keys: make(map[string]bool, len(conf.AllowedKeys))}
+	for _, value := range conf.AllowedIPs {
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil {
+				bits := 8 * net.IPv6len
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 8*net.IPv4len
+				}
+				allowList.networks = append(allowList.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			logger.WithError(err).Error("Couldn't parse debug headers allowed IP, skipping")
+			continue
+		}
+		allowList.networks = append(allowList.networks, network)
+	}
+
+	for _, key := range conf.AllowedKeys {
+		allowList.keys[key] = true
+	}
+
+	return allowList
+}
+
+// allowed returns true if the client of the request gets the debug headers, by IP or key. The IP
+// is the one of the connection, the forwarding headers are set by the clients.
+func (a *debugHeadersAllowList) allowed(r *http.Request, session *user.SessionState) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range a.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	if session == nil || len(a.keys) == 0 {
+		return false
+	}
+
+	return (session.KeyID != "" && a.keys[session.KeyID]) || (!session.KeyHashEmpty() && a.keys[session.KeyHash()])
+}
+
+type middlewareTiming struct {
+	name     string
+	duration time.Duration
+}
+
+// debugDiagnostics records the diagnostics of a request reported by the debug headers.
+type debugDiagnostics struct {
+	mu sync.Mutex
+
+	start            time.Time
+	cacheStatus      string
+	rateLimitBuckets []string
+	upstreamTarget   string
+	timings          []middlewareTiming
+}
+
+func (d *debugDiagnostics) setCacheStatus(status string) {
+	d.mu.Lock()
+	d.cacheStatus = status
+	d.mu.Unlock()
+}
+
+// addRateLimitBucket records a rate limiter key checked for the request, such as the ones of
+// the organisation and of the key.
+func (d *debugDiagnostics) addRateLimitBucket(bucket string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, b := range d.rateLimitBuckets {
+		if b == bucket {
+			return
+		}
+	}
+	d.rateLimitBuckets = append(d.rateLimitBuckets, bucket)
+}
+
+func (d *debugDiagnostics) setUpstreamTarget(target string) {
+	d.mu.Lock()
+	d.upstreamTarget = target
+	d.mu.Unlock()
+}
+
+func (d *debugDiagnostics) middleware(name string, duration time.Duration) {
+	d.mu.Lock()
+	d.timings = append(d.timings, middlewareTiming{name: name, duration: duration})
+	d.mu.Unlock()
+}
+
+// setHeaders sets the debug headers of the diagnostics. The middleware timings use the
+// Server-Timing syntax, in milliseconds, ending with the total time of the request at now.
+func (d *debugDiagnostics) setHeaders(h http.Header, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cacheStatus := d.cacheStatus
+	if cacheStatus == "" {
+		cacheStatus = debugCacheBypass
+	}
+	h.Set(header.XTykCacheStatus, cacheStatus)
+
+	if len(d.rateLimitBuckets) > 0 {
+		h.Set(header.XTykRateLimitBucket, strings.Join(d.rateLimitBuckets, ", "))
+	}
+
+	if d.upstreamTarget != "" {
+		h.Set(header.XTykUpstreamTarget, d.upstreamTarget)
+	}
+
+	timings := make([]string, 0, len(d.timings)+1)
+	for _, timing := range d.timings {
+		timings = append(timings, timing.name+";dur="+formatTimingMs(timing.duration))
+	}
+	timings = append(timings, "total;dur="+formatTimingMs(now.Sub(d.start)))
+	h.Set(header.XTykMiddlewareTiming, strings.Join(timings, ", "))
+}
+
+func formatTimingMs(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// withoutDebugHeaders returns a copy of h without the debug headers, h when it has none.
+func withoutDebugHeaders(h http.Header) http.Header {
+	var stripped http.Header
+	for _, name := range debugHeaderNames {
+		if len(h.Values(name)) == 0 {
+			continue
+		}
+
+		if stripped == nil {
+			stripped = h.Clone()
+		}
+		stripped.Del(name)
+	}
+
+	if stripped == nil {
+		return h
+	}
+	return stripped
+}
+
+func ctxSetDebugDiagnostics(r *http.Request, d *debugDiagnostics) {
+	setCtxValue(r, ctx.DebugDiagnostics, d)
+}
+
+func ctxGetDebugDiagnostics(r *http.Request) *debugDiagnostics {
+	if v, ok := r.Context().Value(ctx.DebugDiagnostics).(*debugDiagnostics); ok {
+		return v
+	}
+	return nil
+}
+
+// setDebugHeaders sets the debug headers of the request when its client is allow-listed.
+func setDebugHeaders(h http.Header, spec *APISpec, r *http.Request, session *user.SessionState) {
+	diagnostics := ctxGetDebugDiagnostics(r)
+	if diagnostics == nil || spec.debugHeaders == nil || !spec.debugHeaders.allowed(r, session) {
+		return
+	}
+
+	diagnostics.setHeaders(h, time.Now())
+}
+
+// DebugHeadersMiddleware starts recording the diagnostics of requests reported by the debug
+// headers. Whether the client gets them is only known once the request is authenticated.
+type DebugHeadersMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *DebugHeadersMiddleware) Name() string {
+	return "DebugHeadersMiddleware"
+}
+
+func (m *DebugHeadersMiddleware) EnabledForSpec() bool {
+	return m.Spec.debugHeaders != nil
+}
+
+func (m *DebugHeadersMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	ctxSetDebugDiagnostics(r, &debugDiagnostics{start: time.Now()})
+	return nil, http.StatusOK
+}
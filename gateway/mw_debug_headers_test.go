@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestDebugHeaders(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream, err := url.Parse(TestHttpAny)
+	require.NoError(t, err)
+
+	createKey := func() string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{
+				"debug-keys": {APIID: "debug-keys"},
+				"debug-ips":  {APIID: "debug-ips"},
+			}
+			s.Rate, s.Per = 100, 60
+		})
+		return key
+	}
+	debugKey, otherKey := createKey(), createKey()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "debug-keys"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/debug-keys/"
+		spec.CacheOptions = apidef.CacheOptions{EnableCache: true, CacheTimeout: 60, CacheAllSafeRequests: true}
+		spec.DebugHeaders = apidef.DebugHeaders{Enabled: true, AllowedKeys: []string{debugKey}}
+	}, func(spec *APISpec) {
+		spec.APIID = "debug-ips"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/debug-ips/"
+		spec.DebugHeaders = apidef.DebugHeaders{Enabled: true, AllowedIPs: []string{"127.0.0.1", "::1"}}
+	})
+
+	debugAuth := map[string]string{header.Authorization: debugKey}
+
+	t.Run("allowed key", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{
+			Path:         "/debug-keys/",
+			Headers:      debugAuth,
+			Code:         http.StatusOK,
+			HeadersMatch: map[string]string{header.XTykCacheStatus: debugCacheMiss, header.XTykUpstreamTarget: "http://" + upstream.Host},
+		})
+		assert.NotEmpty(t, resp.Header.Get(header.XTykRateLimitBucket))
+		assert.Contains(t, resp.Header.Get(header.XTykMiddlewareTiming), "AuthKey;dur=")
+		assert.Contains(t, resp.Header.Get(header.XTykMiddlewareTiming), "total;dur=")
+
+		// the cached response has no debug headers, it may be served to other clients
+		store := &storage.RedisCluster{ConnectionHandler: ts.Gw.StorageConnectionHandler}
+		cacheKeys, err := store.ScanKeys("cache-debug-keys*")
+		require.NoError(t, err)
+		require.NotEmpty(t, cacheKeys)
+		for _, key := range cacheKeys {
+			value, err := store.GetRawKey(key)
+			require.NoError(t, err)
+			encoded, _, _ := strings.Cut(value, "|")
+			cached, err := base64.StdEncoding.DecodeString(encoded)
+			require.NoError(t, err)
+			for _, name := range debugHeaderNames {
+				assert.NotContains(t, string(cached), http.CanonicalHeaderKey(name))
+			}
+		}
+
+		_, _ = ts.Run(t, test.TestCase{
+			Path:         "/debug-keys/",
+			Headers:      debugAuth,
+			Code:         http.StatusOK,
+			HeadersMatch: map[string]string{header.XTykCacheStatus: debugCacheHit},
+		})
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{Path: "/debug-keys/", Headers: map[string]string{header.Authorization: otherKey}, Code: http.StatusOK})
+		assert.Empty(t, resp.Header.Get(header.XTykCacheStatus))
+		assert.Empty(t, resp.Header.Get(header.XTykMiddlewareTiming))
+	})
+
+	t.Run("allowed IP", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			Path:         "/debug-ips/",
+			Headers:      map[string]string{header.Authorization: otherKey},
+			Code:         http.StatusOK,
+			HeadersMatch: map[string]string{header.XTykCacheStatus: debugCacheBypass},
+		})
+
+		// error responses of the Gateway have the headers too
+		resp, _ := ts.Run(t, test.TestCase{Path: "/debug-ips/", Code: http.StatusUnauthorized})
+		assert.Contains(t, resp.Header.Get(header.XTykMiddlewareTiming), "AuthKey;dur=")
+		assert.Empty(t, resp.Header.Get(header.XTykUpstreamTarget))
+	})
+}
+
+func TestDebugHeadersAllowList(t *testing.T) {
+	assert.Nil(t, compileDebugHeaders(apidef.DebugHeaders{AllowedIPs: []string{"10.0.0.1"}}, logrus.NewEntry(log)))
+
+	allowList := compileDebugHeaders(apidef.DebugHeaders{
+		Enabled:     true,
+		AllowedIPs:  []string{"10.0.0.0/8", "192.168.1.10", "invalid"},
+		AllowedKeys: []string{"debug-hash"},
+	}, logrus.NewEntry(log))
+	require.Len(t, allowList.networks, 2)
+
+	request := func(ip string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = ip + ":1234"
+		return r
+	}
+
+	assert.True(t, allowList.allowed(request("10.1.2.3"), nil))
+	assert.True(t, allowList.allowed(request("192.168.1.10"), nil))
+	assert.False(t, allowList.allowed(request("192.168.1.11"), nil))
+
+	spoofed := request("192.168.1.11")
+	spoofed.Header.Set(header.XRealIP, "10.1.2.3")
+	spoofed.Header.Set(header.XForwardFor, "10.1.2.3")
+	assert.False(t, allowList.allowed(spoofed, nil), "forwarding headers are set by the clients")
+
+	session := &user.SessionState{KeyID: "debug-key"}
+	assert.False(t, allowList.allowed(request("192.168.1.11"), session))
+	session.SetKeyHash("debug-hash")
+	assert.True(t, allowList.allowed(request("192.168.1.11"), session))
+}
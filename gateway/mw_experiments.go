@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+)
+
+const experimentTagPrefix = "experiment-"
+
+type compiledExperiment struct {
+	apidef.Experiment
+
+	variants    []compiledExperimentVariant
+	totalWeight uint64
+}
+
+type compiledExperimentVariant struct {
+	apidef.ExperimentVariant
+
+	target *url.URL
+}
+
+// experimentAssignment is the variant of an experiment assigned to a request.
+type experimentAssignment struct {
+	experiment string
+	variant    string
+}
+
+func compileExperiments(conf apidef.Experiments, protocol string, logger *logrus.Entry) []*compiledExperiment {
+	if !conf.Enabled {
+		return nil
+	}
+
+	var experiments []*compiledExperiment
+	for _, test := range conf.Tests {
+		experiment := &compiledExperiment{Experiment: test}
+		if experiment.BucketBy == "" {
+			experiment.BucketBy = apidef.ExperimentBucketKey
+		}
+
+		valid := true
+		for _, variant := range test.Variants {
+			compiled := compiledExperimentVariant{ExperimentVariant: variant}
+			if variant.Target != "" {
+				target, err := url.Parse(EnsureTransport(variant.Target, protocol))
+				if err != nil {
+					logger.WithField("experiment", test.Name).WithError(err).Error("Couldn't parse experiment variant target, skipping experiment")
+					valid = false
+					break
+				}
+				compiled.target = target
+			}
+
+			if variant.Weight > 0 {
+				experiment.totalWeight += uint64(variant.Weight)
+			}
+			experiment.variants = append(experiment.variants, compiled)
+		}
+
+		if !valid {
+			continue
+		}
+
+		if experiment.totalWeight == 0 {
+			logger.WithField("experiment", test.Name).Error("Experiment has no weighted variant, skipping")
+			continue
+		}
+
+		experiments = append(experiments, experiment)
+	}
+
+	return experiments
+}
+
+// value returns the value of the key, claim, header or metadata the consumers are bucketed by.
+func (e *compiledExperiment) value(r *http.Request) string {
+	var value interface{}
+
+	switch e.BucketBy {
+	case apidef.ExperimentBucketKey:
+		if session := ctxGetSession(r); session != nil {
+			return session.KeyID
+		}
+	case apidef.ExperimentBucketHeader:
+		return r.Header.Get(e.Key)
+	case apidef.ExperimentBucketClaim:
+		value = ctxGetData(r)["jwt_claims_"+e.Key]
+	case apidef.ExperimentBucketMeta:
+		if session := ctxGetSession(r); session != nil {
+			value = session.MetaData[e.Key]
+		}
+	}
+
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+// assign returns the variant of the consumer with the bucketing value. The hash is salted
+// with the name of the experiment, so that the experiments bucket the consumers independently.
+func (e *compiledExperiment) assign(value string) *compiledExperimentVariant {
+	sum := sha256.Sum256([]byte(e.Name + "\x00" + value))
+	bucket := binary.BigEndian.Uint64(sum[:8]) % e.totalWeight
+
+	for i := range e.variants {
+		variant := &e.variants[i]
+		if variant.Weight <= 0 {
+			continue
+		}
+
+		if bucket < uint64(variant.Weight) {
+			return variant
+		}
+		bucket -= uint64(variant.Weight)
+	}
+
+	return nil
+}
+
+// ExperimentsMiddleware assigns the consumers a variant of the A/B experiments of the API,
+// routing the requests to the upstream of the variant and adding its headers.
+type ExperimentsMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *ExperimentsMiddleware) Name() string {
+	return "ExperimentsMiddleware"
+}
+
+func (m *ExperimentsMiddleware) EnabledForSpec() bool {
+	return len(m.Spec.experiments) > 0
+}
+
+func (m *ExperimentsMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	var assignments []experimentAssignment
+
+	for _, experiment := range m.Spec.experiments {
+		value := experiment.value(r)
+		if value == "" {
+			continue
+		}
+
+		variant := experiment.assign(value)
+		if variant == nil {
+			continue
+		}
+
+		assignments = append(assignments, experimentAssignment{experiment: experiment.Name, variant: variant.Name})
+
+		for name, value := range variant.Headers {
+			r.Header.Set(name, value)
+		}
+
+		// upstream routing rules and earlier experiments take precedence
+		if variant.target != nil && ctxGetUpstreamRoute(r) == nil {
+			ctxSetUpstreamRoute(r, &upstreamRoute{target: variant.target})
+		}
+	}
+
+	if len(assignments) > 0 {
+		ctxSetExperiments(r, assignments)
+	}
+
+	return nil, http.StatusOK
+}
+
+func ctxSetExperiments(r *http.Request, assignments []experimentAssignment) {
+	setCtxValue(r, ctx.Experiments, assignments)
+}
+
+func ctxGetExperiments(r *http.Request) []experimentAssignment {
+	if v, ok := r.Context().Value(ctx.Experiments).([]experimentAssignment); ok {
+		return v
+	}
+	return nil
+}
+
+// tagExperiments adds the variants of the experiments assigned to the request to the analytics tags.
+func tagExperiments(r *http.Request, tags []string) []string {
+	for _, assignment := range ctxGetExperiments(r) {
+		tags = append(tags, experimentTagPrefix+assignment.experiment+"-"+assignment.variant)
+	}
+
+	return tags
+}
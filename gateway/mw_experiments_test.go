@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestExperiments(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	treatment := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("treatment"))
+	}))
+	defer treatment.Close()
+
+	api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "experiments"
+		spec.Proxy.ListenPath = "/experiments/"
+		spec.Experiments = apidef.Experiments{
+			Enabled: true,
+			Tests: []apidef.Experiment{
+				{Name: "checkout", BucketBy: apidef.ExperimentBucketHeader, Key: "X-User", Variants: []apidef.ExperimentVariant{
+					{Name: "control", Weight: 1, Headers: map[string]string{"X-Variant": "control"}},
+					{Name: "new", Weight: 1, Target: treatment.URL},
+				}},
+			},
+		}
+	})[0]
+	require.Len(t, api.experiments, 1)
+
+	var cases []test.TestCase
+	variants := map[string]int{}
+	for i := 0; i < 20; i++ {
+		user := "user-" + strconv.Itoa(i)
+		variant := api.experiments[0].assign(user)
+		require.NotNil(t, variant)
+		variants[variant.Name]++
+
+		tc := test.TestCase{Path: "/experiments/", Headers: map[string]string{"X-User": user}, Code: http.StatusOK, BodyMatch: `"X-Variant":"control"`}
+		if variant.Name == "new" {
+			tc.BodyMatch = "^treatment$"
+		}
+
+		// consumers get the same variant on every request
+		cases = append(cases, tc, tc)
+	}
+	assert.Len(t, variants, 2)
+
+	// requests without a bucketing value aren't assigned
+	cases = append(cases, test.TestCase{Path: "/experiments/", Code: http.StatusOK, BodyNotMatch: "X-Variant"})
+
+	_, _ = ts.Run(t, cases...)
+}
+
+func TestExperiment_assign(t *testing.T) {
+	experiments := compileExperiments(apidef.Experiments{
+		Enabled: true,
+		Tests: []apidef.Experiment{
+			{Name: "weighted", Variants: []apidef.ExperimentVariant{
+				{Name: "a", Weight: 9},
+				{Name: "b", Weight: 1},
+				{Name: "drained", Weight: 0},
+			}},
+			{Name: "unweighted", Variants: []apidef.ExperimentVariant{{Name: "a"}}},
+			{Name: "invalid", Variants: []apidef.ExperimentVariant{{Name: "a", Weight: 1, Target: "http://[::1"}}},
+		},
+	}, "http", logrus.NewEntry(log))
+	require.Len(t, experiments, 1)
+	assert.Equal(t, apidef.ExperimentBucketKey, experiments[0].BucketBy)
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[experiments[0].assign(strconv.Itoa(i)).Name]++
+	}
+
+	assert.InDelta(t, 9000, counts["a"], 300)
+	assert.InDelta(t, 1000, counts["b"], 300)
+	assert.Zero(t, counts["drained"])
+}
+
+func TestTagExperiments(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, tagExperiments(r, nil))
+
+	ctxSetExperiments(r, []experimentAssignment{{experiment: "checkout", variant: "new"}})
+	assert.Equal(t, []string{"tag", "experiment-checkout-new"}, tagExperiments(r, []string{"tag"}))
+}
@@ -0,0 +1,367 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/cache"
+	"github.com/TykTechnologies/tyk/request"
+)
+
+const (
+	defaultExtAuthzTimeout = 500
+	// extAuthzMaxDeniedBody bounds the bodies of the denials of HTTP services.
+	extAuthzMaxDeniedBody = 64 << 10
+)
+
+var errExtAuthzUnavailable = errors.New("Authorization service unavailable")
+
+// extAuthzRequest holds the attributes of a request sent to an external authorization service.
+type extAuthzRequest struct {
+	Method string
+	// Path is the request target, the path and query of the request.
+	Path     string
+	Host     string
+	Scheme   string
+	Protocol string
+	// Headers are the forwarded headers, by lower case name, their values joined by commas.
+	Headers map[string]string
+	Body    []byte
+	// RawBody sends the body to gRPC services as bytes rather than as a string.
+	RawBody bool
+	// RemoteAddr and RemotePort are the address of the connection of the client.
+	RemoteAddr string
+	RemotePort int
+	// ForwardedFor is the X-Forwarded-For header of the request, set by the client or the
+	// proxies in front of the Gateway, sent apart from the connection address.
+	ForwardedFor string
+}
+
+// cacheKey returns the key of the decision for the request of the consumer. The key covers the
+// attributes sent to the service, but the remote port, which changes with each connection.
+func (a *extAuthzRequest) cacheKey(consumer string) string {
+	names := make([]string, 0, len(a.Headers))
+	for name := range a.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	h.Write([]byte(consumer + "\n" + a.Method + "\n" + a.Scheme + "\n" + a.Protocol + "\n" + a.Host + "\n" + a.Path + "\n" + a.RemoteAddr + "\n" + a.ForwardedFor + "\n"))
+	for _, name := range names {
+		h.Write([]byte(name + ":" + a.Headers[name] + "\n"))
+	}
+	h.Write(a.Body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// extAuthzDecision is the decision of an external authorization service.
+type extAuthzDecision struct {
+	Allowed bool
+	// Status, Headers and Body are the response of denied requests.
+	Status int
+	Body   []byte
+	// Headers are the response headers of denied requests, the upstream request
+	// headers set for allowed requests.
+	Headers http.Header
+	// AppendHeaders are added to allowed requests.
+	AppendHeaders http.Header
+	// RemoveHeaders are removed from allowed requests.
+	RemoveHeaders []string
+}
+
+// extAuthzClient calls an external authorization service.
+type extAuthzClient interface {
+	check(ctx context.Context, attrs *extAuthzRequest) (*extAuthzDecision, error)
+	close()
+}
+
+// httpExtAuthzClient calls HTTP services with the method, path and forwarded headers of requests.
+type httpExtAuthzClient struct {
+	url            string
+	allowedHeaders []string
+	client         *http.Client
+}
+
+func (c *httpExtAuthzClient) check(ctx context.Context, attrs *extAuthzRequest) (*extAuthzDecision, error) {
+	req, err := http.NewRequestWithContext(ctx, attrs.Method, strings.TrimSuffix(c.url, "/")+attrs.Path, bytes.NewReader(attrs.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range attrs.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("X-Forwarded-Host", attrs.Host)
+	req.Header.Set("X-Forwarded-Proto", attrs.Scheme)
+	forwardedFor := attrs.RemoteAddr
+	if attrs.ForwardedFor != "" {
+		forwardedFor = attrs.ForwardedFor + ", " + forwardedFor
+	}
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		decision := &extAuthzDecision{Allowed: true, Headers: http.Header{}}
+		for _, name := range c.allowedHeaders {
+			if value := resp.Header.Get(name); value != "" {
+				decision.Headers.Set(name, value)
+			}
+		}
+		return decision, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, extAuthzMaxDeniedBody))
+	if err != nil {
+		return nil, err
+	}
+
+	headers := resp.Header.Clone()
+	for _, name := range []string{"Content-Length", "Transfer-Encoding", "Connection", "Date"} {
+		headers.Del(name)
+	}
+
+	return &extAuthzDecision{Status: resp.StatusCode, Headers: headers, Body: body}, nil
+}
+
+func (c *httpExtAuthzClient) close() {
+	c.client.CloseIdleConnections()
+}
+
+// grpcExtAuthzClient calls the Check method of gRPC services.
+type grpcExtAuthzClient struct {
+	conn *grpc.ClientConn
+}
+
+func (c *grpcExtAuthzClient) check(ctx context.Context, attrs *extAuthzRequest) (*extAuthzDecision, error) {
+	var resp []byte
+	if err := c.conn.Invoke(ctx, extAuthzCheckMethod, encodeCheckRequest(attrs), &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return nil, err
+	}
+	return decodeCheckResponse(resp)
+}
+
+func (c *grpcExtAuthzClient) close() {
+	_ = c.conn.Close()
+}
+
+func newExtAuthzClient(conf apidef.ExternalAuthorization, timeout time.Duration) (extAuthzClient, error) {
+	switch conf.Protocol {
+	case "", apidef.ExternalAuthorizationHTTP:
+		return &httpExtAuthzClient{
+			url:            conf.URL,
+			allowedHeaders: conf.AllowedUpstreamHeaders,
+			client:         &http.Client{Timeout: timeout},
+		}, nil
+	case apidef.ExternalAuthorizationGRPC:
+		creds := insecure.NewCredentials()
+		if conf.TLS {
+			creds = credentials.NewClientTLSFromCert(nil, "")
+		}
+		conn, err := grpc.NewClient(conf.URL, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, err
+		}
+		return &grpcExtAuthzClient{conn: conn}, nil
+	default:
+		return nil, errors.New("unknown external authorization protocol " + strconv.Quote(conf.Protocol))
+	}
+}
+
+// ExternalAuthorizationMiddleware enforces the decisions of the external authorization service of the API.
+type ExternalAuthorizationMiddleware struct {
+	*BaseMiddleware
+
+	client  extAuthzClient
+	timeout time.Duration
+	cache   cache.Repository
+}
+
+func (m *ExternalAuthorizationMiddleware) Name() string {
+	return "ExternalAuthorizationMiddleware"
+}
+
+func (m *ExternalAuthorizationMiddleware) EnabledForSpec() bool {
+	return m.Spec.ExternalAuthorization.Enabled
+}
+
+func (m *ExternalAuthorizationMiddleware) Init() {
+	conf := m.Spec.ExternalAuthorization
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultExtAuthzTimeout
+	}
+	m.timeout = time.Duration(timeout) * time.Millisecond
+
+	client, err := newExtAuthzClient(conf, m.timeout)
+	if err != nil {
+		m.Logger().WithError(err).Error("Couldn't create external authorization client")
+	}
+	m.client = client
+
+	if conf.CacheTTL > 0 {
+		m.cache = cache.New(conf.CacheTTL, 60)
+	}
+}
+
+func (m *ExternalAuthorizationMiddleware) Unload() {
+	if m.client != nil {
+		m.client.close()
+	}
+}
+
+func (m *ExternalAuthorizationMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	decision, err := m.check(r)
+	if err != nil {
+		m.Logger().WithError(err).Warning("External authorization failed")
+		if m.Spec.ExternalAuthorization.FailOpen {
+			return nil, http.StatusOK
+		}
+		return errExtAuthzUnavailable, http.StatusForbidden
+	}
+
+	if !decision.Allowed {
+		status := decision.Status
+		if status == 0 {
+			status = http.StatusForbidden
+		}
+		for name, values := range decision.Headers {
+			w.Header()[name] = slices.Clone(values)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(decision.Body)
+		return nil, mwStatusRespond
+	}
+
+	for _, name := range decision.RemoveHeaders {
+		r.Header.Del(name)
+	}
+	for name, values := range decision.Headers {
+		r.Header.Del(name)
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+	for name, values := range decision.AppendHeaders {
+		for _, value := range values {
+			r.Header.Add(name, value)
+		}
+	}
+
+	return nil, http.StatusOK
+}
+
+func (m *ExternalAuthorizationMiddleware) check(r *http.Request) (*extAuthzDecision, error) {
+	if m.client == nil {
+		return nil, errors.New("no external authorization client")
+	}
+
+	attrs, err := m.requestAttributes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var key string
+	if m.cache != nil {
+		var consumer string
+		if session := ctxGetSession(r); session != nil {
+			consumer = session.KeyHash()
+		}
+		key = attrs.cacheKey(consumer)
+		if cached, ok := m.cache.Get(key); ok {
+			return cached.(*extAuthzDecision), nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), m.timeout)
+	defer cancel()
+
+	decision, err := m.client.check(ctx, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.cache != nil {
+		m.cache.Set(key, decision, cache.DefaultExpiration)
+	}
+
+	return decision, nil
+}
+
+// requestAttributes returns the attributes of the request sent to the service.
+func (m *ExternalAuthorizationMiddleware) requestAttributes(r *http.Request) (*extAuthzRequest, error) {
+	conf := m.Spec.ExternalAuthorization
+
+	attrs := &extAuthzRequest{
+		Method:       r.Method,
+		Path:         r.URL.RequestURI(),
+		Host:         r.Host,
+		Scheme:       "http",
+		Protocol:     r.Proto,
+		Headers:      map[string]string{},
+		RemoteAddr:   request.ConnectionIP(r),
+		ForwardedFor: r.Header.Get(header.XForwardFor),
+	}
+	if r.TLS != nil {
+		attrs.Scheme = "https"
+	}
+	if _, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		attrs.RemotePort, _ = strconv.Atoi(port)
+	}
+
+	if len(conf.ForwardHeaders) == 0 {
+		for name, values := range r.Header {
+			attrs.Headers[strings.ToLower(name)] = strings.Join(values, ",")
+		}
+	} else {
+		for _, name := range conf.ForwardHeaders {
+			if values := r.Header.Values(name); len(values) > 0 {
+				attrs.Headers[strings.ToLower(name)] = strings.Join(values, ",")
+			}
+		}
+	}
+
+	if conf.MaxBodySize > 0 && r.Body != nil {
+		// only the beginning of the body is read, the rest streams to the upstream
+		body, err := io.ReadAll(io.LimitReader(r.Body, conf.MaxBodySize))
+		if err != nil {
+			return nil, err
+		}
+		r.Body = extAuthzBody{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+
+		attrs.Body = body
+		attrs.RawBody = conf.AllowBinary || !utf8.Valid(body)
+	}
+
+	return attrs, nil
+}
+
+// extAuthzBody is a request body which beginning was sent to the authorization service.
+type extAuthzBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,308 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestExternalAuthorization_HTTP(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	var calls int64
+	authz := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		if r.Header.Get("X-User") != "alice" || r.URL.Path != "/authz/orders" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("denied " + r.URL.Path))
+			return
+		}
+		w.Header().Set("X-Auth-User", "alice")
+		w.Header().Set("X-Internal", "secret")
+	}))
+	defer authz.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/authz/"
+		spec.ExternalAuthorization = apidef.ExternalAuthorization{
+			Enabled:                true,
+			URL:                    authz.URL,
+			ForwardHeaders:         []string{"X-User"},
+			AllowedUpstreamHeaders: []string{"X-Auth-User"},
+			CacheTTL:               60,
+		}
+	}, func(spec *APISpec) {
+		spec.APIID = "closed"
+		spec.Proxy.ListenPath = "/closed/"
+		spec.ExternalAuthorization = apidef.ExternalAuthorization{Enabled: true, URL: "http://127.0.0.1:1"}
+	}, func(spec *APISpec) {
+		spec.APIID = "open"
+		spec.Proxy.ListenPath = "/open/"
+		spec.ExternalAuthorization = apidef.ExternalAuthorization{Enabled: true, URL: "http://127.0.0.1:1", FailOpen: true}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/authz/orders", Headers: map[string]string{"X-User": "alice"}, Code: http.StatusOK,
+			BodyMatch: `"X-Auth-User":"alice"`, BodyNotMatch: "X-Internal"},
+		{Path: "/authz/orders", Headers: map[string]string{"X-User": "bob"}, Code: http.StatusUnauthorized,
+			BodyMatch: "^denied /authz/orders$", HeadersMatch: map[string]string{"WWW-Authenticate": "Bearer"}},
+		{Path: "/closed/", Code: http.StatusForbidden, BodyMatch: "Authorization service unavailable"},
+		{Path: "/open/", Code: http.StatusOK},
+	}...)
+
+	// the decisions are cached
+	_, _ = ts.Run(t, test.TestCase{Path: "/authz/orders", Headers: map[string]string{"X-User": "alice"}, Code: http.StatusOK})
+	assert.Equal(t, int64(2), atomic.LoadInt64(&calls))
+}
+
+func TestExtAuthzRequest_cacheKey(t *testing.T) {
+	attrs := func() *extAuthzRequest {
+		return &extAuthzRequest{
+			Method:     http.MethodGet,
+			Path:       "/orders",
+			Host:       "api.example.com",
+			Scheme:     "https",
+			Protocol:   "HTTP/1.1",
+			Headers:    map[string]string{"x-user": "alice"},
+			RemoteAddr: "10.0.0.1",
+			RemotePort: 1234,
+		}
+	}
+
+	key := attrs().cacheKey("consumer")
+
+	samePort := attrs()
+	samePort.RemotePort = 4321
+	assert.Equal(t, key, samePort.cacheKey("consumer"), "the remote port shouldn't change the key")
+
+	assert.NotEqual(t, key, attrs().cacheKey("other"), "the consumer should change the key")
+
+	for name, change := range map[string]func(a *extAuthzRequest){
+		"remote address": func(a *extAuthzRequest) { a.RemoteAddr = "10.0.0.2" },
+		"forwarded for":  func(a *extAuthzRequest) { a.ForwardedFor = "203.0.113.1" },
+		"scheme":         func(a *extAuthzRequest) { a.Scheme = "http" },
+		"protocol":       func(a *extAuthzRequest) { a.Protocol = "HTTP/2.0" },
+		"header":         func(a *extAuthzRequest) { a.Headers["x-user"] = "bob" },
+	} {
+		changed := attrs()
+		change(changed)
+		assert.NotEqual(t, key, changed.cacheKey("consumer"), name+" should change the key")
+	}
+}
+
+func TestExternalAuthorization_requestBody(t *testing.T) {
+	// checkRequestBody returns the number of the body field of an encoded CheckRequest, and the body.
+	checkRequestBody := func(t *testing.T, b []byte) (num protowire.Number, body string) {
+		t.Helper()
+
+		var walk func(b []byte, path ...protowire.Number)
+		walk = func(b []byte, path ...protowire.Number) {
+			require.NoError(t, protoFields(b, func(n protowire.Number, _ uint64, content []byte) error {
+				switch {
+				case len(path) > 0 && n == path[0]:
+					walk(content, path[1:]...)
+				case len(path) == 0 && (n == 11 || n == 12):
+					num, body = n, string(content)
+				}
+				return nil
+			}))
+		}
+		// CheckRequest.attributes.request.http
+		walk(b, 1, 4, 2)
+
+		return num, body
+	}
+
+	attrsOf := func(t *testing.T, conf apidef.ExternalAuthorization, body string) *extAuthzRequest {
+		t.Helper()
+
+		m := &ExternalAuthorizationMiddleware{BaseMiddleware: &BaseMiddleware{Spec: &APISpec{APIDefinition: &apidef.APIDefinition{
+			ExternalAuthorization: conf,
+		}}}}
+
+		r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		attrs, err := m.requestAttributes(r)
+		require.NoError(t, err)
+
+		forwarded, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(forwarded), "the whole body should reach the upstream")
+
+		return attrs
+	}
+
+	t.Run("limited", func(t *testing.T) {
+		attrs := attrsOf(t, apidef.ExternalAuthorization{MaxBodySize: 4}, "hello world")
+		num, body := checkRequestBody(t, encodeCheckRequest(attrs))
+		assert.Equal(t, protowire.Number(11), num)
+		assert.Equal(t, "hell", body)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		attrs := attrsOf(t, apidef.ExternalAuthorization{MaxBodySize: 16}, "\xff\xfe")
+		num, body := checkRequestBody(t, encodeCheckRequest(attrs))
+		assert.Equal(t, protowire.Number(12), num, "invalid UTF-8 should be sent in raw_body")
+		assert.Equal(t, "\xff\xfe", body)
+	})
+
+	t.Run("allow binary", func(t *testing.T) {
+		attrs := attrsOf(t, apidef.ExternalAuthorization{MaxBodySize: 16, AllowBinary: true}, "hello")
+		num, _ := checkRequestBody(t, encodeCheckRequest(attrs))
+		assert.Equal(t, protowire.Number(12), num)
+	})
+}
+
+func TestExternalAuthorization_sourceAddress(t *testing.T) {
+	m := &ExternalAuthorizationMiddleware{BaseMiddleware: &BaseMiddleware{Spec: &APISpec{APIDefinition: &apidef.APIDefinition{
+		ExternalAuthorization: apidef.ExternalAuthorization{ForwardHeaders: []string{"X-User"}},
+	}}}}
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.1")
+	r.Header.Set("X-Real-IP", "203.0.113.2")
+
+	attrs, err := m.requestAttributes(r)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", attrs.RemoteAddr, "the source address should be the connection address")
+	assert.Equal(t, 1234, attrs.RemotePort)
+	assert.Equal(t, "203.0.113.1", attrs.ForwardedFor)
+
+	t.Run("http", func(t *testing.T) {
+		var forwardedFor string
+		authz := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			forwardedFor = r.Header.Get("X-Forwarded-For")
+		}))
+		defer authz.Close()
+
+		client := &httpExtAuthzClient{url: authz.URL, client: authz.Client()}
+		_, err := client.check(context.Background(), attrs)
+		require.NoError(t, err)
+		assert.Equal(t, "203.0.113.1, 10.0.0.1", forwardedFor)
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		assert.Equal(t, "203.0.113.1", checkRequestHeader(t, encodeCheckRequest(attrs), "x-forwarded-for"))
+	})
+}
+
+// checkRequestHeader returns a header of an encoded CheckRequest.
+func checkRequestHeader(t *testing.T, b []byte, name string) string {
+	t.Helper()
+
+	var value string
+	var walk func(b []byte, path ...protowire.Number)
+	walk = func(b []byte, path ...protowire.Number) {
+		require.NoError(t, protoFields(b, func(num protowire.Number, _ uint64, content []byte) error {
+			switch {
+			case len(path) > 0 && num == path[0]:
+				walk(content, path[1:]...)
+			case len(path) == 0 && num == 3:
+				// map entries have the key and the value in fields 1 and 2
+				var key, v string
+				_ = protoFields(content, func(num protowire.Number, _ uint64, field []byte) error {
+					if num == 1 {
+						key = string(field)
+					} else if num == 2 {
+						v = string(field)
+					}
+					return nil
+				})
+				if key == name {
+					value = v
+				}
+			}
+			return nil
+		}))
+	}
+	// CheckRequest.attributes.request.http.headers
+	walk(b, 1, 4, 2)
+
+	return value
+}
+
+func TestExternalAuthorization_GRPC(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	header := func(name, value string, appendValue bool) []byte {
+		hv := appendStringField(nil, 1, name)
+		hv = appendStringField(hv, 2, value)
+		option := appendMessageField(nil, 1, hv)
+		if appendValue {
+			option = appendMessageField(option, 2, appendVarintField(nil, 1, 1))
+		}
+		return option
+	}
+
+	server := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "envoy.service.auth.v3.Authorization",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Check",
+			Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				var req []byte
+				if err := dec(&req); err != nil {
+					return nil, err
+				}
+
+				var resp []byte
+				if checkRequestHeader(t, req, "x-user") == "alice" {
+					ok := appendMessageField(nil, 2, header("X-Auth-User", "alice", false))
+					ok = appendMessageField(ok, 2, header("X-Tag", "authz", true))
+					ok = appendStringField(ok, 5, "X-Remove")
+					resp = appendMessageField(nil, 1, nil)
+					resp = appendMessageField(resp, 3, ok)
+				} else {
+					// PERMISSION_DENIED
+					resp = appendMessageField(nil, 1, appendVarintField(nil, 1, 7))
+					denied := appendMessageField(nil, 1, appendVarintField(nil, 1, http.StatusUnauthorized))
+					denied = appendMessageField(denied, 2, header("X-Reason", "user", false))
+					denied = appendStringField(denied, 3, "nope")
+					resp = appendMessageField(resp, 2, denied)
+				}
+				return &resp, nil
+			},
+		}},
+	}, struct{}{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/grpc-authz/"
+		spec.ExternalAuthorization = apidef.ExternalAuthorization{
+			Enabled:  true,
+			Protocol: apidef.ExternalAuthorizationGRPC,
+			URL:      listener.Addr().String(),
+			Timeout:  2000,
+		}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/grpc-authz/", Headers: map[string]string{"X-User": "alice", "X-Remove": "yes"}, Code: http.StatusOK,
+			BodyMatch: `"X-Auth-User":"alice"`, BodyNotMatch: "X-Remove"},
+		{Path: "/grpc-authz/", Headers: map[string]string{"X-User": "alice"}, Code: http.StatusOK,
+			BodyMatch: `"X-Tag":"authz"`},
+		{Path: "/grpc-authz/", Headers: map[string]string{"X-User": "bob"}, Code: http.StatusUnauthorized,
+			BodyMatch: "^nope$", HeadersMatch: map[string]string{"X-Reason": "user"}},
+	}...)
+}
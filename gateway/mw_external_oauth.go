@@ -60,12 +60,14 @@ func (k *ExternalOAuthMiddleware) ProcessRequest(w http.ResponseWriter, r *http.
 		return errors.New("authorization field missing"), http.StatusBadRequest
 	}
 
-	token = stripBearer(token)
+	authorization := token
+	token = stripDPoP(stripBearer(token))
 
 	var (
 		valid      bool
 		err        error
 		identifier string
+		claims     jwt.MapClaims
 	)
 
 	if len(k.Spec.ExternalOAuth.Providers) == 0 {
@@ -76,9 +78,9 @@ func (k *ExternalOAuthMiddleware) ProcessRequest(w http.ResponseWriter, r *http.
 	provider := k.Spec.ExternalOAuth.Providers[0]
 
 	if provider.JWT.Enabled {
-		valid, identifier, err = k.jwt(token)
+		valid, identifier, claims, err = k.jwt(token)
 	} else if provider.Introspection.Enabled {
-		valid, identifier, err = k.introspection(token)
+		valid, identifier, claims, err = k.introspection(token)
 	} else {
 		return errors.New("access token validation method is not specified"), http.StatusInternalServerError
 	}
@@ -97,6 +99,15 @@ func (k *ExternalOAuthMiddleware) ProcessRequest(w http.ResponseWriter, r *http.
 		return errors.New("access token is not valid"), http.StatusUnauthorized
 	}
 
+	if err, code := k.Gw.checkDPoP(k.Spec, w, r, authorization, token, claims); err != nil {
+		return err, code
+	}
+
+	if err, code := checkResourceAudience(k.Spec, w, claims); err != nil {
+		k.Logger().WithError(err).Info("Attempted access with token for another resource")
+		return err, code
+	}
+
 	sessionID := k.generateSessionID(identifier)
 
 	k.Logger().Debug("External OAuth Temporary session ID is: ", sessionID)
@@ -116,13 +127,17 @@ func (k *ExternalOAuthMiddleware) ProcessRequest(w http.ResponseWriter, r *http.
 
 // jwt makes access token validation without making a network call and validates access token locally.
 // The access token should be JWT type.
-func (k *ExternalOAuthMiddleware) jwt(accessToken string) (bool, string, error) {
+func (k *ExternalOAuthMiddleware) jwt(accessToken string) (bool, string, jwt.MapClaims, error) {
 	jwtValidation := k.Spec.ExternalOAuth.Providers[0].JWT
 	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
 	// Verify the token
 	token, err := parser.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
 		// don't forget to validate the alg is what you expect:
-		if err := assertSigningMethod(jwtValidation.SigningMethod, token); err != nil {
+		if k.Spec.FAPI.Enabled {
+			if err := assertFAPISigningMethod(token); err != nil {
+				return nil, err
+			}
+		} else if err := assertSigningMethod(jwtValidation.SigningMethod, token); err != nil {
 			return nil, err
 		}
 
@@ -136,25 +151,25 @@ func (k *ExternalOAuthMiddleware) jwt(accessToken string) (bool, string, error)
 	})
 
 	if err != nil {
-		return false, "", fmt.Errorf("token verification failed: %w", err)
+		return false, "", nil, fmt.Errorf("token verification failed: %w", err)
 	}
 
 	if token != nil && !token.Valid {
-		return false, "", errors.New("invalid token")
+		return false, "", nil, errors.New("invalid token")
 	}
 
 	if err := timeValidateJWTClaims(token.Claims.(jwt.MapClaims), jwtValidation.ExpiresAtValidationSkew,
 		jwtValidation.IssuedAtValidationSkew, jwtValidation.NotBeforeValidationSkew); err != nil {
-		return false, "", fmt.Errorf("key not authorized: %w", err)
+		return false, "", nil, fmt.Errorf("key not authorized: %w", err)
 	}
 
 	var userID string
 	userID, err = getUserIDFromClaim(token.Claims.(jwt.MapClaims), jwtValidation.IdentityBaseField)
 	if err != nil {
-		return false, "", err
+		return false, "", nil, err
 	}
 
-	return true, userID, nil
+	return true, userID, token.Claims.(jwt.MapClaims), nil
 }
 
 // getSecretFromJWKURL gets the secret to verify jwt signature from a JWK URL.
@@ -212,7 +227,7 @@ func (k *ExternalOAuthMiddleware) getSecretFromJWKOrConfig(kid interface{}, jwtV
 
 // introspection makes an introspection request to third-party provider to check whether the access token is valid or not.
 // The access token can be both JWT and opaque type.
-func (k *ExternalOAuthMiddleware) introspection(accessToken string) (bool, string, error) {
+func (k *ExternalOAuthMiddleware) introspection(accessToken string) (bool, string, jwt.MapClaims, error) {
 	opts := k.Spec.ExternalOAuth.Providers[0].Introspection
 
 	var (
@@ -233,7 +248,7 @@ func (k *ExternalOAuthMiddleware) introspection(accessToken string) (bool, strin
 		log.WithError(err).Debug("Doing OAuth introspection call")
 		claims, err = introspect(opts, accessToken)
 		if err != nil {
-			return false, "", fmt.Errorf("introspection err: %w", err)
+			return false, "", nil, fmt.Errorf("introspection err: %w", err)
 		}
 
 		if opts.Cache.Enabled {
@@ -246,25 +261,25 @@ func (k *ExternalOAuthMiddleware) introspection(accessToken string) (bool, strin
 		log.WithError(err).Debug("Found OAuth introspection result in the redis cache")
 
 		if isExpired(claims) {
-			return false, "", jwt.ErrTokenExpired
+			return false, "", nil, jwt.ErrTokenExpired
 		}
 	}
 
 	active, ok := claims["active"]
 	if !ok {
-		return false, "", errors.New("introspection result doesn't have active flag")
+		return false, "", nil, errors.New("introspection result doesn't have active flag")
 	}
 
 	if !active.(bool) {
-		return false, "", nil
+		return false, "", nil, nil
 	}
 
 	userID, err := getUserIDFromClaim(claims, opts.IdentityBaseField)
 	if err != nil {
-		return false, "", err
+		return false, "", nil, err
 	}
 
-	return true, userID, nil
+	return true, userID, claims, nil
 }
 
 // generateVirtualSessionFor generates a virtual session for the given access token by using its identifier.
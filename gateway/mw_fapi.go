@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+)
+
+// fapiAccessTokenParam is the query parameter of RFC 6750 query string access tokens.
+const fapiAccessTokenParam = "access_token"
+
+var errFAPIQueryStringToken = errors.New("Access tokens are not allowed in the query string")
+
+// FAPIMiddleware enforces the request requirements of the FAPI profile which are not
+// enforced by the authentication middlewares: required headers, the interaction ID and no
+// access tokens in the query string.
+type FAPIMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *FAPIMiddleware) Name() string {
+	return "FAPIMiddleware"
+}
+
+func (m *FAPIMiddleware) EnabledForSpec() bool {
+	return m.Spec.FAPI.Enabled
+}
+
+func (m *FAPIMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	if r.URL.Query().Has(fapiAccessTokenParam) {
+		return errFAPIQueryStringToken, http.StatusBadRequest
+	}
+
+	for _, name := range m.Spec.FAPI.RequiredHeaders {
+		if r.Header.Get(name) == "" {
+			return fmt.Errorf("Header %s is required", name), http.StatusBadRequest
+		}
+	}
+
+	// the interaction ID identifies the request for both the client and the upstream
+	interactionID := r.Header.Get(header.XFAPIInteractionID)
+	if interactionID == "" {
+		interactionID = uuid.New()
+	} else if !uuid.Valid(interactionID) {
+		return fmt.Errorf("Header %s must be a UUID", header.XFAPIInteractionID), http.StatusBadRequest
+	}
+
+	r.Header.Set(header.XFAPIInteractionID, interactionID)
+	w.Header().Set(header.XFAPIInteractionID, interactionID)
+
+	return nil, http.StatusOK
+}
+
+// assertFAPISigningMethod asserts a token is signed with one of the algorithms allowed by
+// the FAPI profile.
+func assertFAPISigningMethod(token *jwt.Token) error {
+	switch token.Method {
+	case jwt.SigningMethodPS256, jwt.SigningMethodES256:
+		return nil
+	default:
+		return fmt.Errorf("%v: %v and not PS256 or ES256 signature", UnexpectedSigningMethod, token.Header["alg"])
+	}
+}
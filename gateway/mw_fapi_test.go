@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestFAPIMiddleware(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec, rs256Token := ts.prepareGenericJWTSession(t.Name(), RSASign, KID, false)
+	spec.FAPI.Enabled = true
+	spec.FAPI.RequiredHeaders = []string{"x-fapi-auth-date"}
+	ts.Gw.LoadAPI(spec)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodPS256, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+	token.Header[KID] = testKey(t.Name(), "token")
+	signKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(jwtRSAPrivKey))
+	require.NoError(t, err)
+	ps256Token, err := token.SignedString(signKey)
+	require.NoError(t, err)
+
+	headers := func(token string) map[string]string {
+		return map[string]string{header.Authorization: token, "x-fapi-auth-date": "Tue, 11 Sep 2012 19:43:31 GMT"}
+	}
+
+	t.Run("PS256 token", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{Headers: headers(ps256Token), Code: http.StatusOK})
+		assert.True(t, uuid.Valid(resp.Header.Get(header.XFAPIInteractionID)))
+	})
+
+	t.Run("interaction ID is echoed", func(t *testing.T) {
+		interactionID := uuid.New()
+		reqHeaders := headers(ps256Token)
+		reqHeaders[header.XFAPIInteractionID] = interactionID
+
+		_, _ = ts.Run(t, test.TestCase{Headers: reqHeaders, Code: http.StatusOK,
+			HeadersMatch: map[string]string{header.XFAPIInteractionID: interactionID}})
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Headers: headers(rs256Token), Code: http.StatusForbidden},
+		{Headers: map[string]string{header.Authorization: ps256Token}, Code: http.StatusBadRequest,
+			BodyMatch: "Header x-fapi-auth-date is required"},
+		{Headers: map[string]string{header.Authorization: ps256Token, "x-fapi-auth-date": "now", header.XFAPIInteractionID: "1"},
+			Code: http.StatusBadRequest},
+		{Path: "/?access_token=" + ps256Token, Headers: headers(ps256Token), Code: http.StatusBadRequest},
+	}...)
+}
+
+func TestFAPIValidation(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	api := BuildAPI(func(spec *APISpec) {
+		spec.APIID = "fapi"
+		spec.UseKeylessAccess = false
+		spec.EnableJWT = true
+		spec.JWTSigningMethod = HMACSign
+		spec.UseMutualTLSAuth = true
+		spec.FAPI.Enabled = true
+	})[0]
+
+	_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/apis", Data: api.APIDefinition,
+		Code: http.StatusBadRequest, BodyMatch: "FAPI profile requires rsa or ecdsa signing method"})
+}
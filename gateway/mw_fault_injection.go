@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/httputil"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+// compiledFault is the compiled form of apidef.Fault.
+type compiledFault struct {
+	apidef.Fault
+	// path is nil when the fault applies to every path.
+	path *URLSpec
+}
+
+func compileFaults(conf apidef.FaultInjection, gwConf config.Config, logger *logrus.Entry) []compiledFault {
+	var faults []compiledFault
+
+	for _, fault := range conf.Faults {
+		compiled := compiledFault{Fault: fault}
+		compiled.Method = strings.ToUpper(strings.TrimSpace(fault.Method))
+
+		if fault.Path != "" {
+			path, err := compileEndpointPath(fault.Path, gwConf)
+			if err != nil {
+				logger.WithError(err).WithField("path", fault.Path).Error("Couldn't compile fault injection path")
+				continue
+			}
+
+			compiled.path = path
+		}
+
+		faults = append(faults, compiled)
+	}
+
+	return faults
+}
+
+// compileEndpointPath compiles an endpoint path of a per-endpoint feature, matched the same
+// way as the extended paths.
+func compileEndpointPath(path string, gwConf config.Config) (*URLSpec, error) {
+	pattern := httputil.PreparePathRegexp(path, gwConf.HttpServerOptions.EnablePathPrefixMatching, gwConf.HttpServerOptions.EnablePathSuffixMatching)
+	if gwConf.IgnoreEndpointCase {
+		pattern = "(?i)" + pattern
+	}
+
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &URLSpec{spec: rx}, nil
+}
+
+func (f *compiledFault) matches(r *http.Request, api *APISpec) bool {
+	if f.Method != "" && f.Method != r.Method {
+		return false
+	}
+
+	return f.path == nil || f.path.matchesPath(r.URL.Path, api)
+}
+
+// FaultInjectionMiddleware injects delays, error responses and connection resets into
+// a share of the requests, for chaos experiments at the Gateway layer.
+type FaultInjectionMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *FaultInjectionMiddleware) Name() string {
+	return "FaultInjectionMiddleware"
+}
+
+// EnabledForSpec doesn't check FaultInjection.Enabled, so the faults can be switched on
+// at runtime through the Gateway API without reloading the API.
+func (m *FaultInjectionMiddleware) EnabledForSpec() bool {
+	return len(m.Spec.faults) > 0
+}
+
+func (m *FaultInjectionMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	if !m.Gw.faultInjectionEnabled(m.Spec) {
+		return nil, http.StatusOK
+	}
+
+	for i := range m.Spec.faults {
+		fault := &m.Spec.faults[i]
+		if !fault.matches(r, m.Spec) {
+			continue
+		}
+
+		if mathrand.Float64()*100 >= fault.Percentage {
+			return nil, http.StatusOK
+		}
+
+		return m.inject(w, r, fault)
+	}
+
+	return nil, http.StatusOK
+}
+
+func (m *FaultInjectionMiddleware) inject(w http.ResponseWriter, r *http.Request, fault *compiledFault) (error, int) {
+	logger := m.Logger().WithFields(logrus.Fields{
+		"path":   r.URL.Path,
+		"method": r.Method,
+	})
+
+	if fault.Delay > 0 {
+		logger.WithField("delay", fault.Delay).Debug("Injecting delay")
+
+		select {
+		case <-time.After(time.Duration(fault.Delay) * time.Millisecond):
+		case <-r.Context().Done():
+			return r.Context().Err(), http.StatusGatewayTimeout
+		}
+	}
+
+	if fault.ResetConnection {
+		logger.Debug("Injecting connection reset")
+
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			logger.Warning("Connection can't be reset, injecting error response instead")
+			return errors.New("Fault injected"), http.StatusBadGateway
+		}
+
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			logger.WithError(err).Warning("Connection can't be reset, injecting error response instead")
+			return errors.New("Fault injected"), http.StatusBadGateway
+		}
+
+		conn.Close()
+		return nil, mwStatusRespond
+	}
+
+	if fault.StatusCode > 0 {
+		logger.WithField("status_code", fault.StatusCode).Debug("Injecting error response")
+		return errors.New("Fault injected"), fault.StatusCode
+	}
+
+	return nil, http.StatusOK
+}
+
+// faultInjectionEnabled reports whether fault injection is active for the API. The setting
+// of the API definition applies unless it was overridden through the Gateway API.
+func (gw *Gateway) faultInjectionEnabled(spec *APISpec) bool {
+	if enabled, ok := gw.faultInjectionToggles.Load(spec.APIID); ok {
+		return enabled.(bool)
+	}
+
+	return spec.FaultInjection.Enabled
+}
+
+type faultInjectionStatus struct {
+	APIID   string `json:"api_id"`
+	Enabled bool   `json:"enabled"`
+	// Overridden is true when the setting of the API definition is overridden at runtime.
+	Overridden bool `json:"overridden"`
+}
+
+// faultInjectionHandler reads and toggles fault injection of an API at runtime. The
+// toggle is local to the Gateway node and is lost on restart; DELETE restores the
+// setting of the API definition.
+func (gw *Gateway) faultInjectionHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var req struct {
+			Enabled bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+			return
+		}
+
+		gw.faultInjectionToggles.Store(apiID, req.Enabled)
+		log.WithFields(logrus.Fields{
+			"prefix":  "api",
+			"api_id":  apiID,
+			"enabled": req.Enabled,
+		}).Info("Fault injection toggled")
+	case http.MethodDelete:
+		gw.faultInjectionToggles.Delete(apiID)
+	}
+
+	_, overridden := gw.faultInjectionToggles.Load(apiID)
+	doJSONWrite(w, http.StatusOK, faultInjectionStatus{
+		APIID:      apiID,
+		Enabled:    gw.faultInjectionEnabled(spec),
+		Overridden: overridden,
+	})
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestFaultInjectionMiddleware(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "chaos"
+		spec.Proxy.ListenPath = "/"
+		spec.FaultInjection = apidef.FaultInjection{
+			Enabled: true,
+			Faults: []apidef.Fault{
+				{Path: "/error", Method: http.MethodGet, Percentage: 100, StatusCode: http.StatusServiceUnavailable},
+				{Path: "/slow", Percentage: 100, Delay: 100},
+				{Path: "/reset", Percentage: 100, ResetConnection: true},
+				{Path: "/never", Percentage: 0, StatusCode: http.StatusInternalServerError},
+			},
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodGet, Path: "/error", Code: http.StatusServiceUnavailable, BodyMatch: "Fault injected"},
+			{Method: http.MethodPost, Path: "/error", Code: http.StatusOK},
+			{Path: "/never", Code: http.StatusOK},
+			{Path: "/other", Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		start := time.Now()
+		_, _ = ts.Run(t, test.TestCase{Path: "/slow", Code: http.StatusOK})
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("connection reset", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/reset")
+		if err == nil {
+			resp.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("runtime toggle", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPut, Path: "/tyk/faults/chaos", Data: `{"enabled": false}`, AdminAuth: true,
+				Code: http.StatusOK, BodyMatch: `"enabled":false,"overridden":true`},
+			{Path: "/error", Code: http.StatusOK},
+			{Method: http.MethodDelete, Path: "/tyk/faults/chaos", AdminAuth: true,
+				Code: http.StatusOK, BodyMatch: `"enabled":true,"overridden":false`},
+			{Path: "/error", Code: http.StatusServiceUnavailable},
+			{Method: http.MethodGet, Path: "/tyk/faults/unknown", AdminAuth: true, Code: http.StatusNotFound},
+		}...)
+	})
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+var (
+	errHeaderDuplicate  = errors.New("Duplicate request headers are not allowed")
+	errHeaderUnderscore = errors.New("Request header names with underscores are not allowed")
+	errHeaderTooMany    = errors.New("Too many request headers")
+	errHeaderTooLarge   = errors.New("Request header is too large")
+)
+
+// HeaderPolicyMiddleware validates and normalizes request headers, before the gateway
+// reads them, so that an upstream can't interpret them differently.
+type HeaderPolicyMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *HeaderPolicyMiddleware) Name() string {
+	return "HeaderPolicyMiddleware"
+}
+
+func (m *HeaderPolicyMiddleware) EnabledForSpec() bool {
+	return m.Spec.HeaderPolicy.Enabled
+}
+
+func (m *HeaderPolicyMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	policy := m.Spec.HeaderPolicy
+
+	if policy.CanonicalizeKeys {
+		canonicalizeHeaderKeys(r.Header)
+	}
+
+	count := 0
+	for name, values := range r.Header {
+		if policy.RejectUnderscores && strings.Contains(name, "_") {
+			return errHeaderUnderscore, http.StatusBadRequest
+		}
+
+		count += len(values)
+		if policy.MaxCount > 0 && count > policy.MaxCount {
+			return errHeaderTooMany, http.StatusRequestHeaderFieldsTooLarge
+		}
+
+		if policy.MaxSize > 0 {
+			for _, value := range values {
+				if len(name)+len(value) > policy.MaxSize {
+					return errHeaderTooLarge, http.StatusRequestHeaderFieldsTooLarge
+				}
+			}
+		}
+	}
+
+	if err := applyHeaderDuplicatesPolicy(r.Header, policy.Duplicates); err != nil {
+		return err, http.StatusBadRequest
+	}
+
+	return nil, http.StatusOK
+}
+
+// canonicalizeHeaderKeys moves the values of non-canonical keys to their canonical key.
+func canonicalizeHeaderKeys(h http.Header) {
+	for name, values := range h {
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+		if canonical == name {
+			continue
+		}
+
+		delete(h, name)
+		h[canonical] = append(h[canonical], values...)
+	}
+}
+
+func applyHeaderDuplicatesPolicy(h http.Header, policy apidef.HeaderDuplicatesPolicy) error {
+	if policy == "" {
+		return nil
+	}
+
+	for name, values := range h {
+		if len(values) < 2 {
+			continue
+		}
+
+		switch policy {
+		case apidef.HeaderDuplicatesReject:
+			return errHeaderDuplicate
+		case apidef.HeaderDuplicatesMerge:
+			separator := ", "
+			if name == "Cookie" {
+				separator = "; "
+			}
+			h[name] = []string{strings.Join(values, separator)}
+		case apidef.HeaderDuplicatesFirstWins:
+			h[name] = values[:1]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestHeaderPolicy(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(r.Header)
+	}))
+	defer upstream.Close()
+
+	load := func(policy apidef.HeaderPolicy) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.HeaderPolicy = policy
+		})
+	}
+
+	duplicates := func(t *testing.T, code int) http.Header {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/", nil)
+		require.NoError(t, err)
+		req.Header.Add("X-Forwarded-Id", "a")
+		req.Header.Add("X-Forwarded-Id", "b")
+		req.Header.Add("Cookie", "a=1")
+		req.Header.Add("Cookie", "b=2")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, code, resp.StatusCode)
+
+		received := http.Header{}
+		_ = json.NewDecoder(resp.Body).Decode(&received)
+		return received
+	}
+
+	t.Run("limits", func(t *testing.T) {
+		load(apidef.HeaderPolicy{Enabled: true, MaxCount: 10, MaxSize: 64, RejectUnderscores: true})
+
+		many := map[string]string{}
+		for i := 0; i < 10; i++ {
+			many["X-Header-"+string(rune('a'+i))] = "value"
+		}
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: map[string]string{"X-Small": "value"}, Code: http.StatusOK},
+			{Path: "/", Headers: map[string]string{"X-Large": strings.Repeat("v", 64)}, Code: http.StatusRequestHeaderFieldsTooLarge},
+			{Path: "/", Headers: many, Code: http.StatusRequestHeaderFieldsTooLarge},
+			{Path: "/", Headers: map[string]string{"X_Api_Key": "value"}, Code: http.StatusBadRequest},
+		}...)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		load(apidef.HeaderPolicy{Enabled: true})
+		received := duplicates(t, http.StatusOK)
+		assert.Equal(t, []string{"a", "b"}, received.Values("X-Forwarded-Id"))
+
+		load(apidef.HeaderPolicy{Enabled: true, Duplicates: apidef.HeaderDuplicatesReject})
+		duplicates(t, http.StatusBadRequest)
+
+		load(apidef.HeaderPolicy{Enabled: true, Duplicates: apidef.HeaderDuplicatesMerge})
+		received = duplicates(t, http.StatusOK)
+		assert.Equal(t, []string{"a, b"}, received.Values("X-Forwarded-Id"))
+		assert.Equal(t, "a=1; b=2", received.Get("Cookie"))
+
+		load(apidef.HeaderPolicy{Enabled: true, Duplicates: apidef.HeaderDuplicatesFirstWins})
+		received = duplicates(t, http.StatusOK)
+		assert.Equal(t, []string{"a"}, received.Values("X-Forwarded-Id"))
+	})
+}
+
+func TestCanonicalizeHeaderKeys(t *testing.T) {
+	h := http.Header{"x-custom": {"a"}, "X-Custom": {"b"}, "Accept": {"*/*"}}
+	canonicalizeHeaderKeys(h)
+
+	assert.ElementsMatch(t, []string{"a", "b"}, h["X-Custom"])
+	assert.Equal(t, []string{"*/*"}, h["Accept"])
+	assert.Len(t, h, 2)
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	inboundDedupPrefix     = "inbound-dedup."
+	defaultInboundDedupTTL = 300
+)
+
+// InboundDedupMiddleware drops the duplicates of the requests received within a TTL, for the
+// webhook providers delivering at least once. The requests are identified by a hash of their
+// method, URL, consumer, body and selected headers, claimed in Redis with SETNX. A claim is
+// released when the request isn't handled successfully, so the provider's retry goes through.
+type InboundDedupMiddleware struct {
+	*BaseMiddleware
+
+	conf  apidef.InboundDedup
+	store *storage.RedisCluster
+}
+
+func (m *InboundDedupMiddleware) Name() string {
+	return "InboundDedupMiddleware"
+}
+
+func (m *InboundDedupMiddleware) EnabledForSpec() bool {
+	conf, enabled := m.Gw.inboundDedupSettings(m.Spec)
+	if !enabled {
+		return false
+	}
+
+	m.conf = conf
+	m.store = &storage.RedisCluster{ConnectionHandler: m.Gw.StorageConnectionHandler}
+	return true
+}
+
+// inboundDedupSettings returns the deduplication settings of an API, its unset fields filled
+// from the Gateway settings, and whether the deduplication is enabled for the API.
+func (gw *Gateway) inboundDedupSettings(spec *APISpec) (apidef.InboundDedup, bool) {
+	global := gw.GetConfig().InboundDedup
+	conf := spec.InboundDedup
+
+	if conf.Disabled || (!conf.Enabled && !global.EnableAllAPIs) {
+		return conf, false
+	}
+
+	if len(conf.Headers) == 0 {
+		conf.Headers = global.Headers
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = global.TTL
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = defaultInboundDedupTTL
+	}
+	if conf.DuplicateStatusCode == 0 {
+		conf.DuplicateStatusCode = global.DuplicateStatusCode
+	}
+	if conf.DuplicateStatusCode == 0 {
+		conf.DuplicateStatusCode = http.StatusOK
+	}
+
+	return conf, true
+}
+
+func (m *InboundDedupMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	body, err := readBody(r)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	r.Body, _ = copyBody(r.Body, false)
+
+	key := inboundDedupPrefix + m.Spec.APIID + "." + m.requestHash(r, body)
+
+	claimed, err := m.store.Lock(key, time.Duration(m.conf.TTL)*time.Second)
+	if err != nil {
+		// fail open, a duplicate is better than a lost delivery
+		m.Logger().WithError(err).Warning("Couldn't check the request for duplicates")
+		return nil, http.StatusOK
+	}
+
+	if !claimed {
+		m.Logger().WithField("path", r.URL.Path).Debug("Dropping duplicate request")
+
+		w.Header().Set(header.XTykDuplicate, "true")
+		w.WriteHeader(m.conf.DuplicateStatusCode)
+		return nil, mwStatusRespond
+	}
+
+	setCtxValue(r, ctx.InboundDedupKey, key)
+	return nil, http.StatusOK
+}
+
+// requestHash hashes the method, path, query, consumer, selected headers and body of a request.
+func (m *InboundDedupMiddleware) requestHash(r *http.Request, body []byte) string {
+	names := make([]string, 0, len(m.conf.Headers))
+	for _, name := range m.conf.Headers {
+		names = append(names, textproto.CanonicalMIMEHeaderKey(name))
+	}
+	sort.Strings(names)
+
+	// the requests of different consumers are never duplicates
+	var consumer string
+	if session := ctxGetSession(r); session != nil {
+		consumer = session.KeyID
+		if !session.KeyHashEmpty() {
+			consumer = session.KeyHash()
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.Query().Encode() + "\n"))
+	h.Write([]byte(consumer + "\n"))
+	for _, name := range names {
+		for _, value := range r.Header.Values(name) {
+			h.Write([]byte(name + ": " + value + "\n"))
+		}
+	}
+	h.Write([]byte{'\n'})
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// releaseInboundDedupKey releases the deduplication claim of a request which wasn't handled
+// successfully, rejected by the middleware chain or by the upstream, so the retries of the
+// provider aren't dropped. A nil response releases the claim.
+func (gw *Gateway) releaseInboundDedupKey(r *http.Request, res *http.Response) {
+	key, ok := r.Context().Value(ctx.InboundDedupKey).(string)
+	if !ok {
+		return
+	}
+
+	if res != nil && res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
+		return
+	}
+
+	store := &storage.RedisCluster{ConnectionHandler: gw.StorageConnectionHandler}
+	store.DeleteKey(key)
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestInboundDedupMiddleware(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.InboundDedup.Headers = []string{"X-Delivery-Id"}
+	})
+	defer ts.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "webhooks"
+		spec.Proxy.ListenPath = "/webhooks/"
+		spec.InboundDedup = apidef.InboundDedup{Enabled: true, DuplicateStatusCode: http.StatusAccepted}
+	}, func(spec *APISpec) {
+		spec.APIID = "failing"
+		spec.Proxy.ListenPath = "/failing/"
+		spec.Proxy.TargetURL = failing.URL
+		spec.InboundDedup = apidef.InboundDedup{Enabled: true}
+	}, func(spec *APISpec) {
+		spec.APIID = "limited"
+		spec.Proxy.ListenPath = "/limited/"
+		spec.GlobalRateLimit = apidef.GlobalRateLimit{Rate: 1, Per: 60}
+		spec.InboundDedup = apidef.InboundDedup{Enabled: true}
+	}, func(spec *APISpec) {
+		spec.APIID = "keyed"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/keyed/"
+		spec.InboundDedup = apidef.InboundDedup{Enabled: true}
+	}, func(spec *APISpec) {
+		spec.APIID = "plain"
+		spec.Proxy.ListenPath = "/plain/"
+	})
+
+	body := uuid.NewHex()
+	delivery := map[string]string{"X-Delivery-Id": "1"}
+	duplicate := map[string]string{header.XTykDuplicate: "true"}
+
+	t.Run("duplicates are dropped", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/webhooks/event", Data: body, Headers: delivery, Code: http.StatusOK, HeadersNotMatch: duplicate},
+			{Method: http.MethodPost, Path: "/webhooks/event", Data: body, Headers: delivery, Code: http.StatusAccepted, HeadersMatch: duplicate},
+			{Method: http.MethodPost, Path: "/webhooks/event", Data: body, Headers: map[string]string{"X-Delivery-Id": "2"}, Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/webhooks/other", Data: body, Headers: delivery, Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/webhooks/event?attempt=1", Data: body, Headers: delivery, Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/webhooks/event?attempt=2", Data: body, Headers: delivery, Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("deliveries rejected by the middlewares are released", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/limited/event", Data: body, Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/limited/event", Data: "other", Code: http.StatusTooManyRequests},
+			{Method: http.MethodPost, Path: "/limited/event", Data: "other", Code: http.StatusTooManyRequests, HeadersNotMatch: duplicate},
+		}...)
+	})
+
+	t.Run("consumers are deduplicated separately", func(t *testing.T) {
+		createKey := func() map[string]string {
+			_, key := ts.CreateSession(func(s *user.SessionState) {
+				s.AccessRights = map[string]user.AccessDefinition{"keyed": {APIID: "keyed"}}
+			})
+			return map[string]string{header.Authorization: key}
+		}
+		first, second := createKey(), createKey()
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/keyed/event", Data: body, Headers: first, Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/keyed/event", Data: body, Headers: second, Code: http.StatusOK, HeadersNotMatch: duplicate},
+			{Method: http.MethodPost, Path: "/keyed/event", Data: body, Headers: first, Code: http.StatusOK, HeadersMatch: duplicate},
+		}...)
+	})
+
+	t.Run("failed deliveries are released", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/failing/event", Data: body, Code: http.StatusInternalServerError},
+			{Method: http.MethodPost, Path: "/failing/event", Data: body, Code: http.StatusInternalServerError, HeadersNotMatch: duplicate},
+		}...)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/plain/event", Data: body, Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/plain/event", Data: body, Code: http.StatusOK},
+		}...)
+	})
+}
+
+func TestInboundDedupSettings(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.InboundDedup = config.InboundDedupConfig{EnableAllAPIs: true, Headers: []string{"X-Delivery-Id"}, TTL: 60}
+	})
+	defer ts.Close()
+
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{}}
+	conf, enabled := ts.Gw.inboundDedupSettings(spec)
+	if !enabled || conf.TTL != 60 || conf.DuplicateStatusCode != http.StatusOK || len(conf.Headers) != 1 {
+		t.Errorf("unexpected settings %+v, enabled %v", conf, enabled)
+	}
+
+	spec.InboundDedup = apidef.InboundDedup{Headers: []string{"X-Id"}, TTL: 10}
+	if conf, _ = ts.Gw.inboundDedupSettings(spec); conf.TTL != 10 || conf.Headers[0] != "X-Id" {
+		t.Errorf("the API settings should apply, got %+v", conf)
+	}
+
+	spec.InboundDedup.Disabled = true
+	if _, enabled = ts.Gw.inboundDedupSettings(spec); enabled {
+		t.Error("the API opted out")
+	}
+}
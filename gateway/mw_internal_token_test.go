@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/crypto"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestInternalTokenMiddleware(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	_, _, combinedPEM, _ := crypto.GenServerCertificate()
+	certID, _ := ts.Gw.CertificateManager.Add(combinedPEM, "")
+	defer ts.Gw.CertificateManager.Delete(certID, "")
+
+	privateKey, err := ts.Gw.signingPrivateKey(certID)
+	assert.NoError(t, err)
+
+	loadAPI := func(conf apidef.InternalToken) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.UseKeylessAccess = false
+			spec.Proxy.ListenPath = "/"
+			spec.InternalToken = conf
+		})
+	}
+
+	key := CreateSession(ts.Gw, func(s *user.SessionState) {
+		s.Alias = "user-1"
+		s.MetaData = map[string]interface{}{"tier": "gold", "secret": "hidden"}
+	})
+	authHeaders := map[string]string{header.Authorization: key}
+
+	upstreamToken := func(t *testing.T, headerName string) func([]byte) bool {
+		t.Helper()
+		return func(body []byte) bool {
+			var resp TestHttpResponse
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return false
+			}
+
+			raw := strings.TrimPrefix(resp.Headers[headerName], "Bearer ")
+			claims := jwt.MapClaims{}
+			token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+				assert.Equal(t, "current", token.Header["kid"])
+				return &privateKey.PublicKey, nil
+			}, jwt.WithValidMethods([]string{"RS256"}))
+			if err != nil || !token.Valid {
+				return false
+			}
+
+			assert.Equal(t, "user-1", claims["sub"])
+			assert.Equal(t, "tyk", claims["iss"])
+			assert.Equal(t, "gold", claims["tier"])
+			assert.NotContains(t, claims, "secret")
+			return true
+		}
+	}
+
+	t.Run("no signing key", func(t *testing.T) {
+		loadAPI(apidef.InternalToken{Enabled: true})
+		_, _ = ts.Run(t, test.TestCase{Headers: authHeaders, Code: http.StatusInternalServerError})
+	})
+
+	globalConf := ts.Gw.GetConfig()
+	globalConf.Security.SigningKeys.Keys = []config.SigningKey{{KeyID: "current", CertificateID: certID}}
+	ts.Gw.SetConfig(globalConf)
+
+	conf := apidef.InternalToken{
+		Enabled:        true,
+		Issuer:         "tyk",
+		MetadataClaims: map[string]string{"tier": "tier"},
+	}
+
+	t.Run("authorization header", func(t *testing.T) {
+		loadAPI(conf)
+		_, _ = ts.Run(t, test.TestCase{Headers: authHeaders, Code: http.StatusOK, BodyMatchFunc: upstreamToken(t, header.Authorization)})
+	})
+
+	t.Run("custom header", func(t *testing.T) {
+		conf.Header = "X-Internal-Token"
+		loadAPI(conf)
+		_, _ = ts.Run(t, test.TestCase{Headers: authHeaders, Code: http.StatusOK, BodyMatchFunc: upstreamToken(t, "X-Internal-Token")})
+	})
+
+	t.Run("invalid credential", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Headers: map[string]string{header.Authorization: "invalid"}, Code: http.StatusForbidden})
+	})
+}
@@ -51,7 +51,7 @@ func (i *IPBlackListMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Re
 func (i *IPBlackListMiddleware) handleError(r *http.Request, blacklistedIP string) (error, int) {
 
 	// Fire Authfailed Event
-	AuthFailed(i, r, blacklistedIP)
+	fireAuthFailure(i, r, blacklistedIP)
 	// Report in health check
 	reportHealthValue(i.Spec, KeyFailure, "-1")
 	return errors.New("access from this IP has been disallowed"), http.StatusForbidden
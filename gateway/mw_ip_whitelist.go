@@ -47,7 +47,7 @@ func (i *IPWhiteListMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Re
 	}
 
 	// Fire Authfailed Event
-	AuthFailed(i, r, remoteIP.String())
+	fireAuthFailure(i, r, remoteIP.String())
 	// Report in health check
 	reportHealthValue(i.Spec, KeyFailure, "-1")
 
@@ -20,6 +20,7 @@ import (
 	"github.com/lonelycode/osin"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/certs"
 	"github.com/TykTechnologies/tyk/storage"
 	"github.com/TykTechnologies/tyk/user"
 
@@ -683,8 +684,21 @@ func (k *JWTMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _
 		return errors.New("Authorization field missing"), http.StatusBadRequest
 	}
 
-	// enable bearer token format
-	rawJWT = stripBearer(rawJWT)
+	// enable bearer and DPoP token formats
+	authorization := rawJWT
+	rawJWT = stripDPoP(stripBearer(rawJWT))
+	accessToken := rawJWT
+
+	// encrypted tokens have five parts, the payload being the signed token
+	if strings.Count(rawJWT, ".") == 4 {
+		nestedJWT, err := k.decryptJWE(rawJWT)
+		if err != nil {
+			logger.WithError(err).Info("Attempted access with an encrypted JWT which could not be decrypted.")
+			k.reportLoginFailure(tykId, r)
+			return errors.New("Key not authorized"), http.StatusForbidden
+		}
+		rawJWT = nestedJWT
+	}
 
 	// Use own validation logic, see below
 	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
@@ -692,7 +706,11 @@ func (k *JWTMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _
 	// Verify the token
 	token, err := parser.Parse(rawJWT, func(token *jwt.Token) (interface{}, error) {
 		// Don't forget to validate the alg is what you expect:
-		if err := assertSigningMethod(k.Spec.JWTSigningMethod, token); err != nil {
+		if k.Spec.FAPI.Enabled {
+			if err := assertFAPISigningMethod(token); err != nil {
+				return nil, err
+			}
+		} else if err := assertSigningMethod(k.Spec.JWTSigningMethod, token); err != nil {
 			return nil, err
 		}
 
@@ -710,6 +728,16 @@ func (k *JWTMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _
 			return errors.New("Key not authorized: " + jwtErr.Error()), http.StatusUnauthorized
 		}
 
+		if err, code := k.Gw.checkDPoP(k.Spec, w, r, authorization, accessToken, token.Claims.(jwt.MapClaims)); err != nil {
+			k.reportLoginFailure(tykId, r)
+			return err, code
+		}
+
+		if err, code := checkResourceAudience(k.Spec, w, token.Claims.(jwt.MapClaims)); err != nil {
+			k.Logger().WithError(err).Info("Attempted access with token for another resource")
+			return err, code
+		}
+
 		// Token is valid - let's move on
 
 		// Are we mapping to a central JWT Secret?
@@ -733,6 +761,39 @@ func (k *JWTMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _
 	return errors.New("Key not authorized"), http.StatusForbidden
 }
 
+// decryptJWE decrypts an encrypted JWT with the first configured key able to, and returns
+// the nested signed JWT.
+func (k *JWTMiddleware) decryptJWE(rawJWE string) (string, error) {
+	if len(k.Spec.JWTDecryptionKeys) == 0 {
+		return "", errors.New("no decryption keys configured")
+	}
+
+	jwe, err := jose.ParseEncrypted(rawJWE)
+	if err != nil {
+		return "", err
+	}
+
+	for _, cert := range k.Gw.CertificateManager.List(k.Spec.JWTDecryptionKeys, certs.CertificatePrivate) {
+		if cert == nil || cert.PrivateKey == nil {
+			continue
+		}
+
+		payload, err := jwe.Decrypt(cert.PrivateKey)
+		if err != nil {
+			continue
+		}
+
+		nestedJWT := strings.TrimSpace(string(payload))
+		if strings.Count(nestedJWT, ".") != 2 {
+			return "", errors.New("encrypted token does not contain a signed JWT")
+		}
+
+		return nestedJWT, nil
+	}
+
+	return "", errors.New("no key could decrypt the token")
+}
+
 func ParseRSAPublicKey(data []byte) (interface{}, error) {
 	input := data
 	block, _ := pem.Decode(data)
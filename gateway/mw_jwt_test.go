@@ -2,6 +2,9 @@ package gateway
 
 import (
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,10 +14,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-jose/go-jose/v3"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/certs"
 	"github.com/TykTechnologies/tyk/test"
 	"github.com/TykTechnologies/tyk/user"
 
@@ -2426,3 +2431,54 @@ func Test_getOAuthClientIDFromClaim(t *testing.T) {
 		})
 	}
 }
+
+func TestJWTSessionJWE(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	_, _, combinedPEM, tlsCert := certs.GenCertificate(&x509.Certificate{}, false)
+	certID, err := ts.Gw.CertificateManager.Add(combinedPEM, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Gw.CertificateManager.Delete(certID, "")
+
+	spec, jwtToken := ts.prepareGenericJWTSession(t.Name(), HMACSign, KID, false)
+
+	encrypt := func(key interface{}, payload string) string {
+		encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: jose.RSA_OAEP_256, Key: key},
+			(&jose.EncrypterOptions{}).WithContentType("JWT"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		jwe, err := encrypter.Encrypt([]byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		serialized, err := jwe.CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return serialized
+	}
+
+	publicKey := &tlsCert.PrivateKey.(*rsa.PrivateKey).PublicKey
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	t.Run("no decryption keys", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			Headers: map[string]string{"authorization": encrypt(publicKey, jwtToken)}, Code: http.StatusForbidden,
+		})
+	})
+
+	spec.JWTDecryptionKeys = []string{certID}
+	ts.Gw.LoadAPI(spec)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Headers: map[string]string{"authorization": "Bearer " + encrypt(publicKey, jwtToken)}, Code: http.StatusOK},
+		{Headers: map[string]string{"authorization": encrypt(&otherKey.PublicKey, jwtToken)}, Code: http.StatusForbidden},
+		{Headers: map[string]string{"authorization": encrypt(publicKey, `{"sub": "unsigned"}`)}, Code: http.StatusForbidden},
+	}...)
+}
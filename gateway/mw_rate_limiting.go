@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"errors"
+	"math"
 	"net/http"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 
 	"github.com/TykTechnologies/tyk/internal/event"
 	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/user"
 )
 
 // RateLimitAndQuotaCheck will check the incomming request and key whether it is within it's quota and
@@ -42,6 +44,58 @@ func (k *RateLimitAndQuotaCheck) handleQuotaFailure(r *http.Request, token strin
 	return errors.New("Quota exceeded"), http.StatusForbidden
 }
 
+// handleQuotaThresholds fires the QuotaThresholdReached events of the thresholds the request
+// made the quota usage of the key reach. The quota counter is incremented once per request,
+// so a threshold is reached by a single request of each quota period across the Gateways.
+func (k *RateLimitAndQuotaCheck) handleQuotaThresholds(r *http.Request, session *user.SessionState, token string) {
+	if !k.Spec.QuotaThresholds.Enabled || k.Spec.DisableQuota {
+		return
+	}
+
+	accessDef, _, err := GetAccessDefinitionByAPIIDOrSession(session, k.Spec)
+	if err != nil || accessDef.Limit.QuotaMax <= 0 {
+		return
+	}
+
+	quotaMax, _, _, _ := quotaPeriod(session, &accessDef.Limit, time.Now())
+	_, remaining, _, renews := session.GetQuotaLimitByAPIID(k.Spec.APIID)
+	used := quotaMax - remaining
+
+	for _, threshold := range k.Spec.QuotaThresholds.Thresholds {
+		if used != quotaThresholdUsage(quotaMax, threshold) {
+			continue
+		}
+
+		k.Logger().WithField("key", k.Gw.obfuscateKey(token)).WithField("threshold", threshold).Info("Key quota threshold reached.")
+
+		k.FireEvent(EventQuotaThresholdReached, EventQuotaThresholdMeta{
+			EventMetaDefault: EventMetaDefault{Message: "Key Quota Threshold Reached", OriginatingRequest: EncodeRequestToEvent(r)},
+			Path:             r.URL.Path,
+			Origin:           request.RealIP(r),
+			Key:              token,
+			APIID:            k.Spec.APIID,
+			Threshold:        threshold,
+			QuotaMax:         quotaMax,
+			QuotaRemaining:   remaining,
+			QuotaRenews:      renews,
+		})
+	}
+}
+
+// quotaThresholdUsage returns the quota usage reaching the threshold percentage of quotaMax,
+// of at least a request.
+func quotaThresholdUsage(quotaMax int64, threshold float64) int64 {
+	// the epsilon keeps percentages like 33.3 of 1000 from rounding up past the exact usage
+	usage := int64(math.Ceil(float64(quotaMax)*threshold/100 - 1e-9))
+	if usage < 1 {
+		usage = 1
+	}
+	if usage > quotaMax {
+		usage = quotaMax
+	}
+	return usage
+}
+
 // ProcessRequest will run any checks on the request on the way through the system, return an error to have the chain fail
 func (k *RateLimitAndQuotaCheck) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
 	if ctxGetRequestStatus(r) == StatusOkAndIgnore {
@@ -95,6 +149,7 @@ func (k *RateLimitAndQuotaCheck) ProcessRequest(w http.ResponseWriter, r *http.R
 
 	switch reason {
 	case sessionFailNone:
+		k.handleQuotaThresholds(r, session, rateLimitKey)
 	case sessionFailRateLimit:
 		err, errCode := k.handleRateLimitFailure(r, event.RateLimitExceeded, "Rate Limit Exceeded", rateLimitKey)
 		if throttleRetryLimit > 0 {
@@ -128,9 +183,15 @@ func (k *RateLimitAndQuotaCheck) ProcessRequest(w http.ResponseWriter, r *http.R
 				}
 			}
 		}
+		if k.Spec.RateLimitHeaders.Enabled {
+			setRateLimitHeaders(w.Header(), k.Spec, session, time.Now(), true)
+		}
 		return err, errCode
 
 	case sessionFailQuota:
+		if k.Spec.RateLimitHeaders.Enabled {
+			setRateLimitHeaders(w.Header(), k.Spec, session, time.Now(), false)
+		}
 		return k.handleQuotaFailure(r, rateLimitKey)
 	case sessionFailInternalServerError:
 		return ProxyingRequestFailedErr, http.StatusInternalServerError
@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -8,6 +9,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TykTechnologies/graphql-go-tools/pkg/graphql"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/header"
 	"github.com/TykTechnologies/tyk/test"
 	"github.com/TykTechnologies/tyk/user"
@@ -102,6 +105,155 @@ func TestNeverRenewQuota(t *testing.T) {
 
 }
 
+func TestQuotaThresholds(t *testing.T) {
+	test.Exclusive(t) // Uses quota, need to limit parallelism due to DeleteAllKeys.
+
+	g := StartTest(nil)
+	defer g.Close()
+
+	api := g.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "quota-thresholds"
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+		spec.QuotaThresholds = apidef.QuotaThresholds{Enabled: true, Thresholds: []float64{50, 100}}
+	})[0]
+
+	reached := make(chan EventQuotaThresholdMeta, 10)
+	api.EventPaths = map[apidef.TykEvent][]config.TykEventHandler{
+		EventQuotaThresholdReached: {&testEventHandler{func(em config.EventMessage) {
+			reached <- em.Meta.(EventQuotaThresholdMeta)
+		}}},
+	}
+
+	_, key := g.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+		s.QuotaMax, s.QuotaRenewalRate = 4, 3600
+	})
+	authHeader := map[string]string{header.Authorization: key}
+
+	_, _ = g.Run(t, []test.TestCase{
+		{Headers: authHeader, Code: http.StatusOK},
+		{Headers: authHeader, Code: http.StatusOK},
+		{Headers: authHeader, Code: http.StatusOK},
+		{Headers: authHeader, Code: http.StatusOK},
+		{Headers: authHeader, Code: http.StatusForbidden},
+	}...)
+
+	var thresholds []float64
+	for len(thresholds) < 2 {
+		select {
+		case meta := <-reached:
+			assert.Equal(t, api.APIID, meta.APIID)
+			assert.Equal(t, int64(4), meta.QuotaMax)
+			assert.Equal(t, key, meta.Key)
+			thresholds = append(thresholds, meta.Threshold)
+		case <-time.After(time.Second):
+			t.Fatalf("quota threshold events not fired, got %v", thresholds)
+		}
+	}
+	assert.ElementsMatch(t, []float64{50, 100}, thresholds)
+
+	select {
+	case meta := <-reached:
+		t.Errorf("unexpected quota threshold event %v", meta.Threshold)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestQuotaThresholdUsage(t *testing.T) {
+	assert.Equal(t, int64(8), quotaThresholdUsage(10, 80))
+	assert.Equal(t, int64(10), quotaThresholdUsage(10, 100))
+	assert.Equal(t, int64(333), quotaThresholdUsage(1000, 33.3))
+	assert.Equal(t, int64(1), quotaThresholdUsage(3, 10))
+}
+
+func TestAtomicRateLimiter(t *testing.T) {
+	test.Exclusive(t) // Uses quota, need to limit parallelism due to DeleteAllKeys.
+
+	g := StartTest(func(globalConf *config.Config) {
+		globalConf.RateLimit.EnableRedisRollingLimiter = true
+		globalConf.RateLimit.EnableAtomicRateLimiter = true
+	})
+	defer g.Close()
+
+	api := g.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "atomic-rate-limiter"
+		spec.Proxy.ListenPath = "/"
+		spec.UseKeylessAccess = false
+	})[0]
+
+	newKey := func(rate float64, quotaMax int64) map[string]string {
+		_, key := g.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{
+				api.APIID: {
+					APIName: api.Name,
+					APIID:   api.APIID,
+					Limit: user.APILimit{
+						RateLimit: user.RateLimit{
+							Rate: rate,
+							Per:  60,
+						},
+						QuotaRenewalRate: 60,
+						QuotaMax:         quotaMax,
+					},
+				},
+			}
+		})
+
+		return map[string]string{header.Authorization: key}
+	}
+
+	t.Run("rate limit", func(t *testing.T) {
+		authHeader := newKey(2, -1)
+
+		_, _ = g.Run(t, []test.TestCase{
+			{Headers: authHeader, Code: http.StatusOK},
+			{Headers: authHeader, Code: http.StatusOK},
+			{Headers: authHeader, Code: http.StatusTooManyRequests},
+		}...)
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		authHeader := newKey(10, 2)
+
+		_, _ = g.Run(t, []test.TestCase{
+			{Headers: authHeader, Code: http.StatusOK, HeadersMatch: map[string]string{header.XRateLimitRemaining: "1"}},
+			{Headers: authHeader, Code: http.StatusOK, HeadersMatch: map[string]string{header.XRateLimitRemaining: "0"}},
+			{Headers: authHeader, Code: http.StatusForbidden},
+		}...)
+	})
+
+	t.Run("throttling dry runs", func(t *testing.T) {
+		_, key := g.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{
+				api.APIID: {
+					APIID: api.APIID,
+					Limit: user.APILimit{
+						RateLimit:          user.RateLimit{Rate: 1, Per: 1},
+						ThrottleInterval:   1.1,
+						ThrottleRetryLimit: 1,
+						QuotaRenewalRate:   60,
+						QuotaMax:           10,
+					},
+				},
+			}
+		})
+		authHeader := map[string]string{header.Authorization: key}
+
+		// the throttled request is retried once the rate allows it, the dry run doesn't count
+		_, _ = g.Run(t, []test.TestCase{
+			{Headers: authHeader, Code: http.StatusOK},
+			{Headers: authHeader, Code: http.StatusOK},
+		}...)
+
+		// the per API limits are counted by API
+		quotaKey := QuotaKeyPrefix + api.APIID + "-" + key
+		used, err := g.Gw.SessionLimiter.limiterStorage.Get(context.Background(), quotaKey).Int64()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), used)
+	})
+}
+
 func TestMwRateLimiting_DepthLimit(t *testing.T) {
 	g := StartTest(nil)
 	defer g.Close()
@@ -55,6 +55,10 @@ func (m *RedisCacheMiddleware) CreateCheckSum(req *http.Request, keyName string,
 	if additionalKeyFromHeaders != "" {
 		key = key + "-" + additionalKeyFromHeaders
 	}
+	// the responses of the upstreams chosen by routes and experiments are cached apart
+	if route := ctxGetUpstreamRoute(req); route != nil {
+		key = key + "-" + route.target.String()
+	}
 
 	_, err := io.WriteString(h, key)
 	if err != nil {
@@ -165,6 +169,12 @@ func (m *RedisCacheMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 		stat = StatusCached
 	}
 
+	// Warmed up endpoints are always cached
+	warmedUp := m.Spec.isCacheWarmedUp(r)
+	if warmedUp {
+		stat = StatusCached
+	}
+
 	if stat != StatusCached {
 		// New request checker, more targeted, less likely to fail
 		found, meta := m.Spec.CheckSpecMatchesStatus(r, versionPaths, Cached)
@@ -186,6 +196,11 @@ func (m *RedisCacheMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 		token = request.RealIP(r)
 	}
 
+	// Warmed up responses of keyless APIs are shared by all clients
+	if warmedUp {
+		token = ""
+	}
+
 	var retBlob string
 	key, err := m.CreateCheckSum(r, token, cacheKeyRegex, m.getCacheKeyFromHeaders(r))
 	if err != nil {
@@ -213,24 +228,61 @@ func (m *RedisCacheMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 		timeout:                timeout,
 	})
 
+	if diagnostics := ctxGetDebugDiagnostics(r); diagnostics != nil {
+		diagnostics.setCacheStatus(debugCacheMiss)
+	}
+
+	// Warm-up requests refresh the cached response
+	if ctxGetCacheWarmUp(r) {
+		return nil, http.StatusOK
+	}
+
 	retBlob, err = m.store.GetKey(key)
 	if err != nil {
 		// Record not found, continue with the middleware chain
-		return nil, http.StatusOK
+		return m.serveStale(w, r, key, t1)
 	}
 
 	cachedData, timestamp, err := m.decodePayload(retBlob)
 	if err != nil {
 		// Tere was an issue with this cache entry - lets remove it:
 		m.store.DeleteKey(key)
-		return nil, http.StatusOK
+		return m.serveStale(w, r, key, t1)
 	}
 
 	if m.isTimeStampExpired(timestamp) || len(cachedData) == 0 {
 		m.store.DeleteKey(key)
+		return m.serveStale(w, r, key, t1)
+	}
+
+	return m.writeCachedResponse(w, r, key, cachedData, t1, false)
+}
+
+// serveStale serves the stale copy of an expired cached response while the upstream of the API
+// is degraded, or continues with the middleware chain.
+func (m *RedisCacheMiddleware) serveStale(w http.ResponseWriter, r *http.Request, key string, t1 time.Time) (error, int) {
+	budget := m.Spec.upstreamErrorBudget
+	if budget == nil || !budget.serveStale() || !budget.isDegraded() {
+		return nil, http.StatusOK
+	}
+
+	staleKey := staleCacheKey(key)
+	retBlob, err := m.store.GetKey(staleKey)
+	if err != nil {
 		return nil, http.StatusOK
 	}
 
+	cachedData, _, err := m.decodePayload(retBlob)
+	if err != nil || len(cachedData) == 0 {
+		m.store.DeleteKey(staleKey)
+		return nil, http.StatusOK
+	}
+
+	return m.writeCachedResponse(w, r, staleKey, cachedData, t1, true)
+}
+
+// writeCachedResponse writes a cached response, stale while the upstream of the API is degraded.
+func (m *RedisCacheMiddleware) writeCachedResponse(w http.ResponseWriter, r *http.Request, key, cachedData string, t1 time.Time, stale bool) (error, int) {
 	bufData := bufio.NewReader(strings.NewReader(cachedData))
 	newRes, err := http.ReadResponse(bufData, r)
 	if err != nil {
@@ -256,6 +308,18 @@ func (m *RedisCacheMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Req
 		newRes.Header.Set(header.XRateLimitReset, strconv.Itoa(int(quotaRenews)))
 	}
 	newRes.Header.Set(cachedResponseHeader, "1")
+	if stale {
+		newRes.Header.Set(degradedResponseHeader, "1")
+	}
+
+	if diagnostics := ctxGetDebugDiagnostics(r); diagnostics != nil {
+		status := debugCacheHit
+		if stale {
+			status = debugCacheStale
+		}
+		diagnostics.setCacheStatus(status)
+		setDebugHeaders(newRes.Header, m.Spec, r, session)
+	}
 
 	copyHeader(w.Header(), newRes.Header, m.Gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
 
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"hash"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -58,6 +60,33 @@ func TestRedisCacheMiddlewareUnit(t *testing.T) {
 				}
 			},
 		},
+		{
+			Name: "CreateCheckSum with upstream route",
+			Fn: func(t *testing.T) {
+				t.Helper()
+				mw := &RedisCacheMiddleware{BaseMiddleware: &BaseMiddleware{Spec: &APISpec{APIDefinition: &apidef.APIDefinition{APIID: "api"}}}}
+
+				newRequest := func(target string) *http.Request {
+					r := httptest.NewRequest(http.MethodGet, "/path", nil)
+					if target != "" {
+						u, err := url.Parse(target)
+						assert.NoError(t, err)
+						ctxSetUpstreamRoute(r, &upstreamRoute{target: u})
+					}
+					return r
+				}
+
+				unrouted, err := mw.CreateCheckSum(newRequest(""), "", "", "")
+				assert.NoError(t, err)
+				first, err := mw.CreateCheckSum(newRequest("http://first"), "", "", "")
+				assert.NoError(t, err)
+				second, err := mw.CreateCheckSum(newRequest("http://second"), "", "", "")
+				assert.NoError(t, err)
+
+				assert.NotEqual(t, unrouted, first)
+				assert.NotEqual(t, first, second)
+			},
+		},
 		{
 			Name: "encodePayload",
 			Fn: func(t *testing.T) {
@@ -0,0 +1,294 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/regexp"
+	"github.com/TykTechnologies/tyk/request"
+)
+
+var errRequestPolicy = errors.New("Couldn't evaluate the request policies")
+
+// requestPolicyLanguage is the expression language of request policies, built on gval.
+var requestPolicyLanguage = gval.Full(
+	// strings can be single quoted
+	gval.PrefixExtension(scanner.Char, func(_ context.Context, p *gval.Parser) (gval.Evaluable, error) {
+		s, err := unquotePolicyString(p.TokenText())
+		if err != nil {
+			return nil, fmt.Errorf("could not parse string: %w", err)
+		}
+		return p.Const(s), nil
+	}),
+	gval.Function("size", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("size takes 1 argument")
+		}
+		switch v := args[0].(type) {
+		case nil:
+			return 0.0, nil
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		default:
+			return nil, fmt.Errorf("size of %T", v)
+		}
+	}),
+	policyStringFunction("lower", 1, func(s []string) interface{} { return strings.ToLower(s[0]) }),
+	policyStringFunction("upper", 1, func(s []string) interface{} { return strings.ToUpper(s[0]) }),
+	policyStringFunction("startsWith", 2, func(s []string) interface{} { return strings.HasPrefix(s[0], s[1]) }),
+	policyStringFunction("endsWith", 2, func(s []string) interface{} { return strings.HasSuffix(s[0], s[1]) }),
+	policyStringFunction("contains", 2, func(s []string) interface{} { return strings.Contains(s[0], s[1]) }),
+	gval.Function("matches", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, errors.New("matches takes 2 arguments")
+		}
+		re, err := regexp.Compile(policyString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(policyString(args[0])), nil
+	}),
+)
+
+// unquotePolicyString unquotes a single quoted string.
+func unquotePolicyString(token string) (string, error) {
+	if len(token) < 2 || token[0] != '\'' || token[len(token)-1] != '\'' {
+		return "", strconv.ErrSyntax
+	}
+
+	inner := token[1 : len(token)-1]
+	inner = strings.ReplaceAll(inner, `\'`, `'`)
+	inner = strings.ReplaceAll(inner, `"`, `\"`)
+
+	return strconv.Unquote(`"` + inner + `"`)
+}
+
+// policyStringFunction returns a function of string arguments, nil arguments are empty strings.
+func policyStringFunction(name string, arity int, fn func([]string) interface{}) gval.Language {
+	return gval.Function(name, func(args ...interface{}) (interface{}, error) {
+		if len(args) != arity {
+			return nil, fmt.Errorf("%s takes %d arguments", name, arity)
+		}
+		s := make([]string, len(args))
+		for i, arg := range args {
+			s[i] = policyString(arg)
+		}
+		return fn(s), nil
+	})
+}
+
+// policyString converts a value of an expression to a string, nil is empty.
+func policyString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// compiledRequestPolicy is a request policy with its compiled expressions.
+type compiledRequestPolicy struct {
+	apidef.RequestPolicy
+
+	when       gval.Evaluable
+	setHeaders map[string]gval.Evaluable
+	// err is the compile error of the policy, requests are rejected rather than
+	// skipping a policy which could deny them.
+	err error
+}
+
+func compileRequestPolicy(policy apidef.RequestPolicy) compiledRequestPolicy {
+	compiled := compiledRequestPolicy{RequestPolicy: policy}
+
+	compiled.when, compiled.err = requestPolicyLanguage.NewEvaluable(policy.When)
+	if compiled.err != nil {
+		return compiled
+	}
+
+	switch policy.Action {
+	case apidef.RequestPolicyAllow, apidef.RequestPolicyDeny:
+	case apidef.RequestPolicyTransform:
+		compiled.setHeaders = make(map[string]gval.Evaluable, len(policy.SetHeaders))
+		for name, expression := range policy.SetHeaders {
+			evaluable, err := requestPolicyLanguage.NewEvaluable(expression)
+			if err != nil {
+				compiled.err = fmt.Errorf("header %s: %w", name, err)
+				return compiled
+			}
+			compiled.setHeaders[name] = evaluable
+		}
+	default:
+		compiled.err = fmt.Errorf("unknown action %q", policy.Action)
+	}
+
+	return compiled
+}
+
+// RequestPolicyMiddleware evaluates the request policies of the API, to allow, deny or
+// transform requests.
+type RequestPolicyMiddleware struct {
+	*BaseMiddleware
+
+	policies []compiledRequestPolicy
+}
+
+func (m *RequestPolicyMiddleware) Name() string {
+	return "RequestPolicyMiddleware"
+}
+
+func (m *RequestPolicyMiddleware) EnabledForSpec() bool {
+	return len(m.Spec.RequestPolicies) > 0
+}
+
+func (m *RequestPolicyMiddleware) Init() {
+	m.policies = make([]compiledRequestPolicy, 0, len(m.Spec.RequestPolicies))
+	for _, policy := range m.Spec.RequestPolicies {
+		compiled := compileRequestPolicy(policy)
+		if compiled.err != nil {
+			m.Logger().WithError(compiled.err).WithField("policy", policy.Name).Error("Invalid request policy")
+		}
+		m.policies = append(m.policies, compiled)
+	}
+}
+
+func (m *RequestPolicyMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	_, err, code := evaluateRequestPolicies(r, m.policies, requestPolicyParameters(r), m.Logger())
+	return err, code
+}
+
+// evaluateRequestPolicies evaluates policies in order, until a policy allows or denies the
+// request. It returns the policy which allowed or denied the request, nil when none did.
+func evaluateRequestPolicies(r *http.Request, policies []compiledRequestPolicy, parameters map[string]interface{}, logger *logrus.Entry) (*compiledRequestPolicy, error, int) {
+	ctx := r.Context()
+
+	for i := range policies {
+		policy := &policies[i]
+		logger := logger.WithField("policy", policy.Name)
+
+		if policy.err != nil {
+			logger.WithError(policy.err).Error("Invalid request policy")
+			return policy, errRequestPolicy, http.StatusInternalServerError
+		}
+
+		matches, err := policy.when.EvalBool(ctx, parameters)
+		if err != nil {
+			logger.WithError(err).Error("Couldn't evaluate request policy")
+			return policy, errRequestPolicy, http.StatusInternalServerError
+		}
+
+		if !matches {
+			continue
+		}
+
+		switch policy.Action {
+		case apidef.RequestPolicyAllow:
+			return policy, nil, http.StatusOK
+		case apidef.RequestPolicyDeny:
+			code := policy.Code
+			if code == 0 {
+				code = http.StatusForbidden
+			}
+			message := policy.Message
+			if message == "" {
+				message = http.StatusText(code)
+			}
+			logger.Debug("Request denied by request policy")
+			return policy, errors.New(message), code
+		case apidef.RequestPolicyTransform:
+			if err := policy.transform(ctx, r, parameters); err != nil {
+				logger.WithError(err).Error("Couldn't evaluate request policy")
+				return policy, errRequestPolicy, http.StatusInternalServerError
+			}
+		}
+	}
+
+	return nil, nil, http.StatusOK
+}
+
+func (p compiledRequestPolicy) transform(ctx context.Context, r *http.Request, parameters map[string]interface{}) error {
+	for _, name := range p.DeleteHeaders {
+		r.Header.Del(name)
+	}
+
+	for name, evaluable := range p.setHeaders {
+		value, err := evaluable(ctx, parameters)
+		if err != nil {
+			return fmt.Errorf("header %s: %w", name, err)
+		}
+		r.Header.Set(name, policyString(value))
+	}
+
+	return nil
+}
+
+// requestPolicyParameters returns the variables of the expressions of the policies for a request.
+func requestPolicyParameters(r *http.Request) map[string]interface{} {
+	headers := make(map[string]interface{}, len(r.Header))
+	for name, values := range r.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+
+	query := map[string]interface{}{}
+	for name, values := range r.URL.Query() {
+		if len(values) > 0 {
+			query[name] = values[0]
+		}
+	}
+
+	session := map[string]interface{}{
+		"meta":   map[string]interface{}{},
+		"alias":  "",
+		"org_id": "",
+		"tags":   []interface{}{},
+	}
+	if s := ctxGetSession(r); s != nil {
+		if s.MetaData != nil {
+			session["meta"] = s.MetaData
+		}
+		session["alias"] = s.Alias
+		session["org_id"] = s.OrgID
+
+		tags := make([]interface{}, len(s.Tags))
+		for i, tag := range s.Tags {
+			tags[i] = tag
+		}
+		session["tags"] = tags
+	}
+
+	claims := map[string]interface{}{}
+	for key, value := range ctxGetData(r) {
+		if name := strings.TrimPrefix(key, "jwt_claims_"); name != key {
+			claims[name] = value
+		}
+	}
+
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"host":        r.Host,
+			"remote_addr": request.RealIP(r),
+			"header":      headers,
+			"query":       query,
+		},
+		"session": session,
+		"claims":  claims,
+	}
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestCompileRequestPolicy(t *testing.T) {
+	assert.NoError(t, compileRequestPolicy(apidef.RequestPolicy{When: "request.method == 'GET'", Action: apidef.RequestPolicyAllow}).err)
+	assert.NoError(t, compileRequestPolicy(apidef.RequestPolicy{When: "true", Action: apidef.RequestPolicyTransform,
+		SetHeaders: map[string]string{"X-Path": "lower(request.path)"}}).err)
+
+	assert.Error(t, compileRequestPolicy(apidef.RequestPolicy{When: "request.method ==", Action: apidef.RequestPolicyDeny}).err)
+	assert.Error(t, compileRequestPolicy(apidef.RequestPolicy{When: "true", Action: "log"}).err)
+	assert.Error(t, compileRequestPolicy(apidef.RequestPolicy{When: "true", Action: apidef.RequestPolicyTransform,
+		SetHeaders: map[string]string{"X-Path": "lower("}}).err)
+}
+
+func TestRequestPolicies(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	t.Run("keyless", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/keyless/"
+			spec.RequestPolicies = []apidef.RequestPolicy{
+				{Name: "debug", When: "request.query.debug == '1'", Action: apidef.RequestPolicyAllow},
+				{Name: "embargo", When: "request.header['x-region'] in ['cn', 'ru']", Action: apidef.RequestPolicyDeny,
+					Code: http.StatusUnavailableForLegalReasons, Message: "Region not served"},
+				{Name: "tag", When: "startsWith(request.path, '/keyless/orders')", Action: apidef.RequestPolicyTransform,
+					SetHeaders: map[string]string{"X-Route": "upper(request.method) + ':orders'"}, DeleteHeaders: []string{"X-Debug"}},
+				{Name: "size", When: "size(request.header['x-long']) > 8", Action: apidef.RequestPolicyDeny},
+			}
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/keyless/", Code: http.StatusOK},
+			{Path: "/keyless/", Headers: map[string]string{"X-Region": "cn"}, Code: http.StatusUnavailableForLegalReasons, BodyMatch: "Region not served"},
+			{Path: "/keyless/?debug=1", Headers: map[string]string{"X-Region": "cn"}, Code: http.StatusOK},
+			{Path: "/keyless/orders/1", Headers: map[string]string{"X-Debug": "yes"}, Code: http.StatusOK,
+				BodyMatch: `"X-Route":"GET:orders"`, BodyNotMatch: "X-Debug"},
+			{Path: "/keyless/", Headers: map[string]string{"X-Long": "123456789"}, Code: http.StatusForbidden},
+		}...)
+	})
+
+	t.Run("session", func(t *testing.T) {
+		api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "policies"
+			spec.UseKeylessAccess = false
+			spec.Proxy.ListenPath = "/session/"
+			spec.RequestPolicies = []apidef.RequestPolicy{
+				{Name: "region", When: "request.header['x-region'] != session.meta.region", Action: apidef.RequestPolicyDeny},
+			}
+		})[0]
+
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+			s.MetaData = map[string]interface{}{"region": "eu"}
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/session/", Headers: map[string]string{header.Authorization: key, "X-Region": "eu"}, Code: http.StatusOK},
+			{Path: "/session/", Headers: map[string]string{header.Authorization: key, "X-Region": "us"}, Code: http.StatusForbidden},
+			{Path: "/session/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusForbidden},
+		}...)
+	})
+
+	t.Run("denied requests keep the quota", func(t *testing.T) {
+		api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "policies-quota"
+			spec.UseKeylessAccess = false
+			spec.Proxy.ListenPath = "/quota/"
+			spec.RequestPolicies = []apidef.RequestPolicy{
+				{Name: "blocked", When: "request.header['x-client'] == 'blocked'", Action: apidef.RequestPolicyDeny},
+			}
+		})[0]
+
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+			s.QuotaMax = 1
+			s.QuotaRemaining = 1
+			s.QuotaRenewalRate = 3600
+		})
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/quota/", Headers: map[string]string{header.Authorization: key, "X-Client": "blocked"}, Code: http.StatusForbidden},
+			{Path: "/quota/", Headers: map[string]string{header.Authorization: key, "X-Client": "blocked"}, Code: http.StatusForbidden},
+			{Path: "/quota/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusOK},
+			{Path: "/quota/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusForbidden,
+				BodyMatch: "Quota exceeded"},
+		}...)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/invalid/"
+			spec.RequestPolicies = []apidef.RequestPolicy{
+				{Name: "broken", When: "request.header[", Action: apidef.RequestPolicyDeny},
+			}
+		})
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/invalid/", Code: http.StatusInternalServerError})
+	})
+}
@@ -11,8 +11,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/TykTechnologies/tyk/certs"
-
 	"github.com/TykTechnologies/tyk/internal/crypto"
 )
 
@@ -91,7 +89,17 @@ func (s *RequestSigning) getRequestPath(r *http.Request) string {
 }
 
 func (s *RequestSigning) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
-	if (s.Spec.RequestSigning.Secret == "" && s.Spec.RequestSigning.CertificateId == "") || s.Spec.RequestSigning.KeyId == "" || s.Spec.RequestSigning.Algorithm == "" {
+	keyID, certificateID := s.Spec.RequestSigning.KeyId, s.Spec.RequestSigning.CertificateId
+
+	// RSA signing falls back to the Gateway signing keys, published via JWKS, so key
+	// rotation doesn't require changes on the API definitions.
+	if strings.HasPrefix(s.Spec.RequestSigning.Algorithm, "rsa") && certificateID == "" {
+		if key, ok := s.Gw.activeSigningKey(); ok {
+			keyID, certificateID = key.KeyID, key.CertificateID
+		}
+	}
+
+	if (s.Spec.RequestSigning.Secret == "" && certificateID == "") || keyID == "" || s.Spec.RequestSigning.Algorithm == "" {
 		log.Error("Fields required for signing the request are missing")
 		return errors.New("Fields required for signing the request are missing"), http.StatusInternalServerError
 	}
@@ -129,21 +137,15 @@ func (s *RequestSigning) ProcessRequest(w http.ResponseWriter, r *http.Request,
 	var encodedSignature string
 
 	if strings.HasPrefix(s.Spec.RequestSigning.Algorithm, "rsa") {
-		if s.Spec.RequestSigning.CertificateId == "" {
+		if certificateID == "" {
 			log.Error("CertificateID is empty")
 			return errors.New("CertificateID is empty"), http.StatusInternalServerError
 		}
 
-		certList := s.Gw.CertificateManager.List([]string{s.Spec.RequestSigning.CertificateId}, certs.CertificatePrivate)
-		if len(certList) == 0 || certList[0] == nil {
-			log.Error("Certificate not found")
-			return errors.New("Certificate not found"), http.StatusInternalServerError
-		}
-		cert := certList[0]
-		rsaKey, ok := cert.PrivateKey.(*rsa.PrivateKey)
-		if !ok {
-			log.Error("Certificate does not contain RSA private key")
-			return errors.New("Certificate does not contain RSA private key"), http.StatusInternalServerError
+		rsaKey, err := s.Gw.signingPrivateKey(certificateID)
+		if err != nil {
+			log.Error(err)
+			return err, http.StatusInternalServerError
 		}
 		encodedSignature, err = generateRSAEncodedSignature(signatureString, rsaKey, s.Spec.RequestSigning.Algorithm)
 		if err != nil {
@@ -161,7 +163,7 @@ func (s *RequestSigning) ProcessRequest(w http.ResponseWriter, r *http.Request,
 	//Generate Authorization header
 	authHeader := "Signature "
 	//Append keyId
-	authHeader += "keyId=\"" + s.Spec.RequestSigning.KeyId + "\","
+	authHeader += "keyId=\"" + keyID + "\","
 	//Append algorithm
 	authHeader += "algorithm=\"" + s.Spec.RequestSigning.Algorithm + "\","
 	//Append Headers
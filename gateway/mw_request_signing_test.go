@@ -18,6 +18,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
 	"github.com/TykTechnologies/tyk/test"
 	"github.com/TykTechnologies/tyk/user"
 )
@@ -387,6 +388,34 @@ func TestRSARequestSigning(t *testing.T) {
 		}
 	})
 
+	t.Run("Gateway signing key", func(t *testing.T) {
+		algo := "rsa-sha256"
+		sessionKey := ts.generateSession(algo, pubCertId)
+		specs := ts.generateSpec(algo, "", "", nil)
+
+		globalConf := ts.Gw.GetConfig()
+		globalConf.Security.SigningKeys.Keys = []config.SigningKey{
+			{KeyID: "retired", CertificateID: "12345"},
+			{KeyID: sessionKey, CertificateID: privCertId},
+		}
+		globalConf.Security.SigningKeys.ActiveKeyID = sessionKey
+		ts.Gw.SetConfig(globalConf)
+		defer func() {
+			globalConf.Security.SigningKeys = config.SigningKeysConfig{}
+			ts.Gw.SetConfig(globalConf)
+		}()
+
+		req := TestReq(t, "get", "/test/get", nil)
+		recorder := httptest.NewRecorder()
+		chain := ts.getMiddlewareChain(specs[0])
+		chain.ServeHTTP(recorder, req)
+
+		if recorder.Code != 200 {
+			t.Error("RSA request signing failed with error:", recorder.Body.String())
+		}
+		assert.Contains(t, req.Header.Get("Authorization"), `keyId="`+sessionKey+`"`)
+	})
+
 	t.Run("Custom Signature header", func(t *testing.T) {
 		algo := "rsa-sha256"
 
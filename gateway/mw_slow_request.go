@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocraft/health"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk-pump/analytics"
+	"github.com/TykTechnologies/tyk/ctx"
+)
+
+const slowRequestTag = "slow-request"
+
+// requestTiming records the timing breakdown of a request. The upstream phases are
+// collected through an httptrace.ClientTrace attached to the outbound request.
+type requestTiming struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	slow bool
+}
+
+func (t *requestTiming) record(field *time.Time) {
+	t.mu.Lock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+	t.mu.Unlock()
+}
+
+func (t *requestTiming) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.record(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.record(&t.dnsDone) },
+		ConnectStart:         func(string, string) { t.record(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { t.record(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.record(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.record(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.record(&t.firstByte) },
+	}
+}
+
+func sinceMs(from, to time.Time) int64 {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+
+	return int64(DurationToMillisecond(to.Sub(from)))
+}
+
+// fields returns the timing breakdown in milliseconds. Phases which didn't happen,
+// e.g. DNS resolution on a reused connection, are reported as 0.
+func (t *requestTiming) fields(total time.Duration, latency analytics.Latency) logrus.Fields {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	totalMs := int64(DurationToMillisecond(total))
+
+	return logrus.Fields{
+		"total_ms":      totalMs,
+		"upstream_ms":   latency.Upstream,
+		"middleware_ms": totalMs - latency.Upstream,
+		"dns_ms":        sinceMs(t.dnsStart, t.dnsDone),
+		"connect_ms":    sinceMs(t.connectStart, t.connectDone),
+		"tls_ms":        sinceMs(t.tlsStart, t.tlsDone),
+		"ttfb_ms":       sinceMs(t.wroteRequest, t.firstByte),
+	}
+}
+
+func ctxSetRequestTiming(r *http.Request, t *requestTiming) {
+	setCtxValue(r, ctx.RequestTiming, t)
+}
+
+func ctxGetRequestTiming(r *http.Request) *requestTiming {
+	if v, ok := r.Context().Value(ctx.RequestTiming).(*requestTiming); ok {
+		return v
+	}
+	return nil
+}
+
+// withUpstreamTrace attaches the client trace of the request timing to the outbound request.
+func withUpstreamTrace(outreq *http.Request) *http.Request {
+	timing := ctxGetRequestTiming(outreq)
+	if timing == nil {
+		return outreq
+	}
+
+	return outreq.WithContext(httptrace.WithClientTrace(outreq.Context(), timing.clientTrace()))
+}
+
+// SlowRequestMiddleware starts the timing of requests for slow request detection.
+type SlowRequestMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *SlowRequestMiddleware) Name() string {
+	return "SlowRequestMiddleware"
+}
+
+func (m *SlowRequestMiddleware) EnabledForSpec() bool {
+	return m.Spec.SlowRequests.Enabled && m.Spec.SlowRequests.Threshold > 0
+}
+
+func (m *SlowRequestMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	ctxSetRequestTiming(r, &requestTiming{start: time.Now()})
+	return nil, http.StatusOK
+}
+
+// checkSlowRequest flags, logs and counts the request if it exceeded the slow request threshold of the API.
+func (t *BaseMiddleware) checkSlowRequest(r *http.Request, latency analytics.Latency) {
+	timing := ctxGetRequestTiming(r)
+	if timing == nil {
+		return
+	}
+
+	total := time.Since(timing.start)
+	if DurationToMillisecond(total) < float64(t.Spec.SlowRequests.Threshold) {
+		return
+	}
+
+	timing.mu.Lock()
+	timing.slow = true
+	timing.mu.Unlock()
+
+	fields := timing.fields(total, latency)
+	t.Logger().WithFields(fields).WithFields(logrus.Fields{
+		"path":      r.URL.Path,
+		"method":    r.Method,
+		"threshold": t.Spec.SlowRequests.Threshold,
+	}).Warning("Slow request detected")
+
+	job := instrument.NewJob("SlowRequest")
+	job.EventKv("slow_request", health.Kvs{
+		"api_id":   t.Spec.APIID,
+		"org_id":   t.Spec.OrgID,
+		"total_ms": strconv.FormatInt(fields["total_ms"].(int64), 10),
+	})
+}
+
+// tagSlowRequest adds the slow request tag to the analytics tags of slow requests.
+func tagSlowRequest(r *http.Request, tags []string) []string {
+	timing := ctxGetRequestTiming(r)
+	if timing == nil {
+		return tags
+	}
+
+	timing.mu.Lock()
+	defer timing.mu.Unlock()
+
+	if timing.slow {
+		tags = append(tags, slowRequestTag)
+	}
+
+	return tags
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk-pump/analytics"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestSlowRequestDetection(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/slow-error":
+			time.Sleep(50 * time.Millisecond)
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.SlowRequests = apidef.SlowRequests{Enabled: true, Threshold: 30}
+	})
+
+	redisAnalyticsKeyName := analyticsKeyName + ts.Gw.Analytics.analyticsSerializer.GetSuffix()
+	ts.Gw.Analytics.Store.GetAndDeleteSet(redisAnalyticsKeyName)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/fast", Code: http.StatusOK},
+		{Path: "/slow", Code: http.StatusOK},
+		{Path: "/slow-error", Code: http.StatusInternalServerError},
+	}...)
+
+	ts.Gw.Analytics.Flush()
+	results := ts.Gw.Analytics.Store.GetAndDeleteSet(redisAnalyticsKeyName)
+	assert.Len(t, results, 3)
+
+	slow := map[string]bool{}
+	for _, result := range results {
+		var record analytics.AnalyticsRecord
+		assert.NoError(t, ts.Gw.Analytics.analyticsSerializer.Decode([]byte(result.(string)), &record))
+		slow[record.Path] = contains(record.Tags, slowRequestTag)
+	}
+
+	assert.Equal(t, map[string]bool{"/fast": false, "/slow": true, "/slow-error": true}, slow)
+}
+
+func TestRequestTiming_fields(t *testing.T) {
+	start := time.Now()
+	timing := &requestTiming{
+		start:        start,
+		connectStart: start,
+		connectDone:  start.Add(5 * time.Millisecond),
+		wroteRequest: start.Add(6 * time.Millisecond),
+		firstByte:    start.Add(26 * time.Millisecond),
+	}
+
+	fields := timing.fields(40*time.Millisecond, analytics.Latency{Upstream: 30})
+
+	assert.Equal(t, int64(40), fields["total_ms"])
+	assert.Equal(t, int64(10), fields["middleware_ms"])
+	assert.Equal(t, int64(0), fields["dns_ms"])
+	assert.Equal(t, int64(5), fields["connect_ms"])
+	assert.Equal(t, int64(20), fields["ttfb_ms"])
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+var (
+	errTenantUpstream = errors.New("Couldn't resolve the upstream of the tenant")
+
+	tenantHostname = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+)
+
+// tenantUpstreamData holds the variables of upstream templates.
+type tenantUpstreamData struct {
+	OrgID string
+	APIID string
+	Alias string
+	Meta  map[string]interface{}
+}
+
+func compileTenantUpstreamTemplate(conf apidef.TenantIsolation, logger *logrus.Entry) *template.Template {
+	if !conf.Enabled {
+		return nil
+	}
+
+	tmpl, err := template.New("upstream").Option("missingkey=error").Parse(conf.UpstreamTemplate)
+	if err != nil {
+		logger.WithError(err).Error("Couldn't parse tenant isolation upstream template")
+		return nil
+	}
+
+	return tmpl
+}
+
+// TenantIsolationMiddleware resolves the upstream URL of requests from the upstream
+// template of the API, for backends which shard tenants by hostname.
+type TenantIsolationMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *TenantIsolationMiddleware) Name() string {
+	return "TenantIsolationMiddleware"
+}
+
+func (m *TenantIsolationMiddleware) EnabledForSpec() bool {
+	return m.Spec.upstreamTemplate != nil
+}
+
+func (m *TenantIsolationMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	// upstream routing rules take precedence
+	if ctxGetUpstreamRoute(r) != nil {
+		return nil, http.StatusOK
+	}
+
+	target, err := m.resolve(r)
+	if err != nil {
+		m.Logger().WithError(err).Error("Couldn't resolve tenant upstream")
+		return errTenantUpstream, http.StatusBadGateway
+	}
+
+	ctxSetUpstreamRoute(r, &upstreamRoute{target: target})
+
+	return nil, http.StatusOK
+}
+
+func (m *TenantIsolationMiddleware) resolve(r *http.Request) (*url.URL, error) {
+	data := tenantUpstreamData{OrgID: m.Spec.OrgID, APIID: m.Spec.APIID, Meta: map[string]interface{}{}}
+	if session := ctxGetSession(r); session != nil {
+		if session.OrgID != "" {
+			data.OrgID = session.OrgID
+		}
+		data.Alias = session.Alias
+		if session.MetaData != nil {
+			data.Meta = session.MetaData
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := m.Spec.upstreamTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(buf.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Scheme != "http" && target.Scheme != "https" || target.User != nil {
+		return nil, fmt.Errorf("invalid upstream URL %q", target.Redacted())
+	}
+
+	if !allowedTenantHost(target.Hostname(), m.Spec.TenantIsolation.AllowedHostSuffixes) {
+		return nil, fmt.Errorf("upstream host %q is not allowed", target.Hostname())
+	}
+
+	return target, nil
+}
+
+// allowedTenantHost tells whether a host is a valid subdomain of one of the suffixes.
+func allowedTenantHost(host string, suffixes []string) bool {
+	host = strings.ToLower(host)
+	if !tenantHostname.MatchString(host) {
+		return false
+	}
+
+	for _, suffix := range suffixes {
+		suffix = strings.ToLower(strings.Trim(suffix, "."))
+		if suffix != "" && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+
+	return false
+}
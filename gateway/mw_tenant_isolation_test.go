@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestTenantIsolationMiddleware_resolve(t *testing.T) {
+	newMiddleware := func(tmpl string) *TenantIsolationMiddleware {
+		conf := apidef.TenantIsolation{Enabled: true, UpstreamTemplate: tmpl, AllowedHostSuffixes: []string{".internal.svc"}}
+		spec := &APISpec{APIDefinition: &apidef.APIDefinition{APIID: "api", OrgID: "default", TenantIsolation: conf}}
+		spec.upstreamTemplate = compileTenantUpstreamTemplate(conf, logrus.NewEntry(log))
+		require.NotNil(t, spec.upstreamTemplate)
+
+		return &TenantIsolationMiddleware{BaseMiddleware: &BaseMiddleware{Spec: spec}}
+	}
+
+	request := func(session *user.SessionState) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if session != nil {
+			setCtxValue(r, ctx.SessionData, session)
+		}
+		return r
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		session  *user.SessionState
+		target   string
+	}{
+		{name: "api org", template: "http://{{.OrgID}}.internal.svc", target: "http://default.internal.svc"},
+		{name: "session org", template: "https://{{.OrgID}}.internal.svc:8443/v1", session: &user.SessionState{OrgID: "acme", KeyID: "key"},
+			target: "https://acme.internal.svc:8443/v1"},
+		{name: "session metadata", template: "http://{{.Meta.shard}}.{{.APIID}}.internal.svc",
+			session: &user.SessionState{KeyID: "key", MetaData: map[string]interface{}{"shard": "eu-1"}}, target: "http://eu-1.api.internal.svc"},
+		{name: "missing metadata", template: "http://{{.Meta.shard}}.internal.svc", session: &user.SessionState{KeyID: "key"}},
+		{name: "host outside suffix", template: "http://{{.OrgID}}.evil.com", session: &user.SessionState{OrgID: "acme", KeyID: "key"}},
+		{name: "suffix itself", template: "http://internal.svc"},
+		{name: "injected host", template: "http://{{.OrgID}}.internal.svc", session: &user.SessionState{OrgID: "evil.com#", KeyID: "key"}},
+		{name: "injected user info", template: "http://{{.OrgID}}.internal.svc", session: &user.SessionState{OrgID: "evil.com@x", KeyID: "key"}},
+		{name: "unsupported scheme", template: "file://{{.OrgID}}.internal.svc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, err := newMiddleware(tc.template).resolve(request(tc.session))
+			if tc.target == "" {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.target, target.String())
+		})
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tenant " + r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "tenants"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/tenants/"
+		spec.Proxy.StripListenPath = true
+		spec.TenantIsolation = apidef.TenantIsolation{
+			Enabled:             true,
+			UpstreamTemplate:    "http://{{.Meta.host}}:" + upstreamURL.Port() + "/shard",
+			AllowedHostSuffixes: []string{"0.0.1"},
+		}
+	})[0]
+
+	createKey := func(host string) string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+			s.MetaData = map[string]interface{}{"host": host}
+		})
+		return key
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/tenants/orders", Headers: map[string]string{header.Authorization: createKey("127.0.0.1")}, Code: http.StatusOK, BodyMatch: "^tenant /shard/orders$"},
+		{Path: "/tenants/orders", Headers: map[string]string{header.Authorization: createKey("example.com")}, Code: http.StatusBadGateway},
+	}...)
+}
@@ -0,0 +1,367 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/fpe"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const defaultTokenizationVaultTimeout = 5
+
+var (
+	errTokenization        = errors.New("Couldn't tokenize the request body")
+	errTokenizationNotJSON = errors.New("Request body is not valid JSON")
+)
+
+// tokenizer tokenizes and detokenizes the fields of JSON bodies of an API.
+type tokenizer struct {
+	fields []*tokenizedField
+	vault  apidef.TokenizationVault
+	client *http.Client
+
+	// err is set when the configuration can't be used, requests are then rejected rather
+	// than forwarded with sensitive fields in clear.
+	err error
+}
+
+type tokenizedField struct {
+	apidef.TokenizationField
+	path []string
+	ff1  *fpe.FF1
+}
+
+func compileTokenizer(conf apidef.Tokenization, logger *logrus.Entry) *tokenizer {
+	if !conf.Enabled || len(conf.Fields) == 0 {
+		return nil
+	}
+
+	timeout := conf.Vault.Timeout
+	if timeout <= 0 {
+		timeout = defaultTokenizationVaultTimeout
+	}
+
+	t := &tokenizer{
+		vault:  conf.Vault,
+		client: &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}
+
+	for _, field := range conf.Fields {
+		compiled := &tokenizedField{TokenizationField: field, path: strings.Split(field.Path, ".")}
+
+		if field.Method == apidef.TokenizationMethodFPE {
+			alphabet := fpe.Digits
+			if field.Alphabet == apidef.TokenizationAlphabetAlphanumeric {
+				alphabet = fpe.Alphanumeric
+			}
+
+			key, err := hex.DecodeString(conf.Key)
+			if err == nil {
+				compiled.ff1, err = fpe.NewFF1(key, alphabet)
+			}
+			if err != nil {
+				logger.WithError(err).Error("Invalid tokenization key")
+				t.err = err
+			}
+		}
+
+		t.fields = append(t.fields, compiled)
+	}
+
+	return t
+}
+
+// transform tokenizes the fields of a JSON body, or detokenizes the fields restored in responses.
+func (t *tokenizer) transform(body []byte, detokenize bool) ([]byte, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, errTokenizationNotJSON
+	}
+
+	for _, field := range t.fields {
+		if detokenize && !field.DetokenizeResponse {
+			continue
+		}
+
+		var err error
+		switch field.Method {
+		case apidef.TokenizationMethodFPE:
+			doc = walkJSONStrings(doc, field.path, func(value string) string {
+				if err != nil {
+					return value
+				}
+
+				var result string
+				result, err = field.cipher(value, detokenize)
+				return result
+			})
+		case apidef.TokenizationMethodVault:
+			doc, err = t.exchange(doc, field.path, detokenize)
+		default:
+			err = fmt.Errorf("unknown tokenization method %q", field.Method)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Path, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// cipher encrypts or decrypts the characters of a value in the alphabet of the field,
+// leaving the other characters in place.
+func (f *tokenizedField) cipher(value string, decrypt bool) (string, error) {
+	runes := []rune(value)
+
+	var (
+		positions []int
+		input     []rune
+	)
+	for i, r := range runes {
+		if f.ff1.Contains(r) {
+			positions = append(positions, i)
+			input = append(input, r)
+		}
+	}
+
+	var (
+		output string
+		err    error
+	)
+	if decrypt {
+		output, err = f.ff1.Decrypt(string(input), []byte(f.Path))
+	} else {
+		output, err = f.ff1.Encrypt(string(input), []byte(f.Path))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for i, r := range []rune(output) {
+		runes[positions[i]] = r
+	}
+
+	return string(runes), nil
+}
+
+// exchange replaces the values of a field with the values returned by the vault.
+func (t *tokenizer) exchange(doc interface{}, path []string, detokenize bool) (interface{}, error) {
+	var values []string
+	doc = walkJSONStrings(doc, path, func(value string) string {
+		values = append(values, value)
+		return value
+	})
+	if len(values) == 0 {
+		return doc, nil
+	}
+
+	action := "tokenize"
+	if detokenize {
+		action = "detokenize"
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{"action": action, "values": values})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.vault.URL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(header.ContentType, header.ApplicationJSON)
+	for name, value := range t.vault.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault responded with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Values []string `json:"values"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Values) != len(values) {
+		return nil, fmt.Errorf("vault returned %d values for %d", len(result.Values), len(values))
+	}
+
+	i := 0
+	return walkJSONStrings(doc, path, func(string) string {
+		i++
+		return result.Values[i-1]
+	}), nil
+}
+
+// walkJSONStrings replaces the string values at a path of a decoded JSON document.
+// A `[]` path segment matches every item of an array.
+func walkJSONStrings(node interface{}, path []string, replace func(string) string) interface{} {
+	if len(path) == 0 {
+		if value, ok := node.(string); ok {
+			return replace(value)
+		}
+		return node
+	}
+
+	switch n := node.(type) {
+	case []interface{}:
+		if path[0] == "[]" {
+			for i := range n {
+				n[i] = walkJSONStrings(n[i], path[1:], replace)
+			}
+		}
+	case map[string]interface{}:
+		if child, ok := n[path[0]]; ok {
+			n[path[0]] = walkJSONStrings(child, path[1:], replace)
+		}
+	}
+
+	return node
+}
+
+// TokenizationMiddleware tokenizes the sensitive fields of JSON request bodies, before they
+// reach the upstream and analytics. It runs after the authentication and the rate limits, so
+// the rejected requests don't reach the vault and the signatures are checked on the received body.
+type TokenizationMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *TokenizationMiddleware) Name() string {
+	return "TokenizationMiddleware"
+}
+
+func (m *TokenizationMiddleware) EnabledForSpec() bool {
+	return m.Spec.tokenizer != nil
+}
+
+func (m *TokenizationMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	body, err := readBody(r)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	// rewind the body for the requests passed through as they are
+	r.Body, _ = copyBody(r.Body, false)
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, http.StatusOK
+	}
+
+	tokenized, err := m.Spec.tokenizer.transform(body, false)
+	if err != nil {
+		switch {
+		case errors.Is(err, errTokenizationNotJSON) && !strings.Contains(r.Header.Get(header.ContentType), "json"):
+			// not a JSON body, it has no field to tokenize
+			return nil, http.StatusOK
+		case errors.Is(err, errTokenizationNotJSON), errors.Is(err, fpe.ErrInputTooShort), errors.Is(err, fpe.ErrInvalidInput):
+			return err, http.StatusBadRequest
+		}
+
+		m.Logger().WithError(err).Error("Tokenization failed")
+		return errTokenization, http.StatusInternalServerError
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(tokenized))
+	r.ContentLength = int64(len(tokenized))
+	r.Header.Set(header.ContentLength, strconv.Itoa(len(tokenized)))
+	nopCloseRequestBody(r)
+
+	return nil, http.StatusOK
+}
+
+// ResponseTokenizationMiddleware restores the original value of tokenized fields in
+// JSON response bodies.
+type ResponseTokenizationMiddleware struct {
+	BaseTykResponseHandler
+}
+
+func (h *ResponseTokenizationMiddleware) Base() *BaseTykResponseHandler {
+	return &h.BaseTykResponseHandler
+}
+
+func (*ResponseTokenizationMiddleware) Name() string {
+	return "ResponseTokenizationMiddleware"
+}
+
+func (h *ResponseTokenizationMiddleware) Enabled() bool {
+	if h.Spec.tokenizer == nil {
+		return false
+	}
+
+	for _, field := range h.Spec.tokenizer.fields {
+		if field.DetokenizeResponse {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *ResponseTokenizationMiddleware) Init(_ interface{}, spec *APISpec) error {
+	h.Spec = spec
+	return nil
+}
+
+func (h *ResponseTokenizationMiddleware) HandleError(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *ResponseTokenizationMiddleware) HandleResponse(_ http.ResponseWriter, res *http.Response, req *http.Request, _ *user.SessionState) error {
+	if !strings.Contains(res.Header.Get(header.ContentType), "json") {
+		return nil
+	}
+
+	respBody := respBodyReader(req, res)
+	body, err := io.ReadAll(respBody)
+	respBody.Close()
+	if err != nil {
+		return err
+	}
+
+	detokenized, err := h.Spec.tokenizer.transform(body, true)
+	if err != nil {
+		log.WithField("api_id", h.Spec.APIID).WithError(err).Error("Response detokenization failed")
+		detokenized = body
+	}
+
+	bodyBuffer := compressBuffer(*bytes.NewBuffer(detokenized), res.Header.Get(header.ContentEncoding))
+
+	res.ContentLength = int64(bodyBuffer.Len())
+	res.Header.Set(header.ContentLength, strconv.Itoa(bodyBuffer.Len()))
+	res.Body = io.NopCloser(&bodyBuffer)
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestTokenization(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	received := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+		w.Header().Set(header.ContentType, header.ApplicationJSON)
+		_, _ = w.Write(body)
+	}))
+	defer upstream.Close()
+
+	var vaultCalls atomic.Int32
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vaultCalls.Add(1)
+
+		var req struct {
+			Action string   `json:"action"`
+			Values []string `json:"values"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if r.Header.Get("X-Vault-Token") != "secret" || req.Action != "tokenize" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		for i, value := range req.Values {
+			req.Values[i] = "tok_" + strings.Repeat("x", len(value))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"values": req.Values})
+	}))
+	defer vault.Close()
+
+	load := func(key string, keyless bool) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "tokenization"
+			spec.UseKeylessAccess = keyless
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.Tokenization = apidef.Tokenization{
+				Enabled: true,
+				Key:     key,
+				Vault: apidef.TokenizationVault{
+					URL:     vault.URL,
+					Headers: map[string]string{"X-Vault-Token": "secret"},
+				},
+				Fields: []apidef.TokenizationField{
+					{Path: "card.pan", Method: apidef.TokenizationMethodFPE, DetokenizeResponse: true},
+					{Path: "customers.[].ssn", Method: apidef.TokenizationMethodVault},
+				},
+			}
+		})
+	}
+	load("2B7E151628AED2A6ABF7158809CF4F3C", true)
+
+	t.Run("tokenize and detokenize", func(t *testing.T) {
+		body := `{"card":{"pan":"4111-1111-1111-1111","holder":"A & B"},"customers":[{"ssn":"123-45-6789"},{"ssn":"987-65-4321"}]}`
+
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/", Data: body,
+			Headers: map[string]string{header.ContentType: header.ApplicationJSON}, Code: http.StatusOK})
+
+		var upstreamBody struct {
+			Card struct {
+				PAN    string `json:"pan"`
+				Holder string `json:"holder"`
+			} `json:"card"`
+			Customers []struct {
+				SSN string `json:"ssn"`
+			} `json:"customers"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(<-received), &upstreamBody))
+		assert.Regexp(t, regexp.MustCompile(`^\d{4}-\d{4}-\d{4}-\d{4}$`), upstreamBody.Card.PAN)
+		assert.NotEqual(t, "4111-1111-1111-1111", upstreamBody.Card.PAN)
+		assert.Equal(t, "A & B", upstreamBody.Card.Holder)
+		require.Len(t, upstreamBody.Customers, 2)
+		assert.Equal(t, "tok_xxxxxxxxxxx", upstreamBody.Customers[0].SSN)
+		assert.Equal(t, "tok_xxxxxxxxxxx", upstreamBody.Customers[1].SSN)
+
+		// only the encrypted field is restored for the client
+		respBody, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(respBody), `"pan":"4111-1111-1111-1111"`)
+		assert.Contains(t, string(respBody), `"ssn":"tok_xxxxxxxxxxx"`)
+	})
+
+	t.Run("invalid bodies", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/", Data: `{"card":`, Headers: map[string]string{header.ContentType: header.ApplicationJSON}, Code: http.StatusBadRequest},
+			{Method: http.MethodPost, Path: "/", Data: `{"card":{"pan":"4111"}}`, Code: http.StatusBadRequest},
+		}...)
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/", Data: "plain text",
+			Headers: map[string]string{header.ContentType: "text/plain"}, Code: http.StatusOK})
+		assert.Equal(t, "plain text", <-received)
+	})
+
+	t.Run("unauthenticated requests", func(t *testing.T) {
+		load("2B7E151628AED2A6ABF7158809CF4F3C", false)
+		vaultCalls.Store(0)
+
+		body := `{"customers":[{"ssn":"123-45-6789"}]}`
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/", Data: body,
+			Headers: map[string]string{header.ContentType: header.ApplicationJSON}, Code: http.StatusUnauthorized})
+		assert.Zero(t, vaultCalls.Load(), "the rejected requests don't reach the vault")
+
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"tokenization": {APIID: "tokenization"}}
+		})
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/", Data: body,
+			Headers: map[string]string{header.ContentType: header.ApplicationJSON, header.Authorization: key}, Code: http.StatusOK})
+		assert.Contains(t, <-received, "tok_")
+		assert.EqualValues(t, 1, vaultCalls.Load())
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		load("not-hex", true)
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/", Data: `{"card":{"pan":"4111111111111111"}}`,
+			Code: http.StatusInternalServerError})
+	})
+}
@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/ctx"
+	"github.com/TykTechnologies/tyk/request"
+)
+
+const upstreamRouteTagPrefix = "upstream-route-"
+
+var errUpstreamRouting = errors.New("Couldn't route request upstream")
+
+type compiledUpstreamRoute struct {
+	apidef.UpstreamRoutingRule
+
+	values     map[string]bool
+	networks   []*net.IPNet
+	ports      map[string]bool
+	roundRobin RoundRobin
+}
+
+// upstreamRoute is the target chosen for a request by an upstream routing rule.
+type upstreamRoute struct {
+	name   string
+	target *url.URL
+}
+
+func compileUpstreamRoutes(conf apidef.UpstreamRouting, logger *logrus.Entry) []*compiledUpstreamRoute {
+	if !conf.Enabled {
+		return nil
+	}
+
+	var routes []*compiledUpstreamRoute
+	for _, rule := range conf.Rules {
+		if len(rule.Targets) == 0 {
+			logger.WithField("rule", rule.Name).Error("Upstream routing rule has no target, skipping")
+			continue
+		}
+
+		route := &compiledUpstreamRoute{UpstreamRoutingRule: rule}
+		if route.matchesAddress() {
+			route.compileNetworks(logger)
+		} else if len(rule.Values) > 0 {
+			route.values = make(map[string]bool, len(rule.Values))
+			for _, value := range rule.Values {
+				route.values[value] = true
+			}
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// matchesAddress returns true if the rule matches the client or listener address, rather
+// than a value of the request.
+func (u *compiledUpstreamRoute) matchesAddress() bool {
+	return u.Source == apidef.UpstreamRoutingSourceIP || u.Source == apidef.UpstreamRoutingSourceListener
+}
+
+// compileNetworks parses the CIDRs, IPs and ports of the rule values.
+func (u *compiledUpstreamRoute) compileNetworks(logger *logrus.Entry) {
+	for _, value := range u.Values {
+		if strings.HasPrefix(value, ":") {
+			if u.ports == nil {
+				u.ports = map[string]bool{}
+			}
+			u.ports[strings.TrimPrefix(value, ":")] = true
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil {
+				bits := 8 * net.IPv6len
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 8*net.IPv4len
+				}
+				u.networks = append(u.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			logger.WithField("rule", u.Name).WithError(err).Error("Couldn't parse upstream routing network, skipping")
+			continue
+		}
+		u.networks = append(u.networks, network)
+	}
+}
+
+// address returns the client or listener address the rule routes on. The client address is
+// the one of the connection, the forwarding headers are set by the clients.
+func (u *compiledUpstreamRoute) address(r *http.Request) (ip net.IP, port string) {
+	if u.Source == apidef.UpstreamRoutingSourceIP {
+		return net.ParseIP(request.ConnectionIP(r)), ""
+	}
+
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return nil, ""
+	}
+
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil, ""
+	}
+
+	return net.ParseIP(host), port
+}
+
+func (u *compiledUpstreamRoute) matchesNetwork(r *http.Request) bool {
+	ip, port := u.address(r)
+	if port != "" && u.ports[port] {
+		return true
+	}
+
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range u.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// value returns the value of the header, claim or metadata the rule routes on.
+func (u *compiledUpstreamRoute) value(r *http.Request) string {
+	var value interface{}
+
+	switch u.Source {
+	case apidef.UpstreamRoutingSourceHeader:
+		return r.Header.Get(u.Key)
+	case apidef.UpstreamRoutingSourceClaim:
+		value = ctxGetData(r)["jwt_claims_"+u.Key]
+	case apidef.UpstreamRoutingSourceMeta:
+		if session := ctxGetSession(r); session != nil {
+			value = session.MetaData[u.Key]
+		}
+	}
+
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+func (u *compiledUpstreamRoute) matches(r *http.Request) bool {
+	if u.matchesAddress() {
+		return u.matchesNetwork(r)
+	}
+
+	value := u.value(r)
+	if u.values == nil {
+		return value != ""
+	}
+
+	return u.values[value]
+}
+
+// UpstreamRoutingMiddleware chooses the upstream target of requests matching an
+// upstream routing rule, before the targets of the API are load balanced.
+type UpstreamRoutingMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *UpstreamRoutingMiddleware) Name() string {
+	return "UpstreamRoutingMiddleware"
+}
+
+func (m *UpstreamRoutingMiddleware) EnabledForSpec() bool {
+	return len(m.Spec.upstreamRoutes) > 0
+}
+
+func (m *UpstreamRoutingMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	for _, route := range m.Spec.upstreamRoutes {
+		if !route.matches(r) {
+			continue
+		}
+
+		host, err := m.nextTarget(route)
+		if err != nil {
+			m.Logger().WithField("rule", route.Name).WithError(err).Error("Couldn't route request upstream")
+			return errUpstreamRouting, http.StatusServiceUnavailable
+		}
+
+		target, err := url.Parse(host)
+		if err != nil {
+			m.Logger().WithField("rule", route.Name).WithError(err).Error("Couldn't parse upstream routing target")
+			return errUpstreamRouting, http.StatusServiceUnavailable
+		}
+
+		ctxSetUpstreamRoute(r, &upstreamRoute{name: route.Name, target: target})
+		break
+	}
+
+	return nil, http.StatusOK
+}
+
+// nextTarget load balances the targets of a route, skipping hosts which are down when
+// the API checks hosts against uptime tests.
+func (m *UpstreamRoutingMiddleware) nextTarget(route *compiledUpstreamRoute) (string, error) {
+	start := route.roundRobin.WithLen(len(route.Targets))
+	for i := range route.Targets {
+		host := EnsureTransport(route.Targets[(start+i)%len(route.Targets)], m.Spec.Protocol)
+		if !m.Spec.Proxy.CheckHostAgainstUptimeTests || m.Gw.GlobalHostChecker.store == nil || !m.Gw.GlobalHostChecker.HostDown(host) {
+			return host, nil
+		}
+	}
+
+	return "", fmt.Errorf("all hosts are down, uptime tests are failing")
+}
+
+func ctxSetUpstreamRoute(r *http.Request, route *upstreamRoute) {
+	setCtxValue(r, ctx.UpstreamRoute, route)
+}
+
+func ctxGetUpstreamRoute(r *http.Request) *upstreamRoute {
+	if v, ok := r.Context().Value(ctx.UpstreamRoute).(*upstreamRoute); ok {
+		return v
+	}
+	return nil
+}
+
+// tagUpstreamRoute adds the routing rule which chose the upstream to the analytics tags.
+func tagUpstreamRoute(r *http.Request, tags []string) []string {
+	if route := ctxGetUpstreamRoute(r); route != nil && route.name != "" {
+		tags = append(tags, upstreamRouteTagPrefix+route.name)
+	}
+
+	return tags
+}
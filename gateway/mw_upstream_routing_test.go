@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestUpstreamRouting(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	newUpstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(name))
+		}))
+	}
+
+	enterprise1, enterprise2, gold := newUpstream("enterprise-1"), newUpstream("enterprise-2"), newUpstream("gold")
+	defer enterprise1.Close()
+	defer enterprise2.Close()
+	defer gold.Close()
+
+	api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "routed"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/routed/"
+		spec.UpstreamRouting = apidef.UpstreamRouting{
+			Enabled: true,
+			Rules: []apidef.UpstreamRoutingRule{
+				{Name: "enterprise", Source: apidef.UpstreamRoutingSourceHeader, Key: "X-Tenant", Values: []string{"enterprise"},
+					Targets: []string{enterprise1.URL, enterprise2.URL}},
+				{Name: "gold", Source: apidef.UpstreamRoutingSourceMeta, Key: "tier", Values: []string{"gold"},
+					Targets: []string{gold.URL}},
+				{Name: "query", Source: apidef.UpstreamRoutingSourceHeader, Key: "X-Tenant", Values: []string{"query"},
+					Targets: []string{TestHttpAny + "?route=query"}},
+			},
+		}
+	})[0]
+
+	_, goldKey := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+		s.MetaData = map[string]interface{}{"tier": "gold"}
+	})
+	_, bronzeKey := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+		s.MetaData = map[string]interface{}{"tier": "bronze"}
+	})
+
+	authorized := func(key string, headers map[string]string) map[string]string {
+		h := map[string]string{header.Authorization: key}
+		for k, v := range headers {
+			h[k] = v
+		}
+		return h
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/routed/", Headers: authorized(bronzeKey, map[string]string{"X-Tenant": "enterprise"}), Code: http.StatusOK, BodyMatch: "^enterprise-1$"},
+		{Path: "/routed/", Headers: authorized(bronzeKey, map[string]string{"X-Tenant": "enterprise"}), Code: http.StatusOK, BodyMatch: "^enterprise-2$"},
+		{Path: "/routed/", Headers: authorized(goldKey, nil), Code: http.StatusOK, BodyMatch: "^gold$"},
+		// the first matching rule applies
+		{Path: "/routed/", Headers: authorized(goldKey, map[string]string{"X-Tenant": "enterprise"}), Code: http.StatusOK, BodyMatch: "^enterprise-1$"},
+		{Path: "/routed/", Headers: authorized(bronzeKey, map[string]string{"X-Tenant": "query"}), Code: http.StatusOK, BodyMatch: `route=query`},
+		// the query of a route doesn't leak into the next requests
+		{Path: "/routed/", Headers: authorized(bronzeKey, map[string]string{"X-Tenant": "startup"}), Code: http.StatusOK, BodyMatch: `"Url":"/routed/"`},
+	}...)
+}
+
+func TestTagUpstreamRoute(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, tagUpstreamRoute(r, nil))
+
+	ctxSetUpstreamRoute(r, &upstreamRoute{name: "enterprise"})
+	assert.Equal(t, []string{"tag", "upstream-route-enterprise"}, tagUpstreamRoute(r, []string{"tag"}))
+}
+
+func TestUpstreamRouting_Networks(t *testing.T) {
+	routes := compileUpstreamRoutes(apidef.UpstreamRouting{
+		Enabled: true,
+		Rules: []apidef.UpstreamRoutingRule{
+			{Name: "internal", Source: apidef.UpstreamRoutingSourceIP, Values: []string{"10.0.0.0/8", "192.168.1.10", "invalid"},
+				Targets: []string{"http://internal"}},
+			{Name: "admin-listener", Source: apidef.UpstreamRoutingSourceListener, Values: []string{"127.0.0.2", ":9443"},
+				Targets: []string{"http://admin"}},
+		},
+	}, logrus.NewEntry(log))
+	internal, listener := routes[0], routes[1]
+
+	newRequest := func(remoteAddr, localAddr string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		if localAddr != "" {
+			addr, err := net.ResolveTCPAddr("tcp", localAddr)
+			assert.NoError(t, err)
+			r = r.WithContext(context.WithValue(r.Context(), http.LocalAddrContextKey, addr))
+		}
+		return r
+	}
+
+	assert.True(t, internal.matches(newRequest("10.1.2.3:1234", "")))
+	assert.True(t, internal.matches(newRequest("192.168.1.10:1234", "")))
+	assert.False(t, internal.matches(newRequest("192.168.1.11:1234", "")))
+	assert.False(t, internal.matches(newRequest("8.8.8.8:1234", "")))
+
+	spoofed := newRequest("8.8.8.8:1234", "")
+	spoofed.Header.Set(header.XRealIP, "10.1.2.3")
+	spoofed.Header.Set(header.XForwardFor, "10.1.2.3")
+	assert.False(t, internal.matches(spoofed), "the forwarding headers are set by the clients")
+
+	assert.True(t, listener.matches(newRequest("8.8.8.8:1234", "127.0.0.2:8080")))
+	assert.True(t, listener.matches(newRequest("8.8.8.8:1234", "127.0.0.1:9443")))
+	assert.False(t, listener.matches(newRequest("8.8.8.8:1234", "127.0.0.1:8080")))
+	assert.False(t, listener.matches(newRequest("8.8.8.8:1234", "")))
+}
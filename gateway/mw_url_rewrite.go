@@ -31,6 +31,7 @@ const (
 )
 
 var dollarMatch = regexp.MustCompile(`\$\d+`)
+var namedGroupMatch = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
 var contextMatch = regexp.MustCompile(`\$tyk_context.([A-Za-z0-9_\-\.]+)`)
 var consulMatch = regexp.MustCompile(`\$secret_consul.([A-Za-z0-9\/\-\.]+)`)
 var vaultMatch = regexp.MustCompile(`\$secret_vault.([A-Za-z0-9\/\-\.]+)`)
@@ -38,7 +39,22 @@ var envValueMatch = regexp.MustCompile(`\$secret_env.([A-Za-z0-9_\-\.]+)`)
 var metaMatch = regexp.MustCompile(`\$tyk_meta.([A-Za-z0-9_\-\.]+)`)
 var secretsConfMatch = regexp.MustCompile(`\$secret_conf.([A-Za-z0-9[.\-\_]+)`)
 
+// urlRewriteResult describes the rewrite of a request URL.
+type urlRewriteResult struct {
+	// Path is the rewritten URL.
+	Path string
+	// Matched is true when the match pattern matched the URL.
+	Matched bool
+	// Trigger is the index of the trigger which matched, -1 for none.
+	Trigger int
+}
+
 func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (string, error) {
+	result, err := gw.rewriteURL(meta, r)
+	return result.Path, err
+}
+
+func (gw *Gateway) rewriteURL(meta *apidef.URLRewriteMeta, r *http.Request) (urlRewriteResult, error) {
 	rawPath := r.URL.String()
 	path := rawPath
 
@@ -49,12 +65,13 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 		var err error
 		meta.MatchRegexp, err = regexp.Compile(meta.MatchPattern)
 		if err != nil {
-			return path, fmt.Errorf("URLRewrite regexp error %s", meta.MatchPattern)
+			return urlRewriteResult{Path: path, Trigger: -1}, fmt.Errorf("URLRewrite regexp error %s", meta.MatchPattern)
 		}
 	}
 
 	// Check triggers
 	rewriteToPath := meta.RewriteTo
+	matchedTrigger := -1
 	if len(meta.Triggers) > 0 {
 
 		// This feature uses context, we must force it if it doesn't exist
@@ -76,7 +93,7 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 				if checkHeaderTrigger(r, triggerOpts.Options.HeaderMatches, checkAny, tn) {
 					setCount += 1
 					if checkAny {
-						rewriteToPath = triggerOpts.RewriteTo
+						rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
 						break
 					}
 				}
@@ -87,7 +104,7 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 				if checkQueryString(r, triggerOpts.Options.QueryValMatches, checkAny, tn) {
 					setCount += 1
 					if checkAny {
-						rewriteToPath = triggerOpts.RewriteTo
+						rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
 						break
 					}
 				}
@@ -98,7 +115,7 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 				if checkPathParts(r, triggerOpts.Options.PathPartMatches, checkAny, tn) {
 					setCount += 1
 					if checkAny {
-						rewriteToPath = triggerOpts.RewriteTo
+						rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
 						break
 					}
 				}
@@ -110,7 +127,7 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 					if checkSessionTrigger(r, session, triggerOpts.Options.SessionMetaMatches, checkAny, tn) {
 						setCount += 1
 						if checkAny {
-							rewriteToPath = triggerOpts.RewriteTo
+							rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
 							break
 						}
 					}
@@ -122,7 +139,18 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 				if checkContextTrigger(r, triggerOpts.Options.RequestContextMatches, checkAny, tn) {
 					setCount += 1
 					if checkAny {
-						rewriteToPath = triggerOpts.RewriteTo
+						rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
+						break
+					}
+				}
+			}
+
+			// Check JWT claims
+			if len(triggerOpts.Options.ClaimMatches) > 0 {
+				if checkClaimTrigger(r, triggerOpts.Options.ClaimMatches, checkAny, tn) {
+					setCount += 1
+					if checkAny {
+						rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
 						break
 					}
 				}
@@ -133,7 +161,7 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 				if checkPayload(r, triggerOpts.Options.PayloadMatches, tn) {
 					setCount += 1
 					if checkAny {
-						rewriteToPath = triggerOpts.RewriteTo
+						rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
 						break
 					}
 				}
@@ -157,22 +185,29 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 				if len(triggerOpts.Options.RequestContextMatches) > 0 {
 					total += 1
 				}
+				if len(triggerOpts.Options.ClaimMatches) > 0 {
+					total += 1
+				}
 				if triggerOpts.Options.PayloadMatches.MatchPattern != "" {
 					total += 1
 				}
 				if total == setCount {
-					rewriteToPath = triggerOpts.RewriteTo
+					rewriteToPath, matchedTrigger = triggerOpts.RewriteTo, tn
 					break
 				}
 			}
 		}
+
+		if matchedTrigger >= 0 && meta.Triggers[matchedTrigger].Name != "" {
+			nameTriggerMatches(ctxGetData(r), matchedTrigger, meta.Triggers[matchedTrigger].Name)
+		}
 	}
 
 	matchGroups := meta.MatchRegexp.FindAllStringSubmatch(path, -1)
 	if len(matchGroups) == 0 && containsEscapedChars(rawPath) {
 		unescapedPath, err := url.PathUnescape(rawPath)
 		if err != nil {
-			return unescapedPath, fmt.Errorf("failed to decode URL path: %s", rawPath)
+			return urlRewriteResult{Path: unescapedPath, Trigger: -1}, fmt.Errorf("failed to decode URL path: %s", rawPath)
 		}
 
 		matchGroups = meta.MatchRegexp.FindAllStringSubmatch(unescapedPath, -1)
@@ -198,6 +233,15 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 			newpath = strings.Replace(newpath, v[0], groupReplace[v[0]], -1)
 		}
 
+		// named groups are referenced as ${name}
+		newpath = namedGroupMatch.ReplaceAllStringFunc(newpath, func(ref string) string {
+			index := meta.MatchRegexp.SubexpIndex(namedGroupMatch.FindStringSubmatch(ref)[1])
+			if index < 0 {
+				return ref
+			}
+			return matchGroups[0][index]
+		})
+
 		log.Debug("URL Re-written from: ", path)
 		log.Debug("URL Re-written to: ", newpath)
 
@@ -207,7 +251,7 @@ func (gw *Gateway) urlRewrite(meta *apidef.URLRewriteMeta, r *http.Request) (str
 
 	newpath = gw.ReplaceTykVariables(r, newpath, true)
 
-	return newpath, nil
+	return urlRewriteResult{Path: newpath, Matched: len(matchGroups) > 0, Trigger: matchedTrigger}, nil
 }
 
 // ReplaceTykVariables implements a variable replacement hook. It will replace
@@ -431,6 +475,10 @@ func (m *URLRewriteMiddleware) InitTriggerRx() (enabled bool) {
 					h.Init()
 					tr.Options.PathPartMatches[key] = h
 				}
+				for key, h := range tr.Options.ClaimMatches {
+					h.Init()
+					tr.Options.ClaimMatches[key] = h
+				}
 				if tr.Options.PayloadMatches.MatchPattern != "" {
 					tr.Options.PayloadMatches.Init()
 				}
@@ -694,6 +742,35 @@ func checkContextTrigger(r *http.Request, options map[string]apidef.StringRegexM
 	return false
 }
 
+func checkClaimTrigger(r *http.Request, options map[string]apidef.StringRegexMap, any bool, triggernum int) bool {
+	contextData := ctxGetData(r)
+	fCount := 0
+
+	for claim, mr := range options {
+		rawVal, ok := contextData["jwt_claims_"+claim]
+		if !ok {
+			continue
+		}
+
+		matched, match := mr.FindStringSubmatch(valToStr(rawVal))
+		if matched {
+			addMatchToContextData(contextData, match, triggernum, claim)
+			fCount++
+		}
+	}
+
+	if fCount > 0 {
+		ctxSetData(r, contextData)
+		if any {
+			return true
+		}
+
+		return len(options) <= fCount
+	}
+
+	return false
+}
+
 func checkPayload(r *http.Request, options apidef.StringRegexMap, triggernum int) bool {
 	contextData := ctxGetData(r)
 	bodyBytes, _ := ioutil.ReadAll(r.Body)
@@ -731,6 +808,19 @@ func addMatchToContextData(cd map[string]interface{}, match []string, trNum int,
 	}
 }
 
+// nameTriggerMatches copies the groups captured by a trigger to keys named after the trigger,
+// `trigger-0-header-0` also becomes `trigger-<name>-header-0`.
+func nameTriggerMatches(cd map[string]interface{}, num int, name string) {
+	prefix := strings.Join([]string{triggerKeyPrefix, strconv.Itoa(num)}, triggerKeySep) + triggerKeySep
+	named := strings.Join([]string{triggerKeyPrefix, name}, triggerKeySep) + triggerKeySep
+
+	for key, value := range cd {
+		if strings.HasPrefix(key, prefix) {
+			cd[named+strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+}
+
 func buildTriggerKey(num int, name string, indices ...int) string {
 	parts := []string{triggerKeyPrefix, strconv.Itoa(num), name}
 
@@ -1382,3 +1382,45 @@ func TestURLRewriteMiddleware_CheckHostRewrite(t *testing.T) {
 		})
 	}
 }
+
+func TestRewriterNamedRules(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	claim := apidef.StringRegexMap{MatchPattern: "^gold-(\\w+)$"}
+	claim.Init()
+
+	meta := &apidef.URLRewriteMeta{
+		Name:         "orders",
+		MatchPattern: "/orders/(?P<id>[0-9]+)",
+		RewriteTo:    "/v2/orders/${id}",
+		Triggers: []apidef.RoutingTrigger{
+			{
+				Name: "gold",
+				On:   apidef.All,
+				Options: apidef.RoutingTriggerOptions{
+					ClaimMatches: map[string]apidef.StringRegexMap{"tier": claim},
+				},
+				RewriteTo: "/v2/$tyk_context.trigger-gold-tier-0/orders/${id}",
+			},
+		},
+	}
+
+	t.Run("named group", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+		ctxSetData(r, map[string]interface{}{})
+
+		result, err := ts.Gw.rewriteURL(meta, r)
+		assert.NoError(t, err)
+		assert.Equal(t, urlRewriteResult{Path: "/v2/orders/42", Matched: true, Trigger: -1}, result)
+	})
+
+	t.Run("claim trigger", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+		ctxSetData(r, map[string]interface{}{"jwt_claims_tier": "gold-eu"})
+
+		result, err := ts.Gw.rewriteURL(meta, r)
+		assert.NoError(t, err)
+		assert.Equal(t, urlRewriteResult{Path: "/v2/eu/orders/42", Matched: true, Trigger: 0}, result)
+	})
+}
@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/internal/xmldsig"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+	wsseNamespace   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	wsuNamespace    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	wssePasswordDigest = "#PasswordDigest"
+
+	defaultWSSecurityClockSkew = 300
+	wsSecurityNoncePrefix      = "ws-security-nonce-"
+)
+
+var (
+	errWSSecurityHeaderMissing = errors.New("WS-Security header missing")
+	errWSSecurityInvalid       = errors.New("WS-Security validation failed")
+)
+
+// WSSecurityMiddleware validates the WS-Security header of SOAP requests: username
+// tokens, timestamps and XML signatures of the body.
+type WSSecurityMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *WSSecurityMiddleware) Name() string {
+	return "WSSecurityMiddleware"
+}
+
+func (m *WSSecurityMiddleware) EnabledForSpec() bool {
+	return m.Spec.WSSecurity.Enabled
+}
+
+func (m *WSSecurityMiddleware) ProcessRequest(w http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	body, err := readBody(r)
+	if err != nil {
+		return err, http.StatusBadRequest
+	}
+	// rewind the body for the next middlewares and the upstream
+	r.Body, _ = copyBody(r.Body, false)
+
+	envelope, err := xmldsig.Parse(body)
+	if err != nil {
+		return errors.New("Request body is not valid XML"), http.StatusBadRequest
+	}
+
+	if envelope.Local != "Envelope" || (envelope.Space != soap11Namespace && envelope.Space != soap12Namespace) {
+		return errors.New("Request body is not a SOAP envelope"), http.StatusBadRequest
+	}
+
+	var security *xmldsig.Element
+	if soapHeader := envelope.Child(envelope.Space, "Header"); soapHeader != nil {
+		security = soapHeader.Child(wsseNamespace, "Security")
+	}
+	if security == nil {
+		return errWSSecurityHeaderMissing, http.StatusUnauthorized
+	}
+
+	conf := m.Spec.WSSecurity
+	for _, check := range []struct {
+		required bool
+		validate func(*xmldsig.Element) error
+	}{
+		{conf.RequireTimestamp, m.validateTimestamp},
+		{conf.RequireUsernameToken, m.validateUsernameToken},
+		{conf.RequireSignature, m.validateSignature},
+	} {
+		if !check.required {
+			continue
+		}
+
+		if err := check.validate(security); err != nil {
+			m.Logger().WithError(err).Debug("WS-Security validation failed")
+			return errWSSecurityInvalid, http.StatusForbidden
+		}
+	}
+
+	return nil, http.StatusOK
+}
+
+func (m *WSSecurityMiddleware) clockSkew() time.Duration {
+	if m.Spec.WSSecurity.ClockSkew > 0 {
+		return time.Duration(m.Spec.WSSecurity.ClockSkew) * time.Second
+	}
+
+	return defaultWSSecurityClockSkew * time.Second
+}
+
+// validateTimestamp checks the message was created in the past and has not expired.
+func (m *WSSecurityMiddleware) validateTimestamp(security *xmldsig.Element) error {
+	timestamp := security.Child(wsuNamespace, "Timestamp")
+	if timestamp == nil {
+		return errors.New("timestamp missing")
+	}
+
+	now := time.Now()
+
+	created, err := wsuTime(timestamp, "Created")
+	if err != nil {
+		return err
+	}
+	if created.After(now.Add(m.clockSkew())) {
+		return errors.New("timestamp created in the future")
+	}
+
+	if timestamp.Child(wsuNamespace, "Expires") != nil {
+		expires, err := wsuTime(timestamp, "Expires")
+		if err != nil {
+			return err
+		}
+		if expires.Before(now.Add(-m.clockSkew())) {
+			return errors.New("timestamp expired")
+		}
+	}
+
+	return nil
+}
+
+func wsuTime(el *xmldsig.Element, name string) (time.Time, error) {
+	child := el.Child(wsuNamespace, name)
+	if child == nil {
+		return time.Time{}, fmt.Errorf("%s missing", name)
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(child.Text()))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// validateUsernameToken checks the credentials of the username token. Digest passwords
+// must be recent and their nonce is remembered to prevent replays.
+func (m *WSSecurityMiddleware) validateUsernameToken(security *xmldsig.Element) error {
+	token := security.Child(wsseNamespace, "UsernameToken")
+	if token == nil {
+		return errors.New("username token missing")
+	}
+
+	usernameEl := token.Child(wsseNamespace, "Username")
+	passwordEl := token.Child(wsseNamespace, "Password")
+	if usernameEl == nil || passwordEl == nil {
+		return errors.New("username or password missing")
+	}
+
+	username := strings.TrimSpace(usernameEl.Text())
+	expected, ok := m.Spec.WSSecurity.Users[username]
+	if !ok {
+		return fmt.Errorf("unknown user %q", username)
+	}
+
+	password := strings.TrimSpace(passwordEl.Text())
+	if passwordType, _ := passwordEl.Attr("", "Type"); !strings.HasSuffix(passwordType, wssePasswordDigest) {
+		if subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+			return errors.New("invalid password")
+		}
+		return nil
+	}
+
+	nonceEl := token.Child(wsseNamespace, "Nonce")
+	if nonceEl == nil {
+		return errors.New("nonce missing")
+	}
+	nonce, err := base64.StdEncoding.DecodeString(strings.TrimSpace(nonceEl.Text()))
+	if err != nil {
+		return fmt.Errorf("invalid nonce: %w", err)
+	}
+
+	created, err := wsuTime(token, "Created")
+	if err != nil {
+		return err
+	}
+	if age := time.Since(created); age > m.clockSkew() || age < -m.clockSkew() {
+		return errors.New("username token is not recent")
+	}
+
+	// digest is Base64(SHA-1(nonce + created + password))
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(strings.TrimSpace(token.Child(wsuNamespace, "Created").Text())))
+	h.Write([]byte(expected))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	if subtle.ConstantTimeCompare([]byte(password), []byte(digest)) != 1 {
+		return errors.New("invalid password digest")
+	}
+
+	store := &storage.RedisCluster{KeyPrefix: wsSecurityNoncePrefix, ConnectionHandler: m.Gw.StorageConnectionHandler}
+	fresh, err := store.Lock(wsSecurityNoncePrefix+m.Spec.APIID+"-"+base64.RawURLEncoding.EncodeToString(nonce), 2*m.clockSkew())
+	if err != nil {
+		return fmt.Errorf("couldn't check nonce: %w", err)
+	}
+	if !fresh {
+		return errors.New("nonce already used")
+	}
+
+	return nil
+}
+
+// validateSignature checks a signature of the security header covers the SOAP body.
+func (m *WSSecurityMiddleware) validateSignature(security *xmldsig.Element) error {
+	signature := security.Child(xmldsig.Namespace, "Signature")
+	if signature == nil {
+		return errors.New("signature missing")
+	}
+
+	var signers []*x509.Certificate
+	for _, cert := range m.Gw.CertificateManager.List(m.Spec.WSSecurity.SignatureCertificates, certs.CertificatePublic) {
+		if cert != nil && cert.Leaf != nil {
+			signers = append(signers, cert.Leaf)
+		}
+	}
+
+	covered, err := xmldsig.Verify(signature, signers)
+	if err != nil {
+		return err
+	}
+
+	envelope := security.Root()
+	body := envelope.Child(envelope.Space, "Body")
+	for _, el := range covered {
+		if el == body || el == envelope {
+			return nil
+		}
+	}
+
+	return errors.New("signature does not cover the SOAP body")
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/internal/xmldsig/xmldsigtest"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func soapRequest(security string) string {
+	return `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" xmlns:wsse="` + wsseNamespace + `" xmlns:wsu="` + wsuNamespace + `">` +
+		`<s:Header><wsse:Security>` + security + `</wsse:Security></s:Header>` +
+		`<s:Body wsu:Id="body"><GetOrder><ID>42</ID></GetOrder></s:Body></s:Envelope>`
+}
+
+func wsTimestamp(created, expires time.Time) string {
+	return `<wsu:Timestamp><wsu:Created>` + created.UTC().Format(time.RFC3339) + `</wsu:Created>` +
+		`<wsu:Expires>` + expires.UTC().Format(time.RFC3339) + `</wsu:Expires></wsu:Timestamp>`
+}
+
+func wsUsernameDigest(username, password string, nonce []byte, created time.Time) string {
+	createdStr := created.UTC().Format(time.RFC3339)
+
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(createdStr))
+	h.Write([]byte(password))
+
+	return `<wsse:UsernameToken><wsse:Username>` + username + `</wsse:Username>` +
+		`<wsse:Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">` +
+		base64.StdEncoding.EncodeToString(h.Sum(nil)) + `</wsse:Password>` +
+		`<wsse:Nonce>` + base64.StdEncoding.EncodeToString(nonce) + `</wsse:Nonce><wsu:Created>` + createdStr + `</wsu:Created></wsse:UsernameToken>`
+}
+
+func TestWSSecurityMiddleware(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	t.Run("username token and timestamp", func(t *testing.T) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = uuid.NewHex()
+			spec.Proxy.ListenPath = "/soap/"
+			spec.WSSecurity.Enabled = true
+			spec.WSSecurity.RequireUsernameToken = true
+			spec.WSSecurity.RequireTimestamp = true
+			spec.WSSecurity.Users = map[string]string{"alice": "secret"}
+		})
+
+		now := time.Now()
+		timestamp := wsTimestamp(now, now.Add(time.Minute))
+		nonce := []byte(uuid.NewHex())
+		digest := wsUsernameDigest("alice", "secret", nonce, now)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/soap/", Data: "not xml", Code: http.StatusBadRequest},
+			{Method: http.MethodPost, Path: "/soap/", Data: `<Envelope/>`, Code: http.StatusBadRequest},
+			{Method: http.MethodPost, Path: "/soap/", Data: strings.Replace(soapRequest(""), "<s:Header><wsse:Security></wsse:Security></s:Header>", "", 1),
+				Code: http.StatusUnauthorized},
+			{Method: http.MethodPost, Path: "/soap/", Data: soapRequest(timestamp + `<wsse:UsernameToken><wsse:Username>alice</wsse:Username><wsse:Password>secret</wsse:Password></wsse:UsernameToken>`),
+				Code: http.StatusOK, BodyMatch: "GetOrder"},
+			{Method: http.MethodPost, Path: "/soap/", Data: soapRequest(timestamp + `<wsse:UsernameToken><wsse:Username>alice</wsse:Username><wsse:Password>wrong</wsse:Password></wsse:UsernameToken>`),
+				Code: http.StatusForbidden},
+			{Method: http.MethodPost, Path: "/soap/", Data: soapRequest(wsTimestamp(now.Add(-time.Hour), now.Add(-30*time.Minute)) + digest),
+				Code: http.StatusForbidden},
+			{Method: http.MethodPost, Path: "/soap/", Data: soapRequest(timestamp + digest), Code: http.StatusOK},
+			// nonce replay
+			{Method: http.MethodPost, Path: "/soap/", Data: soapRequest(timestamp + digest), Code: http.StatusForbidden},
+		}...)
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		certPem, _, _, tlsCert := certs.GenCertificate(&x509.Certificate{}, false)
+		certID, err := ts.Gw.CertificateManager.Add(certPem, "")
+		require.NoError(t, err)
+		defer ts.Gw.CertificateManager.Delete(certID, "")
+
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = uuid.NewHex()
+			spec.Proxy.ListenPath = "/soap/"
+			spec.WSSecurity.Enabled = true
+			spec.WSSecurity.RequireSignature = true
+			spec.WSSecurity.SignatureCertificates = []string{certID}
+		})
+
+		signed := xmldsigtest.Sign(t, tlsCert.PrivateKey.(*rsa.PrivateKey), soapRequest(xmldsigtest.SignaturePlaceholder), "body")
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: "/soap/", Data: signed, Code: http.StatusOK},
+			{Method: http.MethodPost, Path: "/soap/", Data: strings.Replace(signed, "<ID>42</ID>", "<ID>43</ID>", 1), Code: http.StatusForbidden},
+			{Method: http.MethodPost, Path: "/soap/", Data: soapRequest(""), Code: http.StatusForbidden},
+		}...)
+	})
+}
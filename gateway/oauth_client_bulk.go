@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/internal/uuid"
+)
+
+// oauthClientsExport is the export of the OAuth clients of an API, which can be imported into
+// the same API or another one.
+type oauthClientsExport struct {
+	APIID      string             `json:"api_id"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Clients    []NewClientRequest `json:"clients"`
+}
+
+// oauthClientsImportResult lists the clients of an import by outcome.
+type oauthClientsImportResult struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	// Skipped are the existing clients, kept unless the overwrite query parameter is set.
+	Skipped []string `json:"skipped"`
+	// Errors are the reasons the other clients weren't imported, by client ID.
+	Errors map[string]string `json:"errors"`
+}
+
+// oAuthClientsExportHandler exports the OAuth clients of an API, with their secrets.
+func (gw *Gateway) oAuthClientsExportHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+
+	clients, _, code := gw.getApiClients(apiID)
+	if code != http.StatusOK {
+		doJSONWrite(w, code, apiError(oAuthClientNotFound))
+		return
+	}
+
+	export := oauthClientsExport{APIID: apiID, ExportedAt: time.Now().UTC(), Clients: []NewClientRequest{}}
+	for _, client := range clients {
+		export.Clients = append(export.Clients, NewClientRequest{
+			ClientID:          client.GetId(),
+			ClientSecret:      client.GetSecret(),
+			ClientRedirectURI: client.GetRedirectUri(),
+			PolicyID:          client.GetPolicyID(),
+			MetaData:          client.GetUserData(),
+			Description:       client.GetDescription(),
+		})
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":  "api",
+		"apiID":   apiID,
+		"clients": len(export.Clients),
+		"status":  "ok",
+	}).Info("Exported OAuth clients")
+
+	doJSONWrite(w, http.StatusOK, export)
+}
+
+// oAuthClientsImportHandler imports OAuth clients into an API, from an export. The clients
+// without ID or secret are given new ones, and the existing clients are only updated when the
+// overwrite query parameter is set.
+func (gw *Gateway) oAuthClientsImportHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+	overwrite := r.URL.Query().Get("overwrite") == "true"
+
+	var export oauthClientsExport
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	apiSpec := gw.getApiSpec(apiID)
+	if apiSpec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API doesn't exist"))
+		return
+	}
+
+	if !apiSpec.UseOauth2 || apiSpec.OAuthManager == nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("API is not OAuth2"))
+		return
+	}
+
+	result := oauthClientsImportResult{Created: []string{}, Updated: []string{}, Skipped: []string{}, Errors: map[string]string{}}
+	storage := apiSpec.OAuthManager.OsinServer.Storage
+
+	for _, imported := range export.Clients {
+		newClient := OAuthClient{
+			ClientID:          imported.ClientID,
+			ClientSecret:      imported.ClientSecret,
+			ClientRedirectURI: imported.ClientRedirectURI,
+			PolicyID:          imported.PolicyID,
+			MetaData:          imported.MetaData,
+			Description:       imported.Description,
+		}
+		if newClient.ClientID == "" {
+			newClient.ClientID = uuid.NewHex()
+		}
+		if newClient.ClientSecret == "" {
+			newClient.ClientSecret = createOauthClientSecret()
+		}
+
+		if msg := gw.checkOauthClientPolicy(newClient.PolicyID, apiID); msg != "" {
+			result.Errors[newClient.ClientID] = msg
+			continue
+		}
+
+		storageID := oauthClientStorageID(newClient.ClientID)
+		existing, err := storage.GetExtendedClientNoPrefix(storageID)
+		if err == nil && !overwrite {
+			result.Skipped = append(result.Skipped, newClient.ClientID)
+			continue
+		}
+
+		if err := storage.SetClient(storageID, apiSpec.OrgID, &newClient, true); err != nil {
+			result.Errors[newClient.ClientID] = "Failure in storing client data"
+			continue
+		}
+
+		if existing != nil {
+			invalidateTokens(existing, newClient, apiSpec.OAuthManager)
+			result.Updated = append(result.Updated, newClient.ClientID)
+		} else {
+			result.Created = append(result.Created, newClient.ClientID)
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":  "api",
+		"apiID":   apiID,
+		"created": len(result.Created),
+		"updated": len(result.Updated),
+		"skipped": len(result.Skipped),
+		"failed":  len(result.Errors),
+	}).Info("Imported OAuth clients")
+
+	doJSONWrite(w, http.StatusOK, result)
+}
+
+// checkOauthClientPolicy returns why a client of an API can't have a policy, if it can't.
+func (gw *Gateway) checkOauthClientPolicy(policyID, apiID string) string {
+	if policyID == "" {
+		return ""
+	}
+
+	gw.policiesMu.RLock()
+	policy, ok := gw.policiesByID[policyID]
+	gw.policiesMu.RUnlock()
+	if !ok {
+		return "Policy doesn't exist"
+	}
+
+	if _, ok := policy.AccessRights[apiID]; !ok {
+		return "Policy access rights doesn't contain API this OAuth client belongs to"
+	}
+
+	return ""
+}
+
+// revokeOauthClientTokensHandler revokes the tokens issued to an OAuth client.
+func (gw *Gateway) revokeOauthClientTokensHandler(w http.ResponseWriter, r *http.Request) {
+	apiID := mux.Vars(r)["apiID"]
+	keyName := mux.Vars(r)["keyName"]
+
+	apiSpec := gw.getApiSpec(apiID)
+	if apiSpec == nil || apiSpec.OAuthManager == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("OAuth Client ID not found"))
+		return
+	}
+
+	storage := apiSpec.OAuthManager.OsinServer.Storage
+	if _, err := storage.GetExtendedClientNoPrefix(oauthClientStorageID(keyName)); err != nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("OAuth Client ID not found"))
+		return
+	}
+
+	status, tokens, err := revokeClientTokens(storage, keyName)
+	if err != nil {
+		doJSONWrite(w, status, apiError(err.Error()))
+		return
+	}
+
+	gw.MainNotifier.Notify(Notification{
+		Command: KeySpaceUpdateNotification,
+		Payload: strings.Join(tokens, ","),
+		Gw:      gw,
+	})
+
+	log.WithFields(logrus.Fields{
+		"prefix": "api",
+		"apiID":  apiID,
+		"client": keyName,
+		"tokens": len(tokens),
+		"status": "ok",
+	}).Info("Revoked OAuth client tokens")
+
+	doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{Key: keyName, Status: "ok", Action: "revoked"})
+}
@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestOAuthClientsImportExport(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.LoadAPI(buildTestOAuthSpec(), buildTestOAuthSpec(func(spec *APISpec) {
+		spec.APIID = "oauth-import"
+		spec.Proxy.ListenPath = "/import/"
+	}))
+
+	importClients := func(t *testing.T, apiID, query string, export oauthClientsExport) oauthClientsImportResult {
+		t.Helper()
+
+		resp, err := ts.Run(t, test.TestCase{
+			Method:    http.MethodPost,
+			Path:      "/tyk/oauth/clients/" + apiID + "/import" + query,
+			AdminAuth: true,
+			Data:      export,
+			Code:      http.StatusOK,
+		})
+		require.NoError(t, err)
+
+		var result oauthClientsImportResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+
+	result := importClients(t, "999999", "", oauthClientsExport{Clients: []NewClientRequest{
+		{ClientID: "imported", ClientSecret: "secret", ClientRedirectURI: authRedirectUri},
+		{ClientRedirectURI: authRedirectUri},
+		{ClientID: "no-policy", ClientRedirectURI: authRedirectUri, PolicyID: "missing"},
+	}})
+	assert.Len(t, result.Created, 2)
+	assert.Contains(t, result.Created, "imported")
+	assert.Equal(t, map[string]string{"no-policy": "Policy doesn't exist"}, result.Errors)
+
+	updated := oauthClientsExport{Clients: []NewClientRequest{
+		{ClientID: "imported", ClientSecret: "secret", ClientRedirectURI: authRedirectUri2, Description: "updated"},
+	}}
+	assert.Equal(t, []string{"imported"}, importClients(t, "999999", "", updated).Skipped)
+	assert.Equal(t, []string{"imported"}, importClients(t, "999999", "?overwrite=true", updated).Updated)
+
+	resp, err := ts.Run(t, test.TestCase{Path: "/tyk/oauth/clients/999999/export", AdminAuth: true, Code: http.StatusOK})
+	require.NoError(t, err)
+
+	var export oauthClientsExport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&export))
+	assert.Equal(t, "999999", export.APIID)
+	require.Len(t, export.Clients, 2)
+	assert.Contains(t, export.Clients, NewClientRequest{ClientID: "imported", ClientSecret: "secret", ClientRedirectURI: authRedirectUri2, Description: "updated"})
+
+	assert.Len(t, importClients(t, "oauth-import", "", export).Created, 2)
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/tyk/oauth/clients/oauth-import/imported", AdminAuth: true, BodyMatch: `"description":"updated"`, Code: http.StatusOK},
+		{Path: "/tyk/oauth/clients/unknown/export", AdminAuth: true, Code: http.StatusNotFound},
+		{Method: http.MethodPost, Path: "/tyk/oauth/clients/999999/import", AdminAuth: true, Data: "{", Code: http.StatusBadRequest},
+	}...)
+}
+
+func TestOAuthClientRotationGracePeriod(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := ts.LoadTestOAuthSpec()
+	ts.createTestOAuthClient(spec, authClientID)
+
+	requestToken := func(t *testing.T, secret string, code int) {
+		t.Helper()
+
+		param := make(url.Values)
+		param.Set("grant_type", "client_credentials")
+		param.Set("redirect_uri", authRedirectUri)
+		param.Set("client_id", authClientID)
+
+		_, _ = ts.Run(t, test.TestCase{
+			Path:   "/APIID/oauth/token/",
+			Method: http.MethodPost,
+			Data:   param.Encode(),
+			Headers: map[string]string{
+				"Content-Type":  "application/x-www-form-urlencoded",
+				"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(authClientID+":"+secret)),
+			},
+			Code: code,
+		})
+	}
+
+	rotate := func(t *testing.T, query string) string {
+		t.Helper()
+
+		resp, err := ts.Run(t, test.TestCase{
+			Method:    http.MethodPut,
+			Path:      "/tyk/oauth/clients/999999/" + authClientID + "/rotate" + query,
+			AdminAuth: true,
+			Code:      http.StatusOK,
+		})
+		require.NoError(t, err)
+
+		var client NewClientRequest
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&client))
+		return client.ClientSecret
+	}
+
+	secret := rotate(t, "?grace_period=60")
+	requestToken(t, secret, http.StatusOK)
+	requestToken(t, authClientSecret, http.StatusOK)
+	requestToken(t, "wrong", http.StatusForbidden)
+
+	// the grace period is kept by updates, and ends with the next rotation
+	policyID := ts.CreatePolicy(func(p *user.Policy) {
+		p.AccessRights = map[string]user.AccessDefinition{spec.APIID: {APIID: spec.APIID}}
+	})
+	_, _ = ts.Run(t, test.TestCase{
+		Method:    http.MethodPut,
+		Path:      "/tyk/oauth/clients/999999/" + authClientID,
+		AdminAuth: true,
+		Data:      NewClientRequest{ClientRedirectURI: authRedirectUri, PolicyID: policyID},
+		Code:      http.StatusOK,
+	})
+	requestToken(t, authClientSecret, http.StatusOK)
+
+	newSecret := rotate(t, "")
+	requestToken(t, newSecret, http.StatusOK)
+	requestToken(t, secret, http.StatusForbidden)
+	requestToken(t, authClientSecret, http.StatusForbidden)
+
+	_, _ = ts.Run(t, test.TestCase{
+		Method:    http.MethodPut,
+		Path:      "/tyk/oauth/clients/999999/" + authClientID + "/rotate?grace_period=soon",
+		AdminAuth: true,
+		Code:      http.StatusBadRequest,
+	})
+}
+
+func TestRevokeOAuthClientTokens(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := ts.LoadTestOAuthSpec()
+	clientID := uuid.New()
+	ts.createOAuthClientIDAndTokens(t, spec, clientID)
+
+	tokensPath := "/tyk/oauth/clients/999999/" + clientID + "/tokens"
+
+	resp, err := ts.Run(t, test.TestCase{Path: tokensPath, AdminAuth: true, Code: http.StatusOK})
+	require.NoError(t, err)
+
+	var tokens []OAuthClientToken
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	require.Len(t, tokens, 3)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodDelete, Path: tokensPath, AdminAuth: true, BodyMatch: `"action":"revoked"`, Code: http.StatusOK},
+		{Path: tokensPath, AdminAuth: true, BodyMatch: `^\[\]`, Code: http.StatusOK},
+		{Path: "/APIID/get", Headers: map[string]string{"Authorization": "Bearer " + tokens[0].Token}, Code: http.StatusForbidden},
+		{Method: http.MethodDelete, Path: "/tyk/oauth/clients/999999/unknown/tokens", AdminAuth: true, Code: http.StatusNotFound},
+	}...)
+}
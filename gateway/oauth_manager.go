@@ -57,6 +57,9 @@ type OAuthClient struct {
 	MetaData          interface{} `json:"meta_data,omitempty"`
 	PolicyID          string      `json:"policyid"`
 	Description       string      `json:"description"`
+	// PreviousSecret is the secret replaced by a rotation, accepted until PreviousSecretExpires.
+	PreviousSecret        string `json:"previous_secret,omitempty"`
+	PreviousSecretExpires int64  `json:"previous_secret_expires,omitempty"`
 }
 
 func (oc *OAuthClient) GetId() string {
@@ -83,6 +86,17 @@ func (oc *OAuthClient) GetDescription() string {
 	return oc.Description
 }
 
+// clientSecretMatches returns true for the secret of the client, and for its previous secret
+// during the grace period of a rotation.
+func clientSecretMatches(client osin.Client, secret string) bool {
+	if client.GetSecret() == secret {
+		return true
+	}
+
+	oc, ok := client.(*OAuthClient)
+	return ok && oc.PreviousSecret != "" && oc.PreviousSecret == secret && time.Now().Unix() < oc.PreviousSecretExpires
+}
+
 // OAuthNotificationType const to reduce risk of collisions
 type OAuthNotificationType string
 
@@ -311,10 +325,16 @@ func RevokeAllTokens(storage ExtendedOsinStorageInterface, clientId, clientSecre
 		return http.StatusNotFound, resp, errors.New("error getting oauth client")
 	}
 
-	if client.GetSecret() != clientSecret {
+	if !clientSecretMatches(client, clientSecret) {
 		return http.StatusUnauthorized, resp, errors.New(oauthClientSecretWrong)
 	}
 
+	return revokeClientTokens(storage, clientId)
+}
+
+// revokeClientTokens revokes the access and refresh tokens issued to a client.
+func revokeClientTokens(storage ExtendedOsinStorageInterface, clientId string) (int, []string, error) {
+	resp := []string{}
 	clientTokens, err := storage.GetClientTokens(clientId)
 	if err != nil {
 		return http.StatusBadRequest, resp, errors.New("cannot retrieve client tokens")
@@ -385,6 +405,30 @@ func JSONToFormValues(r *http.Request) error {
 	return nil
 }
 
+// acceptPreviousClientSecret replaces the previous secret of a client, during the grace period
+// of a rotation, with its current secret for osin to authenticate the client.
+func (o *OAuthManager) acceptPreviousClientSecret(r *http.Request) {
+	clientID, secret, basic := r.BasicAuth()
+	if !basic {
+		clientID, secret = r.Form.Get("client_id"), r.Form.Get("client_secret")
+	}
+
+	if clientID == "" || secret == "" {
+		return
+	}
+
+	client, err := o.OsinServer.Storage.GetClient(clientID)
+	if err != nil || client == nil || client.GetSecret() == secret || !clientSecretMatches(client, secret) {
+		return
+	}
+
+	if basic {
+		r.SetBasicAuth(clientID, client.GetSecret())
+	} else {
+		r.Form.Set("client_secret", client.GetSecret())
+	}
+}
+
 // HandleAccess wraps an access request with osin's primitives
 func (o *OAuthManager) HandleAccess(r *http.Request) *osin.Response {
 	resp := o.OsinServer.NewResponse()
@@ -395,6 +439,8 @@ func (o *OAuthManager) HandleAccess(r *http.Request) *osin.Response {
 	if err := JSONToFormValues(r); err != nil {
 		log.Errorf("trying to set url values decoded from json body :%v", err)
 	}
+	o.acceptPreviousClientSecret(r)
+	o.detectRefreshTokenReuse(r)
 	var username string
 
 	if ar := o.OsinServer.HandleAccessRequest(resp, r); ar != nil {
@@ -458,6 +504,9 @@ func (o *OAuthManager) HandleAccess(r *http.Request) *osin.Response {
 
 		log.Debug("[OAuth] Finishing access request ")
 		o.OsinServer.FinishAccessRequest(resp, r, ar)
+		if !resp.IsError {
+			o.trackRefreshTokenFamily(r, ar, resp)
+		}
 		new_token, foundNewToken := resp.Output["access_token"]
 		if username != "" && foundNewToken {
 			log.Debug("Updating token data in key")
@@ -1018,12 +1067,8 @@ func (r *RedisOsinStorageInterface) SaveAccess(accessData *osin.AccessData) erro
 			return err
 		}
 		key := prefixRefresh + accessData.RefreshToken
-		refreshExpire := int64(1209600) // 14 days
-		if oauthRefreshExpire := r.Gw.GetConfig().OauthRefreshExpire; oauthRefreshExpire != 0 {
-			refreshExpire = oauthRefreshExpire
-		}
 		log.Debug("STORING ACCESS DATA: ", string(accessDataJSON))
-		err = r.store.SetKey(key, string(accessDataJSON), refreshExpire)
+		err = r.store.SetKey(key, string(accessDataJSON), r.Gw.oauthRefreshExpire())
 		if err != nil {
 			log.WithError(err).Error("could not save access data")
 		}
@@ -1033,6 +1078,14 @@ func (r *RedisOsinStorageInterface) SaveAccess(accessData *osin.AccessData) erro
 	return nil
 }
 
+// oauthRefreshExpire returns the number of seconds the refresh tokens are stored for.
+func (gw *Gateway) oauthRefreshExpire() int64 {
+	if oauthRefreshExpire := gw.GetConfig().OauthRefreshExpire; oauthRefreshExpire != 0 {
+		return oauthRefreshExpire
+	}
+	return 1209600 // 14 days
+}
+
 // LoadAccess will load access data from redis
 func (r *RedisOsinStorageInterface) LoadAccess(token string) (*osin.AccessData, error) {
 	key := prefixAccess + storage.HashKey(token, r.Gw.GetConfig().HashKeys)
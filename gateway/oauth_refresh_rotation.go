@@ -0,0 +1,174 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lonelycode/osin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/request"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+// The refresh tokens are rotated by osin, a refresh issues a new refresh token and removes the
+// presented one. The tokens issued for a grant and its refreshes form a token family, tracked to
+// revoke the live tokens of the family when a rotated refresh token is presented again.
+const (
+	prefixRefreshFamily  = "refresh-family."
+	prefixRefreshRotated = "refresh-rotated."
+	prefixTokenFamily    = "token-family."
+)
+
+// tokenFamily holds the live tokens of a token family.
+type tokenFamily struct {
+	ClientID     string `json:"client_id"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// rotatedRefreshToken is the record of a rotated refresh token.
+type rotatedRefreshToken struct {
+	Family    string `json:"family"`
+	RotatedAt int64  `json:"rotated_at"`
+}
+
+func (o *OAuthManager) refreshTokenStore() *storage.RedisCluster {
+	return &storage.RedisCluster{KeyPrefix: generateOAuthPrefix(o.API.APIID), HashKeys: false, ConnectionHandler: o.Gw.StorageConnectionHandler}
+}
+
+// trackRefreshTokenFamily records the tokens issued by an access request in their token family.
+// The family of a refresh is taken from the presented refresh token atomically, so that of two
+// concurrent refreshes with the same token only one carries the family on; a refresh token
+// without a family is a reused one, the tokens issued for it are revoked.
+func (o *OAuthManager) trackRefreshTokenFamily(r *http.Request, ar *osin.AccessRequest, resp *osin.Response) {
+	if !o.API.OAuthRefreshTokens.ReuseDetection {
+		return
+	}
+
+	refreshToken, _ := resp.Output["refresh_token"].(string)
+	accessToken, _ := resp.Output["access_token"].(string)
+	if refreshToken == "" {
+		return
+	}
+
+	store := o.refreshTokenStore()
+	expire := o.Gw.oauthRefreshExpire()
+
+	family := uuid.NewHex()
+	if ar.Type == osin.REFRESH_TOKEN {
+		// the presented refresh token was rotated
+		var err error
+		family, err = store.GetAndDeleteKey(prefixRefreshFamily + storage.HashStr(ar.Code))
+		if err != nil {
+			o.rejectRefreshTokenReuse(r, store, tokenFamily{ClientID: ar.Client.GetId(), AccessToken: accessToken, RefreshToken: refreshToken}, resp)
+			return
+		}
+
+		rotated, _ := json.Marshal(rotatedRefreshToken{Family: family, RotatedAt: time.Now().Unix()})
+		store.SetKey(prefixRefreshRotated+storage.HashStr(ar.Code), string(rotated), expire)
+	}
+
+	members, _ := json.Marshal(tokenFamily{ClientID: ar.Client.GetId(), AccessToken: accessToken, RefreshToken: refreshToken})
+	if err := store.SetKey(prefixTokenFamily+family, string(members), expire); err != nil {
+		log.WithError(err).Error("could not save OAuth token family")
+		return
+	}
+	store.SetKey(prefixRefreshFamily+storage.HashStr(refreshToken), family, expire)
+}
+
+// rejectRefreshTokenReuse revokes the tokens issued by the refresh of a refresh token without a
+// family, and turns the response into an error. The refresh token was taken by a concurrent
+// refresh, or issued before the reuse detection was enabled.
+func (o *OAuthManager) rejectRefreshTokenReuse(r *http.Request, store storage.Handler, issued tokenFamily, resp *osin.Response) {
+	o.revokeTokens(store, issued)
+	resp.SetError(osin.E_INVALID_GRANT, "")
+
+	log.WithFields(logrus.Fields{
+		"prefix":     "oauth",
+		"api_id":     o.API.APIID,
+		"client_id":  issued.ClientID,
+		"RemoteAddr": request.RealIP(r),
+	}).Warning("OAuth refresh token without a token family presented, rejecting the refresh")
+
+	// the family of a concurrent refresh is revoked the same way as for a rotated token
+	o.detectRefreshTokenReuse(r)
+}
+
+// detectRefreshTokenReuse revokes the token family of a rotated refresh token presented again,
+// unless it was rotated less than the reuse interval of the API ago. The request itself is
+// rejected by osin, as the rotated refresh token was removed.
+func (o *OAuthManager) detectRefreshTokenReuse(r *http.Request) {
+	if !o.API.OAuthRefreshTokens.ReuseDetection || r.Form.Get("grant_type") != string(osin.REFRESH_TOKEN) {
+		return
+	}
+
+	refreshToken := r.Form.Get("refresh_token")
+	if refreshToken == "" {
+		return
+	}
+
+	store := o.refreshTokenStore()
+	value, err := store.GetKey(prefixRefreshRotated + storage.HashStr(refreshToken))
+	if err != nil {
+		return
+	}
+
+	var rotated rotatedRefreshToken
+	if err := json.Unmarshal([]byte(value), &rotated); err != nil {
+		log.WithError(err).Error("could not decode rotated OAuth refresh token")
+		return
+	}
+
+	revoke := time.Now().Unix()-rotated.RotatedAt >= o.API.OAuthRefreshTokens.ReuseInterval
+
+	var clientID string
+	if members, err := store.GetKey(prefixTokenFamily + rotated.Family); err == nil {
+		var family tokenFamily
+		if json.Unmarshal([]byte(members), &family) == nil {
+			clientID = family.ClientID
+			if revoke {
+				o.revokeTokenFamily(store, rotated.Family, family)
+			}
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix":     "oauth",
+		"api_id":     o.API.APIID,
+		"client_id":  clientID,
+		"revoked":    revoke,
+		"RemoteAddr": request.RealIP(r),
+	}).Warning("Rotated OAuth refresh token presented again")
+
+	o.API.FireEvent(EventOAuthRefreshTokenReused, EventOAuthRefreshTokenReusedMeta{
+		EventMetaDefault: EventMetaDefault{Message: "Rotated OAuth refresh token reused", OriginatingRequest: EncodeRequestToEvent(r)},
+		ClientID:         clientID,
+		Revoked:          revoke,
+	})
+}
+
+// revokeTokenFamily revokes the live tokens of a token family.
+func (o *OAuthManager) revokeTokenFamily(store storage.Handler, id string, family tokenFamily) {
+	o.revokeTokens(store, family)
+	store.DeleteKey(prefixTokenFamily + id)
+}
+
+// revokeTokens revokes the tokens of a token family.
+func (o *OAuthManager) revokeTokens(store storage.Handler, family tokenFamily) {
+	if family.AccessToken != "" {
+		o.OsinServer.Storage.RemoveAccess(family.AccessToken)
+	}
+	if family.RefreshToken != "" {
+		o.OsinServer.Storage.RemoveRefresh(family.RefreshToken)
+		store.DeleteKey(prefixRefreshFamily + storage.HashStr(family.RefreshToken))
+	}
+
+	o.Gw.MainNotifier.Notify(Notification{
+		Command: KeySpaceUpdateNotification,
+		Payload: family.AccessToken,
+		Gw:      o.Gw,
+	})
+}
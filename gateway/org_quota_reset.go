@@ -0,0 +1,253 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	// orgQuotaResetPrefix prefixes the Redis keys of the quota reset schedules of organisations.
+	orgQuotaResetPrefix = "org-quota-reset-"
+	// orgQuotaResetIndex is the Redis set holding the IDs of the organisations with a schedule.
+	orgQuotaResetIndex = "org-quota-reset-index"
+	// orgQuotaResetInterval is how often the schedules are checked, the resolution of cron schedules.
+	orgQuotaResetInterval = time.Minute
+)
+
+var errOrgQuotaResetNotFound = errors.New("Quota reset schedule not found")
+
+// orgQuotaResetSchedule resets the quota of an organisation on a cron schedule, e.g. on the
+// first day of every month, on top of the renewal rate of the quota.
+type orgQuotaResetSchedule struct {
+	OrgID string `json:"org_id"`
+	// Schedule is a standard 5 fields cron expression, or a descriptor like `@monthly`.
+	Schedule string `json:"schedule"`
+	// Timezone is the IANA time zone the schedule is evaluated in, UTC when empty.
+	Timezone  string `json:"timezone,omitempty"`
+	NextReset int64  `json:"next_reset"`
+	LastReset int64  `json:"last_reset,omitempty"`
+}
+
+// next returns the time of the first reset of the schedule after now.
+func (s *orgQuotaResetSchedule) next(now time.Time) (time.Time, error) {
+	schedule, err := cron.ParseStandard(s.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc := time.UTC
+	if s.Timezone != "" {
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return schedule.Next(now.In(loc)), nil
+}
+
+func (gw *Gateway) orgQuotaResetSchedule(ctx context.Context, orgID string) (*orgQuotaResetSchedule, error) {
+	data, err := gw.SessionLimiter.limiterStorage.Get(ctx, orgQuotaResetPrefix+orgID).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errOrgQuotaResetNotFound
+		}
+		return nil, err
+	}
+
+	schedule := &orgQuotaResetSchedule{}
+	if err := json.Unmarshal(data, schedule); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (gw *Gateway) storeOrgQuotaResetSchedule(ctx context.Context, schedule *orgQuotaResetSchedule) error {
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+
+	_, err = gw.SessionLimiter.limiterStorage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, orgQuotaResetPrefix+schedule.OrgID, data, 0)
+		pipe.SAdd(ctx, orgQuotaResetIndex, schedule.OrgID)
+		return nil
+	})
+	return err
+}
+
+// resetOrgQuota resets the quota of the organisation session, and its quota counter.
+func (gw *Gateway) resetOrgQuota(orgID string, sessionManager SessionHandler, session *user.SessionState) {
+	sessionManager.ResetQuota(orgID, session, false)
+	session.QuotaRenews = time.Now().Unix() + session.QuotaRenewalRate
+	rawKey := QuotaKeyPrefix + storage.HashKey(orgID, gw.GetConfig().HashKeys)
+
+	// manage quotas separately
+	gw.DefaultQuotaStore.RemoveSession(orgID, rawKey, false)
+}
+
+// orgSessionManager returns the session manager of the organisation sessions of orgID.
+func (gw *Gateway) orgSessionManager(orgID string) SessionHandler {
+	if spec := gw.getSpecForOrg(orgID); spec != nil {
+		return spec.OrgSessionManager
+	}
+
+	return &gw.DefaultOrgStore
+}
+
+// runOrgQuotaResets resets the quotas of the organisations whose scheduled reset is due. A
+// single Gateway of the cluster checks the schedules per interval.
+func (gw *Gateway) runOrgQuotaResets() error {
+	store := &storage.RedisCluster{KeyPrefix: "", HashKeys: false, ConnectionHandler: gw.StorageConnectionHandler}
+	ok, err := store.Lock("org-quota-reset-lock", orgQuotaResetInterval*9/10)
+	if err != nil {
+		log.WithError(err).Error("error acquiring lock to reset the organisation quotas")
+		return err
+	}
+
+	if !ok {
+		log.Debug("organisation quota reset lock not acquired, skipping")
+		return nil
+	}
+
+	return gw.resetScheduledOrgQuotas(gw.ctx, time.Now())
+}
+
+// resetScheduledOrgQuotas resets the quotas of the organisations whose scheduled reset is due
+// at now, and schedules their next reset. Resets missed while no Gateway was running are
+// caught up with a single reset.
+func (gw *Gateway) resetScheduledOrgQuotas(ctx context.Context, now time.Time) error {
+	orgIDs, err := gw.SessionLimiter.limiterStorage.SMembers(ctx, orgQuotaResetIndex).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, orgID := range orgIDs {
+		logger := log.WithFields(logrus.Fields{"prefix": "api", "org": orgID})
+
+		schedule, err := gw.orgQuotaResetSchedule(ctx, orgID)
+		if err != nil {
+			if !errors.Is(err, errOrgQuotaResetNotFound) {
+				logger.WithError(err).Error("Couldn't get the quota reset schedule of the organisation")
+			}
+			continue
+		}
+
+		if schedule.NextReset > now.Unix() {
+			continue
+		}
+
+		next, err := schedule.next(now)
+		if err != nil {
+			logger.WithError(err).Error("Couldn't parse the quota reset schedule of the organisation")
+			continue
+		}
+
+		sessionManager := gw.orgSessionManager(orgID)
+		if session, found := sessionManager.SessionDetail(orgID, orgID, false); found {
+			gw.resetOrgQuota(orgID, sessionManager, &session)
+			session.QuotaRemaining = session.QuotaMax
+			session.LastUpdated = strconv.Itoa(int(now.Unix()))
+
+			if err := sessionManager.UpdateSession(orgID, &session, 0, false); err != nil {
+				logger.WithError(err).Error("Couldn't update the organisation session after resetting its quota")
+			}
+			gw.SessionCache.Delete(orgID)
+
+			logger.Info("Reset the organisation quota on schedule.")
+		}
+
+		schedule.LastReset = now.Unix()
+		schedule.NextReset = next.Unix()
+		if err := gw.storeOrgQuotaResetSchedule(ctx, schedule); err != nil {
+			logger.WithError(err).Error("Couldn't store the quota reset schedule of the organisation")
+		}
+	}
+
+	return nil
+}
+
+func (gw *Gateway) handleSetOrgQuotaResetSchedule(ctx context.Context, orgID string, r *http.Request) (interface{}, int) {
+	schedule := &orgQuotaResetSchedule{}
+	if err := json.NewDecoder(r.Body).Decode(schedule); err != nil {
+		return apiError("Request malformed"), http.StatusBadRequest
+	}
+
+	if schedule.OrgID != "" && schedule.OrgID != orgID {
+		return apiError("Request org ID does not match that in the schedule!"), http.StatusBadRequest
+	}
+	schedule.OrgID = orgID
+
+	next, err := schedule.next(time.Now())
+	if err != nil {
+		return apiError("Invalid quota reset schedule: " + err.Error()), http.StatusBadRequest
+	}
+	schedule.NextReset = next.Unix()
+	schedule.LastReset = 0
+
+	if previous, err := gw.orgQuotaResetSchedule(ctx, orgID); err == nil {
+		schedule.LastReset = previous.LastReset
+	}
+
+	if err := gw.storeOrgQuotaResetSchedule(ctx, schedule); err != nil {
+		log.WithError(err).Error("Couldn't store the quota reset schedule")
+		return apiError("Failed to store quota reset schedule"), http.StatusInternalServerError
+	}
+
+	return schedule, http.StatusOK
+}
+
+func (gw *Gateway) handleDeleteOrgQuotaResetSchedule(ctx context.Context, orgID string) (interface{}, int) {
+	conn := gw.SessionLimiter.limiterStorage
+
+	deleted, err := conn.Del(ctx, orgQuotaResetPrefix+orgID).Result()
+	if err != nil {
+		return apiError("Failed to delete quota reset schedule"), http.StatusInternalServerError
+	}
+
+	if deleted == 0 {
+		return apiError(errOrgQuotaResetNotFound.Error()), http.StatusNotFound
+	}
+	conn.SRem(ctx, orgQuotaResetIndex, orgID)
+
+	return apiModifyKeySuccess{Key: orgID, Status: "ok", Action: "deleted"}, http.StatusOK
+}
+
+// orgQuotaResetHandler returns, stores and deletes the quota reset schedule of an organisation.
+func (gw *Gateway) orgQuotaResetHandler(w http.ResponseWriter, r *http.Request) {
+	orgID := mux.Vars(r)["keyName"]
+
+	var obj interface{}
+	var code int
+
+	switch r.Method {
+	case http.MethodGet:
+		schedule, err := gw.orgQuotaResetSchedule(r.Context(), orgID)
+		switch {
+		case errors.Is(err, errOrgQuotaResetNotFound):
+			obj, code = apiError(err.Error()), http.StatusNotFound
+		case err != nil:
+			obj, code = apiError("Couldn't get quota reset schedule"), http.StatusInternalServerError
+		default:
+			obj, code = schedule, http.StatusOK
+		}
+	case http.MethodPost, http.MethodPut:
+		obj, code = gw.handleSetOrgQuotaResetSchedule(r.Context(), orgID, r)
+	case http.MethodDelete:
+		obj, code = gw.handleDeleteOrgQuotaResetSchedule(r.Context(), orgID)
+	}
+
+	doJSONWrite(w, code, obj)
+}
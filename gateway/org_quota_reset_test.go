@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestOrgQuotaResetSchedule(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnforceOrgQuotas = true
+		globalConf.ExperimentalProcessOrgOffThread = false
+	})
+	defer ts.Close()
+
+	orgID := "scheduled-org-" + uuid.New()
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.UseKeylessAccess = true
+		spec.OrgID = orgID
+		spec.Proxy.ListenPath = "/"
+	})
+
+	schedulePath := "/tyk/org/keys/" + orgID + "/quota-reset-schedule"
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/tyk/org/keys/" + orgID + "?reset_quota=1", AdminAuth: true, Method: http.MethodPost, Code: http.StatusOK,
+			Data: map[string]interface{}{"org_id": orgID, "quota_max": 2, "quota_remaining": 2, "quota_renewal_rate": 3600}},
+		{Code: http.StatusOK},
+		{Code: http.StatusOK},
+		{Code: http.StatusForbidden},
+		{Path: schedulePath, AdminAuth: true, Code: http.StatusNotFound},
+		{Path: schedulePath, AdminAuth: true, Method: http.MethodPut, Data: map[string]string{"schedule": "not a schedule"}, Code: http.StatusBadRequest},
+		{Path: schedulePath, AdminAuth: true, Method: http.MethodPut, Data: map[string]string{"schedule": "@monthly", "timezone": "Mars/Olympus_Mons"}, Code: http.StatusBadRequest},
+	}...)
+
+	resp, _ := ts.Run(t, test.TestCase{Path: schedulePath, AdminAuth: true, Method: http.MethodPut, Data: map[string]string{"schedule": "0 0 1 * *"}, Code: http.StatusOK})
+	schedule := &orgQuotaResetSchedule{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(schedule))
+	assert.Equal(t, orgID, schedule.OrgID)
+	nextReset := time.Unix(schedule.NextReset, 0).UTC()
+	assert.Equal(t, 1, nextReset.Day())
+	assert.True(t, nextReset.After(time.Now()))
+
+	ctx := context.Background()
+
+	t.Run("not due", func(t *testing.T) {
+		require.NoError(t, ts.Gw.resetScheduledOrgQuotas(ctx, time.Now()))
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusForbidden})
+	})
+
+	t.Run("due", func(t *testing.T) {
+		require.NoError(t, ts.Gw.resetScheduledOrgQuotas(ctx, nextReset))
+		_, _ = ts.Run(t, test.TestCase{Code: http.StatusOK})
+
+		stored, err := ts.Gw.orgQuotaResetSchedule(ctx, orgID)
+		require.NoError(t, err)
+		assert.Equal(t, nextReset.Unix(), stored.LastReset)
+		assert.Equal(t, nextReset.AddDate(0, 1, 0).Unix(), stored.NextReset)
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: schedulePath, AdminAuth: true, Code: http.StatusOK, BodyMatch: `"last_reset":`},
+		{Path: schedulePath, AdminAuth: true, Method: http.MethodDelete, Code: http.StatusOK},
+		{Path: schedulePath, AdminAuth: true, Method: http.MethodDelete, Code: http.StatusNotFound},
+		{Path: schedulePath, AdminAuth: true, Code: http.StatusNotFound},
+	}...)
+}
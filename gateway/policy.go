@@ -34,6 +34,9 @@ type DBAccessDefinition struct {
 
 	// Endpoints contains endpoint rate limit settings.
 	Endpoints user.Endpoints `json:"endpoints,omitempty"`
+
+	// Scopes are the scopes granted for the API as a resource.
+	Scopes []string `json:"scopes,omitempty"`
 }
 
 func (d *DBAccessDefinition) ToRegularAD() user.AccessDefinition {
@@ -47,6 +50,7 @@ func (d *DBAccessDefinition) ToRegularAD() user.AccessDefinition {
 		DisableIntrospection: d.DisableIntrospection,
 		FieldAccessRights:    d.FieldAccessRights,
 		Endpoints:            d.Endpoints,
+		Scopes:               d.Scopes,
 	}
 
 	if d.Limit != nil {
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const (
+	defaultPolicyBundlePollInterval = 30
+	policyBundleDownloadTimeout     = 10 * time.Second
+	// policyBundleMaxSize bounds the size of downloaded bundles.
+	policyBundleMaxSize = 8 << 20
+
+	policyBundleAdmin    = "admin"
+	policyBundleRequests = "requests"
+)
+
+var errPolicyBundleNotLoaded = errors.New("Policy bundle not loaded")
+
+// policyBundle is the content of a policy bundle.
+type policyBundle struct {
+	// Admin are the policies of the Control API requests.
+	Admin []apidef.RequestPolicy `json:"admin"`
+	// Requests are the policies of the requests of all the APIs.
+	Requests []apidef.RequestPolicy `json:"requests"`
+}
+
+// compiledPolicyBundle is a loaded policy bundle.
+type compiledPolicyBundle struct {
+	// revision is the SHA-256 of the bundle, logged with the decisions.
+	revision string
+	admin    []compiledRequestPolicy
+	requests []compiledRequestPolicy
+}
+
+func compilePolicyBundle(data []byte) (*compiledPolicyBundle, error) {
+	var bundle policyBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	compiled := &compiledPolicyBundle{revision: hex.EncodeToString(sum[:])}
+
+	compile := func(kind string, policies []apidef.RequestPolicy) ([]compiledRequestPolicy, error) {
+		out := make([]compiledRequestPolicy, 0, len(policies))
+		for _, policy := range policies {
+			c := compileRequestPolicy(policy)
+			if c.err != nil {
+				return nil, fmt.Errorf("%s policy %q: %w", kind, policy.Name, c.err)
+			}
+			out = append(out, c)
+		}
+		return out, nil
+	}
+
+	var err error
+	if compiled.admin, err = compile(policyBundleAdmin, bundle.Admin); err != nil {
+		return nil, err
+	}
+	if compiled.requests, err = compile(policyBundleRequests, bundle.Requests); err != nil {
+		return nil, err
+	}
+
+	return compiled, nil
+}
+
+// readPolicyBundle reads the bundle from its path, or downloads it from its URL.
+func (gw *Gateway) readPolicyBundle() ([]byte, error) {
+	conf := gw.GetConfig().PolicyBundle
+	if conf.Path != "" {
+		return os.ReadFile(conf.Path)
+	}
+	if conf.URL == "" {
+		return nil, errors.New("no policy bundle path or URL")
+	}
+
+	client := &http.Client{Timeout: policyBundleDownloadTimeout}
+	resp, err := client.Get(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status downloading policy bundle: %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, policyBundleMaxSize))
+}
+
+// loadPolicyBundle loads the policy bundle. The previous bundle is kept when the bundle
+// can't be read or compiled.
+func (gw *Gateway) loadPolicyBundle() error {
+	data, err := gw.readPolicyBundle()
+	if err != nil {
+		return err
+	}
+
+	bundle, err := compilePolicyBundle(data)
+	if err != nil {
+		return err
+	}
+
+	if previous := gw.policyBundle.Swap(bundle); previous == nil || previous.revision != bundle.revision {
+		mainLog.WithField("revision", bundle.revision).Info("Loaded policy bundle")
+	}
+
+	return nil
+}
+
+// evaluatePolicyBundle evaluates the admin or requests policies of the bundle for a request.
+func (gw *Gateway) evaluatePolicyBundle(kind string, r *http.Request, logger *logrus.Entry) (error, int) {
+	bundle := gw.policyBundle.Load()
+	if bundle == nil {
+		return errPolicyBundleNotLoaded, http.StatusServiceUnavailable
+	}
+
+	policies := bundle.requests
+	if kind == policyBundleAdmin {
+		policies = bundle.admin
+	}
+
+	policy, err, code := evaluateRequestPolicies(r, policies, requestPolicyParameters(r), logger)
+
+	if gw.GetConfig().PolicyBundle.DecisionLogs {
+		fields := logrus.Fields{
+			"bundle":   kind,
+			"revision": bundle.revision,
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"code":     code,
+			"decision": "allow",
+		}
+		if policy != nil {
+			fields["policy"] = policy.Name
+		}
+		if err != nil {
+			fields["decision"] = "deny"
+		}
+		logger.WithFields(fields).Info("Policy bundle decision")
+	}
+
+	return err, code
+}
+
+// checkPolicyBundle gates the Control API requests with the admin policies of the bundle. The
+// requests are allowed until a bundle is loaded, unless the admin policies fail closed, so the
+// Gateway can still be operated when the bundle is missing.
+func (gw *Gateway) checkPolicyBundle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conf := gw.GetConfig().PolicyBundle
+		if !conf.Enabled || gw.policyBundle.Load() == nil && !conf.AdminFailClosed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err, code := gw.evaluatePolicyBundle(policyBundleAdmin, r, mainLog.WithField("prefix", "policy-bundle")); err != nil {
+			doJSONWrite(w, code, apiError(err.Error()))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PolicyBundleMiddleware gates the requests of the API with the requests policies of the bundle.
+type PolicyBundleMiddleware struct {
+	*BaseMiddleware
+}
+
+func (m *PolicyBundleMiddleware) Name() string {
+	return "PolicyBundleMiddleware"
+}
+
+func (m *PolicyBundleMiddleware) EnabledForSpec() bool {
+	return m.Gw.GetConfig().PolicyBundle.Enabled
+}
+
+func (m *PolicyBundleMiddleware) ProcessRequest(_ http.ResponseWriter, r *http.Request, _ interface{}) (error, int) {
+	return m.Gw.evaluatePolicyBundle(policyBundleRequests, r, m.Logger())
+}
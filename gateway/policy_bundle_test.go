@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+const testPolicyBundle = `{
+	"admin": [
+		{"name": "read-only", "when": "request.method != 'GET'", "action": "deny", "code": 403, "message": "Read only Control API"}
+	],
+	"requests": [
+		{"name": "blocked-clients", "when": "request.header['x-client'] == 'blocked'", "action": "deny"},
+		{"name": "tag", "when": "true", "action": "transform", "set_headers": {"X-Bundle": "'yes'"}}
+	]
+}`
+
+func TestPolicyBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicyBundle), 0o644))
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.PolicyBundle = config.PolicyBundleConfig{Enabled: true, Path: path, DecisionLogs: true}
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/bundle/"
+	})
+
+	t.Run("not loaded", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/bundle/", Code: http.StatusServiceUnavailable, BodyMatch: "Policy bundle not loaded"},
+			{Path: "/tyk/apis", AdminAuth: true, Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("not loaded, admin fail closed", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.PolicyBundle.AdminFailClosed = true
+		ts.Gw.SetConfig(globalConf)
+		defer func() {
+			globalConf.PolicyBundle.AdminFailClosed = false
+			ts.Gw.SetConfig(globalConf)
+		}()
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/tyk/apis", AdminAuth: true, Code: http.StatusServiceUnavailable,
+			BodyMatch: "Policy bundle not loaded"})
+	})
+
+	require.NoError(t, ts.Gw.loadPolicyBundle())
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/bundle/", Code: http.StatusOK, BodyMatch: `"X-Bundle":"yes"`},
+		{Path: "/bundle/", Headers: map[string]string{"X-Client": "blocked"}, Code: http.StatusForbidden},
+		{Path: "/tyk/apis", AdminAuth: true, Code: http.StatusOK},
+		{Path: "/tyk/reload/group", AdminAuth: true, Code: http.StatusOK},
+		{Path: "/tyk/apis/test", Method: http.MethodDelete, AdminAuth: true, Code: http.StatusForbidden,
+			BodyMatch: "Read only Control API"},
+		{Path: "/tyk/apis", Code: http.StatusForbidden, BodyMatch: "invalid or missing key"},
+	}...)
+
+	t.Run("invalid bundles keep the loaded bundle", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(`{"requests": [{"name": "broken", "when": "(", "action": "deny"}]}`), 0o644))
+		assert.Error(t, ts.Gw.loadPolicyBundle())
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/bundle/", Headers: map[string]string{"X-Client": "blocked"}, Code: http.StatusForbidden})
+	})
+}
+
+func TestPolicyBundle_URL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"requests": [{"name": "deny-all", "when": "true", "action": "deny", "code": 401}]}`))
+	}))
+	defer server.Close()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.PolicyBundle = config.PolicyBundleConfig{Enabled: true, URL: server.URL}
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/bundle/"
+	})
+
+	require.NoError(t, ts.Gw.loadPolicyBundle())
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/bundle/", Code: http.StatusUnauthorized},
+		{Path: "/tyk/apis", AdminAuth: true, Code: http.StatusOK},
+	}...)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/TykTechnologies/tyk/internal/httputil"
 	"github.com/TykTechnologies/tyk/tcp"
 
+	"github.com/gocraft/health"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/http2"
@@ -34,6 +35,12 @@ type handleWrapper struct {
 
 	maxContentLength   int64
 	maxRequestBodySize int64
+
+	// strict is set when the listener checks the framing of requests
+	strict bool
+
+	// normalization configures the normalization of request paths
+	normalization config.PathNormalizationConfig
 }
 
 // h2cWrapper tracks handleWrapper for swapping w.router on reloads.
@@ -63,6 +70,19 @@ func (h *handleWrapper) handleRequestLimits(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *handleWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.strict {
+		httputil.RestoreTLSState(r)
+	}
+
+	// paths are normalized before routing, so that the access rules
+	// and endpoint configurations can't be bypassed with path variants
+	if h.normalization.Enabled {
+		if err := httputil.NormalizeRequestPath(r, h.normalization.Strict); err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError("Request path is not allowed"))
+			return
+		}
+	}
+
 	if r.Body != nil {
 		if !h.handleRequestLimits(w, r) {
 			return
@@ -74,12 +94,14 @@ func (h *handleWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// make request body to be nopCloser and re-readable
 		// before serve it through chain of middlewares
 		if err := nopCloseRequestBodyErr(r); err != nil {
-			if err.Error() == "http: request body too large" {
+			switch {
+			case httputil.IsAnomaly(err):
+				doJSONWrite(w, http.StatusBadRequest, apiError(http.StatusText(http.StatusBadRequest)))
+			case err.Error() == "http: request body too large":
 				httputil.EntityTooLarge(w, r)
-				return
+			default:
+				httputil.InternalServerError(w, r)
 			}
-
-			httputil.InternalServerError(w, r)
 			return
 		}
 	} else {
@@ -455,6 +477,8 @@ func (m *proxyMux) serve(gw *Gateway) {
 			h := &handleWrapper{
 				router:             p.router,
 				maxRequestBodySize: conf.HttpServerOptions.MaxRequestBodySize,
+				strict:             conf.HttpServerOptions.StrictParsing.EnabledForPort(p.port),
+				normalization:      conf.HttpServerOptions.PathNormalization,
 			}
 
 			// by default enabling h2c by wrapping handler in h2c. This ensures all features including tracing work
@@ -475,16 +499,46 @@ func (m *proxyMux) serve(gw *Gateway) {
 			if gw.ConnectionWatcher != nil {
 				p.httpServer.ConnState = gw.ConnectionWatcher.OnStateChange
 			}
+			if conf.EnableConnectionMetrics {
+				watch := p.httpServer.ConnState
+				p.httpServer.ConnState = func(conn net.Conn, state http.ConnState) {
+					if watch != nil {
+						watch(conn, state)
+					}
+					gw.connectionMetrics.clientConnState(conn, state)
+				}
+			}
 
 			if conf.CloseConnections {
 				p.httpServer.SetKeepAlivesEnabled(false)
 			}
-			go p.httpServer.Serve(p.listener)
+
+			listener := p.listener
+			if h.strict {
+				strict := httputil.NewStrictListener(listener, gw.recordRequestAnomaly)
+				p.httpServer.ConnContext = strict.ConnContext
+				listener = strict
+			}
+			go p.httpServer.Serve(listener)
 		}
 		p.started = true
 	}
 }
 
+// recordRequestAnomaly counts the requests rejected by strict listeners.
+func (gw *Gateway) recordRequestAnomaly(kind string, remote net.Addr) {
+	gw.RequestAnomalies.Add(kind)
+
+	mainLog.WithFields(logrus.Fields{
+		"anomaly": kind,
+		"origin":  remote.String(),
+	}).Warning("Rejected ambiguous request")
+
+	if instrumentationEnabled {
+		instrument.NewJob("RequestAnomaly").EventKv(kind, health.Kvs{"origin": remote.String()})
+	}
+}
+
 func target(listenAddress string, listenPort int) string {
 	return fmt.Sprintf("%s:%d", listenAddress, listenPort)
 }
@@ -535,6 +589,9 @@ func (m *proxyMux) generateListener(listenPort int, protocol string, gw *Gateway
 		}
 
 		tlsConfig.GetConfigForClient = gw.getTLSConfigForClient(&tlsConfig, listenPort)
+		if conf.EnableConnectionMetrics {
+			tlsConfig.GetConfigForClient = gw.connectionMetrics.instrumentTLS(tlsConfig.GetConfigForClient)
+		}
 		l, err = tls.Listen("tcp", targetPort, &tlsConfig)
 
 	default:
@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,10 +14,14 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/TykTechnologies/tyk/apidef"
 	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/httputil"
 	"github.com/TykTechnologies/tyk/test"
 )
 
@@ -441,3 +446,120 @@ func TestRequestBodyLimit(t *testing.T) {
 		{Path: "/sample/", Method: "POST", Data: strings.Repeat("a", 1025), Code: http.StatusRequestEntityTooLarge},
 	}...)
 }
+
+func TestStrictParsing(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.HttpServerOptions.StrictParsing.Enabled = true
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+	})
+
+	send := func(t *testing.T, request string) int {
+		t.Helper()
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte(request))
+		assert.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, send(t, "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n"))
+	assert.Equal(t, http.StatusBadRequest, send(t, "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n"))
+	assert.Equal(t, http.StatusBadRequest, send(t, "GET / HTTP/1.1\r\nHost: a\r\nX-Folded: a\r\n b\r\n\r\n"))
+	assert.Equal(t, http.StatusBadRequest, send(t, "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n5;\"\r\nhello\r\n0\r\n\r\n"))
+
+	t.Run("anomaly in a streamed body", func(t *testing.T) {
+		conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		// the body isn't buffered, its abnormal chunk is found while it's proxied
+		_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n"))
+		assert.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		_, err = conn.Write([]byte("5\r\nhello\n0\r\n\r\n"))
+		assert.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	assert.Equal(t, map[string]uint64{
+		httputil.AnomalyBareLF:                            1,
+		httputil.AnomalyContentLengthWithTransferEncoding: 1,
+		httputil.AnomalyObsFold:                           1,
+		httputil.AnomalyChunkExtension:                    1,
+	}, ts.Gw.RequestAnomalies.Snapshot())
+}
+
+func TestPathNormalization(t *testing.T) {
+	setup := func(t *testing.T, strict bool) (*Test, func(path string) int) {
+		t.Helper()
+
+		ts := StartTest(func(globalConf *config.Config) {
+			globalConf.HttpServerOptions.PathNormalization.Enabled = true
+			globalConf.HttpServerOptions.PathNormalization.Strict = strict
+		})
+
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+				v.ExtendedPaths.BlackList = []apidef.EndPointMeta{{
+					Path:          "/admin",
+					MethodActions: map[string]apidef.EndpointMethodMeta{http.MethodGet: {Action: apidef.NoAction}},
+				}}
+			})
+			spec.Proxy.ListenPath = "/"
+		})
+
+		send := func(path string) int {
+			conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: a\r\n\r\n"))
+			require.NoError(t, err)
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			return resp.StatusCode
+		}
+
+		return ts, send
+	}
+
+	t.Run("normalize", func(t *testing.T) {
+		ts, send := setup(t, false)
+		defer ts.Close()
+
+		for _, path := range []string{"/admin", "//admin", "/./admin", "/public/../admin", "/%61dmin", "/public/%2e%2e/admin", "/../admin"} {
+			assert.Equal(t, http.StatusForbidden, send(path), path)
+		}
+		assert.Equal(t, http.StatusOK, send("/public//users"))
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		ts, send := setup(t, true)
+		defer ts.Close()
+
+		assert.Equal(t, http.StatusForbidden, send("/public/../admin"))
+		assert.Equal(t, http.StatusBadRequest, send("/../admin"))
+		assert.Equal(t, http.StatusBadRequest, send("/users%2Fadmin"))
+		assert.Equal(t, http.StatusOK, send("/users"))
+	})
+}
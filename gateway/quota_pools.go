@@ -0,0 +1,300 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TykTechnologies/tyk/internal/cache"
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// The prefixes of the Redis keys of the pools don't overlap, so that no pool ID makes the key
+// of a pool clash with another key.
+const (
+	// quotaPoolPrefix prefixes the Redis keys of the quota pool definitions.
+	quotaPoolPrefix = "quotapool-def-"
+	// quotaPoolIndex is the Redis set holding the IDs of the quota pools.
+	quotaPoolIndex = "quotapool-idx"
+	// quotaPoolUsagePrefix prefixes the Redis hashes of the quota used by every key of a
+	// pool in the current period.
+	quotaPoolUsagePrefix = "quotapool-usage-"
+)
+
+var errQuotaPoolNotFound = errors.New("Quota pool not found")
+
+// quotaPool is a quota shared by all the keys referencing it, e.g. the keys of the
+// applications of a customer sharing an organisation-wide quota.
+type quotaPool struct {
+	ID               string               `json:"id"`
+	Name             string               `json:"name,omitempty"`
+	QuotaMax         int64                `json:"quota_max"`
+	QuotaRenewalRate int64                `json:"quota_renewal_rate"`
+	QuotaAlignment   *user.QuotaAlignment `json:"quota_alignment,omitempty"`
+}
+
+// limit returns the quota of the pool. The quotas of pools aren't prorated, since pools
+// aren't created with the keys sharing them.
+func (p *quotaPool) limit() *user.APILimit {
+	limit := &user.APILimit{
+		QuotaMax:         p.QuotaMax,
+		QuotaRenewalRate: p.QuotaRenewalRate,
+	}
+
+	if p.QuotaAlignment != nil {
+		alignment := *p.QuotaAlignment
+		alignment.Prorate = false
+		limit.QuotaAlignment = &alignment
+	}
+
+	return limit
+}
+
+// quotaPoolUsage is the quota used in the current period of a pool, in total and per key.
+type quotaPoolUsage struct {
+	PoolID         string           `json:"pool_id"`
+	QuotaMax       int64            `json:"quota_max"`
+	QuotaUsed      int64            `json:"quota_used"`
+	QuotaRemaining int64            `json:"quota_remaining"`
+	QuotaRenews    int64            `json:"quota_renews"`
+	Keys           map[string]int64 `json:"keys"`
+}
+
+// quotaPoolCounterKey returns the key of the quota counter of a pool. The prefixes of the
+// other keys of pools differ from QuotaKeyPrefix so that they don't clash with it.
+func quotaPoolCounterKey(id string) string {
+	return QuotaKeyPrefix + "pool-" + id
+}
+
+// quotaPool returns the definition of the pool from the limiter storage, or the cache. The
+// definitions are read on every request by the keys of the pools: they're cached, and the
+// updates made on other gateways apply within the expiration of the cache.
+func (l *SessionLimiter) quotaPool(ctx context.Context, id string) (*quotaPool, error) {
+	if l.quotaPools != nil {
+		if cached, ok := l.quotaPools.Get(id); ok {
+			return cached.(*quotaPool), nil
+		}
+	}
+
+	data, err := l.limiterStorage.Get(ctx, quotaPoolPrefix+id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errQuotaPoolNotFound
+		}
+		return nil, err
+	}
+
+	pool := &quotaPool{}
+	if err := json.Unmarshal(data, pool); err != nil {
+		return nil, err
+	}
+
+	if l.quotaPools != nil {
+		l.quotaPools.Set(id, pool, cache.DefaultExpiration)
+	}
+	return pool, nil
+}
+
+// QuotaPoolExceeded returns true if the request should be blocked as over the quota of the
+// pool of the session. The quota used by the key in the current period is recorded in the
+// usage of the pool. Sessions referencing unknown pools aren't blocked.
+func (l *SessionLimiter) QuotaPoolExceeded(session *user.SessionState, quotaKey string) bool {
+	ctx := context.Background()
+
+	pool, err := l.quotaPool(ctx, session.QuotaPool)
+	if err != nil {
+		log.WithError(err).WithField("pool", session.QuotaPool).Error("Couldn't get the quota pool of the key")
+		return false
+	}
+
+	member := quotaKey
+	if member == "" {
+		member = storage.HashStr(session.KeyID)
+	}
+
+	return l.quotaExceeded(session, quotaPoolCounterKey(pool.ID), pool.limit(), func(_, renews int64) {
+		usageKey := quotaPoolUsagePrefix + pool.ID
+		_, err := l.limiterStorage.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HIncrBy(ctx, usageKey, member, 1)
+			// the usage is cleared with the pool counter, when the period ends
+			if renewsAt := time.Unix(renews, 0); renewsAt.After(time.Now()) {
+				pipe.ExpireAt(ctx, usageKey, renewsAt)
+			}
+			return nil
+		})
+		if err != nil {
+			log.WithError(err).WithField("pool", pool.ID).Error("Couldn't record the quota pool usage")
+		}
+	})
+}
+
+func (gw *Gateway) handleGetQuotaPool(ctx context.Context, id string) (interface{}, int) {
+	pool, err := gw.SessionLimiter.quotaPool(ctx, id)
+	if err != nil {
+		if errors.Is(err, errQuotaPoolNotFound) {
+			return apiError(errQuotaPoolNotFound.Error()), http.StatusNotFound
+		}
+		return apiError("Couldn't get quota pool"), http.StatusInternalServerError
+	}
+
+	return pool, http.StatusOK
+}
+
+func (gw *Gateway) handleGetQuotaPoolList(ctx context.Context) (interface{}, int) {
+	ids, err := gw.SessionLimiter.limiterStorage.SMembers(ctx, quotaPoolIndex).Result()
+	if err != nil {
+		return apiError("Couldn't list quota pools"), http.StatusInternalServerError
+	}
+
+	pools := []*quotaPool{}
+	for _, id := range ids {
+		pool, err := gw.SessionLimiter.quotaPool(ctx, id)
+		if err != nil {
+			continue
+		}
+		pools = append(pools, pool)
+	}
+
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].ID < pools[j].ID
+	})
+
+	return pools, http.StatusOK
+}
+
+func (gw *Gateway) handleAddOrUpdateQuotaPool(ctx context.Context, id string, r *http.Request) (interface{}, int) {
+	pool := &quotaPool{}
+	if err := json.NewDecoder(r.Body).Decode(pool); err != nil {
+		return apiError("Request malformed"), http.StatusBadRequest
+	}
+
+	if id != "" && pool.ID != "" && pool.ID != id {
+		return apiError("Request ID does not match that in quota pool!"), http.StatusBadRequest
+	}
+
+	switch {
+	case id != "":
+		pool.ID = id
+	case pool.ID == "":
+		pool.ID = uuid.NewHex()
+	}
+
+	data, err := json.Marshal(pool)
+	if err != nil {
+		return apiError("Marshalling failed"), http.StatusInternalServerError
+	}
+
+	conn := gw.SessionLimiter.limiterStorage
+	_, err = conn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, quotaPoolPrefix+pool.ID, data, 0)
+		pipe.SAdd(ctx, quotaPoolIndex, pool.ID)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("Couldn't store quota pool")
+		return apiError("Failed to store quota pool"), http.StatusInternalServerError
+	}
+	gw.QuotaPoolCache.Delete(pool.ID)
+
+	action := "modified"
+	if r.Method == http.MethodPost {
+		action = "added"
+	}
+
+	return apiModifyKeySuccess{Key: pool.ID, Status: "ok", Action: action}, http.StatusOK
+}
+
+func (gw *Gateway) handleDeleteQuotaPool(ctx context.Context, id string) (interface{}, int) {
+	conn := gw.SessionLimiter.limiterStorage
+
+	deleted, err := conn.Del(ctx, quotaPoolPrefix+id).Result()
+	if err != nil {
+		return apiError("Failed to delete quota pool"), http.StatusInternalServerError
+	}
+
+	if deleted == 0 {
+		return apiError(errQuotaPoolNotFound.Error()), http.StatusNotFound
+	}
+
+	conn.SRem(ctx, quotaPoolIndex, id)
+	conn.Del(ctx, quotaPoolCounterKey(id), quotaPoolUsagePrefix+id)
+	gw.QuotaPoolCache.Delete(id)
+
+	return apiModifyKeySuccess{Key: id, Status: "ok", Action: "deleted"}, http.StatusOK
+}
+
+// quotaPoolsHandler lists, returns, stores and deletes quota pools.
+func (gw *Gateway) quotaPoolsHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["poolID"]
+
+	var obj interface{}
+	var code int
+
+	switch r.Method {
+	case http.MethodGet:
+		if id == "" {
+			obj, code = gw.handleGetQuotaPoolList(r.Context())
+			break
+		}
+		obj, code = gw.handleGetQuotaPool(r.Context(), id)
+	case http.MethodPost, http.MethodPut:
+		obj, code = gw.handleAddOrUpdateQuotaPool(r.Context(), id, r)
+	case http.MethodDelete:
+		obj, code = gw.handleDeleteQuotaPool(r.Context(), id)
+	}
+
+	doJSONWrite(w, code, obj)
+}
+
+// quotaPoolUsageHandler returns the quota used in the current period of a pool, in total
+// and by every key of the pool.
+func (gw *Gateway) quotaPoolUsageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	pool, err := gw.SessionLimiter.quotaPool(ctx, mux.Vars(r)["poolID"])
+	if err != nil {
+		doJSONWrite(w, http.StatusNotFound, apiError(errQuotaPoolNotFound.Error()))
+		return
+	}
+
+	conn := gw.SessionLimiter.limiterStorage
+	usage := quotaPoolUsage{PoolID: pool.ID, QuotaMax: pool.QuotaMax, Keys: map[string]int64{}}
+
+	used, err := conn.Get(ctx, quotaPoolCounterKey(pool.ID)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't get quota pool usage"))
+		return
+	}
+	usage.QuotaUsed = used
+
+	if ttl, err := conn.PTTL(ctx, quotaPoolCounterKey(pool.ID)).Result(); err == nil && ttl > 0 {
+		usage.QuotaRenews = time.Now().Add(ttl).Unix()
+	}
+
+	if pool.QuotaMax > 0 && used < pool.QuotaMax {
+		usage.QuotaRemaining = pool.QuotaMax - used
+	}
+
+	keys, err := conn.HGetAll(ctx, quotaPoolUsagePrefix+pool.ID).Result()
+	if err != nil {
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't get quota pool usage"))
+		return
+	}
+
+	for key, value := range keys {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			usage.Keys[key] = n
+		}
+	}
+
+	doJSONWrite(w, http.StatusOK, usage)
+}
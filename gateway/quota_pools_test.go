@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestQuotaPools(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "pooled"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/pooled/"
+	})
+
+	poolID := uuid.NewHex()
+	poolPath := "/tyk/quota-pools/" + poolID
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{AdminAuth: true, Method: http.MethodPut, Path: poolPath, Data: `{"id": "other"}`, Code: http.StatusBadRequest},
+		{AdminAuth: true, Method: http.MethodPut, Path: poolPath, Data: `{"name": "acme", "quota_max": 3, "quota_renewal_rate": 3600}`, Code: http.StatusOK},
+		{AdminAuth: true, Path: "/tyk/quota-pools", BodyMatch: `"id":"` + poolID + `"`, Code: http.StatusOK},
+		{AdminAuth: true, Path: poolPath, BodyMatch: `"name":"acme"`, Code: http.StatusOK},
+		// the cached definition is invalidated by the updates
+		{AdminAuth: true, Method: http.MethodPut, Path: poolPath, Data: `{"name": "acme-corp", "quota_max": 3, "quota_renewal_rate": 3600}`, Code: http.StatusOK},
+		{AdminAuth: true, Path: poolPath, BodyMatch: `"name":"acme-corp"`, Code: http.StatusOK},
+		// the keys of the pools don't clash with the index of the pools or their usage
+		{AdminAuth: true, Method: http.MethodPut, Path: "/tyk/quota-pools/index", Data: `{"quota_max": 1}`, Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodPut, Path: "/tyk/quota-pools/usage-" + poolID, Data: `{"quota_max": 1}`, Code: http.StatusOK},
+		{AdminAuth: true, Path: "/tyk/quota-pools", BodyMatch: `"id":"index"`, Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/quota-pools/index", Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/quota-pools/usage-" + poolID, Code: http.StatusOK},
+	}...)
+
+	createKey := func() string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"pooled": {APIID: "pooled"}}
+			s.QuotaMax = -1
+			s.QuotaPool = poolID
+		})
+		return key
+	}
+	first, second := createKey(), createKey()
+
+	request := func(key string, code int) test.TestCase {
+		return test.TestCase{Path: "/pooled/", Headers: map[string]string{header.Authorization: key}, Code: code}
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		request(first, http.StatusOK),
+		request(first, http.StatusOK),
+		request(second, http.StatusOK),
+		request(second, http.StatusForbidden),
+		request(first, http.StatusForbidden),
+	}...)
+
+	// the counter of the pool is stored apart from its definition
+	ts.Gw.QuotaPoolCache.Flush()
+	resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Path: poolPath + "/usage", Code: http.StatusOK})
+
+	var usage quotaPoolUsage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&usage))
+	assert.Equal(t, int64(3), usage.QuotaMax)
+	assert.Equal(t, int64(5), usage.QuotaUsed)
+	assert.Equal(t, int64(0), usage.QuotaRemaining)
+	assert.NotZero(t, usage.QuotaRenews)
+	assert.Equal(t, map[string]int64{storage.HashStr(first): 3, storage.HashStr(second): 2}, usage.Keys)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{AdminAuth: true, Method: http.MethodDelete, Path: poolPath, Code: http.StatusOK},
+		{AdminAuth: true, Method: http.MethodDelete, Path: poolPath, Code: http.StatusNotFound},
+		{AdminAuth: true, Path: poolPath, Code: http.StatusNotFound},
+		// keys of deleted pools are only limited by their own quota
+		request(first, http.StatusOK),
+	}...)
+}
@@ -0,0 +1,386 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/buger/jsonparser"
+
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	rateLimitCounterString = "string"
+	rateLimitCounterZSet   = "zset"
+	rateLimitCounterHash   = "hash"
+
+	// atomicCounterKeyPrefix prefixes the sliding logs of the atomic rate limiter, which share
+	// the hash slot of the quota counter.
+	atomicCounterKeyPrefix = "{" + QuotaKeyPrefix
+
+	rateLimitStateScanCount = 1000
+)
+
+// rateLimitCounterPatterns match the Redis keys of the rate limit and quota counters.
+var rateLimitCounterPatterns = []string{QuotaKeyPrefix + "*", RateLimitKeyPrefix + "*", atomicCounterKeyPrefix + "*"}
+
+var errRateLimitStateStorage = errors.New("rate limit state requires the Redis rate limiter storage")
+
+// rateLimitState is the exported state of the rate limit and quota counters, to be imported in
+// the Redis of another cluster or region.
+type rateLimitState struct {
+	ExportedAt time.Time          `json:"exported_at"`
+	Counters   []rateLimitCounter `json:"counters"`
+}
+
+// rateLimitCounter is a Redis key of the rate limit and quota counters.
+type rateLimitCounter struct {
+	Key string `json:"key"`
+	// Type is the Redis type of the counter: string, zset or hash.
+	Type string `json:"type"`
+
+	Value   string                   `json:"value,omitempty"`
+	Members []rateLimitCounterMember `json:"members,omitempty"`
+	Fields  map[string]string        `json:"fields,omitempty"`
+
+	// ExpiresAt is the expiry of the counter, which renews the quotas. It's absolute so that the
+	// time the migration takes doesn't extend the quota period.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// rateLimitCounterMember is a member of the sliding log of a rate limiter.
+type rateLimitCounterMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+type rateLimitImportResult struct {
+	Status   string `json:"status"`
+	Imported int    `json:"imported"`
+	// Skipped is the number of counters which expired since they were exported.
+	Skipped int `json:"skipped"`
+}
+
+// isRateLimitCounterKey returns true if key is in the key space of the rate limit and quota counters.
+func isRateLimitCounterKey(key string) bool {
+	return strings.HasPrefix(key, QuotaKeyPrefix) || strings.HasPrefix(key, RateLimitKeyPrefix) ||
+		strings.HasPrefix(key, atomicCounterKeyPrefix)
+}
+
+// rateLimitStateKeys returns the names the counters of the requested keys and orgs are stored
+// by. The counters of an org are its own and the ones of its keys.
+func (gw *Gateway) rateLimitStateKeys(r *http.Request) ([]string, error) {
+	query := r.URL.Query()
+	hashKeys := gw.GetConfig().HashKeys
+
+	byHash := query.Get("hashed") != ""
+	if byHash && !hashKeys {
+		return nil, errors.New("Keys requested by hash but key hashing is not enabled")
+	}
+
+	var names []string
+	for _, key := range query["key"] {
+		if !byHash {
+			key = storage.HashKey(key, hashKeys)
+		}
+		names = append(names, key)
+	}
+
+	orgIDs := query["org_id"]
+	for _, orgID := range orgIDs {
+		names = append(names, storage.HashKey(orgID, hashKeys))
+	}
+
+	if len(orgIDs) > 0 {
+		sessions := gw.GlobalSessionManager.Store().GetKeysAndValuesWithFilter("*")
+		for key, session := range sessions {
+			orgID, err := jsonparser.GetString([]byte(session), "org_id")
+			if err != nil {
+				continue
+			}
+
+			for _, id := range orgIDs {
+				if orgID == id {
+					names = append(names, key)
+					break
+				}
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("At least one key or org_id is required")
+	}
+
+	return names, nil
+}
+
+// exportRateLimitState returns the state of the counters of the key names. The counters are
+// stored by the key name, prefixed by the allowance scope or the quota bucket and suffixed by
+// the endpoint or the limiter, so they're matched by containing it as a segment of the key.
+func exportRateLimitState(ctx context.Context, conn redis.UniversalClient, names []string) (*rateLimitState, error) {
+	state := &rateLimitState{ExportedAt: time.Now().UTC(), Counters: []rateLimitCounter{}}
+
+	for _, pattern := range rateLimitCounterPatterns {
+		keys, err := scanRedisKeys(ctx, conn, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if !containsAnySegment(key, names) {
+				continue
+			}
+
+			counter, err := exportRateLimitCounter(ctx, conn, key)
+			if err != nil {
+				return nil, err
+			}
+
+			if counter != nil {
+				state.Counters = append(state.Counters, *counter)
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// rateLimitKeySeparators separate the segments of the Redis keys of the counters.
+const rateLimitKeySeparators = "-.:{}"
+
+// containsAnySegment returns true if key contains any of the names as a whole segment, between
+// separators, so that a name doesn't match the counters of the longer names it's a part of.
+func containsAnySegment(key string, names []string) bool {
+	for _, name := range names {
+		if name != "" && containsSegment(key, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsSegment(key, name string) bool {
+	for offset := 0; ; {
+		i := strings.Index(key[offset:], name)
+		if i < 0 {
+			return false
+		}
+
+		start, end := offset+i, offset+i+len(name)
+		if (start == 0 || strings.IndexByte(rateLimitKeySeparators, key[start-1]) >= 0) &&
+			(end == len(key) || strings.IndexByte(rateLimitKeySeparators, key[end]) >= 0) {
+			return true
+		}
+
+		offset = start + 1
+	}
+}
+
+// exportRateLimitCounter returns the counter at key, nil when it expired since it was scanned.
+func exportRateLimitCounter(ctx context.Context, conn redis.UniversalClient, key string) (*rateLimitCounter, error) {
+	redisType, err := conn.Type(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &rateLimitCounter{Key: key, Type: redisType}
+
+	switch redisType {
+	case "none":
+		return nil, nil
+	case rateLimitCounterString:
+		counter.Value, err = conn.Get(ctx, key).Result()
+	case rateLimitCounterZSet:
+		var members []redis.Z
+		if members, err = conn.ZRangeWithScores(ctx, key, 0, -1).Result(); err == nil {
+			for _, m := range members {
+				counter.Members = append(counter.Members, rateLimitCounterMember{Member: fmt.Sprint(m.Member), Score: m.Score})
+			}
+		}
+	case rateLimitCounterHash:
+		counter.Fields, err = conn.HGetAll(ctx, key).Result()
+	default:
+		return nil, fmt.Errorf("unsupported type %q of the counter %s", redisType, key)
+	}
+
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := conn.PTTL(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl).UTC()
+		counter.ExpiresAt = &expiresAt
+	}
+
+	return counter, nil
+}
+
+// validate returns an error if a counter of the state isn't a rate limit or quota counter, so
+// that the import doesn't write other keys.
+func (s *rateLimitState) validate() error {
+	for _, counter := range s.Counters {
+		if !isRateLimitCounterKey(counter.Key) {
+			return fmt.Errorf("%s isn't a rate limit or quota counter", counter.Key)
+		}
+
+		switch counter.Type {
+		case rateLimitCounterString, rateLimitCounterZSet, rateLimitCounterHash:
+		default:
+			return fmt.Errorf("unsupported type %q of the counter %s", counter.Type, counter.Key)
+		}
+	}
+
+	return nil
+}
+
+// importRateLimitState writes the counters of the state, replacing the existing ones. The
+// counters which expired since they were exported are skipped.
+func importRateLimitState(ctx context.Context, conn redis.UniversalClient, state *rateLimitState) (*rateLimitImportResult, error) {
+	result := &rateLimitImportResult{Status: "ok"}
+	now := time.Now()
+
+	for _, counter := range state.Counters {
+		if counter.ExpiresAt != nil && !counter.ExpiresAt.After(now) {
+			result.Skipped++
+			continue
+		}
+
+		_, err := conn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, counter.Key)
+
+			switch counter.Type {
+			case rateLimitCounterString:
+				pipe.Set(ctx, counter.Key, counter.Value, 0)
+			case rateLimitCounterZSet:
+				members := make([]redis.Z, 0, len(counter.Members))
+				for _, m := range counter.Members {
+					members = append(members, redis.Z{Member: m.Member, Score: m.Score})
+				}
+				if len(members) > 0 {
+					pipe.ZAdd(ctx, counter.Key, members...)
+				}
+			case rateLimitCounterHash:
+				if len(counter.Fields) > 0 {
+					pipe.HSet(ctx, counter.Key, counter.Fields)
+				}
+			}
+
+			if counter.ExpiresAt != nil {
+				pipe.PExpireAt(ctx, counter.Key, *counter.ExpiresAt)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result.Imported++
+	}
+
+	return result, nil
+}
+
+// scanRedisKeys returns the keys matching pattern, scanning every master of a cluster.
+func scanRedisKeys(ctx context.Context, conn redis.UniversalClient, pattern string) ([]string, error) {
+	cluster, ok := conn.(*redis.ClusterClient)
+	if !ok {
+		return scanClientKeys(ctx, conn, pattern)
+	}
+
+	var mu sync.Mutex
+	var keys []string
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		found, err := scanClientKeys(ctx, client, pattern)
+
+		mu.Lock()
+		keys = append(keys, found...)
+		mu.Unlock()
+		return err
+	})
+
+	return keys, err
+}
+
+func scanClientKeys(ctx context.Context, conn redis.UniversalClient, pattern string) ([]string, error) {
+	var keys []string
+
+	iter := conn.Scan(ctx, 0, pattern, rateLimitStateScanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+
+	return keys, iter.Err()
+}
+
+// rateLimitStateExportHandler exports the rate limit and quota counters of the keys and orgs
+// of the query, so that migrating them to another Redis doesn't reset their quotas.
+func (gw *Gateway) rateLimitStateExportHandler(w http.ResponseWriter, r *http.Request) {
+	conn := gw.SessionLimiter.limiterStorage
+	if conn == nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(errRateLimitStateStorage.Error()))
+		return
+	}
+
+	names, err := gw.rateLimitStateKeys(r)
+	if err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	state, err := exportRateLimitState(r.Context(), conn, names)
+	if err != nil {
+		log.WithError(err).Error("Couldn't export the rate limit state")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't export the rate limit state"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, state)
+}
+
+// rateLimitStateImportHandler imports the rate limit and quota counters exported by
+// rateLimitStateExportHandler.
+func (gw *Gateway) rateLimitStateImportHandler(w http.ResponseWriter, r *http.Request) {
+	conn := gw.SessionLimiter.limiterStorage
+	if conn == nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(errRateLimitStateStorage.Error()))
+		return
+	}
+
+	state := &rateLimitState{}
+	if err := json.NewDecoder(r.Body).Decode(state); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	if err := state.validate(); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	result, err := importRateLimitState(r.Context(), conn, state)
+	if err != nil {
+		log.WithError(err).Error("Couldn't import the rate limit state")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't import the rate limit state"))
+		return
+	}
+
+	log.WithField("imported", result.Imported).WithField("skipped", result.Skipped).Info("Imported the rate limit state")
+	doJSONWrite(w, http.StatusOK, result)
+}
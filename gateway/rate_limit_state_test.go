@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestRateLimitState(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.EnableRedisRollingLimiter = true
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "limited"
+		spec.OrgID = "migrated-org"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/limited/"
+	})
+
+	createKey := func(orgID string) string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.OrgID = orgID
+			s.AccessRights = map[string]user.AccessDefinition{"limited": {APIID: "limited"}}
+			s.Rate, s.Per = 100, 60
+			s.QuotaMax, s.QuotaRenewalRate = 10, 3600
+		})
+		return key
+	}
+	key, other := createKey("migrated-org"), createKey("other-org")
+
+	request := func(key string) test.TestCase {
+		return test.TestCase{Path: "/limited/", Headers: map[string]string{header.Authorization: key}, Code: http.StatusOK}
+	}
+	_, _ = ts.Run(t, request(key), request(key), request(key), request(other))
+
+	quotaKey := QuotaKeyPrefix + storage.HashKey(key, ts.Gw.GetConfig().HashKeys)
+
+	export := func(t *testing.T, query string) *rateLimitState {
+		t.Helper()
+
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Path: "/tyk/rate-limits/export?" + query, Code: http.StatusOK})
+
+		state := &rateLimitState{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(state))
+		return state
+	}
+
+	counter := func(state *rateLimitState, key string) *rateLimitCounter {
+		for i := range state.Counters {
+			if state.Counters[i].Key == key {
+				return &state.Counters[i]
+			}
+		}
+		return nil
+	}
+
+	t.Run("export by key", func(t *testing.T) {
+		state := export(t, "key="+key)
+
+		quota := counter(state, quotaKey)
+		require.NotNil(t, quota)
+		assert.Equal(t, rateLimitCounterString, quota.Type)
+		assert.Equal(t, "3", quota.Value)
+		require.NotNil(t, quota.ExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *quota.ExpiresAt, time.Minute)
+
+		rateLimit := counter(state, RateLimitKeyPrefix+storage.HashKey(key, ts.Gw.GetConfig().HashKeys))
+		require.NotNil(t, rateLimit)
+		assert.Equal(t, rateLimitCounterZSet, rateLimit.Type)
+		assert.Len(t, rateLimit.Members, 3)
+
+		for _, c := range state.Counters {
+			assert.NotContains(t, c.Key, storage.HashKey(other, ts.Gw.GetConfig().HashKeys))
+		}
+	})
+
+	t.Run("export by org", func(t *testing.T) {
+		state := export(t, "org_id=migrated-org")
+		assert.NotNil(t, counter(state, quotaKey))
+		assert.Nil(t, counter(state, QuotaKeyPrefix+storage.HashKey(other, ts.Gw.GetConfig().HashKeys)))
+	})
+
+	t.Run("import", func(t *testing.T) {
+		state := export(t, "key="+key)
+		data, err := json.Marshal(state)
+		require.NoError(t, err)
+
+		// the counters are reset by the migration
+		conn := ts.Gw.SessionLimiter.limiterStorage
+		for _, c := range state.Counters {
+			require.NoError(t, conn.Del(context.Background(), c.Key).Err())
+		}
+
+		resp, _ := ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/rate-limits/import", Data: data, Code: http.StatusOK})
+
+		result := &rateLimitImportResult{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+		assert.Equal(t, len(state.Counters), result.Imported)
+		assert.Zero(t, result.Skipped)
+
+		used, err := conn.Get(context.Background(), quotaKey).Result()
+		require.NoError(t, err)
+		assert.Equal(t, "3", used)
+
+		ttl, err := conn.PTTL(context.Background(), quotaKey).Result()
+		require.NoError(t, err)
+		assert.InDelta(t, time.Hour.Seconds(), ttl.Seconds(), 60, "the quota period is kept")
+	})
+
+	t.Run("import expired counters", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			AdminAuth: true,
+			Method:    http.MethodPost,
+			Path:      "/tyk/rate-limits/import",
+			Data:      `{"counters": [{"key": "quota-expired", "type": "string", "value": "1", "expires_at": "2020-01-01T00:00:00Z"}]}`,
+			BodyMatch: `"skipped":1`,
+			Code:      http.StatusOK,
+		})
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Path: "/tyk/rate-limits/export", Code: http.StatusBadRequest},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/rate-limits/import", Data: `{"counters": [{"key": "apikey-abc", "type": "string"}]}`, Code: http.StatusBadRequest},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/rate-limits/import", Data: `{"counters": [{"key": "quota-abc", "type": "list"}]}`, Code: http.StatusBadRequest},
+		}...)
+	})
+}
+
+func TestContainsAnySegment(t *testing.T) {
+	for key, matches := range map[string]bool{
+		"quota-abc":                 true,
+		"quota-api-abc":             true,
+		"quota-bucket-reads-abc":    true,
+		"rate-limit-abc":            true,
+		"rate-limit-abc.BLOCKED":    true,
+		"rate-limit-abc:1700000000": true,
+		"{quota-abc}rate-limit-abc": true,
+		"quota-abcd":                false,
+		"quota-xabc":                false,
+		"rate-limit-abcdef.BLOCKED": false,
+	} {
+		assert.Equal(t, matches, containsAnySegment(key, []string{"", "abc"}), key)
+	}
+
+	assert.True(t, containsAnySegment("quota-org-1-key", []string{"org-1"}), "names can contain separators")
+	assert.False(t, containsAnySegment("quota-org-12", []string{"org-1"}))
+}
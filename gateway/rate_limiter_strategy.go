@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/TykTechnologies/tyk/internal/rate"
+	"github.com/TykTechnologies/tyk/internal/rate/limiter"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// rateLimiterStrategy is a rate limiting algorithm, selected by name with `rate_limiter` in the
+// Gateway configuration or in the API definition.
+type rateLimiterStrategy interface {
+	// Limit returns true if the request is over the rate limit of the limiter key.
+	Limit(r *http.Request, session *user.SessionState, limiterKey string, apiLimit *user.APILimit, dryRun bool) bool
+}
+
+// rateLimiterStrategyFunc adapts a function to a rateLimiterStrategy.
+type rateLimiterStrategyFunc func(r *http.Request, session *user.SessionState, limiterKey string, apiLimit *user.APILimit, dryRun bool) bool
+
+func (f rateLimiterStrategyFunc) Limit(r *http.Request, session *user.SessionState, limiterKey string, apiLimit *user.APILimit, dryRun bool) bool {
+	return f(r, session, limiterKey, apiLimit, dryRun)
+}
+
+// limiterFuncStrategy adapts a rate limiter of the limiter package to a rateLimiterStrategy.
+// Like with the flags enabling them, the requests are counted on dry runs.
+func limiterFuncStrategy(limit limiter.LimiterFunc) rateLimiterStrategy {
+	return rateLimiterStrategyFunc(func(r *http.Request, _ *user.SessionState, limiterKey string, apiLimit *user.APILimit, _ bool) bool {
+		err := limit(r.Context(), limiterKey, apiLimit.Rate, apiLimit.Per)
+		return errors.Is(err, rate.ErrLimitExhausted)
+	})
+}
+
+// rateLimiterName returns the name of the rate limiter selected by the API, or by the Gateway
+// when the API doesn't select one. It's empty when the flags select the rate limiter.
+func (l *SessionLimiter) rateLimiterName(api *APISpec) string {
+	if api != nil && rate.IsLimiter(api.RateLimiter) {
+		return api.RateLimiter
+	}
+
+	if rate.IsLimiter(l.config.RateLimiter) {
+		return l.config.RateLimiter
+	}
+
+	return ""
+}
+
+// rateLimiterStrategy returns the rate limiter strategy named name, which must be a rate.Limiters.
+func (l *SessionLimiter) rateLimiterStrategy(name string, useCustomKey bool) rateLimiterStrategy {
+	switch name {
+	case rate.LimitDRL:
+		return rateLimiterStrategyFunc(func(r *http.Request, session *user.SessionState, limiterKey string, apiLimit *user.APILimit, dryRun bool) bool {
+			return l.limitDistributed(r, session, limiterKey, apiLimit, useCustomKey, dryRun)
+		})
+	case rate.LimitSentinel:
+		return rateLimiterStrategyFunc(l.limitSentinel)
+	case rate.LimitSlidingLog:
+		return rateLimiterStrategyFunc(l.limitRedis)
+	case rate.LimitSlidingWindow:
+		// unlike with the flag, rate requests are allowed per window
+		return limiterFuncStrategy(limiter.NewLimiter(l.limiterStorage).SlidingWindowAtCapacity)
+	}
+
+	return limiterFuncStrategy(rate.LimiterByName(name, l.limiterStorage))
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/rate"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestSessionLimiter_rateLimiterName(t *testing.T) {
+	conf := &config.Config{}
+	limiter := &SessionLimiter{config: conf}
+	api := &APISpec{APIDefinition: &apidef.APIDefinition{}}
+
+	assert.Empty(t, limiter.rateLimiterName(api), "the flags select the rate limiter")
+
+	conf.RateLimiter = rate.LimitGCRA
+	assert.Equal(t, rate.LimitGCRA, limiter.rateLimiterName(api))
+
+	api.RateLimiter = rate.LimitFixedWindow
+	assert.Equal(t, rate.LimitFixedWindow, limiter.rateLimiterName(api), "the API overrides the Gateway")
+
+	api.RateLimiter = "unknown"
+	assert.Equal(t, rate.LimitGCRA, limiter.rateLimiterName(api))
+
+	conf.RateLimiter = "unknown"
+	assert.Empty(t, limiter.rateLimiterName(api))
+}
+
+func TestRateLimiterStrategies(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		// overridden by the APIs
+		globalConf.RateLimiter = rate.LimitDRL
+	})
+	defer ts.Close()
+
+	// the leaky bucket delays the requests over the rate instead of blocking them
+	strategies := []string{rate.LimitDRL, rate.LimitSentinel, rate.LimitSlidingLog, rate.LimitFixedWindow, rate.LimitSlidingWindow, rate.LimitTokenBucket, rate.LimitGCRA}
+
+	for _, strategy := range strategies {
+		t.Run(strategy, func(t *testing.T) {
+			api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+				spec.APIID = "rate-limited-" + strategy
+				spec.Proxy.ListenPath = "/"
+				spec.UseKeylessAccess = false
+				spec.RateLimiter = strategy
+			})[0]
+
+			_, key := ts.CreateSession(func(s *user.SessionState) {
+				s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+				s.Rate, s.Per = 3, 60
+			})
+			authHeader := map[string]string{header.Authorization: key}
+
+			for i := 0; i < 3; i++ {
+				_, _ = ts.Run(t, test.TestCase{Headers: authHeader, Code: http.StatusOK})
+			}
+
+			if strategy == rate.LimitSentinel {
+				// the sentinel is set after the requests
+				assert.Eventually(t, func() bool {
+					resp, err := ts.Run(t, test.TestCase{Headers: authHeader})
+					return err == nil && resp.StatusCode == http.StatusTooManyRequests
+				}, time.Second, 10*time.Millisecond)
+				return
+			}
+
+			_, _ = ts.Run(t, test.TestCase{Headers: authHeader, Code: http.StatusTooManyRequests})
+		})
+	}
+}
@@ -2,7 +2,6 @@ package gateway
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/TykTechnologies/tyk/storage"
@@ -42,13 +41,14 @@ func (r *RedisPurger) PurgeCache() {
 		expireAfter = 60 // 1 minute
 	}
 
-	for i := -1; i < 10; i++ {
+	shards := discoverAnalyticsKeyShards(r.Store, r.Gw.GetConfig().AnalyticsConfig)
+	for i := -1; i < shards; i++ {
 		var analyticsKey string
 		if i == -1 {
 			//if it's the first iteration, we look for tyk-system-analytics to maintain backwards compatibility or if analytics_config.enable_multiple_analytics_keys is disabled in the gateway
 			analyticsKey = analyticsKeyName
 		} else {
-			analyticsKey = fmt.Sprintf("%v_%v", analyticsKeyName, i)
+			analyticsKey = analyticsShardKey(i)
 		}
 		exp, _ := r.Store.GetExp(analyticsKey)
 		if exp == -1 {
@@ -1,12 +1,13 @@
 package gateway
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
 	"os"
-	"syscall"
 	"time"
 
+	"github.com/TykTechnologies/goverify"
 	"github.com/sirupsen/logrus"
 
 	"github.com/TykTechnologies/tyk/config"
@@ -16,19 +17,42 @@ type ConfigPayload struct {
 	Configuration config.Config
 	ForHostname   string
 	ForNodeID     string
-	TimeStamp     int64
+	// ForGroup targets the Gateways of the `slave_options.group_id` group.
+	ForGroup string
+	// ForTags targets the Gateways with any of the `db_app_conf_options.tags` segment tags.
+	ForTags   []string
+	TimeStamp int64
+	// Signature is the base64 signature of the payload, see SignHMAC and SignRSA.
+	Signature string
 }
 
-func (gw *Gateway) backupConfiguration() error {
-	oldConfig, err := json.MarshalIndent(gw.GetConfig(), "", "    ")
-	if err != nil {
-		return err
+// targets returns whether the payload targets the Gateway, by hostname, node ID, group or
+// segment tag.
+func (p *ConfigPayload) targets(gw *Gateway) bool {
+	return gw.isConfigTarget(p.ForHostname, p.ForNodeID, p.ForGroup, p.ForTags)
+}
+
+// isConfigTarget returns whether a remote configuration targets the Gateway, by hostname, node
+// ID, group or segment tag.
+func (gw *Gateway) isConfigTarget(forHostname, forNodeID, forGroup string, forTags []string) bool {
+	if forHostname == gw.hostDetails.Hostname || forNodeID == gw.GetNodeID() {
+		return true
 	}
 
-	now := time.Now()
-	asStr := now.Format("Mon-Jan-_2-15-04-05-2006")
-	fName := asStr + ".tyk.conf"
-	return ioutil.WriteFile(fName, oldConfig, 0644)
+	conf := gw.GetConfig()
+	if forGroup != "" && forGroup == conf.SlaveOptions.GroupID {
+		return true
+	}
+
+	for _, tag := range forTags {
+		for _, nodeTag := range conf.DBAppConfOptions.Tags {
+			if tag == nodeTag {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 func writeNewConfiguration(payload ConfigPayload) error {
@@ -55,11 +79,11 @@ func (gw *Gateway) handleNewConfiguration(payload string) {
 		return
 	}
 
-	// Make sure payload matches nodeID and hostname
-	if configPayload.ForHostname != gw.hostDetails.Hostname && configPayload.ForNodeID != gw.GetNodeID() {
+	// Make sure payload matches nodeID, hostname, group or tags
+	if !configPayload.targets(gw) {
 		log.WithFields(logrus.Fields{
 			"prefix": "pub-sub",
-		}).Info("Configuration update received, no NodeID/Hostname match found")
+		}).Info("Configuration update received, no NodeID/Hostname/Group/Tags match found")
 		return
 	}
 
@@ -70,6 +94,29 @@ func (gw *Gateway) handleNewConfiguration(payload string) {
 		return
 	}
 
+	if err := gw.verifyConfigPayload(payload); err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Error("Ignoring new config: ", err)
+		return
+	}
+
+	gw.applyRemoteConfiguration(configPayload.Configuration, "", nil)
+}
+
+// applyRemoteConfiguration validates a remote configuration, writes it after backing up the
+// current configuration, and reloads the process with it. The contents of the configuration
+// file are written to path when set, rather than the configuration.
+func (gw *Gateway) applyRemoteConfiguration(conf config.Config, path string, contents []byte) {
+	if errs := validateRemoteConfig(&conf); len(errs) > 0 {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+			"errors": errs,
+		}).Error("Rejecting new config: The configuration is invalid.")
+		gw.rejectConfiguration(errs)
+		return
+	}
+
 	if err := gw.backupConfiguration(); err != nil {
 		log.WithFields(logrus.Fields{
 			"prefix": "pub-sub",
@@ -77,7 +124,12 @@ func (gw *Gateway) handleNewConfiguration(payload string) {
 		return
 	}
 
-	if err := writeNewConfiguration(configPayload); err != nil {
+	write := func() error { return writeNewConfiguration(ConfigPayload{Configuration: conf}) }
+	if contents != nil {
+		write = func() error { return ioutil.WriteFile(path, contents, 0644) }
+	}
+
+	if err := write(); err != nil {
 		log.WithFields(logrus.Fields{
 			"prefix": "pub-sub",
 		}).Error("Failed to write new configuration: ", err)
@@ -88,16 +140,135 @@ func (gw *Gateway) handleNewConfiguration(payload string) {
 		"prefix": "pub-sub",
 	}).Info("Initiating configuration reload")
 
-	myPID := gw.hostDetails.PID
-	if myPID == 0 {
-		log.Error("No PID found, cannot reload")
+	gw.reloadProcess()
+}
+
+// ConfigRollbackPayload is the payload of a configuration rollback. When Backup is empty, the
+// most recent backup is restored, going back past the backups restored by the previous rollbacks.
+type ConfigRollbackPayload struct {
+	ForHostname string
+	ForNodeID   string
+	// ForGroup targets the Gateways of the `slave_options.group_id` group.
+	ForGroup string
+	// ForTags targets the Gateways with any of the `db_app_conf_options.tags` segment tags.
+	ForTags   []string
+	Backup    string
+	TimeStamp int64
+	// Signature is the base64 signature of the payload, see SignHMAC and SignRSA.
+	Signature string
+}
+
+// configRollbackMessagePrefix prefixes the signed messages of the rollbacks, so that a signed
+// rollback can't be replayed as another payload.
+const configRollbackMessagePrefix = "rollback\n"
+
+func (p *ConfigRollbackPayload) message() ([]byte, error) {
+	backup, err := json.Marshal(p.Backup)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := configPayloadMessage(p.ForHostname, p.ForNodeID, p.ForGroup, p.ForTags, p.TimeStamp, backup)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(configRollbackMessagePrefix), message...), nil
+}
+
+// SignHMAC signs the payload with the shared secret of `remote_config_signature.secret`.
+func (p *ConfigRollbackPayload) SignHMAC(secret string) error {
+	message, err := p.message()
+	if err != nil {
+		return err
+	}
+
+	p.Signature = base64.StdEncoding.EncodeToString(configPayloadHMAC(secret, message))
+	return nil
+}
+
+// SignRSA signs the payload with the private key of `remote_config_signature.public_key_path`.
+func (p *ConfigRollbackPayload) SignRSA(signer goverify.Signer) error {
+	message, err := p.message()
+	if err != nil {
+		return err
+	}
+
+	p.Signature, err = configPayloadRSA(signer, message)
+	return err
+}
+
+func (gw *Gateway) handleConfigRollback(payload string) {
+	rollbackPayload := ConfigRollbackPayload{}
+	if err := json.Unmarshal([]byte(payload), &rollbackPayload); err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Error("Failed to decode configuration rollback payload: ", err)
 		return
 	}
 
-	log.Info("Sending reload signal to PID: ", myPID)
-	if err := syscall.Kill(myPID, syscall.SIGUSR2); err != nil {
-		log.Error("Process reload failed: ", err)
+	// Make sure payload matches nodeID, hostname, group or tags
+	if !gw.isConfigTarget(rollbackPayload.ForHostname, rollbackPayload.ForNodeID, rollbackPayload.ForGroup, rollbackPayload.ForTags) {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Info("Configuration rollback received, no NodeID/Hostname/Group/Tags match found")
+		return
 	}
+
+	if !gw.GetConfig().AllowRemoteConfig {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Warning("Ignoring config rollback: Remote configuration is not allowed for this node.")
+		return
+	}
+
+	if gw.configSignaturesEnabled() {
+		message, err := rollbackPayload.message()
+		if err == nil {
+			err = gw.verifyConfigSignature(rollbackPayload.Signature, message)
+		}
+		if err == nil {
+			err = gw.consumeConfigTimeStamp(rollbackPayload.TimeStamp)
+		}
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"prefix": "pub-sub",
+			}).Error("Ignoring config rollback: ", err)
+			return
+		}
+	}
+
+	name := rollbackPayload.Backup
+	if name == "" {
+		backups, err := gw.listConfigBackups()
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"prefix": "pub-sub",
+			}).Error("Failed to list configuration backups: ", err)
+			return
+		}
+		var found bool
+		if name, found = rollbackConfigBackup(backups); !found {
+			log.WithFields(logrus.Fields{
+				"prefix": "pub-sub",
+			}).Warning("Ignoring config rollback: No configuration backup found.")
+			return
+		}
+	}
+
+	if err := gw.restoreConfigBackup(name); err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+			"backup": name,
+		}).Error("Failed to restore configuration backup: ", err)
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"prefix": "pub-sub",
+		"backup": name,
+	}).Info("Restored configuration backup, initiating configuration reload")
+
+	gw.reloadProcess()
 }
 
 type GetConfigPayload struct {
@@ -120,6 +291,7 @@ func sanitizeConfig(mc map[string]interface{}) map[string]interface{} {
 		"storage",
 		"slave_options",
 		"auth_override",
+		"remote_config_signature",
 	}
 	for _, field_name := range sanitzeFields {
 		delete(mc, field_name)
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestConfigPayloadTargets(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.SlaveOptions.GroupID = "group"
+		globalConf.DBAppConfOptions.Tags = []string{"edge", "eu"}
+	})
+	defer ts.Close()
+
+	for name, tc := range map[string]struct {
+		payload ConfigPayload
+		targets bool
+	}{
+		"node":        {ConfigPayload{ForNodeID: ts.Gw.GetNodeID()}, true},
+		"hostname":    {ConfigPayload{ForHostname: ts.Gw.hostDetails.Hostname}, true},
+		"group":       {ConfigPayload{ForGroup: "group"}, true},
+		"other group": {ConfigPayload{ForGroup: "other"}, false},
+		"tag":         {ConfigPayload{ForTags: []string{"us", "eu"}}, true},
+		"other tags":  {ConfigPayload{ForTags: []string{"us"}}, false},
+		"other node":  {ConfigPayload{ForNodeID: "other", ForHostname: "other"}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.targets, tc.payload.targets(ts.Gw))
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	temporalmodel "github.com/TykTechnologies/storage/temporal/model"
+	"github.com/spf13/afero"
 
 	"github.com/TykTechnologies/tyk/internal/crypto"
 	"github.com/TykTechnologies/tyk/storage"
@@ -36,11 +37,16 @@ const (
 	NoticeDashboardZeroConf      NotificationCommand = "NoticeDashboardZeroConf"
 	NoticeDashboardConfigRequest NotificationCommand = "NoticeDashboardConfigRequest"
 	NoticeGatewayConfigResponse  NotificationCommand = "NoticeGatewayConfigResponse"
+	NoticeGatewayConfigRollback  NotificationCommand = "NoticeGatewayConfigRollback"
+	NoticeGatewayConfigRejected  NotificationCommand = "NoticeGatewayConfigRejected"
+	NoticeGatewayConfigPatch     NotificationCommand = "NoticeGatewayConfigPatch"
 	NoticeGatewayDRLNotification NotificationCommand = "NoticeGatewayDRLNotification"
 	KeySpaceUpdateNotification   NotificationCommand = "KeySpaceUpdateNotification"
 	OAuthPurgeLapsedTokens       NotificationCommand = "OAuthPurgeLapsedTokens"
 	// NoticeDeleteAPICache is the command with which event is emitted from dashboard to invalidate cache for an API.
 	NoticeDeleteAPICache NotificationCommand = "DeleteAPICache"
+	// NoticeScheduledAPIChange is the command with which a staged API definition change is activated across the cluster.
+	NoticeScheduledAPIChange NotificationCommand = "ScheduledApiChange"
 )
 
 // Notification is a type that encodes a message published to a pub sub channel (shared between implementations)
@@ -119,7 +125,7 @@ func (gw *Gateway) handleRedisEvent(v interface{}, handled func(NotificationComm
 
 	// Add messages to ignore here
 	switch notif.Command {
-	case NoticeGatewayConfigResponse:
+	case NoticeGatewayConfigResponse, NoticeGatewayConfigRejected:
 		return
 	}
 
@@ -134,6 +140,10 @@ func (gw *Gateway) handleRedisEvent(v interface{}, handled func(NotificationComm
 		gw.handleDashboardZeroConfMessage(notif.Payload)
 	case NoticeConfigUpdate:
 		gw.handleNewConfiguration(notif.Payload)
+	case NoticeGatewayConfigRollback:
+		gw.handleConfigRollback(notif.Payload)
+	case NoticeGatewayConfigPatch:
+		gw.handleConfigPatch(notif.Payload)
 	case NoticeDashboardConfigRequest:
 		gw.handleSendMiniConfig(notif.Payload)
 	case NoticeGatewayDRLNotification:
@@ -147,6 +157,9 @@ func (gw *Gateway) handleRedisEvent(v interface{}, handled func(NotificationComm
 	case NoticeApiUpdated, NoticeApiRemoved, NoticeApiAdded, NoticePolicyChanged, NoticeGroupReload:
 		pubSubLog.Info("Reloading endpoints")
 		gw.reloadURLStructure(reloaded)
+	case NoticeScheduledAPIChange:
+		gw.applyScheduledAPIChange(notif.Payload, afero.NewOsFs())
+		gw.reloadURLStructure(reloaded)
 	case KeySpaceUpdateNotification:
 		gw.handleKeySpaceEventCacheFlush(notif.Payload)
 	case OAuthPurgeLapsedTokens:
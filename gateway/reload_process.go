@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// requestProcessReload queues an in-process reload of the configuration file. The reloads
+// requested while one is pending are merged.
+func (gw *Gateway) requestProcessReload() {
+	select {
+	case gw.processReloads <- struct{}{}:
+	default:
+		log.Debug("Configuration reload already pending")
+	}
+}
+
+// handleProcessReloads reloads the configuration file in-process, until ctx is done.
+func (gw *Gateway) handleProcessReloads(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gw.processReloads:
+			if err := gw.reloadConfigInProcess(); err != nil {
+				log.WithError(err).Error("Configuration reload failed")
+			}
+		}
+	}
+}
+
+// reloadConfigInProcess loads the configuration file and reloads the APIs and policies with it,
+// without restarting the process. The listeners are kept: changes to the listen address and
+// ports require a restart.
+func (gw *Gateway) reloadConfigInProcess() error {
+	current := gw.GetConfig()
+
+	conf := config.Config{}
+	if err := config.Load(confPaths, &conf); err != nil {
+		return err
+	}
+
+	if conf.ListenAddress != current.ListenAddress || conf.ListenPort != current.ListenPort || conf.ControlAPIPort != current.ControlAPIPort {
+		log.Warning("The listen address and ports changes require a restart, keeping the current listeners")
+		conf.ListenAddress = current.ListenAddress
+		conf.ListenPort = current.ListenPort
+		conf.ControlAPIPort = current.ControlAPIPort
+	}
+	if conf.PIDFileLocation == "" {
+		conf.PIDFileLocation = current.PIDFileLocation
+	}
+
+	gw.SetConfig(conf)
+	gw.afterConfSetup()
+
+	log.Info("Configuration reloaded, reloading the APIs and policies")
+	gw.DoReload()
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadConfigInProcess(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	confFile := filepath.Join(t.TempDir(), "tyk.conf")
+	oldConfPaths := confPaths
+	confPaths = []string{confFile}
+	defer func() { confPaths = oldConfPaths }()
+
+	current := ts.Gw.GetConfig()
+	conf := current
+	conf.ListenPort = current.ListenPort + 1
+	conf.HttpServerOptions.EnableStrictRoutes = !current.HttpServerOptions.EnableStrictRoutes
+
+	data, err := json.Marshal(conf)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(confFile, data, 0o644))
+
+	ctx, cancel := context.WithCancel(ts.Gw.ctx)
+	defer cancel()
+	go ts.Gw.handleProcessReloads(ctx)
+
+	ts.Gw.requestProcessReload()
+	// the reloads requested while one is pending are merged
+	ts.Gw.requestProcessReload()
+
+	assert.Eventually(t, func() bool {
+		return ts.Gw.GetConfig().HttpServerOptions.EnableStrictRoutes == conf.HttpServerOptions.EnableStrictRoutes
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, current.ListenPort, ts.Gw.GetConfig().ListenPort, "the listeners are kept")
+}
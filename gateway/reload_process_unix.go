@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package gateway
+
+import (
+	"syscall"
+)
+
+// reloadProcess signals the process to reload, with the configuration file. The process
+// forks a child with the new configuration, which takes over the listeners.
+func (gw *Gateway) reloadProcess() {
+	myPID := gw.hostDetails.PID
+	if myPID == 0 {
+		log.Error("No PID found, cannot reload")
+		return
+	}
+
+	log.Info("Sending reload signal to PID: ", myPID)
+	if err := syscall.Kill(myPID, syscall.SIGUSR2); err != nil {
+		log.Error("Process reload failed: ", err)
+	}
+}
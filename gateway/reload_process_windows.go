@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package gateway
+
+// reloadProcess reloads the configuration file in-process, as processes can't be signalled
+// to fork on Windows.
+func (gw *Gateway) reloadProcess() {
+	log.Info("Reloading the configuration in-process")
+	gw.requestProcessReload()
+}
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/TykTechnologies/goverify"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// configPatchMessagePrefix prefixes the signed messages of the patches, so that a signed patch
+// can't be replayed as a whole configuration.
+const configPatchMessagePrefix = "patch\n"
+
+var errConfigPatchNotObject = errors.New("configuration patch must be a JSON object")
+
+// ConfigPatchPayload is the payload of the NoticeGatewayConfigPatch notification. It carries a
+// JSON merge patch (RFC 7386) of the configuration, applied to the configuration file of the
+// targeted Gateways: the fields of the patch are set, the null fields are reset to their
+// default values and the other fields are kept.
+type ConfigPatchPayload struct {
+	Patch       json.RawMessage
+	ForHostname string
+	ForNodeID   string
+	// ForGroup targets the Gateways of the `slave_options.group_id` group.
+	ForGroup string
+	// ForTags targets the Gateways with any of the `db_app_conf_options.tags` segment tags.
+	ForTags   []string
+	TimeStamp int64
+	// Signature is the base64 signature of the payload, see SignHMAC and SignRSA.
+	Signature string
+}
+
+func (p *ConfigPatchPayload) message() ([]byte, error) {
+	patch := p.Patch
+	if len(patch) == 0 {
+		patch = json.RawMessage("null")
+	}
+
+	message, err := configPayloadMessage(p.ForHostname, p.ForNodeID, p.ForGroup, p.ForTags, p.TimeStamp, patch)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(configPatchMessagePrefix), message...), nil
+}
+
+// SignHMAC signs the payload with the shared secret of `remote_config_signature.secret`.
+func (p *ConfigPatchPayload) SignHMAC(secret string) error {
+	message, err := p.message()
+	if err != nil {
+		return err
+	}
+
+	p.Signature = base64.StdEncoding.EncodeToString(configPayloadHMAC(secret, message))
+	return nil
+}
+
+// SignRSA signs the payload with the private key of `remote_config_signature.public_key_path`.
+func (p *ConfigPatchPayload) SignRSA(signer goverify.Signer) error {
+	message, err := p.message()
+	if err != nil {
+		return err
+	}
+
+	p.Signature, err = configPayloadRSA(signer, message)
+	return err
+}
+
+// applyConfigPatch applies a JSON merge patch to the contents of a configuration file. The
+// patches with unknown fields are rejected, the fields of the file out of the patch aren't
+// checked so that a patch still applies to files with fields of other versions.
+func applyConfigPatch(contents []byte, patch json.RawMessage) ([]byte, error) {
+	var patchObj map[string]interface{}
+	if err := json.Unmarshal(patch, &patchObj); err != nil || patchObj == nil {
+		return nil, errConfigPatchNotObject
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patch))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config.Config{}); err != nil {
+		return nil, err
+	}
+
+	var confObj map[string]interface{}
+	if err := json.Unmarshal(contents, &confObj); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(mergePatch(confObj, patchObj), "", "    ")
+}
+
+// readConfigFile returns the path and the contents of the configuration file, the first of
+// the configuration paths found.
+func readConfigFile() (string, []byte, error) {
+	for _, path := range confPaths {
+		contents, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		return path, contents, err
+	}
+	return "", nil, os.ErrNotExist
+}
+
+// mergePatch merges a patch into a JSON value, as specified by RFC 7386.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+func (gw *Gateway) handleConfigPatch(payload string) {
+	patchPayload := ConfigPatchPayload{}
+	if err := json.Unmarshal([]byte(payload), &patchPayload); err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Error("Failed to decode configuration patch payload: ", err)
+		return
+	}
+
+	if !gw.isConfigTarget(patchPayload.ForHostname, patchPayload.ForNodeID, patchPayload.ForGroup, patchPayload.ForTags) {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Info("Configuration patch received, no NodeID/Hostname/Group/Tags match found")
+		return
+	}
+
+	if !gw.GetConfig().AllowRemoteConfig {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Warning("Ignoring config patch: Remote configuration is not allowed for this node.")
+		return
+	}
+
+	if gw.configSignaturesEnabled() {
+		message, err := patchPayload.message()
+		if err == nil {
+			err = gw.verifyConfigSignature(patchPayload.Signature, message)
+		}
+		if err == nil {
+			err = gw.consumeConfigTimeStamp(patchPayload.TimeStamp)
+		}
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"prefix": "pub-sub",
+			}).Error("Ignoring config patch: ", err)
+			return
+		}
+	}
+
+	// the patch applies to the configuration file, not to the defaults set at startup nor to
+	// the environment variables, so that the secrets set in the environment aren't written
+	path, current, err := readConfigFile()
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Error("Failed to read configuration to patch: ", err)
+		return
+	}
+
+	patched, err := applyConfigPatch(current, patchPayload.Patch)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Error("Rejecting config patch: ", err)
+		gw.rejectConfiguration([]string{err.Error()})
+		return
+	}
+
+	// the patched configuration is validated as the Gateway loads it, with the environment
+	conf := config.Config{}
+	if err := json.Unmarshal(patched, &conf); err == nil {
+		err = config.FillEnv(&conf)
+	}
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Error("Failed to load patched configuration: ", err)
+		return
+	}
+
+	gw.applyRemoteConfiguration(conf, path, patched)
+}
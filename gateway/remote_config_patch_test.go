@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestMergePatch(t *testing.T) {
+	var target, patch interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"a":"b","c":{"d":"e","f":"g"},"h":["i"]}`), &target))
+	require.NoError(t, json.Unmarshal([]byte(`{"a":"z","c":{"f":null},"h":["j"],"k":{"l":1}}`), &patch))
+
+	merged, err := json.Marshal(mergePatch(target, patch))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":"z","c":{"d":"e"},"h":["j"],"k":{"l":1}}`, string(merged))
+}
+
+func TestConfigPatch(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AllowRemoteConfig = true
+		globalConf.ConfigBackups.Path = filepath.Join(dir, "backups")
+	})
+	defer ts.Close()
+
+	confFile := filepath.Join(dir, "tyk.conf")
+	oldConfPaths := confPaths
+	confPaths = []string{confFile}
+	defer func() { confPaths = oldConfPaths }()
+
+	reset := func(t *testing.T) {
+		t.Helper()
+
+		conf := config.Config{ListenPort: 8080, LogLevel: "info", HashKeys: true}
+		conf.Storage = config.StorageOptionsConf{Type: "redis", Host: "localhost", Port: 6379}
+		data, err := json.Marshal(conf)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(confFile, data, 0o644))
+	}
+
+	load := func(t *testing.T) config.Config {
+		t.Helper()
+
+		data, err := os.ReadFile(confFile)
+		require.NoError(t, err)
+		var conf config.Config
+		require.NoError(t, json.Unmarshal(data, &conf))
+		return conf
+	}
+
+	stamp := time.Now().Unix()
+	patch := func(t *testing.T, patch string, sign func(*ConfigPatchPayload) error) {
+		t.Helper()
+
+		stamp++
+		payload := ConfigPatchPayload{ForNodeID: ts.Gw.GetNodeID(), Patch: json.RawMessage(patch), TimeStamp: stamp}
+		if sign != nil {
+			require.NoError(t, sign(&payload))
+		}
+		data, err := json.Marshal(payload)
+		require.NoError(t, err)
+		ts.Gw.handleConfigPatch(string(data))
+	}
+
+	t.Run("merge", func(t *testing.T) {
+		reset(t)
+		patch(t, `{"log_level":"debug","storage":{"port":6380},"hash_keys":null}`, nil)
+
+		conf := load(t)
+		assert.Equal(t, "debug", conf.LogLevel)
+		assert.Equal(t, 6380, conf.Storage.Port)
+		assert.Equal(t, "localhost", conf.Storage.Host, "the fields out of the patch are kept")
+		assert.Equal(t, 8080, conf.ListenPort)
+		assert.False(t, conf.HashKeys, "the null fields are reset")
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("TYK_GW_STORAGE_PASSWORD", "env-secret")
+
+		reset(t)
+		patch(t, `{"log_level":"debug"}`, nil)
+
+		data, err := os.ReadFile(confFile)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "env-secret", "the environment isn't written to the configuration file")
+		assert.Equal(t, "debug", load(t).LogLevel)
+	})
+
+	t.Run("fields out of the patch aren't checked", func(t *testing.T) {
+		reset(t)
+		data, err := os.ReadFile(confFile)
+		require.NoError(t, err)
+		var confObj map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &confObj))
+		confObj["removed_option"] = true
+		data, err = json.Marshal(confObj)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(confFile, data, 0o644))
+
+		patch(t, `{"log_level":"debug"}`, nil)
+
+		data, err = os.ReadFile(confFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "removed_option")
+		assert.Equal(t, "debug", load(t).LogLevel)
+	})
+
+	t.Run("written to the file read", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.conf")
+		confPaths = []string{missing, confFile}
+		defer func() { confPaths = []string{confFile} }()
+
+		reset(t)
+		patch(t, `{"log_level":"debug"}`, nil)
+
+		assert.Equal(t, "debug", load(t).LogLevel)
+		assert.NoFileExists(t, missing)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		for name, rejected := range map[string]string{
+			"unknown field": `{"log_levl":"debug"}`,
+			"not an object": `["log_level"]`,
+			"invalid":       `{"listen_port":-1}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				reset(t)
+				patch(t, rejected, nil)
+				assert.Equal(t, 8080, load(t).ListenPort)
+				assert.Equal(t, "info", load(t).LogLevel)
+			})
+		}
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.RemoteConfigSignature.Secret = "secret"
+		ts.Gw.SetConfig(globalConf)
+
+		reset(t)
+		patch(t, `{"log_level":"debug"}`, nil)
+		assert.Equal(t, "info", load(t).LogLevel, "unsigned patches are ignored")
+
+		patch(t, `{"log_level":"debug"}`, func(p *ConfigPatchPayload) error { return p.SignHMAC("secret") })
+		assert.Equal(t, "debug", load(t).LogLevel)
+
+		reset(t)
+		patch(t, `{"log_level":"debug"}`, func(p *ConfigPatchPayload) error {
+			p.TimeStamp = stamp - 1
+			return p.SignHMAC("secret")
+		})
+		assert.Equal(t, "info", load(t).LogLevel, "replayed patches are ignored")
+
+		// a signed patch isn't a signed configuration
+		reset(t)
+		payload := ConfigPatchPayload{ForNodeID: ts.Gw.GetNodeID(), Patch: json.RawMessage(`{"log_level":"debug"}`), TimeStamp: stamp + 1}
+		require.NoError(t, payload.SignHMAC("secret"))
+		data, err := json.Marshal(map[string]interface{}{
+			"Configuration": payload.Patch,
+			"ForNodeID":     payload.ForNodeID,
+			"TimeStamp":     payload.TimeStamp,
+			"Signature":     payload.Signature,
+		})
+		require.NoError(t, err)
+		ts.Gw.handleNewConfiguration(string(data))
+		assert.Equal(t, "info", load(t).LogLevel)
+	})
+}
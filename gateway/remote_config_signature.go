@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/goverify"
+)
+
+const (
+	defaultConfigPayloadMaxAge = 300
+	// configTimeStampFile holds the timestamp of the last applied payload, in the backups
+	// directory, so that it outlives the reloads of the process.
+	configTimeStampFile = "remote-config.timestamp"
+)
+
+var (
+	errConfigPayloadNotSigned        = errors.New("configuration payload isn't signed")
+	errConfigPayloadInvalidSignature = errors.New("configuration payload signature is invalid")
+	errConfigPayloadExpired          = errors.New("configuration payload timestamp is outside of the accepted window")
+	errConfigPayloadReplayed         = errors.New("configuration payload isn't newer than the last applied payload")
+)
+
+// signedConfigPayload is a ConfigPayload with the configuration as it was signed.
+type signedConfigPayload struct {
+	Configuration json.RawMessage
+	ForHostname   string
+	ForNodeID     string
+	ForGroup      string
+	ForTags       []string
+	TimeStamp     int64
+	Signature     string
+}
+
+// configPayloadMessage returns the signed message of a configuration payload, the compacted
+// configuration JSON after the target and the timestamp of the payload. The group and tags are
+// only part of the target of the payloads targeting them.
+func configPayloadMessage(forHostname, forNodeID, forGroup string, forTags []string, timeStamp int64, configuration []byte) ([]byte, error) {
+	var message bytes.Buffer
+	message.WriteString(forHostname + "\n" + forNodeID + "\n")
+	if forGroup != "" || len(forTags) > 0 {
+		message.WriteString(forGroup + "\n" + strings.Join(forTags, ",") + "\n")
+	}
+	message.WriteString(strconv.FormatInt(timeStamp, 10) + "\n")
+	if err := json.Compact(&message, configuration); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+func (p *ConfigPayload) message() ([]byte, error) {
+	configuration, err := json.Marshal(p.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	return configPayloadMessage(p.ForHostname, p.ForNodeID, p.ForGroup, p.ForTags, p.TimeStamp, configuration)
+}
+
+// SignHMAC signs the payload with the shared secret of `remote_config_signature.secret`.
+func (p *ConfigPayload) SignHMAC(secret string) error {
+	message, err := p.message()
+	if err != nil {
+		return err
+	}
+
+	p.Signature = base64.StdEncoding.EncodeToString(configPayloadHMAC(secret, message))
+	return nil
+}
+
+// SignRSA signs the payload with the private key of `remote_config_signature.public_key_path`.
+func (p *ConfigPayload) SignRSA(signer goverify.Signer) error {
+	message, err := p.message()
+	if err != nil {
+		return err
+	}
+
+	p.Signature, err = configPayloadRSA(signer, message)
+	return err
+}
+
+func configPayloadHMAC(secret string, message []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+func configPayloadRSA(signer goverify.Signer, message []byte) (string, error) {
+	signature, err := signer.Sign(message)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// verifyConfigPayload verifies the signature of a configuration payload, when signatures are
+// configured.
+func (gw *Gateway) verifyConfigPayload(payload string) error {
+	if !gw.configSignaturesEnabled() {
+		return nil
+	}
+
+	var signed signedConfigPayload
+	if err := json.Unmarshal([]byte(payload), &signed); err != nil {
+		return err
+	}
+
+	if len(signed.Configuration) == 0 {
+		signed.Configuration = json.RawMessage("null")
+	}
+
+	message, err := configPayloadMessage(signed.ForHostname, signed.ForNodeID, signed.ForGroup, signed.ForTags, signed.TimeStamp, signed.Configuration)
+	if err != nil {
+		return err
+	}
+
+	if err := gw.verifyConfigSignature(signed.Signature, message); err != nil {
+		return err
+	}
+
+	return gw.consumeConfigTimeStamp(signed.TimeStamp)
+}
+
+// consumeConfigTimeStamp checks that the timestamp of a signed payload is within the accepted
+// window and newer than the last applied payload, and records it as the last applied one.
+func (gw *Gateway) consumeConfigTimeStamp(timeStamp int64) error {
+	maxAge := gw.GetConfig().RemoteConfigSignature.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultConfigPayloadMaxAge
+	}
+
+	if age := time.Now().Unix() - timeStamp; age > maxAge || age < -maxAge {
+		return errConfigPayloadExpired
+	}
+
+	gw.configTimeStampMu.Lock()
+	defer gw.configTimeStampMu.Unlock()
+
+	path := filepath.Join(gw.configBackupDir(), configTimeStampFile)
+	if data, err := os.ReadFile(path); err == nil {
+		last, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if timeStamp <= last {
+			return errConfigPayloadReplayed
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(gw.configBackupDir(), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strconv.FormatInt(timeStamp, 10)), 0o600)
+}
+
+func (gw *Gateway) configSignaturesEnabled() bool {
+	conf := gw.GetConfig().RemoteConfigSignature
+	return conf.Secret != "" || conf.PublicKeyPath != ""
+}
+
+// verifyConfigSignature verifies the base64 signature of a signed message, with the public key
+// when it's configured, or with the shared secret.
+func (gw *Gateway) verifyConfigSignature(encoded string, message []byte) error {
+	conf := gw.GetConfig().RemoteConfigSignature
+
+	if encoded == "" {
+		return errConfigPayloadNotSigned
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errConfigPayloadInvalidSignature
+	}
+
+	if conf.PublicKeyPath != "" {
+		verifier, err := goverify.LoadPublicKeyFromFile(conf.PublicKeyPath)
+		if err != nil {
+			return err
+		}
+		if err := verifier.Verify(message, signature); err != nil {
+			return errConfigPayloadInvalidSignature
+		}
+		return nil
+	}
+
+	if !hmac.Equal(signature, configPayloadHMAC(conf.Secret, message)) {
+		return errConfigPayloadInvalidSignature
+	}
+
+	return nil
+}
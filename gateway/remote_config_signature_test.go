@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/goverify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestRemoteConfigSignature(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicKeyPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}), 0o644))
+	signer, err := goverify.LoadPrivateKeyFromString(string(pem.EncodeToMemory(&pem.Block{
+		Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})))
+	require.NoError(t, err)
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AllowRemoteConfig = true
+		globalConf.ConfigBackups.Path = filepath.Join(dir, "backups")
+	})
+	defer ts.Close()
+
+	confFile := filepath.Join(dir, "tyk.conf")
+	oldConfPaths := confPaths
+	confPaths = []string{confFile}
+	defer func() { confPaths = oldConfPaths }()
+
+	stamp := time.Now().Unix()
+	push := func(t *testing.T, sign func(*ConfigPayload) error) bool {
+		t.Helper()
+		_ = os.Remove(confFile)
+
+		stamp++
+		payload := ConfigPayload{ForNodeID: ts.Gw.GetNodeID(), TimeStamp: stamp}
+		payload.Configuration.ListenPort = 8181
+		payload.Configuration.Storage = config.StorageOptionsConf{Type: "redis", Host: "localhost", Port: 6379}
+		if sign != nil {
+			require.NoError(t, sign(&payload))
+		}
+
+		data, err := json.Marshal(payload)
+		require.NoError(t, err)
+		ts.Gw.handleNewConfiguration(string(data))
+
+		// the configuration file is created with defaults when missing
+		pushed, err := os.ReadFile(confFile)
+		require.NoError(t, err)
+
+		var conf config.Config
+		require.NoError(t, json.Unmarshal(pushed, &conf))
+		return conf.ListenPort == 8181
+	}
+
+	setSignature := func(signature config.RemoteConfigSignatureConfig) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.RemoteConfigSignature = signature
+		ts.Gw.SetConfig(globalConf)
+	}
+
+	t.Run("signatures not configured", func(t *testing.T) {
+		assert.True(t, push(t, nil))
+	})
+
+	t.Run("shared secret", func(t *testing.T) {
+		setSignature(config.RemoteConfigSignatureConfig{Secret: "secret"})
+
+		assert.False(t, push(t, nil))
+		assert.False(t, push(t, func(p *ConfigPayload) error { return p.SignHMAC("other") }))
+		assert.False(t, push(t, func(p *ConfigPayload) error {
+			err := p.SignHMAC("secret")
+			p.TimeStamp++
+			return err
+		}))
+		assert.False(t, push(t, func(p *ConfigPayload) error {
+			err := p.SignHMAC("secret")
+			p.ForTags = []string{"retargeted"}
+			return err
+		}))
+		assert.True(t, push(t, func(p *ConfigPayload) error { return p.SignHMAC("secret") }))
+		assert.True(t, push(t, func(p *ConfigPayload) error {
+			p.ForGroup = "group"
+			return p.SignHMAC("secret")
+		}))
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		setSignature(config.RemoteConfigSignatureConfig{Secret: "secret", MaxAge: 60})
+
+		assert.False(t, push(t, func(p *ConfigPayload) error {
+			p.TimeStamp = stamp - 1
+			return p.SignHMAC("secret")
+		}), "payloads not newer than the last applied payload are rejected")
+		assert.False(t, push(t, func(p *ConfigPayload) error {
+			p.TimeStamp = time.Now().Unix() + 3600
+			return p.SignHMAC("secret")
+		}), "payloads outside of the max age are rejected")
+		assert.True(t, push(t, func(p *ConfigPayload) error { return p.SignHMAC("secret") }))
+	})
+
+	t.Run("public key", func(t *testing.T) {
+		setSignature(config.RemoteConfigSignatureConfig{Secret: "secret", PublicKeyPath: publicKeyPath})
+
+		assert.False(t, push(t, nil))
+		assert.False(t, push(t, func(p *ConfigPayload) error { return p.SignHMAC("secret") }))
+		assert.False(t, push(t, func(p *ConfigPayload) error {
+			err := p.SignRSA(signer)
+			p.Configuration.ListenAddress = "tampered"
+			return err
+		}))
+		assert.True(t, push(t, func(p *ConfigPayload) error { return p.SignRSA(signer) }))
+	})
+}
@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// ConfigRejectedPayload is the payload of the NoticeGatewayConfigRejected notification a Gateway
+// sends when it rejects a remote configuration.
+type ConfigRejectedPayload struct {
+	FromHostname string
+	FromNodeID   string
+	// Errors are the validation errors of the configuration.
+	Errors    []string
+	TimeStamp int64
+}
+
+// validateRemoteConfig validates a remote configuration before it's written, so that invalid
+// configurations don't leave the Gateway in a broken state after the reload.
+func validateRemoteConfig(conf *config.Config) []string {
+	var errs []string
+
+	if conf.ListenPort <= 0 || conf.ListenPort > 65535 {
+		errs = append(errs, fmt.Sprintf("listen_port %d is invalid", conf.ListenPort))
+	}
+	if conf.ControlAPIPort < 0 || conf.ControlAPIPort > 65535 {
+		errs = append(errs, fmt.Sprintf("control_api_port %d is invalid", conf.ControlAPIPort))
+	}
+
+	if conf.Storage.Type != "redis" {
+		errs = append(errs, fmt.Sprintf("storage.type %q is invalid, the storage must be redis", conf.Storage.Type))
+	}
+	if conf.Storage.Host == "" && len(conf.Storage.Addrs) == 0 && len(conf.Storage.Hosts) == 0 {
+		errs = append(errs, "storage has no host, addrs or hosts")
+	}
+	if conf.Storage.Port < 0 || conf.Storage.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("storage.port %d is invalid", conf.Storage.Port))
+	}
+
+	files := [][2]string{
+		{"storage.ca_file", conf.Storage.CAFile},
+		{"storage.cert_file", conf.Storage.CertFile},
+		{"storage.key_file", conf.Storage.KeyFile},
+	}
+	for i, cert := range conf.HttpServerOptions.Certificates {
+		files = append(files,
+			[2]string{fmt.Sprintf("http_server_options.certificates[%d].cert_file", i), cert.CertFile},
+			[2]string{fmt.Sprintf("http_server_options.certificates[%d].key_file", i), cert.KeyFile},
+		)
+	}
+	for _, file := range files {
+		if file[1] == "" {
+			continue
+		}
+		if _, err := os.Stat(file[1]); err != nil {
+			errs = append(errs, fmt.Sprintf("%s %q doesn't exist", file[0], file[1]))
+		}
+	}
+
+	if conf.HttpServerOptions.UseSSL && len(conf.HttpServerOptions.Certificates) == 0 && len(conf.HttpServerOptions.SSLCertificates) == 0 {
+		errs = append(errs, "http_server_options.use_ssl is set without certificates")
+	}
+
+	return errs
+}
+
+// rejectConfiguration notifies the cluster that a remote configuration was rejected.
+func (gw *Gateway) rejectConfiguration(errs []string) {
+	payload, err := json.Marshal(ConfigRejectedPayload{
+		FromHostname: gw.hostDetails.Hostname,
+		FromNodeID:   gw.GetNodeID(),
+		Errors:       errs,
+		TimeStamp:    time.Now().Unix(),
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"prefix": "pub-sub",
+		}).Error("Failed to marshal configuration rejection: ", err)
+		return
+	}
+
+	gw.MainNotifier.Notify(Notification{
+		Command: NoticeGatewayConfigRejected,
+		Payload: string(payload),
+		Gw:      gw,
+	})
+}
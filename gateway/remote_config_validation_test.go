@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	temporalmodel "github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+func TestValidateRemoteConfig(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0o644))
+
+	valid := config.Config{ListenPort: 8080}
+	valid.Storage.Type = "redis"
+	valid.Storage.Host = "localhost"
+	valid.Storage.Port = 6379
+	valid.HttpServerOptions.UseSSL = true
+	valid.HttpServerOptions.Certificates = []config.CertData{{CertFile: certFile, KeyFile: certFile}}
+	assert.Empty(t, validateRemoteConfig(&valid))
+
+	invalid := valid
+	invalid.ListenPort = 70000
+	invalid.Storage = config.StorageOptionsConf{Type: "mongo", KeyFile: "/missing/key.pem"}
+	invalid.HttpServerOptions.Certificates = []config.CertData{{CertFile: certFile, KeyFile: "/missing/key.pem"}}
+	assert.Equal(t, []string{
+		"listen_port 70000 is invalid",
+		`storage.type "mongo" is invalid, the storage must be redis`,
+		"storage has no host, addrs or hosts",
+		`storage.key_file "/missing/key.pem" doesn't exist`,
+		`http_server_options.certificates[0].key_file "/missing/key.pem" doesn't exist`,
+	}, validateRemoteConfig(&invalid))
+
+	invalid = valid
+	invalid.HttpServerOptions.Certificates = nil
+	assert.Equal(t, []string{"http_server_options.use_ssl is set without certificates"}, validateRemoteConfig(&invalid))
+}
+
+func TestRemoteConfigRejected(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.AllowRemoteConfig = true
+		globalConf.ConfigBackups.Path = filepath.Join(dir, "backups")
+	})
+	defer ts.Close()
+
+	confFile := filepath.Join(dir, "tyk.conf")
+	oldConfPaths := confPaths
+	confPaths = []string{confFile}
+	defer func() { confPaths = oldConfPaths }()
+
+	ctx, cancel := context.WithTimeout(ts.Gw.ctx, 5*time.Second)
+	defer cancel()
+
+	subscribed := make(chan struct{})
+	rejected := make(chan ConfigRejectedPayload, 1)
+	go func() {
+		cacheStore := storage.RedisCluster{ConnectionHandler: ts.Gw.StorageConnectionHandler}
+		cacheStore.Connect()
+
+		_ = cacheStore.StartPubSubHandler(ctx, RedisPubSubChannel, func(v interface{}) {
+			msg, ok := v.(temporalmodel.Message)
+			if !ok {
+				return
+			}
+			if msg.Type() == temporalmodel.MessageTypeSubscription {
+				close(subscribed)
+				return
+			}
+
+			payload, err := msg.Payload()
+			if err != nil {
+				return
+			}
+			notif := Notification{}
+			if err := json.Unmarshal([]byte(payload), &notif); err != nil || notif.Command != NoticeGatewayConfigRejected {
+				return
+			}
+
+			var nack ConfigRejectedPayload
+			if err := json.Unmarshal([]byte(notif.Payload), &nack); err == nil {
+				rejected <- nack
+			}
+		})
+	}()
+
+	select {
+	case <-subscribed:
+	case <-ctx.Done():
+		t.Fatal("couldn't subscribe to the notifications")
+	}
+
+	// the configuration file is created with defaults by the push
+	ts.Gw.handleNewConfiguration(`{"ForNodeID":"` + ts.Gw.GetNodeID() + `","Configuration":{"listen_port":-1}}`)
+
+	select {
+	case nack := <-rejected:
+		assert.Equal(t, ts.Gw.GetNodeID(), nack.FromNodeID)
+		assert.Contains(t, nack.Errors, "listen_port -1 is invalid")
+	case <-ctx.Done():
+		t.Fatal("the configuration wasn't rejected")
+	}
+
+	data, err := os.ReadFile(confFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"listen_port": -1`)
+
+	backups, err := ts.Gw.listConfigBackups()
+	require.NoError(t, err)
+	assert.Empty(t, backups, "rejected configurations aren't backed up")
+}
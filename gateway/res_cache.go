@@ -126,8 +126,13 @@ func (m *ResponseCacheMiddleware) HandleResponse(w http.ResponseWriter, res *htt
 			return nil
 		}
 
+		// the debug headers are for the allow-listed client of the request, not the consumers
+		// of the cached response
+		stored := *res
+		stored.Header = withoutDebugHeaders(res.Header)
+
 		var wireFormatReq bytes.Buffer
-		if err := res.Write(&wireFormatReq); err != nil {
+		if err := stored.Write(&wireFormatReq); err != nil {
 			m.Logger().WithError(err).Error("error encoding cache")
 			return nil
 		}
@@ -135,12 +140,34 @@ func (m *ResponseCacheMiddleware) HandleResponse(w http.ResponseWriter, res *htt
 		ts := m.getTimeTTL(cacheTTL)
 		toStore = m.encodePayload(wireFormatReq.String(), ts)
 
+		// back off while the cache exceeds its budget, until entries expire
+		if !m.Gw.cacheBudget.reserve(int64(len(toStore)), cacheTTL) {
+			m.Logger().Debug("Cache budget exceeded, not caching the response")
+			return nil
+		}
+
 		go func() {
 			err := m.store.SetKey(options.key, toStore, cacheTTL)
 			if err != nil {
 				m.Logger().WithError(err).Error("could not save key in cache store")
 			}
 		}()
+
+		// keep a copy past the expiry to serve while the upstream is degraded
+		if budget := m.Spec.upstreamErrorBudget; budget != nil && budget.serveStale() {
+			staleTTL := cacheTTL + budget.staleTTL()
+			if !m.Gw.cacheBudget.reserve(int64(len(toStore)), staleTTL) {
+				m.Logger().Debug("Cache budget exceeded, not keeping a stale copy of the response")
+				return nil
+			}
+
+			go func() {
+				err := m.store.SetKey(staleCacheKey(options.key), toStore, staleTTL)
+				if err != nil {
+					m.Logger().WithError(err).Error("could not save stale copy in cache store")
+				}
+			}()
+		}
 	}
 
 	/*
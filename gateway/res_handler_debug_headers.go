@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// ResponseDebugHeadersMiddleware adds the cache, rate limiter, upstream and timing diagnostics
+// headers to the upstream responses of the allow-listed clients of the API.
+type ResponseDebugHeadersMiddleware struct {
+	BaseTykResponseHandler
+}
+
+func (h *ResponseDebugHeadersMiddleware) Base() *BaseTykResponseHandler {
+	return &h.BaseTykResponseHandler
+}
+
+func (*ResponseDebugHeadersMiddleware) Name() string {
+	return "ResponseDebugHeadersMiddleware"
+}
+
+func (h *ResponseDebugHeadersMiddleware) Enabled() bool {
+	return h.Spec.debugHeaders != nil
+}
+
+func (h *ResponseDebugHeadersMiddleware) Init(_ interface{}, spec *APISpec) error {
+	h.Spec = spec
+	return nil
+}
+
+func (h *ResponseDebugHeadersMiddleware) HandleError(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *ResponseDebugHeadersMiddleware) HandleResponse(_ http.ResponseWriter, res *http.Response, req *http.Request, session *user.SessionState) error {
+	setDebugHeaders(res.Header, h.Spec, req, session)
+	return nil
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/jsonfilter"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+type compiledFieldFilter struct {
+	method string
+	path   *URLSpec
+	filter *jsonfilter.Filter
+}
+
+func compileFieldFilters(conf apidef.ResponseFieldFilters, gwConf config.Config, logger *logrus.Entry) []compiledFieldFilter {
+	if !conf.Enabled {
+		return nil
+	}
+
+	var filters []compiledFieldFilter
+	for _, filter := range conf.Filters {
+		compiled := compiledFieldFilter{
+			method: strings.ToUpper(strings.TrimSpace(filter.Method)),
+			filter: jsonfilter.New(filter.Include, filter.Exclude, conf.MaxBufferedNodeSize),
+		}
+
+		if filter.Path != "" {
+			path, err := compileEndpointPath(filter.Path, gwConf)
+			if err != nil {
+				logger.WithError(err).WithField("path", filter.Path).Error("Couldn't compile response field filter path")
+				continue
+			}
+
+			compiled.path = path
+		}
+
+		filters = append(filters, compiled)
+	}
+
+	return filters
+}
+
+func (f *compiledFieldFilter) matches(r *http.Request, api *APISpec) bool {
+	if f.method != "" && f.method != r.Method {
+		return false
+	}
+
+	return f.path == nil || f.path.matchesPath(r.URL.Path, api)
+}
+
+// ResponseFieldFilterMiddleware removes fields of JSON response bodies. The body is filtered
+// while it is streamed to the client, so the memory used doesn't depend on its size. The gzip
+// and deflate bodies are decompressed and compressed again as they stream; the bodies in other
+// encodings can't be filtered and are replaced by an error.
+type ResponseFieldFilterMiddleware struct {
+	BaseTykResponseHandler
+}
+
+func (h *ResponseFieldFilterMiddleware) Base() *BaseTykResponseHandler {
+	return &h.BaseTykResponseHandler
+}
+
+func (*ResponseFieldFilterMiddleware) Name() string {
+	return "ResponseFieldFilterMiddleware"
+}
+
+func (h *ResponseFieldFilterMiddleware) Enabled() bool {
+	return len(h.Spec.fieldFilters) > 0
+}
+
+func (h *ResponseFieldFilterMiddleware) Init(_ interface{}, spec *APISpec) error {
+	h.Spec = spec
+	return nil
+}
+
+func (h *ResponseFieldFilterMiddleware) HandleError(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *ResponseFieldFilterMiddleware) HandleResponse(_ http.ResponseWriter, res *http.Response, req *http.Request, _ *user.SessionState) error {
+	if res.Body == nil || !strings.Contains(res.Header.Get(header.ContentType), "json") {
+		return nil
+	}
+
+	for i := range h.Spec.fieldFilters {
+		filter := &h.Spec.fieldFilters[i]
+		if !filter.matches(req, h.Spec) {
+			continue
+		}
+
+		encoding := strings.ToLower(strings.TrimSpace(res.Header.Get(header.ContentEncoding)))
+		if encoding != "" && encoding != "gzip" && encoding != "deflate" {
+			// the fields can't be removed, the body isn't sent
+			errBody, _ := json.Marshal(apiError("Response could not be filtered"))
+			res.Body.Close()
+			res.StatusCode = http.StatusBadGateway
+			res.Status = http.StatusText(http.StatusBadGateway)
+			res.Body = io.NopCloser(bytes.NewReader(errBody))
+			res.ContentLength = -1
+			res.Header.Del(header.ContentEncoding)
+			res.Header.Del(header.ContentLength)
+			return fmt.Errorf("response field filtering doesn't support the %q content encoding", encoding)
+		}
+
+		body := res.Body
+		pr, pw := io.Pipe()
+		go func() {
+			defer body.Close()
+
+			// the status and headers are sent already, a failure cuts the body
+			if err := copyFieldFiltered(filter.filter, pw, body, encoding); err != nil {
+				log.WithField("api_id", h.Spec.APIID).WithError(err).Error("Response field filtering failed")
+				pw.CloseWithError(err)
+				return
+			}
+			pw.Close()
+		}()
+
+		res.Body = pr
+		res.ContentLength = -1
+		res.Header.Del(header.ContentLength)
+
+		return nil
+	}
+
+	return nil
+}
+
+// copyFieldFiltered filters a body in the given content encoding, decompressing it and
+// compressing it again.
+func copyFieldFiltered(filter *jsonfilter.Filter, dst io.Writer, src io.Reader, encoding string) error {
+	switch encoding {
+	case "gzip":
+		zr, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		zw := gzip.NewWriter(dst)
+		if err := filter.Copy(zw, zr); err != nil {
+			return err
+		}
+		return zw.Close()
+	case "deflate":
+		zr := flate.NewReader(src)
+		defer zr.Close()
+
+		zw, err := flate.NewWriter(dst, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		if err := filter.Copy(zw, zr); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+
+	return filter.Copy(dst, src)
+}
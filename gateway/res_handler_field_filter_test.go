@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestResponseFieldFilter(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set(header.ContentType, "text/plain")
+			_, _ = w.Write([]byte(`{"secret":"s"}`))
+		case "/gzip":
+			w.Header().Set(header.ContentType, header.ApplicationJSON)
+			w.Header().Set(header.ContentEncoding, "gzip")
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write([]byte(`{"id":1,"secret":"s"}`))
+			_ = zw.Close()
+		case "/br":
+			w.Header().Set(header.ContentType, header.ApplicationJSON)
+			w.Header().Set(header.ContentEncoding, "br")
+			_, _ = w.Write([]byte(`{"id":1,"secret":"s"}`))
+		case "/large":
+			w.Header().Set(header.ContentType, header.ApplicationJSON)
+			_, _ = w.Write([]byte(`{"items":[`))
+			for i := 0; i < 10000; i++ {
+				if i > 0 {
+					_, _ = w.Write([]byte(","))
+				}
+				_, _ = fmt.Fprintf(w, `{"id":%d,"secret":"%s"}`, i, strings.Repeat("s", 64))
+			}
+			_, _ = w.Write([]byte(`]}`))
+		default:
+			w.Header().Set(header.ContentType, header.ApplicationJSON)
+			_, _ = w.Write([]byte(`{"id":1,"user":{"name":"Jane","email":"jane@example.com"},"secret":"s"}`))
+		}
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.ResponseFieldFilters = apidef.ResponseFieldFilters{
+			Enabled: true,
+			Filters: []apidef.ResponseFieldFilter{
+				{Path: "/users", Method: http.MethodGet, Include: []string{"id", "user.name"}},
+				{Path: "/large", Exclude: []string{"items.[].secret"}},
+				{Exclude: []string{"secret"}},
+			},
+		}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/users", Code: http.StatusOK, BodyMatch: `^{"id":1,"user":{"name":"Jane"}}$`},
+		{Method: http.MethodPost, Path: "/users", Code: http.StatusOK, BodyMatch: `^{"id":1,"user":{"name":"Jane","email":"jane@example.com"}}$`},
+		{Path: "/other", Code: http.StatusOK, BodyNotMatch: `secret`},
+		{Path: "/text", Code: http.StatusOK, BodyMatch: `^{"secret":"s"}$`},
+	}...)
+
+	t.Run("large response", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{Path: "/large", Code: http.StatusOK})
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.NotContains(t, string(body), "secret")
+		assert.True(t, strings.HasSuffix(string(body), `{"id":9999}]}`))
+		assert.Empty(t, resp.Header.Get(header.ContentLength))
+	})
+
+	t.Run("compressed response", func(t *testing.T) {
+		headers := map[string]string{header.AcceptEncoding: "gzip"}
+		resp, _ := ts.Run(t, test.TestCase{Path: "/gzip", Headers: headers, Code: http.StatusOK})
+
+		assert.Equal(t, "gzip", resp.Header.Get(header.ContentEncoding))
+		zr, err := gzip.NewReader(resp.Body)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"id":1}`, string(body))
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/br", Headers: headers, Code: http.StatusBadGateway, BodyNotMatch: `secret`})
+	})
+}
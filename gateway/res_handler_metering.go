@@ -0,0 +1,386 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	// meteringTotalsPrefix prefixes the Redis hashes of the cost units accumulated per key,
+	// holding the units of every API.
+	meteringTotalsPrefix = "metering-totals-"
+	// meteringExportList is the Redis list of the exported metering events.
+	meteringExportList = "tyk-metering-events"
+	// meteringMaxFieldBody is the largest response body the units field is read from. The size of
+	// larger bodies is still metered, as they stream to the client.
+	meteringMaxFieldBody = 1 << 20
+
+	defaultMeteringEventType         = "tyk.api.request"
+	defaultMeteringMaxExportedEvents = 100000
+	defaultMeteringTotalsTTL         = 30 * 24 * 60 * 60
+)
+
+type compiledMeteredEndpoint struct {
+	method     string
+	path       *URLSpec
+	units      float64
+	unitsPerKB float64
+	unitsField []string
+}
+
+type compiledMetering struct {
+	units     float64
+	endpoints []compiledMeteredEndpoint
+}
+
+func compileMetering(conf apidef.Metering, gwConf config.Config, logger *logrus.Entry) *compiledMetering {
+	if !conf.Enabled {
+		return nil
+	}
+
+	metering := &compiledMetering{units: conf.Units}
+	for _, endpoint := range conf.Endpoints {
+		compiled := compiledMeteredEndpoint{
+			method:     strings.ToUpper(strings.TrimSpace(endpoint.Method)),
+			units:      endpoint.Units,
+			unitsPerKB: endpoint.UnitsPerKB,
+		}
+
+		if endpoint.UnitsField != "" {
+			compiled.unitsField = strings.Split(endpoint.UnitsField, ".")
+		}
+
+		if endpoint.Path != "" {
+			path, err := compileEndpointPath(endpoint.Path, gwConf)
+			if err != nil {
+				logger.WithError(err).WithField("path", endpoint.Path).Error("Couldn't compile metered endpoint path")
+				continue
+			}
+
+			compiled.path = path
+		}
+
+		metering.endpoints = append(metering.endpoints, compiled)
+	}
+
+	return metering
+}
+
+// endpoint returns the first metered endpoint matching the request, or the static units of
+// the API when none matches.
+func (m *compiledMetering) endpoint(r *http.Request, api *APISpec) compiledMeteredEndpoint {
+	for _, endpoint := range m.endpoints {
+		if endpoint.method != "" && endpoint.method != r.Method {
+			continue
+		}
+
+		if endpoint.path == nil || endpoint.path.matchesPath(r.URL.Path, api) {
+			return endpoint
+		}
+	}
+
+	return compiledMeteredEndpoint{units: m.units}
+}
+
+// usesBody returns true if the units of the endpoint depend on the response body.
+func (e *compiledMeteredEndpoint) usesBody() bool {
+	return e.unitsPerKB > 0 || len(e.unitsField) > 0
+}
+
+// unitsOfSize returns the cost units of a response of the given size, leaving out the units field.
+func (e *compiledMeteredEndpoint) unitsOfSize(size int64) float64 {
+	return e.units + e.unitsPerKB*float64(size)/1024
+}
+
+// unitsOf returns the cost units of a response with the given body.
+func (e *compiledMeteredEndpoint) unitsOf(body []byte, isJSON bool) float64 {
+	units := e.unitsOfSize(int64(len(body)))
+
+	if len(e.unitsField) == 0 || !isJSON {
+		return units
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return units
+	}
+
+	for _, field := range e.unitsField {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[field]
+		case []interface{}:
+			i, err := strconv.Atoi(field)
+			if err != nil || i < 0 || i >= len(node) {
+				return units
+			}
+			value = node[i]
+		default:
+			return units
+		}
+	}
+
+	if n, ok := value.(float64); ok && n > 0 {
+		units += n
+	}
+
+	return units
+}
+
+// meteringEvent is a metered request, as a CloudEvent.
+type meteringEvent struct {
+	SpecVersion     string       `json:"specversion"`
+	ID              string       `json:"id"`
+	Source          string       `json:"source"`
+	Type            string       `json:"type"`
+	Subject         string       `json:"subject"`
+	Time            time.Time    `json:"time"`
+	DataContentType string       `json:"datacontenttype"`
+	Data            meteringData `json:"data"`
+}
+
+type meteringData struct {
+	APIID        string  `json:"api_id"`
+	OrgID        string  `json:"org_id"`
+	Alias        string  `json:"alias,omitempty"`
+	Method       string  `json:"method"`
+	Path         string  `json:"path"`
+	ResponseCode int     `json:"response_code"`
+	Units        float64 `json:"units"`
+}
+
+// ResponseMeteringMiddleware accumulates the cost units of the responses of metered APIs per
+// key, and exports them as metering events when enabled.
+type ResponseMeteringMiddleware struct {
+	BaseTykResponseHandler
+}
+
+func (h *ResponseMeteringMiddleware) Base() *BaseTykResponseHandler {
+	return &h.BaseTykResponseHandler
+}
+
+func (*ResponseMeteringMiddleware) Name() string {
+	return "ResponseMeteringMiddleware"
+}
+
+func (h *ResponseMeteringMiddleware) Enabled() bool {
+	return h.Spec.metering != nil
+}
+
+func (h *ResponseMeteringMiddleware) Init(_ interface{}, spec *APISpec) error {
+	h.Spec = spec
+	return nil
+}
+
+func (h *ResponseMeteringMiddleware) HandleError(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *ResponseMeteringMiddleware) HandleResponse(_ http.ResponseWriter, res *http.Response, req *http.Request, session *user.SessionState) error {
+	if session == nil || session.KeyID == "" {
+		return nil
+	}
+
+	endpoint := h.Spec.metering.endpoint(req, h.Spec)
+
+	if !endpoint.usesBody() || res.Body == nil {
+		if endpoint.units > 0 {
+			h.Gw.recordMeteredRequest(h.Spec, req, res.StatusCode, session, endpoint.units)
+		}
+		return nil
+	}
+
+	// the fields of compressed bodies can't be read, their size is still metered
+	isJSON := strings.Contains(res.Header.Get(header.ContentType), "json") && res.Header.Get(header.ContentEncoding) == ""
+
+	var head []byte
+	if len(endpoint.unitsField) > 0 && isJSON && res.ContentLength <= meteringMaxFieldBody {
+		var err error
+		head, err = io.ReadAll(io.LimitReader(res.Body, meteringMaxFieldBody+1))
+		if err != nil {
+			res.Body.Close()
+			return err
+		}
+
+		if len(head) <= meteringMaxFieldBody {
+			res.Body.Close()
+			res.Body = io.NopCloser(bytes.NewReader(head))
+
+			if units := endpoint.unitsOf(head, true); units > 0 {
+				h.Gw.recordMeteredRequest(h.Spec, req, res.StatusCode, session, units)
+			}
+			return nil
+		}
+	}
+
+	// the units of larger bodies are recorded once they streamed to the client
+	code := res.StatusCode
+	res.Body = &meteredBody{
+		Reader: io.MultiReader(bytes.NewReader(head), res.Body),
+		Closer: res.Body,
+		size:   int64(len(head)),
+		done: func(size int64) {
+			if units := endpoint.unitsOfSize(size); units > 0 {
+				h.Gw.recordMeteredRequest(h.Spec, req, code, session, units)
+			}
+		},
+	}
+
+	return nil
+}
+
+// meteredBody is a response body counting the bytes read from it, metered once it's closed.
+type meteredBody struct {
+	io.Reader
+	io.Closer
+
+	size int64
+	once sync.Once
+	done func(size int64)
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.size += int64(n)
+	return n, err
+}
+
+func (b *meteredBody) Close() error {
+	b.once.Do(func() { b.done(b.size) })
+	return b.Closer.Close()
+}
+
+func (gw *Gateway) meteringStore() *storage.RedisCluster {
+	return &storage.RedisCluster{ConnectionHandler: gw.StorageConnectionHandler}
+}
+
+// recordMeteredRequest adds the units of a request to the totals of the key, and exports the
+// metering event of the request when enabled.
+func (gw *Gateway) recordMeteredRequest(spec *APISpec, r *http.Request, code int, session *user.SessionState, units float64) {
+	subject := storage.HashStr(session.KeyID)
+	conf := gw.GetConfig().Metering
+
+	totalsTTL := conf.TotalsTTL
+	if totalsTTL <= 0 {
+		totalsTTL = defaultMeteringTotalsTTL
+	}
+
+	client, err := gw.meteringStore().Client()
+	if err == nil {
+		ctx := context.Background()
+		_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HIncrByFloat(ctx, meteringTotalsPrefix+subject, spec.APIID, units)
+			pipe.Expire(ctx, meteringTotalsPrefix+subject, time.Duration(totalsTTL)*time.Second)
+			return nil
+		})
+	}
+	if err != nil {
+		log.WithError(err).WithField("api_id", spec.APIID).Error("Couldn't accumulate metered units")
+	}
+
+	if !conf.EnableExport {
+		return
+	}
+
+	event := meteringEvent{
+		SpecVersion:     "1.0",
+		ID:              uuid.New(),
+		Source:          conf.Source,
+		Type:            conf.EventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data: meteringData{
+			APIID:        spec.APIID,
+			OrgID:        spec.OrgID,
+			Alias:        session.Alias,
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			ResponseCode: code,
+			Units:        units,
+		},
+	}
+
+	if event.Source == "" {
+		event.Source = "tyk-gateway/" + gw.GetNodeID()
+	}
+
+	if event.Type == "" {
+		event.Type = defaultMeteringEventType
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.WithError(err).Error("Couldn't encode metering event")
+		return
+	}
+
+	maxEvents := conf.MaxExportedEvents
+	if maxEvents <= 0 {
+		maxEvents = defaultMeteringMaxExportedEvents
+	}
+
+	store := &storage.RedisCluster{IsAnalytics: true, ConnectionHandler: gw.StorageConnectionHandler}
+	client, err = store.Client()
+	if err == nil {
+		ctx := context.Background()
+		_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.RPush(ctx, meteringExportList, data)
+			pipe.LTrim(ctx, meteringExportList, -maxEvents, -1)
+			return nil
+		})
+	}
+	if err != nil {
+		log.WithError(err).Error("Couldn't export metering event")
+	}
+}
+
+// meteringHandler returns the cost units accumulated by a key, per API. Keys are given
+// hashed when the `hashed` query parameter is set.
+func (gw *Gateway) meteringHandler(w http.ResponseWriter, r *http.Request) {
+	subject := mux.Vars(r)["keyName"]
+	if r.URL.Query().Get("hashed") == "" {
+		subject = storage.HashStr(subject)
+	}
+
+	totals := map[string]float64{}
+
+	client, err := gw.meteringStore().Client()
+	if err != nil {
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't read metered units"))
+		return
+	}
+
+	values, err := client.HGetAll(r.Context(), meteringTotalsPrefix+subject).Result()
+	if err != nil {
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't read metered units"))
+		return
+	}
+
+	for apiID, value := range values {
+		if units, err := strconv.ParseFloat(value, 64); err == nil {
+			totals[apiID] = units
+		}
+	}
+
+	doJSONWrite(w, http.StatusOK, map[string]interface{}{
+		"key_hash": subject,
+		"units":    totals,
+	})
+}
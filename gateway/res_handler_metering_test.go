@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestResponseMetering(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Metering.EnableExport = true
+		globalConf.Metering.Source = "billing-test"
+	})
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(header.ContentType, header.ApplicationJSON)
+		_, _ = w.Write([]byte(`{"usage": {"tokens": 42}, "choices": [{"text": "hello"}]}`))
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "metered"
+		spec.OrgID = "org"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/metered/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.Metering = apidef.Metering{
+			Enabled: true,
+			Units:   1,
+			Endpoints: []apidef.MeteredEndpoint{
+				{Path: "/completions", Method: http.MethodPost, Units: 2, UnitsField: "usage.tokens"},
+				{Path: "/free", Units: 0},
+			},
+		}
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"metered": {APIID: "metered"}}
+		s.Alias = "customer-1"
+	})
+	authHeaders := map[string]string{header.Authorization: key}
+
+	store := &storage.RedisCluster{IsAnalytics: true, ConnectionHandler: ts.Gw.StorageConnectionHandler}
+	store.DeleteKey(meteringExportList)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/metered/completions", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/metered/completions", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/metered/free", Headers: authHeaders, Code: http.StatusOK},
+		{Path: "/tyk/keys/" + key + "/metering", AdminAuth: true, Code: http.StatusOK,
+			BodyMatch: `"units":{"metered":45}`},
+	}...)
+
+	events, err := store.GetListRange(meteringExportList, 0, -1)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	var event meteringEvent
+	require.NoError(t, json.Unmarshal([]byte(events[0]), &event))
+	assert.Equal(t, "1.0", event.SpecVersion)
+	assert.Equal(t, "billing-test", event.Source)
+	assert.Equal(t, defaultMeteringEventType, event.Type)
+	assert.Equal(t, storage.HashStr(key), event.Subject)
+	assert.Equal(t, meteringData{
+		APIID:        "metered",
+		OrgID:        "org",
+		Alias:        "customer-1",
+		Method:       http.MethodPost,
+		Path:         "/metered/completions",
+		ResponseCode: http.StatusOK,
+		Units:        44,
+	}, event.Data)
+}
+
+func TestCompiledMeteredEndpoint_UnitsOf(t *testing.T) {
+	endpoint := compiledMeteredEndpoint{units: 1, unitsPerKB: 2, unitsField: []string{"items", "1", "cost"}}
+	body := []byte(`{"items": [{"cost": 5}, {"cost": 3}]}`)
+	perKB := 2 * float64(len(body)) / 1024
+
+	assert.Equal(t, 1+perKB+3, endpoint.unitsOf(body, true))
+	assert.Equal(t, 1+perKB, endpoint.unitsOf(body, false))
+	assert.Equal(t, 1+2*float64(len("[]"))/1024, endpoint.unitsOf([]byte("[]"), true))
+}
+
+func TestResponseMetering_limits(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Metering.EnableExport = true
+		globalConf.Metering.MaxExportedEvents = 2
+		globalConf.Metering.TotalsTTL = 60
+	})
+	defer ts.Close()
+
+	// larger than the bodies the units field is read from, its size is still metered
+	body := `{"usage": {"tokens": 42}, "padding": "` + strings.Repeat("a", meteringMaxFieldBody) + `"}`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(header.ContentType, header.ApplicationJSON)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "metered"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/metered/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.Metering = apidef.Metering{
+			Enabled:   true,
+			Endpoints: []apidef.MeteredEndpoint{{UnitsPerKB: 1024, UnitsField: "usage.tokens"}},
+		}
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"metered": {APIID: "metered"}}
+	})
+	authHeaders := map[string]string{header.Authorization: key}
+
+	store := &storage.RedisCluster{IsAnalytics: true, ConnectionHandler: ts.Gw.StorageConnectionHandler}
+	store.DeleteKey(meteringExportList)
+
+	for i := 0; i < 3; i++ {
+		_, _ = ts.Run(t, test.TestCase{Path: "/metered/", Headers: authHeaders, Code: http.StatusOK, BodyMatch: `"tokens": 42`})
+	}
+
+	events, err := store.GetListRange(meteringExportList, 0, -1)
+	require.NoError(t, err)
+	require.Len(t, events, 2, "the exported events are capped")
+
+	var event meteringEvent
+	require.NoError(t, json.Unmarshal([]byte(events[0]), &event))
+	assert.Equal(t, float64(len(body)), event.Data.Units, "the units field of large bodies isn't read")
+
+	ttl, err := ts.Gw.meteringStore().GetKeyTTL(meteringTotalsPrefix + storage.HashStr(key))
+	require.NoError(t, err)
+	assert.True(t, ttl > 0 && ttl <= 60, "the totals expire")
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// ResponseRateLimitHeadersMiddleware adds the RateLimit headers of the draft IETF RateLimit
+// header fields to the responses, from the current quota or the rate limit of the key.
+type ResponseRateLimitHeadersMiddleware struct {
+	BaseTykResponseHandler
+}
+
+func (h *ResponseRateLimitHeadersMiddleware) Base() *BaseTykResponseHandler {
+	return &h.BaseTykResponseHandler
+}
+
+func (*ResponseRateLimitHeadersMiddleware) Name() string {
+	return "ResponseRateLimitHeadersMiddleware"
+}
+
+func (h *ResponseRateLimitHeadersMiddleware) Enabled() bool {
+	return h.Spec.RateLimitHeaders.Enabled
+}
+
+func (h *ResponseRateLimitHeadersMiddleware) Init(_ interface{}, spec *APISpec) error {
+	h.Spec = spec
+	return nil
+}
+
+func (h *ResponseRateLimitHeadersMiddleware) HandleError(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *ResponseRateLimitHeadersMiddleware) HandleResponse(_ http.ResponseWriter, res *http.Response, _ *http.Request, session *user.SessionState) error {
+	if session == nil {
+		return nil
+	}
+
+	setRateLimitHeaders(res.Header, h.Spec, session, time.Now(), false)
+	return nil
+}
+
+// setRateLimitHeaders sets the RateLimit headers of the quota of the session for the API,
+// replacing the ones of the upstream. RateLimit-Reset is the number of seconds before the
+// quota renews. When the request is rate limited, or the quota is unlimited, the headers
+// report the rate limit window instead: RateLimit-Remaining is only set to 0 on the rate
+// limited requests, as the rate limiters don't tell the remaining requests of the window.
+// The headers aren't set when both the quota and the rate limit are unlimited.
+func setRateLimitHeaders(h http.Header, spec *APISpec, session *user.SessionState, now time.Time, rateLimited bool) {
+	accessDef, _, err := GetAccessDefinitionByAPIIDOrSession(session, spec)
+	if err != nil {
+		return
+	}
+	limit := accessDef.Limit
+
+	quota := !spec.DisableQuota && limit.QuotaMax > 0
+	rate := !spec.DisableRateLimit && limit.Rate > 0 && limit.Per > 0
+	switch {
+	case rate && (rateLimited || !quota):
+		h.Set(header.RateLimitLimit, strconv.FormatFloat(limit.Rate, 'f', -1, 64))
+		if rateLimited {
+			h.Set(header.RateLimitRemaining, "0")
+		} else {
+			h.Del(header.RateLimitRemaining)
+		}
+		h.Set(header.RateLimitReset, strconv.FormatInt(int64(math.Ceil(limit.Per)), 10))
+	case quota:
+		_, remaining, renewalRate, renews := session.GetQuotaLimitByAPIID(spec.APIID)
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		reset := renews - now.Unix()
+		if reset <= 0 {
+			// the counter was created by the request, and renews after the renewal rate
+			reset = renewalRate
+		}
+		if reset < 0 {
+			reset = 0
+		}
+
+		h.Set(header.RateLimitLimit, strconv.FormatInt(limit.QuotaMax, 10))
+		h.Set(header.RateLimitRemaining, strconv.FormatInt(remaining, 10))
+		h.Set(header.RateLimitReset, strconv.FormatInt(reset, 10))
+	}
+
+	if !spec.RateLimitHeaders.Policy {
+		return
+	}
+
+	var policies []string
+	if quota && limit.QuotaRenewalRate > 0 {
+		policies = append(policies, strconv.FormatInt(limit.QuotaMax, 10)+";w="+strconv.FormatInt(limit.QuotaRenewalRate, 10))
+	}
+	if rate {
+		policies = append(policies, strconv.FormatFloat(limit.Rate, 'f', -1, 64)+";w="+strconv.FormatFloat(limit.Per, 'f', -1, 64))
+	}
+
+	if len(policies) > 0 {
+		h.Set(header.RateLimitPolicy, strings.Join(policies, ", "))
+	}
+}
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestResponseRateLimitHeaders(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "headers"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/headers/"
+		spec.RateLimitHeaders = apidef.RateLimitHeaders{Enabled: true, Policy: true}
+	}, func(spec *APISpec) {
+		spec.APIID = "rate-headers"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/rate-headers/"
+		spec.RateLimitHeaders = apidef.RateLimitHeaders{Enabled: true}
+	}, func(spec *APISpec) {
+		spec.APIID = "no-headers"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/no-headers/"
+		spec.DisableQuota = true
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{
+			"headers":    {APIID: "headers"},
+			"no-headers": {APIID: "no-headers"},
+		}
+		s.Rate, s.Per = 10, 60
+		s.QuotaMax, s.QuotaRenewalRate = 2, 3600
+	})
+	authHeaders := map[string]string{header.Authorization: key}
+
+	t.Run("quota headers", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{
+			Path:    "/headers/",
+			Headers: authHeaders,
+			Code:    http.StatusOK,
+			HeadersMatch: map[string]string{
+				header.RateLimitLimit:     "2",
+				header.RateLimitRemaining: "1",
+				header.RateLimitPolicy:    "2;w=3600, 10;w=60",
+			},
+		})
+
+		reset, err := strconv.Atoi(resp.Header.Get(header.RateLimitReset))
+		assert.NoError(t, err)
+		assert.InDelta(t, 3600, reset, 60)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{
+			Path:            "/no-headers/",
+			Headers:         authHeaders,
+			Code:            http.StatusOK,
+			HeadersNotMatch: map[string]string{header.RateLimitLimit: "2"},
+		})
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/headers/", Headers: authHeaders, Code: http.StatusOK, HeadersMatch: map[string]string{header.RateLimitRemaining: "0"}},
+			{Path: "/headers/", Headers: authHeaders, Code: http.StatusForbidden, HeadersMatch: map[string]string{header.RateLimitRemaining: "0"}},
+		}...)
+	})
+
+	t.Run("rate limit exceeded", func(t *testing.T) {
+		_, rateKey := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{"rate-headers": {APIID: "rate-headers"}}
+			s.Rate, s.Per = 1, 60
+			s.QuotaMax = -1
+		})
+		rateHeaders := map[string]string{header.Authorization: rateKey}
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{
+				Path: "/rate-headers/", Headers: rateHeaders, Code: http.StatusOK,
+				HeadersMatch:    map[string]string{header.RateLimitLimit: "1", header.RateLimitReset: "60"},
+				HeadersNotMatch: map[string]string{header.RateLimitRemaining: "0"},
+			},
+			{
+				Path: "/rate-headers/", Headers: rateHeaders, Code: http.StatusTooManyRequests,
+				HeadersMatch: map[string]string{header.RateLimitLimit: "1", header.RateLimitRemaining: "0", header.RateLimitReset: "60"},
+			},
+		}...)
+	})
+}
@@ -0,0 +1,190 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/regexp"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// maxStatusMappingBody is the size of the response bodies read to check the body conditions of
+// the status mapping rules. Larger bodies are matched on their beginning.
+const maxStatusMappingBody = 1 << 20
+
+type compiledStatusRule struct {
+	apidef.ResponseStatusRule
+	path      *URLSpec
+	bodyField []string
+	pattern   *regexp.Regexp
+}
+
+func compileStatusRules(conf apidef.ResponseStatusMapping, gwConf config.Config, logger *logrus.Entry) []compiledStatusRule {
+	if !conf.Enabled {
+		return nil
+	}
+
+	var rules []compiledStatusRule
+	for _, rule := range conf.Rules {
+		compiled := compiledStatusRule{ResponseStatusRule: rule}
+		compiled.Method = strings.ToUpper(strings.TrimSpace(rule.Method))
+		ruleLogger := logger.WithFields(logrus.Fields{"path": rule.Path, "status": rule.Status})
+
+		if rule.Status < 100 || rule.Status > 599 {
+			ruleLogger.Error("Invalid response status mapping status")
+			continue
+		}
+
+		if rule.Path != "" {
+			path, err := compileEndpointPath(rule.Path, gwConf)
+			if err != nil {
+				ruleLogger.WithError(err).Error("Couldn't compile response status mapping path")
+				continue
+			}
+
+			compiled.path = path
+		}
+
+		if rule.BodyField != "" {
+			compiled.bodyField = strings.Split(rule.BodyField, ".")
+		}
+
+		if rule.BodyPattern != "" {
+			pattern, err := regexp.Compile(rule.BodyPattern)
+			if err != nil {
+				ruleLogger.WithError(err).Error("Couldn't compile response status mapping body pattern")
+				continue
+			}
+
+			compiled.pattern = pattern
+		}
+
+		rules = append(rules, compiled)
+	}
+
+	return rules
+}
+
+// needsBody returns true when the rule has body conditions.
+func (r *compiledStatusRule) needsBody() bool {
+	return r.bodyField != nil || r.pattern != nil
+}
+
+func (r *compiledStatusRule) matches(req *http.Request, res *http.Response, api *APISpec) bool {
+	if r.Method != "" && r.Method != req.Method {
+		return false
+	}
+
+	if len(r.UpstreamStatuses) > 0 && !slices.Contains(r.UpstreamStatuses, res.StatusCode) {
+		return false
+	}
+
+	return r.path == nil || r.path.matchesPath(req.URL.Path, api)
+}
+
+func (r *compiledStatusRule) matchesBody(body []byte) bool {
+	if r.bodyField != nil {
+		value, _, _, err := jsonparser.Get(body, r.bodyField...)
+		if err != nil || string(value) != r.BodyValue {
+			return false
+		}
+	}
+
+	return r.pattern == nil || r.pattern.Match(body)
+}
+
+// ResponseStatusMappingMiddleware maps the upstream response statuses to the statuses of the
+// status mapping rules of the API.
+type ResponseStatusMappingMiddleware struct {
+	BaseTykResponseHandler
+}
+
+func (h *ResponseStatusMappingMiddleware) Base() *BaseTykResponseHandler {
+	return &h.BaseTykResponseHandler
+}
+
+func (*ResponseStatusMappingMiddleware) Name() string {
+	return "ResponseStatusMappingMiddleware"
+}
+
+func (h *ResponseStatusMappingMiddleware) Enabled() bool {
+	return len(h.Spec.statusRules) > 0
+}
+
+func (h *ResponseStatusMappingMiddleware) Init(_ interface{}, spec *APISpec) error {
+	h.Spec = spec
+	return nil
+}
+
+func (h *ResponseStatusMappingMiddleware) HandleError(_ http.ResponseWriter, _ *http.Request) {}
+
+func (h *ResponseStatusMappingMiddleware) HandleResponse(_ http.ResponseWriter, res *http.Response, req *http.Request, _ *user.SessionState) error {
+	var body []byte
+	var bodyRead bool
+
+	for i := range h.Spec.statusRules {
+		rule := &h.Spec.statusRules[i]
+		if !rule.matches(req, res, h.Spec) {
+			continue
+		}
+
+		if rule.needsBody() {
+			// compressed bodies aren't decompressed to be matched
+			if res.Header.Get(header.ContentEncoding) != "" {
+				continue
+			}
+
+			if !bodyRead {
+				body = readStatusMappingBody(res)
+				bodyRead = true
+			}
+
+			if !rule.matchesBody(body) {
+				continue
+			}
+		}
+
+		log.WithFields(logrus.Fields{
+			"api_id":          h.Spec.APIID,
+			"upstream_status": res.StatusCode,
+			"status":          rule.Status,
+		}).Debug("Mapping response status")
+
+		res.StatusCode = rule.Status
+		res.Status = strconv.Itoa(rule.Status) + " " + http.StatusText(rule.Status)
+		return nil
+	}
+
+	return nil
+}
+
+// readStatusMappingBody reads the beginning of a response body, and restores the body.
+func readStatusMappingBody(res *http.Response) []byte {
+	if res.Body == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxStatusMappingBody))
+	if err != nil {
+		log.WithError(err).Warning("Couldn't read the response body for status mapping")
+	}
+
+	res.Body = statusMappingBody{Reader: io.MultiReader(bytes.NewReader(body), res.Body), Closer: res.Body}
+	return body
+}
+
+// statusMappingBody is a partially read response body.
+type statusMappingBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestResponseStatusMapping(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"code":"NOT_FOUND"}}`))
+		case "/conflict":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`duplicate key value violates unique constraint`))
+		case "/created":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"code":"INTERNAL"}}`))
+		}
+	}))
+	defer upstream.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.ResponseStatusMapping = apidef.ResponseStatusMapping{
+			Enabled: true,
+			Rules: []apidef.ResponseStatusRule{
+				{UpstreamStatuses: []int{http.StatusInternalServerError}, BodyField: "error.code", BodyValue: "NOT_FOUND", Status: http.StatusNotFound},
+				{UpstreamStatuses: []int{http.StatusInternalServerError}, BodyPattern: "unique constraint", Status: http.StatusConflict},
+				{Path: "/created", Method: http.MethodPost, Status: http.StatusCreated},
+				{Path: "/invalid", Status: 1000},
+			},
+		}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/missing", Code: http.StatusNotFound, BodyMatch: `"code":"NOT_FOUND"`},
+		{Path: "/conflict", Code: http.StatusConflict, BodyMatch: "unique constraint"},
+		{Path: "/other", Code: http.StatusInternalServerError, BodyMatch: "INTERNAL"},
+		{Method: http.MethodPost, Path: "/created", Code: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/created", Code: http.StatusOK},
+		{Path: "/invalid", Code: http.StatusInternalServerError},
+	}...)
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// checkResourceAudience checks the audience of an access token includes the resource
+// indicator of the API.
+func checkResourceAudience(spec *APISpec, w http.ResponseWriter, claims jwt.MapClaims) (error, int) {
+	conf := spec.ResourceIndicator
+	if !conf.Enabled || conf.Resource == "" {
+		return nil, http.StatusOK
+	}
+
+	if claims.VerifyAudience(conf.Resource, true) {
+		return nil, http.StatusOK
+	}
+
+	description := fmt.Sprintf("access token audience does not include resource %s", conf.Resource)
+	w.Header().Set(header.WWWAuthenticate, fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, description))
+
+	return fmt.Errorf("Access to this API has been disallowed: %s", description), http.StatusForbidden
+}
+
+// checkResourceScopes checks the access rights of a session to the API grant the scopes
+// the API requires.
+func checkResourceScopes(spec *APISpec, w http.ResponseWriter, accessDef user.AccessDefinition) (error, int) {
+	conf := spec.ResourceIndicator
+	if !conf.Enabled {
+		return nil, http.StatusOK
+	}
+
+	var missing []string
+	for _, scope := range conf.RequiredScopes {
+		if !slices.Contains(accessDef.Scopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil, http.StatusOK
+	}
+
+	resource := conf.Resource
+	if resource == "" {
+		resource = spec.APIID
+	}
+
+	description := fmt.Sprintf("missing scopes %v for resource %s", missing, resource)
+	w.Header().Set(header.WWWAuthenticate, fmt.Sprintf(`Bearer error="insufficient_scope", error_description=%q`, description))
+
+	return fmt.Errorf("Access to this API has been disallowed: %s", description), http.StatusForbidden
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const testResourceIndicator = "https://api.example.com/orders"
+
+func TestResourceIndicator_Audience(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	tokenKID := testKey(t.Name(), "token")
+	require.NoError(t, ts.Gw.GlobalSessionManager.UpdateSession(tokenKID, createJWTSession(), 60, false))
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.UseKeylessAccess = false
+		spec.EnableJWT = true
+		spec.JWTSigningMethod = HMACSign
+		spec.Proxy.ListenPath = "/orders/"
+		spec.ResourceIndicator = apidef.ResourceIndicator{Enabled: true, Resource: testResourceIndicator}
+	})
+
+	token := func(aud interface{}) string {
+		return createJWKTokenHMAC(func(t *jwt.Token) {
+			t.Header[KID] = tokenKID
+			t.Claims.(jwt.MapClaims)["exp"] = time.Now().Add(time.Hour).Unix()
+			if aud != nil {
+				t.Claims.(jwt.MapClaims)["aud"] = aud
+			}
+		})
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/orders/", Headers: map[string]string{header.Authorization: token(testResourceIndicator)}, Code: http.StatusOK},
+		{Path: "/orders/", Headers: map[string]string{header.Authorization: token([]string{"https://api.example.com/users", testResourceIndicator})}, Code: http.StatusOK},
+		{Path: "/orders/", Headers: map[string]string{header.Authorization: token("https://api.example.com/users")}, Code: http.StatusForbidden,
+			BodyMatch:    "access token audience does not include resource " + testResourceIndicator,
+			HeadersMatch: map[string]string{header.WWWAuthenticate: `Bearer error="invalid_token", error_description="access token audience does not include resource ` + testResourceIndicator + `"`}},
+		{Path: "/orders/", Headers: map[string]string{header.Authorization: token(nil)}, Code: http.StatusForbidden},
+	}...)
+}
+
+func TestResourceIndicator_Scopes(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	api := ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "orders"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/orders/"
+		spec.ResourceIndicator = apidef.ResourceIndicator{Enabled: true, Resource: testResourceIndicator, RequiredScopes: []string{"orders:read"}}
+	})[0]
+
+	createKey := func(scopes ...string) string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID, Versions: []string{"v1"}, Scopes: scopes}}
+		})
+		return key
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/orders/", Headers: map[string]string{header.Authorization: createKey("orders:read", "orders:write")}, Code: http.StatusOK},
+		{Path: "/orders/", Headers: map[string]string{header.Authorization: createKey("users:read")}, Code: http.StatusForbidden,
+			BodyMatch:    `missing scopes \[orders:read\] for resource ` + testResourceIndicator,
+			HeadersMatch: map[string]string{header.WWWAuthenticate: `Bearer error="insufficient_scope", error_description="missing scopes [orders:read] for resource ` + testResourceIndicator + `"`}},
+	}...)
+}
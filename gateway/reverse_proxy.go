@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/textproto"
 	"net/url"
 	"strconv"
@@ -66,7 +67,7 @@ func urlFromService(spec *APISpec, gw *Gateway) (*apidef.HostList, error) {
 		log.Debug("--> Refreshing")
 		spec.ServiceRefreshInProgress = true
 		defer func() { spec.ServiceRefreshInProgress = false }()
-		sd := ServiceDiscovery{}
+		sd := ServiceDiscovery{client: gw.serviceDiscoveryClient()}
 		sd.Init(&spec.Proxy.ServiceDiscovery)
 		data, err := sd.Target(spec.Proxy.ServiceDiscovery.QueryEndpoint)
 		if err != nil {
@@ -230,15 +231,20 @@ func (gw *Gateway) TykNewSingleHostReverseProxy(target *url.URL, spec *APISpec,
 
 	logger = logger.WithField("mw", "ReverseProxy")
 
-	targetQuery := target.RawQuery
 	director := func(req *http.Request) {
 		logger := logger
 		spec := spec
 		target := target
 		gw := gw
+		// the query of the target is request local, the routes and load balancing replace it
+		targetQuery := target.RawQuery
 
 		hostList := spec.Proxy.StructuredTargetList
-		switch {
+		switch route := ctxGetUpstreamRoute(req); {
+		case route != nil:
+			// chosen by an upstream routing rule
+			target = route.target
+			targetQuery = target.RawQuery
 		case spec.Proxy.ServiceDiscovery.UseDiscoveryService:
 			var err error
 			hostList, err = urlFromService(spec, gw)
@@ -316,6 +322,10 @@ func (gw *Gateway) TykNewSingleHostReverseProxy(target *url.URL, spec *APISpec,
 			req.URL.RawPath = ""
 		}
 
+		if diagnostics := ctxGetDebugDiagnostics(req); diagnostics != nil {
+			diagnostics.setUpstreamTarget(req.URL.Scheme + "://" + req.URL.Host)
+		}
+
 		switch req.URL.Scheme {
 		case "ws":
 			req.URL.Scheme = "http"
@@ -399,7 +409,7 @@ func (p *ReverseProxy) defaultTransport(dialerTimeout float64) *http.Transport {
 		DualStack: true,
 	}
 	dialContextFunc := dialer.DialContext
-	if p.Gw.dnsCacheManager.IsCacheEnabled() {
+	if p.Gw.dnsCacheManager.IsCacheEnabled() || p.Gw.dnsResolver != nil {
 		dialContextFunc = p.Gw.dnsCacheManager.WrapDialer(dialer)
 	}
 
@@ -648,6 +658,8 @@ func tlsClientConfig(s *APISpec, gw *Gateway) *tls.Config {
 		config.InsecureSkipVerify = true
 	}
 
+	config.RootCAs = gw.upstreamCertPool(s)
+
 	if s.GlobalConfig.ProxySSLMinVersion > 0 {
 		config.MinVersion = s.GlobalConfig.ProxySSLMinVersion
 	}
@@ -693,6 +705,8 @@ func (p *ReverseProxy) httpTransport(timeOut float64, rw http.ResponseWriter, re
 		transport.TLSClientConfig.InsecureSkipVerify = true
 	}
 
+	transport.TLSClientConfig.RootCAs = p.Gw.upstreamCertPool(p.TykAPISpec)
+
 	// When request routed through the proxy `DialTLS` is not used, and only VerifyPeerCertificate is supported
 	// The reason behind two separate checks is that `DialTLS` supports specifying public keys per hostname, and `VerifyPeerCertificate` only global ones, e.g. `*`
 	if proxyURL, _ := transport.Proxy(req); proxyURL != nil {
@@ -736,13 +750,19 @@ func (p *ReverseProxy) httpTransport(timeOut float64, rw http.ResponseWriter, re
 
 	transport.DisableKeepAlives = p.TykAPISpec.GlobalConfig.ProxyCloseConnections
 
-	if p.Gw.GetConfig().ProxyEnableHttp2 {
-		http2.ConfigureTransport(transport)
+	h2conf := p.TykAPISpec.Proxy.Transport.HTTP2
+	if p.Gw.GetConfig().ProxyEnableHttp2 || h2conf.Enabled {
+		h2t, err := http2.ConfigureTransports(transport)
+		if err != nil {
+			p.logger.WithError(err).Error("Couldn't enable HTTP/2 to the upstream")
+		} else {
+			p.configureHTTP2(h2t)
+		}
 	}
 
 	p.logger.Debug("Out request url: ", outReq.URL.String())
 
-	if outReq.URL.Scheme == "h2c" {
+	if outReq.URL.Scheme == "h2c" || (h2conf.PriorKnowledge && outReq.URL.Scheme == "http") {
 		p.logger.Info("Enabling h2c mode")
 		h2t := &http2.Transport{
 			// kind of a hack, but for plaintext/H2C requests, pretend to dial TLS
@@ -751,10 +771,11 @@ func (p *ReverseProxy) httpTransport(timeOut float64, rw http.ResponseWriter, re
 			},
 			AllowHTTP: true,
 		}
-		return &TykRoundTripper{transport, h2t, p.logger, p.Gw}
+		p.configureHTTP2(h2t)
+		return p.limitStreams(&TykRoundTripper{transport, h2t, p.logger, p.Gw, nil})
 	}
 
-	return &TykRoundTripper{transport, nil, p.logger, p.Gw}
+	return p.limitStreams(&TykRoundTripper{transport, nil, p.logger, p.Gw, nil})
 }
 
 func (p *ReverseProxy) setCommonNameVerifyPeerCertificate(tlsConfig *tls.Config, hostName string) {
@@ -811,9 +832,35 @@ type TykRoundTripper struct {
 	h2ctransport *http2.Transport
 	logger       *logrus.Entry
 	Gw           *Gateway `json:"-"`
+
+	// streams caps the concurrent requests to the upstream, when set.
+	streams chan struct{}
 }
 
 func (rt *TykRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if rt.streams != nil && r.URL.Scheme != "tyk" {
+		select {
+		case rt.streams <- struct{}{}:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+
+		res, err := rt.roundTrip(r)
+		if err != nil || res.StatusCode == http.StatusSwitchingProtocols {
+			// upgraded connections aren't HTTP/2 streams
+			<-rt.streams
+			return res, err
+		}
+
+		// the stream is released once the response body is consumed
+		res.Body = &streamReleaser{ReadCloser: res.Body, release: func() { <-rt.streams }}
+		return res, nil
+	}
+
+	return rt.roundTrip(r)
+}
+
+func (rt *TykRoundTripper) roundTrip(r *http.Request) (*http.Response, error) {
 
 	hasInternalHeader := r.Header.Get(apidef.TykInternalApiHeader) != ""
 
@@ -1043,7 +1090,7 @@ func (p *ReverseProxy) handleGraphQL(roundTripper *TykRoundTripper, outreq *http
 }
 
 func (p *ReverseProxy) sendRequestToUpstream(roundTripper *TykRoundTripper, outreq *http.Request) (res *http.Response, err error) {
-	return roundTripper.RoundTrip(outreq)
+	return roundTripper.RoundTrip(withUpstreamTrace(outreq))
 }
 
 func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Request, withCache bool) ProxyResponse {
@@ -1124,13 +1171,17 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		if hv == "" {
 			continue
 		}
-		if h == "Te" && hv == "trailers" {
+		if h == "Te" && hv == "trailers" && !p.TykAPISpec.ProtocolPassthrough.DisableTrailers {
 			continue
 		}
 		outreq.Header.Del(h)
 		logreq.Header.Del(h)
 	}
 
+	if p.TykAPISpec.ProtocolPassthrough.DisableTrailers {
+		outreq.Trailer = nil
+	}
+
 	if outReqUpgrade {
 		outreq.Header.Set("Connection", "Upgrade")
 		logreq.Header.Set("Connection", "Upgrade")
@@ -1221,6 +1272,14 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 
 	p.addAuthInfo(outreq, req)
 
+	if p.Gw.GetConfig().EnableConnectionMetrics {
+		outreq = outreq.WithContext(httptrace.WithClientTrace(outreq.Context(), p.Gw.connectionMetrics.upstreamTrace(outreq.URL.Host)))
+	}
+
+	if p.TykAPISpec.ProtocolPassthrough.InterimResponses {
+		outreq = outreq.WithContext(httptrace.WithClientTrace(outreq.Context(), p.informationalResponseTrace(rw)))
+	}
+
 	// do request round trip
 	var (
 		res             *http.Response
@@ -1229,6 +1288,13 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		err             error
 	)
 
+	budget := p.TykAPISpec.upstreamErrorBudget
+	if budget != nil && budget.isDegraded() {
+		p.logger.Debug("ON REQUEST: Upstream is degraded, serving the fallback response")
+		p.serveDegraded(rw, logreq)
+		return ProxyResponse{}
+	}
+
 	if breakerEnforced {
 		if !breakerConf.CB.Ready() {
 			p.logger.Debug("ON REQUEST: Circuit Breaker is in OPEN state")
@@ -1238,7 +1304,7 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		p.logger.Debug("ON REQUEST: Circuit Breaker is in CLOSED or HALF-OPEN state")
 
 		res, isHijacked, upstreamLatency, err = p.handleOutboundRequest(roundTripper, outreq, rw)
-		if err != nil || res.StatusCode/100 == 5 {
+		if err != nil && httputil.RequestAnomaly(req) == nil || err == nil && res.StatusCode/100 == 5 {
 			breakerConf.CB.Fail()
 		} else {
 			breakerConf.CB.Success()
@@ -1247,6 +1313,16 @@ func (p *ReverseProxy) WrappedServeHTTP(rw http.ResponseWriter, req *http.Reques
 		res, isHijacked, upstreamLatency, err = p.handleOutboundRequest(roundTripper, outreq, rw)
 	}
 
+	if err != nil && httputil.RequestAnomaly(req) != nil {
+		// the body sent by the client is abnormal, the upstream isn't at fault
+		p.ErrorHandler.HandleError(rw, logreq, http.StatusText(http.StatusBadRequest), http.StatusBadRequest, true)
+		return ProxyResponse{UpstreamLatency: upstreamLatency}
+	}
+
+	if budget != nil {
+		p.Gw.recordUpstreamRequest(p.TykAPISpec, budget, err)
+	}
+
 	if err != nil {
 		token := ctxGetAuthToken(req)
 
@@ -1389,8 +1465,10 @@ func (p *ReverseProxy) HandleResponse(rw http.ResponseWriter, res *http.Response
 
 	copyHeader(rw.Header(), res.Header, p.Gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
 
+	trailers := !p.TykAPISpec.ProtocolPassthrough.DisableTrailers
+
 	announcedTrailers := len(res.Trailer)
-	if announcedTrailers > 0 {
+	if trailers && announcedTrailers > 0 {
 		trailerKeys := make([]string, 0, len(res.Trailer))
 		for k := range res.Trailer {
 			trailerKeys = append(trailerKeys, k)
@@ -1403,7 +1481,7 @@ func (p *ReverseProxy) HandleResponse(rw http.ResponseWriter, res *http.Response
 		rw.WriteHeader(res.StatusCode)
 	}
 
-	if len(res.Trailer) > 0 {
+	if trailers && len(res.Trailer) > 0 {
 		// Force chunking if we saw a response trailer.
 		// This prevents net/http from calculating the length for short
 		// bodies and adding a Content-Length.
@@ -1414,6 +1492,10 @@ func (p *ReverseProxy) HandleResponse(rw http.ResponseWriter, res *http.Response
 
 	p.CopyResponse(rw, res.Body, p.flushInterval(res))
 
+	if !trailers {
+		return nil
+	}
+
 	if len(res.Trailer) == announcedTrailers {
 		copyHeader(rw.Header(), res.Trailer, p.Gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
 		return nil
@@ -1428,6 +1510,37 @@ func (p *ReverseProxy) HandleResponse(rw http.ResponseWriter, res *http.Response
 	return nil
 }
 
+// informationalResponseTrace forwards the 1xx responses of the upstream, other than
+// 101 Switching Protocols, to the client before the final response.
+func (p *ReverseProxy) informationalResponseTrace(rw http.ResponseWriter) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, hdr textproto.MIMEHeader) error {
+			if code == http.StatusSwitchingProtocols {
+				return nil
+			}
+
+			// the headers set for the final response are restored once the 1xx response is written
+			h := rw.Header()
+			final := h.Clone()
+			for k := range h {
+				delete(h, k)
+			}
+
+			copyHeader(h, http.Header(hdr), p.Gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
+			rw.WriteHeader(code)
+
+			for k := range h {
+				delete(h, k)
+			}
+			for k, vv := range final {
+				h[k] = vv
+			}
+
+			return nil
+		},
+	}
+}
+
 // flushInterval returns the p.FlushInterval value, conditionally
 // overriding its value for a specific request/response.
 func (p *ReverseProxy) flushInterval(res *http.Response) time.Duration {
@@ -12,6 +12,8 @@ import (
 	mathrand "math/rand"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -2059,3 +2061,79 @@ func BenchmarkLargeResponsePayload(b *testing.B) {
 		})
 	}
 }
+
+func TestProtocolPassthrough(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Link", "</style.css>; rel=preload")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("X-Request-Te", r.Header.Get("Te"))
+		_, _ = w.Write(body)
+		w.Header().Set("X-Checksum", "sum-"+r.Trailer.Get("X-Request-Checksum"))
+	}))
+	defer upstream.Close()
+
+	load := func(passthrough apidef.ProtocolPassthrough) {
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = upstream.URL
+			spec.ProtocolPassthrough = passthrough
+		})
+	}
+
+	do := func(t *testing.T) (*http.Response, []int) {
+		t.Helper()
+
+		var informational []int
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, hdr textproto.MIMEHeader) error {
+				assert.Equal(t, "</style.css>; rel=preload", hdr.Get("Link"))
+				informational = append(informational, code)
+				return nil
+			},
+		}
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/", io.NopCloser(strings.NewReader("payload")))
+		require.NoError(t, err)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		req.ContentLength = -1
+		req.Header.Set("Te", "trailers")
+		req.Trailer = http.Header{"X-Request-Checksum": {"abc"}}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "payload", string(body))
+
+		return resp, informational
+	}
+
+	t.Run("passthrough", func(t *testing.T) {
+		load(apidef.ProtocolPassthrough{InterimResponses: true})
+
+		resp, informational := do(t)
+		assert.Equal(t, []int{http.StatusEarlyHints}, informational)
+		assert.Equal(t, "trailers", resp.Header.Get("X-Request-Te"))
+		assert.Empty(t, resp.Header.Get("Link"))
+		assert.Equal(t, "sum-abc", resp.Trailer.Get("X-Checksum"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		load(apidef.ProtocolPassthrough{DisableTrailers: true})
+
+		resp, informational := do(t)
+		assert.Empty(t, informational)
+		assert.Empty(t, resp.Header.Get("X-Request-Te"))
+		assert.Empty(t, resp.Trailer.Get("X-Checksum"))
+	})
+}
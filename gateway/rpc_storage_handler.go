@@ -77,6 +77,9 @@ var (
 		"PurgeAnalyticsData": func(data string) error {
 			return nil
 		},
+		"PurgeAnalyticsAggregates": func(data string) error {
+			return nil
+		},
 		"CheckReload": func(clientAddr, orgId string) (bool, error) {
 			return false, nil
 		},
@@ -0,0 +1,276 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	// secretScanningPath is where GitHub secret scanning reports leaked keys.
+	secretScanningPath = "/secret-scanning/verify"
+
+	defaultSecretScanningKeysURL  = "https://api.github.com/meta/public_keys/secret_scanning"
+	secretScanningKeysCachePrefix = "secret-scanning-keys-"
+	secretScanningKeysCacheTTL    = 3600
+	secretScanningKeysMinRefresh  = time.Minute
+	// secretScanningKeysFailureBackoff is the number of seconds the keys aren't fetched again
+	// for after a failed fetch, as the endpoint is reachable without authentication.
+	secretScanningKeysFailureBackoff = 30
+	// secretScanningMaxBodySize limits the size of the reports, which are small batches of tokens.
+	secretScanningMaxBodySize = 1 << 20
+
+	secretScanningKeyIdentifierHeader = "Github-Public-Key-Identifier"
+	secretScanningSignatureHeader     = "Github-Public-Key-Signature"
+
+	secretScanningTruePositive  = "true_positive"
+	secretScanningFalsePositive = "false_positive"
+)
+
+var (
+	errSecretScanningSignature = errors.New("Invalid secret scanning signature")
+	errSecretScanningKeys      = errors.New("Couldn't load secret scanning public keys")
+
+	secretScanningHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// secretScanningKeys are the cached GitHub public keys, by identifier.
+type secretScanningKeys struct {
+	keys    map[string]*ecdsa.PublicKey
+	fetched time.Time
+}
+
+// secretScanningMatch is a candidate token reported by GitHub.
+type secretScanningMatch struct {
+	Token  string `json:"token"`
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Source string `json:"source"`
+}
+
+// secretScanningResult tells GitHub whether a candidate token is an active key.
+type secretScanningResult struct {
+	TokenRaw  string `json:"token_raw"`
+	TokenType string `json:"token_type"`
+	Label     string `json:"label"`
+}
+
+func (gw *Gateway) secretScanningHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		doJSONWrite(w, http.StatusMethodNotAllowed, apiError(http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, secretScanningMaxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			doJSONWrite(w, http.StatusRequestEntityTooLarge, apiError(http.StatusText(http.StatusRequestEntityTooLarge)))
+			return
+		}
+		doJSONWrite(w, http.StatusBadRequest, apiError("Couldn't read request body"))
+		return
+	}
+
+	if err := gw.verifySecretScanningSignature(r.Header.Get(secretScanningKeyIdentifierHeader), r.Header.Get(secretScanningSignatureHeader), body); err != nil {
+		log.WithError(err).Warning("Rejected secret scanning report")
+		doJSONWrite(w, http.StatusUnauthorized, apiError(errSecretScanningSignature.Error()))
+		return
+	}
+
+	var matches []secretScanningMatch
+	if err := json.Unmarshal(body, &matches); err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	results := make([]secretScanningResult, 0, len(matches))
+	for _, match := range matches {
+		label := secretScanningFalsePositive
+		if gw.handleLeakedKey(match) {
+			label = secretScanningTruePositive
+		}
+
+		results = append(results, secretScanningResult{
+			TokenRaw:  match.Token,
+			TokenType: match.Type,
+			Label:     label,
+		})
+	}
+
+	doJSONWrite(w, http.StatusOK, results)
+}
+
+// handleLeakedKey looks up the session of a reported token and, when it is an active key,
+// fires a KeyLeaked event and suspends the key if configured for its organisation.
+// It returns whether the token is an active key.
+func (gw *Gateway) handleLeakedKey(match secretScanningMatch) bool {
+	if match.Token == "" {
+		return false
+	}
+
+	orgID := storage.TokenOrg(match.Token)
+	session, found := gw.GlobalSessionManager.SessionDetail(orgID, match.Token, false)
+	if !found || session.IsInactive || gw.GlobalSessionManager.KeyExpired(&session) {
+		return false
+	}
+
+	keyID := storage.HashKey(match.Token, gw.GetConfig().HashKeys)
+	logger := log.WithFields(logrus.Fields{
+		"prefix": "secret-scanning",
+		"org_id": session.OrgID,
+		"key":    gw.obfuscateKey(match.Token),
+		"url":    match.URL,
+	})
+
+	suspended := false
+	if gw.suspendLeakedKeys(session.OrgID) {
+		session.IsInactive = true
+		if err := gw.GlobalSessionManager.UpdateSession(match.Token, &session, gw.ApplyLifetime(&session, nil), false); err != nil {
+			logger.WithError(err).Error("Couldn't suspend leaked key")
+		} else {
+			suspended = true
+		}
+	}
+
+	logger.WithField("suspended", suspended).Warning("Key reported as leaked by secret scanning")
+
+	gw.FireSystemEvent(EventKeyLeaked, EventKeyLeakedMeta{
+		EventMetaDefault: EventMetaDefault{Message: "Key reported as leaked by secret scanning."},
+		Org:              session.OrgID,
+		KeyID:            keyID,
+		URL:              match.URL,
+		Source:           match.Source,
+		Suspended:        suspended,
+	})
+
+	return true
+}
+
+// suspendLeakedKeys tells whether leaked keys of an organisation are deactivated.
+func (gw *Gateway) suspendLeakedKeys(orgID string) bool {
+	conf := gw.GetConfig().Security.SecretScanning
+	if suspend, ok := conf.OrgSuspendLeakedKeys[orgID]; ok {
+		return suspend
+	}
+
+	return conf.SuspendLeakedKeys
+}
+
+// verifySecretScanningSignature checks the body is signed with the GitHub public key of
+// the given identifier. Keys are cached, and fetched again for an unknown identifier to
+// pick up rotated keys, at most once per secretScanningKeysMinRefresh.
+func (gw *Gateway) verifySecretScanningSignature(keyID, signature string, body []byte) error {
+	if keyID == "" || signature == "" {
+		return errors.New("signature headers missing")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	key, err := gw.secretScanningKey(keyID)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(body)
+	if !ecdsa.VerifyASN1(key, digest[:], sig) {
+		return errSecretScanningSignature
+	}
+
+	return nil
+}
+
+func (gw *Gateway) secretScanningKey(keyID string) (*ecdsa.PublicKey, error) {
+	keysURL := gw.GetConfig().Security.SecretScanning.PublicKeysURL
+	if keysURL == "" {
+		keysURL = defaultSecretScanningKeysURL
+	}
+	cacheKey := secretScanningKeysCachePrefix + keysURL
+
+	if cached, found := gw.UtilCache.Get(cacheKey); found {
+		cachedKeys := cached.(secretScanningKeys)
+		if key, ok := cachedKeys.keys[keyID]; ok {
+			return key, nil
+		}
+		if time.Since(cachedKeys.fetched) < secretScanningKeysMinRefresh {
+			return nil, fmt.Errorf("unknown public key %q", keyID)
+		}
+	}
+
+	failedKey := cacheKey + "-failed"
+	if _, failed := gw.UtilCache.Get(failedKey); failed {
+		return nil, errSecretScanningKeys
+	}
+
+	keys, err := fetchSecretScanningKeys(keysURL)
+	if err != nil {
+		log.WithError(err).Error(errSecretScanningKeys.Error())
+		gw.UtilCache.Set(failedKey, true, secretScanningKeysFailureBackoff)
+		return nil, errSecretScanningKeys
+	}
+	gw.UtilCache.Set(cacheKey, secretScanningKeys{keys: keys, fetched: time.Now()}, secretScanningKeysCacheTTL)
+
+	key, ok := keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown public key %q", keyID)
+	}
+
+	return key, nil
+}
+
+// fetchSecretScanningKeys downloads the GitHub secret scanning public keys, by identifier.
+func fetchSecretScanningKeys(keysURL string) (map[string]*ecdsa.PublicKey, error) {
+	resp, err := secretScanningHTTPClient.Get(keysURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var meta struct {
+		PublicKeys []struct {
+			KeyIdentifier string `json:"key_identifier"`
+			Key           string `json:"key"`
+		} `json:"public_keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*ecdsa.PublicKey, len(meta.PublicKeys))
+	for _, publicKey := range meta.PublicKeys {
+		block, _ := pem.Decode([]byte(publicKey.Key))
+		if block == nil {
+			continue
+		}
+
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		if key, ok := parsed.(*ecdsa.PublicKey); ok {
+			keys[publicKey.KeyIdentifier] = key
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestSecretScanning(t *testing.T) {
+	githubKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&githubKey.PublicKey)
+	require.NoError(t, err)
+
+	keysServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"public_keys": []map[string]interface{}{{
+				"key_identifier": "github-key",
+				"key":            string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+				"is_current":     true,
+			}},
+		})
+	}))
+	defer keysServer.Close()
+
+	leaked := make(chan EventKeyLeakedMeta, 10)
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Security.SecretScanning.Enabled = true
+		globalConf.Security.SecretScanning.PublicKeysURL = keysServer.URL
+		globalConf.Security.SecretScanning.SuspendLeakedKeys = true
+		globalConf.Security.SecretScanning.OrgSuspendLeakedKeys = map[string]bool{"no-suspend": false}
+		globalConf.SetEventTriggers(map[apidef.TykEvent][]config.TykEventHandler{
+			EventKeyLeaked: {&testEventHandler{func(em config.EventMessage) {
+				leaked <- em.Meta.(EventKeyLeakedMeta)
+			}}},
+		})
+	})
+	defer ts.Close()
+
+	api := BuildAPI(func(spec *APISpec) {
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/"
+	})[0]
+	ts.Gw.LoadAPI(api)
+
+	accessRights := func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{api.APIID: {APIID: api.APIID}}
+	}
+	_, suspendedKey := ts.CreateSession(accessRights)
+	_, activeKey := ts.CreateSession(func(s *user.SessionState) {
+		accessRights(s)
+		s.OrgID = "no-suspend"
+	})
+
+	report := func(matches ...secretScanningMatch) (string, map[string]string) {
+		body, _ := json.Marshal(matches)
+		digest := sha256.Sum256(body)
+		sig, err := ecdsa.SignASN1(rand.Reader, githubKey, digest[:])
+		require.NoError(t, err)
+
+		return string(body), map[string]string{
+			secretScanningKeyIdentifierHeader: "github-key",
+			secretScanningSignatureHeader:     base64.StdEncoding.EncodeToString(sig),
+		}
+	}
+
+	t.Run("invalid signature", func(t *testing.T) {
+		body, headers := report(secretScanningMatch{Token: suspendedKey})
+		headers[secretScanningSignatureHeader] = base64.StdEncoding.EncodeToString([]byte("invalid"))
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Method: http.MethodPost, Path: secretScanningPath, Data: body, Headers: headers, Code: http.StatusUnauthorized},
+			{Method: http.MethodPost, Path: secretScanningPath, Data: body, Code: http.StatusUnauthorized},
+			{Method: http.MethodPost, Path: secretScanningPath, Data: body, Code: http.StatusUnauthorized, Headers: map[string]string{
+				secretScanningKeyIdentifierHeader: "unknown",
+				secretScanningSignatureHeader:     headers[secretScanningSignatureHeader],
+			}},
+		}...)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		body, headers := report(
+			secretScanningMatch{Token: suspendedKey, Type: "tyk_key", URL: "https://github.com/example/repo/blob/main/config"},
+			secretScanningMatch{Token: activeKey, Type: "tyk_key"},
+			secretScanningMatch{Token: "unknown", Type: "tyk_key"},
+		)
+
+		resp, _ := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: secretScanningPath, Data: body, Headers: headers, Code: http.StatusOK})
+
+		var results []secretScanningResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+		assert.Equal(t, []secretScanningResult{
+			{TokenRaw: suspendedKey, TokenType: "tyk_key", Label: secretScanningTruePositive},
+			{TokenRaw: activeKey, TokenType: "tyk_key", Label: secretScanningTruePositive},
+			{TokenRaw: "unknown", TokenType: "tyk_key", Label: secretScanningFalsePositive},
+		}, results)
+
+		events := map[bool]EventKeyLeakedMeta{}
+		for i := 0; i < 2; i++ {
+			meta := <-leaked
+			events[meta.Suspended] = meta
+		}
+		assert.Equal(t, "https://github.com/example/repo/blob/main/config", events[true].URL)
+		assert.Equal(t, "no-suspend", events[false].Org)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{Path: "/", Headers: map[string]string{"Authorization": suspendedKey}, Code: http.StatusForbidden},
+			{Path: "/", Headers: map[string]string{"Authorization": activeKey}, Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("suspended key is no longer reported", func(t *testing.T) {
+		body, headers := report(secretScanningMatch{Token: suspendedKey, Type: "tyk_key"})
+
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: secretScanningPath, Data: body, Headers: headers,
+			Code: http.StatusOK, BodyMatch: secretScanningFalsePositive})
+	})
+}
+
+func TestSecretScanning_limits(t *testing.T) {
+	var fetches int32
+	keysServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer keysServer.Close()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Security.SecretScanning.Enabled = true
+		globalConf.Security.SecretScanning.PublicKeysURL = keysServer.URL
+	})
+	defer ts.Close()
+
+	headers := map[string]string{
+		secretScanningKeyIdentifierHeader: "github-key",
+		secretScanningSignatureHeader:     base64.StdEncoding.EncodeToString([]byte("signature")),
+	}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: secretScanningPath, Data: strings.Repeat("a", secretScanningMaxBodySize+1), Headers: headers,
+			Code: http.StatusRequestEntityTooLarge},
+		{Method: http.MethodPost, Path: secretScanningPath, Data: `[]`, Headers: headers, Code: http.StatusUnauthorized},
+		{Method: http.MethodPost, Path: secretScanningPath, Data: `[]`, Headers: headers, Code: http.StatusUnauthorized},
+	}...)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "failed fetches are backed off")
+}
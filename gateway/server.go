@@ -28,6 +28,7 @@ import (
 	"github.com/TykTechnologies/tyk/internal/crypto"
 	"github.com/TykTechnologies/tyk/internal/httputil"
 	"github.com/TykTechnologies/tyk/internal/otel"
+	"github.com/TykTechnologies/tyk/internal/policy"
 	"github.com/TykTechnologies/tyk/internal/scheduler"
 	"github.com/TykTechnologies/tyk/test"
 
@@ -108,7 +109,16 @@ type Gateway struct {
 
 	drlOnce    sync.Once
 	DRLManager *drl.DRL
-	reloadMu   sync.Mutex
+	// drlServers keeps the server notifications received by the DRL, for debugging.
+	drlServers drlServerRegistry
+
+	// upstreamBudgets are the upstream error budgets of the APIs, by API ID.
+	upstreamBudgetsMu sync.Mutex
+	upstreamBudgets   map[string]*upstreamErrorBudget
+
+	reloadMu sync.Mutex
+	// apisReloaded is set once the first reload loaded the APIs and policies.
+	apisReloaded atomic.Bool
 
 	Analytics            RedisAnalyticsHandler
 	GlobalEventsJSVM     JSVM
@@ -122,6 +132,7 @@ type Gateway struct {
 	CertificateManager   certs.CertificateManager
 	GlobalHostChecker    HostCheckerManager
 	ConnectionWatcher    *httputil.ConnectionWatcher
+	RequestAnomalies     *httputil.AnomalyCounters
 	HostCheckTicker      chan struct{}
 	HostCheckerClient    *http.Client
 	TracerProvider       otel.TracerProvider
@@ -143,6 +154,8 @@ type Gateway struct {
 	UtilCache cache.Repository
 	// ServiceCache is the service discovery cache
 	ServiceCache cache.Repository
+	// QuotaPoolCache caches the quota pool definitions
+	QuotaPoolCache cache.Repository
 
 	// Nonce to use when interacting with the dashboard service
 	ServiceNonce      string
@@ -156,7 +169,24 @@ type Gateway struct {
 	policiesMu   sync.RWMutex
 	policiesByID map[string]user.Policy
 
+	// faultInjectionToggles holds the fault injection settings overridden at runtime, by API ID.
+	faultInjectionToggles sync.Map
+
+	// connectionMetrics collects the metrics of client and upstream connections, when enabled.
+	connectionMetrics *connectionMetrics
+
+	// cacheWarmers prefetches the warm-up endpoints of the APIs into their cache.
+	cacheWarmers cacheWarmers
+
+	// configTimeStampMu guards the timestamp of the last applied signed remote configuration.
+	configTimeStampMu sync.Mutex
+
+	// chainCache keeps the chains of the APIs unloaded by reloads, keyed by definition checksum.
+	chainCache chainCache
+
 	dnsCacheManager dnscache.IDnsCacheManager
+	// dnsResolver resolves the upstream host names when a DNS resolver is configured.
+	dnsResolver *dnscache.Resolver
 
 	consulKVStore kv.Store
 	vaultKVStore  kv.Store
@@ -164,6 +194,35 @@ type Gateway struct {
 	// signatureVerifier is used to verify signatures with config.PublicKeyPath.
 	signatureVerifier atomic.Pointer[goverify.Verifier]
 
+	// loadDiagnostics keeps the diagnostics bundles of the APIs which failed to load.
+	loadDiagnostics loadDiagnosticsStore
+
+	// policyBundle is the loaded policy bundle, see config.PolicyBundle.
+	policyBundle atomic.Pointer[compiledPolicyBundle]
+
+	// fingerprintCache is the fingerprint of the loaded APIs, policies and configuration.
+	fingerprintCache      atomic.Pointer[stateFingerprint]
+	fingerprintGeneration atomic.Uint64
+
+	// serverCertificates are the certificates served by the TLS listeners.
+	serverCertificates atomic.Pointer[serverCertificates]
+
+	// externalSessionLookupClient is the client of config.ExternalSessionLookup, built for its timeout.
+	externalSessionLookupClient atomic.Pointer[externalSessionLookupClient]
+
+	// storageGCReport is the last report of the orphaned storage artifacts.
+	storageGCReport atomic.Pointer[storageGCReport]
+
+	// analyticsPurgerStats counts the analytics shipped by the analytics purger, when enabled.
+	analyticsPurgerStats atomic.Pointer[rpc.PurgerStats]
+
+	// analyticsBudget and cacheBudget are the byte budgets of config.StorageBudgets.
+	analyticsBudget *storageBudget
+	cacheBudget     *storageBudget
+
+	// processReloads receives the in-process reloads of the configuration, see reloadProcess.
+	processReloads chan struct{}
+
 	RedisPurgeOnce sync.Once
 	RpcPurgeOnce   sync.Once
 
@@ -225,10 +284,13 @@ func NewGateway(config config.Config, ctx context.Context) *Gateway {
 		Timeout: 500 * time.Millisecond,
 	}
 	gw.ConnectionWatcher = httputil.NewConnectionWatcher()
+	gw.RequestAnomalies = &httputil.AnomalyCounters{}
+	gw.connectionMetrics = newConnectionMetrics()
 
 	gw.SessionCache = cache.New(10, 5)
 	gw.ExpiryCache = cache.New(600, 10*60)
 	gw.UtilCache = cache.New(3600, 10*60)
+	gw.QuotaPoolCache = cache.New(10, 60)
 
 	var timeout = int64(config.ServiceDiscovery.DefaultCacheTimeout)
 	if timeout <= 0 {
@@ -244,11 +306,13 @@ func NewGateway(config config.Config, ctx context.Context) *Gateway {
 
 	// reload
 	gw.reloadQueue = make(chan func())
+	gw.processReloads = make(chan struct{}, 1)
 	// only for tests
 	gw.ReloadTestCase = NewReloadMachinery()
 	gw.TestBundles = map[string]map[string]string{}
 
 	gw.StorageConnectionHandler = storage.NewConnectionHandler(ctx)
+	gw.StorageConnectionHandler.ObserveCommands(observeStorageCommand)
 
 	gw.SetNodeID("solo-" + uuid.New())
 	gw.SessionID = uuid.New()
@@ -331,6 +395,17 @@ func (gw *Gateway) setupGlobals() {
 	gw.SetConfig(gwConfig)
 	gw.dnsCacheManager = dnscache.NewDnsCacheManager(gwConfig.DnsCache.MultipleIPsHandleStrategy)
 
+	gw.dnsResolver = nil
+	if gwConfig.DNSResolver.Enabled {
+		resolver, err := dnscache.NewResolver(gwConfig.DNSResolver)
+		if err != nil {
+			mainLog.WithError(err).Error("Could not configure the DNS resolver, using the host resolver")
+		} else {
+			gw.dnsResolver = resolver
+		}
+	}
+	gw.dnsCacheManager.SetResolver(gw.dnsResolver)
+
 	if gwConfig.DnsCache.Enabled {
 		gw.dnsCacheManager.InitDNSCaching(
 			time.Duration(gwConfig.DnsCache.TTL)*time.Second,
@@ -364,6 +439,8 @@ func (gw *Gateway) setupGlobals() {
 		mainLog.WithError(err).Error("Could not set version in versionStore")
 	}
 
+	gw.initStorageBudgets()
+
 	if gwConfig.EnableAnalytics && gw.Analytics.Store == nil {
 		Conf := gwConfig
 		Conf.LoadIgnoredIPs()
@@ -372,23 +449,54 @@ func (gw *Gateway) setupGlobals() {
 
 		analyticsStore := storage.RedisCluster{KeyPrefix: "analytics-", IsAnalytics: true, ConnectionHandler: gw.StorageConnectionHandler}
 		gw.Analytics.Store = &analyticsStore
+		gw.Analytics.ShardsStore = &analyticsStore
 		gw.Analytics.Init()
 
+		if gw.analyticsBudget != nil {
+			go gw.analyticsBudgetLoop(gw.ctx)
+		}
+
 		store := storage.RedisCluster{KeyPrefix: "analytics-", IsAnalytics: true, ConnectionHandler: gw.StorageConnectionHandler}
 		redisPurger := RedisPurger{Store: &store, Gw: gw}
 		go redisPurger.PurgeLoop(gw.ctx)
 
-		if gw.GetConfig().AnalyticsConfig.Type == "rpc" {
+		if gw.analyticsPurgerEnabled() {
 			if gw.GetConfig().AnalyticsConfig.SerializerType == serializer.PROTOBUF_SERIALIZER {
-				mainLog.Error("Protobuf analytics serialization is not supported with rpc analytics.")
+				mainLog.Error("Protobuf analytics serialization is not supported with the analytics purger.")
+			} else if backend, err := gw.newAnalyticsPurgerBackend(); err != nil {
+				mainLog.WithError(err).Error("Failed to set up the analytics purger")
 			} else {
-				mainLog.Debug("Using RPC cache purge")
+				mainLog.Debug("Using analytics purger")
 
 				store := storage.RedisCluster{KeyPrefix: "analytics-", IsAnalytics: true, ConnectionHandler: gw.StorageConnectionHandler}
 				purger := rpc.Purger{
-					Store: &store,
+					Store:          &store,
+					Aggregate:      gw.GetConfig().AnalyticsConfig.RPCAggregation.Enabled,
+					ShipRawRecords: gw.GetConfig().AnalyticsConfig.RPCAggregation.ShipRawRecords,
+					Shards: func() int {
+						return discoverAnalyticsKeyShards(&store, gw.GetConfig().AnalyticsConfig)
+					},
+					MaxBatchRecords: gw.GetConfig().AnalyticsConfig.PurgeBatch.MaxRecords,
+					MaxBatchBytes:   gw.GetConfig().AnalyticsConfig.PurgeBatch.MaxBytes,
+					Requeue:         !gw.GetConfig().AnalyticsConfig.PurgeBatch.DisableRequeue,
+					Protobuf:        gw.GetConfig().AnalyticsConfig.PurgeSerializerType == serializer.PROTOBUF_SERIALIZER,
+					Concurrency:     gw.GetConfig().AnalyticsConfig.PurgeConcurrency,
+					Backend:         backend,
+					Stats:           rpc.NewPurgerStats(),
+				}
+				gw.analyticsPurgerStats.Store(purger.Stats)
+
+				if spillConf := gw.GetConfig().AnalyticsConfig.PurgeSpill; spillConf.Path != "" {
+					spill, err := rpc.NewDiskSpill(spillConf.Path, spillConf.MaxBytes, time.Duration(spillConf.TTL)*time.Second)
+					if err != nil {
+						mainLog.WithError(err).Error("Failed to set up the analytics spill")
+					}
+					purger.Spill = spill
+				}
+
+				if _, ok := backend.(rpc.RPCBackend); ok {
+					purger.Connect()
 				}
-				purger.Connect()
 				go purger.PurgeLoop(gw.ctx, time.Duration(gw.GetConfig().AnalyticsConfig.PurgeInterval))
 			}
 
@@ -509,6 +617,7 @@ func (gw *Gateway) syncAPISpecs() (int, error) {
 	for _, v := range s {
 		if err := v.Validate(gw.GetConfig().OAS); err != nil {
 			mainLog.WithError(err).WithField("spec", v.Name).Error("Skipping loading spec because it failed validation")
+			gw.recordLoadFailure(v.mergedAPI(), loadStageValidation, err)
 			continue
 		}
 		filter = append(filter, v)
@@ -560,6 +669,13 @@ func (gw *Gateway) syncPolicies() (count int, err error) {
 			pols, err = LoadPoliciesFromFile(gw.GetConfig().Policies.PolicyRecordName)
 		}
 	}
+	for id, pol := range pols {
+		if err := policy.ValidateDynamicLimits(pol); err != nil {
+			mainLog.WithError(err).WithField("policy_id", id).Error("Skipping loading policy because its dynamic limits are invalid")
+			delete(pols, id)
+		}
+	}
+
 	mainLog.Infof("Policies found (%d total):", len(pols))
 	for id := range pols {
 		mainLog.Debugf(" - %s", id)
@@ -569,6 +685,7 @@ func (gw *Gateway) syncPolicies() (count int, err error) {
 	defer gw.policiesMu.Unlock()
 	if len(pols) > 0 {
 		gw.policiesByID = pols
+		gw.invalidateFingerprint()
 	}
 
 	return len(pols), err
@@ -623,9 +740,21 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 
 	muxer.HandleFunc("/"+gw.GetConfig().HealthCheckEndpointName, gw.liveCheckHandler)
 
+	if gw.GetConfig().Security.SigningKeys.EnableJWKS {
+		muxer.HandleFunc(jwksPath, gw.jwksHandler)
+	}
+
+	if gw.GetConfig().Security.SecretScanning.Enabled {
+		muxer.HandleFunc(secretScanningPath, gw.secretScanningHandler)
+	}
+
+	if gw.GetConfig().DeveloperCatalogue.Enabled {
+		muxer.HandleFunc(gw.cataloguePath(), gw.catalogueHandler).Methods(http.MethodGet)
+	}
+
 	r := mux.NewRouter()
 	muxer.PathPrefix("/tyk/").Handler(http.StripPrefix("/tyk",
-		stripSlashes(gw.checkIsAPIOwner(gw.controlAPICheckClientCertificate("/gateway/client", InstrumentationMW(r)))),
+		stripSlashes(gw.checkIsAPIOwner(gw.checkPolicyBundle(gw.controlAPICheckClientCertificate("/gateway/client", InstrumentationMW(r))))),
 	))
 
 	if hostname != "" {
@@ -645,15 +774,19 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 	// set up main API handlers
 	r.HandleFunc("/reload/group", gw.groupResetHandler).Methods("GET")
 	r.HandleFunc("/reload", gw.resetHandler(nil)).Methods("GET")
+	r.HandleFunc("/config/backups", gw.configBackupsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/config/backups/{name}/restore", gw.configBackupRestoreHandler).Methods(http.MethodPost)
 
 	if !gw.isRPCMode() {
 		versionsHandler := NewVersionHandler(gw.getAPIDefinition)
 		r.HandleFunc("/org/keys", gw.orgHandler).Methods("GET")
 		r.HandleFunc("/org/keys/{keyName:[^/]*}", gw.orgHandler).Methods("POST", "PUT", "GET", "DELETE")
+		r.HandleFunc("/org/keys/{keyName}/quota-reset-schedule", gw.orgQuotaResetHandler).Methods(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete)
 		r.HandleFunc("/keys/policy/{keyName}", gw.policyUpdateHandler).Methods("POST")
 		r.HandleFunc("/keys/create", gw.createKeyHandler).Methods("POST")
 		r.HandleFunc("/apis", gw.apiHandler).Methods(http.MethodGet)
 		r.HandleFunc("/apis", gw.blockInDashboardMode(gw.apiHandler)).Methods(http.MethodPost)
+		r.HandleFunc("/apis/export", gw.apiExportHandler).Methods(http.MethodGet)
 		r.HandleFunc("/apis/oas", gw.apiOASGetHandler).Methods(http.MethodGet)
 		r.HandleFunc("/apis/oas", gw.blockInDashboardMode(gw.validateOAS(gw.apiOASPostHandler))).Methods(http.MethodPost)
 		r.HandleFunc("/apis/{apiID}", gw.apiHandler).Methods(http.MethodGet)
@@ -661,6 +794,14 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 		r.HandleFunc("/apis/{apiID}", gw.blockInDashboardMode(gw.apiHandler)).Methods(http.MethodPut)
 		r.HandleFunc("/apis/{apiID}", gw.apiHandler).Methods(http.MethodDelete)
 		r.HandleFunc("/apis/{apiID}/versions", versionsHandler.ServeHTTP).Methods(http.MethodGet)
+		r.HandleFunc("/apis/{apiID}/history", gw.definitionHistoryHandler(apiHistory, "apiID")).Methods(http.MethodGet)
+		r.HandleFunc("/apis/{apiID}/history/diff", gw.definitionDiffHandler(apiHistory, "apiID")).Methods(http.MethodGet)
+		r.HandleFunc("/apis/{apiID}/history/{revision:[0-9]+}", gw.definitionHistoryHandler(apiHistory, "apiID")).Methods(http.MethodGet)
+		r.HandleFunc("/apis/{apiID}/scheduled", gw.scheduledAPIChangeHandler).Methods(http.MethodGet, http.MethodDelete)
+		r.HandleFunc("/apis/{apiID}/slots/staged", gw.apiStagedSlotHandler).Methods(http.MethodGet, http.MethodDelete)
+		r.HandleFunc("/apis/{apiID}/slots/staged", gw.blockInDashboardMode(gw.apiStagedSlotHandler)).Methods(http.MethodPut)
+		r.HandleFunc("/apis/{apiID}/slots/swap", gw.blockInDashboardMode(gw.apiSlotSwapHandler)).Methods(http.MethodPost)
+		r.HandleFunc("/apis/{apiID}/slots/rollback", gw.blockInDashboardMode(gw.apiSlotRollbackHandler)).Methods(http.MethodPost)
 		r.HandleFunc("/apis/oas/export", gw.apiOASExportHandler).Methods("GET")
 		r.HandleFunc("/apis/oas/import", gw.blockInDashboardMode(gw.validateOAS(gw.makeImportedOASTykAPI(gw.apiOASPostHandler)))).Methods(http.MethodPost)
 		r.HandleFunc("/apis/oas/{apiID}", gw.apiOASGetHandler).Methods(http.MethodGet)
@@ -672,7 +813,21 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 		r.HandleFunc("/health", gw.healthCheckhandler).Methods("GET")
 		r.HandleFunc("/policies", gw.polHandler).Methods("GET", "POST", "PUT", "DELETE")
 		r.HandleFunc("/policies/{polID}", gw.polHandler).Methods("GET", "POST", "PUT", "DELETE")
+		r.HandleFunc("/policies/{polID}/history", gw.definitionHistoryHandler(policyHistory, "polID")).Methods(http.MethodGet)
+		r.HandleFunc("/policies/{polID}/history/diff", gw.definitionDiffHandler(policyHistory, "polID")).Methods(http.MethodGet)
+		r.HandleFunc("/policies/{polID}/history/{revision:[0-9]+}", gw.definitionHistoryHandler(policyHistory, "polID")).Methods(http.MethodGet)
+		r.HandleFunc("/templates", gw.templatesHandler).Methods(http.MethodGet, http.MethodPost)
+		r.HandleFunc("/templates/{name}", gw.templatesHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+		r.HandleFunc("/templates/{name}/instantiate", gw.templateInstantiateHandler).Methods(http.MethodPost)
+		r.HandleFunc("/quota-pools", gw.quotaPoolsHandler).Methods(http.MethodGet, http.MethodPost)
+		r.HandleFunc("/quota-pools/{poolID}", gw.quotaPoolsHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+		r.HandleFunc("/quota-pools/{poolID}/usage", gw.quotaPoolUsageHandler).Methods(http.MethodGet)
+		r.HandleFunc("/rate-limits/export", gw.rateLimitStateExportHandler).Methods(http.MethodGet)
+		r.HandleFunc("/rate-limits/import", gw.rateLimitStateImportHandler).Methods(http.MethodPost)
+		r.HandleFunc("/key-space/snapshots", gw.keySpaceSnapshotsHandler).Methods(http.MethodGet, http.MethodPost)
+		r.HandleFunc("/key-space/snapshots/{name}/restore", gw.keySpaceSnapshotRestoreHandler).Methods(http.MethodPost)
 		r.HandleFunc("/oauth/clients/create", gw.createOauthClient).Methods("POST")
+		r.HandleFunc("/oauth/clients/{apiID}/import", gw.oAuthClientsImportHandler).Methods(http.MethodPost)
 		r.HandleFunc("/oauth/clients/{apiID}/{keyName:[^/]*}", gw.oAuthClientHandler).Methods("PUT")
 		r.HandleFunc("/oauth/clients/{apiID}/{keyName:[^/]*}/rotate", gw.rotateOauthClientHandler).Methods("PUT")
 		r.HandleFunc("/oauth/clients/apis/{appID}", gw.getApisForOauthApp).Queries("orgID", "{[0-9]*?}").Methods("GET")
@@ -684,16 +839,40 @@ func (gw *Gateway) loadControlAPIEndpoints(muxer *mux.Router) {
 		mainLog.Info("Node is slaved, REST API minimised")
 	}
 
+	r.HandleFunc("/fingerprint", gw.fingerprintHandler).Methods(http.MethodGet)
 	r.HandleFunc("/debug", gw.traceHandler).Methods("POST")
+	if gw.GetConfig().EnableConnectionMetrics {
+		r.HandleFunc("/debug/connections", gw.connectionMetricsHandler).Methods(http.MethodGet)
+	}
+	r.HandleFunc("/debug/cache-warmup", gw.cacheWarmUpHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debug/rewrite", gw.urlRewriteTestHandler).Methods(http.MethodPost)
+	r.HandleFunc("/debug/domains", gw.domainsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debug/load-failures", gw.loadFailuresHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debug/load-failures/{id}", gw.loadFailureHandler).Methods(http.MethodGet)
+	if gw.GetConfig().StorageInstrumentation.Enabled {
+		r.HandleFunc("/debug/storage", gw.storageInstrumentationHandler).Methods(http.MethodGet)
+	}
+	r.HandleFunc("/debug/analytics-purger", gw.analyticsPurgerStatsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debug/drl", gw.drlDebugHandler).Methods(http.MethodGet)
+	r.HandleFunc("/debug/simulate", gw.limitSimulationHandler).Methods(http.MethodPost)
 	r.HandleFunc("/cache/{apiID}", gw.invalidateCacheHandler).Methods("DELETE")
+	r.HandleFunc("/faults/{apiID}", gw.faultInjectionHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
 	r.HandleFunc("/keys", gw.keyHandler).Methods("POST", "PUT", "GET", "DELETE")
 	r.HandleFunc("/keys/preview", gw.previewKeyHandler).Methods("POST")
 	r.HandleFunc("/keys/{keyName:[^/]*}", gw.keyHandler).Methods("POST", "PUT", "GET", "DELETE")
+	r.HandleFunc("/keys/{keyName:[^/]*}/metering", gw.meteringHandler).Methods(http.MethodGet)
+	r.HandleFunc("/storage-gc", gw.storageGCHandler).Methods(http.MethodGet)
+	r.HandleFunc("/storage-gc/cleanup", gw.storageGCCleanupHandler).Methods(http.MethodPost)
+	r.HandleFunc("/trash", gw.trashHandler).Methods(http.MethodGet, http.MethodDelete)
+	r.HandleFunc("/trash/{kind:api|key}/{id:[^/]*}", gw.trashItemHandler).Methods(http.MethodGet, http.MethodDelete)
+	r.HandleFunc("/trash/{kind:api|key}/{id:[^/]*}/restore", gw.trashRestoreHandler).Methods(http.MethodPost)
 	r.HandleFunc("/certs", gw.certHandler).Methods("POST", "GET")
 	r.HandleFunc("/certs/{certID:[^/]*}", gw.certHandler).Methods("POST", "GET", "DELETE")
 	r.HandleFunc("/oauth/clients/{apiID}", gw.oAuthClientHandler).Methods("GET", "DELETE")
+	r.HandleFunc("/oauth/clients/{apiID}/export", gw.oAuthClientsExportHandler).Methods(http.MethodGet)
 	r.HandleFunc("/oauth/clients/{apiID}/{keyName:[^/]*}", gw.oAuthClientHandler).Methods("GET", "DELETE")
 	r.HandleFunc("/oauth/clients/{apiID}/{keyName}/tokens", gw.oAuthClientTokensHandler).Methods("GET")
+	r.HandleFunc("/oauth/clients/{apiID}/{keyName}/tokens", gw.revokeOauthClientTokensHandler).Methods(http.MethodDelete)
 	r.HandleFunc("/oauth/tokens", gw.oAuthTokensHandler).Methods(http.MethodDelete)
 
 	r.HandleFunc("/schema", gw.schemaHandler).Methods(http.MethodGet)
@@ -890,7 +1069,13 @@ func (gw *Gateway) createResponseMiddlewareChain(spec *APISpec, responseFuncs []
 		responseMWChain []TykResponseHandler
 		baseHandler     = BaseTykResponseHandler{Spec: spec, Gw: gw}
 	)
+	gw.responseMWAppendEnabled(&responseMWChain, &ResponseStatusMappingMiddleware{BaseTykResponseHandler: baseHandler})
+	gw.responseMWAppendEnabled(&responseMWChain, &ResponseTokenizationMiddleware{BaseTykResponseHandler: baseHandler})
+	gw.responseMWAppendEnabled(&responseMWChain, &ResponseFieldFilterMiddleware{BaseTykResponseHandler: baseHandler})
 	gw.responseMWAppendEnabled(&responseMWChain, &ResponseTransformMiddleware{BaseTykResponseHandler: baseHandler})
+	gw.responseMWAppendEnabled(&responseMWChain, &ResponseMeteringMiddleware{BaseTykResponseHandler: baseHandler})
+	gw.responseMWAppendEnabled(&responseMWChain, &ResponseRateLimitHeadersMiddleware{BaseTykResponseHandler: baseHandler})
+	gw.responseMWAppendEnabled(&responseMWChain, &ResponseDebugHeadersMiddleware{BaseTykResponseHandler: baseHandler})
 
 	headerInjector := &HeaderInjector{BaseTykResponseHandler: baseHandler}
 	headerInjectorAdded := gw.responseMWAppendEnabled(&responseMWChain, headerInjector)
@@ -1001,6 +1186,7 @@ func (gw *Gateway) DoReload() {
 	}
 
 	gw.loadGlobalApps()
+	gw.apisReloaded.Store(true)
 
 	mainLog.Info("API reload complete")
 }
@@ -1383,6 +1569,11 @@ var readPIDFromFile = func(file string) (int, error) {
 func (gw *Gateway) afterConfSetup() {
 	conf := gw.GetConfig()
 
+	if conf.AirGapped.Enabled && conf.SlaveOptions.UseRPC {
+		mainLog.Warning("Air-gapped mode enabled, disabling the RPC connection")
+		conf.SlaveOptions.UseRPC = false
+	}
+
 	if conf.SlaveOptions.UseRPC {
 		if conf.SlaveOptions.GroupID == "" {
 			conf.SlaveOptions.GroupID = "ungrouped"
@@ -1643,7 +1834,7 @@ func Start() {
 	}
 
 	gw.setupPortsWhitelist()
-	gw.keyGen = DefaultKeyGenerator{Gw: gw}
+	gw.keyGen = gw.newKeyGenerator()
 
 	onFork := func() {
 		mainLog.Warning("PREPARING TO FORK")
@@ -1691,6 +1882,12 @@ func Start() {
 		gw.reloadURLStructure(func() {})
 	}, &configs)
 
+	if configs.StartupDependencies.Enabled {
+		if err := gw.waitForStartupDependencies(gw.ctx, gw.startupDependencies()); err != nil {
+			mainLog.WithError(err).Fatal("Startup dependencies aren't ready")
+		}
+	}
+
 	unix := time.Now().Unix()
 
 	var (
@@ -1819,6 +2016,7 @@ func (gw *Gateway) start() {
 	if !conf.SuppressRedisSignalReload {
 		go gw.startPubSubLoop()
 	}
+	go gw.handleProcessReloads(gw.ctx)
 
 	purgeInterval := conf.Private.GetOAuthTokensPurgeInterval()
 	purgeJob := scheduler.NewJob("purge-oauth-tokens", gw.purgeLapsedOAuthTokens, purgeInterval)
@@ -1826,6 +2024,67 @@ func (gw *Gateway) start() {
 	oauthTokensPurger := scheduler.NewScheduler(log)
 	go oauthTokensPurger.Start(gw.ctx, purgeJob)
 
+	if !conf.UseDBAppConfigs {
+		scheduledChangesJob := scheduler.NewJob("activate-scheduled-api-changes", gw.activateScheduledAPIChanges, scheduledAPIChangesInterval)
+
+		scheduledChangesActivator := scheduler.NewScheduler(log)
+		go scheduledChangesActivator.Start(gw.ctx, scheduledChangesJob)
+	}
+
+	if conf.AirGapped.Enabled {
+		importInterval := time.Duration(conf.AirGapped.ImportInterval) * time.Second
+		if importInterval <= 0 {
+			importInterval = defaultAirGappedImportInterval * time.Second
+		}
+		importJob := scheduler.NewJob("import-air-gapped-keys", gw.importAirGappedKeys, importInterval)
+
+		airGappedImporter := scheduler.NewScheduler(log)
+		go airGappedImporter.Start(gw.ctx, importJob)
+	}
+
+	if conf.StorageGC.Enabled {
+		gcInterval := time.Duration(conf.StorageGC.Interval) * time.Second
+		if gcInterval <= 0 {
+			gcInterval = defaultStorageGCInterval * time.Second
+		}
+		storageGCJob := scheduler.NewJob("report-orphaned-storage", gw.runStorageGC, gcInterval)
+
+		storageGCReporter := scheduler.NewScheduler(log)
+		go storageGCReporter.Start(gw.ctx, storageGCJob)
+	}
+
+	if conf.KeySpaceSnapshots.Enabled {
+		snapshotJob := scheduler.NewJob("export-key-space-snapshot", gw.runKeySpaceSnapshot, gw.keySpaceSnapshotInterval())
+
+		keySpaceSnapshotter := scheduler.NewScheduler(log)
+		go keySpaceSnapshotter.Start(gw.ctx, snapshotJob)
+	}
+
+	if !gw.isRPCMode() {
+		orgQuotaResetJob := scheduler.NewJob("reset-org-quotas", gw.runOrgQuotaResets, orgQuotaResetInterval)
+
+		orgQuotaResetter := scheduler.NewScheduler(log)
+		go orgQuotaResetter.Start(gw.ctx, orgQuotaResetJob)
+	}
+
+	if reloadInterval := conf.HttpServerOptions.CertificateReloadInterval; conf.HttpServerOptions.UseSSL && reloadInterval > 0 {
+		certReloadJob := scheduler.NewJob("reload-server-certificates", gw.reloadServerCertificates, time.Duration(reloadInterval)*time.Second)
+
+		certReloader := scheduler.NewScheduler(log)
+		go certReloader.Start(gw.ctx, certReloadJob)
+	}
+
+	if conf.PolicyBundle.Enabled {
+		pollInterval := time.Duration(conf.PolicyBundle.PollInterval) * time.Second
+		if pollInterval <= 0 {
+			pollInterval = defaultPolicyBundlePollInterval * time.Second
+		}
+		policyBundleJob := scheduler.NewJob("load-policy-bundle", gw.loadPolicyBundle, pollInterval)
+
+		policyBundleLoader := scheduler.NewScheduler(log)
+		go policyBundleLoader.Start(gw.ctx, policyBundleJob)
+	}
+
 	if slaveOptions := conf.SlaveOptions; slaveOptions.UseRPC {
 		mainLog.Debug("Starting RPC reload listener")
 		gw.RPCListener = RPCStorageHandler{
@@ -1889,6 +2148,8 @@ func (gw *Gateway) startDRL() {
 	gw.drlOnce.Do(func() {
 		drlManager := &drl.DRL{}
 		gw.SessionLimiter = NewSessionLimiter(gw.ctx, &gwConfig, drlManager)
+		gw.SessionLimiter.drlServers = &gw.drlServers
+		gw.SessionLimiter.quotaPools = gw.QuotaPoolCache
 
 		gw.DRLManager = drlManager
 
@@ -1903,6 +2164,20 @@ func (gw *Gateway) startDRL() {
 		drlManager.ThisServerID = nodeID
 		drlManager.Init(gw.ctx)
 
+		ttl, err := drlServerEvictTTL(gwConfig.DRLServerTTL)
+		if err != nil {
+			mainLog.Fatal(err)
+		}
+
+		if ttl > 0 {
+			gw.drlServers.setEvictTTL(ttl)
+
+			evictionJob := scheduler.NewJob("evict-stale-drl-servers", gw.evictStaleDRLServers, time.Second)
+
+			drlServerEvictor := scheduler.NewScheduler(log)
+			go drlServerEvictor.Start(gw.ctx, evictionJob)
+		}
+
 		log.Debug("DRL: Setting node ID: ", nodeID)
 
 		gw.startRateLimitNotifications()
@@ -1974,4 +2249,5 @@ func (gw *Gateway) SetConfig(conf config.Config, skipReload ...bool) {
 	gw.configMu.Lock()
 	gw.config.Store(conf)
 	gw.configMu.Unlock()
+	gw.invalidateFingerprint()
 }
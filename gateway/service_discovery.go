@@ -2,9 +2,11 @@ package gateway
 
 import (
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Jeffail/gabs/v2"
 
@@ -23,6 +25,26 @@ type ServiceDiscovery struct {
 	parentPath          string
 	portPath            string
 	targetPath          string
+	// client queries the service discovery endpoint, http.DefaultClient when nil.
+	client *http.Client
+}
+
+// serviceDiscoveryClient returns the client querying the service discovery endpoints, resolving
+// their host names with the configured DNS resolver.
+func (gw *Gateway) serviceDiscoveryClient() *http.Client {
+	if gw.dnsResolver == nil {
+		return http.DefaultClient
+	}
+
+	// the client isn't reused across the refreshes, so it doesn't keep the connections alive
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	transport.DialContext = gw.dnsResolver.WrapDialer(&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	})
+
+	return &http.Client{Transport: transport}
 }
 
 func (s *ServiceDiscovery) Init(spec *apidef.ServiceDiscoveryConfiguration) {
@@ -46,7 +68,12 @@ func (s *ServiceDiscovery) Init(spec *apidef.ServiceDiscoveryConfiguration) {
 
 func (s *ServiceDiscovery) getServiceData(name string) (string, error) {
 	log.Debug("Getting ", name)
-	resp, err := http.Get(name)
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(name)
 	if err != nil {
 		return "", err
 	}
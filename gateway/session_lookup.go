@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/internal/cache"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	defaultExternalSessionLookupTimeout     = 5
+	defaultExternalSessionLookupCacheTTL    = 3600
+	defaultExternalSessionLookupNegativeTTL = 60
+)
+
+// externalSessionMisses caches the keys unknown to the external identity store, so that
+// requests with invalid keys don't hit it on every request.
+var externalSessionMisses = cache.New(defaultExternalSessionLookupNegativeTTL, 60)
+
+// externalSessionLookupRequest is the body of the requests to the external identity store.
+type externalSessionLookupRequest struct {
+	Key   string `json:"key"`
+	APIID string `json:"api_id"`
+	OrgID string `json:"org_id"`
+}
+
+// externalSessionLookupClient is the HTTP client of the external identity store, reused by
+// the lookups until the timeout of the configuration changes.
+type externalSessionLookupClient struct {
+	timeout time.Duration
+	client  *http.Client
+}
+
+// externalSessionClient returns the HTTP client of the external identity store.
+func (gw *Gateway) externalSessionClient(timeout time.Duration) *http.Client {
+	if c := gw.externalSessionLookupClient.Load(); c != nil && c.timeout == timeout {
+		return c.client
+	}
+
+	c := &externalSessionLookupClient{
+		timeout: timeout,
+		client:  &http.Client{Timeout: timeout, Transport: http.DefaultTransport.(*http.Transport).Clone()},
+	}
+	gw.externalSessionLookupClient.Store(c)
+
+	return c.client
+}
+
+// lookupExternalSession queries the external identity store for the session of a key not
+// found in the key store. Found sessions are stored in the key store for the configured TTL,
+// so that the following requests are served locally.
+func (t *BaseMiddleware) lookupExternalSession(key string) (user.SessionState, bool) {
+	conf := t.Gw.GetConfig().ExternalSessionLookup
+	if !conf.Enabled || conf.URL == "" {
+		return user.SessionState{}, false
+	}
+
+	missKey := storage.HashStr(t.Spec.OrgID+key, storage.HashMurmur64)
+	if _, miss := externalSessionMisses.Get(missKey); miss {
+		return user.SessionState{}, false
+	}
+
+	session, found, err := t.Gw.fetchExternalSession(key, t.Spec.APIID, t.Spec.OrgID)
+	if err != nil {
+		// errors aren't cached, the identity store may recover on the next request
+		t.Logger().WithError(err).Error("Couldn't look up the key in the external identity store")
+		return user.SessionState{}, false
+	}
+
+	if !found {
+		ttl := conf.NegativeCacheTTL
+		if ttl <= 0 {
+			ttl = defaultExternalSessionLookupNegativeTTL
+		}
+		externalSessionMisses.Set(missKey, true, ttl)
+		return user.SessionState{}, false
+	}
+
+	if session.OrgID == "" {
+		session.OrgID = t.Spec.OrgID
+	}
+	session.KeyID = key
+
+	ttl := conf.CacheTTL
+	if ttl <= 0 {
+		ttl = defaultExternalSessionLookupCacheTTL
+	}
+
+	if err := t.Gw.GlobalSessionManager.UpdateSession(key, session, ttl, false); err != nil {
+		t.Logger().WithError(err).Error("Couldn't store the session of the external identity store")
+	}
+
+	t.Logger().Info("Provisioned key from the external identity store: ", t.Gw.obfuscateKey(key))
+
+	return *session, true
+}
+
+// fetchExternalSession requests the session of a key from the external identity store. It
+// returns false when the identity store doesn't know the key.
+func (gw *Gateway) fetchExternalSession(key, apiID, orgID string) (*user.SessionState, bool, error) {
+	conf := gw.GetConfig().ExternalSessionLookup
+
+	body, err := json.Marshal(externalSessionLookupRequest{Key: key, APIID: apiID, OrgID: orgID})
+	if err != nil {
+		return nil, false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set(header.ContentType, header.ApplicationJSON)
+	for name, value := range conf.Headers {
+		req.Header.Set(name, value)
+	}
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultExternalSessionLookupTimeout
+	}
+
+	resp, err := gw.externalSessionClient(time.Duration(timeout * float64(time.Second))).Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	session := user.NewSessionState()
+	if err := json.NewDecoder(resp.Body).Decode(session); err != nil {
+		return nil, false, err
+	}
+
+	return session, true, nil
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestExternalSessionLookup(t *testing.T) {
+	var lookups int32
+
+	identityStore := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		assert.Equal(t, "secret", r.Header.Get("X-Auth"))
+
+		var req externalSessionLookupRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "external-api", req.APIID)
+
+		if req.Key != "external-key-1234" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		session := user.SessionState{
+			Rate: 100,
+			Per:  1,
+			AccessRights: map[string]user.AccessDefinition{
+				"external-api": {APIID: "external-api", Versions: []string{"Default"}},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(session)
+	}))
+	defer identityStore.Close()
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.ExternalSessionLookup = config.ExternalSessionLookupConfig{
+			Enabled: true,
+			URL:     identityStore.URL,
+			Headers: map[string]string{"X-Auth": "secret"},
+		}
+	})
+	defer ts.Close()
+	externalSessionMisses.Flush()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "external-api"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/external/"
+	})
+
+	known := map[string]string{header.Authorization: "external-key-1234"}
+	unknown := map[string]string{header.Authorization: "unknown-key-1234"}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/external/", Headers: known, Code: http.StatusOK},
+		{Path: "/external/", Headers: known, Code: http.StatusOK},
+	}...)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups), "found sessions should be stored")
+
+	session, found := ts.Gw.GlobalSessionManager.SessionDetail("", "external-key-1234", false)
+	require.True(t, found)
+	assert.Equal(t, float64(100), session.Rate)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/external/", Headers: unknown, Code: http.StatusForbidden},
+		{Path: "/external/", Headers: unknown, Code: http.StatusForbidden},
+	}...)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lookups), "unknown keys should be cached")
+}
+
+func TestExternalSessionClient(t *testing.T) {
+	gw := &Gateway{}
+
+	client := gw.externalSessionClient(time.Second)
+	assert.Same(t, client, gw.externalSessionClient(time.Second), "the client should be reused")
+
+	other := gw.externalSessionClient(2 * time.Second)
+	assert.NotSame(t, client, other, "the client should be rebuilt for a new timeout")
+	assert.Equal(t, 2*time.Second, other.Timeout)
+}
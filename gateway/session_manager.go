@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/TykTechnologies/leakybucket/memorycache"
 
 	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/cache"
 	"github.com/TykTechnologies/tyk/internal/httputil"
 	"github.com/TykTechnologies/tyk/internal/rate"
 	"github.com/TykTechnologies/tyk/internal/rate/limiter"
@@ -56,6 +58,10 @@ type SessionLimiter struct {
 	bucketStore    leakybucket.Storage
 	limiterStorage redis.UniversalClient
 	smoothing      *rate.Smoothing
+	// drlServers counts the servers the DRL rate is shared between, when they're evicted.
+	drlServers *drlServerRegistry
+	// quotaPools caches the quota pool definitions, when set.
+	quotaPools cache.Repository
 }
 
 // NewSessionLimiter initializes the session limiter.
@@ -74,6 +80,9 @@ func NewSessionLimiter(ctx context.Context, conf *config.Config, drlManager *drl
 	}
 
 	log.Infof("[RATELIMIT] %s", conf.RateLimit.String())
+	if conf.RateLimiter != "" && !rate.IsLimiter(conf.RateLimiter) {
+		log.Errorf("[RATELIMIT] Unknown rate limiter %q, the rate limiter flags apply instead", conf.RateLimiter)
+	}
 
 	storageConf := conf.GetRateLimiterStorage()
 
@@ -91,7 +100,9 @@ func (l *SessionLimiter) Context() context.Context {
 	return l.ctx
 }
 
-func (l *SessionLimiter) doRollingWindowWrite(r *http.Request, session *user.SessionState, rateLimiterKey string, apiLimit *user.APILimit, dryRun bool) bool {
+// doRollingWindowWrite counts the request in the sliding log of the rate limiter key. With
+// sentinel set, it sets the sentinel key when the request is over the rate limit.
+func (l *SessionLimiter) doRollingWindowWrite(r *http.Request, session *user.SessionState, rateLimiterKey string, apiLimit *user.APILimit, sentinel, dryRun bool) bool {
 	ctx := l.Context()
 	rateLimiterSentinelKey := rateLimiterKey + SentinelRateLimitKeyPostfix
 
@@ -107,7 +118,7 @@ func (l *SessionLimiter) doRollingWindowWrite(r *http.Request, session *user.Ses
 	smoothingFn := func(_ context.Context, key string, currentRate, maxAllowedRate int64) bool {
 		// Subtract by 1 because of the delayed add in the window
 		var subtractor int64 = 1
-		if l.config.EnableSentinelRateLimiter || l.config.DRLEnableSentinelRateLimiter {
+		if sentinel {
 			// and another subtraction because of the preemptive limit
 			subtractor = 2
 		}
@@ -144,7 +155,7 @@ func (l *SessionLimiter) doRollingWindowWrite(r *http.Request, session *user.Ses
 	shouldBlock, err := ratelimit.Do(ctx, time.Now(), rateLimiterKey, int64(cost), int64(per))
 	if shouldBlock {
 		// Set a sentinel value with expire
-		if l.config.EnableSentinelRateLimiter || l.config.DRLEnableSentinelRateLimiter {
+		if sentinel {
 			if !dryRun {
 				l.limiterStorage.SetNX(ctx, rateLimiterSentinelKey, "1", time.Second*time.Duration(int64(per)))
 			}
@@ -169,7 +180,7 @@ const (
 
 func (l *SessionLimiter) limitSentinel(r *http.Request, session *user.SessionState, rateLimiterKey string, apiLimit *user.APILimit, dryRun bool) bool {
 	defer func() {
-		go l.doRollingWindowWrite(r, session, rateLimiterKey, apiLimit, dryRun)
+		go l.doRollingWindowWrite(r, session, rateLimiterKey, apiLimit, true, dryRun)
 	}()
 
 	// Check sentinel
@@ -180,7 +191,37 @@ func (l *SessionLimiter) limitSentinel(r *http.Request, session *user.SessionSta
 }
 
 func (l *SessionLimiter) limitRedis(r *http.Request, session *user.SessionState, rateLimiterKey string, apiLimit *user.APILimit, dryRun bool) bool {
-	return l.doRollingWindowWrite(r, session, rateLimiterKey, apiLimit, dryRun)
+	sentinel := l.config.EnableSentinelRateLimiter || l.config.DRLEnableSentinelRateLimiter
+	return l.doRollingWindowWrite(r, session, rateLimiterKey, apiLimit, sentinel, dryRun)
+}
+
+// limitDistributed limits the rate with the distributed rate limiter, or with the sliding log
+// when the rate per Gateway is under the DRL threshold.
+func (l *SessionLimiter) limitDistributed(r *http.Request, session *user.SessionState, limiterKey string, apiLimit *user.APILimit, useCustomKey, dryRun bool) bool {
+	var n float64
+	if l.drlManager.Servers != nil {
+		n = float64(l.drlServers.count(l.drlManager))
+	}
+	cost := apiLimit.Rate / apiLimit.Per
+	c := l.config.DRLThreshold
+	if c == 0 {
+		// defaults to 5
+		c = 5
+	}
+
+	if n <= 1 || n*c < cost {
+		// If we have 1 server, there is no need to strain redis at all the leaky
+		// bucket algorithm will suffice.
+
+		bucketKey := limiterKey + ":" + session.LastUpdated
+		if useCustomKey {
+			bucketKey = limiterKey
+		}
+
+		return l.limitDRL(bucketKey, apiLimit, dryRun)
+	}
+
+	return l.limitRedis(r, session, limiterKey, apiLimit, dryRun)
 }
 
 func (l *SessionLimiter) limitDRL(bucketKey string, apiLimit *user.APILimit, dryRun bool) bool {
@@ -301,6 +342,10 @@ func (l *SessionLimiter) ForwardMessage(r *http.Request, session *user.SessionSt
 	// If quotaKey is not set then the default ratelimit keys should be used.
 	useCustomKey := quotaKey != ""
 
+	// The atomic rate limiter checks the Redis rate limit and the quota in one Redis round trip.
+	atomic := l.config.EnableAtomicRateLimiter && l.limiterStorage != nil
+	var atomicLimiterKey string
+
 	// If rate is -1 or 0, it means unlimited and no need for rate limiting.
 	if enableRL && apiLimit.Rate > 0 {
 		log.Debug("[RATELIMIT] Inbound raw key is: ", rateLimitKey)
@@ -315,9 +360,20 @@ func (l *SessionLimiter) ForwardMessage(r *http.Request, session *user.SessionSt
 
 		log.Debug("[RATELIMIT] Rate limiter key is: ", limiterKey)
 
+		if diagnostics := ctxGetDebugDiagnostics(r); diagnostics != nil && !dryRun {
+			diagnostics.addRateLimitBucket(limiterKey)
+		}
+
 		limiter := rate.Limiter(l.config, l.limiterStorage)
+		strategy := l.rateLimiterName(api)
 
 		switch {
+		case strategy == rate.LimitSlidingLog && atomic:
+			atomicLimiterKey = limiterKey
+		case strategy != "":
+			if l.rateLimiterStrategy(strategy, useCustomKey).Limit(r, session, limiterKey, apiLimit, dryRun) {
+				return sessionFailRateLimit
+			}
 		case limiter != nil:
 			err := limiter(r.Context(), limiterKey, apiLimit.Rate, apiLimit.Per)
 
@@ -330,47 +386,44 @@ func (l *SessionLimiter) ForwardMessage(r *http.Request, session *user.SessionSt
 				return sessionFailRateLimit
 			}
 		case l.config.EnableRedisRollingLimiter:
+			if atomic {
+				atomicLimiterKey = limiterKey
+				break
+			}
+
 			if l.limitRedis(r, session, limiterKey, apiLimit, dryRun) {
 				return sessionFailRateLimit
 			}
 		default:
-			var n float64
-			if l.drlManager.Servers != nil {
-				n = float64(l.drlManager.Servers.Count())
-			}
-			cost := apiLimit.Rate / apiLimit.Per
-			c := l.config.DRLThreshold
-			if c == 0 {
-				// defaults to 5
-				c = 5
+			if l.limitDistributed(r, session, limiterKey, apiLimit, useCustomKey, dryRun) {
+				return sessionFailRateLimit
 			}
+		}
+	}
 
-			if n <= 1 || n*c < cost {
-				// If we have 1 server, there is no need to strain redis at all the leaky
-				// bucket algorithm will suffice.
-
-				bucketKey := limiterKey + ":" + session.LastUpdated
-				if useCustomKey {
-					bucketKey = limiterKey
-				}
+	if enableQ && l.config.LegacyEnableAllowanceCountdown {
+		session.Allowance = session.Allowance - 1
+	}
 
-				if l.limitDRL(bucketKey, apiLimit, dryRun) {
-					return sessionFailRateLimit
-				}
-			} else {
-				if l.limitRedis(r, session, limiterKey, apiLimit, dryRun) {
-					return sessionFailRateLimit
-				}
-			}
+	quotaChecked := false
+	if atomic && (atomicLimiterKey != "" || enableQ) {
+		var reason sessionFailReason
+		reason, quotaChecked = l.limitAtomic(r, session, atomicLimiterKey, quotaKey, allowanceScope, apiLimit, enableQ, dryRun)
+		if reason != sessionFailNone {
+			return reason
 		}
 	}
 
 	if enableQ {
-		if l.config.LegacyEnableAllowanceCountdown {
-			session.Allowance = session.Allowance - 1
+		if !quotaChecked && l.RedisQuotaExceeded(r, session, quotaKey, allowanceScope, apiLimit, store, l.config.HashKeys) {
+			return sessionFailQuota
 		}
 
-		if l.RedisQuotaExceeded(r, session, quotaKey, allowanceScope, apiLimit, store, l.config.HashKeys) {
+		if l.QuotaBucketsExceeded(r, session, quotaKey, api, l.config.HashKeys) {
+			return sessionFailQuota
+		}
+
+		if session.QuotaPool != "" && l.QuotaPoolExceeded(session, quotaKey) {
 			return sessionFailQuota
 		}
 	}
@@ -381,41 +434,112 @@ func (l *SessionLimiter) ForwardMessage(r *http.Request, session *user.SessionSt
 
 // RedisQuotaExceeded returns true if the request should be blocked as over quota.
 func (l *SessionLimiter) RedisQuotaExceeded(r *http.Request, session *user.SessionState, quotaKey, scope string, limit *user.APILimit, store storage.Handler, hashKeys bool) bool {
-	logger := log.WithFields(logrus.Fields{
-		"quotaMax":         limit.QuotaMax,
-		"quotaRenewalRate": limit.QuotaRenewalRate,
+	// rawKey is the redis key for quota
+	rawKey := quotaScopeKey(session, quotaKey, scope, hashKeys)
+
+	return l.quotaExceeded(session, rawKey, limit, func(remaining, renews int64) {
+		l.updateSessionQuota(session, scope, remaining, renews)
 	})
+}
 
-	if limit.QuotaMax <= 0 {
+// QuotaBucketsExceeded returns true if the request should be blocked as over the quota of
+// one of the quota buckets of the session it matches.
+func (l *SessionLimiter) QuotaBucketsExceeded(r *http.Request, session *user.SessionState, quotaKey string, api *APISpec, hashKeys bool) bool {
+	for i := range session.QuotaBuckets {
+		bucket := &session.QuotaBuckets[i]
+		if !l.quotaBucketMatches(r, api, bucket) {
+			continue
+		}
+
+		limit := bucket.APILimit()
+		rawKey := quotaBucketPrefix(bucket.Name) + quotaCounterKey(session, quotaKey, hashKeys)
+
+		exceeded := l.quotaExceeded(session, rawKey, &limit, func(remaining, renews int64) {
+			if remaining < 0 {
+				remaining = 0
+			}
+			bucket.QuotaRemaining = remaining
+			bucket.QuotaRenews = renews
+			session.Touch()
+		})
+		if exceeded {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quotaBucketMatches returns true if the request is counted by the quota bucket.
+func (l *SessionLimiter) quotaBucketMatches(r *http.Request, api *APISpec, bucket *user.QuotaBucket) bool {
+	if len(bucket.Methods) > 0 && !slices.ContainsFunc(bucket.Methods, func(method string) bool {
+		return strings.EqualFold(method, r.Method)
+	}) {
 		return false
 	}
 
-	// don't use the requests cancellation context
-	ctx := context.Background()
+	if bucket.Path == "" {
+		return true
+	}
+
+	pattern := httputil.PreparePathRegexp(bucket.Path, l.config.HttpServerOptions.EnablePathPrefixMatching, l.config.HttpServerOptions.EnablePathSuffixMatching)
+	asRegex, err := regexp.Compile(pattern)
+	if err != nil {
+		log.WithError(err).WithField("bucket", bucket.Name).Error("quota bucket: error compiling regex")
+		return false
+	}
+
+	return asRegex.MatchString(api.StripListenPath(r.URL.Path))
+}
 
+// quotaBucketPrefix prefixes the redis keys of the quota counters of a bucket.
+func quotaBucketPrefix(name string) string {
+	return QuotaKeyPrefix + "bucket-" + name + "-"
+}
+
+// quotaScopeKey returns the redis key of the quota counter of the session for the allowance scope.
+func quotaScopeKey(session *user.SessionState, quotaKey, scope string, hashKeys bool) string {
 	quotaScope := ""
 	if scope != "" {
 		quotaScope = scope + "-"
 	}
 
-	key := session.KeyID
-	if hashKeys {
-		key = storage.HashStr(session.KeyID)
-	}
+	return QuotaKeyPrefix + quotaScope + quotaCounterKey(session, quotaKey, hashKeys)
+}
+
+// quotaCounterKey returns the key the quota counters of the session are stored by.
+func quotaCounterKey(session *user.SessionState, quotaKey string, hashKeys bool) string {
 	if quotaKey != "" {
-		key = quotaKey
+		return quotaKey
 	}
 
-	now := time.Now()
+	if hashKeys {
+		return storage.HashStr(session.KeyID)
+	}
 
-	// rawKey is the redis key for quota
-	rawKey := QuotaKeyPrefix + quotaScope + key
+	return session.KeyID
+}
 
-	var quotaRenewalRate time.Duration
-	if limit.QuotaRenewalRate > 0 {
-		quotaRenewalRate = time.Second * time.Duration(limit.QuotaRenewalRate)
+// quotaExceeded counts the request in the quota counter at rawKey, renewing it when
+// expired, and returns true if the request is over the quota of limit. The remaining
+// quota and renewal time are passed to update.
+func (l *SessionLimiter) quotaExceeded(session *user.SessionState, rawKey string, limit *user.APILimit, update func(remaining, renews int64)) bool {
+	logger := log.WithFields(logrus.Fields{
+		"quotaMax":         limit.QuotaMax,
+		"quotaRenewalRate": limit.QuotaRenewalRate,
+	})
+
+	if limit.QuotaMax <= 0 {
+		return false
 	}
 
+	// don't use the requests cancellation context
+	ctx := context.Background()
+
+	now := time.Now()
+
+	quotaMax, quotaRenewalRate, periodEnd, aligned := quotaPeriod(session, limit, now)
+
 	conn := l.limiterStorage
 
 	var expired, exists bool
@@ -434,6 +558,12 @@ func (l *SessionLimiter) RedisQuotaExceeded(r *http.Request, session *user.Sessi
 
 	expiredAt = now.Add(dur)
 
+	if aligned {
+		// counters started before the quota was aligned are renewed at the period end
+		expired = expired || expiredAt.After(periodEnd.Add(time.Second))
+		expiredAt = periodEnd
+	}
+
 	logger = logger.WithFields(logrus.Fields{
 		"exists":  exists,
 		"expired": expired,
@@ -455,8 +585,8 @@ func (l *SessionLimiter) RedisQuotaExceeded(r *http.Request, session *user.Sessi
 		}
 
 		quota := res.Val()
-		blocked := quota-1 >= limit.QuotaMax
-		remaining := limit.QuotaMax - quota
+		blocked := quota-1 >= quotaMax
+		remaining := quotaMax - quota
 		if blocked {
 			remaining = 0
 		}
@@ -466,7 +596,7 @@ func (l *SessionLimiter) RedisQuotaExceeded(r *http.Request, session *user.Sessi
 		logger = logger.WithField("remaining", remaining)
 		logger.Debug("[QUOTA] Update quota key")
 
-		l.updateSessionQuota(session, scope, remaining, expiredAt.Unix())
+		update(remaining, expiredAt.Unix())
 		return blocked
 	}
 
@@ -477,7 +607,7 @@ func (l *SessionLimiter) RedisQuotaExceeded(r *http.Request, session *user.Sessi
 
 	// if key is expired and can't renew, update the counter and
 	// block traffic going forward.
-	if limit.QuotaRenewalRate <= 0 {
+	if quotaRenewalRate <= 0 {
 		return increment()
 	}
 
@@ -502,6 +632,92 @@ func (l *SessionLimiter) RedisQuotaExceeded(r *http.Request, session *user.Sessi
 	return increment()
 }
 
+// quotaPeriod returns the quota max and renewal of the limit at now. Calendar-aligned quotas
+// renew at the end of the current period, and may be prorated in the period the key was
+// created in.
+func quotaPeriod(session *user.SessionState, limit *user.APILimit, now time.Time) (quotaMax int64, renewal time.Duration, periodEnd time.Time, aligned bool) {
+	if limit.QuotaRenewalRate > 0 {
+		renewal = time.Second * time.Duration(limit.QuotaRenewalRate)
+	}
+
+	quotaMax = limit.QuotaMax
+	aligned = limit.QuotaAlignment.Enabled()
+	if aligned {
+		_, periodEnd = limit.QuotaAlignment.Bounds(now)
+		renewal = periodEnd.Sub(now)
+		quotaMax = limit.QuotaAlignment.Quota(limit.QuotaMax, session.DateCreated, now)
+	}
+
+	return quotaMax, renewal, periodEnd, aligned
+}
+
+// limitAtomic checks the Redis rate limit of limiterKey, when set, and counts the request in
+// the quota, when enableQ, with a single Lua script. Dry runs check without counting. It returns false when the quota wasn't
+// checked, the keys of the session falling back to the separate rate limiter and quota.
+func (l *SessionLimiter) limitAtomic(r *http.Request, session *user.SessionState, limiterKey, quotaKey, scope string, apiLimit *user.APILimit, enableQ, dryRun bool) (sessionFailReason, bool) {
+	now := time.Now()
+	rawKey := quotaScopeKey(session, quotaKey, scope, l.config.HashKeys)
+
+	rateKey, err := rate.AtomicKey(rawKey, limiterKey)
+	if err != nil {
+		log.WithError(err).Debug("[RATELIMIT] falling back to the Redis rate limiter")
+		if limiterKey != "" && l.limitRedis(r, session, limiterKey, apiLimit, dryRun) {
+			return sessionFailRateLimit, false
+		}
+		return sessionFailNone, false
+	}
+
+	limit := rate.AtomicLimit{DryRun: dryRun}
+	if limiterKey != "" {
+		limit.Rate = int64(apiLimit.Rate)
+		limit.Per = int64(apiLimit.Per)
+	}
+
+	var periodEnd time.Time
+	var aligned bool
+	if enableQ {
+		limit.QuotaMax, limit.QuotaRenewal, periodEnd, aligned = quotaPeriod(session, apiLimit, now)
+		if aligned {
+			// counters started before the quota was aligned are renewed at the period end
+			limit.QuotaMaxTTL = limit.QuotaRenewal + time.Second
+		}
+	}
+
+	// don't use the requests cancellation context
+	result, err := rate.NewAtomicLimiter(l.limiterStorage).Do(context.Background(), now, rateKey, rawKey, limit)
+	if err != nil {
+		log.WithError(err).Error("error running the atomic rate limiter")
+		if limit.QuotaMax > 0 {
+			return sessionFailQuota, true
+		}
+		return sessionFailNone, true
+	}
+
+	if result.RateLimited {
+		return sessionFailRateLimit, true
+	}
+
+	if limit.QuotaMax > 0 {
+		var renews int64
+		switch {
+		case aligned:
+			renews = periodEnd.Unix()
+		case !result.QuotaRenews.IsZero():
+			renews = result.QuotaRenews.Unix()
+		}
+
+		// dry runs don't consume the quota of the session
+		if !dryRun {
+			l.updateSessionQuota(session, scope, result.QuotaRemaining, renews)
+		}
+		if result.QuotaExceeded {
+			return sessionFailQuota, true
+		}
+	}
+
+	return sessionFailNone, true
+}
+
 func GetAccessDefinitionByAPIIDOrSession(session *user.SessionState, api *APISpec) (accessDef *user.AccessDefinition, allowanceScope string, err error) {
 	accessDef = &user.AccessDefinition{}
 	if len(session.AccessRights) > 0 {
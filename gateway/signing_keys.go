@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"crypto/rsa"
+	"errors"
+	"net/http"
+
+	"github.com/go-jose/go-jose/v3"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/header"
+)
+
+// jwksPath is where the Gateway publishes the public part of its signing keys.
+const jwksPath = "/.well-known/jwks.json"
+
+var (
+	errSigningKeyNotFound      = errors.New("Certificate not found")
+	errSigningKeyNotRSAPrivate = errors.New("Certificate does not contain RSA private key")
+)
+
+// activeSigningKey returns the Gateway key currently used for signing.
+func (gw *Gateway) activeSigningKey() (config.SigningKey, bool) {
+	conf := gw.GetConfig().Security.SigningKeys
+	if len(conf.Keys) == 0 {
+		return config.SigningKey{}, false
+	}
+
+	if conf.ActiveKeyID == "" {
+		return conf.Keys[0], true
+	}
+
+	for _, key := range conf.Keys {
+		if key.KeyID == conf.ActiveKeyID {
+			return key, true
+		}
+	}
+
+	log.WithField("kid", conf.ActiveKeyID).Error("Active signing key is not in the list of signing keys")
+	return config.SigningKey{}, false
+}
+
+// signingPrivateKey loads the RSA private key stored under certID in the certificate store.
+func (gw *Gateway) signingPrivateKey(certID string) (*rsa.PrivateKey, error) {
+	certList := gw.CertificateManager.List([]string{certID}, certs.CertificatePrivate)
+	if len(certList) == 0 || certList[0] == nil {
+		return nil, errSigningKeyNotFound
+	}
+
+	rsaKey, ok := certList[0].PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errSigningKeyNotRSAPrivate
+	}
+
+	return rsaKey, nil
+}
+
+// signingJWKS builds the key set with the public part of all configured signing keys.
+// Keys which can't be loaded are skipped, so a single missing certificate doesn't
+// prevent upstreams from verifying signatures made with the other keys.
+func (gw *Gateway) signingJWKS() jose.JSONWebKeySet {
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+
+	for _, key := range gw.GetConfig().Security.SigningKeys.Keys {
+		privateKey, err := gw.signingPrivateKey(key.CertificateID)
+		if err != nil {
+			log.WithError(err).WithField("kid", key.KeyID).Warning("Couldn't load signing key for JWKS")
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jose.JSONWebKey{
+			Key:       &privateKey.PublicKey,
+			KeyID:     key.KeyID,
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		})
+	}
+
+	return jwks
+}
+
+func (gw *Gateway) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		doJSONWrite(w, http.StatusMethodNotAllowed, apiError(http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+
+	w.Header().Set(header.CacheControl, "max-age=300")
+	doJSONWrite(w, http.StatusOK, gw.signingJWKS())
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/crypto"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestSigningKeysJWKS(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Security.SigningKeys.EnableJWKS = true
+	})
+	defer ts.Close()
+
+	_, _, combinedPEM, _ := crypto.GenServerCertificate()
+	certID, _ := ts.Gw.CertificateManager.Add(combinedPEM, "")
+	defer ts.Gw.CertificateManager.Delete(certID, "")
+
+	_, _, nextPEM, _ := crypto.GenServerCertificate()
+	nextCertID, _ := ts.Gw.CertificateManager.Add(nextPEM, "")
+	defer ts.Gw.CertificateManager.Delete(nextCertID, "")
+
+	globalConf := ts.Gw.GetConfig()
+	globalConf.Security.SigningKeys.Keys = []config.SigningKey{
+		{KeyID: "2024-01", CertificateID: certID},
+		{KeyID: "2024-02", CertificateID: nextCertID},
+		{KeyID: "missing", CertificateID: "unknown"},
+	}
+	ts.Gw.SetConfig(globalConf)
+
+	t.Run("active key", func(t *testing.T) {
+		key, ok := ts.Gw.activeSigningKey()
+		assert.True(t, ok)
+		assert.Equal(t, "2024-01", key.KeyID)
+
+		globalConf.Security.SigningKeys.ActiveKeyID = "2024-02"
+		ts.Gw.SetConfig(globalConf)
+
+		key, ok = ts.Gw.activeSigningKey()
+		assert.True(t, ok)
+		assert.Equal(t, "2024-02", key.KeyID)
+
+		globalConf.Security.SigningKeys.ActiveKeyID = "unknown"
+		ts.Gw.SetConfig(globalConf)
+
+		_, ok = ts.Gw.activeSigningKey()
+		assert.False(t, ok)
+	})
+
+	t.Run("published keys", func(t *testing.T) {
+		resp, _ := ts.Run(t, test.TestCase{Path: jwksPath, Code: http.StatusOK})
+
+		var jwks jose.JSONWebKeySet
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+		assert.Len(t, jwks.Key("2024-01"), 1)
+		assert.Len(t, jwks.Key("2024-02"), 1)
+		assert.Empty(t, jwks.Key("missing"))
+
+		for _, key := range jwks.Keys {
+			assert.True(t, key.IsPublic())
+			assert.Equal(t, "sig", key.Use)
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: jwksPath, Code: http.StatusMethodNotAllowed})
+	})
+}
@@ -0,0 +1,264 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/certs"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/rpc"
+)
+
+const (
+	startupDependencyRedis        = "redis"
+	startupDependencyRPC          = "rpc"
+	startupDependencyCertificates = "certificates"
+
+	defaultStartupDependencyTimeout          = 60
+	defaultStartupDependencyRetryInterval    = 1
+	defaultStartupDependencyMaxRetryInterval = 10
+)
+
+var defaultStartupDependencyOrder = []string{startupDependencyRedis, startupDependencyRPC, startupDependencyCertificates}
+
+// startupDependency is a dependency the Gateway waits for on startup.
+type startupDependency struct {
+	name             string
+	timeout          time.Duration
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+	optional         bool
+	check            func() error
+}
+
+func newStartupDependency(name string, conf config.StartupDependencyConfig, check func() error) startupDependency {
+	seconds := func(value, defaultValue int) time.Duration {
+		if value <= 0 {
+			value = defaultValue
+		}
+		return time.Duration(value) * time.Second
+	}
+
+	dep := startupDependency{
+		name:             name,
+		timeout:          seconds(conf.Timeout, defaultStartupDependencyTimeout),
+		retryInterval:    seconds(conf.RetryInterval, defaultStartupDependencyRetryInterval),
+		maxRetryInterval: seconds(conf.MaxRetryInterval, defaultStartupDependencyMaxRetryInterval),
+		optional:         conf.Optional,
+		check:            check,
+	}
+	if dep.maxRetryInterval < dep.retryInterval {
+		dep.maxRetryInterval = dep.retryInterval
+	}
+
+	return dep
+}
+
+// startupDependencies returns the dependencies to wait for on startup, in the configured order.
+func (gw *Gateway) startupDependencies() []startupDependency {
+	conf := gw.GetConfig()
+	depsConf := conf.StartupDependencies
+
+	order := depsConf.Order
+	if len(order) == 0 {
+		order = defaultStartupDependencyOrder
+	}
+
+	var deps []startupDependency
+	for _, name := range order {
+		switch name {
+		case startupDependencyRedis:
+			deps = append(deps, newStartupDependency(name, depsConf.Redis, gw.checkRedisDependency))
+		case startupDependencyRPC:
+			if conf.SlaveOptions.UseRPC {
+				deps = append(deps, newStartupDependency(name, depsConf.RPC, checkRPCDependency))
+			}
+		case startupDependencyCertificates:
+			if len(conf.HttpServerOptions.SSLCertificates) > 0 {
+				deps = append(deps, newStartupDependency(name, depsConf.Certificates, gw.checkCertificatesDependency))
+			}
+		default:
+			mainLog.Warningf("Ignoring unknown startup dependency %q", name)
+		}
+	}
+
+	return deps
+}
+
+func (gw *Gateway) checkRedisDependency() error {
+	if !gw.StorageConnectionHandler.Connected() {
+		return errors.New("Redis isn't connected")
+	}
+	return nil
+}
+
+func checkRPCDependency() error {
+	if !rpc.Login() {
+		return errors.New("Could not connect to RPC")
+	}
+	return nil
+}
+
+func (gw *Gateway) checkCertificatesDependency() error {
+	ids := gw.GetConfig().HttpServerOptions.SSLCertificates
+
+	loaded := 0
+	for _, cert := range gw.CertificateManager.List(ids, certs.CertificatePrivate) {
+		if cert != nil {
+			loaded++
+		}
+	}
+
+	if loaded < len(ids) {
+		return fmt.Errorf("%d of the %d server certificates are loaded", loaded, len(ids))
+	}
+	return nil
+}
+
+// waitForStartupDependencies waits for the startup dependencies in order. It returns an error when
+// a dependency which isn't optional isn't ready in time.
+func (gw *Gateway) waitForStartupDependencies(ctx context.Context, deps []startupDependency) error {
+	status := newStartupStatus(deps)
+	if gw.GetConfig().StartupDependencies.HealthOnly {
+		gw.serveStartupHealthOnly(status)
+	}
+
+	for _, dep := range deps {
+		logger := mainLog.WithFields(logrus.Fields{"dependency": dep.name, "timeout": dep.timeout})
+		logger.Info("Waiting for startup dependency")
+
+		err := waitForStartupDependency(ctx, dep, func(err error) {
+			status.set(dep.name, err)
+		})
+		if err == nil {
+			logger.Info("Startup dependency is ready")
+			continue
+		}
+
+		if !dep.optional {
+			return err
+		}
+		logger.WithError(err).Warning("Optional startup dependency isn't ready, starting without it")
+	}
+
+	return nil
+}
+
+// waitForStartupDependency checks a dependency until it's ready or the timeout elapses, the
+// interval between the checks doubling up to the maximum retry interval.
+func waitForStartupDependency(ctx context.Context, dep startupDependency, report func(error)) error {
+	deadline := time.Now().Add(dep.timeout)
+	interval := dep.retryInterval
+
+	for {
+		err := dep.check()
+		report(err)
+		if err == nil {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("startup dependency %s isn't ready after %v: %w", dep.name, dep.timeout, err)
+		}
+
+		mainLog.WithField("dependency", dep.name).WithError(err).Debug("Startup dependency isn't ready, retrying")
+
+		wait := interval
+		if wait > remaining {
+			wait = remaining
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		if interval *= 2; interval > dep.maxRetryInterval {
+			interval = dep.maxRetryInterval
+		}
+	}
+}
+
+// serveStartupHealthOnly starts the listeners serving only the health check endpoint, which
+// reports the status of the startup dependencies until the Gateway starts.
+func (gw *Gateway) serveStartupHealthOnly(status *startupStatus) {
+	conf := gw.GetConfig()
+
+	healthRouter := func() *mux.Router {
+		router := mux.NewRouter()
+		router.HandleFunc("/"+conf.HealthCheckEndpointName, status.handler)
+		return router
+	}
+
+	muxer := &proxyMux{}
+	muxer.setRouter(conf.ControlAPIPort, "", healthRouter(), conf)
+	if muxer.router(conf.ListenPort, "", conf) == nil {
+		muxer.setRouter(conf.ListenPort, "", healthRouter(), conf)
+	}
+
+	gw.DefaultProxyMux.swap(muxer, gw)
+	mainLog.Info("Serving only the health check until the startup dependencies are ready")
+}
+
+// startupStatus is the status of the startup dependencies, reported by the health check while
+// the Gateway waits for them.
+type startupStatus struct {
+	mu    sync.Mutex
+	items map[string]HealthCheckItem
+}
+
+func newStartupStatus(deps []startupDependency) *startupStatus {
+	status := &startupStatus{items: make(map[string]HealthCheckItem, len(deps))}
+	for _, dep := range deps {
+		status.items[dep.name] = HealthCheckItem{
+			Status:        Fail,
+			ComponentType: System,
+			Output:        "waiting",
+			Time:          time.Now().Format(time.RFC3339),
+		}
+	}
+
+	return status
+}
+
+func (s *startupStatus) set(name string, err error) {
+	item := HealthCheckItem{Status: Pass, ComponentType: System, Time: time.Now().Format(time.RFC3339)}
+	if err != nil {
+		item.Status = Fail
+		item.Output = err.Error()
+	}
+
+	s.mu.Lock()
+	s.items[name] = item
+	s.mu.Unlock()
+}
+
+func (s *startupStatus) handler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		doJSONWrite(w, http.StatusMethodNotAllowed, apiError(http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+
+	s.mu.Lock()
+	details := make(map[string]HealthCheckItem, len(s.items))
+	for name, item := range s.items {
+		details[name] = item
+	}
+	s.mu.Unlock()
+
+	doJSONWrite(w, http.StatusServiceUnavailable, HealthCheckResponse{
+		Status:      Fail,
+		Version:     VERSION,
+		Description: "Tyk GW starting, waiting for its dependencies",
+		Details:     details,
+	})
+}
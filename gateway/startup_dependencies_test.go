@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestStartupDependencies(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.StartupDependencies.Order = []string{"certificates", "rpc", "unknown", "redis"}
+		globalConf.StartupDependencies.Redis = config.StartupDependencyConfig{Timeout: 5, RetryInterval: 2, MaxRetryInterval: 1}
+		globalConf.HttpServerOptions.SSLCertificates = []string{"missing"}
+	})
+	defer ts.Close()
+
+	deps := ts.Gw.startupDependencies()
+	require.Len(t, deps, 2, "RPC is waited for in RPC mode only")
+	assert.Equal(t, startupDependencyCertificates, deps[0].name)
+	assert.Equal(t, startupDependencyRedis, deps[1].name)
+	assert.Equal(t, 5*time.Second, deps[1].timeout)
+	assert.Equal(t, 2*time.Second, deps[1].maxRetryInterval, "the maximum interval is at least the first one")
+
+	assert.Error(t, deps[0].check(), "the server certificate isn't in the store")
+	assert.NoError(t, deps[1].check())
+}
+
+func TestWaitForStartupDependencies(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	var calls []string
+	dependency := func(name string, failures int, optional bool) startupDependency {
+		return startupDependency{
+			name:             name,
+			timeout:          100 * time.Millisecond,
+			retryInterval:    time.Millisecond,
+			maxRetryInterval: 5 * time.Millisecond,
+			optional:         optional,
+			check: func() error {
+				calls = append(calls, name)
+				if failures != 0 {
+					failures--
+					return errors.New("not ready")
+				}
+				return nil
+			},
+		}
+	}
+
+	t.Run("in order with retries", func(t *testing.T) {
+		calls = nil
+		err := ts.Gw.waitForStartupDependencies(context.Background(), []startupDependency{
+			dependency("redis", 2, false),
+			dependency("rpc", 0, false),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"redis", "redis", "redis", "rpc"}, calls)
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		calls = nil
+		err := ts.Gw.waitForStartupDependencies(context.Background(), []startupDependency{
+			dependency("rpc", -1, true),
+			dependency("redis", 0, false),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "redis", calls[len(calls)-1], "the startup goes on without the optional dependency")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		calls = nil
+		err := ts.Gw.waitForStartupDependencies(context.Background(), []startupDependency{
+			dependency("redis", -1, false),
+			dependency("rpc", 0, false),
+		})
+		assert.ErrorContains(t, err, "startup dependency redis isn't ready")
+		assert.NotContains(t, calls, "rpc")
+	})
+}
+
+func TestStartupStatusHandler(t *testing.T) {
+	status := newStartupStatus([]startupDependency{{name: "redis"}, {name: "rpc"}})
+	status.set("redis", nil)
+	status.set("rpc", errors.New("Could not connect to RPC"))
+
+	recorder := httptest.NewRecorder()
+	status.handler(recorder, httptest.NewRequest(http.MethodGet, "/hello", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var res HealthCheckResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+	assert.EqualValues(t, Fail, res.Status)
+	assert.EqualValues(t, Pass, res.Details["redis"].Status)
+	assert.Equal(t, "Could not connect to RPC", res.Details["rpc"].Output)
+}
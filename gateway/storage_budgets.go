@@ -0,0 +1,209 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	storageBudgetAnalytics = "analytics"
+	storageBudgetCache     = "cache"
+
+	defaultStorageBudgetAlertThreshold = 0.8
+	// analyticsBudgetCheckInterval is how often the size of the analytics lists is estimated.
+	analyticsBudgetCheckInterval = 5 * time.Second
+)
+
+const (
+	storageBudgetOK int32 = iota
+	storageBudgetApproached
+	storageBudgetExceeded
+)
+
+// storageBudget is a byte budget of Redis keys, estimated by this Gateway rather than measured
+// in Redis. A nil budget is unlimited.
+type storageBudget struct {
+	gw        *Gateway
+	name      string
+	limit     int64
+	threshold float64
+
+	used  atomic.Int64
+	state atomic.Int32
+
+	// expiries are the bytes of the tracked entries by the unix time they expire at.
+	mu       sync.Mutex
+	expiries map[int64]int64
+}
+
+func (gw *Gateway) newStorageBudget(name string, limit int64) *storageBudget {
+	if limit <= 0 {
+		return nil
+	}
+
+	threshold := gw.GetConfig().StorageBudgets.AlertThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = defaultStorageBudgetAlertThreshold
+	}
+
+	return &storageBudget{gw: gw, name: name, limit: limit, threshold: threshold, expiries: map[int64]int64{}}
+}
+
+// initStorageBudgets sets up the budgets of the configuration.
+func (gw *Gateway) initStorageBudgets() {
+	conf := gw.GetConfig().StorageBudgets
+	gw.analyticsBudget = gw.newStorageBudget(storageBudgetAnalytics, conf.AnalyticsBytes)
+	gw.cacheBudget = gw.newStorageBudget(storageBudgetCache, conf.CacheBytes)
+}
+
+// exceeded returns true while the budget is exceeded.
+func (b *storageBudget) exceeded() bool {
+	return b != nil && b.state.Load() == storageBudgetExceeded
+}
+
+// set sets the bytes used, as estimated from the storage.
+func (b *storageBudget) set(used int64) {
+	if b == nil {
+		return
+	}
+
+	b.used.Store(used)
+	b.alert(used)
+}
+
+// reserve tracks an entry of size bytes expiring after ttl seconds, or returns false when it
+// would exceed the budget. The entries without ttl are tracked until the Gateway stops.
+func (b *storageBudget) reserve(size, ttl int64) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now().Unix()
+	for expiry, bytes := range b.expiries {
+		if expiry <= now {
+			b.used.Add(-bytes)
+			delete(b.expiries, expiry)
+		}
+	}
+
+	used := b.used.Load()
+	if used+size > b.limit {
+		b.alert(used + size)
+		return false
+	}
+
+	if ttl > 0 {
+		b.expiries[now+ttl] += size
+	}
+	b.alert(b.used.Add(size))
+
+	return true
+}
+
+// alert fires an event when the budget reaches its threshold or is exceeded.
+func (b *storageBudget) alert(used int64) {
+	state := storageBudgetOK
+	switch {
+	case used > b.limit:
+		state = storageBudgetExceeded
+	case float64(used) >= b.threshold*float64(b.limit):
+		state = storageBudgetApproached
+	}
+
+	previous := b.state.Swap(state)
+	if state == previous {
+		return
+	}
+
+	logger := log.WithField("budget", b.name).WithField("used_bytes", used).WithField("limit_bytes", b.limit)
+	if state < previous {
+		logger.Info("Storage budget recovered")
+		return
+	}
+
+	var event apidef.TykEvent
+	var message string
+	if state == storageBudgetExceeded {
+		event, message = EventStorageBudgetExceeded, "Storage budget exceeded."
+		logger.Warning("Storage budget exceeded, backing off")
+	} else {
+		event, message = EventStorageBudgetApproached, "Storage budget approached."
+		logger.Warning("Storage budget approached")
+	}
+
+	b.gw.FireSystemEvent(event, EventStorageBudgetMeta{
+		EventMetaDefault: EventMetaDefault{Message: message},
+		Budget:           b.name,
+		UsedBytes:        used,
+		LimitBytes:       b.limit,
+	})
+}
+
+// analyticsBudgetLoop estimates the size of the analytics lists periodically, until ctx is done.
+func (gw *Gateway) analyticsBudgetLoop(ctx context.Context) {
+	tick := time.NewTicker(analyticsBudgetCheckInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			gw.checkAnalyticsBudget()
+		}
+	}
+}
+
+// checkAnalyticsBudget estimates the size of the analytics lists, with their length and the
+// average size of the records encoded by the Gateway. The lists are shared by the Gateways and
+// drained by the purgers.
+func (gw *Gateway) checkAnalyticsBudget() {
+	if gw.analyticsBudget == nil {
+		return
+	}
+
+	records := gw.Analytics.encodedRecords.Load()
+	if records == 0 {
+		return
+	}
+	average := gw.Analytics.encodedBytes.Load() / records
+
+	store := &storage.RedisCluster{KeyPrefix: "analytics-", IsAnalytics: true, ConnectionHandler: gw.StorageConnectionHandler}
+	client, err := store.Client()
+	if err != nil {
+		log.WithError(err).Debug("Couldn't estimate the size of the analytics lists")
+		return
+	}
+
+	suffix := ""
+	if gw.Analytics.analyticsSerializer != nil {
+		suffix = gw.Analytics.analyticsSerializer.GetSuffix()
+	}
+
+	keys := []string{analyticsKeyName}
+	if gw.Analytics.enableMultipleAnalyticsKeys {
+		for shard := 0; shard < maxAnalyticsKeyShards(gw.GetConfig().AnalyticsConfig); shard++ {
+			keys = append(keys, analyticsShardKey(shard))
+		}
+	}
+
+	var length int64
+	for _, key := range keys {
+		n, err := client.LLen(context.Background(), store.KeyPrefix+key+suffix).Result()
+		if err != nil {
+			log.WithError(err).Debug("Couldn't estimate the size of the analytics lists")
+			return
+		}
+		length += n
+	}
+
+	gw.analyticsBudget.set(length * average)
+}
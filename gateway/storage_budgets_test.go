@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestStorageBudget(t *testing.T) {
+	events := make(chan config.EventMessage, 10)
+	handler := &testEventHandler{func(em config.EventMessage) { events <- em }}
+
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.StorageBudgets.CacheBytes = 100
+		globalConf.StorageBudgets.AlertThreshold = 0.5
+		globalConf.SetEventTriggers(map[apidef.TykEvent][]config.TykEventHandler{
+			EventStorageBudgetApproached: {handler},
+			EventStorageBudgetExceeded:   {handler},
+		})
+	})
+	defer ts.Close()
+
+	next := func(t *testing.T) config.EventMessage {
+		t.Helper()
+		select {
+		case em := <-events:
+			return em
+		case <-time.After(time.Second):
+			t.Fatal("no event fired")
+			return config.EventMessage{}
+		}
+	}
+
+	assert.Nil(t, ts.Gw.analyticsBudget, "budgets without limit are unlimited")
+	assert.False(t, ts.Gw.analyticsBudget.exceeded())
+	assert.True(t, ts.Gw.analyticsBudget.reserve(1<<30, 0))
+
+	budget := ts.Gw.cacheBudget
+	assert.True(t, budget.reserve(40, 0))
+	assert.Empty(t, events)
+
+	assert.True(t, budget.reserve(20, 1))
+	em := next(t)
+	assert.Equal(t, EventStorageBudgetApproached, em.Type)
+	assert.Equal(t, int64(60), em.Meta.(EventStorageBudgetMeta).UsedBytes)
+	assert.Equal(t, storageBudgetCache, em.Meta.(EventStorageBudgetMeta).Budget)
+
+	assert.False(t, budget.reserve(50, 0))
+	assert.True(t, budget.exceeded())
+	assert.Equal(t, EventStorageBudgetExceeded, next(t).Type)
+
+	// the expired entries are released
+	assert.Eventually(t, func() bool { return budget.reserve(50, 0) }, 3*time.Second, 100*time.Millisecond)
+	assert.Equal(t, int64(90), budget.used.Load())
+	assert.False(t, budget.exceeded())
+
+	budget.set(10)
+	assert.False(t, budget.exceeded())
+	budget.set(200)
+	assert.True(t, budget.exceeded())
+}
+
+func TestStorageBudgetCache(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.StorageBudgets.CacheBytes = 1
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/budget/"
+		spec.CacheOptions = apidef.CacheOptions{EnableCache: true, CacheTimeout: 60, CacheAllSafeRequests: true}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/budget/", Code: http.StatusOK},
+		{Path: "/budget/", Code: http.StatusOK, HeadersNotMatch: map[string]string{cachedResponseHeader: "1"}},
+	}...)
+
+	assert.True(t, ts.Gw.cacheBudget.exceeded())
+}
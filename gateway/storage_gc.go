@@ -0,0 +1,238 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+const (
+	// defaultStorageGCInterval is the default interval in seconds between two storage reports.
+	defaultStorageGCInterval = 60 * 60
+
+	storageOrphanQuota       = "quota"
+	storageOrphanOAuthClient = "oauth-client"
+
+	sessionKeyPrefix       = "apikey-"
+	orgSessionKeyPrefix    = "orgkey."
+	oauthDataPrefix        = "oauth-data."
+	quotaCounterKeyPattern = QuotaKeyPrefix + "*"
+	oauthClientKeyPattern  = oauthDataPrefix + "*" + prefixClient + "*"
+)
+
+// storageOrphan is a storage artifact which outlived the key or the API it belongs to.
+type storageOrphan struct {
+	Kind string `json:"kind"`
+	// Key is the storage key of the artifact.
+	Key string `json:"key"`
+	// TTL is the number of seconds before the artifact expires, -1 when it doesn't expire.
+	TTL int64 `json:"ttl"`
+}
+
+// storageGCReport lists the orphaned storage artifacts.
+//
+// The OAuth clients are only reported, never deleted: the APIs loaded by a Gateway aren't
+// necessarily the ones of the cluster. The DRL servers aren't part of the report: they're kept in
+// memory and expire when they stop notifying their load.
+type storageGCReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Scanned is the number of artifacts scanned.
+	Scanned int             `json:"scanned"`
+	Orphans []storageOrphan `json:"orphans"`
+	// Cleaned is set when the orphans were deleted.
+	Cleaned bool `json:"cleaned"`
+}
+
+// runStorageGC reports the orphaned storage artifacts periodically, and deletes them when
+// `storage_gc.cleanup` is set. A single Gateway of the cluster runs it at a time, once its APIs
+// are loaded.
+func (gw *Gateway) runStorageGC() error {
+	if !gw.apisReloaded.Load() {
+		log.Debug("APIs not loaded yet, skipping storage gc report")
+		return nil
+	}
+
+	// the keys of the RPC Gateways are cached from the management layer, deleted locally when
+	// they expire from the cache
+	if gw.GetConfig().SlaveOptions.UseRPC {
+		log.Debug("storage gc doesn't run in RPC mode, skipping report")
+		return nil
+	}
+
+	store := &storage.RedisCluster{KeyPrefix: "", HashKeys: false, ConnectionHandler: gw.StorageConnectionHandler}
+
+	ok, err := store.Lock("storage-gc-lock", time.Minute)
+	if err != nil {
+		log.WithError(err).Error("error acquiring lock to report orphaned storage artifacts")
+		return err
+	}
+
+	if !ok {
+		log.Debug("storage gc lock not acquired, skipping report")
+		return nil
+	}
+
+	report := gw.storageGC(gw.GetConfig().StorageGC.Cleanup)
+	log.WithField("scanned", report.Scanned).
+		WithField("orphans", len(report.Orphans)).
+		WithField("cleaned", report.Cleaned).
+		Info("Reported orphaned storage artifacts")
+
+	return nil
+}
+
+// storageGC finds the orphaned storage artifacts, and deletes them when cleanup is set. The report
+// is kept for the Gateway API.
+func (gw *Gateway) storageGC(cleanup bool) *storageGCReport {
+	store := &storage.RedisCluster{KeyPrefix: "", HashKeys: false, ConnectionHandler: gw.StorageConnectionHandler}
+
+	report := &storageGCReport{GeneratedAt: time.Now().UTC(), Orphans: []storageOrphan{}}
+
+	gw.findOrphanedQuotaCounters(store, report)
+
+	// segmented Gateways only load a part of the APIs
+	if !gw.GetConfig().DBAppConfOptions.NodeIsSegmented {
+		gw.findOrphanedOAuthClients(store, report)
+	}
+
+	if cleanup {
+		for _, orphan := range report.Orphans {
+			if orphan.Kind == storageOrphanQuota {
+				store.DeleteRawKey(orphan.Key)
+			}
+		}
+		report.Cleaned = true
+	}
+
+	gw.storageGCReport.Store(report)
+	return report
+}
+
+// findOrphanedQuotaCounters reports the quota counters of the deleted keys. The counters are
+// stored by key or organisation, prefixed by an allowance scope or a quota bucket, so the key is
+// searched for with each suffix of the counter. The counters of the quota pools belong to their
+// pool.
+func (gw *Gateway) findOrphanedQuotaCounters(store *storage.RedisCluster, report *storageGCReport) {
+	orgKeys, err := store.ScanKeys(orgSessionKeyPrefix + "*")
+	if err != nil {
+		log.WithError(err).Error("error while scanning for organisations")
+		return
+	}
+
+	// the org quota counters are hashed like the keys, the org sessions aren't
+	orgs := make(map[string]bool, len(orgKeys))
+	for _, key := range orgKeys {
+		orgs[storage.HashKey(strings.TrimPrefix(key, orgSessionKeyPrefix), gw.GetConfig().HashKeys)] = true
+	}
+
+	keys, err := store.ScanKeys(quotaCounterKeyPattern)
+	if err != nil {
+		log.WithError(err).Error("error while scanning for quota counters")
+		return
+	}
+
+	for _, key := range keys {
+		report.Scanned++
+
+		counter := strings.TrimPrefix(key, QuotaKeyPrefix)
+		if sessionExistsForCounter(store, orgs, counter) {
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, newStorageOrphan(store, storageOrphanQuota, key))
+	}
+}
+
+func sessionExistsForCounter(store *storage.RedisCluster, orgs map[string]bool, counter string) bool {
+	if poolID, ok := strings.CutPrefix(counter, "pool-"); ok {
+		if exists, err := store.Exists(quotaPoolPrefix + poolID); err != nil || exists {
+			return true
+		}
+	}
+
+	for suffix := counter; suffix != ""; {
+		if orgs[suffix] {
+			return true
+		}
+
+		if exists, err := store.Exists(sessionKeyPrefix + suffix); err != nil || exists {
+			// keep the counter when the lookup failed
+			return true
+		}
+
+		i := strings.Index(suffix, "-")
+		if i < 0 {
+			break
+		}
+		suffix = suffix[i+1:]
+	}
+
+	return false
+}
+
+// findOrphanedOAuthClients reports the OAuth clients of the APIs which aren't loaded. They're
+// candidates for a manual cleanup, the API may still be loaded by other Gateways.
+func (gw *Gateway) findOrphanedOAuthClients(store *storage.RedisCluster, report *storageGCReport) {
+	keys, err := store.ScanKeys(oauthClientKeyPattern)
+	if err != nil {
+		log.WithError(err).Error("error while scanning for oauth clients")
+		return
+	}
+
+	for _, key := range keys {
+		apiID, _, found := strings.Cut(strings.TrimPrefix(key, oauthDataPrefix), "."+prefixClient)
+		if !found {
+			continue
+		}
+
+		report.Scanned++
+		if gw.getApiSpec(apiID) != nil {
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, newStorageOrphan(store, storageOrphanOAuthClient, key))
+	}
+}
+
+func newStorageOrphan(store *storage.RedisCluster, kind, key string) storageOrphan {
+	ttl, err := store.GetKeyTTL(key)
+	if err != nil {
+		ttl = -1
+	}
+
+	return storageOrphan{Kind: kind, Key: key, TTL: ttl}
+}
+
+// storageGCHandler returns the last report of the orphaned storage artifacts, a new report when
+// there's none or the refresh query parameter is set.
+func (gw *Gateway) storageGCHandler(w http.ResponseWriter, r *http.Request) {
+	if gw.GetConfig().SlaveOptions.UseRPC {
+		doJSONWrite(w, http.StatusNotImplemented, apiError("Storage gc isn't available in RPC mode"))
+		return
+	}
+
+	report := gw.storageGCReport.Load()
+	if report == nil || r.URL.Query().Get("refresh") == "true" {
+		report = gw.storageGC(false)
+	}
+
+	doJSONWrite(w, http.StatusOK, report)
+}
+
+// storageGCCleanupHandler deletes the orphaned quota counters and returns the orphaned artifacts.
+//
+// The keys of the RPC Gateways are only cached locally, their valid quota counters would look
+// orphaned, so the cleanup is refused in RPC mode.
+func (gw *Gateway) storageGCCleanupHandler(w http.ResponseWriter, _ *http.Request) {
+	if gw.GetConfig().SlaveOptions.UseRPC {
+		doJSONWrite(w, http.StatusNotImplemented, apiError("Storage gc isn't available in RPC mode"))
+		return
+	}
+
+	report := gw.storageGC(true)
+
+	log.WithField("orphans", len(report.Orphans)).Info("Deleted orphaned storage artifacts")
+	doJSONWrite(w, http.StatusOK, report)
+}
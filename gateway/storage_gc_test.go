@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestStorageGC(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "gc-api"
+		spec.Proxy.ListenPath = "/gc/"
+	})
+
+	store := &storage.RedisCluster{ConnectionHandler: ts.Gw.StorageConnectionHandler}
+	for key, ttl := range map[string]int64{
+		"apikey-gc-live":                                 0,
+		"orgkey.gc-org":                                  0,
+		"quota-gc-live":                                  0,
+		"quota-scope-gc-live":                            0,
+		"quota-gc-org":                                   0,
+		"quota-gc-deleted":                               60,
+		quotaPoolPrefix + "gc-pool":                      0,
+		quotaPoolCounterKey("gc-pool"):                   0,
+		quotaPoolCounterKey("gc-deleted-pool"):           0,
+		"oauth-data.gc-api.oauth-clientid.live":          0,
+		"oauth-data.gc-removed.oauth-clientid.gone":      0,
+		"oauth-data.gc-removed.oauth-client-tokens.gone": 0,
+	} {
+		require.NoError(t, store.SetRawKey(key, "1", ttl))
+	}
+
+	orphans := func(report storageGCReport) map[string]storageOrphan {
+		byKey := map[string]storageOrphan{}
+		for _, orphan := range report.Orphans {
+			byKey[orphan.Key] = orphan
+		}
+		return byKey
+	}
+
+	resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/storage-gc", AdminAuth: true, Code: http.StatusOK})
+	var report storageGCReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.False(t, report.Cleaned)
+
+	found := orphans(report)
+	assert.NotContains(t, found, "quota-gc-live")
+	assert.NotContains(t, found, "quota-scope-gc-live", "scoped counters belong to their key")
+	assert.NotContains(t, found, "quota-gc-org", "org counters belong to their org")
+	assert.NotContains(t, found, "oauth-data.gc-api.oauth-clientid.live")
+	assert.NotContains(t, found, quotaPoolCounterKey("gc-pool"), "pool counters belong to their pool")
+	assert.Contains(t, found, quotaPoolCounterKey("gc-deleted-pool"))
+	assert.Equal(t, storageOrphanQuota, found["quota-gc-deleted"].Kind)
+	assert.Greater(t, found["quota-gc-deleted"].TTL, int64(0))
+	assert.Equal(t, storageOrphanOAuthClient, found["oauth-data.gc-removed.oauth-clientid.gone"].Kind)
+
+	resp, _ = ts.Run(t, test.TestCase{Path: "/tyk/storage-gc/cleanup", Method: http.MethodPost, AdminAuth: true, Code: http.StatusOK})
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.True(t, report.Cleaned)
+
+	for key, exists := range map[string]bool{
+		"quota-gc-live":                                  true,
+		"quota-gc-org":                                   true,
+		"oauth-data.gc-api.oauth-clientid.live":          true,
+		"quota-gc-deleted":                               false,
+		quotaPoolCounterKey("gc-pool"):                   true,
+		quotaPoolCounterKey("gc-deleted-pool"):           false,
+		"oauth-data.gc-removed.oauth-clientid.gone":      true,
+		"oauth-data.gc-removed.oauth-client-tokens.gone": true,
+	} {
+		found, err := store.Exists(key)
+		require.NoError(t, err)
+		assert.Equal(t, exists, found, key)
+	}
+
+	assert.NotContains(t, orphans(*ts.Gw.storageGC(false)), "quota-gc-deleted")
+}
+
+func TestStorageGC_beforeReload(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.StorageGC.Cleanup = true
+	})
+	defer ts.Close()
+
+	store := &storage.RedisCluster{ConnectionHandler: ts.Gw.StorageConnectionHandler}
+	require.NoError(t, store.SetRawKey("quota-gc-not-loaded", "1", 60))
+
+	ts.Gw.apisReloaded.Store(false)
+	require.NoError(t, ts.Gw.runStorageGC())
+
+	exists, err := store.Exists("quota-gc-not-loaded")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Nil(t, ts.Gw.storageGCReport.Load())
+}
+
+func TestStorageGC_RPCMode(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	store := &storage.RedisCluster{ConnectionHandler: ts.Gw.StorageConnectionHandler}
+	require.NoError(t, store.SetRawKey("quota-gc-rpc-cached", "1", 0))
+
+	conf := ts.Gw.GetConfig()
+	conf.SlaveOptions.UseRPC = true
+	ts.Gw.SetConfig(conf)
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/tyk/storage-gc", AdminAuth: true, Code: http.StatusNotImplemented},
+		{Path: "/tyk/storage-gc/cleanup", Method: http.MethodPost, AdminAuth: true, Code: http.StatusNotImplemented},
+	}...)
+
+	exists, err := store.Exists("quota-gc-rpc-cached")
+	require.NoError(t, err)
+	assert.True(t, exists, "the quota counters of the cached keys are kept")
+}
@@ -0,0 +1,25 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+)
+
+// observeStorageCommand sends the latency of the commands sent to Redis to StatsD.
+func observeStorageCommand(command string, d time.Duration) {
+	if instrumentationEnabled {
+		instrument.NewJob("StorageCommand").Timing(command, d.Nanoseconds())
+	}
+}
+
+// storageInstrumentationHandler lists the latency of the commands sent to Redis and the last
+// slow commands.
+func (gw *Gateway) storageInstrumentationHandler(w http.ResponseWriter, _ *http.Request) {
+	instrumentation := gw.StorageConnectionHandler.Instrumentation()
+	if instrumentation == nil {
+		doJSONWrite(w, http.StatusServiceUnavailable, apiError("Storage isn't connected"))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, instrumentation.Snapshot())
+}
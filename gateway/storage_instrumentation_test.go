@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestStorageInstrumentation(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.StorageInstrumentation.Enabled = true
+	})
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "instrumented"
+		spec.UseKeylessAccess = false
+		spec.Proxy.ListenPath = "/"
+	})
+
+	_, key := ts.CreateSession(func(s *user.SessionState) {
+		s.AccessRights = map[string]user.AccessDefinition{"instrumented": {APIID: "instrumented"}}
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Path: "/", Headers: map[string]string{"Authorization": key}, Code: http.StatusOK},
+		{AdminAuth: true, Path: "/tyk/debug/storage", BodyMatch: `"apikey-\*":`, Code: http.StatusOK},
+		{AdminAuth: true, Path: "/tyk/debug/storage", BodyMatch: `"commands":\{.*"set":\{"calls":`, Code: http.StatusOK},
+	}...)
+}
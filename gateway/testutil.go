@@ -1179,7 +1179,7 @@ func (s *Test) newGateway(genConf func(globalConf *config.Config)) *Gateway {
 		}
 	}()
 
-	gw.keyGen = DefaultKeyGenerator{Gw: gw}
+	gw.keyGen = gw.newKeyGenerator()
 	gw.CoProcessInit()
 	gw.afterConfSetup()
 
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk/ctx"
+)
+
+// OTLP span kinds and status codes, enums are integers in the OTLP JSON encoding.
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindServer   = 2
+	otlpSpanKindClient   = 3
+
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// otlpTraces is an OTLP ExportTraceServiceRequest in the JSON encoding, which can be sent as
+// is to the /v1/traces endpoint of an OpenTelemetry collector.
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// otlpSpan is a span, the IDs are hex encoded and the times are nanoseconds since the epoch.
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	// IntValue is a string, as int64 values are in the OTLP JSON encoding.
+	IntValue *string `json:"intValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int) otlpAttribute {
+	s := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+// traceRecorder records the spans of a traced request: the root span of the request, a span
+// per middleware of the chain and a span for the upstream call.
+type traceRecorder struct {
+	mu      sync.Mutex
+	traceID string
+	rootID  string
+	start   time.Time
+	spans   []otlpSpan
+}
+
+func newTraceRecorder() *traceRecorder {
+	return &traceRecorder{traceID: randomSpanID(16), rootID: randomSpanID(8), start: time.Now()}
+}
+
+func randomSpanID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (t *traceRecorder) record(name string, kind int, start time.Time, status otlpStatus, attrs ...otlpAttribute) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.spans = append(t.spans, otlpSpan{
+		TraceID:           t.traceID,
+		SpanID:            randomSpanID(8),
+		ParentSpanID:      t.rootID,
+		Name:              name,
+		Kind:              kind,
+		StartTimeUnixNano: strconv.FormatInt(start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes:        attrs,
+		Status:            status,
+	})
+}
+
+// middleware records the span of a middleware which processed the request.
+func (t *traceRecorder) middleware(name string, start time.Time, err error, code int) {
+	attrs := []otlpAttribute{otlpInt("tyk.middleware.code", code)}
+	status := otlpStatus{Code: otlpStatusOK}
+
+	switch {
+	case err != nil:
+		status = otlpStatus{Code: otlpStatusError, Message: err.Error()}
+	case code == mwStatusRespond:
+		attrs = append(attrs, otlpString("tyk.middleware.responded", "true"))
+	}
+
+	t.record(name, otlpSpanKindInternal, start, status, attrs...)
+}
+
+// export returns the recorded spans with the root span of the request, which ended with the
+// response status.
+func (t *traceRecorder) export(spec *APISpec, r *http.Request, code int) otlpTraces {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := otlpStatus{Code: otlpStatusOK}
+	if code >= http.StatusBadRequest {
+		status = otlpStatus{Code: otlpStatusError, Message: http.StatusText(code)}
+	}
+
+	root := otlpSpan{
+		TraceID:           t.traceID,
+		SpanID:            t.rootID,
+		Name:              r.Method + " " + spec.Proxy.ListenPath,
+		Kind:              otlpSpanKindServer,
+		StartTimeUnixNano: strconv.FormatInt(t.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes: []otlpAttribute{
+			otlpString("http.method", r.Method),
+			otlpString("http.target", r.URL.RequestURI()),
+			otlpInt("http.status_code", code),
+			otlpString("tyk.api.id", spec.APIID),
+			otlpString("tyk.api.name", spec.Name),
+		},
+		Status: status,
+	}
+
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{otlpString("service.name", "tyk-gateway")}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "tyk-trace"},
+			Spans: append([]otlpSpan{root}, t.spans...),
+		}},
+	}}}
+}
+
+func hasOTLPAttribute(attrs []otlpAttribute, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func ctxSetTraceRecorder(r *http.Request, t *traceRecorder) {
+	setCtxValue(r, ctx.TraceSpans, t)
+}
+
+func ctxGetTraceRecorder(r *http.Request) *traceRecorder {
+	if v, ok := r.Context().Value(ctx.TraceSpans).(*traceRecorder); ok {
+		return v
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package gateway
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
 	"github.com/TykTechnologies/tyk/internal/httputil"
 	"github.com/TykTechnologies/tyk/internal/model"
 )
@@ -20,6 +22,8 @@ type traceHttpRequest struct {
 	Path    string      `json:"path"`
 	Body    string      `json:"body"`
 	Headers http.Header `json:"headers"`
+	// ExpectedCode is the status code a request of a batch passes with.
+	ExpectedCode int `json:"expected_code,omitempty"`
 }
 
 func (tr *traceHttpRequest) toRequest(ignoreCanonicalMIMEHeaderKey bool) (*http.Request, error) {
@@ -40,8 +44,13 @@ func (tr *traceHttpRequest) toRequest(ignoreCanonicalMIMEHeaderKey bool) (*http.
 // TraceRequest is for tracing an HTTP request
 // swagger:model TraceRequest
 type traceRequest struct {
-	Request *traceHttpRequest     `json:"request"`
-	Spec    *apidef.APIDefinition `json:"spec"`
+	Request *traceHttpRequest `json:"request"`
+	// Requests are traced one after the other against the spec, as a batch.
+	Requests []*traceHttpRequest   `json:"requests,omitempty"`
+	Spec     *apidef.APIDefinition `json:"spec"`
+	// OAS is a Tyk OAS API definition, with the x-tyk-api-gateway extension, traced instead of
+	// a classic spec.
+	OAS *oas.OAS `json:"oas,omitempty"`
 }
 
 // TraceResponse is for tracing an HTTP response
@@ -50,6 +59,38 @@ type traceResponse struct {
 	Message  string `json:"message"`
 	Response string `json:"response"`
 	Logs     string `json:"logs"`
+	// Spans are the spans of the request, a span per middleware of the chain and a span for the
+	// upstream call under the span of the request, as an OTLP export request in the JSON encoding.
+	Spans *otlpTraces `json:"spans,omitempty"`
+	// Results are the results of the requests of a batch, in order.
+	Results []traceResult `json:"results,omitempty"`
+	// Summary aggregates the results of a batch.
+	Summary *traceSummary `json:"summary,omitempty"`
+}
+
+// traceResult is the result of a request of a batch.
+type traceResult struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Code is the status code of the response.
+	Code int `json:"code"`
+	// Passed is true when the code is the expected code, or below 400 without one.
+	Passed bool `json:"passed"`
+	// Middleware are the middleware of the chain which processed the request, in order.
+	Middleware []string `json:"middleware"`
+	// RejectedBy is the middleware which rejected the request or responded to it.
+	RejectedBy string      `json:"rejected_by,omitempty"`
+	Response   string      `json:"response"`
+	Spans      *otlpTraces `json:"spans,omitempty"`
+}
+
+// traceSummary aggregates the results of a batch.
+type traceSummary struct {
+	Total  int `json:"total"`
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	// StatusCodes counts the responses by status code.
+	StatusCodes map[int]int `json:"status_codes"`
 }
 
 // Tracing request
@@ -95,13 +136,30 @@ func (gw *Gateway) traceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if traceReq.Spec != nil && traceReq.OAS != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Only one of the spec and oas fields can be set"))
+		return
+	}
+
+	if traceReq.OAS != nil {
+		if traceReq.OAS.GetTykExtension() == nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError(apidef.ErrPayloadWithoutTykExtension.Error()))
+			return
+		}
+
+		var def apidef.APIDefinition
+		traceReq.OAS.ExtractTo(&def)
+		def.IsOAS = true
+		traceReq.Spec = &def
+	}
+
 	if traceReq.Spec == nil {
 		log.Error("Spec field is missing")
 		doJSONWrite(w, http.StatusBadRequest, apiError("Spec field is missing"))
 		return
 	}
 
-	if traceReq.Request == nil {
+	if traceReq.Request == nil && len(traceReq.Requests) == 0 {
 		log.Error("Request field is missing")
 		doJSONWrite(w, http.StatusBadRequest, apiError("Request field is missing"))
 		return
@@ -118,7 +176,7 @@ func (gw *Gateway) traceHandler(w http.ResponseWriter, r *http.Request) {
 
 	loader := &APIDefinitionLoader{Gw: gw}
 
-	spec, err := loader.MakeSpec(&model.MergedAPI{APIDefinition: traceReq.Spec}, logrus.NewEntry(logger))
+	spec, err := loader.MakeSpec(&model.MergedAPI{APIDefinition: traceReq.Spec, OAS: traceReq.OAS}, logrus.NewEntry(logger))
 	if err != nil {
 		doJSONWrite(w, http.StatusBadRequest, traceResponse{Message: "error", Logs: logStorage.String()})
 		return
@@ -135,14 +193,57 @@ func (gw *Gateway) traceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(traceReq.Requests) == 0 {
+		result, err := gw.traceSample(spec, chainObj.ThisHandler, traceReq.Request)
+		if err != nil {
+			doJSONWrite(w, http.StatusInternalServerError, apiError("Unexpected failure: "+err.Error()))
+			return
+		}
+
+		doJSONWrite(w, http.StatusOK, traceResponse{Message: "ok", Response: result.Response, Logs: logStorage.String(), Spans: result.Spans})
+		return
+	}
+
+	summary := &traceSummary{StatusCodes: map[int]int{}}
+	results := make([]traceResult, 0, len(traceReq.Requests))
+	for i, sample := range traceReq.Requests {
+		if sample == nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError(fmt.Sprintf("Request %d is missing", i)))
+			return
+		}
+
+		result, err := gw.traceSample(spec, chainObj.ThisHandler, sample)
+		if err != nil {
+			doJSONWrite(w, http.StatusBadRequest, apiError(fmt.Sprintf("Request %d malformed: %s", i, err)))
+			return
+		}
+
+		summary.Total++
+		summary.StatusCodes[result.Code]++
+		if result.Passed {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		results = append(results, *result)
+	}
+
+	doJSONWrite(w, http.StatusOK, traceResponse{Message: "ok", Logs: logStorage.String(), Results: results, Summary: summary})
+}
+
+// traceSample serves a sample request with the chain of a traced spec.
+func (gw *Gateway) traceSample(spec *APISpec, handler http.Handler, sample *traceHttpRequest) (*traceResult, error) {
 	wr := httptest.NewRecorder()
-	tr, err := traceReq.Request.toRequest(gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
+	tr, err := sample.toRequest(gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
 	if err != nil {
-		doJSONWrite(w, http.StatusInternalServerError, apiError("Unexpected failure: "+err.Error()))
-		return
+		return nil, err
 	}
 	nopCloseRequestBody(tr)
-	chainObj.ThisHandler.ServeHTTP(wr, tr)
+
+	recorder := newTraceRecorder()
+	ctxSetTraceRecorder(tr, recorder)
+	handler.ServeHTTP(wr, tr)
+	spans := recorder.export(spec, tr, wr.Code)
 
 	var response string
 	if dump, err := httputil.DumpResponse(wr.Result(), true); err == nil {
@@ -158,7 +259,30 @@ func (gw *Gateway) traceHandler(w http.ResponseWriter, r *http.Request) {
 		request = err.Error()
 	}
 
-	requestDump := "====== Request ======\n" + request + "\n====== Response ======\n" + response
+	result := &traceResult{
+		Method:     sample.Method,
+		Path:       sample.Path,
+		Code:       wr.Code,
+		Middleware: []string{},
+		Response:   "====== Request ======\n" + request + "\n====== Response ======\n" + response,
+		Spans:      &spans,
+	}
+
+	if sample.ExpectedCode != 0 {
+		result.Passed = wr.Code == sample.ExpectedCode
+	} else {
+		result.Passed = wr.Code < http.StatusBadRequest
+	}
+
+	for _, span := range recorder.spans {
+		if span.Kind != otlpSpanKindInternal {
+			continue
+		}
+		result.Middleware = append(result.Middleware, span.Name)
+		if span.Status.Code == otlpStatusError || hasOTLPAttribute(span.Attributes, "tyk.middleware.responded") {
+			result.RejectedBy = span.Name
+		}
+	}
 
-	doJSONWrite(w, http.StatusOK, traceResponse{Message: "ok", Response: requestDump, Logs: logStorage.String()})
+	return result, nil
 }
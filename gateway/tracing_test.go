@@ -1,11 +1,18 @@
 package gateway
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"testing"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/TykTechnologies/tyk/test"
 )
 
 func TestTraceHttpRequest_toRequest(t *testing.T) {
@@ -27,3 +34,181 @@ func TestTraceHttpRequest_toRequest(t *testing.T) {
 	assert.Equal(t, header, request.Header)
 	assert.Equal(t, string(bodyInBytes), body)
 }
+
+func TestTraceSpans(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := BuildAPI(func(spec *APISpec) {
+		spec.UseKeylessAccess = false
+	})[0]
+
+	keyID := CreateSession(ts.Gw)
+
+	trace := func(t *testing.T, headers http.Header) []otlpSpan {
+		t.Helper()
+
+		resp, err := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusOK,
+			Data: traceRequest{Spec: spec.APIDefinition, Request: &traceHttpRequest{Method: http.MethodGet, Path: "/", Headers: headers}}})
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var traceResp traceResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&traceResp))
+		require.NotNil(t, traceResp.Spans)
+		require.Len(t, traceResp.Spans.ResourceSpans, 1)
+		require.Len(t, traceResp.Spans.ResourceSpans[0].ScopeSpans, 1)
+
+		spans := traceResp.Spans.ResourceSpans[0].ScopeSpans[0].Spans
+		require.NotEmpty(t, spans)
+		for _, span := range spans[1:] {
+			assert.Equal(t, spans[0].TraceID, span.TraceID)
+			assert.Equal(t, spans[0].SpanID, span.ParentSpanID)
+		}
+
+		return spans
+	}
+
+	names := func(spans []otlpSpan) []string {
+		var names []string
+		for _, span := range spans {
+			names = append(names, span.Name)
+		}
+		return names
+	}
+
+	t.Run("rejected", func(t *testing.T) {
+		spans := trace(t, nil)
+
+		assert.Equal(t, otlpSpanKindServer, spans[0].Kind)
+		assert.Equal(t, otlpStatusError, spans[0].Status.Code)
+		assert.Contains(t, spans[0].Attributes, otlpInt("http.status_code", http.StatusUnauthorized))
+
+		last := spans[len(spans)-1]
+		assert.Equal(t, "AuthKey", last.Name)
+		assert.Equal(t, otlpStatusError, last.Status.Code)
+		assert.NotContains(t, names(spans), "proxy")
+	})
+
+	t.Run("proxied", func(t *testing.T) {
+		spans := trace(t, http.Header{"Authorization": {keyID}})
+
+		assert.Equal(t, otlpStatusOK, spans[0].Status.Code)
+		assert.Subset(t, names(spans), []string{"AuthKey", "KeyExpired", "AccessRightsCheck", "proxy"})
+		assert.Equal(t, "proxy", spans[len(spans)-1].Name)
+		assert.Equal(t, otlpSpanKindClient, spans[len(spans)-1].Kind)
+	})
+}
+
+func TestTraceBatch(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	spec := BuildAPI(func(spec *APISpec) {
+		spec.UseKeylessAccess = false
+	})[0]
+
+	keyID := CreateSession(ts.Gw)
+
+	resp, err := ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusOK,
+		Data: traceRequest{Spec: spec.APIDefinition, Requests: []*traceHttpRequest{
+			{Method: http.MethodGet, Path: "/", Headers: http.Header{"Authorization": {keyID}}},
+			{Method: http.MethodGet, Path: "/"},
+			{Method: http.MethodGet, Path: "/", ExpectedCode: http.StatusUnauthorized},
+		}}})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var traceResp traceResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&traceResp))
+
+	assert.Equal(t, &traceSummary{Total: 3, Passed: 2, Failed: 1, StatusCodes: map[int]int{http.StatusOK: 1, http.StatusUnauthorized: 2}}, traceResp.Summary)
+	require.Len(t, traceResp.Results, 3)
+
+	assert.True(t, traceResp.Results[0].Passed)
+	assert.Contains(t, traceResp.Results[0].Middleware, "AuthKey")
+	assert.Empty(t, traceResp.Results[0].RejectedBy)
+	assert.Contains(t, traceResp.Results[0].Response, "200 OK")
+
+	assert.False(t, traceResp.Results[1].Passed)
+	assert.Equal(t, http.StatusUnauthorized, traceResp.Results[1].Code)
+	assert.Equal(t, "AuthKey", traceResp.Results[1].RejectedBy)
+
+	assert.True(t, traceResp.Results[2].Passed)
+
+	_, _ = ts.Run(t, test.TestCase{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusBadRequest,
+		Data:      `{"spec": {"api_id": "a", "proxy": {"listen_path": "/", "target_url": "http://localhost"}}, "requests": [null]}`,
+		BodyMatch: "Request 0 is missing"})
+}
+
+func TestTraceOAS(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	oasAPI := oas.OAS{T: openapi3.T{
+		OpenAPI: "3.0.3",
+		Info:    &openapi3.Info{Title: "oas api", Version: "1"},
+		Paths:   openapi3.Paths{},
+	}}
+	oasAPI.SetTykExtension(&oas.XTykAPIGateway{
+		Info:     oas.Info{Name: "oas api", State: oas.State{Active: true}},
+		Upstream: oas.Upstream{URL: TestHttpAny},
+		Server: oas.Server{
+			ListenPath: oas.ListenPath{Value: "/oas/", Strip: true},
+			Authentication: &oas.Authentication{
+				Enabled:              true,
+				SecuritySchemes:      oas.SecuritySchemes{"token": &oas.Token{Enabled: true}},
+				BaseIdentityProvider: apidef.AuthToken,
+			},
+		},
+	})
+	oasAPI.Components = &openapi3.Components{SecuritySchemes: openapi3.SecuritySchemes{
+		"token": {Value: openapi3.NewSecurityScheme().WithType("apiKey").WithIn("header").WithName("Authorization")},
+	}}
+	oasAPI.Security = openapi3.SecurityRequirements{{"token": []string{}}}
+
+	keyless := oas.OAS{T: openapi3.T{OpenAPI: "3.0.3", Info: &openapi3.Info{Title: "plain", Version: "1"}, Paths: openapi3.Paths{}}}
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusOK, BodyMatch: `401 Unauthorized`,
+			Data: traceRequest{OAS: &oasAPI, Request: &traceHttpRequest{Method: http.MethodGet, Path: "/oas/"}}},
+		{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusBadRequest,
+			BodyMatch: apidef.ErrPayloadWithoutTykExtension.Error(),
+			Data:      traceRequest{OAS: &keyless, Request: &traceHttpRequest{Method: http.MethodGet, Path: "/"}}},
+		{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusBadRequest, BodyMatch: "Only one of",
+			Data: traceRequest{OAS: &oasAPI, Spec: &apidef.APIDefinition{}, Request: &traceHttpRequest{Method: http.MethodGet, Path: "/"}}},
+	}...)
+}
+
+func TestTraceOAS_validateRequest(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	oasDoc, err := openapi3.NewLoader().LoadFromData([]byte(testOASForValidateRequest))
+	require.NoError(t, err)
+
+	oasAPI := oas.OAS{T: *oasDoc}
+	oasAPI.SetTykExtension(&oas.XTykAPIGateway{
+		Info:     oas.Info{Name: "validated", State: oas.State{Active: true}},
+		Upstream: oas.Upstream{URL: TestHttpAny},
+		Server: oas.Server{
+			ListenPath:     oas.ListenPath{Value: "/product/", Strip: true},
+			Authentication: &oas.Authentication{Enabled: false},
+		},
+		Middleware: &oas.Middleware{
+			Operations: oas.Operations{
+				"postpost": {ValidateRequest: &oas.ValidateRequest{Enabled: true}},
+			},
+		},
+	})
+
+	headers := http.Header{"Content-Type": []string{"application/json"}}
+
+	// the OAS only middleware runs in the trace as it does for the loaded API
+	_, _ = ts.Run(t, []test.TestCase{
+		{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusOK, BodyMatch: `422 Unprocessable Entity`,
+			Data: traceRequest{OAS: &oasAPI, Request: &traceHttpRequest{Method: http.MethodPost, Path: "/product/post", Body: `{"name": 123}`, Headers: headers}}},
+		{Method: http.MethodPost, Path: "/tyk/debug", AdminAuth: true, Code: http.StatusOK, BodyMatch: `200 OK`,
+			Data: traceRequest{OAS: &oasAPI, Request: &traceHttpRequest{Method: http.MethodPost, Path: "/product/post", Body: `{"name": "my-product"}`, Headers: headers}}},
+	}...)
+}
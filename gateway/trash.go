@@ -0,0 +1,319 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+
+	"github.com/TykTechnologies/tyk/internal/model"
+	"github.com/TykTechnologies/tyk/storage"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+const (
+	// trashPrefix prefixes the Redis keys of the deleted APIs and keys.
+	trashPrefix = "trash-"
+	// trashIndex is the Redis set holding the names of the trashed items.
+	trashIndex = "index"
+
+	trashKindAPI = "api"
+	trashKindKey = "key"
+
+	defaultTrashRetentionPeriod = 24 * 60 * 60
+)
+
+var (
+	errTrashItemNotFound = errors.New("Item not found in the trash")
+	errTrashItemExists   = errors.New("Item already exists, delete it before restoring it")
+)
+
+// trashItem is a deleted API or key, kept until it expires.
+type trashItem struct {
+	Kind string `json:"kind"`
+	// ID is the API ID, or the storage key of the key. It's hashed when keys are hashed.
+	ID        string    `json:"id"`
+	OrgID     string    `json:"org_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	API     *model.MergedAPI   `json:"api,omitempty"`
+	Session *user.SessionState `json:"session,omitempty"`
+}
+
+func (t *trashItem) name() string {
+	return t.Kind + "-" + t.ID
+}
+
+func (gw *Gateway) trashStore() *storage.RedisCluster {
+	return &storage.RedisCluster{KeyPrefix: trashPrefix, ConnectionHandler: gw.StorageConnectionHandler}
+}
+
+func (gw *Gateway) trashRetentionPeriod() int64 {
+	if period := gw.GetConfig().Trash.RetentionPeriod; period > 0 {
+		return period
+	}
+	return defaultTrashRetentionPeriod
+}
+
+// newAPITrashItem returns the trash item of an API about to be deleted, nil when the trash is disabled.
+func (gw *Gateway) newAPITrashItem(apiID string) *trashItem {
+	if !gw.GetConfig().Trash.Enabled {
+		return nil
+	}
+
+	spec := gw.getApiSpec(apiID)
+	if spec == nil {
+		return nil
+	}
+
+	return &trashItem{Kind: trashKindAPI, ID: spec.APIID, OrgID: spec.OrgID, Name: spec.Name, API: spec.mergedAPI()}
+}
+
+// newKeyTrashItem returns the trash item of a key about to be deleted, nil when the trash is disabled.
+func (gw *Gateway) newKeyTrashItem(keyName, orgID string, hashed bool) *trashItem {
+	if !gw.GetConfig().Trash.Enabled {
+		return nil
+	}
+
+	session, ok := gw.GlobalSessionManager.SessionDetail(orgID, keyName, hashed)
+	if !ok {
+		return nil
+	}
+
+	// keep the storage key, so that keys aren't stored in clear when they're hashed
+	id := session.KeyID
+	if !hashed {
+		id = storage.HashKey(session.KeyID, gw.GetConfig().HashKeys)
+	}
+
+	clone := session.Clone()
+	return &trashItem{Kind: trashKindKey, ID: id, OrgID: session.OrgID, Name: session.Alias, Session: &clone}
+}
+
+// trash keeps a deleted item until the end of the retention period.
+func (gw *Gateway) trash(item *trashItem) {
+	if item == nil {
+		return
+	}
+
+	period := gw.trashRetentionPeriod()
+	item.DeletedAt = time.Now().UTC()
+	item.ExpiresAt = item.DeletedAt.Add(time.Duration(period) * time.Second)
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		log.WithError(err).Error("Couldn't marshal trash item")
+		return
+	}
+
+	store := gw.trashStore()
+	if err := store.SetKey(item.name(), string(data), period); err != nil {
+		log.WithError(err).Error("Couldn't store trash item")
+		return
+	}
+	store.AddToSet(trashIndex, item.name())
+}
+
+func (gw *Gateway) getTrashItem(kind, id string) (*trashItem, error) {
+	data, err := gw.trashStore().GetKey(kind + "-" + id)
+	if err != nil {
+		if errors.Is(err, storage.ErrKeyNotFound) {
+			return nil, errTrashItemNotFound
+		}
+		return nil, err
+	}
+
+	item := &trashItem{}
+	if err := json.Unmarshal([]byte(data), item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// listTrash returns the trashed items of a kind, or of all kinds, the most recently deleted first.
+// The expired items are dropped from the index.
+func (gw *Gateway) listTrash(kind string) ([]*trashItem, error) {
+	store := gw.trashStore()
+	names, err := store.GetSet(trashIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []*trashItem{}
+	for _, name := range names {
+		itemKind, id, _ := strings.Cut(name, "-")
+		item, err := gw.getTrashItem(itemKind, id)
+		if errors.Is(err, errTrashItemNotFound) {
+			store.RemoveFromSet(trashIndex, name)
+			continue
+		}
+		if err != nil || (kind != "" && item.Kind != kind) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+func (gw *Gateway) purgeTrashItem(item *trashItem) {
+	store := gw.trashStore()
+	store.DeleteKey(item.name())
+	store.RemoveFromSet(trashIndex, item.name())
+}
+
+// restoreAPI writes the definition files of a trashed API back. As for added APIs, the API is
+// loaded with the next reload. Version APIs must be added back to their base API.
+func (gw *Gateway) restoreAPI(item *trashItem) error {
+	if item.API == nil || item.API.APIDefinition == nil {
+		return errTrashItemNotFound
+	}
+
+	if gw.getApiSpec(item.ID) != nil {
+		return errTrashItemExists
+	}
+	if _, err := os.Stat(filepath.Join(gw.GetConfig().AppPath, item.ID+".json")); err == nil {
+		return errTrashItemExists
+	}
+
+	fs := afero.NewOsFs()
+	var err error
+	if item.API.OAS != nil {
+		err, _ = gw.writeOASAndAPIDefToFile(fs, item.API.APIDefinition, item.API.OAS)
+	} else {
+		err, _ = gw.writeToFile(fs, item.API.APIDefinition, item.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	gw.FireSystemEvent(EventAPIRestored, EventAPIRestoredMeta{
+		EventMetaDefault: EventMetaDefault{Message: "API restored."},
+		Org:              item.OrgID,
+		APIID:            item.ID,
+	})
+
+	return nil
+}
+
+// restoreKey stores a trashed key back, with its lifetime.
+func (gw *Gateway) restoreKey(item *trashItem) error {
+	if item.Session == nil {
+		return errTrashItemNotFound
+	}
+
+	if _, ok := gw.GlobalSessionManager.SessionDetail(item.OrgID, item.ID, true); ok {
+		return errTrashItemExists
+	}
+
+	lifetime := gw.ApplyLifetime(item.Session, nil)
+	if err := gw.GlobalSessionManager.UpdateSession(item.ID, item.Session, lifetime, true); err != nil {
+		return err
+	}
+
+	gw.FireSystemEvent(EventTokenRestored, EventTokenMeta{
+		EventMetaDefault: EventMetaDefault{Message: "Key restored."},
+		Org:              item.OrgID,
+		Key:              item.ID,
+	})
+
+	return nil
+}
+
+// trashHandler lists the trashed APIs and keys, filtered with the kind query parameter, and
+// empties the trash.
+func (gw *Gateway) trashHandler(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != trashKindAPI && kind != trashKindKey {
+		doJSONWrite(w, http.StatusBadRequest, apiError("kind must be api or key"))
+		return
+	}
+
+	items, err := gw.listTrash(kind)
+	if err != nil {
+		log.WithError(err).Error("Couldn't list the trash")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't list the trash"))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		for _, item := range items {
+			gw.purgeTrashItem(item)
+		}
+		doJSONWrite(w, http.StatusOK, apiOk("purged"))
+		return
+	}
+
+	// the definitions and sessions are returned by the item endpoint
+	summaries := make([]trashItem, 0, len(items))
+	for _, item := range items {
+		summary := *item
+		summary.API = nil
+		summary.Session = nil
+		summaries = append(summaries, summary)
+	}
+
+	doJSONWrite(w, http.StatusOK, summaries)
+}
+
+// trashItemHandler returns and purges a trashed API or key.
+func (gw *Gateway) trashItemHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	item, err := gw.getTrashItem(vars["kind"], vars["id"])
+	if err != nil {
+		doJSONWrite(w, http.StatusNotFound, apiError(errTrashItemNotFound.Error()))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		gw.purgeTrashItem(item)
+		doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{Key: item.ID, Status: "ok", Action: "purged"})
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, item)
+}
+
+// trashRestoreHandler restores a trashed API or key and removes it from the trash.
+func (gw *Gateway) trashRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	item, err := gw.getTrashItem(vars["kind"], vars["id"])
+	if err != nil {
+		doJSONWrite(w, http.StatusNotFound, apiError(errTrashItemNotFound.Error()))
+		return
+	}
+
+	if item.Kind == trashKindAPI {
+		err = gw.restoreAPI(item)
+	} else {
+		err = gw.restoreKey(item)
+	}
+
+	switch {
+	case errors.Is(err, errTrashItemExists):
+		doJSONWrite(w, http.StatusConflict, apiError(err.Error()))
+		return
+	case err != nil:
+		log.WithError(err).WithField("kind", item.Kind).Error("Couldn't restore trash item")
+		doJSONWrite(w, http.StatusInternalServerError, apiError("Couldn't restore the item"))
+		return
+	}
+
+	gw.purgeTrashItem(item)
+
+	log.WithField("kind", item.Kind).WithField("id", gw.obfuscateKey(item.ID)).Info("Restored item from the trash")
+	doJSONWrite(w, http.StatusOK, apiModifyKeySuccess{Key: item.ID, Status: "ok", Action: "restored"})
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/test"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+func TestTrash(t *testing.T) {
+	ts := StartTest(func(globalConf *config.Config) {
+		globalConf.Trash.Enabled = true
+	})
+	defer ts.Close()
+
+	list := func(t *testing.T, kind string) []trashItem {
+		t.Helper()
+
+		resp, _ := ts.Run(t, test.TestCase{Path: "/tyk/trash?kind=" + kind, AdminAuth: true, Code: http.StatusOK})
+		var items []trashItem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&items))
+		return items
+	}
+
+	createSession := func(alias string) string {
+		_, key := ts.CreateSession(func(s *user.SessionState) {
+			s.Alias = alias
+			s.AccessRights = map[string]user.AccessDefinition{"trashed": {APIID: "trashed", Versions: []string{"Default"}}}
+		})
+		return key
+	}
+
+	t.Run("api", func(t *testing.T) {
+		api := BuildAPI(func(spec *APISpec) {
+			spec.APIID = "trashed"
+			spec.Proxy.ListenPath = "/trashed/"
+		})[0]
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/apis", Data: &api, Code: http.StatusOK},
+		}...)
+		ts.Gw.DoReload()
+
+		_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/apis/trashed", Code: http.StatusOK})
+		ts.Gw.DoReload()
+
+		items := list(t, trashKindAPI)
+		require.Len(t, items, 1)
+		assert.Equal(t, "trashed", items[0].ID)
+		assert.Nil(t, items[0].API, "the list doesn't include the definitions")
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Path: "/tyk/trash/api/trashed", BodyMatch: `"listen_path":"/trashed/"`, Code: http.StatusOK},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/trash/api/trashed/restore", BodyMatch: `"action":"restored"`, Code: http.StatusOK},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/trash/api/trashed/restore", Code: http.StatusNotFound},
+		}...)
+
+		ts.Gw.DoReload()
+		assert.NotNil(t, ts.Gw.getApiSpec("trashed"))
+		assert.Empty(t, list(t, trashKindAPI))
+	})
+
+	t.Run("key", func(t *testing.T) {
+		key := createSession("trashed")
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/keys/" + key, Code: http.StatusOK},
+			{AdminAuth: true, Path: "/tyk/keys/" + key, Code: http.StatusNotFound},
+		}...)
+
+		items := list(t, trashKindKey)
+		require.Len(t, items, 1)
+		assert.Equal(t, "trashed", items[0].Name)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/trash/key/" + items[0].ID + "/restore", Code: http.StatusOK},
+			{AdminAuth: true, Path: "/tyk/keys/" + key, BodyMatch: `"alias":"trashed"`, Code: http.StatusOK},
+		}...)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		first, second := createSession("first"), createSession("second")
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/keys/" + first, Code: http.StatusOK},
+			{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/keys/" + second, Code: http.StatusOK},
+		}...)
+
+		items := list(t, "")
+		require.Len(t, items, 2)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/trash/key/" + items[0].ID, BodyMatch: `"action":"purged"`, Code: http.StatusOK},
+			{AdminAuth: true, Method: http.MethodPost, Path: "/tyk/trash/key/" + items[0].ID + "/restore", Code: http.StatusNotFound},
+		}...)
+		assert.Len(t, list(t, ""), 1)
+
+		_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/trash", Code: http.StatusOK})
+		assert.Empty(t, list(t, ""))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		globalConf := ts.Gw.GetConfig()
+		globalConf.Trash.Enabled = false
+		ts.Gw.SetConfig(globalConf)
+		defer func() {
+			globalConf.Trash.Enabled = true
+			ts.Gw.SetConfig(globalConf)
+		}()
+
+		_, _ = ts.Run(t, test.TestCase{AdminAuth: true, Method: http.MethodDelete, Path: "/tyk/keys/" + createSession("disabled"), Code: http.StatusOK})
+		assert.Empty(t, list(t, ""))
+	})
+}
@@ -0,0 +1,412 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/apidef"
+)
+
+const (
+	// staleCacheKeySuffix suffixes the cache keys of the copies of the cached responses kept after
+	// they expire, to be served while the API is degraded. The copies keep the API ID prefix of
+	// the cache keys, so that they are invalidated with the cache of the API.
+	staleCacheKeySuffix = "-stale"
+
+	// degradedResponseHeader is set on the responses served while the API is degraded.
+	degradedResponseHeader = "x-tyk-degraded-response"
+
+	defaultErrorBudgetWindow        = 60 * time.Second
+	defaultErrorBudgetMinRequests   = 10
+	defaultErrorBudgetFailureRatio  = 0.5
+	defaultErrorBudgetProbeInterval = 10 * time.Second
+	defaultErrorBudgetStaleTTL      = 86400
+	defaultErrorBudgetFallbackCode  = http.StatusServiceUnavailable
+
+	upstreamProbeTimeout = 5 * time.Second
+)
+
+// upstreamErrorBudget counts the connect failures of the upstream of an API over a sliding
+// window, with one bucket per second, and degrades the API past its budget.
+type upstreamErrorBudget struct {
+	apiID string
+
+	mu       sync.Mutex
+	conf     apidef.UpstreamErrorBudget
+	buckets  []errorBudgetBucket
+	degraded bool
+	since    time.Time
+}
+
+type errorBudgetBucket struct {
+	second   int64
+	requests int
+	failures int
+}
+
+func newUpstreamErrorBudget(apiID string, conf apidef.UpstreamErrorBudget) *upstreamErrorBudget {
+	b := &upstreamErrorBudget{apiID: apiID}
+	b.configure(conf)
+	return b
+}
+
+func (b *upstreamErrorBudget) configure(conf apidef.UpstreamErrorBudget) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.conf = conf
+	b.buckets = make([]errorBudgetBucket, int(b.window()/time.Second))
+}
+
+func (b *upstreamErrorBudget) window() time.Duration {
+	if b.conf.Window > 0 {
+		return time.Duration(b.conf.Window) * time.Second
+	}
+	return defaultErrorBudgetWindow
+}
+
+func (b *upstreamErrorBudget) probeInterval() time.Duration {
+	if b.conf.ProbeInterval > 0 {
+		return time.Duration(b.conf.ProbeInterval) * time.Second
+	}
+	return defaultErrorBudgetProbeInterval
+}
+
+func (b *upstreamErrorBudget) serveStale() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.conf.ServeStale
+}
+
+// staleTTL returns the number of seconds the cached responses are kept after they expire.
+func (b *upstreamErrorBudget) staleTTL() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conf.StaleTTL > 0 {
+		return b.conf.StaleTTL
+	}
+	return defaultErrorBudgetStaleTTL
+}
+
+func (b *upstreamErrorBudget) isDegraded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.degraded
+}
+
+// record counts a request to the upstream, and returns true when its connect failure degrades
+// the API, with the requests and the failures of the window.
+func (b *upstreamErrorBudget) record(now time.Time, failed bool) (degraded bool, requests, failures int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	second := now.Unix()
+	bucket := &b.buckets[second%int64(len(b.buckets))]
+	if bucket.second != second {
+		*bucket = errorBudgetBucket{second: second}
+	}
+
+	bucket.requests++
+	if failed {
+		bucket.failures++
+	}
+
+	if b.degraded || !failed {
+		return false, 0, 0
+	}
+
+	oldest := second - int64(len(b.buckets))
+	for _, bucket := range b.buckets {
+		if bucket.second > oldest {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+
+	minRequests := b.conf.MinRequests
+	if minRequests <= 0 {
+		minRequests = defaultErrorBudgetMinRequests
+	}
+
+	ratio := b.conf.FailureRatio
+	if ratio <= 0 {
+		ratio = defaultErrorBudgetFailureRatio
+	}
+
+	if requests < minRequests || float64(failures)/float64(requests) < ratio {
+		return false, 0, 0
+	}
+
+	b.degraded = true
+	b.since = now
+	return true, requests, failures
+}
+
+// recover returns the API to service, and returns when it was degraded.
+func (b *upstreamErrorBudget) recover() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.degraded = false
+	for i := range b.buckets {
+		b.buckets[i] = errorBudgetBucket{}
+	}
+
+	return b.since
+}
+
+// loadUpstreamErrorBudget returns the error budget of the upstream of an API, nil when disabled.
+// The budgets are kept across the API reloads, so that a reload doesn't send the traffic back to
+// a degraded upstream.
+func (gw *Gateway) loadUpstreamErrorBudget(spec *APISpec) *upstreamErrorBudget {
+	gw.upstreamBudgetsMu.Lock()
+	defer gw.upstreamBudgetsMu.Unlock()
+
+	conf := spec.UpstreamErrorBudget
+	if !conf.Enabled {
+		delete(gw.upstreamBudgets, spec.APIID)
+		return nil
+	}
+
+	if gw.upstreamBudgets == nil {
+		gw.upstreamBudgets = make(map[string]*upstreamErrorBudget)
+	}
+
+	budget, ok := gw.upstreamBudgets[spec.APIID]
+	if !ok {
+		budget = newUpstreamErrorBudget(spec.APIID, conf)
+		gw.upstreamBudgets[spec.APIID] = budget
+	} else if !reflect.DeepEqual(budget.currentConf(), conf) {
+		budget.configure(conf)
+	}
+
+	return budget
+}
+
+// pruneUpstreamErrorBudgets removes the error budgets of the APIs which aren't loaded anymore.
+func (gw *Gateway) pruneUpstreamErrorBudgets(specs []*APISpec) {
+	gw.upstreamBudgetsMu.Lock()
+	defer gw.upstreamBudgetsMu.Unlock()
+
+	loaded := make(map[string]struct{}, len(specs))
+	for _, spec := range specs {
+		loaded[spec.APIID] = struct{}{}
+	}
+
+	for apiID := range gw.upstreamBudgets {
+		if _, ok := loaded[apiID]; !ok {
+			delete(gw.upstreamBudgets, apiID)
+		}
+	}
+}
+
+func staleCacheKey(key string) string {
+	return key + staleCacheKeySuffix
+}
+
+func (b *upstreamErrorBudget) currentConf() apidef.UpstreamErrorBudget {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.conf
+}
+
+// isUpstreamConnectError returns true when an error of the upstream round trip is a failure to
+// connect to the upstream: dialing, resolving its host or the TLS handshake.
+func isUpstreamConnectError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &dnsErr) || errors.As(err, &recordErr) || errors.As(err, &certErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "tls: ")
+}
+
+// recordUpstreamRequest counts a request to the upstream of an API in its error budget, and
+// degrades the API past the budget.
+func (gw *Gateway) recordUpstreamRequest(spec *APISpec, budget *upstreamErrorBudget, err error) {
+	degraded, requests, failures := budget.record(time.Now(), isUpstreamConnectError(err))
+	if !degraded {
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"api_id":   spec.APIID,
+		"requests": requests,
+		"failures": failures,
+	}).Warning("Upstream exceeded its error budget, the API is degraded")
+
+	spec.FireEvent(EventUpstreamDegraded, EventUpstreamErrorBudgetMeta{
+		EventMetaDefault: EventMetaDefault{Message: "Upstream exceeded its error budget, the API is degraded."},
+		APIID:            spec.APIID,
+		Requests:         requests,
+		Failures:         failures,
+		DegradedSince:    time.Now(),
+	})
+
+	go gw.probeDegradedUpstream(budget)
+}
+
+// probeDegradedUpstream probes the upstream of a degraded API at the probe interval, and returns
+// the API to service when the upstream responds to a probe.
+func (gw *Gateway) probeDegradedUpstream(budget *upstreamErrorBudget) {
+	ticker := time.NewTicker(budget.probeInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-gw.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		spec := gw.getApiSpec(budget.apiID)
+		if spec == nil || spec.upstreamErrorBudget != budget {
+			// the API was removed, or its budget disabled
+			return
+		}
+
+		if err := gw.probeUpstream(gw.ctx, spec); err != nil {
+			log.WithError(err).WithField("api_id", spec.APIID).Debug("Degraded upstream recovery probe failed")
+			continue
+		}
+
+		since := budget.recover()
+		log.WithField("api_id", spec.APIID).Info("Upstream responded to the recovery probe, the API is back in service")
+
+		spec.FireEvent(EventUpstreamRecovered, EventUpstreamErrorBudgetMeta{
+			EventMetaDefault: EventMetaDefault{Message: "Upstream recovered, the API is back in service."},
+			APIID:            spec.APIID,
+			DegradedSince:    since,
+		})
+		return
+	}
+}
+
+// probeUpstream sends a HEAD request to the upstream targets of an API, through the transport of
+// the API: with its proxy, its upstream certificates and its TLS settings, to the targets of its
+// service discovery. It returns nil when one of them responds, whatever the response status.
+func (gw *Gateway) probeUpstream(ctx context.Context, spec *APISpec) error {
+	targets := []string{spec.Proxy.TargetURL}
+	switch {
+	case spec.Proxy.ServiceDiscovery.UseDiscoveryService:
+		hostList, err := urlFromService(spec, gw)
+		if err != nil {
+			return err
+		}
+		targets = hostList.All()
+	case spec.Proxy.EnableLoadBalancing && spec.Proxy.StructuredTargetList != nil && spec.Proxy.StructuredTargetList.Len() > 0:
+		targets = spec.Proxy.StructuredTargetList.All()
+	}
+
+	err := errors.New("the upstream has no target to probe")
+	for _, target := range targets {
+		u, parseErr := url.Parse(target)
+		if parseErr != nil {
+			err = parseErr
+			continue
+		}
+
+		switch u.Scheme {
+		case "http", "https", "h2c":
+		case "ws":
+			u.Scheme = "http"
+		case "wss":
+			u.Scheme = "https"
+		default:
+			// the internal and the non-HTTP upstreams can't be probed
+			return nil
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, upstreamProbeTimeout)
+		err = gw.probeUpstreamTarget(probeCtx, spec, u)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (gw *Gateway) probeUpstreamTarget(ctx context.Context, spec *APISpec, target *url.URL) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	proxy := &ReverseProxy{TykAPISpec: spec, Gw: gw, logger: log.WithFields(logrus.Fields{"mw": "ReverseProxy", "api_id": spec.APIID})}
+	roundTripper := proxy.httpTransport(upstreamProbeTimeout.Seconds(), nil, req, req)
+	defer roundTripper.transport.CloseIdleConnections()
+
+	if cert := gw.getUpstreamCertificate(req.URL.Host, spec); cert != nil {
+		roundTripper.transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+	}
+
+	if req.URL.Scheme == "h2c" {
+		req.URL.Scheme = "http"
+	}
+
+	res, err := roundTripper.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
+// serveDegraded responds to a request to a degraded API, which wasn't served from the stale
+// cached responses, with the fallback response.
+func (p *ReverseProxy) serveDegraded(rw http.ResponseWriter, req *http.Request) {
+	conf := p.TykAPISpec.UpstreamErrorBudget
+
+	code := conf.FallbackCode
+	if code == 0 {
+		code = defaultErrorBudgetFallbackCode
+	}
+
+	for name, value := range conf.FallbackHeaders {
+		rw.Header().Set(name, value)
+	}
+	rw.Header().Set(degradedResponseHeader, "1")
+
+	if conf.FallbackBody == "" {
+		p.ErrorHandler.HandleError(rw, req, "Service temporarily unavailable.", code, true)
+		return
+	}
+
+	rw.WriteHeader(code)
+	rw.Write([]byte(conf.FallbackBody))
+
+	// record the analytics of the fallback response
+	p.ErrorHandler.HandleError(rw, req, "Service temporarily unavailable.", code, false)
+}
@@ -0,0 +1,274 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/crypto"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestUpstreamErrorBudget_record(t *testing.T) {
+	budget := newUpstreamErrorBudget("api", apidef.UpstreamErrorBudget{Enabled: true, Window: 10, MinRequests: 4, FailureRatio: 0.5})
+
+	now := time.Now().Truncate(time.Second)
+	at := func(seconds int) time.Time { return now.Add(time.Duration(seconds) * time.Second) }
+
+	degraded, _, _ := budget.record(at(0), true)
+	assert.False(t, degraded, "the budget applies from the min requests")
+
+	budget.record(at(5), false)
+	budget.record(at(6), false)
+	degraded, _, _ = budget.record(at(7), false)
+	assert.False(t, degraded, "the successes don't degrade the API")
+
+	// the first failure left the window
+	degraded, _, _ = budget.record(at(11), true)
+	assert.False(t, degraded)
+	degraded, _, _ = budget.record(at(11), true)
+	assert.False(t, degraded)
+
+	degraded, requests, failures := budget.record(at(11), true)
+	assert.True(t, degraded)
+	assert.Equal(t, 6, requests)
+	assert.Equal(t, 3, failures)
+	assert.True(t, budget.isDegraded())
+
+	degraded, _, _ = budget.record(at(11), true)
+	assert.False(t, degraded, "the API is degraded once")
+
+	assert.Equal(t, at(11), budget.recover())
+	assert.False(t, budget.isDegraded())
+	degraded, _, _ = budget.record(at(12), true)
+	assert.False(t, degraded, "the window is reset on recovery")
+}
+
+func TestGateway_loadUpstreamErrorBudget(t *testing.T) {
+	gw := &Gateway{}
+	spec := &APISpec{APIDefinition: &apidef.APIDefinition{
+		APIID:               "api",
+		UpstreamErrorBudget: apidef.UpstreamErrorBudget{Enabled: true, MinRequests: 1},
+	}}
+
+	budget := gw.loadUpstreamErrorBudget(spec)
+	require.NotNil(t, budget)
+	budget.record(time.Now(), true)
+	require.True(t, budget.isDegraded())
+
+	assert.Same(t, budget, gw.loadUpstreamErrorBudget(spec), "the budget is kept across the reloads")
+	assert.True(t, budget.isDegraded())
+
+	spec.UpstreamErrorBudget.Enabled = false
+	assert.Nil(t, gw.loadUpstreamErrorBudget(spec))
+	assert.Empty(t, gw.upstreamBudgets)
+
+	spec.UpstreamErrorBudget.Enabled = true
+	gw.loadUpstreamErrorBudget(spec)
+	gw.pruneUpstreamErrorBudgets([]*APISpec{})
+	assert.Empty(t, gw.upstreamBudgets, "the budgets of the unloaded APIs are removed")
+}
+
+func TestIsUpstreamConnectError(t *testing.T) {
+	assert.True(t, isUpstreamConnectError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.True(t, isUpstreamConnectError(&net.DNSError{Err: "no such host", Name: "upstream"}))
+	assert.True(t, isUpstreamConnectError(errors.New("remote error: tls: handshake failure")))
+	assert.False(t, isUpstreamConnectError(&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}))
+	assert.False(t, isUpstreamConnectError(errors.New("context canceled")))
+	assert.False(t, isUpstreamConnectError(nil))
+}
+
+func TestUpstreamErrorBudget(t *testing.T) {
+	degradedEvents := make(chan EventUpstreamErrorBudgetMeta, 1)
+	recoveredEvents := make(chan EventUpstreamErrorBudgetMeta, 1)
+
+	eventPaths := map[apidef.TykEvent][]config.TykEventHandler{
+		EventUpstreamDegraded: {&testEventHandler{func(em config.EventMessage) {
+			degradedEvents <- em.Meta.(EventUpstreamErrorBudgetMeta)
+		}}},
+		EventUpstreamRecovered: {&testEventHandler{func(em config.EventMessage) {
+			recoveredEvents <- em.Meta.(EventUpstreamErrorBudgetMeta)
+		}}},
+	}
+
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	serve := func(listener net.Listener) *http.Server {
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte("upstream"))
+		})}
+		go server.Serve(listener)
+		return server
+	}
+
+	listen := func(addr string) net.Listener {
+		listener, err := net.Listen("tcp", addr)
+		require.NoError(t, err)
+		return listener
+	}
+
+	waitEvent := func(t *testing.T, events chan EventUpstreamErrorBudgetMeta) EventUpstreamErrorBudgetMeta {
+		t.Helper()
+		select {
+		case meta := <-events:
+			return meta
+		case <-time.After(5 * time.Second):
+			t.Fatal("the event wasn't fired")
+		}
+		return EventUpstreamErrorBudgetMeta{}
+	}
+
+	t.Run("fallback and recovery", func(t *testing.T) {
+		listener := listen("127.0.0.1:0")
+		addr := listener.Addr().String()
+		upstream := serve(listener)
+
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "degraded-api"
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = "http://" + addr
+			spec.UpstreamErrorBudget = apidef.UpstreamErrorBudget{
+				Enabled:         true,
+				MinRequests:     2,
+				FailureRatio:    0.5,
+				ProbeInterval:   1,
+				FallbackBody:    "degraded",
+				FallbackHeaders: map[string]string{"Retry-After": "1"},
+			}
+		})
+		ts.Gw.getApiSpec("degraded-api").EventPaths = eventPaths
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusOK, BodyMatch: "upstream"})
+
+		upstream.Close()
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusInternalServerError})
+
+		meta := waitEvent(t, degradedEvents)
+		assert.Equal(t, "degraded-api", meta.APIID)
+		assert.Equal(t, 2, meta.Requests)
+		assert.Equal(t, 1, meta.Failures)
+
+		_, _ = ts.Run(t, test.TestCase{
+			Path:         "/",
+			Code:         http.StatusServiceUnavailable,
+			BodyMatch:    "degraded",
+			HeadersMatch: map[string]string{degradedResponseHeader: "1", "Retry-After": "1"},
+		})
+
+		// the upstream is back
+		upstream = serve(listen(addr))
+		defer upstream.Close()
+
+		meta = waitEvent(t, recoveredEvents)
+		assert.Equal(t, "degraded-api", meta.APIID)
+		assert.False(t, meta.DegradedSince.IsZero())
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/", Code: http.StatusOK, BodyMatch: "upstream"})
+	})
+
+	t.Run("stale cached responses", func(t *testing.T) {
+		listener := listen("127.0.0.1:0")
+		upstream := serve(listener)
+
+		ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+			spec.APIID = "stale-api"
+			spec.Proxy.ListenPath = "/"
+			spec.Proxy.TargetURL = "http://" + listener.Addr().String()
+			spec.CacheOptions.EnableCache = true
+			spec.CacheOptions.CacheTimeout = 1
+			UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+				v.ExtendedPaths.Cached = []string{"/cached"}
+			})
+			spec.UpstreamErrorBudget = apidef.UpstreamErrorBudget{
+				Enabled:       true,
+				MinRequests:   2,
+				FailureRatio:  0.5,
+				ProbeInterval: 60,
+				ServeStale:    true,
+			}
+		})
+		ts.Gw.getApiSpec("stale-api").EventPaths = eventPaths
+
+		_, _ = ts.Run(t, test.TestCase{Path: "/cached", Code: http.StatusOK, BodyMatch: "upstream"})
+
+		// the cached response expires
+		time.Sleep(2 * time.Second)
+
+		upstream.Close()
+		_, _ = ts.Run(t, test.TestCase{Path: "/uncached", Code: http.StatusInternalServerError})
+		waitEvent(t, degradedEvents)
+
+		_, _ = ts.Run(t, []test.TestCase{
+			{
+				Path:         "/cached",
+				Code:         http.StatusOK,
+				BodyMatch:    "upstream",
+				HeadersMatch: map[string]string{cachedResponseHeader: "1", degradedResponseHeader: "1"},
+			},
+			{Path: "/uncached", Code: http.StatusServiceUnavailable},
+		}...)
+
+		// the stale copies are invalidated with the cache of the API
+		require.True(t, ts.Gw.invalidateAPICache("stale-api"))
+		_, _ = ts.Run(t, test.TestCase{Path: "/cached", Code: http.StatusServiceUnavailable})
+	})
+}
+
+func TestGateway_probeUpstream(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	globalConf := ts.Gw.GetConfig()
+	globalConf.ProxySSLInsecureSkipVerify = true
+	ts.Gw.SetConfig(globalConf)
+
+	_, _, combinedClientPEM, clientCert := crypto.GenCertificate(&x509.Certificate{}, false)
+	clientCert.Leaf, _ = x509.ParseCertificate(clientCert.Certificate[0])
+
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert.Leaf)
+
+	// mutual TLS protected upstream
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	upstream.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MaxVersion: tls.VersionTLS12,
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	clientCertID, err := ts.Gw.CertificateManager.Add(combinedClientPEM, "")
+	require.NoError(t, err)
+	defer ts.Gw.CertificateManager.Delete(clientCertID, "")
+
+	spec := BuildAPI(func(spec *APISpec) {
+		spec.Proxy.TargetURL = upstream.URL
+		spec.UpstreamCertificates = map[string]string{"*": clientCertID}
+	})[0]
+
+	t.Run("upstream certificate", func(t *testing.T) {
+		assert.NoError(t, ts.Gw.probeUpstream(context.Background(), spec))
+	})
+
+	t.Run("without upstream certificate", func(t *testing.T) {
+		spec.UpstreamCertificatesDisabled = true
+		defer func() { spec.UpstreamCertificatesDisabled = false }()
+
+		assert.Error(t, ts.Gw.probeUpstream(context.Background(), spec))
+	})
+}
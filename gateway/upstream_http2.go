@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gocraft/health"
+	"golang.org/x/net/http2"
+)
+
+// configureHTTP2 applies the HTTP/2 configuration of the API to the transport: the ping based
+// health checks of the connections and how the upstream's max concurrent streams are honoured.
+// The errors of the connections, GOAWAY frames included, are logged and counted so that HTTP/2
+// upstreams can be diagnosed.
+func (p *ReverseProxy) configureHTTP2(h2t *http2.Transport) {
+	conf := p.TykAPISpec.Proxy.Transport.HTTP2
+
+	if conf.PingInterval > 0 {
+		h2t.ReadIdleTimeout = time.Duration(conf.PingInterval * float64(time.Second))
+	}
+
+	if conf.PingTimeout > 0 {
+		h2t.PingTimeout = time.Duration(conf.PingTimeout * float64(time.Second))
+	}
+
+	h2t.StrictMaxConcurrentStreams = conf.StrictMaxConcurrentStreams
+
+	logger := p.logger
+	h2t.CountError = func(errType string) {
+		logger.WithField("error", errType).Debug("HTTP/2 upstream connection error")
+
+		if instrumentationEnabled {
+			instrument.NewJob("UpstreamHTTP2").EventKv(errType, health.Kvs{"api_id": p.TykAPISpec.APIID})
+		}
+	}
+}
+
+// limitStreams caps the concurrent requests of the round tripper to the max concurrent streams
+// of the API.
+func (p *ReverseProxy) limitStreams(rt *TykRoundTripper) *TykRoundTripper {
+	if maxStreams := p.TykAPISpec.Proxy.Transport.HTTP2.MaxConcurrentStreams; maxStreams > 0 {
+		rt.streams = make(chan struct{}, maxStreams)
+	}
+
+	return rt
+}
+
+// streamReleaser releases the stream of a response once its body is closed.
+type streamReleaser struct {
+	io.ReadCloser
+
+	once    sync.Once
+	release func()
+}
+
+func (s *streamReleaser) Close() error {
+	err := s.ReadCloser.Close()
+	s.once.Do(s.release)
+	return err
+}
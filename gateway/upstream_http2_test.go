@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestUpstreamHTTP2_PriorKnowledge(t *testing.T) {
+	var active, maxActive int32
+
+	upstream := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		for {
+			seen := atomic.LoadInt32(&maxActive)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxActive, seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer upstream.Close()
+
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.Proxy.ListenPath = "/"
+		spec.Proxy.TargetURL = upstream.URL
+		spec.Proxy.Transport.HTTP2.PriorKnowledge = true
+		spec.Proxy.Transport.HTTP2.PingInterval = 1
+		spec.Proxy.Transport.HTTP2.PingTimeout = 1
+		spec.Proxy.Transport.HTTP2.MaxConcurrentStreams = 1
+	})
+
+	_, _ = ts.Run(t, test.TestCase{Path: "/", BodyMatch: "HTTP/2.0", Code: http.StatusOK})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = ts.Run(t, test.TestCase{Path: "/", BodyMatch: "HTTP/2.0", Code: http.StatusOK})
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxActive), "requests should be capped to the max concurrent streams")
+}
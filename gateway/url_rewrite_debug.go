@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// urlRewriteTestRequest is a sample request to test the URL rewrite rules of an API with.
+type urlRewriteTestRequest struct {
+	APIID   string            `json:"api_id"`
+	Request *traceHttpRequest `json:"request"`
+	// Claims are the claims of the JWT of the request, matched by claim triggers.
+	Claims map[string]interface{} `json:"claims"`
+	// Metadata is the metadata of the session of the request, matched by session triggers.
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// urlRewriteTestResponse shows how a sample request is rewritten.
+type urlRewriteTestResponse struct {
+	// Matched is true when a rule matched the request.
+	Matched bool `json:"matched"`
+	// Rule is the name of the rule which matched, or its method and path when it has no name.
+	Rule string `json:"rule,omitempty"`
+	// Trigger is the name of the trigger which matched, or its index when it has no name.
+	Trigger string `json:"trigger,omitempty"`
+	// RewrittenURL is the URL the request is rewritten to.
+	RewrittenURL string `json:"rewritten_url,omitempty"`
+	// UpstreamURL is the URL the request is proxied to.
+	UpstreamURL string `json:"upstream_url"`
+}
+
+// urlRewriteTestHandler runs the URL rewrite rules of an API for a sample request, without
+// proxying it.
+func (gw *Gateway) urlRewriteTestHandler(w http.ResponseWriter, r *http.Request) {
+	var testReq urlRewriteTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&testReq); err != nil {
+		log.Error("Couldn't decode URL rewrite test request: ", err)
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	if testReq.Request == nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request field is missing"))
+		return
+	}
+
+	spec := gw.getApiSpec(testReq.APIID)
+	if spec == nil {
+		doJSONWrite(w, http.StatusNotFound, apiError("API not found"))
+		return
+	}
+
+	sample, err := testReq.Request.toRequest(gw.GetConfig().IgnoreCanonicalMIMEHeaderKey)
+	if err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError("Request malformed"))
+		return
+	}
+
+	contextData := map[string]interface{}{}
+	for claim, value := range testReq.Claims {
+		contextData["jwt_claims_"+claim] = value
+	}
+	ctxSetData(sample, contextData)
+
+	if testReq.Metadata != nil {
+		ctxSetSession(sample, &user.SessionState{MetaData: testReq.Metadata}, false, false)
+	}
+
+	result, err := gw.testURLRewrite(spec, sample)
+	if err != nil {
+		doJSONWrite(w, http.StatusBadRequest, apiError(err.Error()))
+		return
+	}
+
+	doJSONWrite(w, http.StatusOK, result)
+}
+
+func (gw *Gateway) testURLRewrite(spec *APISpec, r *http.Request) (*urlRewriteTestResponse, error) {
+	result := &urlRewriteTestResponse{}
+	target := r.URL
+
+	vInfo, _ := spec.Version(r)
+	if found, meta := spec.CheckSpecMatchesStatus(r, spec.RxPaths[vInfo.Name], URLRewrite); found {
+		umeta := meta.(*apidef.URLRewriteMeta)
+
+		rewrite, err := gw.rewriteURL(umeta, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if rewrite.Matched {
+			newURL, err := url.Parse(rewrite.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Matched = true
+			result.Rule = umeta.Name
+			if result.Rule == "" {
+				result.Rule = umeta.Method + " " + umeta.Path
+			}
+			if rewrite.Trigger >= 0 {
+				result.Trigger = umeta.Triggers[rewrite.Trigger].Name
+				if result.Trigger == "" {
+					result.Trigger = strconv.Itoa(rewrite.Trigger)
+				}
+			}
+			result.RewrittenURL = newURL.String()
+
+			if shouldRewriteHost(r.URL, newURL) {
+				result.UpstreamURL = newURL.String()
+				return result, nil
+			}
+
+			target = newURL
+		}
+	}
+
+	upstream := &url.URL{}
+	if spec.target != nil {
+		*upstream = *spec.target
+	}
+
+	path := target.Path
+	if spec.Proxy.StripListenPath {
+		path = spec.StripListenPath(path)
+	}
+	upstream.Path = singleJoiningSlash(upstream.Path, path, spec.Proxy.DisableStripSlash)
+	upstream.RawQuery = target.RawQuery
+	result.UpstreamURL = upstream.String()
+
+	return result, nil
+}
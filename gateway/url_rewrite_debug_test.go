@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/test"
+)
+
+func TestURLRewriteTestHandler(t *testing.T) {
+	ts := StartTest(nil)
+	defer ts.Close()
+
+	ts.Gw.BuildAndLoadAPI(func(spec *APISpec) {
+		spec.APIID = "rewritten"
+		spec.Proxy.ListenPath = "/rewritten/"
+		spec.Proxy.StripListenPath = true
+		spec.Proxy.TargetURL = "http://upstream.example.com/base"
+		UpdateAPIVersion(spec, "v1", func(v *apidef.VersionInfo) {
+			v.UseExtendedPaths = true
+			v.ExtendedPaths.URLRewrite = []apidef.URLRewriteMeta{{
+				Name:         "users",
+				Path:         "/users/{id}",
+				Method:       http.MethodGet,
+				MatchPattern: "/users/(?P<id>[0-9]+)",
+				RewriteTo:    "/accounts/${id}",
+				Triggers: []apidef.RoutingTrigger{{
+					Name: "beta",
+					On:   apidef.Any,
+					Options: apidef.RoutingTriggerOptions{
+						HeaderMatches: map[string]apidef.StringRegexMap{"X-Beta": {MatchPattern: "on"}},
+					},
+					RewriteTo: "https://beta.example.com/accounts/${id}",
+				}},
+			}}
+		})
+	})
+
+	_, _ = ts.Run(t, []test.TestCase{
+		{
+			AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/rewrite",
+			Data:      `{"api_id": "rewritten", "request": {"method": "GET", "path": "/rewritten/users/7?a=b"}}`,
+			BodyMatch: `{"matched":true,"rule":"users","rewritten_url":"/accounts/7","upstream_url":"http://upstream.example.com/base/accounts/7"}`,
+			Code:      http.StatusOK,
+		},
+		{
+			AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/rewrite",
+			Data:      `{"api_id": "rewritten", "request": {"method": "GET", "path": "/rewritten/users/7", "headers": {"X-Beta": ["on"]}}}`,
+			BodyMatch: `"trigger":"beta".*"upstream_url":"https://beta.example.com/accounts/7"`,
+			Code:      http.StatusOK,
+		},
+		{
+			AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/rewrite",
+			Data:      `{"api_id": "rewritten", "request": {"method": "GET", "path": "/rewritten/other?a=b"}}`,
+			BodyMatch: `{"matched":false,"upstream_url":"http://upstream.example.com/base/other\?a=b"}`,
+			Code:      http.StatusOK,
+		},
+		{
+			AdminAuth: true, Method: http.MethodPost, Path: "/tyk/debug/rewrite",
+			Data: `{"api_id": "missing", "request": {"method": "GET", "path": "/"}}`,
+			Code: http.StatusNotFound,
+		},
+	}...)
+}
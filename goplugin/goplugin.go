@@ -54,3 +54,18 @@ func GetResponseHandler(modulePath string, symbol string) (func(rw http.Response
 
 	return respPluginHandler, nil
 }
+
+func GetKeyGenerator(modulePath string, symbol string) (func(orgID string) string, error) {
+	funcSymbol, err := GetSymbol(modulePath, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// try to cast symbol to real func
+	keyGenerator, ok := funcSymbol.(func(orgID string) string)
+	if !ok {
+		return nil, errors.New("could not cast function symbol to key generator function")
+	}
+
+	return keyGenerator, nil
+}
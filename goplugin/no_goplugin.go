@@ -23,3 +23,7 @@ func GetHandler(path string, symbol string) (http.HandlerFunc, error) {
 func GetResponseHandler(path string, symbol string) (func(rw http.ResponseWriter, res *http.Response, req *http.Request), error) {
 	return nil, fmt.Errorf(errNotImplemented, "GetResponseHandler")
 }
+
+func GetKeyGenerator(path string, symbol string) (func(orgID string) string, error) {
+	return nil, fmt.Errorf(errNotImplemented, "GetKeyGenerator")
+}
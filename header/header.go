@@ -14,6 +14,9 @@ const (
 	Expires                 = "Expires"
 	Connection              = "Connection"
 	WWWAuthenticate         = "WWW-Authenticate"
+	DPoP                    = "DPoP"
+	DPoPNonce               = "DPoP-Nonce"
+	RetryAfter              = "Retry-After"
 )
 
 const (
@@ -39,6 +42,9 @@ const (
 	XTykHostname        = "x-tyk-hostname"
 	XGenerator          = "X-Generator"
 	XTykAuthorization   = "X-Tyk-Authorization"
+	XTykAuthor          = "X-Tyk-Author"
+	XTykDPoPThumbprint  = "X-Tyk-DPoP-Thumbprint"
+	XFAPIInteractionID  = "X-Fapi-Interaction-Id"
 )
 
 // upgrade and websocket
@@ -54,4 +60,21 @@ const (
 	XRateLimitLimit     = "X-RateLimit-Limit"
 	XRateLimitRemaining = "X-RateLimit-Remaining"
 	XRateLimitReset     = "X-RateLimit-Reset"
+	XTykDuplicate       = "X-Tyk-Duplicate"
+)
+
+// Gateway's diagnostics response headers, sent to the allow-listed clients of APIs with debug headers
+const (
+	XTykCacheStatus      = "X-Tyk-Cache-Status"
+	XTykRateLimitBucket  = "X-Tyk-RateLimit-Bucket"
+	XTykUpstreamTarget   = "X-Tyk-Upstream-Target"
+	XTykMiddlewareTiming = "X-Tyk-Middleware-Timing"
+)
+
+// RateLimit header fields of the draft IETF RateLimit header fields
+const (
+	RateLimitLimit     = "RateLimit-Limit"
+	RateLimitRemaining = "RateLimit-Remaining"
+	RateLimitReset     = "RateLimit-Reset"
+	RateLimitPolicy    = "RateLimit-Policy"
 )
@@ -13,6 +13,8 @@ type Event string
 const (
 	// QuotaExceeded is the event triggered when quota for a specific key has been exceeded.
 	QuotaExceeded Event = "QuotaExceeded"
+	// QuotaThresholdReached is the event triggered when the quota usage of a key reaches a configured threshold.
+	QuotaThresholdReached Event = "QuotaThresholdReached"
 	// AuthFailure is the event triggered when key has failed authentication or has attempted access and was denied.
 	AuthFailure Event = "AuthFailure"
 	// KeyExpired is the event triggered when a key has attempted access but is expired.
@@ -42,6 +44,28 @@ const (
 	TokenUpdated Event = "TokenUpdated"
 	// TokenDeleted is the event triggered when a token is deleted.
 	TokenDeleted Event = "TokenDeleted"
+	// KeyLeaked is the event triggered when secret scanning reports an active key as publicly leaked.
+	KeyLeaked Event = "KeyLeaked"
+	// BruteForceLockout is the event triggered when a client is banned after repeated authentication failures.
+	BruteForceLockout Event = "BruteForceLockout"
+	// TokenRestored is the event triggered when a deleted token is restored from the trash.
+	TokenRestored Event = "TokenRestored"
+	// APIRestored is the event triggered when a deleted API is restored from the trash.
+	APIRestored Event = "APIRestored"
+	// StorageBudgetApproached is the event triggered when a Redis byte budget reaches its alert threshold.
+	StorageBudgetApproached Event = "StorageBudgetApproached"
+	// StorageBudgetExceeded is the event triggered when a Redis byte budget is exceeded.
+	StorageBudgetExceeded Event = "StorageBudgetExceeded"
+	// OAuthRefreshTokenReused is the event triggered when a rotated OAuth refresh token is presented again.
+	OAuthRefreshTokenReused Event = "OAuthRefreshTokenReused"
+	// ServerCertificatesChanged is the event triggered when the server certificates are swapped into the listeners.
+	ServerCertificatesChanged Event = "ServerCertificatesChanged"
+	// DRLServerEvicted is the event triggered when a Gateway which stopped sending DRL notifications is evicted.
+	DRLServerEvicted Event = "DRLServerEvicted"
+	// UpstreamDegraded is the event triggered when an API is degraded as its upstream exceeded its error budget.
+	UpstreamDegraded Event = "UpstreamDegraded"
+	// UpstreamRecovered is the event triggered when a recovery probe connects to the upstream of a degraded API.
+	UpstreamRecovered Event = "UpstreamRecovered"
 )
 
 // Rate limiter events
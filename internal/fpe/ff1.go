@@ -0,0 +1,213 @@
+// Package fpe implements the FF1 format-preserving encryption mode of NIST SP 800-38G.
+package fpe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+)
+
+const rounds = 10
+
+// minDomainSize is the minimal number of possible values of an input, per SP 800-38G Rev. 1.
+const minDomainSize = 1000000
+
+// Alphabets usable with NewFF1.
+const (
+	Digits       = "0123456789"
+	Alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	ErrInvalidAlphabet = errors.New("alphabet must have between 2 and 65536 unique characters")
+	ErrInputTooShort   = errors.New("input is too short to be encrypted securely")
+	ErrInvalidInput    = errors.New("input has characters outside of the alphabet")
+)
+
+// FF1 encrypts strings of characters of an alphabet into strings of the same length and alphabet.
+type FF1 struct {
+	block    cipher.Block
+	alphabet []rune
+	index    map[rune]int
+	radix    *big.Int
+	minLen   int
+}
+
+// NewFF1 returns an FF1 cipher using an AES-128, AES-192 or AES-256 key.
+func NewFF1(key []byte, alphabet string) (*FF1, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(alphabet)
+	if len(runes) < 2 || len(runes) > 1<<16 {
+		return nil, ErrInvalidAlphabet
+	}
+
+	index := make(map[rune]int, len(runes))
+	for i, r := range runes {
+		if _, ok := index[r]; ok {
+			return nil, ErrInvalidAlphabet
+		}
+		index[r] = i
+	}
+
+	minLen := 1
+	for size := len(runes); size < minDomainSize; size *= len(runes) {
+		minLen++
+	}
+
+	return &FF1{
+		block:    block,
+		alphabet: runes,
+		index:    index,
+		radix:    big.NewInt(int64(len(runes))),
+		minLen:   minLen,
+	}, nil
+}
+
+// Contains tells whether a character is in the alphabet of the cipher.
+func (f *FF1) Contains(r rune) bool {
+	_, ok := f.index[r]
+	return ok
+}
+
+// Encrypt encrypts a string of characters of the alphabet with a tweak.
+func (f *FF1) Encrypt(plaintext string, tweak []byte) (string, error) {
+	return f.cipher(plaintext, tweak, true)
+}
+
+// Decrypt decrypts a string encrypted with the same tweak.
+func (f *FF1) Decrypt(ciphertext string, tweak []byte) (string, error) {
+	return f.cipher(ciphertext, tweak, false)
+}
+
+func (f *FF1) cipher(input string, tweak []byte, encrypt bool) (string, error) {
+	x := []rune(input)
+	n := len(x)
+	if n < f.minLen {
+		return "", ErrInputTooShort
+	}
+
+	numerals := make([]int, n)
+	for i, r := range x {
+		numeral, ok := f.index[r]
+		if !ok {
+			return "", ErrInvalidInput
+		}
+		numerals[i] = numeral
+	}
+
+	u := n / 2
+	v := n - u
+	a, b := f.num(numerals[:u]), f.num(numerals[u:])
+
+	// byte length of the numbers of v numerals, and of the pseudorandom output
+	bLen := (int(math.Ceil(float64(v)*math.Log2(float64(len(f.alphabet))))) + 7) / 8
+	d := 4*((bLen+3)/4) + 4
+
+	p := make([]byte, 16)
+	p[0], p[1], p[2] = 1, 2, 1
+	radix := uint32(len(f.alphabet))
+	p[3], p[4], p[5] = byte(radix>>16), byte(radix>>8), byte(radix)
+	p[6] = 10
+	p[7] = byte(u)
+	binary.BigEndian.PutUint32(p[8:12], uint32(n))
+	binary.BigEndian.PutUint32(p[12:16], uint32(len(tweak)))
+
+	padding := (16 - (len(tweak)+bLen+1)%16) % 16
+	q := make([]byte, len(tweak)+padding+1+bLen)
+	copy(q, tweak)
+
+	modU := new(big.Int).Exp(f.radix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(f.radix, big.NewInt(int64(v)), nil)
+
+	for j := 0; j < rounds; j++ {
+		i := j
+		if !encrypt {
+			i = rounds - 1 - j
+		}
+
+		source := b
+		if !encrypt {
+			source = a
+		}
+
+		q[len(tweak)+padding] = byte(i)
+		source.FillBytes(q[len(q)-bLen:])
+
+		y := new(big.Int).SetBytes(f.expand(f.prf(append(append([]byte{}, p...), q...)), d))
+
+		m := modV
+		if i%2 == 0 {
+			m = modU
+		}
+
+		if encrypt {
+			c := new(big.Int).Add(a, y)
+			c.Mod(c, m)
+			a, b = b, c
+		} else {
+			c := new(big.Int).Sub(b, y)
+			c.Mod(c, m)
+			a, b = c, a
+		}
+	}
+
+	return f.str(a, u) + f.str(b, v), nil
+}
+
+// prf is the CBC-MAC of the input, which is a multiple of the block size.
+func (f *FF1) prf(input []byte) []byte {
+	y := make([]byte, 16)
+	for i := 0; i < len(input); i += 16 {
+		for k := 0; k < 16; k++ {
+			y[k] ^= input[i+k]
+		}
+		f.block.Encrypt(y, y)
+	}
+
+	return y
+}
+
+// expand extends the output of the PRF to d bytes.
+func (f *FF1) expand(r []byte, d int) []byte {
+	s := append([]byte{}, r...)
+	for j := 1; len(s) < d; j++ {
+		block := make([]byte, 16)
+		binary.BigEndian.PutUint64(block[8:], uint64(j))
+		for k := range block {
+			block[k] ^= r[k]
+		}
+		f.block.Encrypt(block, block)
+		s = append(s, block...)
+	}
+
+	return s[:d]
+}
+
+func (f *FF1) num(numerals []int) *big.Int {
+	x := new(big.Int)
+	for _, numeral := range numerals {
+		x.Mul(x, f.radix)
+		x.Add(x, big.NewInt(int64(numeral)))
+	}
+
+	return x
+}
+
+func (f *FF1) str(x *big.Int, length int) string {
+	numerals := make([]rune, length)
+	x = new(big.Int).Set(x)
+	mod := new(big.Int)
+	for i := length - 1; i >= 0; i-- {
+		x.DivMod(x, f.radix, mod)
+		numerals[i] = f.alphabet[mod.Int64()]
+	}
+
+	return string(numerals)
+}
@@ -0,0 +1,76 @@
+package fpe
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Samples of NIST SP 800-38G.
+func TestFF1_NISTSamples(t *testing.T) {
+	const (
+		key128 = "2B7E151628AED2A6ABF7158809CF4F3C"
+		key192 = key128 + "EF4359D8D580AA4F"
+		key256 = key192 + "7F036D6F04FC6A94"
+	)
+
+	tests := []struct {
+		name       string
+		key        string
+		alphabet   string
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{"sample 1", key128, Digits, "", "0123456789", "2433477484"},
+		{"sample 2", key128, Digits, "39383736353433323130", "0123456789", "6124200773"},
+		{"sample 3", key128, "0123456789abcdefghijklmnopqrstuvwxyz", "3737373770717273373737", "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+		{"sample 4", key192, Digits, "", "0123456789", "2830668132"},
+		{"sample 7", key256, Digits, "", "0123456789", "6657667009"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			key, _ := hex.DecodeString(tc.key)
+			tweak, _ := hex.DecodeString(tc.tweak)
+
+			ff1, err := NewFF1(key, tc.alphabet)
+			require.NoError(t, err)
+
+			ciphertext, err := ff1.Encrypt(tc.plaintext, tweak)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ciphertext, ciphertext)
+
+			plaintext, err := ff1.Decrypt(ciphertext, tweak)
+			require.NoError(t, err)
+			assert.Equal(t, tc.plaintext, plaintext)
+		})
+	}
+}
+
+func TestFF1_Errors(t *testing.T) {
+	key := make([]byte, 16)
+
+	_, err := NewFF1(key, "a")
+	assert.ErrorIs(t, err, ErrInvalidAlphabet)
+
+	_, err = NewFF1(key, "aa")
+	assert.ErrorIs(t, err, ErrInvalidAlphabet)
+
+	_, err = NewFF1([]byte("short"), Digits)
+	assert.Error(t, err)
+
+	ff1, err := NewFF1(key, Digits)
+	require.NoError(t, err)
+
+	_, err = ff1.Encrypt("12345", nil)
+	assert.ErrorIs(t, err, ErrInputTooShort)
+
+	_, err = ff1.Encrypt("123456", nil)
+	assert.NoError(t, err)
+
+	_, err = ff1.Encrypt("12345a", nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,155 @@
+package httputil
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrAmbiguousPath is returned in strict mode for the paths which the upstreams or the
+// access rules could interpret differently than the router.
+var ErrAmbiguousPath = errors.New("ambiguous request path")
+
+const upperHex = "0123456789ABCDEF"
+
+// NormalizePath normalizes an escaped request path: the percent-encodings of unreserved
+// characters are decoded, the other percent-encodings are upper cased, duplicate slashes are
+// collapsed and the dot-segments are resolved.
+//
+// In strict mode, the paths with encoded slashes or backslashes, backslashes, NUL bytes or
+// dot-segments climbing above the root return ErrAmbiguousPath, otherwise dot-segments
+// climbing above the root are dropped. Invalid percent-encodings are always an error.
+func NormalizePath(escapedPath string, strict bool) (string, error) {
+	decoded, err := decodeUnreserved(escapedPath, strict)
+	if err != nil {
+		return "", err
+	}
+
+	if strict && strings.IndexByte(decoded, '\\') >= 0 {
+		return "", ErrAmbiguousPath
+	}
+
+	segments := strings.Split(decoded, "/")
+	out := make([]string, 0, len(segments))
+	trailingSlash := false
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		switch segment {
+		case "":
+			trailingSlash = last && i > 0
+			continue
+		case ".":
+			trailingSlash = last
+			continue
+		case "..":
+			if len(out) == 0 {
+				if strict {
+					return "", ErrAmbiguousPath
+				}
+			} else {
+				out = out[:len(out)-1]
+			}
+			trailingSlash = last
+			continue
+		}
+
+		out = append(out, segment)
+		trailingSlash = false
+	}
+
+	path := "/" + strings.Join(out, "/")
+	if trailingSlash && len(out) > 0 {
+		path += "/"
+	}
+
+	return path, nil
+}
+
+// decodeUnreserved decodes the percent-encoded unreserved characters of an escaped path.
+func decodeUnreserved(escapedPath string, strict bool) (string, error) {
+	if strings.IndexByte(escapedPath, '%') < 0 {
+		return escapedPath, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(escapedPath))
+
+	for i := 0; i < len(escapedPath); i++ {
+		if escapedPath[i] != '%' {
+			b.WriteByte(escapedPath[i])
+			continue
+		}
+
+		if i+2 >= len(escapedPath) || !isHex(escapedPath[i+1]) || !isHex(escapedPath[i+2]) {
+			return "", url.EscapeError(escapedPath[i:min(i+3, len(escapedPath))])
+		}
+
+		c := unhex(escapedPath[i+1])<<4 | unhex(escapedPath[i+2])
+		i += 2
+
+		if strict && (c == '/' || c == '\\' || c == 0) {
+			return "", ErrAmbiguousPath
+		}
+
+		if isUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+
+		b.WriteByte('%')
+		b.WriteByte(upperHex[c>>4])
+		b.WriteByte(upperHex[c&15])
+	}
+
+	return b.String(), nil
+}
+
+// NormalizeRequestPath normalizes the path of a request, see NormalizePath.
+func NormalizeRequestPath(r *http.Request, strict bool) error {
+	escaped := r.URL.EscapedPath()
+
+	normalized, err := NormalizePath(escaped, strict)
+	if err != nil {
+		return err
+	}
+
+	if normalized == escaped {
+		return nil
+	}
+
+	path, err := url.PathUnescape(normalized)
+	if err != nil {
+		return err
+	}
+
+	r.URL.Path = path
+	r.URL.RawPath = normalized
+	if r.URL.EscapedPath() != normalized {
+		r.URL.RawPath = ""
+	}
+
+	return nil
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
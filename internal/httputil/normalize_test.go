@@ -0,0 +1,73 @@
+package httputil
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		want      string
+		ambiguous bool
+	}{
+		{name: "clean", path: "/api/admin", want: "/api/admin"},
+		{name: "root", path: "/", want: "/"},
+		{name: "trailing slash", path: "/api/admin/", want: "/api/admin/"},
+		{name: "duplicate slashes", path: "//api///admin", want: "/api/admin"},
+		{name: "duplicate trailing slashes", path: "/api/admin//", want: "/api/admin/"},
+		{name: "dot segment", path: "/api/./admin", want: "/api/admin"},
+		{name: "trailing dot segment", path: "/api/admin/.", want: "/api/admin/"},
+		{name: "dot dot segment", path: "/api/public/../admin", want: "/api/admin"},
+		{name: "trailing dot dot segment", path: "/api/admin/x/..", want: "/api/admin/"},
+		{name: "encoded unreserved", path: "/api/%61dmin%7e", want: "/api/admin~"},
+		{name: "encoded dot segment", path: "/api/public/%2e%2E/admin", want: "/api/admin"},
+		{name: "reserved escapes upper cased", path: "/api/a%3fb%2f", want: "/api/a%3Fb%2F", ambiguous: true},
+		{name: "dot dot above root", path: "/../api/admin", want: "/api/admin", ambiguous: true},
+		{name: "encoded dot dot above root", path: "/%2e%2e/api", want: "/api", ambiguous: true},
+		{name: "encoded slash", path: "/api%2Fadmin", want: "/api%2Fadmin", ambiguous: true},
+		{name: "encoded backslash", path: "/api%5cadmin", want: "/api%5Cadmin", ambiguous: true},
+		{name: "backslash", path: `/api\admin`, want: `/api\admin`, ambiguous: true},
+		{name: "encoded NUL", path: "/api/admin%00", want: "/api/admin%00", ambiguous: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizePath(tc.path, false)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+
+			got, err = NormalizePath(tc.path, true)
+			if tc.ambiguous {
+				assert.ErrorIs(t, err, ErrAmbiguousPath)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("invalid escape", func(t *testing.T) {
+		_, err := NormalizePath("/api/%zz", false)
+		assert.Error(t, err)
+
+		_, err = NormalizePath("/api/%4", false)
+		assert.Error(t, err)
+	})
+}
+
+func TestNormalizeRequestPath(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api//%61dmin/../users%3Fx/?q=1", nil)
+	require.NoError(t, NormalizeRequestPath(r, false))
+
+	assert.Equal(t, "/api/users?x/", r.URL.Path)
+	assert.Equal(t, "/api/users%3Fx/", r.URL.EscapedPath())
+	assert.Equal(t, "q=1", r.URL.RawQuery)
+
+	r = httptest.NewRequest("GET", "/api/%2e%2e/%2e%2e/admin", nil)
+	assert.ErrorIs(t, NormalizeRequestPath(r, true), ErrAmbiguousPath)
+}
@@ -0,0 +1,589 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Request anomalies rejected by strict listeners.
+const (
+	AnomalyContentLengthWithTransferEncoding = "content_length_with_transfer_encoding"
+	AnomalyMultipleContentLength             = "multiple_content_length"
+	AnomalyInvalidTransferEncoding           = "invalid_transfer_encoding"
+	AnomalyObsFold                           = "obs_fold"
+	AnomalyBareLF                            = "bare_lf"
+	AnomalyChunkExtension                    = "chunk_extension"
+	AnomalyInvalidChunk                      = "invalid_chunk"
+)
+
+const (
+	// maxStrictLine is the length of the lines kept to check them. Longer lines are
+	// rejected by the HTTP server, except header values, which aren't checked.
+	maxStrictLine = 256
+	// maxChunkExtension is the length of chunk extensions considered normal.
+	maxChunkExtension = 128
+	// strictHandshakeTimeout limits the TLS handshakes done by strict listeners.
+	strictHandshakeTimeout = 10 * time.Second
+)
+
+// AnomalyError is the error returned when reading a request which could be parsed
+// differently by the upstream servers.
+type AnomalyError struct {
+	Kind string
+}
+
+func (e *AnomalyError) Error() string {
+	return "http request anomaly: " + e.Kind
+}
+
+// IsAnomaly tells whether an error was caused by a request anomaly.
+func IsAnomaly(err error) bool {
+	var anomaly *AnomalyError
+	return errors.As(err, &anomaly)
+}
+
+// AnomalyCounters counts the request anomalies rejected by kind.
+type AnomalyCounters struct {
+	counters sync.Map
+}
+
+// Add increments the counter of an anomaly.
+func (c *AnomalyCounters) Add(kind string) {
+	counter, _ := c.counters.LoadOrStore(kind, new(uint64))
+	atomic.AddUint64(counter.(*uint64), 1)
+}
+
+// Snapshot returns the counters of the anomalies seen.
+func (c *AnomalyCounters) Snapshot() map[string]uint64 {
+	snapshot := map[string]uint64{}
+	c.counters.Range(func(kind, counter interface{}) bool {
+		snapshot[kind.(string)] = atomic.LoadUint64(counter.(*uint64))
+		return true
+	})
+
+	return snapshot
+}
+
+// StrictListener checks the HTTP/1.x requests read from its connections, before they are
+// parsed by the HTTP server, and fails the connections sending requests framed ambiguously:
+// conflicting Content-Length and Transfer-Encoding headers, obsolete line folding, bare LF
+// line endings and abnormal chunks. The HTTP server replies to these with 400 Bad Request.
+//
+// TLS connections are handshaken by the listener, HTTP/2 connections are passed as they are
+// and the TLS state of HTTP/1.x connections is restored by RestoreTLSState.
+type StrictListener struct {
+	net.Listener
+
+	onAnomaly func(kind string, remote net.Addr)
+
+	start sync.Once
+	conns chan net.Conn
+	errs  chan error
+	done  chan struct{}
+	err   error
+}
+
+// NewStrictListener wraps a listener. onAnomaly is called for every anomaly rejected.
+func NewStrictListener(l net.Listener, onAnomaly func(kind string, remote net.Addr)) *StrictListener {
+	return &StrictListener{
+		Listener:  l,
+		onAnomaly: onAnomaly,
+		conns:     make(chan net.Conn),
+		errs:      make(chan error),
+		done:      make(chan struct{}),
+	}
+}
+
+// Accept returns the next connection, once its TLS handshake completed.
+func (l *StrictListener) Accept() (net.Conn, error) {
+	l.start.Do(func() { go l.accept() })
+
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+func (l *StrictListener) accept() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				select {
+				case l.errs <- err:
+					continue
+				case <-l.done:
+					return
+				}
+			}
+
+			l.err = err
+			close(l.done)
+			return
+		}
+
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			// handshakes are done concurrently, a slow client doesn't hold the others
+			go l.handshake(tlsConn)
+			continue
+		}
+
+		l.deliver(newStrictConn(conn, l.onAnomaly))
+	}
+}
+
+func (l *StrictListener) handshake(conn *tls.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), strictHandshakeTimeout)
+	defer cancel()
+
+	if err := conn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return
+	}
+
+	if conn.ConnectionState().NegotiatedProtocol == "h2" {
+		// HTTP/2 frames carry the length of the messages; the server has to see the
+		// TLS connection to serve it
+		l.deliver(conn)
+		return
+	}
+
+	l.deliver(newStrictConn(conn, l.onAnomaly))
+}
+
+func (l *StrictListener) deliver(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+type strictConnKey struct{}
+
+// ConnContext is the http.Server ConnContext function making the TLS state of the
+// connections available to RestoreTLSState.
+func (l *StrictListener) ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if sc, ok := conn.(*strictConn); ok {
+		return context.WithValue(ctx, strictConnKey{}, sc)
+	}
+
+	return ctx
+}
+
+// RestoreTLSState sets the TLS state of requests read from TLS connections of strict
+// listeners, which the HTTP server can't see.
+func RestoreTLSState(r *http.Request) {
+	if r.TLS != nil {
+		return
+	}
+
+	if sc, ok := r.Context().Value(strictConnKey{}).(*strictConn); ok {
+		if conn, ok := sc.Conn.(*tls.Conn); ok {
+			state := conn.ConnectionState()
+			r.TLS = &state
+		}
+	}
+}
+
+// RequestAnomaly returns the anomaly found while reading a request from a strict
+// listener. Bodies are checked as they stream through, so an anomaly in the chunks of
+// a body is only found once the body is read.
+func RequestAnomaly(r *http.Request) error {
+	if sc, ok := r.Context().Value(strictConnKey{}).(*strictConn); ok {
+		return sc.anomaly()
+	}
+
+	return nil
+}
+
+type strictState int
+
+const (
+	stateRequestLine strictState = iota
+	stateHeaders
+	stateBody
+	stateChunkSize
+	stateChunkData
+	stateChunkCR
+	stateChunkLF
+	stateTrailers
+	statePassthrough
+)
+
+// strictConn checks the requests read from a connection, following the framing of the
+// requests to find where the next one starts.
+type strictConn struct {
+	net.Conn
+
+	onAnomaly func(kind string, remote net.Addr)
+	err       atomic.Value
+
+	state     strictState
+	line      []byte
+	lineLen   int
+	last      byte
+	remaining uint64
+
+	contentLengths    int
+	transferEncodings [][]byte
+	upgrade           bool
+	connect           bool
+
+	// switching is the kind of request whose response may switch the connection to
+	// another protocol, set once its head is read and cleared by the next response.
+	switching atomic.Int32
+	// switched is set once the response accepted the switch to another protocol.
+	switched atomic.Bool
+}
+
+const (
+	switchingUpgrade int32 = iota + 1
+	switchingConnect
+)
+
+func newStrictConn(conn net.Conn, onAnomaly func(kind string, remote net.Addr)) *strictConn {
+	return &strictConn{Conn: conn, onAnomaly: onAnomaly}
+}
+
+func (c *strictConn) Read(p []byte) (int, error) {
+	if err := c.anomaly(); err != nil {
+		return 0, err
+	}
+
+	n, err := c.Conn.Read(p)
+	if kind := c.scan(p[:n]); kind != "" {
+		anomaly := &AnomalyError{Kind: kind}
+		// the anomaly is kept before the HTTP server sees the error and cancels the
+		// request, so that the handler reading the body can tell the client is at fault
+		c.err.Store(anomaly)
+		if c.onAnomaly != nil {
+			c.onAnomaly(kind, c.RemoteAddr())
+		}
+		return 0, anomaly
+	}
+
+	return n, err
+}
+
+// Write switches the connection to passthrough when the response to an upgrade request
+// is a 101 Switching Protocols, or the response to a CONNECT request is a success. The
+// connection is checked as before when the switch is declined.
+func (c *strictConn) Write(p []byte) (int, error) {
+	if switching := c.switching.Swap(0); switching != 0 && switchesProtocol(p, switching == switchingConnect) {
+		c.switched.Store(true)
+	}
+
+	return c.Conn.Write(p)
+}
+
+// switchesProtocol tells whether a response, starting with its status line, accepts the
+// switch to another protocol.
+func switchesProtocol(response []byte, connect bool) bool {
+	status, ok := bytes.CutPrefix(response, []byte("HTTP/1.1 "))
+	if !ok {
+		return false
+	}
+
+	if connect {
+		return len(status) > 0 && status[0] == '2'
+	}
+
+	return bytes.HasPrefix(status, []byte("101"))
+}
+
+// anomaly returns the anomaly found in the connection, if any.
+func (c *strictConn) anomaly() error {
+	if err, ok := c.err.Load().(*AnomalyError); ok {
+		return err
+	}
+
+	return nil
+}
+
+// scan checks the bytes read, returning the kind of the first anomaly found.
+func (c *strictConn) scan(data []byte) string {
+	if c.switched.Load() {
+		// the data read after the response switching the protocol isn't HTTP
+		c.state = statePassthrough
+	}
+
+	for len(data) > 0 {
+		switch c.state {
+		case statePassthrough:
+			return ""
+		case stateBody, stateChunkData:
+			skip := uint64(len(data))
+			if skip > c.remaining {
+				skip = c.remaining
+			}
+			data = data[skip:]
+			c.remaining -= skip
+
+			if c.remaining == 0 {
+				if c.state == stateBody {
+					c.state = stateRequestLine
+				} else {
+					c.state = stateChunkCR
+				}
+			}
+		case stateChunkCR:
+			switch data[0] {
+			case '\r':
+				c.state = stateChunkLF
+			case '\n':
+				return AnomalyBareLF
+			default:
+				return AnomalyInvalidChunk
+			}
+			data = data[1:]
+		case stateChunkLF:
+			if data[0] != '\n' {
+				return AnomalyInvalidChunk
+			}
+			data = data[1:]
+			c.state = stateChunkSize
+		default:
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				c.appendLine(data)
+				return ""
+			}
+
+			c.appendLine(data[:i])
+			data = data[i+1:]
+
+			if kind := c.endLine(); kind != "" {
+				return kind
+			}
+		}
+	}
+
+	return ""
+}
+
+func (c *strictConn) appendLine(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	c.lineLen += len(data)
+	c.last = data[len(data)-1]
+	if room := maxStrictLine - len(c.line); room > 0 {
+		if len(data) > room {
+			data = data[:room]
+		}
+		c.line = append(c.line, data...)
+	}
+}
+
+// endLine checks a line of the request head, chunk size or trailers.
+func (c *strictConn) endLine() string {
+	line, truncated, last := c.line, c.lineLen > len(c.line), c.last
+	c.line, c.lineLen, c.last = c.line[:0], 0, 0
+
+	if last != '\r' {
+		return AnomalyBareLF
+	}
+	if truncated && c.state == stateChunkSize {
+		return AnomalyInvalidChunk
+	}
+	if !truncated {
+		line = line[:len(line)-1]
+	}
+
+	switch c.state {
+	case stateRequestLine:
+		if len(line) == 0 {
+			// empty lines before a request are ignored by servers
+			return ""
+		}
+		if bytes.HasPrefix(line, []byte("PRI * HTTP/2.0")) {
+			c.state = statePassthrough
+			return ""
+		}
+
+		c.connect = bytes.HasPrefix(line, []byte("CONNECT "))
+		c.state = stateHeaders
+	case stateHeaders:
+		if len(line) == 0 {
+			return c.endHeaders()
+		}
+		return c.header(line)
+	case stateChunkSize:
+		return c.chunkSize(line)
+	case stateTrailers:
+		if len(line) == 0 {
+			c.state = stateRequestLine
+			return ""
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return AnomalyObsFold
+		}
+	}
+
+	return ""
+}
+
+func (c *strictConn) header(line []byte) string {
+	if line[0] == ' ' || line[0] == '\t' {
+		return AnomalyObsFold
+	}
+
+	colon := bytes.IndexByte(line, ':')
+	if colon < 0 {
+		// malformed headers are rejected by the HTTP server
+		return ""
+	}
+
+	name, value := line[:colon], bytes.TrimSpace(line[colon+1:])
+	switch {
+	case bytes.EqualFold(name, []byte("Content-Length")):
+		c.contentLengths++
+		if c.contentLengths == 1 {
+			c.remaining = parseContentLength(value)
+		}
+	case bytes.EqualFold(name, []byte("Transfer-Encoding")):
+		c.transferEncodings = append(c.transferEncodings, append([]byte{}, value...))
+	case bytes.EqualFold(name, []byte("Upgrade")):
+		c.upgrade = true
+	}
+
+	return ""
+}
+
+func parseContentLength(value []byte) uint64 {
+	var n uint64
+	for _, b := range value {
+		if b < '0' || b > '9' || n > 1<<60 {
+			// invalid lengths are rejected by the HTTP server
+			return 0
+		}
+		n = n*10 + uint64(b-'0')
+	}
+
+	return n
+}
+
+func (c *strictConn) endHeaders() string {
+	contentLengths, transferEncodings := c.contentLengths, c.transferEncodings
+	upgrade, connect := c.upgrade, c.connect
+	c.contentLengths, c.transferEncodings, c.upgrade, c.connect = 0, nil, false, false
+
+	switch {
+	case contentLengths > 0 && len(transferEncodings) > 0:
+		return AnomalyContentLengthWithTransferEncoding
+	case contentLengths > 1:
+		return AnomalyMultipleContentLength
+	case len(transferEncodings) > 1:
+		return AnomalyInvalidTransferEncoding
+	case len(transferEncodings) == 1 && !bytes.EqualFold(transferEncodings[0], []byte("chunked")):
+		return AnomalyInvalidTransferEncoding
+	}
+
+	// the connection carries another protocol once the response accepts the switch, until
+	// then the data read is checked as requests, as servers may decline the switch
+	switch {
+	case connect:
+		c.switching.Store(switchingConnect)
+	case upgrade:
+		c.switching.Store(switchingUpgrade)
+	}
+
+	switch {
+	case len(transferEncodings) == 1:
+		c.state = stateChunkSize
+	case c.remaining > 0:
+		c.state = stateBody
+	default:
+		c.state = stateRequestLine
+	}
+
+	return ""
+}
+
+func (c *strictConn) chunkSize(line []byte) string {
+	size, extension := line, []byte(nil)
+	if i := bytes.IndexByte(line, ';'); i >= 0 {
+		size, extension = line[:i], line[i+1:]
+	}
+
+	if len(size) == 0 || len(size) > 16 {
+		return AnomalyInvalidChunk
+	}
+
+	var n uint64
+	for _, b := range size {
+		var digit byte
+		switch {
+		case b >= '0' && b <= '9':
+			digit = b - '0'
+		case b >= 'a' && b <= 'f':
+			digit = b - 'a' + 10
+		case b >= 'A' && b <= 'F':
+			digit = b - 'A' + 10
+		default:
+			return AnomalyInvalidChunk
+		}
+		n = n<<4 | uint64(digit)
+	}
+
+	if extension != nil && !validChunkExtension(extension) {
+		return AnomalyChunkExtension
+	}
+
+	if n == 0 {
+		c.state = stateTrailers
+		return ""
+	}
+
+	c.remaining = n
+	c.state = stateChunkData
+	return ""
+}
+
+// validChunkExtension tells whether chunk extensions are short lists of `name[=value]`
+// tokens, which is all the legitimate uses of extensions need.
+func validChunkExtension(extension []byte) bool {
+	if len(extension) > maxChunkExtension {
+		return false
+	}
+
+	for _, ext := range bytes.Split(extension, []byte(";")) {
+		name, value, hasValue := bytes.Cut(ext, []byte("="))
+		if !isToken(name) || hasValue && !isToken(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isToken(s []byte) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, b := range s {
+		switch {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		case bytes.IndexByte([]byte("!#$%&'*+-.^_`|~"), b) >= 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,308 @@
+package httputil
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictConn_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		anomaly string
+	}{
+		{
+			name:    "simple requests",
+			request: "GET / HTTP/1.1\r\nHost: a\r\n\r\nPOST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\n\r\nhelloGET / HTTP/1.1\r\n\r\n",
+		},
+		{
+			name:    "chunked request",
+			request: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5;name=value\r\nhello\r\n0\r\nX-Trailer: a\r\n\r\nGET / HTTP/1.1\r\n\r\n",
+		},
+		{
+			name:    "body looking like a request",
+			request: "POST / HTTP/1.1\r\nContent-Length: 20\r\n\r\nGET /\nX: a\r\n b\r\n\r\n\r\n",
+		},
+		{
+			name:    "content length and transfer encoding",
+			request: "POST / HTTP/1.1\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n",
+			anomaly: AnomalyContentLengthWithTransferEncoding,
+		},
+		{
+			name:    "identical content lengths",
+			request: "POST / HTTP/1.1\r\nContent-Length: 1\r\nContent-Length: 1\r\n\r\na",
+			anomaly: AnomalyMultipleContentLength,
+		},
+		{
+			name:    "obfuscated transfer encoding",
+			request: "POST / HTTP/1.1\r\nTransfer-Encoding: xchunked\r\n\r\n",
+			anomaly: AnomalyInvalidTransferEncoding,
+		},
+		{
+			name:    "repeated transfer encoding",
+			request: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\nTransfer-Encoding: identity\r\n\r\n",
+			anomaly: AnomalyInvalidTransferEncoding,
+		},
+		{
+			name:    "obs-fold",
+			request: "GET / HTTP/1.1\r\nX-Header: a\r\n\tb\r\n\r\n",
+			anomaly: AnomalyObsFold,
+		},
+		{
+			name:    "bare LF",
+			request: "GET / HTTP/1.1\nHost: a\r\n\r\n",
+			anomaly: AnomalyBareLF,
+		},
+		{
+			name:    "chunk extension",
+			request: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5;a=\"\r\nhello\r\n0\r\n\r\n",
+			anomaly: AnomalyChunkExtension,
+		},
+		{
+			name:    "long chunk extension",
+			request: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5;" + strings.Repeat("a", 200) + "\r\nhello\r\n0\r\n\r\n",
+			anomaly: AnomalyChunkExtension,
+		},
+		{
+			name:    "invalid chunk size",
+			request: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n0x5\r\nhello\r\n0\r\n\r\n",
+			anomaly: AnomalyInvalidChunk,
+		},
+		{
+			name:    "chunk longer than its size",
+			request: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nhello\r\n0\r\n\r\n",
+			anomaly: AnomalyInvalidChunk,
+		},
+		{
+			name:    "upgrade without response",
+			request: "GET / HTTP/1.1\r\nUpgrade: foo\r\nConnection: Upgrade\r\n\r\nPOST / HTTP/1.1\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n",
+			anomaly: AnomalyContentLengthWithTransferEncoding,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// the result doesn't depend on how the request is split in reads
+			for _, size := range []int{1, 3, len(tc.request)} {
+				conn := newStrictConn(nil, nil)
+
+				anomaly := ""
+				for i := 0; i < len(tc.request) && anomaly == ""; i += size {
+					end := i + size
+					if end > len(tc.request) {
+						end = len(tc.request)
+					}
+					anomaly = conn.scan([]byte(tc.request[i:end]))
+				}
+
+				assert.Equal(t, tc.anomaly, anomaly, "read size %d", size)
+			}
+		})
+	}
+}
+
+func TestStrictConn_SwitchProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		response string
+		anomaly  string
+	}{
+		{
+			name:     "upgraded",
+			request:  "GET / HTTP/1.1\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n",
+			response: "HTTP/1.1 101 Switching Protocols\r\n\r\n",
+		},
+		{
+			name:     "upgrade declined",
+			request:  "GET / HTTP/1.1\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n",
+			response: "HTTP/1.1 200 OK\r\n\r\n",
+			anomaly:  AnomalyBareLF,
+		},
+		{
+			name:     "connected",
+			request:  "CONNECT a:443 HTTP/1.1\r\n\r\n",
+			response: "HTTP/1.1 200 OK\r\n\r\n",
+		},
+		{
+			name:     "connect declined",
+			request:  "CONNECT a:443 HTTP/1.1\r\n\r\n",
+			response: "HTTP/1.1 405 Method Not Allowed\r\n\r\n",
+			anomaly:  AnomalyBareLF,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+			go io.Copy(io.Discard, client)
+
+			conn := newStrictConn(server, nil)
+			require.Equal(t, "", conn.scan([]byte(tc.request)))
+
+			_, err := conn.Write([]byte(tc.response))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.anomaly, conn.scan([]byte("\x81\x05hello\n")))
+		})
+	}
+}
+
+func TestStrictListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var counters AnomalyCounters
+	strict := NewStrictListener(l, func(kind string, _ net.Addr) { counters.Add(kind) })
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s %s", r.URL.Path, body)
+		}),
+		ConnContext: strict.ConnContext,
+	}
+	go server.Serve(strict)
+	defer server.Close()
+
+	send := func(request string) string {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte(request))
+		require.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Sprintf("%d %s", resp.StatusCode, body)
+	}
+
+	assert.Equal(t, "200 /ok hello", send("POST /ok HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\n\r\nhello"))
+	assert.Equal(t, "400 400 Bad Request", send("POST /smuggled HTTP/1.1\r\nHost: a\r\nContent-Length: 6\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\nX"))
+
+	assert.Equal(t, map[string]uint64{AnomalyContentLengthWithTransferEncoding: 1}, counters.Snapshot())
+
+	t.Run("declined upgrade", func(t *testing.T) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("GET /upgrade HTTP/1.1\r\nHost: a\r\nUpgrade: foo\r\nConnection: Upgrade\r\n\r\n"))
+		require.NoError(t, err)
+
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "200 /upgrade ", fmt.Sprintf("%d %s", resp.StatusCode, body))
+
+		// the request smuggled behind the declined upgrade is checked
+		_, err = conn.Write([]byte("POST /smuggled HTTP/1.1\r\nHost: a\r\nContent-Length: 6\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\nX"))
+		require.NoError(t, err)
+
+		// the connection is closed, or the request rejected, without serving the smuggled request
+		if resp, err := http.ReadResponse(reader, nil); err == nil {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		}
+		assert.Equal(t, map[string]uint64{AnomalyContentLengthWithTransferEncoding: 2}, counters.Snapshot())
+	})
+}
+
+func TestStrictListener_StreamedBody(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	strict := NewStrictListener(l, nil)
+
+	anomalies := make(chan error, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the head and first chunk are read before the abnormal chunk is sent
+			assert.NoError(t, RequestAnomaly(r))
+			_, err := io.ReadAll(r.Body)
+			assert.True(t, IsAnomaly(err))
+			anomalies <- RequestAnomaly(r)
+		}),
+		ConnContext: strict.ConnContext,
+	}
+	go server.Serve(strict)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, err = conn.Write([]byte("5;\"\r\nhello\r\n0\r\n\r\n"))
+	require.NoError(t, err)
+
+	select {
+	case err := <-anomalies:
+		assert.Equal(t, &AnomalyError{Kind: AnomalyChunkExtension}, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the body wasn't read")
+	}
+}
+
+func TestStrictListener_TLS(t *testing.T) {
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	certificates := certServer.TLS.Certificates
+	client := certServer.Client()
+	certServer.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	tlsListener := tls.NewListener(l, &tls.Config{Certificates: certificates, NextProtos: []string{"h2", "http/1.1"}})
+	strict := NewStrictListener(tlsListener, nil)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			RestoreTLSState(r)
+			fmt.Fprintf(w, "%s %t", r.Proto, r.TLS != nil)
+		}),
+		ConnContext: strict.ConnContext,
+	}
+	go server.Serve(strict)
+	defer server.Close()
+
+	get := func(transport *http.Transport) string {
+		resp, err := (&http.Client{Transport: transport}).Get("https://" + l.Addr().String())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	transport := client.Transport.(*http.Transport)
+
+	http1 := transport.Clone()
+	http1.ForceAttemptHTTP2 = false
+	http1.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	assert.Equal(t, "HTTP/1.1 true", get(http1))
+
+	http2 := transport.Clone()
+	http2.ForceAttemptHTTP2 = true
+	assert.Equal(t, "HTTP/2.0 true", get(http2))
+}
@@ -0,0 +1,571 @@
+// Package jsonfilter filters the fields of JSON documents while streaming them, so that
+// the memory used doesn't depend on the size of the document.
+package jsonfilter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// DefaultMaxNodeSize is the default size limit of buffered nodes, in bytes.
+	DefaultMaxNodeSize = 64 * 1024
+
+	// maxDepth limits the nesting of documents, which are filtered recursively.
+	maxDepth = 1000
+
+	// arrayItem is the path segment matching every item of an array.
+	arrayItem = "[]"
+)
+
+var (
+	ErrNodeTooLarge = errors.New("JSON node exceeds the buffered node size limit")
+	ErrTooDeep      = errors.New("JSON document is nested too deeply")
+)
+
+// Filter keeps or removes fields of JSON documents by path. Paths are dot separated, with
+// `[]` for every item of an array, e.g. `orders.[].customer.email`.
+type Filter struct {
+	include     [][]string
+	exclude     [][]string
+	maxNodeSize int
+}
+
+// New returns a filter keeping only the included paths, or every path when none is
+// included, without the excluded paths. Object keys are the only nodes buffered while
+// filtering, up to maxNodeSize bytes; all values are streamed.
+func New(include, exclude []string, maxNodeSize int) *Filter {
+	if maxNodeSize <= 0 {
+		maxNodeSize = DefaultMaxNodeSize
+	}
+
+	return &Filter{
+		include:     splitPaths(include),
+		exclude:     splitPaths(exclude),
+		maxNodeSize: maxNodeSize,
+	}
+}
+
+func splitPaths(paths []string) [][]string {
+	split := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		split = append(split, strings.Split(path, "."))
+	}
+
+	return split
+}
+
+// Copy writes the filtered document read from src to dst.
+func (f *Filter) Copy(dst io.Writer, src io.Reader) error {
+	s := &stream{
+		filter: f,
+		r:      bufio.NewReader(src),
+		w:      bufio.NewWriter(dst),
+	}
+
+	if err := s.root(); err != nil {
+		return err
+	}
+
+	return s.w.Flush()
+}
+
+type action int
+
+const (
+	drop action = iota
+	keep
+	descend
+)
+
+// decide tells what to do with the value at a path.
+func (f *Filter) decide(path []string, container bool) action {
+	for _, exclude := range f.exclude {
+		if matchPrefix(exclude, path) && len(exclude) == len(path) {
+			return drop
+		}
+	}
+
+	included := len(f.include) == 0
+	partial := false
+	for _, include := range f.include {
+		switch {
+		case matchPrefix(path, include):
+			included = true
+		case matchPrefix(include, path):
+			partial = true
+		}
+	}
+
+	excludedBelow := false
+	for _, exclude := range f.exclude {
+		if len(exclude) > len(path) && matchPrefix(exclude, path) {
+			excludedBelow = true
+		}
+	}
+
+	switch {
+	case included && (!excludedBelow || !container):
+		return keep
+	case container && (included || partial):
+		return descend
+	default:
+		return drop
+	}
+}
+
+// matchPrefix tells whether prefix is a prefix of path, or the path itself.
+func matchPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type stream struct {
+	filter *Filter
+	r      *bufio.Reader
+	w      *bufio.Writer
+	depth  int
+}
+
+func (s *stream) root() error {
+	c, err := s.peek()
+	if err != nil {
+		return err
+	}
+
+	if c == '{' || c == '[' {
+		switch s.filter.decide(nil, true) {
+		case keep:
+			err = s.copyValue()
+		default:
+			err = s.filterValue(nil)
+		}
+	} else {
+		err = s.copyValue()
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.peek(); err != io.EOF {
+		if err == nil {
+			return errors.New("unexpected data after JSON document")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// peek returns the next byte which isn't whitespace, without consuming it.
+func (s *stream) peek() (byte, error) {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+
+		return c, s.r.UnreadByte()
+	}
+}
+
+func (s *stream) expect(expected byte) error {
+	c, err := s.peek()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if c != expected {
+		return fmt.Errorf("invalid character %q, expected %q", c, expected)
+	}
+
+	_, err = s.r.ReadByte()
+	return err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+func (s *stream) enter() error {
+	s.depth++
+	if s.depth > maxDepth {
+		return ErrTooDeep
+	}
+
+	return nil
+}
+
+// filterValue writes the kept members or items of an object or array.
+func (s *stream) filterValue(path []string) error {
+	c, err := s.peek()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if err := s.enter(); err != nil {
+		return err
+	}
+	defer func() { s.depth-- }()
+
+	if c == '[' {
+		return s.filterArray(path)
+	}
+
+	return s.filterObject(path)
+}
+
+func (s *stream) filterObject(path []string) error {
+	if err := s.expect('{'); err != nil {
+		return err
+	}
+	s.w.WriteByte('{')
+
+	written := false
+	for first := true; ; first = false {
+		c, err := s.peek()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if c == '}' && first {
+			break
+		}
+
+		rawKey, key, err := s.readKey()
+		if err != nil {
+			return err
+		}
+		if err := s.expect(':'); err != nil {
+			return err
+		}
+
+		child := append(path[:len(path):len(path)], key)
+		act, err := s.decideNext(child)
+		if err != nil {
+			return err
+		}
+
+		if act != drop {
+			if written {
+				s.w.WriteByte(',')
+			}
+			s.w.Write(rawKey)
+			s.w.WriteByte(':')
+			written = true
+		}
+
+		if err := s.apply(act, child); err != nil {
+			return err
+		}
+
+		if done, err := s.next('}'); err != nil || done {
+			return s.closeWith(err, '}')
+		}
+	}
+
+	_, err := s.r.ReadByte()
+	s.w.WriteByte('}')
+	return err
+}
+
+func (s *stream) filterArray(path []string) error {
+	if err := s.expect('['); err != nil {
+		return err
+	}
+	s.w.WriteByte('[')
+
+	child := append(path[:len(path):len(path)], arrayItem)
+
+	written := false
+	for first := true; ; first = false {
+		c, err := s.peek()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if c == ']' && first {
+			break
+		}
+
+		act, err := s.decideNext(child)
+		if err != nil {
+			return err
+		}
+
+		if act != drop {
+			if written {
+				s.w.WriteByte(',')
+			}
+			written = true
+		}
+
+		if err := s.apply(act, child); err != nil {
+			return err
+		}
+
+		if done, err := s.next(']'); err != nil || done {
+			return s.closeWith(err, ']')
+		}
+	}
+
+	_, err := s.r.ReadByte()
+	s.w.WriteByte(']')
+	return err
+}
+
+// decideNext decides what to do with the next value, at the given path.
+func (s *stream) decideNext(path []string) (action, error) {
+	c, err := s.peek()
+	if err != nil {
+		return drop, unexpectedEOF(err)
+	}
+
+	return s.filter.decide(path, c == '{' || c == '['), nil
+}
+
+func (s *stream) apply(act action, path []string) error {
+	switch act {
+	case keep:
+		return s.copyValue()
+	case descend:
+		return s.filterValue(path)
+	default:
+		return s.skipValue()
+	}
+}
+
+// next consumes the separator after a member or item, and tells whether it was the
+// closing delimiter.
+func (s *stream) next(closing byte) (bool, error) {
+	c, err := s.peek()
+	if err != nil {
+		return false, unexpectedEOF(err)
+	}
+
+	switch c {
+	case ',':
+		_, err = s.r.ReadByte()
+		return false, err
+	case closing:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid character %q after value", c)
+	}
+}
+
+func (s *stream) closeWith(err error, closing byte) error {
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.r.ReadByte(); err != nil {
+		return err
+	}
+
+	return s.w.WriteByte(closing)
+}
+
+// readKey reads an object key, returning it as it is in the document and decoded.
+func (s *stream) readKey() ([]byte, string, error) {
+	if c, err := s.peek(); err != nil || c != '"' {
+		if err != nil {
+			return nil, "", unexpectedEOF(err)
+		}
+		return nil, "", fmt.Errorf("invalid character %q, expected object key", c)
+	}
+
+	var raw []byte
+	if err := s.scanString(func(c byte) error {
+		if len(raw) >= s.filter.maxNodeSize {
+			return ErrNodeTooLarge
+		}
+		raw = append(raw, c)
+		return nil
+	}); err != nil {
+		return nil, "", err
+	}
+
+	var key string
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return nil, "", err
+	}
+
+	return raw, key, nil
+}
+
+func (s *stream) copyValue() error {
+	return s.scanValue(s.w.WriteByte)
+}
+
+func (s *stream) skipValue() error {
+	return s.scanValue(func(byte) error { return nil })
+}
+
+// scanValue streams the next value to emit, without whitespace.
+func (s *stream) scanValue(emit func(byte) error) error {
+	c, err := s.peek()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	switch {
+	case c == '"':
+		return s.scanString(emit)
+	case c == '{' || c == '[':
+		return s.scanContainer(emit)
+	case c == '-' || c >= '0' && c <= '9':
+		return s.scanWhile(emit, func(c byte) bool {
+			return c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+		})
+	case c == 't' || c == 'f' || c == 'n':
+		var literal []byte
+		err := s.scanWhile(func(c byte) error {
+			literal = append(literal, c)
+			return emit(c)
+		}, func(c byte) bool { return c >= 'a' && c <= 'z' && len(literal) < 5 })
+		if err != nil {
+			return err
+		}
+
+		switch string(literal) {
+		case "true", "false", "null":
+			return nil
+		}
+		return fmt.Errorf("invalid literal %q", literal)
+	default:
+		return fmt.Errorf("invalid character %q looking for beginning of value", c)
+	}
+}
+
+func (s *stream) scanWhile(emit func(byte) error, accept func(byte) bool) error {
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !accept(c) {
+			return s.r.UnreadByte()
+		}
+
+		if err := emit(c); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *stream) scanString(emit func(byte) error) error {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if err := emit(c); err != nil {
+		return err
+	}
+
+	escaped := false
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if err := emit(c); err != nil {
+			return err
+		}
+
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return nil
+		}
+	}
+}
+
+func (s *stream) scanContainer(emit func(byte) error) error {
+	if err := s.enter(); err != nil {
+		return err
+	}
+	defer func() { s.depth-- }()
+
+	opening, _ := s.r.ReadByte()
+	closing := byte('}')
+	if opening == '[' {
+		closing = ']'
+	}
+	if err := emit(opening); err != nil {
+		return err
+	}
+
+	if c, err := s.peek(); err != nil || c == closing {
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		s.r.ReadByte()
+		return emit(closing)
+	}
+
+	for {
+		if opening == '{' {
+			if c, err := s.peek(); err != nil || c != '"' {
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				return fmt.Errorf("invalid character %q, expected object key", c)
+			}
+			if err := s.scanString(emit); err != nil {
+				return err
+			}
+			if err := s.expect(':'); err != nil {
+				return err
+			}
+			if err := emit(':'); err != nil {
+				return err
+			}
+		}
+
+		if err := s.scanValue(emit); err != nil {
+			return err
+		}
+
+		done, err := s.next(closing)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			if _, err := s.r.ReadByte(); err != nil {
+				return err
+			}
+			return emit(closing)
+		}
+		if err := emit(','); err != nil {
+			return err
+		}
+	}
+}
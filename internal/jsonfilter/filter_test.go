@@ -0,0 +1,136 @@
+package jsonfilter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const document = `{
+	"id": 1,
+	"user": {"name": "Jane", "email": "jane@example.com", "tags": ["a", "b"]},
+	"orders": [
+		{"id": "o1", "total": 12.5e0, "card": {"pan": "4111", "exp": "12/30"}},
+		{"id": "o2", "total": -3, "card": null}
+	],
+	"esc\"aped": "va\"lue\\",
+	"flags": [true, false, null, [], {}]
+}`
+
+func TestFilter_Copy(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected string
+	}{
+		{
+			name:     "no filter",
+			expected: `{"id":1,"user":{"name":"Jane","email":"jane@example.com","tags":["a","b"]},"orders":[{"id":"o1","total":12.5e0,"card":{"pan":"4111","exp":"12/30"}},{"id":"o2","total":-3,"card":null}],"esc\"aped":"va\"lue\\","flags":[true,false,null,[],{}]}`,
+		},
+		{
+			name:     "exclude",
+			exclude:  []string{"user.email", "orders.[].card.pan", "esc\"aped", "flags"},
+			expected: `{"id":1,"user":{"name":"Jane","tags":["a","b"]},"orders":[{"id":"o1","total":12.5e0,"card":{"exp":"12/30"}},{"id":"o2","total":-3,"card":null}]}`,
+		},
+		{
+			name:     "include",
+			include:  []string{"id", "user.name", "orders.[].id"},
+			expected: `{"id":1,"user":{"name":"Jane"},"orders":[{"id":"o1"},{"id":"o2"}]}`,
+		},
+		{
+			name:     "include and exclude",
+			include:  []string{"orders"},
+			exclude:  []string{"orders.[].card"},
+			expected: `{"orders":[{"id":"o1","total":12.5e0},{"id":"o2","total":-3}]}`,
+		},
+		{
+			name:     "exclude array items",
+			exclude:  []string{"user.tags.[]"},
+			expected: `{"id":1,"user":{"name":"Jane","email":"jane@example.com","tags":[]},"orders":[{"id":"o1","total":12.5e0,"card":{"pan":"4111","exp":"12/30"}},{"id":"o2","total":-3,"card":null}],"esc\"aped":"va\"lue\\","flags":[true,false,null,[],{}]}`,
+		},
+		{
+			name:     "include missing path",
+			include:  []string{"missing.field"},
+			expected: `{}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, New(tc.include, tc.exclude, 0).Copy(&out, strings.NewReader(document)))
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestFilter_Compact(t *testing.T) {
+	const doc = `{"id":1,"user":{"name":"Jane","email":"jane@example.com"},"tags":["a","b"],"secret":"s"}`
+
+	var out bytes.Buffer
+	require.NoError(t, New(nil, []string{"secret"}, 0).Copy(&out, strings.NewReader(doc)))
+	assert.Equal(t, `{"id":1,"user":{"name":"Jane","email":"jane@example.com"},"tags":["a","b"]}`, out.String())
+}
+
+func TestFilter_Scalars(t *testing.T) {
+	for _, doc := range []string{`"string"`, `12`, `true`, `null`} {
+		var out bytes.Buffer
+		require.NoError(t, New([]string{"a"}, nil, 0).Copy(&out, strings.NewReader(" "+doc+"\n")))
+		assert.Equal(t, doc, out.String())
+	}
+}
+
+func TestFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  error
+	}{
+		{name: "truncated", doc: `{"a": [1, 2`, err: io.ErrUnexpectedEOF},
+		{name: "unterminated string", doc: `{"a": "b`, err: io.ErrUnexpectedEOF},
+		{name: "missing colon", doc: `{"a" 1}`},
+		{name: "trailing comma", doc: `{"a": 1,}`},
+		{name: "invalid literal", doc: `{"a": nope}`},
+		{name: "trailing data", doc: `{} {}`},
+		{name: "key too large", doc: `{"` + strings.Repeat("k", 20) + `": 1}`, err: ErrNodeTooLarge},
+		{name: "too deep", doc: strings.Repeat("[", maxDepth+1) + strings.Repeat("]", maxDepth+1), err: ErrTooDeep},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := New([]string{"a"}, nil, 16).Copy(io.Discard, strings.NewReader(tc.doc))
+			assert.Error(t, err)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestFilter_Streaming(t *testing.T) {
+	const items = 100000
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte(`{"items":[`))
+		for i := 0; i < items; i++ {
+			if i > 0 {
+				pw.Write([]byte(","))
+			}
+			fmt.Fprintf(pw, `{"id":%d,"secret":"%s"}`, i, strings.Repeat("s", 100))
+		}
+		pw.Write([]byte(`]}`))
+		pw.Close()
+	}()
+
+	var out bytes.Buffer
+	require.NoError(t, New(nil, []string{"items.[].secret"}, 0).Copy(&out, pr))
+	assert.NotContains(t, out.String(), "secret")
+	assert.True(t, strings.HasSuffix(out.String(), fmt.Sprintf(`{"id":%d}]}`, items-1)))
+}
@@ -96,8 +96,12 @@ func (t *Service) Apply(session *user.SessionState) error {
 	}
 
 	var (
-		err       error
-		policyIDs []string
+		err          error
+		policyIDs    []string
+		quotaBuckets []user.QuotaBucket
+		quotaPool    string
+		didQuota     bool
+		flags        map[string]bool
 	)
 
 	storage := t.storage
@@ -129,6 +133,10 @@ func (t *Service) Apply(session *user.SessionState) error {
 			return err
 		}
 
+		if len(policy.DynamicLimits) > 0 {
+			policy = t.applyDynamicLimits(policy, session)
+		}
+
 		if policy.Partitions.PerAPI && policy.Partitions.Enabled() {
 			err := fmt.Errorf("cannot apply policy %s which has per_api and any of partitions set", policy.ID)
 			t.logger.Error(err)
@@ -145,6 +153,15 @@ func (t *Service) Apply(session *user.SessionState) error {
 			}
 		}
 
+		if !policy.Partitions.Enabled() || policy.Partitions.Quota {
+			didQuota = true
+			quotaBuckets = mergeQuotaBuckets(quotaBuckets, policy.QuotaBuckets)
+
+			if policy.QuotaPool != "" {
+				quotaPool = policy.QuotaPool
+			}
+		}
+
 		session.IsInactive = session.IsInactive || policy.IsInactive
 
 		for _, tag := range policy.Tags {
@@ -155,6 +172,13 @@ func (t *Service) Apply(session *user.SessionState) error {
 			session.MetaData[k] = v
 		}
 
+		for name, enabled := range policy.MiddlewareFlags {
+			if flags == nil {
+				flags = make(map[string]bool)
+			}
+			flags[name] = enabled
+		}
+
 		if policy.LastUpdated > session.LastUpdated {
 			session.LastUpdated = policy.LastUpdated
 		}
@@ -170,6 +194,19 @@ func (t *Service) Apply(session *user.SessionState) error {
 		session.Tags = appendIfMissing(session.Tags, tag)
 	}
 
+	if len(policyIDs) > 0 {
+		session.QuotaBuckets = keepQuotaBucketState(quotaBuckets, session.QuotaBuckets)
+	}
+
+	// the flags of the policies are kept apart from the flags of the key, so that a flag removed
+	// from the policies doesn't stay on the key
+	session.PolicyMiddlewareFlags = flags
+
+	// the pool is the one of the policies setting the quota, a key leaves a pool removed from them
+	if didQuota {
+		session.QuotaPool = quotaPool
+	}
+
 	if len(policyIDs) == 0 {
 		for apiID, accessRight := range session.AccessRights {
 			// check if the api in the session has per api limit
@@ -212,6 +249,7 @@ func (t *Service) Apply(session *user.SessionState) error {
 			v.Limit.QuotaMax = session.QuotaMax
 			v.Limit.QuotaRenewalRate = session.QuotaRenewalRate
 			v.Limit.QuotaRenews = session.QuotaRenews
+			v.Limit.QuotaAlignment = session.QuotaAlignment
 		}
 
 		// If multime ACL
@@ -370,6 +408,7 @@ func (t *Service) applyPartitions(policy user.Policy, session *user.SessionState
 				r.Versions = appendIfMissing(rights[k].Versions, v.Versions...)
 
 				r.AllowedURLs = MergeAllowedURLs(r.AllowedURLs, v.AllowedURLs)
+				r.Scopes = appendIfMissing(r.Scopes, v.Scopes...)
 
 				if len(r.RestrictedTypes) == 0 {
 					r.RestrictedTypes = v.RestrictedTypes
@@ -441,6 +480,13 @@ func (t *Service) applyPartitions(policy user.Policy, session *user.SessionState
 					session.QuotaRenewalRate = policy.QuotaRenewalRate
 				}
 			}
+
+			if policy.QuotaAlignment != nil && ar.Limit.QuotaAlignment == nil {
+				ar.Limit.QuotaAlignment = policy.QuotaAlignment
+				if session.QuotaAlignment == nil {
+					session.QuotaAlignment = policy.QuotaAlignment
+				}
+			}
 		}
 
 		if !usePartitions || policy.Partitions.RateLimit {
@@ -503,6 +549,7 @@ func (t *Service) applyPartitions(policy user.Policy, session *user.SessionState
 		if !usePartitions || policy.Partitions.Quota {
 			session.QuotaMax = policy.QuotaMax
 			session.QuotaRenewalRate = policy.QuotaRenewalRate
+			session.QuotaAlignment = policy.QuotaAlignment
 		}
 	}
 
@@ -532,6 +579,7 @@ func (t *Service) updateSessionRootVars(session *user.SessionState, rights map[s
 				session.QuotaMax = v.Limit.QuotaMax
 				session.QuotaRenews = v.Limit.QuotaRenews
 				session.QuotaRenewalRate = v.Limit.QuotaRenewalRate
+				session.QuotaAlignment = v.Limit.QuotaAlignment
 			}
 
 			if len(applyState.didComplexity) == 1 {
@@ -559,6 +607,10 @@ func (t *Service) applyAPILevelLimits(policyAD user.AccessDefinition, currAD use
 		policyAD.Limit.QuotaRenewalRate = currAD.Limit.QuotaRenewalRate
 	}
 
+	if policyAD.Limit.QuotaAlignment == nil {
+		policyAD.Limit.QuotaAlignment = currAD.Limit.QuotaAlignment
+	}
+
 	if policyAD.Limit.QuotaMax == -1 {
 		policyAD.Limit.QuotaRenewalRate = 0
 	}
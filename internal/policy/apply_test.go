@@ -143,6 +143,201 @@ func TestApplyRateLimits_FromCustomPolicies(t *testing.T) {
 	assert.Equal(t, 10, int(session.Rate))
 }
 
+func TestApplyRateLimits_DynamicLimits(t *testing.T) {
+	svc := policy.New(nil, nil, logrus.New())
+
+	pol := user.Policy{
+		ID:           "tiered",
+		Partitions:   user.PolicyPartitions{RateLimit: true, Quota: true},
+		Rate:         10,
+		Per:          1,
+		QuotaMax:     100,
+		AccessRights: map[string]user.AccessDefinition{"a": {}},
+		DynamicLimits: []user.DynamicLimit{
+			{When: `meta.tier ==`, Rate: 5000},
+			{When: `meta.tier == "gold"`, Rate: 1000, QuotaMax: -1},
+			{When: `meta.tier == "silver" || meta.seats > 10`, Rate: 100},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		meta     map[string]interface{}
+		rate     float64
+		quotaMax int64
+	}{
+		{name: "no metadata", rate: 10, quotaMax: 100},
+		{name: "gold", meta: map[string]interface{}{"tier": "gold"}, rate: 1000, quotaMax: -1},
+		{name: "silver", meta: map[string]interface{}{"tier": "silver"}, rate: 100, quotaMax: 100},
+		{name: "seats", meta: map[string]interface{}{"tier": "bronze", "seats": 20}, rate: 100, quotaMax: 100},
+		{name: "bronze", meta: map[string]interface{}{"tier": "bronze", "seats": 2}, rate: 10, quotaMax: 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			session := &user.SessionState{MetaData: tc.meta}
+			session.SetCustomPolicies([]user.Policy{pol})
+
+			assert.NoError(t, svc.Apply(session))
+			assert.Equal(t, tc.rate, session.Rate)
+			assert.Equal(t, float64(1), session.Per)
+			assert.Equal(t, tc.quotaMax, session.QuotaMax)
+		})
+	}
+}
+
+func TestApplyRateLimits_DynamicLimitsPerAPI(t *testing.T) {
+	svc := policy.New(nil, nil, logrus.New())
+
+	pol := user.Policy{
+		ID:         "tiered-per-api",
+		Partitions: user.PolicyPartitions{PerAPI: true},
+		AccessRights: map[string]user.AccessDefinition{"a": {
+			APIID: "a",
+			Limit: user.APILimit{RateLimit: user.RateLimit{Rate: 10, Per: 1}, QuotaMax: 100},
+		}},
+		DynamicLimits: []user.DynamicLimit{{When: `meta.tier == "gold"`, Rate: 1000, QuotaMax: -1}},
+	}
+
+	session := &user.SessionState{MetaData: map[string]interface{}{"tier": "gold"}}
+	session.SetCustomPolicies([]user.Policy{pol})
+
+	assert.NoError(t, svc.Apply(session))
+	assert.Equal(t, float64(1000), session.AccessRights["a"].Limit.Rate)
+	assert.Equal(t, float64(1), session.AccessRights["a"].Limit.Per)
+	assert.Equal(t, int64(-1), session.AccessRights["a"].Limit.QuotaMax)
+	assert.Equal(t, float64(10), pol.AccessRights["a"].Limit.Rate, "the policy shouldn't change")
+}
+
+func TestValidateDynamicLimits(t *testing.T) {
+	assert.NoError(t, policy.ValidateDynamicLimits(user.Policy{DynamicLimits: []user.DynamicLimit{{When: `meta.tier == "gold"`}}}))
+	assert.Error(t, policy.ValidateDynamicLimits(user.Policy{DynamicLimits: []user.DynamicLimit{{When: `meta.tier ==`}}}))
+}
+
+func TestApplyQuota_QuotaBuckets(t *testing.T) {
+	svc := &policy.Service{}
+
+	session := &user.SessionState{
+		QuotaBuckets: []user.QuotaBucket{{Name: "writes", QuotaMax: 10, QuotaRemaining: 3, QuotaRenews: 1700000000}},
+	}
+	session.SetCustomPolicies([]user.Policy{
+		{
+			ID:           "reads",
+			AccessRights: map[string]user.AccessDefinition{"a": {}},
+			QuotaBuckets: []user.QuotaBucket{{Name: "reads", Methods: []string{"GET"}, QuotaMax: 1000}},
+		},
+		{
+			ID:           "writes",
+			Partitions:   user.PolicyPartitions{Quota: true},
+			AccessRights: map[string]user.AccessDefinition{"a": {}},
+			QuotaBuckets: []user.QuotaBucket{
+				{Name: "reads", Methods: []string{"GET"}, QuotaMax: 100},
+				{Name: "writes", Methods: []string{"POST", "PUT"}, QuotaMax: 50},
+			},
+		},
+	})
+
+	assert.NoError(t, svc.Apply(session))
+	assert.Equal(t, []user.QuotaBucket{
+		{Name: "reads", Methods: []string{"GET"}, QuotaMax: 1000},
+		{Name: "writes", Methods: []string{"POST", "PUT"}, QuotaMax: 50, QuotaRemaining: 3, QuotaRenews: 1700000000},
+	}, session.QuotaBuckets)
+}
+
+func TestApplyQuota_QuotaPool(t *testing.T) {
+	svc := &policy.Service{}
+
+	pooled := user.Policy{
+		ID:           "pooled",
+		AccessRights: map[string]user.AccessDefinition{"a": {}},
+		QuotaPool:    "acme",
+	}
+
+	session := &user.SessionState{}
+	session.SetCustomPolicies([]user.Policy{pooled})
+	assert.NoError(t, svc.Apply(session))
+	assert.Equal(t, "acme", session.QuotaPool)
+
+	t.Run("pool removed from the policy", func(t *testing.T) {
+		unpooled := pooled
+		unpooled.QuotaPool = ""
+		session.SetCustomPolicies([]user.Policy{unpooled})
+
+		assert.NoError(t, svc.Apply(session))
+		assert.Empty(t, session.QuotaPool)
+	})
+
+	t.Run("policies not setting the quota", func(t *testing.T) {
+		session := &user.SessionState{QuotaPool: "own"}
+		session.SetCustomPolicies([]user.Policy{{
+			ID:           "acl",
+			Partitions:   user.PolicyPartitions{Acl: true},
+			AccessRights: map[string]user.AccessDefinition{"a": {}},
+		}})
+
+		assert.NoError(t, svc.Apply(session))
+		assert.Equal(t, "own", session.QuotaPool)
+	})
+}
+
+func TestApplyMiddlewareFlags(t *testing.T) {
+	svc := &policy.Service{}
+
+	pilot := user.Policy{
+		ID:              "pilot",
+		AccessRights:    map[string]user.AccessDefinition{"a": {}},
+		MiddlewareFlags: map[string]bool{"TransformHeaders": true, "HeaderInjector": false},
+	}
+
+	session := &user.SessionState{MiddlewareFlags: map[string]bool{"HeaderInjector": true, "URLRewrite": true}}
+	session.SetCustomPolicies([]user.Policy{pilot})
+
+	assert.NoError(t, svc.Apply(session))
+	assert.Equal(t, map[string]bool{"HeaderInjector": true, "URLRewrite": true}, session.MiddlewareFlags, "the flags of the key are kept")
+	assert.True(t, session.MiddlewareEnabled("TransformHeaders"))
+	assert.False(t, session.MiddlewareEnabled("HeaderInjector"), "the flags of the policies take precedence")
+	assert.True(t, session.MiddlewareEnabled("URLRewrite"))
+
+	t.Run("flag removed from the policy", func(t *testing.T) {
+		removed := pilot
+		removed.MiddlewareFlags = map[string]bool{"HeaderInjector": false}
+		session.SetCustomPolicies([]user.Policy{removed})
+
+		assert.NoError(t, svc.Apply(session))
+		assert.Equal(t, map[string]bool{"HeaderInjector": false}, session.PolicyMiddlewareFlags)
+		assert.False(t, session.MiddlewareEnabled("TransformHeaders"))
+	})
+
+	t.Run("flags removed from the policy", func(t *testing.T) {
+		removed := pilot
+		removed.MiddlewareFlags = nil
+		session.SetCustomPolicies([]user.Policy{removed})
+
+		assert.NoError(t, svc.Apply(session))
+		assert.Nil(t, session.PolicyMiddlewareFlags)
+		assert.True(t, session.MiddlewareEnabled("HeaderInjector"))
+	})
+}
+
+func TestApplyACL_MergesScopes(t *testing.T) {
+	svc := &policy.Service{}
+
+	session := &user.SessionState{}
+	session.SetCustomPolicies([]user.Policy{
+		{
+			ID:           "read",
+			AccessRights: map[string]user.AccessDefinition{"orders": {Scopes: []string{"orders:read"}}},
+		},
+		{
+			ID:           "write",
+			AccessRights: map[string]user.AccessDefinition{"orders": {Scopes: []string{"orders:read", "orders:write"}}},
+		},
+	})
+
+	assert.NoError(t, svc.Apply(session))
+	assert.Equal(t, []string{"orders:read", "orders:write"}, session.AccessRights["orders"].Scopes)
+}
+
 func TestApplyACL_FromCustomPolicies(t *testing.T) {
 	svc := &policy.Service{}
 
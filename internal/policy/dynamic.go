@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/PaesslerAG/gval"
+
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// dynamicLimitExpressions caches the compiled expressions of dynamic limits, policies are
+// applied on every request.
+var dynamicLimitExpressions sync.Map
+
+func dynamicLimitExpression(expression string) (gval.Evaluable, error) {
+	if cached, ok := dynamicLimitExpressions.Load(expression); ok {
+		return cached.(gval.Evaluable), nil
+	}
+
+	evaluable, err := gval.Full().NewEvaluable(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicLimitExpressions.Store(expression, evaluable)
+	return evaluable, nil
+}
+
+// ValidateDynamicLimits compiles the expressions of the dynamic limits of a policy, and returns
+// an error when one of them is invalid. The compiled expressions are cached for the requests.
+func ValidateDynamicLimits(policy user.Policy) error {
+	for _, limit := range policy.DynamicLimits {
+		if _, err := dynamicLimitExpression(limit.When); err != nil {
+			return fmt.Errorf("invalid dynamic limit expression %q: %w", limit.When, err)
+		}
+	}
+
+	return nil
+}
+
+// applyDynamicLimits returns the policy with the rate limit and quota of the first dynamic
+// limit matching the session metadata, applied to the policy and to its per API limits.
+func (t *Service) applyDynamicLimits(policy user.Policy, session *user.SessionState) user.Policy {
+	parameters := map[string]interface{}{"meta": session.MetaData}
+
+	for _, limit := range policy.DynamicLimits {
+		evaluable, err := dynamicLimitExpression(limit.When)
+		if err != nil {
+			t.Logger().WithError(err).WithField("policy_id", policy.ID).Errorf("invalid dynamic limit expression %q", limit.When)
+			continue
+		}
+
+		// metadata missing from the session doesn't match
+		matches, err := evaluable.EvalBool(context.Background(), parameters)
+		if err != nil || !matches {
+			continue
+		}
+
+		if limit.Rate != 0 {
+			policy.Rate = limit.Rate
+		}
+		if limit.Per != 0 {
+			policy.Per = limit.Per
+		}
+		if limit.QuotaMax != 0 {
+			policy.QuotaMax = limit.QuotaMax
+		}
+		if limit.QuotaRenewalRate != 0 {
+			policy.QuotaRenewalRate = limit.QuotaRenewalRate
+		}
+
+		// the access rights are copied, they are shared with the stored policy
+		rights := make(map[string]user.AccessDefinition, len(policy.AccessRights))
+		for apiID, access := range policy.AccessRights {
+			if !access.Limit.IsEmpty() {
+				access.Limit = dynamicAPILimit(limit, access.Limit)
+			}
+			rights[apiID] = access
+		}
+		policy.AccessRights = rights
+
+		return policy
+	}
+
+	return policy
+}
+
+// dynamicAPILimit returns a per API limit with the rate limit and quota of a dynamic limit.
+func dynamicAPILimit(limit user.DynamicLimit, apiLimit user.APILimit) user.APILimit {
+	if limit.Rate != 0 {
+		apiLimit.Rate = limit.Rate
+	}
+	if limit.Per != 0 {
+		apiLimit.Per = limit.Per
+	}
+	if limit.QuotaMax != 0 {
+		apiLimit.QuotaMax = limit.QuotaMax
+	}
+	if limit.QuotaRenewalRate != 0 {
+		apiLimit.QuotaRenewalRate = limit.QuotaRenewalRate
+	}
+
+	return apiLimit
+}
@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"github.com/TykTechnologies/tyk/user"
+)
+
+// mergeQuotaBuckets adds the quota buckets of a policy to buckets. When several policies
+// define a bucket with the same name, the bucket with the greatest quota applies.
+func mergeQuotaBuckets(buckets []user.QuotaBucket, policyBuckets []user.QuotaBucket) []user.QuotaBucket {
+	for _, bucket := range policyBuckets {
+		i := quotaBucketIndex(buckets, bucket.Name)
+		if i < 0 {
+			buckets = append(buckets, bucket)
+			continue
+		}
+
+		if greaterThanInt64(bucket.QuotaMax, buckets[i].QuotaMax) {
+			buckets[i] = bucket
+		}
+	}
+
+	return buckets
+}
+
+// keepQuotaBucketState carries the remaining quota and the renewal time of the buckets of
+// the session over to the buckets set by the policies.
+func keepQuotaBucketState(buckets []user.QuotaBucket, current []user.QuotaBucket) []user.QuotaBucket {
+	for i := range buckets {
+		if j := quotaBucketIndex(current, buckets[i].Name); j >= 0 {
+			buckets[i].QuotaRemaining = current[j].QuotaRemaining
+			buckets[i].QuotaRenews = current[j].QuotaRenews
+		}
+	}
+
+	return buckets
+}
+
+func quotaBucketIndex(buckets []user.QuotaBucket, name string) int {
+	for i := range buckets {
+		if buckets[i].Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,166 @@
+package rate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/tyk/internal/redis"
+)
+
+// atomicScript checks the sliding log rate limit and counts the request in the quota in a
+// single round trip. Requests blocked by the rate limit aren't counted in the quota.
+//
+// KEYS[1] is the sliding log, KEYS[2] the quota counter.
+// ARGV are the current time and the start of the window in nanoseconds, the rate, the period in
+// seconds, the quota max, the quota renewal in milliseconds, the TTL in milliseconds above
+// which the quota is renewed and whether it's a dry run (0 or 1). Dry runs only read the keys.
+//
+// It returns the rate limit and quota verdicts (0 or 1), the quota used and the quota TTL in
+// milliseconds.
+var atomicScript = redis.NewScript(`
+local rate = tonumber(ARGV[3])
+local per = tonumber(ARGV[4])
+local quotaMax = tonumber(ARGV[5])
+local renewal = tonumber(ARGV[6])
+local maxTTL = tonumber(ARGV[7])
+local dryRun = ARGV[8] == '1'
+
+if rate > 0 then
+	local count
+	if dryRun then
+		count = redis.call('ZCOUNT', KEYS[1], '(' .. ARGV[2], '+inf')
+	else
+		redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[2])
+		count = redis.call('ZCARD', KEYS[1])
+		redis.call('ZADD', KEYS[1], ARGV[1], ARGV[1])
+		redis.call('PEXPIRE', KEYS[1], per * 1000)
+	end
+	if count >= rate then
+		return {1, 0, 0, 0}
+	end
+end
+
+if quotaMax <= 0 then
+	return {0, 0, 0, 0}
+end
+
+local used
+if dryRun then
+	used = tonumber(redis.call('GET', KEYS[2]) or '0') + 1
+else
+	used = redis.call('INCR', KEYS[2])
+end
+local ttl = redis.call('PTTL', KEYS[2])
+if renewal > 0 and (ttl < 0 or (maxTTL > 0 and ttl > maxTTL)) then
+	used = 1
+	ttl = renewal
+	if not dryRun then
+		redis.call('SET', KEYS[2], used, 'PX', renewal)
+	end
+end
+
+local blocked = 0
+if used > quotaMax then
+	blocked = 1
+end
+
+return {0, blocked, used, ttl}
+`)
+
+// ErrAtomicKeys is returned when the keys of the atomic limiter can't share a hash slot.
+var ErrAtomicKeys = errors.New("atomic rate limiter keys can't contain hash tags")
+
+// AtomicLimit is the limit checked by the atomic limiter.
+type AtomicLimit struct {
+	// Rate is the number of requests allowed per Per seconds, the rate isn't limited when 0.
+	Rate int64
+	Per  int64
+
+	// QuotaMax is the number of requests allowed until the quota renews, the quota isn't
+	// counted when 0.
+	QuotaMax int64
+	// QuotaRenewal is the duration after which the quota renews, it never renews when 0.
+	QuotaRenewal time.Duration
+	// QuotaMaxTTL renews counters expiring later than it, it's ignored when 0.
+	QuotaMaxTTL time.Duration
+
+	// DryRun returns the verdict the request would get, without counting it.
+	DryRun bool
+}
+
+// AtomicResult is the verdict of the atomic limiter.
+type AtomicResult struct {
+	RateLimited   bool
+	QuotaExceeded bool
+	// QuotaRemaining is the quota left after the request.
+	QuotaRemaining int64
+	// QuotaRenews is the time the quota renews at, zero when it doesn't expire.
+	QuotaRenews time.Time
+}
+
+// AtomicLimiter checks the sliding log rate limit and the quota of a request with a single
+// Lua script, in one Redis round trip.
+type AtomicLimiter struct {
+	conn redis.UniversalClient
+}
+
+// NewAtomicLimiter creates a new AtomicLimiter.
+func NewAtomicLimiter(conn redis.UniversalClient) *AtomicLimiter {
+	return &AtomicLimiter{conn: conn}
+}
+
+// AtomicKey returns the key of the sliding log of the rate limiter key, sharing the Redis
+// Cluster hash slot of the quota key: the quota key is used as the hash tag.
+func AtomicKey(quotaKey, limiterKey string) (string, error) {
+	if strings.ContainsAny(quotaKey, "{}") {
+		return "", ErrAtomicKeys
+	}
+
+	return "{" + quotaKey + "}" + limiterKey, nil
+}
+
+// Do checks the limit for the sliding log at rateKey and the quota counter at quotaKey.
+func (l *AtomicLimiter) Do(ctx context.Context, now time.Time, rateKey, quotaKey string, limit AtomicLimit) (*AtomicResult, error) {
+	windowStart := now.Add(-time.Duration(limit.Per) * time.Second)
+
+	values, err := atomicScript.Run(ctx, l.conn, []string{rateKey, quotaKey},
+		strconv.FormatInt(now.UnixNano(), 10),
+		strconv.FormatInt(windowStart.UnixNano(), 10),
+		limit.Rate,
+		limit.Per,
+		limit.QuotaMax,
+		limit.QuotaRenewal.Milliseconds(),
+		limit.QuotaMaxTTL.Milliseconds(),
+		dryRunArg(limit.DryRun),
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected atomic rate limiter result %v", values)
+	}
+
+	result := &AtomicResult{
+		RateLimited:    values[0] == 1,
+		QuotaExceeded:  values[1] == 1,
+		QuotaRemaining: max(limit.QuotaMax-values[2], 0),
+	}
+
+	if values[3] > 0 {
+		result.QuotaRenews = now.Add(time.Duration(values[3]) * time.Millisecond)
+	}
+
+	return result, nil
+}
+
+func dryRunArg(dryRun bool) int {
+	if dryRun {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package rate_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/rate"
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+func TestAtomicLimiter_Do(t *testing.T) {
+	ctx := context.Background()
+
+	conf, err := config.New()
+	assert.NoError(t, err)
+
+	conn, err := storage.NewConnector(storage.DefaultConn, *conf)
+	assert.NoError(t, err)
+
+	var db redis.UniversalClient
+	assert.True(t, conn.As(&db))
+
+	quotaKey := "quota-" + uuid.New()
+	rateKey, err := rate.AtomicKey(quotaKey, "rate-limit-"+uuid.New())
+	assert.NoError(t, err)
+
+	limiter := rate.NewAtomicLimiter(db)
+	limit := rate.AtomicLimit{Rate: 2, Per: 60, QuotaMax: 3, QuotaRenewal: time.Minute}
+
+	now := time.Now()
+
+	result, err := limiter.Do(ctx, now, rateKey, quotaKey, limit)
+	assert.NoError(t, err)
+	assert.False(t, result.RateLimited)
+	assert.False(t, result.QuotaExceeded)
+	assert.Equal(t, int64(2), result.QuotaRemaining)
+	assert.WithinDuration(t, now.Add(time.Minute), result.QuotaRenews, time.Second)
+
+	result, err = limiter.Do(ctx, now.Add(time.Millisecond), rateKey, quotaKey, limit)
+	assert.NoError(t, err)
+	assert.False(t, result.RateLimited)
+	assert.Equal(t, int64(1), result.QuotaRemaining)
+
+	// blocked by the rate limit, not counted in the quota
+	result, err = limiter.Do(ctx, now.Add(2*time.Millisecond), rateKey, quotaKey, limit)
+	assert.NoError(t, err)
+	assert.True(t, result.RateLimited)
+
+	used, err := db.Get(ctx, quotaKey).Int64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), used)
+
+	// without a rate limit, requests are only counted in the quota
+	limit.Rate = 0
+	for i := 0; i < 2; i++ {
+		result, err = limiter.Do(ctx, now.Add(time.Duration(3+i)*time.Millisecond), rateKey, quotaKey, limit)
+		assert.NoError(t, err)
+	}
+	assert.True(t, result.QuotaExceeded)
+	assert.Equal(t, int64(0), result.QuotaRemaining)
+}
+
+func TestAtomicLimiter_Do_dryRun(t *testing.T) {
+	ctx := context.Background()
+
+	conf, err := config.New()
+	assert.NoError(t, err)
+
+	conn, err := storage.NewConnector(storage.DefaultConn, *conf)
+	assert.NoError(t, err)
+
+	var db redis.UniversalClient
+	assert.True(t, conn.As(&db))
+
+	quotaKey := "quota-" + uuid.New()
+	rateKey, err := rate.AtomicKey(quotaKey, "rate-limit-"+uuid.New())
+	assert.NoError(t, err)
+
+	limiter := rate.NewAtomicLimiter(db)
+	limit := rate.AtomicLimit{Rate: 1, Per: 60, QuotaMax: 2, QuotaRenewal: time.Minute}
+
+	now := time.Now()
+
+	// dry runs don't count the requests
+	dryRun := limit
+	dryRun.DryRun = true
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Do(ctx, now, rateKey, quotaKey, dryRun)
+		assert.NoError(t, err)
+		assert.False(t, result.RateLimited)
+		assert.Equal(t, int64(1), result.QuotaRemaining)
+	}
+
+	exists, err := db.Exists(ctx, rateKey, quotaKey).Result()
+	assert.NoError(t, err)
+	assert.Zero(t, exists)
+
+	result, err := limiter.Do(ctx, now, rateKey, quotaKey, limit)
+	assert.NoError(t, err)
+	assert.False(t, result.RateLimited)
+
+	// the dry run sees the counted request
+	result, err = limiter.Do(ctx, now.Add(time.Millisecond), rateKey, quotaKey, dryRun)
+	assert.NoError(t, err)
+	assert.True(t, result.RateLimited)
+
+	used, err := db.Get(ctx, quotaKey).Int64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), used)
+}
+
+func TestAtomicKey(t *testing.T) {
+	key, err := rate.AtomicKey("quota-abc", "rate-limit-abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "{quota-abc}rate-limit-abc", key)
+
+	_, err = rate.AtomicKey("quota-{abc}", "rate-limit-abc")
+	assert.ErrorIs(t, err, rate.ErrAtomicKeys)
+}
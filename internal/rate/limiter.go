@@ -14,6 +14,12 @@ func Limiter(gwConfig *config.Config, redis redis.UniversalClient) limiter.Limit
 		return nil
 	}
 
+	return LimiterByName(name, redis)
+}
+
+// LimiterByName returns the rate limiter named name, nil when it isn't implemented by the
+// limiter package. The distributed rate limiter and the sliding logs are implemented by the gateway.
+func LimiterByName(name string, redis redis.UniversalClient) limiter.LimiterFunc {
 	res := limiter.NewLimiter(redis)
 
 	switch name {
@@ -25,6 +31,8 @@ func Limiter(gwConfig *config.Config, redis redis.UniversalClient) limiter.Limit
 		return res.FixedWindow
 	case LimitSlidingWindow:
 		return res.SlidingWindow
+	case LimitGCRA:
+		return res.GCRA
 	}
 
 	return nil
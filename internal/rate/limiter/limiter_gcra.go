@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/tyk/internal/redis"
+)
+
+// gcraScript applies the generic cell rate algorithm to the theoretical arrival time at KEYS[1].
+//
+// ARGV are the current time, the emission interval and the burst tolerance in microseconds.
+//
+// It returns 1 when the request is allowed, 0 when it's over the rate.
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local tolerance = tonumber(ARGV[3])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+if tat - tolerance > now then
+	return 0
+end
+
+tat = tat + interval
+redis.call('SET', KEYS[1], string.format('%.0f', tat), 'PX', math.ceil((tat - now) / 1000))
+return 1
+`)
+
+// gcraLocalMaxKeys is the number of local arrival times from which the expired ones are pruned.
+const gcraLocalMaxKeys = 10000
+
+// gcraLocal keeps the theoretical arrival times when redis is not in use.
+var gcraLocal = struct {
+	sync.Mutex
+	tat map[string]time.Time
+}{tat: map[string]time.Time{}}
+
+// GCRA applies the generic cell rate algorithm: a leaky bucket of rate requests which blocks
+// the requests over the rate instead of queuing them. It keeps a single timestamp per key,
+// and allows bursts of up to rate requests, or of a single request for rates below 1.
+func (l *Limiter) GCRA(ctx context.Context, key string, rate float64, per float64) error {
+	if rate <= 0 || per <= 0 {
+		return nil
+	}
+
+	var (
+		interval  = time.Duration((per / rate) * float64(time.Second))
+		tolerance = time.Duration(float64(interval) * max(rate-1, 0))
+		now       = l.clock.Now()
+	)
+
+	if l.redis == nil {
+		return gcraLocalLimit(key, now, interval, tolerance)
+	}
+
+	allowed, err := gcraScript.Run(ctx, l.redis, []string{key},
+		strconv.FormatInt(now.UnixMicro(), 10),
+		strconv.FormatInt(interval.Microseconds(), 10),
+		strconv.FormatInt(tolerance.Microseconds(), 10),
+	).Int()
+	if err != nil {
+		return err
+	}
+
+	if allowed == 0 {
+		return ErrLimitExhausted
+	}
+	return nil
+}
+
+func gcraLocalLimit(key string, now time.Time, interval, tolerance time.Duration) error {
+	gcraLocal.Lock()
+	defer gcraLocal.Unlock()
+
+	tat, ok := gcraLocal.tat[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	if tat.Add(-tolerance).After(now) {
+		return ErrLimitExhausted
+	}
+
+	if len(gcraLocal.tat) >= gcraLocalMaxKeys {
+		for k, t := range gcraLocal.tat {
+			if t.Before(now) {
+				delete(gcraLocal.tat, k)
+			}
+		}
+	}
+
+	gcraLocal.tat[key] = tat.Add(interval)
+	return nil
+}
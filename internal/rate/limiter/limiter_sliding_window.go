@@ -7,7 +7,22 @@ import (
 	"github.com/TykTechnologies/exp/pkg/limiters"
 )
 
+// slidingWindowEpsilon allows the request counted at the capacity of the sliding window.
+const slidingWindowEpsilon = 1e-9
+
 func (l *Limiter) SlidingWindow(ctx context.Context, key string, rate float64, per float64) error {
+	return l.slidingWindow(ctx, key, rate, per, 0)
+}
+
+// SlidingWindowAtCapacity is the sliding window allowing the request counted at its capacity,
+// so that rate requests are allowed per window. It's the sliding window selected by name with
+// `rate_limiter`; SlidingWindow, enabled by `enable_sliding_window_rate_limiter`, blocks that
+// request.
+func (l *Limiter) SlidingWindowAtCapacity(ctx context.Context, key string, rate float64, per float64) error {
+	return l.slidingWindow(ctx, key, rate, per, slidingWindowEpsilon)
+}
+
+func (l *Limiter) slidingWindow(ctx context.Context, key string, rate float64, per float64, epsilon float64) error {
 	var (
 		storage limiters.SlidingWindowIncrementer
 
@@ -27,7 +42,10 @@ func (l *Limiter) SlidingWindow(ctx context.Context, key string, rate float64, p
 	//
 	//       the epsilon value is used to allow some requests to go over the defined
 	//       rate limit at any point of the calculation (start of window, end of ...).
-	limiter := limiters.NewSlidingWindow(capacity, ttl, storage, l.clock, 0)
+	//
+	//       the count includes the request, which is blocked from a count of
+	//       capacity + epsilon, so a small epsilon allows the request at capacity.
+	limiter := limiters.NewSlidingWindow(capacity, ttl, storage, l.clock, epsilon)
 
 	// Rate limiter returns a zero duration and a possible ErrLimitExhausted when no tokens are available.
 	_, err := limiter.Limit(ctx)
@@ -0,0 +1,99 @@
+package rate_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/internal/rate"
+	"github.com/TykTechnologies/tyk/internal/rate/limiter"
+	"github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/internal/uuid"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+func TestLimiterByName(t *testing.T) {
+	for _, name := range rate.Limiters {
+		assert.True(t, rate.IsLimiter(name))
+	}
+	assert.False(t, rate.IsLimiter("unknown"))
+
+	assert.NotNil(t, rate.LimiterByName(rate.LimitGCRA, nil))
+	assert.Nil(t, rate.LimiterByName(rate.LimitDRL, nil), "the DRL is implemented by the gateway")
+}
+
+func TestLimiter_GCRA(t *testing.T) {
+	ctx := context.Background()
+
+	conf, err := config.New()
+	require.NoError(t, err)
+
+	conn, err := storage.NewConnector(storage.DefaultConn, *conf)
+	require.NoError(t, err)
+
+	var db redis.UniversalClient
+	require.True(t, conn.As(&db))
+
+	for name, client := range map[string]redis.UniversalClient{"redis": db, "local": nil} {
+		t.Run(name, func(t *testing.T) {
+			limit := rate.LimiterByName(rate.LimitGCRA, client)
+			key := rate.LimiterKeyPrefix + uuid.New()
+
+			// bursts of the rate are allowed
+			for i := 0; i < 3; i++ {
+				assert.NoError(t, limit(ctx, key, 3, 60))
+			}
+			assert.ErrorIs(t, limit(ctx, key, 3, 60), rate.ErrLimitExhausted)
+
+			// the keys are limited apart
+			assert.NoError(t, limit(ctx, key+"-other", 3, 60))
+
+			// a request is allowed every emission interval
+			key = rate.LimiterKeyPrefix + uuid.New()
+			for i := 0; i < 3; i++ {
+				assert.NoError(t, limit(ctx, key, 3, 0.3))
+			}
+			assert.ErrorIs(t, limit(ctx, key, 3, 0.3), rate.ErrLimitExhausted)
+			time.Sleep(150 * time.Millisecond)
+			assert.NoError(t, limit(ctx, key, 3, 0.3))
+			assert.ErrorIs(t, limit(ctx, key, 3, 0.3), rate.ErrLimitExhausted)
+
+			// rates below 1 allow a single request per emission interval
+			key = rate.LimiterKeyPrefix + uuid.New()
+			assert.NoError(t, limit(ctx, key, 0.5, 60))
+			assert.ErrorIs(t, limit(ctx, key, 0.5, 60), rate.ErrLimitExhausted)
+
+			// unlimited
+			assert.NoError(t, limit(ctx, key, 0, 60))
+		})
+	}
+}
+
+func TestLimiter_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("flag", func(t *testing.T) {
+		limit := rate.LimiterByName(rate.LimitSlidingWindow, nil)
+		key := rate.LimiterKeyPrefix + uuid.New()
+
+		// the request counted at the capacity of the window is blocked
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, limit(ctx, key, 3, 60))
+		}
+		assert.ErrorIs(t, limit(ctx, key, 3, 60), rate.ErrLimitExhausted)
+	})
+
+	t.Run("at capacity", func(t *testing.T) {
+		limit := limiter.NewLimiter(nil).SlidingWindowAtCapacity
+		key := rate.LimiterKeyPrefix + uuid.New()
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, limit(ctx, key, 3, 60))
+		}
+		assert.ErrorIs(t, limit(ctx, key, 3, 60), rate.ErrLimitExhausted)
+	})
+}
@@ -1,6 +1,7 @@
 package rate
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/TykTechnologies/tyk/internal/rate/limiter"
@@ -17,8 +18,33 @@ const (
 	LimitTokenBucket   string = "token-bucket"
 	LimitFixedWindow   string = "fixed-window"
 	LimitSlidingWindow string = "sliding-window"
+	LimitGCRA          string = "gcra"
+
+	// LimitDRL is the distributed rate limiter, falling back to the sliding log over the DRL threshold.
+	LimitDRL string = "drl"
+	// LimitSentinel is the sliding log, checking a sentinel key on the request path.
+	LimitSentinel string = "sentinel"
+	// LimitSlidingLog is the sliding log of the Redis Rate Limiter.
+	LimitSlidingLog string = "sliding-log"
 )
 
+// Limiters lists the rate limiters which can be selected by name.
+var Limiters = []string{
+	LimitDRL,
+	LimitSentinel,
+	LimitSlidingLog,
+	LimitFixedWindow,
+	LimitSlidingWindow,
+	LimitLeakyBucket,
+	LimitTokenBucket,
+	LimitGCRA,
+}
+
+// IsLimiter returns true if name is a rate limiter which can be selected by name.
+func IsLimiter(name string) bool {
+	return slices.Contains(Limiters, name)
+}
+
 const (
 	// LimiterKeyPrefix serves as a standard prefix for generating rate limit keys.
 	LimiterKeyPrefix = "rate-limit-"
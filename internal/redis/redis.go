@@ -16,6 +16,7 @@ var (
 	NewClient         = redis.NewClient
 	NewClientMock     = redismock.NewClientMock
 	NewPool           = goredis.NewPool
+	NewScript         = redis.NewScript
 
 	Nil       = redis.Nil
 	ErrClosed = redis.ErrClosed
@@ -34,8 +35,15 @@ type (
 	ZRangeArgs   = redis.ZRangeArgs
 	Message      = redis.Message
 	Subscription = redis.Subscription
+	Script       = redis.Script
 
 	IntCmd         = redis.IntCmd
 	StringCmd      = redis.StringCmd
 	StringSliceCmd = redis.StringSliceCmd
+
+	Hook                = redis.Hook
+	Cmder               = redis.Cmder
+	DialHook            = redis.DialHook
+	ProcessHook         = redis.ProcessHook
+	ProcessPipelineHook = redis.ProcessPipelineHook
 )
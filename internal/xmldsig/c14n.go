@@ -0,0 +1,137 @@
+package xmldsig
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Canonicalize serializes the subtree of an element with exclusive XML canonicalization
+// without comments. The excluded element and its descendants are left out, which
+// implements the enveloped signature transform. The inclusive prefixes are rendered as
+// with inclusive canonicalization, as listed by an `InclusiveNamespaces` element.
+func Canonicalize(el *Element, excluded *Element, inclusivePrefixes []string) []byte {
+	c := canonicalizer{excluded: excluded, inclusive: make(map[string]bool, len(inclusivePrefixes))}
+	for _, prefix := range inclusivePrefixes {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		c.inclusive[prefix] = true
+	}
+
+	c.element(el, map[string]string{})
+
+	return c.buf.Bytes()
+}
+
+type canonicalizer struct {
+	buf       bytes.Buffer
+	excluded  *Element
+	inclusive map[string]bool
+}
+
+func (c *canonicalizer) element(el *Element, rendered map[string]string) {
+	if el == c.excluded {
+		return
+	}
+
+	// namespaces visibly utilized by the element, and listed inclusive ones in scope
+	utilized := map[string]bool{el.Prefix: true}
+	for _, attr := range el.Attrs {
+		if attr.Prefix != "" {
+			utilized[attr.Prefix] = true
+		}
+	}
+	for prefix := range c.inclusive {
+		if _, ok := el.Lookup(prefix); ok {
+			utilized[prefix] = true
+		}
+	}
+	delete(utilized, "xml")
+
+	prefixes := make([]string, 0, len(utilized))
+	for prefix := range utilized {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	scope := rendered
+	var declarations []string
+	for _, prefix := range prefixes {
+		uri, _ := el.Lookup(prefix)
+
+		previous, ok := rendered[prefix]
+		if ok && previous == uri || !ok && prefix == "" && uri == "" {
+			continue
+		}
+
+		if len(declarations) == 0 {
+			scope = make(map[string]string, len(rendered)+len(prefixes))
+			for k, v := range rendered {
+				scope[k] = v
+			}
+		}
+		scope[prefix] = uri
+
+		name := "xmlns"
+		if prefix != "" {
+			name += ":" + prefix
+		}
+		declarations = append(declarations, " "+name+`="`+escapeAttr(uri)+`"`)
+	}
+
+	attrs := append([]Attr(nil), el.Attrs...)
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].Space != attrs[j].Space {
+			return attrs[i].Space < attrs[j].Space
+		}
+		return attrs[i].Local < attrs[j].Local
+	})
+
+	c.buf.WriteString("<" + qualifiedName(el.Prefix, el.Local))
+	for _, declaration := range declarations {
+		c.buf.WriteString(declaration)
+	}
+	for _, attr := range attrs {
+		c.buf.WriteString(" " + qualifiedName(attr.Prefix, attr.Local) + `="` + escapeAttr(attr.Value) + `"`)
+	}
+	c.buf.WriteString(">")
+
+	for _, node := range el.Children {
+		switch n := node.(type) {
+		case *Element:
+			c.element(n, scope)
+		case Text:
+			c.buf.WriteString(escapeText(string(n)))
+		case *ProcInst:
+			c.buf.WriteString("<?" + n.Target)
+			if len(n.Inst) > 0 {
+				c.buf.WriteString(" " + string(n.Inst))
+			}
+			c.buf.WriteString("?>")
+		}
+	}
+
+	c.buf.WriteString("</" + qualifiedName(el.Prefix, el.Local) + ">")
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
@@ -0,0 +1,259 @@
+// Package xmldsig verifies XML signatures (XML-DSig) using exclusive XML canonicalization,
+// as used by WS-Security.
+package xmldsig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Node is a node of a parsed document: an *Element, a Text or a *ProcInst.
+type Node interface{}
+
+// Text is character data.
+type Text string
+
+// ProcInst is a processing instruction.
+type ProcInst struct {
+	Target string
+	Inst   []byte
+}
+
+// Attr is an attribute. Namespace declarations are not attributes.
+type Attr struct {
+	Prefix string
+	Local  string
+	Space  string
+	Value  string
+}
+
+// Element is an element of a parsed document. Prefixes are kept as written so that the
+// document can be canonicalized, Space holds the resolved namespace.
+type Element struct {
+	Prefix   string
+	Local    string
+	Space    string
+	Attrs    []Attr
+	NS       map[string]string
+	Children []Node
+	Parent   *Element
+}
+
+// Parse parses an XML document and returns its root element. Comments and directives are
+// dropped, as canonicalization would remove them.
+func Parse(data []byte) (*Element, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *Element
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if current == nil && root != nil {
+				return nil, errors.New("xml: multiple root elements")
+			}
+
+			el := &Element{Prefix: t.Name.Space, Local: t.Name.Local, Parent: current}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					el.declare(attr.Name.Local, attr.Value)
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					el.declare("", attr.Value)
+				default:
+					el.Attrs = append(el.Attrs, Attr{Prefix: attr.Name.Space, Local: attr.Name.Local, Value: attr.Value})
+				}
+			}
+
+			var ok bool
+			if el.Space, ok = el.Lookup(el.Prefix); !ok {
+				return nil, fmt.Errorf("xml: undeclared namespace prefix %q", el.Prefix)
+			}
+			for i := range el.Attrs {
+				if el.Attrs[i].Prefix == "" {
+					continue
+				}
+				if el.Attrs[i].Space, ok = el.Lookup(el.Attrs[i].Prefix); !ok {
+					return nil, fmt.Errorf("xml: undeclared namespace prefix %q", el.Attrs[i].Prefix)
+				}
+			}
+
+			if current == nil {
+				root = el
+			} else {
+				current.Children = append(current.Children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || t.Name.Space != current.Prefix || t.Name.Local != current.Local {
+				return nil, fmt.Errorf("xml: unexpected end element </%s>", t.Name.Local)
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.Children = append(current.Children, Text(t))
+			}
+		case xml.ProcInst:
+			if current != nil {
+				current.Children = append(current.Children, &ProcInst{Target: t.Target, Inst: append([]byte(nil), t.Inst...)})
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("xml: no root element")
+	}
+	if current != nil {
+		return nil, errors.New("xml: unexpected end of document")
+	}
+
+	return root, nil
+}
+
+func (e *Element) declare(prefix, uri string) {
+	if e.NS == nil {
+		e.NS = make(map[string]string)
+	}
+	e.NS[prefix] = uri
+}
+
+// Lookup returns the namespace a prefix is bound to in the scope of the element.
+func (e *Element) Lookup(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNamespace, true
+	}
+
+	for el := e; el != nil; el = el.Parent {
+		if uri, ok := el.NS[prefix]; ok {
+			return uri, true
+		}
+	}
+
+	// the default namespace is empty unless declared
+	return "", prefix == ""
+}
+
+// Attr returns the value of an attribute, with an empty space matching attributes without namespace.
+func (e *Element) Attr(space, local string) (string, bool) {
+	for _, attr := range e.Attrs {
+		if attr.Space == space && attr.Local == local {
+			return attr.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Child returns the first child element with the given name.
+func (e *Element) Child(space, local string) *Element {
+	for _, node := range e.Children {
+		if el, ok := node.(*Element); ok && el.Space == space && el.Local == local {
+			return el
+		}
+	}
+
+	return nil
+}
+
+// ChildElements returns the child elements.
+func (e *Element) ChildElements() []*Element {
+	var elements []*Element
+	for _, node := range e.Children {
+		if el, ok := node.(*Element); ok {
+			elements = append(elements, el)
+		}
+	}
+
+	return elements
+}
+
+// Text returns the concatenated character data of the element and its descendants.
+func (e *Element) Text() string {
+	var buf bytes.Buffer
+	e.walk(func(el *Element) {
+		for _, node := range el.Children {
+			if text, ok := node.(Text); ok {
+				buf.WriteString(string(text))
+			}
+		}
+	})
+
+	return buf.String()
+}
+
+// Find returns the elements with the given name in the subtree, in document order.
+func (e *Element) Find(space, local string) []*Element {
+	var found []*Element
+	e.walk(func(el *Element) {
+		if el.Space == space && el.Local == local {
+			found = append(found, el)
+		}
+	})
+
+	return found
+}
+
+// Root returns the root element of the document.
+func (e *Element) Root() *Element {
+	root := e
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	return root
+}
+
+// ID returns the identifier of the element, from an `Id`, `ID` or `id` attribute in any
+// namespace, such as `wsu:Id`.
+func (e *Element) ID() string {
+	for _, attr := range e.Attrs {
+		switch attr.Local {
+		case "Id", "ID", "id":
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+// findByID returns the element with the given identifier. Several elements having the
+// same identifier is an error, as it could be used to make a signature cover another
+// element than the one processed.
+func (e *Element) findByID(id string) (*Element, error) {
+	var found []*Element
+	e.walk(func(el *Element) {
+		if el.ID() == id {
+			found = append(found, el)
+		}
+	})
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no element with id %q", id)
+	case 1:
+		return found[0], nil
+	default:
+		return nil, fmt.Errorf("several elements with id %q", id)
+	}
+}
+
+func (e *Element) walk(fn func(*Element)) {
+	fn(e)
+	for _, node := range e.Children {
+		if el, ok := node.(*Element); ok {
+			el.walk(fn)
+		}
+	}
+}
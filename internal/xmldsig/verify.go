@@ -0,0 +1,221 @@
+package xmldsig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	// register the hash functions used by signatures
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Namespace is the XML-DSig namespace.
+const Namespace = "http://www.w3.org/2000/09/xmldsig#"
+
+// Algorithm identifiers.
+const (
+	ExcC14N            = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	EnvelopedSignature = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+
+	RSASHA1     = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	RSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	RSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	ECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	ECDSASHA512 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512"
+
+	SHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	SHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	SHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+var (
+	ErrNoCertificate    = errors.New("no certificate to verify the signature with")
+	ErrInvalidSignature = errors.New("signature verification failed")
+)
+
+var digestMethods = map[string]crypto.Hash{
+	SHA1:   crypto.SHA1,
+	SHA256: crypto.SHA256,
+	SHA512: crypto.SHA512,
+}
+
+var signatureMethods = map[string]crypto.Hash{
+	RSASHA1:     crypto.SHA1,
+	RSASHA256:   crypto.SHA256,
+	RSASHA512:   crypto.SHA512,
+	ECDSASHA256: crypto.SHA256,
+	ECDSASHA512: crypto.SHA512,
+}
+
+// Verify checks a `Signature` element: the digests of the referenced elements and the
+// signature of `SignedInfo`, which must be made by the key of one of the certificates.
+// It returns the elements the signature covers.
+func Verify(signature *Element, certs []*x509.Certificate) ([]*Element, error) {
+	if len(certs) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	signedInfo := signature.Child(Namespace, "SignedInfo")
+	if signedInfo == nil {
+		return nil, errors.New("missing SignedInfo")
+	}
+
+	c14nMethod := signedInfo.Child(Namespace, "CanonicalizationMethod")
+	if c14nMethod == nil {
+		return nil, errors.New("missing CanonicalizationMethod")
+	}
+	if algorithm, _ := c14nMethod.Attr("", "Algorithm"); algorithm != ExcC14N {
+		return nil, fmt.Errorf("unsupported canonicalization method %q", algorithm)
+	}
+
+	signatureMethod := signedInfo.Child(Namespace, "SignatureMethod")
+	if signatureMethod == nil {
+		return nil, errors.New("missing SignatureMethod")
+	}
+	signatureAlgorithm, _ := signatureMethod.Attr("", "Algorithm")
+	hash, ok := signatureMethods[signatureAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature method %q", signatureAlgorithm)
+	}
+
+	var covered []*Element
+	for _, reference := range signedInfo.ChildElements() {
+		if reference.Space != Namespace || reference.Local != "Reference" {
+			continue
+		}
+
+		el, err := verifyReference(signature, reference)
+		if err != nil {
+			return nil, err
+		}
+		covered = append(covered, el)
+	}
+
+	if len(covered) == 0 {
+		return nil, errors.New("signature has no reference")
+	}
+
+	signatureValue := signature.Child(Namespace, "SignatureValue")
+	if signatureValue == nil {
+		return nil, errors.New("missing SignatureValue")
+	}
+	sig, err := decodeBase64(signatureValue.Text())
+	if err != nil {
+		return nil, fmt.Errorf("invalid SignatureValue: %w", err)
+	}
+
+	h := hash.New()
+	h.Write(Canonicalize(signedInfo, nil, inclusivePrefixes(c14nMethod)))
+	digest := h.Sum(nil)
+
+	for _, cert := range certs {
+		if verifySignature(cert.PublicKey, hash, digest, sig) {
+			return covered, nil
+		}
+	}
+
+	return nil, ErrInvalidSignature
+}
+
+func verifyReference(signature, reference *Element) (*Element, error) {
+	uri, _ := reference.Attr("", "URI")
+
+	var target *Element
+	switch {
+	case uri == "":
+		target = signature.Root()
+	case strings.HasPrefix(uri, "#"):
+		var err error
+		if target, err = signature.Root().findByID(uri[1:]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported reference %q", uri)
+	}
+
+	var (
+		excluded  *Element
+		inclusive []string
+	)
+	if transforms := reference.Child(Namespace, "Transforms"); transforms != nil {
+		for _, transform := range transforms.ChildElements() {
+			switch algorithm, _ := transform.Attr("", "Algorithm"); algorithm {
+			case EnvelopedSignature:
+				excluded = signature
+			case ExcC14N:
+				inclusive = inclusivePrefixes(transform)
+			default:
+				return nil, fmt.Errorf("unsupported transform %q", algorithm)
+			}
+		}
+	}
+
+	digestMethod := reference.Child(Namespace, "DigestMethod")
+	if digestMethod == nil {
+		return nil, errors.New("missing DigestMethod")
+	}
+	digestAlgorithm, _ := digestMethod.Attr("", "Algorithm")
+	hash, ok := digestMethods[digestAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest method %q", digestAlgorithm)
+	}
+
+	digestValue := reference.Child(Namespace, "DigestValue")
+	if digestValue == nil {
+		return nil, errors.New("missing DigestValue")
+	}
+	expected, err := decodeBase64(digestValue.Text())
+	if err != nil {
+		return nil, fmt.Errorf("invalid DigestValue: %w", err)
+	}
+
+	h := hash.New()
+	h.Write(Canonicalize(target, excluded, inclusive))
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return nil, fmt.Errorf("digest mismatch for reference %q", uri)
+	}
+
+	return target, nil
+}
+
+// inclusivePrefixes returns the prefix list of the `InclusiveNamespaces` child of a
+// canonicalization method or transform.
+func inclusivePrefixes(el *Element) []string {
+	inclusive := el.Child(ExcC14N, "InclusiveNamespaces")
+	if inclusive == nil {
+		return nil
+	}
+
+	prefixList, _ := inclusive.Attr("", "PrefixList")
+	return strings.Fields(prefixList)
+}
+
+func verifySignature(publicKey interface{}, hash crypto.Hash, digest, sig []byte) bool {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, hash, digest, sig) == nil
+	case *ecdsa.PublicKey:
+		// XML-DSig ECDSA signatures are the concatenation of r and s
+		if len(sig) == 0 || len(sig)%2 != 0 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:len(sig)/2])
+		s := new(big.Int).SetBytes(sig[len(sig)/2:])
+		return ecdsa.Verify(key, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
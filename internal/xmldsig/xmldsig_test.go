@@ -0,0 +1,71 @@
+package xmldsig_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/TykTechnologies/tyk/internal/xmldsig"
+	"github.com/TykTechnologies/tyk/internal/xmldsig/xmldsigtest"
+)
+
+func TestCanonicalize(t *testing.T) {
+	doc, err := Parse([]byte(`<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org"><n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"/></n1:elem2></n0:local>`))
+	require.NoError(t, err)
+
+	elem2 := doc.ChildElements()[0]
+	assert.Equal(t, `<n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`,
+		string(Canonicalize(elem2, nil, nil)))
+
+	assert.Equal(t, `<n1:elem2 xmlns:n0="foo:bar" xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`,
+		string(Canonicalize(elem2, nil, []string{"n0"})))
+
+	doc, err = Parse([]byte(`<a xmlns="urn:a" z="1" b="&amp;&#10;"><!-- comment --><b xmlns="">x &gt; y</b><c/></a>`))
+	require.NoError(t, err)
+	assert.Equal(t, `<a xmlns="urn:a" b="&amp;&#xA;" z="1"><b xmlns="">x &gt; y</b><c></c></a>`, string(Canonicalize(doc, nil, nil)))
+}
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	cert := &x509.Certificate{PublicKey: &key.PublicKey}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherCert := &x509.Certificate{PublicKey: &otherKey.PublicKey}
+
+	signed := xmldsigtest.Sign(t, key, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Header>`+xmldsigtest.SignaturePlaceholder+`</s:Header>`+
+		`<s:Body xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd" wsu:Id="body"><Order>42</Order></s:Body></s:Envelope>`, "body")
+
+	verify := func(doc string, certs ...*x509.Certificate) ([]*Element, error) {
+		root, err := Parse([]byte(doc))
+		require.NoError(t, err)
+
+		signatures := root.Find(Namespace, "Signature")
+		require.Len(t, signatures, 1)
+		return Verify(signatures[0], certs)
+	}
+
+	covered, err := verify(signed, otherCert, cert)
+	require.NoError(t, err)
+	require.Len(t, covered, 1)
+	assert.Equal(t, "Body", covered[0].Local)
+
+	_, err = verify(signed, otherCert)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = verify(signed)
+	assert.ErrorIs(t, err, ErrNoCertificate)
+
+	_, err = verify(strings.Replace(signed, "<Order>42</Order>", "<Order>43</Order>", 1), cert)
+	assert.ErrorContains(t, err, "digest mismatch")
+
+	wrapped := strings.Replace(signed, "</s:Header>", `<Wrapper xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd" wsu:Id="body"/></s:Header>`, 1)
+	_, err = verify(wrapped, cert)
+	assert.ErrorContains(t, err, "several elements")
+}
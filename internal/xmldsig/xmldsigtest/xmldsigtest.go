@@ -0,0 +1,62 @@
+// Package xmldsigtest provides helpers signing XML documents in tests.
+package xmldsigtest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/TykTechnologies/tyk/internal/xmldsig"
+)
+
+// SignaturePlaceholder is replaced by the signature in documents signed by Sign.
+const SignaturePlaceholder = "<!--signature-->"
+
+// Sign signs the element with the given id of a document with RSA-SHA256.
+func Sign(t *testing.T, key *rsa.PrivateKey, doc, id string) string {
+	t.Helper()
+
+	root, err := xmldsig.Parse([]byte(strings.Replace(doc, SignaturePlaceholder, "", 1)))
+	require.NoError(t, err)
+
+	target := findByID(root, id)
+	require.NotNil(t, target, "no element with id %q", id)
+
+	digest := crypto.SHA256.New()
+	digest.Write(xmldsig.Canonicalize(target, nil, nil))
+
+	signedInfo := `<ds:SignedInfo xmlns:ds="` + xmldsig.Namespace + `"><ds:CanonicalizationMethod Algorithm="` + xmldsig.ExcC14N + `"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="` + xmldsig.RSASHA256 + `"></ds:SignatureMethod><ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="` + xmldsig.ExcC14N + `"></ds:Transform></ds:Transforms><ds:DigestMethod Algorithm="` + xmldsig.SHA256 + `"></ds:DigestMethod>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest.Sum(nil)) + `</ds:DigestValue></ds:Reference></ds:SignedInfo>`
+
+	h := crypto.SHA256.New()
+	h.Write([]byte(signedInfo))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h.Sum(nil))
+	require.NoError(t, err)
+
+	signature := `<ds:Signature xmlns:ds="` + xmldsig.Namespace + `">` + strings.Replace(signedInfo, ` xmlns:ds="`+xmldsig.Namespace+`"`, "", 1) +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(sig) + `</ds:SignatureValue></ds:Signature>`
+
+	return strings.Replace(doc, SignaturePlaceholder, signature, 1)
+}
+
+// findByID returns the first element of the tree with the given id.
+func findByID(el *xmldsig.Element, id string) *xmldsig.Element {
+	if el.ID() == id {
+		return el
+	}
+
+	for _, child := range el.ChildElements() {
+		if found := findByID(child, id); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package regexp
+
+import (
+	"errors"
+	"fmt"
+	"regexp/syntax"
+)
+
+const (
+	defaultSafeMaxRepeat      = 1000
+	defaultSafeMaxProgramSize = 10000
+)
+
+// ErrUnsafe is returned for the patterns rejected by CheckSafe.
+var ErrUnsafe = errors.New("unsafe regular expression")
+
+// SafeLimits bounds the complexity of the patterns accepted by CheckSafe.
+type SafeLimits struct {
+	// MaxRepeat is the maximum count of counted repetitions, 1000 when 0.
+	MaxRepeat int
+	// MaxProgramSize is the maximum number of instructions of the compiled pattern, 10000 when 0.
+	MaxProgramSize int
+}
+
+// CheckSafe returns an error when expr doesn't compile, or when it could be expensive to match:
+// nested unbounded repetitions like `(a+)+`, which backtrack catastrophically in engines other
+// than RE2, counted repetitions above the limit, and patterns compiling to large programs, which
+// are slow to match and memory hungry even in RE2.
+func CheckSafe(expr string, limits SafeLimits) error {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return err
+	}
+
+	maxRepeat := limits.MaxRepeat
+	if maxRepeat <= 0 {
+		maxRepeat = defaultSafeMaxRepeat
+	}
+
+	if err := checkRepeats(re, maxRepeat, false); err != nil {
+		return fmt.Errorf("%w %q: %s", ErrUnsafe, expr, err)
+	}
+
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return err
+	}
+
+	maxProgramSize := limits.MaxProgramSize
+	if maxProgramSize <= 0 {
+		maxProgramSize = defaultSafeMaxProgramSize
+	}
+
+	if len(prog.Inst) > maxProgramSize {
+		return fmt.Errorf("%w %q: compiles to %d instructions, above %d", ErrUnsafe, expr, len(prog.Inst), maxProgramSize)
+	}
+
+	return nil
+}
+
+// checkRepeats walks the pattern, inUnbounded is true below an unbounded repetition.
+func checkRepeats(re *syntax.Regexp, maxRepeat int, inUnbounded bool) error {
+	unbounded := false
+
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		unbounded = true
+	case syntax.OpRepeat:
+		if re.Min > maxRepeat || re.Max > maxRepeat {
+			return fmt.Errorf("repeats more than %d times", maxRepeat)
+		}
+		unbounded = re.Max == -1
+	}
+
+	if unbounded && inUnbounded {
+		return errors.New("nests unbounded repetitions")
+	}
+
+	for _, sub := range re.Sub {
+		if err := checkRepeats(sub, maxRepeat, inUnbounded || unbounded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package regexp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSafe(t *testing.T) {
+	for _, expr := range []string{`^/users/([^/]+)$`, `(?i)^/orders/[0-9]{1,10}`, `a+b*`, `(ab|cd)+`} {
+		assert.NoError(t, CheckSafe(expr, SafeLimits{}), expr)
+	}
+
+	for _, expr := range []string{`(a+)+`, `(a*b*)*c`, `(x{2,})+`, `a{101}`} {
+		err := CheckSafe(expr, SafeLimits{MaxRepeat: 100})
+		assert.True(t, errors.Is(err, ErrUnsafe), expr)
+	}
+
+	t.Run("program size", func(t *testing.T) {
+		err := CheckSafe(`(a{30}){30}`, SafeLimits{MaxProgramSize: 500})
+		assert.True(t, errors.Is(err, ErrUnsafe))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		err := CheckSafe(`(a`, SafeLimits{})
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrUnsafe))
+	})
+}
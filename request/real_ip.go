@@ -39,3 +39,14 @@ func RealIP(r *http.Request) string {
 	host, _, _ := net.SplitHostPort(r.RemoteAddr)
 	return host
 }
+
+// ConnectionIP returns the IP address of the connection the request was received on. Unlike
+// RealIP, it ignores the X-Real-IP and X-Forwarded-For headers, which clients can set.
+func ConnectionIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -49,6 +49,20 @@ func TestRealIP(t *testing.T) {
 	}
 }
 
+func TestConnectionIP(t *testing.T) {
+	for _, test := range ipHeaderTests {
+		t.Log(test.comment)
+
+		r, _ := http.NewRequest(http.MethodGet, "http://abc.com:8080", nil)
+		r.Header.Set(test.key, test.value)
+		r.RemoteAddr = test.remoteAddr
+
+		if ip := ConnectionIP(r); ip != "10.0.1.4" {
+			t.Errorf("\texpected %s got %s", "10.0.1.4", ip)
+		}
+	}
+}
+
 func BenchmarkRealIP_RemoteAddr(b *testing.B) {
 	b.ReportAllocs()
 
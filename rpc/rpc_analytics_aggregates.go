@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/TykTechnologies/tyk-pump/analytics"
+)
+
+// AnalyticsAggregate is the rollup of the analytics records of a minute with the same API,
+// key and response code, shipped to MDCB instead of the records.
+type AnalyticsAggregate struct {
+	TimeStamp    time.Time `json:"timestamp"`
+	APIID        string    `json:"api_id"`
+	OrgID        string    `json:"org_id"`
+	APIKey       string    `json:"api_key"`
+	ResponseCode int       `json:"response_code"`
+	Hits         int64     `json:"hits"`
+	// RequestTime is the total duration of the requests, in milliseconds.
+	RequestTime    int64 `json:"request_time"`
+	MaxRequestTime int64 `json:"max_request_time"`
+	// UpstreamLatency is the total latency of the upstream, in milliseconds.
+	UpstreamLatency int64 `json:"upstream_latency"`
+	BytesIn         int64 `json:"bytes_in"`
+	BytesOut        int64 `json:"bytes_out"`
+}
+
+type analyticsAggregateKey struct {
+	minute       int64
+	apiID        string
+	orgID        string
+	apiKey       string
+	responseCode int
+}
+
+// aggregateAnalyticsRecords rolls the decoded analytics records up per minute, API, key and
+// response code. Records which couldn't be decoded are skipped.
+func aggregateAnalyticsRecords(records []interface{}) []AnalyticsAggregate {
+	index := map[analyticsAggregateKey]*AnalyticsAggregate{}
+
+	for _, value := range records {
+		record, ok := value.(analytics.AnalyticsRecord)
+		if !ok {
+			continue
+		}
+
+		minute := record.TimeStamp.UTC().Truncate(time.Minute)
+		key := analyticsAggregateKey{
+			minute:       minute.Unix(),
+			apiID:        record.APIID,
+			orgID:        record.OrgID,
+			apiKey:       record.APIKey,
+			responseCode: record.ResponseCode,
+		}
+
+		aggregate, ok := index[key]
+		if !ok {
+			aggregate = &AnalyticsAggregate{
+				TimeStamp:    minute,
+				APIID:        record.APIID,
+				OrgID:        record.OrgID,
+				APIKey:       record.APIKey,
+				ResponseCode: record.ResponseCode,
+			}
+			index[key] = aggregate
+		}
+
+		aggregate.Hits++
+		aggregate.RequestTime += record.RequestTime
+		aggregate.UpstreamLatency += record.Latency.Upstream
+		aggregate.BytesIn += record.Network.BytesIn
+		aggregate.BytesOut += record.Network.BytesOut
+		if record.RequestTime > aggregate.MaxRequestTime {
+			aggregate.MaxRequestTime = record.RequestTime
+		}
+	}
+
+	aggregates := make([]AnalyticsAggregate, 0, len(index))
+	for _, aggregate := range index {
+		aggregates = append(aggregates, *aggregate)
+	}
+
+	sort.Slice(aggregates, func(i, j int) bool {
+		a, b := aggregates[i], aggregates[j]
+		if !a.TimeStamp.Equal(b.TimeStamp) {
+			return a.TimeStamp.Before(b.TimeStamp)
+		}
+		if a.APIID != b.APIID {
+			return a.APIID < b.APIID
+		}
+		if a.APIKey != b.APIKey {
+			return a.APIKey < b.APIKey
+		}
+		return a.ResponseCode < b.ResponseCode
+	})
+
+	return aggregates
+}
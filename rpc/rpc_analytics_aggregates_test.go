@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk-pump/analytics"
+)
+
+func TestAggregateAnalyticsRecords(t *testing.T) {
+	minute := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+
+	record := func(offset time.Duration, apiKey string, code int, requestTime int64) analytics.AnalyticsRecord {
+		return analytics.AnalyticsRecord{
+			TimeStamp:    minute.Add(offset),
+			APIID:        "api",
+			OrgID:        "org",
+			APIKey:       apiKey,
+			ResponseCode: code,
+			RequestTime:  requestTime,
+			Latency:      analytics.Latency{Total: requestTime, Upstream: requestTime / 2},
+			Network:      analytics.NetworkStats{BytesIn: 10, BytesOut: 100},
+		}
+	}
+
+	aggregates := aggregateAnalyticsRecords([]interface{}{
+		record(10*time.Second, "key-1", 200, 20),
+		record(50*time.Second, "key-1", 200, 40),
+		nil, // record which couldn't be decoded
+		record(20*time.Second, "key-1", 500, 5),
+		record(70*time.Second, "key-1", 200, 30),
+		record(30*time.Second, "key-2", 200, 10),
+	})
+
+	assert.Equal(t, []AnalyticsAggregate{
+		{TimeStamp: minute, APIID: "api", OrgID: "org", APIKey: "key-1", ResponseCode: 200, Hits: 2,
+			RequestTime: 60, MaxRequestTime: 40, UpstreamLatency: 30, BytesIn: 20, BytesOut: 200},
+		{TimeStamp: minute, APIID: "api", OrgID: "org", APIKey: "key-1", ResponseCode: 500, Hits: 1,
+			RequestTime: 5, MaxRequestTime: 5, UpstreamLatency: 2, BytesIn: 10, BytesOut: 100},
+		{TimeStamp: minute, APIID: "api", OrgID: "org", APIKey: "key-2", ResponseCode: 200, Hits: 1,
+			RequestTime: 10, MaxRequestTime: 10, UpstreamLatency: 5, BytesIn: 10, BytesOut: 100},
+		{TimeStamp: minute.Add(time.Minute), APIID: "api", OrgID: "org", APIKey: "key-1", ResponseCode: 200, Hits: 1,
+			RequestTime: 30, MaxRequestTime: 30, UpstreamLatency: 15, BytesIn: 10, BytesOut: 100},
+	}, aggregates)
+
+	assert.Empty(t, aggregateAnalyticsRecords(nil))
+}
@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// defaultAnalyticsBackendTimeout is the default timeout of the analytics backends.
+const defaultAnalyticsBackendTimeout = 30 * time.Second
+
+// AnalyticsFunctionHeader is the header of the HTTP backend naming the RPC function of the payload.
+const AnalyticsFunctionHeader = "X-Tyk-Analytics-Function"
+
+// PurgerBackend ships the analytics of the purger. The payloads are the JSON arrays of the RPC
+// functions: the records for PurgeAnalyticsData, and their rollups for PurgeAnalyticsAggregates.
+// The payloads of PurgeAnalyticsDataProtobuf are the records serialized with protobuf, each
+// prefixed with its varint encoded length.
+type PurgerBackend interface {
+	// Ready returns an error when the analytics can't be shipped, the purge is skipped then.
+	Ready() error
+	// Ship ships the payload of an RPC function.
+	Ship(funcName, data string) error
+	// SupportsAggregates returns true when the backend accepts the rollups of the records.
+	SupportsAggregates() bool
+	// SupportsProtobuf returns true when the backend accepts the records serialized with protobuf.
+	SupportsProtobuf() bool
+}
+
+// RPCBackend ships the analytics to MDCB.
+type RPCBackend struct{}
+
+func (RPCBackend) Ready() error {
+	if !values.ClientIsConnected() {
+		Log.Error("RPC client is not connected, use Connect method 1st")
+	}
+
+	if _, err := FuncClientSingleton("Ping", nil); err != nil {
+		return fmt.Errorf("failed to ping RPC: %w", err)
+	}
+	return nil
+}
+
+func (RPCBackend) Ship(funcName, data string) error {
+	_, err := FuncClientSingleton(funcName, data)
+	if err != nil {
+		EmitErrorEvent(FuncClientSingletonCall, funcName, err)
+	}
+	return err
+}
+
+// SupportsAggregates returns true when the RPC server supports PurgeAnalyticsAggregates.
+func (RPCBackend) SupportsAggregates() bool {
+	return Supports("PurgeAnalyticsAggregates")
+}
+
+// SupportsProtobuf returns true when the RPC server supports PurgeAnalyticsDataProtobuf.
+func (RPCBackend) SupportsProtobuf() bool {
+	return Supports("PurgeAnalyticsDataProtobuf")
+}
+
+// SinkBackend ships the analytics with a function instead of RPC calls, e.g. to export them
+// to files in air-gapped environments. It's given the RPC function and its payload.
+type SinkBackend func(funcName, data string) error
+
+func (SinkBackend) Ready() error {
+	return nil
+}
+
+func (s SinkBackend) Ship(funcName, data string) error {
+	return s(funcName, data)
+}
+
+func (SinkBackend) SupportsAggregates() bool {
+	return true
+}
+
+func (SinkBackend) SupportsProtobuf() bool {
+	return true
+}
+
+// HTTPBackend posts the analytics to an HTTP endpoint.
+type HTTPBackend struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewHTTPBackend returns the HTTP backend of the configuration.
+func NewHTTPBackend(conf config.AnalyticsHTTPBackendConfig) (*HTTPBackend, error) {
+	if conf.URL == "" {
+		return nil, errors.New("analytics http backend requires a url")
+	}
+
+	timeout := defaultAnalyticsBackendTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.SSLInsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &HTTPBackend{
+		url:     conf.URL,
+		headers: conf.Headers,
+		client:  &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+func (*HTTPBackend) Ready() error {
+	return nil
+}
+
+func (h *HTTPBackend) Ship(funcName, data string) error {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewBufferString(data))
+	if err != nil {
+		return err
+	}
+
+	for name, value := range h.headers {
+		req.Header.Set(name, value)
+	}
+	contentType := "application/json"
+	if funcName == purgeProtobufFuncName {
+		contentType = "application/x-protobuf"
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(AnalyticsFunctionHeader, funcName)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("analytics endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (*HTTPBackend) SupportsAggregates() bool {
+	return true
+}
+
+func (*HTTPBackend) SupportsProtobuf() bool {
+	return true
+}
@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+// KafkaBackend produces the analytics to a Kafka topic, a message per record keyed by the RPC
+// function of the payload. The records shipped with protobuf are produced serialized with
+// protobuf. The producer connects to the brokers when the purger first ships, and reconnects
+// after failures.
+type KafkaBackend struct {
+	brokers []string
+	topic   string
+	config  *sarama.Config
+
+	mu       sync.Mutex
+	producer sarama.SyncProducer
+}
+
+// NewKafkaBackend returns the Kafka backend of the configuration.
+func NewKafkaBackend(conf config.AnalyticsKafkaBackendConfig) (*KafkaBackend, error) {
+	if len(conf.Brokers) == 0 || conf.Topic == "" {
+		return nil, errors.New("analytics kafka backend requires brokers and a topic")
+	}
+
+	timeout := defaultAnalyticsBackendTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+
+	saramaConf := sarama.NewConfig()
+	saramaConf.Producer.Return.Successes = true
+	saramaConf.Producer.RequiredAcks = sarama.WaitForLocal
+	saramaConf.Producer.Timeout = timeout
+	saramaConf.Net.DialTimeout = timeout
+	saramaConf.Net.ReadTimeout = timeout
+	saramaConf.Net.WriteTimeout = timeout
+	if conf.ClientID != "" {
+		saramaConf.ClientID = conf.ClientID
+	}
+
+	if conf.UseSSL {
+		saramaConf.Net.TLS.Enable = true
+		saramaConf.Net.TLS.Config = &tls.Config{InsecureSkipVerify: conf.SSLInsecureSkipVerify}
+	}
+
+	if conf.SASLUsername != "" {
+		saramaConf.Net.SASL.Enable = true
+		saramaConf.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		saramaConf.Net.SASL.User = conf.SASLUsername
+		saramaConf.Net.SASL.Password = conf.SASLPassword
+	}
+
+	if err := saramaConf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &KafkaBackend{brokers: conf.Brokers, topic: conf.Topic, config: saramaConf}, nil
+}
+
+// Ready connects the producer to the brokers.
+func (k *KafkaBackend) Ready() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.producer != nil {
+		return nil
+	}
+
+	producer, err := sarama.NewSyncProducer(k.brokers, k.config)
+	if err != nil {
+		return err
+	}
+
+	k.producer = producer
+	return nil
+}
+
+func (k *KafkaBackend) Ship(funcName, data string) error {
+	var records [][]byte
+	if funcName == purgeProtobufFuncName {
+		var err error
+		if records, err = splitProtobufRecords(data); err != nil {
+			return err
+		}
+	} else {
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(data), &raw); err != nil {
+			return err
+		}
+		for _, record := range raw {
+			records = append(records, record)
+		}
+	}
+
+	messages := make([]*sarama.ProducerMessage, 0, len(records))
+	for _, record := range records {
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic: k.topic,
+			Key:   sarama.StringEncoder(funcName),
+			Value: sarama.ByteEncoder(record),
+		})
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.producer == nil {
+		return errors.New("analytics kafka producer isn't connected")
+	}
+
+	if err := k.producer.SendMessages(messages); err != nil {
+		// reconnect on the next purge
+		k.producer.Close()
+		k.producer = nil
+		return err
+	}
+	return nil
+}
+
+func (*KafkaBackend) SupportsAggregates() bool {
+	return true
+}
+
+func (*KafkaBackend) SupportsProtobuf() bool {
+	return true
+}
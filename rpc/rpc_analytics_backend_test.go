@@ -0,0 +1,134 @@
+package rpc
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/TykTechnologies/tyk-pump/analytics"
+
+	"github.com/TykTechnologies/tyk/config"
+	"github.com/TykTechnologies/tyk/storage"
+)
+
+// analyticsSetStore is a store of analytics sets.
+type analyticsSetStore struct {
+	storage.Handler
+	mu   sync.Mutex
+	sets map[string][]interface{}
+}
+
+func (s *analyticsSetStore) GetAndDeleteSet(key string) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := s.sets[key]
+	delete(s.sets, key)
+	return values
+}
+
+func (s *analyticsSetStore) AppendToSet(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sets[key] = append(s.sets[key], value)
+}
+
+func TestPurgerBackend(t *testing.T) {
+	encoded, err := msgpack.Marshal(&analytics.AnalyticsRecord{Method: http.MethodPost, APIID: "api"})
+	require.NoError(t, err)
+
+	shipped := map[string]string{}
+	purger := Purger{
+		Store: &analyticsSetStore{sets: map[string][]interface{}{
+			ANALYTICS_KEYNAME:              {string(encoded)},
+			ANALYTICS_KEYNAME + "_1":       {string(encoded), string(encoded)},
+			ANALYTICS_KEYNAME + "_ignored": {string(encoded)},
+		}},
+		Shards: func() int { return 2 },
+		Backend: SinkBackend(func(funcName, data string) error {
+			shipped[funcName] += data
+			return nil
+		}),
+	}
+	purger.PurgeCache()
+
+	assert.Len(t, shipped, 1)
+	assert.Contains(t, shipped["PurgeAnalyticsData"], `"api_id":"api"`)
+}
+
+func TestHTTPBackend(t *testing.T) {
+	var function, auth, body string
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		function = r.Header.Get(AnalyticsFunctionHeader)
+		auth = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPBackend(config.AnalyticsHTTPBackendConfig{})
+	assert.Error(t, err)
+
+	backend, err := NewHTTPBackend(config.AnalyticsHTTPBackendConfig{URL: server.URL, Headers: map[string]string{"Authorization": "secret"}})
+	require.NoError(t, err)
+	require.NoError(t, backend.Ready())
+
+	require.NoError(t, backend.Ship("PurgeAnalyticsAggregates", `[{"api_id":"api"}]`))
+	assert.Equal(t, "PurgeAnalyticsAggregates", function)
+	assert.Equal(t, "secret", auth)
+	assert.Equal(t, `[{"api_id":"api"}]`, body)
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, backend.Ship("PurgeAnalyticsData", `[]`))
+}
+
+func TestKafkaBackend(t *testing.T) {
+	_, err := NewKafkaBackend(config.AnalyticsKafkaBackendConfig{Brokers: []string{"localhost:9092"}})
+	assert.Error(t, err, "a topic is required")
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	produced := sarama.NewMockProduceResponse(t)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("analytics", 0, broker.BrokerID()),
+		"ProduceRequest": produced,
+	})
+
+	backend, err := NewKafkaBackend(config.AnalyticsKafkaBackendConfig{Brokers: []string{broker.Addr()}, Topic: "analytics", Timeout: 1})
+	require.NoError(t, err)
+	assert.Error(t, backend.Ship("PurgeAnalyticsData", `[]`), "the producer connects when ready")
+	require.NoError(t, backend.Ready())
+
+	records, err := json.Marshal([]map[string]string{{"api_id": "a"}, {"api_id": "b"}})
+	require.NoError(t, err)
+	require.NoError(t, backend.Ship("PurgeAnalyticsData", string(records)))
+	assert.Error(t, backend.Ship("PurgeAnalyticsData", "{"))
+
+	record, err := protobufRecords.encode(analytics.AnalyticsRecord{APIID: "a"})
+	require.NoError(t, err)
+	require.NoError(t, backend.Ship(purgeProtobufFuncName, protobufRecords.payload([][]byte{record, record})))
+	assert.Error(t, backend.Ship(purgeProtobufFuncName, "\xff"))
+
+	var produceRequests int
+	for _, call := range broker.History() {
+		if _, ok := call.Request.(*sarama.ProduceRequest); ok {
+			produceRequests++
+		}
+	}
+	assert.GreaterOrEqual(t, produceRequests, 2, "the producer may split the messages of a payload")
+}
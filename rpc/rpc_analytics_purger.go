@@ -1,12 +1,18 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/TykTechnologies/tyk-pump/analytics"
+	"github.com/TykTechnologies/tyk-pump/serializer"
 
 	"github.com/vmihailenco/msgpack"
 
@@ -15,10 +21,52 @@ import (
 
 const ANALYTICS_KEYNAME = "tyk-system-analytics"
 
+// purgeProtobufFuncName is the RPC function shipping the records serialized with protobuf.
+const purgeProtobufFuncName = "PurgeAnalyticsDataProtobuf"
+
+// defaultAnalyticsKeyShards is the number of analytics keys records are divided in by default.
+const defaultAnalyticsKeyShards = 10
+
+const (
+	// defaultMaxBatchRecords is the default maximum number of records shipped at once.
+	defaultMaxBatchRecords = 1000
+	// defaultMaxBatchBytes is the default maximum size of the batches of records, in bytes.
+	defaultMaxBatchBytes = 4 << 20
+)
+
 // RPCPurger will purge analytics data into a Mongo database, requires that the Mongo DB string is specified
 // in the Config object
 type Purger struct {
 	Store storage.Handler
+	// Aggregate ships the per-minute rollups of the records with PurgeAnalyticsAggregates.
+	Aggregate bool
+	// ShipRawRecords ships the records along with their rollups, when aggregating.
+	ShipRawRecords bool
+	// Backend ships the analytics, to RPC when it isn't set.
+	Backend PurgerBackend
+	// Shards returns the number of analytics keys to purge, 10 when it isn't set.
+	Shards func() int
+	// Concurrency is the number of analytics keys purged in parallel, 1 when it isn't set.
+	Concurrency int
+	// MaxBatchRecords and MaxBatchBytes cap the batches of records shipped at once, 1000 records
+	// and 4MB when they aren't set.
+	MaxBatchRecords int
+	MaxBatchBytes   int
+	// Requeue pushes the records of the batches which failed to ship back to their analytics key,
+	// for the next purge to ship them. They expire with the key while the backend keeps failing.
+	Requeue bool
+	// Protobuf ships the records serialized with protobuf with PurgeAnalyticsDataProtobuf, cutting
+	// the size of the payloads. The records are shipped as JSON to the backends which don't
+	// support it.
+	Protobuf bool
+	// Stats counts the analytics shipped, when set.
+	Stats *PurgerStats
+	// Spill buffers the payloads which fail to ship on disk, when set, instead of requeuing the
+	// records. The spilled payloads are shipped first once the backend is reachable again.
+	Spill *DiskSpill
+
+	// spilling is set for the rest of a purge once a payload failed to ship, accessed atomically.
+	spilling int32
 }
 
 // Connect Connects to RPC
@@ -40,6 +88,18 @@ func (r *Purger) Connect() {
 		})
 		addedFuncs["PurgeAnalyticsData"] = true
 	}
+	if !addedFuncs["PurgeAnalyticsAggregates"] {
+		dispatcher.AddFunc("PurgeAnalyticsAggregates", func(data string) error {
+			return nil
+		})
+		addedFuncs["PurgeAnalyticsAggregates"] = true
+	}
+	if !addedFuncs[purgeProtobufFuncName] {
+		dispatcher.AddFunc(purgeProtobufFuncName, func(data string) error {
+			return nil
+		})
+		addedFuncs[purgeProtobufFuncName] = true
+	}
 
 	Log.Info("RPC Analytics client using singleton")
 }
@@ -61,46 +121,339 @@ func (r Purger) PurgeLoop(ctx context.Context, interval time.Duration) {
 
 // PurgeCache will pull the data from the in-memory store and drop it into the specified MongoDB collection
 func (r *Purger) PurgeCache() {
+	backend := r.backend()
+	r.setSpilling(false)
 
-	if !values.ClientIsConnected() {
-		Log.Error("RPC client is not connected, use Connect method 1st")
+	if err := backend.Ready(); err != nil {
+		r.Stats.failed(err)
+		if r.Spill == nil {
+			Log.WithError(err).Error("Can't purge cache, analytics backend isn't ready")
+			return
+		}
+
+		Log.WithError(err).Warning("Analytics backend isn't ready, spilling analytics to disk")
+		r.setSpilling(true)
+	} else if r.Spill != nil {
+		shipped, err := r.Spill.Drain(backend.Ship)
+		if shipped > 0 {
+			Log.WithField("payloads", shipped).Info("Shipped spilled analytics")
+		}
+		if err != nil {
+			Log.WithError(err).Warning("Failed to ship spilled analytics, spilling analytics to disk")
+			r.Stats.failed(err)
+			r.setSpilling(true)
+		}
 	}
 
-	if _, err := FuncClientSingleton("Ping", nil); err != nil {
-		Log.WithError(err).Error("Can't purge cache, failed to ping RPC")
-		return
+	shards := defaultAnalyticsKeyShards
+	if r.Shards != nil {
+		shards = r.Shards()
 	}
 
-	for i := -1; i < 10; i++ {
-		var analyticsKeyName string
-		if i == -1 {
-			//if it's the first iteration, we look for tyk-system-analytics to maintain backwards compatibility or if analytics_config.enable_multiple_analytics_keys is disabled in the gateway
-			analyticsKeyName = ANALYTICS_KEYNAME
-		} else {
-			// keyname + serializationmethod
-			analyticsKeyName = fmt.Sprintf("%v_%v", ANALYTICS_KEYNAME, i)
+	//if it's the first key, we look for tyk-system-analytics to maintain backwards compatibility or if analytics_config.enable_multiple_analytics_keys is disabled in the gateway
+	keyNames := []string{ANALYTICS_KEYNAME}
+	for i := 0; i < shards; i++ {
+		// keyname + serializationmethod
+		keyNames = append(keyNames, fmt.Sprintf("%v_%v", ANALYTICS_KEYNAME, i))
+	}
+
+	concurrency := r.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(keyNames) {
+		concurrency = len(keyNames)
+	}
+
+	// the keys left once a key failed to purge are kept for the next purge, backing off the backend
+	var failed int32
+	work := make(chan string)
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			defer wg.Done()
+			for analyticsKeyName := range work {
+				if atomic.LoadInt32(&failed) == 1 {
+					continue
+				}
+				if !r.purgeKey(backend, analyticsKeyName) {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+
+	for _, analyticsKeyName := range keyNames {
+		if atomic.LoadInt32(&failed) == 1 {
+			break
+		}
+		work <- analyticsKeyName
+	}
+	close(work)
+	wg.Wait()
+
+	if atomic.LoadInt32(&failed) == 0 && !r.isSpilling() {
+		r.Stats.purged()
+	}
+}
+
+// purgeKey ships the records of an analytics key. It returns false when the records failed to
+// ship, for the purge to back off until the next one.
+func (r *Purger) purgeKey(backend PurgerBackend, analyticsKeyName string) bool {
+	analyticsValues := r.Store.GetAndDeleteSet(analyticsKeyName)
+	if len(analyticsValues) == 0 {
+		return true
+	}
+	keys, failedRecords := processAnalyticsValues(analyticsValues)
+	Log.Debugf("could not decode %v records", failedRecords)
+	r.Stats.undecodable(failedRecords)
+
+	requeue := r.Requeue
+
+	// servers not supporting aggregates get the records
+	if r.Aggregate && backend.SupportsAggregates() {
+		if err := r.purgeAggregates(keys); err != nil {
+			Log.Warn("Failed to call aggregates purge: ", err)
+			if requeue {
+				r.requeue(analyticsKeyName, analyticsValues, keys)
+			}
+			return false
+		}
+		if !r.ShipRawRecords {
+			if !r.isSpilling() {
+				r.Stats.shipped(len(keys) - failedRecords)
+			}
+			return true
+		}
+		// the rollups of the records are shipped, shipping the records again would count them twice
+		requeue = false
+	}
+
+	if err := r.purgeRecords(analyticsKeyName, analyticsValues, keys, requeue); err != nil {
+		Log.Warn("Failed to call purge, retrying: ", err)
+		return false
+	}
+
+	return true
+}
+
+func (r *Purger) isSpilling() bool {
+	return atomic.LoadInt32(&r.spilling) == 1
+}
+
+func (r *Purger) setSpilling(spilling bool) {
+	var v int32
+	if spilling {
+		v = 1
+	}
+	atomic.StoreInt32(&r.spilling, v)
+}
+
+// purgeRecords ships the records in batches capped by MaxBatchRecords and MaxBatchBytes. When a
+// batch fails to ship, the records of the batch and of the next batches are requeued if requeue
+// is set.
+func (r *Purger) purgeRecords(analyticsKeyName string, values, records []interface{}, requeue bool) error {
+	maxRecords, maxBytes := r.MaxBatchRecords, r.MaxBatchBytes
+	if maxRecords <= 0 {
+		maxRecords = defaultMaxBatchRecords
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBatchBytes
+	}
+
+	encoding := jsonRecords
+	if r.Protobuf && r.backend().SupportsProtobuf() {
+		encoding = protobufRecords
+	}
+
+	var batch [][]byte
+	size, start := 0, 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
 		}
 
-		analyticsValues := r.Store.GetAndDeleteSet(analyticsKeyName)
-		if len(analyticsValues) == 0 {
+		err := r.ship(encoding.funcName, encoding.payload(batch))
+		if err == nil && !r.isSpilling() {
+			r.Stats.shipped(len(batch))
+		}
+		batch, size = batch[:0], 0
+		return err
+	}
+
+	for i, record := range records {
+		if record == nil {
+			// the record couldn't be decoded
 			continue
 		}
-		keys, failedRecords := processAnalyticsValues(analyticsValues)
-		Log.Debugf("could not decode %v records", failedRecords)
 
-		data, err := json.Marshal(keys)
+		data, err := encoding.encode(record)
 		if err != nil {
 			Log.WithError(err).Error("Failed to marshal analytics data")
-			return
+			continue
+		}
+
+		if len(batch) > 0 && (len(batch) >= maxRecords || size+len(data)+1 > maxBytes) {
+			if err := flush(); err != nil {
+				if requeue {
+					r.requeue(analyticsKeyName, values[start:], records[start:])
+				}
+				return err
+			}
+			start = i
+		}
+
+		batch = append(batch, data)
+		size += len(data) + 1
+	}
+
+	if err := flush(); err != nil {
+		if requeue {
+			r.requeue(analyticsKeyName, values[start:], records[start:])
+		}
+		return err
+	}
+
+	return nil
+}
+
+// recordsEncoding is a serialization of the batches of records shipped by the purger.
+type recordsEncoding struct {
+	funcName string
+	encode   func(record interface{}) ([]byte, error)
+	payload  func(batch [][]byte) string
+}
+
+// jsonRecords ships the batches as JSON arrays.
+var jsonRecords = recordsEncoding{
+	funcName: "PurgeAnalyticsData",
+	encode:   json.Marshal,
+	payload: func(batch [][]byte) string {
+		return "[" + string(bytes.Join(batch, []byte(","))) + "]"
+	},
+}
+
+// protobufRecords ships the batches as the records serialized with protobuf, each prefixed with
+// its varint encoded length.
+var protobufRecords = recordsEncoding{
+	funcName: purgeProtobufFuncName,
+	encode: func(record interface{}) ([]byte, error) {
+		rec, ok := record.(analytics.AnalyticsRecord)
+		if !ok {
+			return nil, fmt.Errorf("unexpected analytics record %T", record)
 		}
 
-		// Send keys to RPC
-		if _, err := FuncClientSingleton("PurgeAnalyticsData", string(data)); err != nil {
-			EmitErrorEvent(FuncClientSingletonCall, "PurgeAnalyticsData", err)
-			Log.Warn("Failed to call purge, retrying: ", err)
+		data, err := (&serializer.ProtobufSerializer{}).Encode(&rec)
+		if err != nil {
+			return nil, err
 		}
+		return append(binary.AppendUvarint(nil, uint64(len(data))), data...), nil
+	},
+	payload: func(batch [][]byte) string {
+		return string(bytes.Join(batch, nil))
+	},
+}
+
+// splitProtobufRecords splits a payload of PurgeAnalyticsDataProtobuf into its records.
+func splitProtobufRecords(data string) ([][]byte, error) {
+	var records [][]byte
+
+	payload := []byte(data)
+	for len(payload) > 0 {
+		size, n := binary.Uvarint(payload)
+		if n <= 0 || uint64(len(payload)-n) < size {
+			return nil, errors.New("malformed protobuf analytics payload")
+		}
+
+		records = append(records, payload[n:n+int(size)])
+		payload = payload[n+int(size):]
+	}
+
+	return records, nil
+}
+
+// requeue pushes the encoded records back to their analytics key, except for the records which
+// couldn't be decoded.
+func (r *Purger) requeue(analyticsKeyName string, values, records []interface{}) {
+	requeued := make([][]byte, 0, len(values))
+	for i, value := range values {
+		encoded, ok := value.(string)
+		if !ok || records[i] == nil {
+			continue
+		}
+		requeued = append(requeued, []byte(encoded))
+	}
+
+	if len(requeued) == 0 {
+		return
+	}
+
+	Log.WithField("records", len(requeued)).Warning("Requeued analytics records which failed to ship")
+	if store, ok := r.Store.(storage.AnalyticsHandler); ok {
+		store.AppendToSetPipelined(analyticsKeyName, requeued)
+		return
+	}
+	for _, encoded := range requeued {
+		r.Store.AppendToSet(analyticsKeyName, string(encoded))
+	}
+}
+
+// purgeAggregates ships the per-minute rollups of the records to RPC.
+func (r *Purger) purgeAggregates(records []interface{}) error {
+	aggregates := aggregateAnalyticsRecords(records)
+	if len(aggregates) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(aggregates)
+	if err != nil {
+		Log.WithError(err).Error("Failed to marshal analytics aggregates")
+		return nil
+	}
+
+	return r.ship("PurgeAnalyticsAggregates", string(data))
+}
+
+// ship sends the analytics to the backend of the purger.
+func (r *Purger) ship(funcName, data string) error {
+	if r.isSpilling() {
+		return r.spill(funcName, data, nil)
+	}
+
+	err := r.backend().Ship(funcName, data)
+	if err != nil {
+		r.Stats.failed(err)
+		if r.Spill != nil {
+			// the next payloads of the purge are spilled without trying the backend
+			r.setSpilling(true)
+			return r.spill(funcName, data, err)
+		}
+	}
+	return err
+}
+
+// spill writes a payload to the disk spill. It returns the error of the spill, or shipErr.
+func (r *Purger) spill(funcName, data string, shipErr error) error {
+	if err := r.Spill.Write(funcName, data); err != nil {
+		Log.WithError(err).Error("Failed to spill analytics to disk")
+		if shipErr != nil {
+			return shipErr
+		}
+		return err
+	}
+
+	r.Stats.spill()
+	return nil
+}
 
+func (r *Purger) backend() PurgerBackend {
+	if r.Backend == nil {
+		return RPCBackend{}
 	}
+	return r.Backend
 }
 
 func processAnalyticsValues(analyticsValues []interface{}) ([]interface{}, int) {
@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PurgerStats counts the analytics shipped by a purger, for operators to alert when shipping
+// stalls.
+type PurgerStats struct {
+	recordsPurged uint64
+	failedDecodes uint64
+	shipFailures  uint64
+	spilled       uint64
+
+	started     time.Time
+	mu          sync.Mutex
+	lastPurge   time.Time
+	lastFailure time.Time
+	lastError   string
+}
+
+// PurgerStatsSnapshot is a point in time copy of the purger stats.
+type PurgerStatsSnapshot struct {
+	// Started is the time the stats were created, when the purger started.
+	Started time.Time `json:"started"`
+	// RecordsPurged is the number of records shipped, the records shipped from the spill aside.
+	RecordsPurged uint64 `json:"records_purged"`
+	// FailedDecodes is the number of records dropped as they couldn't be decoded.
+	FailedDecodes uint64 `json:"failed_decodes"`
+	// ShipFailures is the number of payloads the backend failed to ship.
+	ShipFailures uint64 `json:"ship_failures"`
+	// Spilled is the number of payloads spilled to disk.
+	Spilled uint64 `json:"spilled"`
+	// LastPurge is the time of the last purge which shipped every payload.
+	LastPurge time.Time `json:"last_purge"`
+	// LastFailure is the time of the last payload which failed to ship, and LastError its error.
+	LastFailure time.Time `json:"last_failure"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// NewPurgerStats returns the stats of a purger starting now.
+func NewPurgerStats() *PurgerStats {
+	return &PurgerStats{started: time.Now()}
+}
+
+func (s *PurgerStats) shipped(records int) {
+	if s != nil {
+		atomic.AddUint64(&s.recordsPurged, uint64(records))
+	}
+}
+
+func (s *PurgerStats) undecodable(records int) {
+	if s != nil {
+		atomic.AddUint64(&s.failedDecodes, uint64(records))
+	}
+}
+
+func (s *PurgerStats) spill() {
+	if s != nil {
+		atomic.AddUint64(&s.spilled, 1)
+	}
+}
+
+func (s *PurgerStats) failed(err error) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddUint64(&s.shipFailures, 1)
+
+	s.mu.Lock()
+	s.lastFailure = time.Now()
+	s.lastError = err.Error()
+	s.mu.Unlock()
+}
+
+func (s *PurgerStats) purged() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.lastPurge = time.Now()
+	s.mu.Unlock()
+}
+
+// Snapshot returns a copy of the stats.
+func (s *PurgerStats) Snapshot() PurgerStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return PurgerStatsSnapshot{
+		Started:       s.started,
+		RecordsPurged: atomic.LoadUint64(&s.recordsPurged),
+		FailedDecodes: atomic.LoadUint64(&s.failedDecodes),
+		ShipFailures:  atomic.LoadUint64(&s.shipFailures),
+		Spilled:       atomic.LoadUint64(&s.spilled),
+		LastPurge:     s.lastPurge,
+		LastFailure:   s.lastFailure,
+		LastError:     s.lastError,
+	}
+}
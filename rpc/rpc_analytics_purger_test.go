@@ -1,12 +1,19 @@
 package rpc
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/vmihailenco/msgpack.v2"
 
 	"github.com/TykTechnologies/tyk-pump/analytics"
+	"github.com/TykTechnologies/tyk-pump/serializer"
 )
 
 func TestDecodeAnalyticsRecord(t *testing.T) {
@@ -91,3 +98,222 @@ func TestProcessAnalyticsValues(t *testing.T) {
 		})
 	}
 }
+
+func TestPurgeBatches(t *testing.T) {
+	encoded := make([]interface{}, 5)
+	for i := range encoded {
+		data, err := msgpack.Marshal(&analytics.AnalyticsRecord{APIID: fmt.Sprint("api-", i)})
+		require.NoError(t, err)
+		encoded[i] = string(data)
+	}
+	withInvalid := append([]interface{}{"invalid"}, encoded...)
+
+	purge := func(t *testing.T, purger Purger, failing func(batch int) bool) ([][]string, []interface{}) {
+		t.Helper()
+
+		store := &analyticsSetStore{sets: map[string][]interface{}{ANALYTICS_KEYNAME: withInvalid}}
+		var batches [][]string
+		purger.Store = store
+		purger.Shards = func() int { return 0 }
+		purger.Backend = SinkBackend(func(funcName, data string) error {
+			if funcName == "PurgeAnalyticsAggregates" {
+				if failing(-1) {
+					return errors.New("failed")
+				}
+				return nil
+			}
+
+			var records []analytics.AnalyticsRecord
+			require.NoError(t, json.Unmarshal([]byte(data), &records))
+			batch := []string{}
+			for _, record := range records {
+				batch = append(batch, record.APIID)
+			}
+			batches = append(batches, batch)
+			if failing(len(batches) - 1) {
+				return errors.New("failed")
+			}
+			return nil
+		})
+		purger.PurgeCache()
+		return batches, store.sets[ANALYTICS_KEYNAME]
+	}
+	never := func(int) bool { return false }
+
+	t.Run("records", func(t *testing.T) {
+		batches, requeued := purge(t, Purger{MaxBatchRecords: 2}, never)
+		assert.Equal(t, [][]string{{"api-0", "api-1"}, {"api-2", "api-3"}, {"api-4"}}, batches)
+		assert.Empty(t, requeued)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		batches, _ := purge(t, Purger{MaxBatchBytes: 1}, never)
+		assert.Len(t, batches, 5, "a record bigger than the batch is shipped alone")
+
+		batches, _ = purge(t, Purger{}, never)
+		assert.Len(t, batches, 1)
+	})
+
+	t.Run("requeue", func(t *testing.T) {
+		batches, requeued := purge(t, Purger{MaxBatchRecords: 2, Requeue: true}, func(batch int) bool { return batch == 1 })
+		assert.Len(t, batches, 2, "the purge backs off after a failure")
+		assert.Equal(t, encoded[2:], requeued, "the undecodable records aren't requeued")
+
+		_, requeued = purge(t, Purger{MaxBatchRecords: 2}, func(batch int) bool { return batch == 1 })
+		assert.Empty(t, requeued)
+	})
+
+	t.Run("aggregates", func(t *testing.T) {
+		batches, requeued := purge(t, Purger{Aggregate: true, ShipRawRecords: true, Requeue: true}, func(batch int) bool { return batch == -1 })
+		assert.Empty(t, batches)
+		assert.Equal(t, encoded, requeued)
+
+		_, requeued = purge(t, Purger{Aggregate: true, ShipRawRecords: true, Requeue: true}, func(batch int) bool { return batch == 0 })
+		assert.Empty(t, requeued, "the records of the shipped aggregates aren't requeued")
+	})
+}
+
+// jsonOnlyBackend is a backend which doesn't support protobuf.
+type jsonOnlyBackend struct {
+	SinkBackend
+}
+
+func (jsonOnlyBackend) SupportsProtobuf() bool {
+	return false
+}
+
+func TestPurgeProtobuf(t *testing.T) {
+	record := analytics.AnalyticsRecord{APIID: "api", Method: "GET", Path: "/path", ResponseCode: 200}
+
+	encoded, err := msgpack.Marshal(&record)
+	require.NoError(t, err)
+
+	purge := func(backend func(SinkBackend) PurgerBackend) map[string]string {
+		shipped := map[string]string{}
+		purger := Purger{
+			Store:    &analyticsSetStore{sets: map[string][]interface{}{ANALYTICS_KEYNAME: {string(encoded), string(encoded)}}},
+			Shards:   func() int { return 0 },
+			Protobuf: true,
+			Backend: backend(func(funcName, data string) error {
+				shipped[funcName] = data
+				return nil
+			}),
+		}
+		purger.PurgeCache()
+		return shipped
+	}
+
+	shipped := purge(func(sink SinkBackend) PurgerBackend { return sink })
+	require.Contains(t, shipped, purgeProtobufFuncName)
+
+	records, err := splitProtobufRecords(shipped[purgeProtobufFuncName])
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	var decoded analytics.AnalyticsRecord
+	require.NoError(t, (&serializer.ProtobufSerializer{}).Decode(records[1], &decoded))
+	assert.Equal(t, record.APIID, decoded.APIID)
+	assert.Equal(t, record.Path, decoded.Path)
+
+	_, err = splitProtobufRecords(shipped[purgeProtobufFuncName][:5])
+	assert.Error(t, err)
+
+	shipped = purge(func(sink SinkBackend) PurgerBackend { return jsonOnlyBackend{sink} })
+	assert.Contains(t, shipped["PurgeAnalyticsData"], `"api_id":"api"`, "records fall back to JSON")
+}
+
+func TestPurgerStats(t *testing.T) {
+	encoded, err := msgpack.Marshal(&analytics.AnalyticsRecord{APIID: "api"})
+	require.NoError(t, err)
+
+	failing := false
+	purger := Purger{
+		Shards: func() int { return 0 },
+		Stats:  NewPurgerStats(),
+		Backend: SinkBackend(func(string, string) error {
+			if failing {
+				return errors.New("unavailable")
+			}
+			return nil
+		}),
+	}
+
+	purger.Store = &analyticsSetStore{sets: map[string][]interface{}{ANALYTICS_KEYNAME: {string(encoded), "invalid", string(encoded)}}}
+	purger.PurgeCache()
+
+	stats := purger.Stats.Snapshot()
+	assert.Equal(t, uint64(2), stats.RecordsPurged)
+	assert.Equal(t, uint64(1), stats.FailedDecodes)
+	assert.Zero(t, stats.ShipFailures)
+	assert.False(t, stats.LastPurge.IsZero())
+
+	failing = true
+	purger.Store = &analyticsSetStore{sets: map[string][]interface{}{ANALYTICS_KEYNAME: {string(encoded)}}}
+	purger.PurgeCache()
+
+	next := purger.Stats.Snapshot()
+	assert.Equal(t, uint64(2), next.RecordsPurged)
+	assert.Equal(t, uint64(1), next.ShipFailures)
+	assert.Equal(t, "unavailable", next.LastError)
+	assert.Equal(t, stats.LastPurge, next.LastPurge, "the failed purge isn't a successful one")
+}
+
+func TestPurgeConcurrency(t *testing.T) {
+	encoded, err := msgpack.Marshal(&analytics.AnalyticsRecord{APIID: "api"})
+	require.NoError(t, err)
+
+	newStore := func() *analyticsSetStore {
+		store := &analyticsSetStore{sets: map[string][]interface{}{ANALYTICS_KEYNAME: {string(encoded)}}}
+		for i := 0; i < defaultAnalyticsKeyShards; i++ {
+			store.sets[fmt.Sprintf("%v_%v", ANALYTICS_KEYNAME, i)] = []interface{}{string(encoded)}
+		}
+		return store
+	}
+
+	var inFlight, maxInFlight, shipped int32
+	purger := Purger{
+		Store:       newStore(),
+		Concurrency: 4,
+		Stats:       NewPurgerStats(),
+		Backend: SinkBackend(func(string, string) error {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&shipped, 1)
+			return nil
+		}),
+	}
+	purger.PurgeCache()
+
+	assert.EqualValues(t, defaultAnalyticsKeyShards+1, shipped)
+	assert.Greater(t, maxInFlight, int32(1), "the keys are purged in parallel")
+	assert.LessOrEqual(t, maxInFlight, int32(4))
+	assert.EqualValues(t, defaultAnalyticsKeyShards+1, purger.Stats.Snapshot().RecordsPurged)
+	assert.False(t, purger.Stats.Snapshot().LastPurge.IsZero())
+
+	t.Run("backs off after a failure", func(t *testing.T) {
+		var calls int32
+		store := newStore()
+		purger := Purger{
+			Store:       store,
+			Concurrency: 2,
+			Stats:       NewPurgerStats(),
+			Backend: SinkBackend(func(string, string) error {
+				atomic.AddInt32(&calls, 1)
+				return errors.New("unavailable")
+			}),
+		}
+		purger.PurgeCache()
+
+		assert.LessOrEqual(t, calls, int32(2), "no key is purged once one failed")
+		assert.NotEmpty(t, store.sets, "the keys left are kept for the next purge")
+		assert.True(t, purger.Stats.Snapshot().LastPurge.IsZero())
+	})
+}
@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spillFileExt = ".spill"
+
+	// defaultSpillMaxBytes is the default size cap of the spilled payloads.
+	defaultSpillMaxBytes = 512 << 20
+	// defaultSpillTTL is the default time the spilled payloads are kept for.
+	defaultSpillTTL = 24 * time.Hour
+)
+
+// DiskSpill is a disk-backed queue of the analytics payloads which failed to ship, drained in
+// order when the backend is reachable again. The oldest payloads are dropped when the queue is
+// over its size cap, and the payloads expire after the TTL.
+type DiskSpill struct {
+	dir      string
+	maxBytes int64
+	ttl      time.Duration
+
+	mu  sync.Mutex
+	seq uint64
+}
+
+// NewDiskSpill returns the spill queue of a directory. The size cap and the TTL default to 512MB
+// and 24 hours when zero.
+func NewDiskSpill(dir string, maxBytes int64, ttl time.Duration) (*DiskSpill, error) {
+	if dir == "" {
+		return nil, errors.New("analytics spill requires a directory")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = defaultSpillMaxBytes
+	}
+	if ttl <= 0 {
+		ttl = defaultSpillTTL
+	}
+
+	return &DiskSpill{dir: dir, maxBytes: maxBytes, ttl: ttl}, nil
+}
+
+type spillFile struct {
+	name     string
+	funcName string
+	size     int64
+	modTime  time.Time
+}
+
+// files lists the spilled payloads, oldest first.
+func (s *DiskSpill) files() ([]spillFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []spillFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != spillFileExt {
+			continue
+		}
+
+		// <unix nano>-<sequence>-<function>.spill
+		parts := strings.SplitN(strings.TrimSuffix(name, spillFileExt), "-", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		files = append(files, spillFile{name: name, funcName: parts[2], size: info.Size(), modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// Write spills the payload of an RPC function, dropping the oldest payloads over the size cap.
+func (s *DiskSpill) Write(funcName, data string) error {
+	if int64(len(data)) > s.maxBytes {
+		return fmt.Errorf("analytics payload of %d bytes is over the spill cap", len(data))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(data))
+	for _, file := range files {
+		size += file.size
+	}
+	for len(files) > 0 && size > s.maxBytes {
+		Log.WithField("file", files[0].name).Warning("Analytics spill is full, dropping the oldest payload")
+		os.Remove(filepath.Join(s.dir, files[0].name))
+		size -= files[0].size
+		files = files[1:]
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d-%s%s", time.Now().UnixNano(), s.seq%1000000, funcName, spillFileExt)
+
+	// write to a temporary file first so that a partial payload is never drained
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := os.WriteFile(tmp, []byte(data), 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+// Drain ships the spilled payloads oldest first, removing them once shipped. It stops at the
+// first payload which fails to ship, which is kept for the next drain.
+func (s *DiskSpill) Drain(ship func(funcName, data string) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+
+	shipped := 0
+	for _, file := range files {
+		path := filepath.Join(s.dir, file.name)
+
+		if time.Since(file.modTime) > s.ttl {
+			Log.WithField("file", file.name).Warning("Dropping expired spilled analytics payload")
+			os.Remove(path)
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return shipped, err
+		}
+
+		if err := ship(file.funcName, string(data)); err != nil {
+			return shipped, err
+		}
+
+		os.Remove(path)
+		shipped++
+	}
+
+	return shipped, nil
+}
+
+// Len returns the number of spilled payloads.
+func (s *DiskSpill) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, _ := s.files()
+	return len(files)
+}
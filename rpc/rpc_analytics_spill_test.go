@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/vmihailenco/msgpack.v2"
+
+	"github.com/TykTechnologies/tyk-pump/analytics"
+)
+
+func TestDiskSpill(t *testing.T) {
+	_, err := NewDiskSpill("", 0, 0)
+	assert.Error(t, err)
+
+	spill, err := NewDiskSpill(t.TempDir(), 10, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, spill.Write("PurgeAnalyticsData", "aaaa"))
+	require.NoError(t, spill.Write("PurgeAnalyticsAggregates", "bbbb"))
+	require.NoError(t, spill.Write("PurgeAnalyticsData", "cccc"))
+	assert.Equal(t, 2, spill.Len(), "the oldest payload is dropped over the cap")
+	assert.Error(t, spill.Write("PurgeAnalyticsData", "too large payload"))
+
+	var shipped []string
+	failing := true
+	ship := func(funcName, data string) error {
+		if failing {
+			return errors.New("unavailable")
+		}
+		shipped = append(shipped, funcName+":"+data)
+		return nil
+	}
+
+	n, err := spill.Drain(ship)
+	assert.Error(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, 2, spill.Len(), "payloads are kept when they fail to ship")
+
+	failing = false
+	n, err = spill.Drain(ship)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"PurgeAnalyticsAggregates:bbbb", "PurgeAnalyticsData:cccc"}, shipped)
+	assert.Zero(t, spill.Len())
+
+	t.Run("expiry", func(t *testing.T) {
+		dir := t.TempDir()
+		spill, err := NewDiskSpill(dir, 0, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, spill.Write("PurgeAnalyticsData", "old"))
+
+		files, err := filepath.Glob(filepath.Join(dir, "*"+spillFileExt))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		past := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(files[0], past, past))
+
+		n, err := spill.Drain(func(string, string) error {
+			t.Error("expired payloads aren't shipped")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		assert.Zero(t, spill.Len())
+	})
+}
+
+func TestPurgeSpill(t *testing.T) {
+	encoded, err := msgpack.Marshal(&analytics.AnalyticsRecord{APIID: "api"})
+	require.NoError(t, err)
+
+	spill, err := NewDiskSpill(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+
+	var shipped []string
+	failing := true
+	store := &analyticsSetStore{sets: map[string][]interface{}{}}
+	purger := Purger{
+		Store:           store,
+		Shards:          func() int { return 0 },
+		MaxBatchRecords: 1,
+		Requeue:         true,
+		Spill:           spill,
+		Stats:           NewPurgerStats(),
+		Backend: SinkBackend(func(funcName, data string) error {
+			if failing {
+				return errors.New("unavailable")
+			}
+			shipped = append(shipped, data)
+			return nil
+		}),
+	}
+
+	store.sets[ANALYTICS_KEYNAME] = []interface{}{string(encoded), string(encoded)}
+	purger.PurgeCache()
+	assert.Empty(t, store.sets[ANALYTICS_KEYNAME], "the records are spilled instead of requeued")
+	assert.Equal(t, 2, spill.Len())
+	assert.Equal(t, uint64(2), purger.Stats.Snapshot().Spilled)
+	assert.Equal(t, uint64(1), purger.Stats.Snapshot().ShipFailures, "the backend isn't tried again once spilling")
+
+	failing = false
+	store.sets[ANALYTICS_KEYNAME] = []interface{}{string(encoded)}
+	purger.PurgeCache()
+	assert.Len(t, shipped, 3, "the spilled payloads are shipped along with the new records")
+	assert.Zero(t, spill.Len())
+	assert.Equal(t, uint64(1), purger.Stats.Snapshot().RecordsPurged)
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the version of the RPC protocol of the gateway. RPC servers which don't
+// negotiate capabilities speak the first version.
+const ProtocolVersion = 2
+
+const (
+	legacyProtocolVersion = 1
+	negotiateFuncName     = "Negotiate"
+)
+
+// OptionalFuncs are the RPC functions added after the first version of the protocol. They
+// are only called when the RPC server supports them, so that gateways can be upgraded before
+// MDCB and the other way around.
+var OptionalFuncs = []string{
+	"PurgeAnalyticsAggregates",
+	"PurgeAnalyticsDataProtobuf",
+}
+
+// ErrRPCFuncUnsupported is returned when calling an optional function the RPC server
+// doesn't support.
+var ErrRPCFuncUnsupported = errors.New("RPC function isn't supported by the RPC server")
+
+// Handshake is exchanged by the gateway and the RPC server on login to negotiate the
+// protocol version and the optional functions both support.
+type Handshake struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Functions       []string `json:"functions"`
+}
+
+// capabilities are the negotiated protocol version and optional functions.
+type capabilities struct {
+	protocolVersion int
+	functions       map[string]bool
+}
+
+var negotiated atomic.Pointer[capabilities]
+
+// negotiate exchanges the handshake with the RPC server. Servers which don't know the
+// Negotiate function are legacy servers, supporting none of the optional functions.
+func negotiate(call func(funcName string, request interface{}) (interface{}, error)) {
+	request, err := json.Marshal(Handshake{ProtocolVersion: ProtocolVersion, Functions: OptionalFuncs})
+	if err != nil {
+		Log.WithError(err).Error("Couldn't encode RPC handshake")
+		return
+	}
+
+	supported := &capabilities{protocolVersion: legacyProtocolVersion, functions: map[string]bool{}}
+	defer negotiated.Store(supported)
+
+	result, err := call(negotiateFuncName, string(request))
+	if err != nil {
+		Log.WithError(err).Debug("RPC server doesn't negotiate capabilities, using the legacy protocol")
+		return
+	}
+
+	handshake, err := decodeHandshake(result)
+	if err != nil {
+		Log.WithError(err).Warning("Couldn't decode RPC handshake, using the legacy protocol")
+		return
+	}
+
+	supported.protocolVersion = min(handshake.ProtocolVersion, ProtocolVersion)
+	for _, funcName := range handshake.Functions {
+		if slices.Contains(OptionalFuncs, funcName) {
+			supported.functions[funcName] = true
+		}
+	}
+
+	Log.WithField("version", supported.protocolVersion).WithField("functions", handshake.Functions).Debug("Negotiated RPC capabilities")
+}
+
+func decodeHandshake(result interface{}) (*Handshake, error) {
+	data, ok := result.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected handshake type %T", result)
+	}
+
+	handshake := &Handshake{}
+	if err := json.Unmarshal([]byte(data), handshake); err != nil {
+		return nil, err
+	}
+
+	return handshake, nil
+}
+
+// NegotiatedProtocolVersion returns the protocol version negotiated with the RPC server.
+func NegotiatedProtocolVersion() int {
+	if supported := negotiated.Load(); supported != nil {
+		return supported.protocolVersion
+	}
+	return legacyProtocolVersion
+}
+
+// Supports returns true if the RPC server supports the function. The functions of the first
+// version of the protocol are always supported.
+func Supports(funcName string) bool {
+	if !slices.Contains(OptionalFuncs, funcName) {
+		return true
+	}
+
+	supported := negotiated.Load()
+	return supported != nil && supported.functions[funcName]
+}
+
+// NegotiateHandler returns the Negotiate function of RPC servers supporting the given
+// optional functions.
+func NegotiateHandler(functions []string) func(string) (string, error) {
+	return func(data string) (string, error) {
+		request := Handshake{}
+		if err := json.Unmarshal([]byte(data), &request); err != nil {
+			return "", err
+		}
+
+		response := Handshake{ProtocolVersion: min(request.ProtocolVersion, ProtocolVersion)}
+		for _, funcName := range functions {
+			if slices.Contains(request.Functions, funcName) {
+				response.Functions = append(response.Functions, funcName)
+			}
+		}
+
+		encoded, err := json.Marshal(response)
+		return string(encoded), err
+	}
+}
@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	defer negotiated.Store(nil)
+
+	t.Run("legacy server", func(t *testing.T) {
+		negotiate(func(string, interface{}) (interface{}, error) {
+			return nil, errors.New("gorpc.Dispatcher: unknown function")
+		})
+
+		assert.Equal(t, legacyProtocolVersion, NegotiatedProtocolVersion())
+		assert.False(t, Supports("PurgeAnalyticsAggregates"))
+		assert.True(t, Supports("PurgeAnalyticsData"))
+	})
+
+	t.Run("negotiating server", func(t *testing.T) {
+		handler := NegotiateHandler([]string{"PurgeAnalyticsAggregates", "UnknownFunction"})
+		negotiate(func(funcName string, request interface{}) (interface{}, error) {
+			assert.Equal(t, negotiateFuncName, funcName)
+			return handler(request.(string))
+		})
+
+		assert.Equal(t, ProtocolVersion, NegotiatedProtocolVersion())
+		assert.True(t, Supports("PurgeAnalyticsAggregates"))
+	})
+
+	t.Run("server without optional functions", func(t *testing.T) {
+		handler := NegotiateHandler(nil)
+		negotiate(func(_ string, request interface{}) (interface{}, error) {
+			return handler(request.(string))
+		})
+
+		assert.Equal(t, ProtocolVersion, NegotiatedProtocolVersion())
+		assert.False(t, Supports("PurgeAnalyticsAggregates"))
+
+		_, err := FuncClientSingleton("PurgeAnalyticsAggregates", "[]")
+		assert.ErrorIs(t, err, ErrRPCFuncUnsupported)
+	})
+}
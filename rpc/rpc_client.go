@@ -90,6 +90,7 @@ func (r *rpcOpts) Reset() {
 	r.emergencyMode.Store(false)
 	r.emergencyModeLoaded.Store(false)
 	r.clientIsConnected.Store(false)
+	negotiated.Store(nil)
 }
 
 func (r *rpcOpts) SetLoadCounts(n int) {
@@ -300,6 +301,11 @@ func Connect(connConfig Config, suppressRegister bool, dispatcherFuncs map[strin
 	clientSingleton.Start()
 
 	loadDispatcher(dispatcherFuncs)
+	loadDispatcher(map[string]interface{}{
+		negotiateFuncName: func(data string) (string, error) {
+			return "", nil
+		},
+	})
 
 	if funcClientSingleton == nil {
 		funcClientSingleton = dispatcher.NewFuncClient(clientSingleton)
@@ -388,6 +394,7 @@ func groupLogin() error {
 	}
 	Log.Debug("[RPC Store] Group Login complete")
 	values.IncrLoadCounts(1)
+	negotiate(FuncClientSingleton)
 	return nil
 }
 
@@ -407,6 +414,7 @@ func login() error {
 	}
 	Log.Debug("[RPC Store] Login complete")
 	values.IncrLoadCounts(1)
+	negotiate(FuncClientSingleton)
 	return nil
 }
 
@@ -468,6 +476,10 @@ func recoverOp(fn func() error) func() error {
 // backoff ensuring indeed we can't connect to the rpc, this will eventually
 // fall into emergency mode( That is handled outside of this function call)
 func FuncClientSingleton(funcName string, request interface{}) (result interface{}, err error) {
+	if !Supports(funcName) {
+		return nil, ErrRPCFuncUnsupported
+	}
+
 	be := backoff.Retry(func() error {
 		if !values.ClientIsConnected() {
 			return ErrRPCIsDown
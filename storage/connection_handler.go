@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/TykTechnologies/storage/temporal/model"
 
 	"github.com/TykTechnologies/tyk/config"
+	redis "github.com/TykTechnologies/tyk/internal/redis"
 )
 
 // ConnectionHandler is a wrapper around the storage connection.
@@ -26,6 +28,9 @@ type ConnectionHandler struct {
 
 	ctx       context.Context
 	reconnect chan struct{}
+
+	instrumentation *Instrumentation
+	observe         func(command string, d time.Duration)
 }
 
 const (
@@ -164,17 +169,45 @@ func (rc *ConnectionHandler) initConnection(conf config.Config) (err error) {
 		AnalyticsConn,
 	}
 
+	if conf.StorageInstrumentation.Enabled && rc.instrumentation == nil {
+		rc.instrumentation = NewInstrumentation(conf.StorageInstrumentation)
+		rc.instrumentation.Observe = rc.observe
+	}
+
 	for _, connType := range connTypes {
 		conn, err := NewConnector(connType, conf)
 		if err != nil {
 			return err
 		}
+
+		if rc.instrumentation != nil {
+			var client redis.UniversalClient
+			if !conn.As(&client) {
+				return errors.New("error converting connection to redis client")
+			}
+			client.AddHook(rc.instrumentation.Hook(connType))
+		}
+
 		rc.connections[connType] = conn
 	}
 
 	return nil
 }
 
+// ObserveCommands sets the function called with the latency of every command when the
+// instrumentation of the storage is enabled. It must be called before connecting.
+func (rc *ConnectionHandler) ObserveCommands(observe func(command string, d time.Duration)) {
+	rc.observe = observe
+}
+
+// Instrumentation returns the instrumentation of the commands sent to the storage, nil when
+// it isn't enabled.
+func (rc *ConnectionHandler) Instrumentation() *Instrumentation {
+	rc.connectionsMu.RLock()
+	defer rc.connectionsMu.RUnlock()
+	return rc.instrumentation
+}
+
 func (rc *ConnectionHandler) isConnected(ctx context.Context, connType string) bool {
 	if conn, ok := rc.connections[connType]; ok && conn != nil {
 		err := conn.Ping(ctx)
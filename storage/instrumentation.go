@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/TykTechnologies/tyk/config"
+	redis "github.com/TykTechnologies/tyk/internal/redis"
+	"github.com/TykTechnologies/tyk/regexp"
+)
+
+const (
+	defaultSlowCommandThreshold = 50
+	defaultSlowLogSize          = 100
+
+	// maxKeyPatterns bounds the number of key patterns counted, patterns seen after are
+	// counted as otherKeyPattern.
+	maxKeyPatterns  = 1000
+	otherKeyPattern = "other"
+)
+
+// latencyBuckets are the upper bounds, in milliseconds, of the latency histogram buckets. The
+// last bucket counts the commands slower than the last bound.
+var latencyBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// keyIdentifiers matches the parts of keys identifying a single entity: UUIDs, hashes, numbers
+// and long tokens, which are replaced by a wildcard in key patterns.
+var keyIdentifiers = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[0-9a-f]{12,}|[a-z0-9+/=_]{24,}|[0-9]+`)
+
+// KeyPattern sanitizes a key so that it can be logged: the identifiers of the key are
+// replaced by a wildcard, `apikey-5d8c...` becomes `apikey-*`.
+func KeyPattern(key string) string {
+	return keyIdentifiers.ReplaceAllString(key, "*")
+}
+
+// CommandStats summarizes the latency of the calls of a command.
+type CommandStats struct {
+	Calls     uint64  `json:"calls"`
+	Errors    uint64  `json:"errors"`
+	AverageMs float64 `json:"average_ms"`
+	MaxMs     float64 `json:"max_ms"`
+	// Histogram counts the calls per latency bucket, keyed by the upper bound of the bucket in
+	// milliseconds.
+	Histogram map[string]uint64 `json:"histogram"`
+
+	total time.Duration
+}
+
+func (s *CommandStats) observe(d time.Duration, failed bool) {
+	s.Calls++
+	if failed {
+		s.Errors++
+	}
+
+	s.total += d
+	ms := float64(d.Microseconds()) / 1000
+	s.AverageMs = float64(s.total.Microseconds()) / float64(s.Calls) / 1000
+	if ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+
+	bucket := "+Inf"
+	for _, bound := range latencyBuckets {
+		if ms <= bound {
+			bucket = strconv.FormatFloat(bound, 'f', -1, 64)
+			break
+		}
+	}
+	s.Histogram[bucket]++
+}
+
+// SlowCommand is a command slower than the slow command threshold.
+type SlowCommand struct {
+	Time       time.Time `json:"time"`
+	Connection string    `json:"connection"`
+	Command    string    `json:"command"`
+	// KeyPattern is the sanitized pattern of the first key of the command.
+	KeyPattern string  `json:"key_pattern,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	// Commands is the number of commands of pipelines.
+	Commands int `json:"commands,omitempty"`
+}
+
+// InstrumentationSnapshot lists the latency of the commands sent to Redis.
+type InstrumentationSnapshot struct {
+	// Commands are the stats per command. Lua scripts are listed per script, as
+	// `evalsha:<sha>`.
+	Commands map[string]CommandStats `json:"commands"`
+	// Keys is the number of commands per key pattern, to find hot keys.
+	Keys map[string]uint64 `json:"keys"`
+	// Slow are the last slow commands, the most recent first.
+	Slow []SlowCommand `json:"slow"`
+}
+
+// Instrumentation records the latency of the commands sent to Redis by every storage handler
+// using the connections, and logs the commands slower than the threshold.
+type Instrumentation struct {
+	// Observe is called for every command, for example to send the latency to StatsD.
+	Observe func(command string, d time.Duration)
+
+	threshold time.Duration
+	size      int
+
+	mu       sync.Mutex
+	commands map[string]*CommandStats
+	keys     map[string]uint64
+	slow     []SlowCommand
+	next     int
+}
+
+// NewInstrumentation returns the instrumentation of the configuration.
+func NewInstrumentation(conf config.StorageInstrumentationConfig) *Instrumentation {
+	threshold := conf.SlowThreshold
+	if threshold <= 0 {
+		threshold = defaultSlowCommandThreshold
+	}
+
+	size := conf.SlowLogSize
+	if size <= 0 {
+		size = defaultSlowLogSize
+	}
+
+	return &Instrumentation{
+		threshold: time.Duration(threshold * float64(time.Millisecond)),
+		size:      size,
+		commands:  map[string]*CommandStats{},
+		keys:      map[string]uint64{},
+	}
+}
+
+// Snapshot returns the stats recorded so far.
+func (i *Instrumentation) Snapshot() InstrumentationSnapshot {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	snapshot := InstrumentationSnapshot{
+		Commands: make(map[string]CommandStats, len(i.commands)),
+		Keys:     make(map[string]uint64, len(i.keys)),
+		Slow:     make([]SlowCommand, 0, len(i.slow)),
+	}
+
+	for name, stats := range i.commands {
+		copied := *stats
+		copied.Histogram = make(map[string]uint64, len(stats.Histogram))
+		for bucket, count := range stats.Histogram {
+			copied.Histogram[bucket] = count
+		}
+		snapshot.Commands[name] = copied
+	}
+
+	for pattern, count := range i.keys {
+		snapshot.Keys[pattern] = count
+	}
+
+	snapshot.Slow = append(snapshot.Slow, i.slow...)
+	sort.SliceStable(snapshot.Slow, func(a, b int) bool {
+		return snapshot.Slow[a].Time.After(snapshot.Slow[b].Time)
+	})
+
+	return snapshot
+}
+
+// Hook returns the Redis hook instrumenting the commands of a connection.
+func (i *Instrumentation) Hook(connType string) redis.Hook {
+	return &instrumentationHook{instrumentation: i, connType: connType}
+}
+
+func (i *Instrumentation) record(connType, command string, patterns []string, d time.Duration, failed bool, commands int) {
+	i.mu.Lock()
+
+	stats, ok := i.commands[command]
+	if !ok {
+		stats = &CommandStats{Histogram: map[string]uint64{}}
+		i.commands[command] = stats
+	}
+	stats.observe(d, failed)
+
+	for _, pattern := range patterns {
+		if _, ok := i.keys[pattern]; !ok && len(i.keys) >= maxKeyPatterns {
+			pattern = otherKeyPattern
+		}
+		i.keys[pattern]++
+	}
+
+	slow := d >= i.threshold
+	var entry SlowCommand
+	if slow {
+		entry = SlowCommand{
+			Time:       time.Now(),
+			Connection: connType,
+			Command:    command,
+			DurationMs: float64(d.Microseconds()) / 1000,
+		}
+		if len(patterns) > 0 {
+			entry.KeyPattern = patterns[0]
+		}
+		if commands > 1 {
+			entry.Commands = commands
+		}
+
+		if len(i.slow) < i.size {
+			i.slow = append(i.slow, entry)
+		} else {
+			i.slow[i.next] = entry
+			i.next = (i.next + 1) % i.size
+		}
+	}
+
+	i.mu.Unlock()
+
+	if slow {
+		log.WithFields(logrus.Fields{
+			"connection":  entry.Connection,
+			"command":     entry.Command,
+			"key_pattern": entry.KeyPattern,
+			"duration_ms": entry.DurationMs,
+		}).Warning("Slow Redis command")
+	}
+
+	if i.Observe != nil {
+		i.Observe(command, d)
+	}
+}
+
+// instrumentationHook records the commands of a Redis connection.
+type instrumentationHook struct {
+	instrumentation *Instrumentation
+	connType        string
+}
+
+func (h *instrumentationHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *instrumentationHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+
+		var patterns []string
+		if pattern, ok := commandKeyPattern(cmd); ok {
+			patterns = append(patterns, pattern)
+		}
+
+		h.instrumentation.record(h.connType, commandName(cmd), patterns, time.Since(start), isCommandError(err), 1)
+		return err
+	}
+}
+
+func (h *instrumentationHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+
+		patterns := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			if pattern, ok := commandKeyPattern(cmd); ok {
+				patterns = append(patterns, pattern)
+			}
+		}
+
+		h.instrumentation.record(h.connType, "pipeline", patterns, time.Since(start), isCommandError(err), len(cmds))
+		return err
+	}
+}
+
+// isCommandError returns true for errors other than missing keys.
+func isCommandError(err error) bool {
+	return err != nil && err != redis.Nil
+}
+
+// commandName returns the name of the command, Lua scripts are named after their SHA1 so that
+// slow scripts can be told apart.
+func commandName(cmd redis.Cmder) string {
+	name := strings.ToLower(cmd.Name())
+	args := cmd.Args()
+	if len(args) < 2 {
+		return name
+	}
+
+	switch name {
+	case "evalsha", "evalsha_ro":
+		return "evalsha:" + shortSHA(fmt.Sprint(args[1]))
+	case "eval", "eval_ro":
+		sum := sha1.Sum([]byte(fmt.Sprint(args[1])))
+		return "evalsha:" + shortSHA(hex.EncodeToString(sum[:]))
+	}
+
+	return name
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// commandKeyPattern returns the pattern of the first key of the command.
+func commandKeyPattern(cmd redis.Cmder) (string, bool) {
+	args := cmd.Args()
+	index := 1
+
+	switch strings.ToLower(cmd.Name()) {
+	case "ping", "info", "select", "auth", "hello", "client", "script", "publish", "subscribe", "psubscribe", "scan":
+		return "", false
+	case "eval", "eval_ro", "evalsha", "evalsha_ro":
+		// EVAL script numkeys key...
+		if len(args) < 4 || fmt.Sprint(args[2]) == "0" {
+			return "", false
+		}
+		index = 3
+	}
+
+	if len(args) <= index {
+		return "", false
+	}
+
+	return KeyPattern(fmt.Sprint(args[index])), true
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/tyk/config"
+)
+
+func TestKeyPattern(t *testing.T) {
+	tests := map[string]string{
+		"apikey-5d8c6e1c2a3b4f5e6d7c8b9a0f1e2d3c":         "apikey-*",
+		"quota-default5d8c6e1c2a3b4f5e6d7c8b9a":           "quota-*",
+		"cache-api1-9f5b8c9d-0f5e-4b2a-9c1d-7e6f5a4b3c2d": "cache-api*-*",
+		"rate-limit-c2FtcGxlLWN1c3RvbS1rZXktbmFtZQ==":     "rate-limit-*",
+		"host-checker:PollerActiveInstanceID":             "host-checker:PollerActiveInstanceID",
+		"tyk-system-analytics_2024":                       "tyk-system-analytics_*",
+	}
+
+	for key, pattern := range tests {
+		assert.Equal(t, pattern, KeyPattern(key), key)
+	}
+}
+
+func TestInstrumentation(t *testing.T) {
+	instrumentation := NewInstrumentation(config.StorageInstrumentationConfig{SlowThreshold: 10, SlowLogSize: 2})
+
+	var observed []string
+	instrumentation.Observe = func(command string, _ time.Duration) {
+		observed = append(observed, command)
+	}
+
+	instrumentation.record(DefaultConn, "get", []string{"apikey-*"}, time.Millisecond, false, 1)
+	instrumentation.record(DefaultConn, "get", []string{"apikey-*"}, 20*time.Millisecond, true, 1)
+	instrumentation.record(CacheConn, "evalsha:0123456789ab", []string{"rate-limit-*"}, 30*time.Millisecond, false, 1)
+	instrumentation.record(AnalyticsConn, "pipeline", []string{"analytics-*", "analytics-*"}, 40*time.Millisecond, false, 2)
+
+	snapshot := instrumentation.Snapshot()
+
+	get := snapshot.Commands["get"]
+	assert.Equal(t, uint64(2), get.Calls)
+	assert.Equal(t, uint64(1), get.Errors)
+	assert.Equal(t, 20.0, get.MaxMs)
+	assert.Equal(t, map[string]uint64{"1": 1, "25": 1}, get.Histogram)
+
+	assert.Equal(t, map[string]uint64{"apikey-*": 2, "rate-limit-*": 1, "analytics-*": 2}, snapshot.Keys)
+
+	// the slow log only keeps the last slow commands
+	assert.Len(t, snapshot.Slow, 2)
+	assert.Equal(t, "pipeline", snapshot.Slow[0].Command)
+	assert.Equal(t, 2, snapshot.Slow[0].Commands)
+	assert.Equal(t, "evalsha:0123456789ab", snapshot.Slow[1].Command)
+	assert.Equal(t, "rate-limit-*", snapshot.Slow[1].KeyPattern)
+
+	assert.Equal(t, []string{"get", "get", "evalsha:0123456789ab", "pipeline"}, observed)
+}
@@ -283,6 +283,26 @@ func (r *RedisCluster) GetKey(keyName string) (string, error) {
 	return value, nil
 }
 
+// GetAndDeleteKey retrieves a key from the database and deletes it atomically, so that
+// concurrent callers don't both get its value.
+func (r *RedisCluster) GetAndDeleteKey(keyName string) (string, error) {
+	client, err := r.Client()
+	if err != nil {
+		log.Error(err)
+		return "", err
+	}
+
+	value, err := client.GetDel(context.Background(), r.fixKey(keyName)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Debug("Error trying to get and delete value:", err)
+		}
+		return "", ErrKeyNotFound
+	}
+
+	return value, nil
+}
+
 // GetMultiKey gets multiple keys from the database
 func (r *RedisCluster) GetMultiKey(keys []string) ([]string, error) {
 	storage, err := r.kv()
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/TykTechnologies/storage/temporal/model"
 	tempmocks "github.com/TykTechnologies/storage/temporal/tempmocks"
@@ -414,6 +415,18 @@ func TestGetKey(t *testing.T) {
 	})
 }
 
+func TestGetAndDeleteKey(t *testing.T) {
+	storage := &RedisCluster{ConnectionHandler: rc, KeyPrefix: "prefix:"}
+	require.NoError(t, storage.SetKey("get-and-delete", "value", 0))
+
+	val, err := storage.GetAndDeleteKey("get-and-delete")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", val)
+
+	_, err = storage.GetAndDeleteKey("get-and-delete")
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
 func TestGetMultiKey(t *testing.T) {
 	t.Run("storage disconnected", func(t *testing.T) {
 		storage := &RedisCluster{ConnectionHandler: rc}
@@ -36,12 +36,74 @@ type Policy struct {
 
 	// Smoothing contains rate limit smoothing settings.
 	Smoothing *apidef.RateLimitSmoothing `json:"smoothing" bson:"smoothing"`
+
+	// QuotaAlignment aligns the quota periods to calendar boundaries.
+	QuotaAlignment *QuotaAlignment `json:"quota_alignment,omitempty" bson:"quota_alignment,omitempty"`
+
+	// DynamicLimits override the rate limit and quota of the policy, and of its per API limits,
+	// for sessions matching their expression. The first matching limit applies. The policies
+	// with an invalid expression are rejected.
+	DynamicLimits []DynamicLimit `json:"dynamic_limits" bson:"dynamic_limits"`
+
+	// QuotaBuckets are named quotas counted separately from the quota of the policy, for
+	// the requests matching their methods and path.
+	QuotaBuckets []QuotaBucket `json:"quota_buckets,omitempty" bson:"quota_buckets,omitempty"`
+
+	// QuotaPool is the ID of the quota pool shared by the keys the policy is applied to.
+	QuotaPool string `json:"quota_pool,omitempty" bson:"quota_pool,omitempty"`
+
+	// MiddlewareFlags enable or disable the consumer scoped middleware of the APIs for the keys
+	// the policy is applied to, by middleware name.
+	MiddlewareFlags map[string]bool `json:"middleware_flags,omitempty" bson:"middleware_flags,omitempty"`
+}
+
+// QuotaBucket is a named quota with its own limit and renewal, counting the requests which
+// match its methods and path, e.g. to give reads and writes or endpoint groups separate
+// quotas. Requests are counted by every matching bucket, in addition to the key quota.
+type QuotaBucket struct {
+	Name string `json:"name" bson:"name" msg:"name"`
+	// Methods are the request methods counted by the bucket, all methods when empty.
+	Methods []string `json:"methods,omitempty" bson:"methods,omitempty" msg:"methods,omitempty"`
+	// Path is a regular expression matched against the request path without the listen
+	// path, all paths when empty.
+	Path string `json:"path,omitempty" bson:"path,omitempty" msg:"path,omitempty"`
+
+	QuotaMax         int64           `json:"quota_max" bson:"quota_max" msg:"quota_max"`
+	QuotaRenewalRate int64           `json:"quota_renewal_rate" bson:"quota_renewal_rate" msg:"quota_renewal_rate"`
+	QuotaAlignment   *QuotaAlignment `json:"quota_alignment,omitempty" bson:"quota_alignment,omitempty" msg:"quota_alignment,omitempty"`
+	QuotaRemaining   int64           `json:"quota_remaining" bson:"quota_remaining" msg:"quota_remaining"`
+	QuotaRenews      int64           `json:"quota_renews" bson:"quota_renews" msg:"quota_renews"`
+}
+
+// APILimit returns the quota of the bucket.
+func (b QuotaBucket) APILimit() APILimit {
+	return APILimit{
+		QuotaMax:         b.QuotaMax,
+		QuotaRenewalRate: b.QuotaRenewalRate,
+		QuotaAlignment:   b.QuotaAlignment,
+		QuotaRemaining:   b.QuotaRemaining,
+		QuotaRenews:      b.QuotaRenews,
+	}
+}
+
+// DynamicLimit holds rate limit and quota values applied to the sessions whose metadata
+// match an expression, so a single policy can serve several tiers. Zero values keep the
+// values of the policy.
+type DynamicLimit struct {
+	// When is a boolean expression evaluated with the session metadata as `meta`,
+	// e.g. `meta.tier == "gold"`.
+	When             string  `json:"when" bson:"when"`
+	Rate             float64 `json:"rate" bson:"rate"`
+	Per              float64 `json:"per" bson:"per"`
+	QuotaMax         int64   `json:"quota_max" bson:"quota_max"`
+	QuotaRenewalRate int64   `json:"quota_renewal_rate" bson:"quota_renewal_rate"`
 }
 
 func (p *Policy) APILimit() APILimit {
 	return APILimit{
 		QuotaMax:           p.QuotaMax,
 		QuotaRenewalRate:   p.QuotaRenewalRate,
+		QuotaAlignment:     p.QuotaAlignment,
 		ThrottleInterval:   p.ThrottleInterval,
 		ThrottleRetryLimit: p.ThrottleRetryLimit,
 		MaxQueryDepth:      p.MaxQueryDepth,
@@ -0,0 +1,127 @@
+package user
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Calendar periods quotas can be aligned to.
+const (
+	QuotaPeriodHour  = "hour"
+	QuotaPeriodDay   = "day"
+	QuotaPeriodWeek  = "week"
+	QuotaPeriodMonth = "month"
+)
+
+// QuotaAlignment aligns the quota periods to calendar boundaries, e.g. midnight or the first
+// of the month, instead of renewing the quota QuotaRenewalRate seconds after its first use.
+type QuotaAlignment struct {
+	// Period is the calendar period the quota renews at: `hour`, `day`, `week` (starting on
+	// Monday) or `month`. It takes precedence over the quota renewal rate.
+	Period string `json:"period" msg:"period"`
+	// Timezone is the IANA name of the timezone of the period boundaries, UTC by default. The
+	// keys and the policies with an unknown timezone are rejected.
+	Timezone string `json:"timezone,omitempty" msg:"timezone,omitempty"`
+	// Prorate scales the quota of the period the key was created in by the part of the
+	// period left, so keys created mid-period are billed for what they can use.
+	Prorate bool `json:"prorate,omitempty" msg:"prorate,omitempty"`
+}
+
+// Enabled returns whether the quota periods are aligned to a known calendar period.
+func (q *QuotaAlignment) Enabled() bool {
+	if q == nil {
+		return false
+	}
+
+	switch q.Period {
+	case QuotaPeriodHour, QuotaPeriodDay, QuotaPeriodWeek, QuotaPeriodMonth:
+		return true
+	}
+
+	return false
+}
+
+// quotaLocations caches the timezones of the quota alignments by name, with the error of the
+// unknown ones, as loading a timezone reads the timezone database.
+var quotaLocations sync.Map
+
+type quotaLocation struct {
+	loc *time.Location
+	err error
+}
+
+// Location returns the timezone of the period boundaries.
+func (q *QuotaAlignment) Location() (*time.Location, error) {
+	if q.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	if cached, ok := quotaLocations.Load(q.Timezone); ok {
+		return cached.(quotaLocation).loc, cached.(quotaLocation).err
+	}
+
+	loc, err := time.LoadLocation(q.Timezone)
+	quotaLocations.Store(q.Timezone, quotaLocation{loc: loc, err: err})
+	return loc, err
+}
+
+// Validate returns an error when the timezone isn't a known IANA timezone.
+func (q *QuotaAlignment) Validate() error {
+	if q == nil {
+		return nil
+	}
+
+	if _, err := q.Location(); err != nil {
+		return fmt.Errorf("invalid quota alignment timezone %q: %w", q.Timezone, err)
+	}
+
+	return nil
+}
+
+// Bounds returns the start and the end of the period containing now. The boundaries are in
+// UTC when the timezone is unknown.
+func (q *QuotaAlignment) Bounds(now time.Time) (start, end time.Time) {
+	loc, err := q.Location()
+	if err != nil {
+		loc = time.UTC
+	}
+
+	now = now.In(loc)
+	year, month, day := now.Date()
+
+	switch q.Period {
+	case QuotaPeriodHour:
+		start = time.Date(year, month, day, now.Hour(), 0, 0, 0, loc)
+		end = time.Date(year, month, day, now.Hour()+1, 0, 0, 0, loc)
+	case QuotaPeriodDay:
+		start = time.Date(year, month, day, 0, 0, 0, 0, loc)
+		end = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	case QuotaPeriodWeek:
+		day -= (int(now.Weekday()) + 6) % 7
+		start = time.Date(year, month, day, 0, 0, 0, 0, loc)
+		end = time.Date(year, month, day+7, 0, 0, 0, 0, loc)
+	default:
+		start = time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		end = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	}
+
+	return start, end
+}
+
+// Quota returns the quota of the period containing now, for a key created at created. The
+// quota is prorated when the key was created within the period.
+func (q *QuotaAlignment) Quota(quotaMax int64, created, now time.Time) int64 {
+	if !q.Prorate || quotaMax <= 0 {
+		return quotaMax
+	}
+
+	start, end := q.Bounds(now)
+	if created.Before(start) || !created.Before(end) {
+		return quotaMax
+	}
+
+	left := float64(end.Sub(created)) / float64(end.Sub(start))
+	return int64(math.Ceil(float64(quotaMax) * left))
+}
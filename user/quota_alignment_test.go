@@ -0,0 +1,62 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaAlignment_Bounds(t *testing.T) {
+	now := time.Date(2024, time.February, 29, 23, 30, 0, 0, time.UTC) // a Thursday
+
+	tests := []struct {
+		period, timezone string
+		start, end       string
+	}{
+		{QuotaPeriodHour, "", "2024-02-29T23:00:00Z", "2024-03-01T00:00:00Z"},
+		{QuotaPeriodDay, "", "2024-02-29T00:00:00Z", "2024-03-01T00:00:00Z"},
+		{QuotaPeriodDay, "Europe/Paris", "2024-03-01T00:00:00+01:00", "2024-03-02T00:00:00+01:00"},
+		{QuotaPeriodWeek, "", "2024-02-26T00:00:00Z", "2024-03-04T00:00:00Z"},
+		{QuotaPeriodMonth, "", "2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+		{QuotaPeriodMonth, "Invalid/Zone", "2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.period+tc.timezone, func(t *testing.T) {
+			q := &QuotaAlignment{Period: tc.period, Timezone: tc.timezone}
+			start, end := q.Bounds(now)
+			assert.Equal(t, tc.start, start.Format(time.RFC3339))
+			assert.Equal(t, tc.end, end.Format(time.RFC3339))
+		})
+	}
+}
+
+func TestQuotaAlignment_Enabled(t *testing.T) {
+	var q *QuotaAlignment
+	assert.False(t, q.Enabled())
+	assert.False(t, (&QuotaAlignment{Period: "year"}).Enabled())
+	assert.True(t, (&QuotaAlignment{Period: QuotaPeriodMonth}).Enabled())
+}
+
+func TestQuotaAlignment_Quota(t *testing.T) {
+	now := time.Date(2024, time.April, 20, 0, 0, 0, 0, time.UTC)
+	q := &QuotaAlignment{Period: QuotaPeriodMonth, Prorate: true}
+
+	assert.Equal(t, int64(1000), q.Quota(1000, time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC), now))
+	assert.Equal(t, int64(500), q.Quota(1000, time.Date(2024, time.April, 16, 0, 0, 0, 0, time.UTC), now))
+	assert.Equal(t, int64(34), q.Quota(100, time.Date(2024, time.April, 21, 0, 0, 0, 0, time.UTC), now))
+	assert.Equal(t, int64(-1), q.Quota(-1, time.Date(2024, time.April, 16, 0, 0, 0, 0, time.UTC), now))
+
+	q.Prorate = false
+	assert.Equal(t, int64(1000), q.Quota(1000, time.Date(2024, time.April, 16, 0, 0, 0, 0, time.UTC), now))
+}
+
+func TestQuotaAlignment_Validate(t *testing.T) {
+	assert.NoError(t, (*QuotaAlignment)(nil).Validate())
+	assert.NoError(t, (&QuotaAlignment{Period: QuotaPeriodDay}).Validate())
+	assert.NoError(t, (&QuotaAlignment{Period: QuotaPeriodDay, Timezone: "Europe/Paris"}).Validate())
+	assert.Error(t, (&QuotaAlignment{Period: QuotaPeriodDay, Timezone: "Invalid/Zone"}).Validate())
+	// the error is cached with the timezone
+	assert.Error(t, (&QuotaAlignment{Period: QuotaPeriodDay, Timezone: "Invalid/Zone"}).Validate())
+}
@@ -59,6 +59,9 @@ type APILimit struct {
 	QuotaRemaining     int64   `json:"quota_remaining" msg:"quota_remaining"`
 	QuotaRenewalRate   int64   `json:"quota_renewal_rate" msg:"quota_renewal_rate"`
 	SetBy              string  `json:"-" msg:"-"`
+
+	// QuotaAlignment aligns the quota periods to calendar boundaries.
+	QuotaAlignment *QuotaAlignment `json:"quota_alignment,omitempty" msg:"quota_alignment,omitempty"`
 }
 
 // Clone does a deepcopy of APILimit.
@@ -69,6 +72,12 @@ func (a APILimit) Clone() *APILimit {
 		smoothingRef = &smoothing
 	}
 
+	var alignmentRef *QuotaAlignment
+	if a.QuotaAlignment != nil {
+		alignment := *a.QuotaAlignment
+		alignmentRef = &alignment
+	}
+
 	return &APILimit{
 		RateLimit: RateLimit{
 			Rate:      a.Rate,
@@ -83,6 +92,7 @@ func (a APILimit) Clone() *APILimit {
 		QuotaRemaining:     a.QuotaRemaining,
 		QuotaRenewalRate:   a.QuotaRenewalRate,
 		SetBy:              a.SetBy,
+		QuotaAlignment:     alignmentRef,
 	}
 }
 
@@ -113,6 +123,9 @@ type AccessDefinition struct {
 	AllowanceScope string `json:"allowance_scope" msg:"allowance_scope"`
 
 	Endpoints Endpoints `json:"endpoints,omitempty" msg:"endpoints,omitempty"`
+
+	// Scopes are the scopes granted for the API as a resource.
+	Scopes []string `json:"scopes,omitempty" msg:"scopes,omitempty"`
 }
 
 // IsEmpty checks if APILimit is empty.
@@ -153,6 +166,10 @@ func (a APILimit) IsEmpty() bool {
 		return false
 	}
 
+	if a.QuotaAlignment != nil {
+		return false
+	}
+
 	if a.SetBy != "" {
 		return false
 	}
@@ -293,6 +310,23 @@ type SessionState struct {
 	// Smoothing contains rate limit smoothing settings.
 	Smoothing *apidef.RateLimitSmoothing `json:"smoothing" bson:"smoothing"`
 
+	// QuotaAlignment aligns the quota periods to calendar boundaries.
+	QuotaAlignment *QuotaAlignment `json:"quota_alignment,omitempty" msg:"quota_alignment,omitempty"`
+
+	// QuotaBuckets are named quotas counted separately from the key quota, set by policies.
+	QuotaBuckets []QuotaBucket `json:"quota_buckets,omitempty" msg:"quota_buckets,omitempty"`
+
+	// QuotaPool is the ID of the quota pool the key shares with other keys.
+	QuotaPool string `json:"quota_pool,omitempty" msg:"quota_pool,omitempty"`
+
+	// MiddlewareFlags enable or disable the consumer scoped middleware of the APIs for the key, by
+	// middleware name. Consumer scoped middleware only runs for the keys enabling it.
+	MiddlewareFlags map[string]bool `json:"middleware_flags,omitempty" msg:"middleware_flags,omitempty"`
+
+	// PolicyMiddlewareFlags are the middleware flags of the policies of the key, recomputed
+	// whenever the policies are applied. They take precedence over the flags of the key.
+	PolicyMiddlewareFlags map[string]bool `json:"policy_middleware_flags,omitempty" msg:"policy_middleware_flags,omitempty"`
+
 	// modified holds the hint if a session has been modified for update.
 	// use Touch() to set it, and IsModified() to get it.
 	modified bool
@@ -313,6 +347,7 @@ func (s *SessionState) APILimit() APILimit {
 		QuotaMax:           s.QuotaMax,
 		QuotaRenewalRate:   s.QuotaRenewalRate,
 		QuotaRenews:        s.QuotaRenews,
+		QuotaAlignment:     s.QuotaAlignment,
 		ThrottleInterval:   s.ThrottleInterval,
 		ThrottleRetryLimit: s.ThrottleRetryLimit,
 		MaxQueryDepth:      s.MaxQueryDepth,
@@ -343,10 +378,29 @@ func (s SessionState) Clone() SessionState {
 	newSession.ApplyPolicies = cloneSlice(s.ApplyPolicies)
 	newSession.MetaData = cloneMetadata(s.MetaData)
 	newSession.Tags = cloneSlice(s.Tags)
+	newSession.MiddlewareFlags = cloneMiddlewareFlags(s.MiddlewareFlags)
+	newSession.PolicyMiddlewareFlags = cloneMiddlewareFlags(s.PolicyMiddlewareFlags)
+	newSession.QuotaBuckets = cloneQuotaBuckets(s.QuotaBuckets)
 
 	return newSession
 }
 
+func cloneQuotaBuckets(b []QuotaBucket) []QuotaBucket {
+	if b == nil {
+		return nil
+	}
+	x := make([]QuotaBucket, len(b))
+	for i, bucket := range b {
+		bucket.Methods = cloneSlice(bucket.Methods)
+		if bucket.QuotaAlignment != nil {
+			alignment := *bucket.QuotaAlignment
+			bucket.QuotaAlignment = &alignment
+		}
+		x[i] = bucket
+	}
+	return x
+}
+
 func cloneSlice(s []string) []string {
 	if s == nil {
 		return nil
@@ -359,6 +413,27 @@ func cloneSlice(s []string) []string {
 	return x
 }
 
+func cloneMiddlewareFlags(m map[string]bool) map[string]bool {
+	if m == nil {
+		return nil
+	}
+	x := make(map[string]bool, len(m))
+	for k, v := range m {
+		x[k] = v
+	}
+	return x
+}
+
+// MiddlewareEnabled returns true when the key enables the consumer scoped middleware. The flags
+// of the policies of the key take precedence over its own.
+func (s *SessionState) MiddlewareEnabled(name string) bool {
+	if enabled, ok := s.PolicyMiddlewareFlags[name]; ok {
+		return enabled
+	}
+
+	return s.MiddlewareFlags[name]
+}
+
 func cloneMetadata(m map[string]interface{}) map[string]interface{} {
 	if m == nil {
 		return nil
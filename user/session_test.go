@@ -37,6 +37,29 @@ func TestSessionState_Touch_and_IsModified(t *testing.T) {
 	assert.Equal(t, "tyk", result.OrgID)
 }
 
+func TestSessionState_Clone_quotaBuckets(t *testing.T) {
+	sess := NewSessionState()
+	sess.QuotaBuckets = []QuotaBucket{{
+		Name:           "reads",
+		Methods:        []string{"GET"},
+		QuotaMax:       10,
+		QuotaRemaining: 10,
+		QuotaAlignment: &QuotaAlignment{Period: QuotaPeriodDay},
+	}}
+
+	clone := sess.Clone()
+	assert.Equal(t, sess.QuotaBuckets, clone.QuotaBuckets)
+
+	clone.QuotaBuckets[0].QuotaRemaining = 9
+	clone.QuotaBuckets[0].Methods[0] = "POST"
+	clone.QuotaBuckets[0].QuotaAlignment.Period = QuotaPeriodMonth
+	assert.Equal(t, int64(10), sess.QuotaBuckets[0].QuotaRemaining)
+	assert.Equal(t, "GET", sess.QuotaBuckets[0].Methods[0])
+	assert.Equal(t, QuotaPeriodDay, sess.QuotaBuckets[0].QuotaAlignment.Period)
+
+	assert.Nil(t, NewSessionState().Clone().QuotaBuckets)
+}
+
 func TestIsHashType(t *testing.T) {
 	assert.False(t, IsHashType(""))
 	assert.False(t, IsHashType("invalid"))
@@ -269,6 +292,13 @@ func TestAPILimit_IsEmpty(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "QuotaAlignment is set",
+			input: APILimit{
+				QuotaAlignment: &QuotaAlignment{Period: QuotaPeriodDay},
+			},
+			expected: false,
+		},
 		{
 			name: "SetBy is non-empty",
 			input: APILimit{
@@ -346,6 +376,7 @@ func TestAPILimit_Clone(t *testing.T) {
 				QuotaRemaining:     250,
 				QuotaRenewalRate:   120,
 				SetBy:              "user",
+				QuotaAlignment:     &QuotaAlignment{Period: QuotaPeriodMonth, Prorate: true},
 			},
 		},
 	}
@@ -370,6 +401,11 @@ func TestAPILimit_Clone(t *testing.T) {
 				clone.Smoothing.Enabled = false
 				assert.NotEqual(t, tt.input, clone)
 			}
+
+			if tt.input.QuotaAlignment != nil {
+				clone.QuotaAlignment.Prorate = false
+				assert.True(t, tt.input.QuotaAlignment.Prorate)
+			}
 		})
 	}
 }